@@ -5,6 +5,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"recharge-go/configs"
 	"recharge-go/internal/config"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/repository/notification"
@@ -17,11 +18,16 @@ import (
 	"recharge-go/pkg/queue"
 	"recharge-go/pkg/redis"
 	"syscall"
+	"time"
 )
 
 func main() {
 	// 初始化配置
 	cfg := config.GetConfig()
+	appCfg, err := configs.LoadConfig("configs/config.yaml")
+	if err != nil {
+		panic(err)
+	}
 
 	// 初始化日志
 	if err := logger.InitLogger("recharge"); err != nil {
@@ -52,6 +58,9 @@ func main() {
 	balanceLogRepo := repository.NewBalanceLogRepository(db)
 	productRepo := repository.NewProductRepository(db)
 
+	// 配置上游平台接口熔断器（需在创建平台管理器、充值服务之前），与服务端共用熔断状态
+	recharge.ConfigureCircuitBreaker(recharge.BreakerConfigFromSettings(appCfg.Breaker), nil)
+
 	// 初始化平台管理器
 	platformManager := recharge.NewManager(db)
 	if err := platformManager.LoadPlatforms(); err != nil {
@@ -125,6 +134,13 @@ func main() {
 	// 启动任务
 	go retryTask.Start()
 	go rechargeTask.Start(ctx)
+	go rechargeService.GetRoutingService().SpendLimiter().RunReconciler(ctx, 5*time.Minute)
+	go rechargeService.GetSplitService().RunSettler(ctx, time.Minute)
+	go rechargeService.GetStatusPoller().Run(ctx, time.Minute)
+	go rechargeService.GetOutbox().Run(ctx, 30*time.Second)
+	reconcileConfig := service.OrderReconcileConfigFromSettings(appCfg.Reconcile)
+	orderReconciler := service.NewOrderReconciler(db, rechargeService, orderService, repository.NewOrderReconcileReportRepository(db), reconcileConfig)
+	go orderReconciler.Run(ctx, reconcileConfig.Interval)
 
 	// 等待中断信号
 	sigChan := make(chan os.Signal, 1)
@@ -14,13 +14,17 @@ type RedisConfig struct {
 }
 
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	DB     DBConfig     `mapstructure:"database"`
-	JWT    JWTConfig    `mapstructure:"jwt"`
-	Log    LogConfig    `mapstructure:"log"`
-	Task   TaskConfig   `mapstructure:"task"`
-	API    APIConfig    `mapstructure:"api"`
-	Redis  RedisConfig  `mapstructure:"redis"`
+	Server           ServerConfig           `mapstructure:"server"`
+	DB               DBConfig               `mapstructure:"database"`
+	JWT              JWTConfig              `mapstructure:"jwt"`
+	Log              LogConfig              `mapstructure:"log"`
+	Task             TaskConfig             `mapstructure:"task"`
+	API              APIConfig              `mapstructure:"api"`
+	Redis            RedisConfig            `mapstructure:"redis"`
+	Breaker          BreakerConfig          `mapstructure:"breaker"`
+	Reconcile        ReconcileConfig        `mapstructure:"reconcile"`
+	Notification     NotificationConfig     `mapstructure:"notification"`
+	BalanceReconcile BalanceReconcileConfig `mapstructure:"balance_reconcile"`
 }
 
 type ServerConfig struct {
@@ -66,6 +70,49 @@ type APIConfig struct {
 	BaseURL string `mapstructure:"base_url"`
 }
 
+// BreakerConfig 上游平台接口熔断配置，未配置的项使用默认值
+type BreakerConfig struct {
+	WindowSeconds  int     `mapstructure:"window_seconds"`   // 滚动统计窗口（秒）
+	MinRequests    int     `mapstructure:"min_requests"`     // 窗口内最少请求数
+	FailureRate    float64 `mapstructure:"failure_rate"`     // 失败率阈值 0-1
+	OpenSeconds    int     `mapstructure:"open_seconds"`     // 熔断持续时间（秒）
+	HalfOpenProbes int     `mapstructure:"half_open_probes"` // 半开探测请求数
+	CallbackWeight float64 `mapstructure:"callback_weight"`  // 回调失败权重
+}
+
+// ReconcileConfig 滞留订单对账配置，SLA 单位为分钟，未配置的项使用默认值
+// sla 小于0表示不处置该状态；fail_after 为订单创建后多久仍未完成即失败退款，小于0表示不自动失败
+type ReconcileConfig struct {
+	IntervalSeconds          int `mapstructure:"interval_seconds"`            // 对账间隔（秒）
+	BatchSize                int `mapstructure:"batch_size"`                  // 每种状态每轮最多处置的订单数
+	PendingPaymentSLA        int `mapstructure:"pending_payment_sla"`         // 待支付超时后取消
+	PendingRechargeSLA       int `mapstructure:"pending_recharge_sla"`        // 待充值超时后重新投递
+	PendingRechargeFailAfter int `mapstructure:"pending_recharge_fail_after"` // 待充值失败退款时限
+	ProcessingSLA            int `mapstructure:"processing_sla"`              // 处理中超时后查询上游
+	ProcessingFailAfter      int `mapstructure:"processing_fail_after"`       // 处理中失败退款时限
+	RechargingSLA            int `mapstructure:"recharging_sla"`              // 充值中超时后查询上游
+	RechargingFailAfter      int `mapstructure:"recharging_fail_after"`       // 充值中失败退款时限
+	SplitSLA                 int `mapstructure:"split_sla"`                   // 已拆单超时后汇总子订单
+}
+
+// BalanceReconcileConfig 资金对账配置，未配置的项使用默认值
+// auto_correct 为 true 时定时对账以流水重算值为准自动修正余额差异，订单扣退款异常始终只报告
+type BalanceReconcileConfig struct {
+	IntervalSeconds    int  `mapstructure:"interval_seconds"`     // 对账间隔（秒）
+	OrderLookbackHours int  `mapstructure:"order_lookback_hours"` // 核对最近多少小时内完结的订单
+	AutoCorrect        bool `mapstructure:"auto_correct"`         // 是否自动修正余额差异
+}
+
+// NotificationConfig 下游通知任务配置，未配置的项使用默认值
+// retry_schedule 为默认重试表，客户 API 密钥或通知模板上配置了重试表时以其为准
+type NotificationConfig struct {
+	WorkerCount   int    `mapstructure:"worker_count"`   // 工作协程数量
+	BatchSize     int    `mapstructure:"batch_size"`     // 重试调度每轮最多分发的通知数
+	ScanSeconds   int    `mapstructure:"scan_seconds"`   // 重试调度扫描间隔（秒）
+	StaleMinutes  int    `mapstructure:"stale_minutes"`  // 待处理或处理中超过该时长视为丢失（分钟）
+	RetrySchedule string `mapstructure:"retry_schedule"` // 逗号分隔的重试间隔，如 0s,15s,1m,5m,30m,2h
+}
+
 var config *Config
 
 // LoadConfig 从指定路径加载配置文件
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"recharge-go/configs"
 	"recharge-go/internal/middleware"
+	notificationModel "recharge-go/internal/model/notification"
 	"recharge-go/internal/pkg/db"
 	"recharge-go/internal/repository"
 	notificationRepo "recharge-go/internal/repository/notification"
 	"recharge-go/internal/service"
 	notificationService "recharge-go/internal/service/notification"
 	"recharge-go/internal/service/platform"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/internal/task"
 	"recharge-go/pkg/database"
 	"recharge-go/pkg/lock"
 	loggerV2 "recharge-go/pkg/logger"
@@ -61,6 +64,8 @@ type Repositories struct {
 	CallbackLog         repository.CallbackLogRepository
 	BalanceLog          *repository.BalanceLogRepository
 	Notification        notificationRepo.Repository
+	NotifyTemplate      *notificationRepo.TemplateRepository
+	NotifyAttempt       *notificationRepo.AttemptRepository
 	TaskConfig          *repository.TaskConfigRepository
 	TaskOrder           *repository.TaskOrderRepository
 	DaichongOrder       *repository.DaichongOrderRepository
@@ -71,6 +76,9 @@ type Repositories struct {
 	CreditLog           *repository.CreditLogRepository     // 添加CreditLog repository
 	SystemConfig        *repository.SystemConfigRepository  // 添加SystemConfig repository
 	ExternalAPIKey      repository.ExternalAPIKeyRepository // 添加ExternalAPIKey repository
+	OrderSplitRule      *repository.OrderSplitRuleRepository
+	OrderReconcile      *repository.OrderReconcileReportRepository
+	BalanceReconcile    *repository.BalanceReconcileReportRepository
 }
 
 // Services 服务集合
@@ -104,6 +112,9 @@ type Services struct {
 	PlatformPushStatus     *platform.PushStatusService       // 添加PlatformPushStatus服务
 	PlatformSvc            *platform.Service                 // 添加platform.Service
 	SystemConfig           *service.SystemConfigService      // 添加SystemConfig服务
+	OrderReconciler        *service.OrderReconciler
+	BalanceReconciler      *service.BalanceReconciler
+	CallbackReplay         *service.CallbackReplayService
 }
 
 // NewContainer 创建新的容器实例
@@ -231,6 +242,8 @@ func (c *Container) initRepositories() {
 		CallbackLog:         repository.NewCallbackLogRepository(c.db),
 		BalanceLog:          repository.NewBalanceLogRepository(c.db),
 		Notification:        notificationRepo.NewRepository(c.db),
+		NotifyTemplate:      notificationRepo.NewTemplateRepository(c.db),
+		NotifyAttempt:       notificationRepo.NewAttemptRepository(c.db),
 		TaskConfig:          repository.NewTaskConfigRepository(c.db),
 		TaskOrder:           repository.NewTaskOrderRepository(c.db),
 		DaichongOrder:       repository.NewDaichongOrderRepository(c.db),
@@ -241,11 +254,17 @@ func (c *Container) initRepositories() {
 		CreditLog:           repository.NewCreditLogRepository(c.db),
 		SystemConfig:        repository.NewSystemConfigRepository(c.db),
 		ExternalAPIKey:      repository.NewExternalAPIKeyRepository(c.db),
+		OrderSplitRule:      repository.NewOrderSplitRuleRepository(c.db),
+		OrderReconcile:      repository.NewOrderReconcileReportRepository(c.db),
+		BalanceReconcile:    repository.NewBalanceReconcileReportRepository(c.db),
 	}
 }
 
 // 初始化服务
 func (c *Container) initServices() error {
+	// 配置上游平台接口熔断器（需在创建充值服务之前）
+	recharge.ConfigureCircuitBreaker(recharge.BreakerConfigFromSettings(c.config.Breaker), c.metricsManager)
+
 	// 创建队列实例
 	queueInstance := queue.NewRedisQueue()
 
@@ -302,7 +321,7 @@ func (c *Container) initServices() error {
 	c.services.Platform = service.NewPlatformService(c.repositories.Platform, c.repositories.Order, c.repositories.ExternalAPIKey)
 	c.services.PlatformService = c.services.Platform
 	c.services.Statistics = service.NewStatisticsService(c.repositories.OrderStatistics, c.repositories.Order)
-	c.services.Notification = notificationService.NewNotificationService(c.repositories.Notification, queueInstance)
+	c.services.Notification = notificationService.NewNotificationService(c.repositories.Notification, c.repositories.NotifyAttempt, queueInstance)
 
 	// 创建充值服务
 	c.services.Recharge = service.NewRechargeService(
@@ -339,6 +358,21 @@ func (c *Container) initServices() error {
 	// 设置相互依赖
 	c.services.Recharge.SetOrderService(c.services.Order)
 
+	// 初始化滞留订单对账
+	c.services.OrderReconciler = service.NewOrderReconciler(
+		c.db,
+		c.services.Recharge,
+		c.services.Order,
+		c.repositories.OrderReconcile,
+		service.OrderReconcileConfigFromSettings(c.config.Reconcile),
+	)
+
+	// 初始化资金对账
+	c.services.BalanceReconciler = service.NewBalanceReconciler(c.db, c.repositories.BalanceReconcile, c.balanceReconcileConfig())
+
+	// 初始化回调重放
+	c.services.CallbackReplay = service.NewCallbackReplayService(c.repositories.CallbackLog, c.services.Recharge)
+
 	// 初始化重试服务
 	c.services.Retry = service.NewRetryService(
 		c.repositories.Retry,
@@ -432,6 +466,46 @@ func (c *Container) initServices() error {
 	return nil
 }
 
+// balanceReconcileConfig 根据配置文件生成资金对账配置
+func (c *Container) balanceReconcileConfig() service.BalanceReconcileConfig {
+	cfg := service.DefaultBalanceReconcileConfig()
+	bc := c.config.BalanceReconcile
+	if bc.IntervalSeconds > 0 {
+		cfg.Interval = time.Duration(bc.IntervalSeconds) * time.Second
+	}
+	if bc.OrderLookbackHours > 0 {
+		cfg.OrderLookback = time.Duration(bc.OrderLookbackHours) * time.Hour
+	}
+	cfg.AutoCorrect = bc.AutoCorrect
+	return cfg
+}
+
+// notificationTaskConfig 根据配置文件生成下游通知任务配置
+func (c *Container) notificationTaskConfig() (task.NotificationTaskConfig, error) {
+	cfg := task.DefaultNotificationTaskConfig()
+	nc := c.config.Notification
+	if nc.WorkerCount > 0 {
+		cfg.WorkerCount = nc.WorkerCount
+	}
+	if nc.BatchSize > 0 {
+		cfg.BatchSize = nc.BatchSize
+	}
+	if nc.ScanSeconds > 0 {
+		cfg.ScanInterval = time.Duration(nc.ScanSeconds) * time.Second
+	}
+	if nc.StaleMinutes > 0 {
+		cfg.StaleTimeout = time.Duration(nc.StaleMinutes) * time.Minute
+	}
+	if nc.RetrySchedule != "" {
+		schedule, err := notificationModel.ParseRetrySchedule(nc.RetrySchedule)
+		if err != nil {
+			return cfg, fmt.Errorf("通知重试表配置错误: %v", err)
+		}
+		cfg.RetrySchedule = schedule
+	}
+	return cfg, nil
+}
+
 // initLogger 初始化日志
 func (c *Container) initLogger(serviceName string) error {
 	// 使用pkg/logger包中的InitLogger函数初始化日志
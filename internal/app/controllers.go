@@ -27,6 +27,13 @@ type Controllers struct {
 	Credit             *controller.CreditController
 	SystemConfig       *controller.SystemConfigController
 	ExternalAPIKey     *controller.ExternalAPIKeyController
+	PlatformAdapter    *controller.PlatformAdapterController
+	SupplierHealth     *controller.SupplierHealthController
+	OrderSplitRule     *controller.OrderSplitRuleController
+	RechargeQueue      *controller.RechargeQueueController
+	OrderReconcile     *controller.OrderReconcileController
+	BalanceReconcile   *controller.BalanceReconcileController
+	CallbackReplay     *controller.CallbackReplayController
 
 	// Handlers
 	Recharge     *handler.RechargeHandler
@@ -59,6 +66,13 @@ func (c *Container) initControllers() {
 		Credit:             controller.NewCreditController(c.services.Credit),
 		SystemConfig:       controller.NewSystemConfigController(c.services.SystemConfig),
 		ExternalAPIKey:     controller.NewExternalAPIKeyController(c.repositories.ExternalAPIKey, c.repositories.User),
+		PlatformAdapter:    controller.NewPlatformAdapterController(c.repositories.PlatformAPI),
+		SupplierHealth:     controller.NewSupplierHealthController(c.repositories.PlatformAPI),
+		OrderSplitRule:     controller.NewOrderSplitRuleController(c.repositories.OrderSplitRule, c.services.Recharge.GetSplitService()),
+		RechargeQueue:      controller.NewRechargeQueueController(c.services.Recharge.GetRechargeQueue()),
+		OrderReconcile:     controller.NewOrderReconcileController(c.services.OrderReconciler, c.repositories.OrderReconcile),
+		BalanceReconcile:   controller.NewBalanceReconcileController(c.services.BalanceReconciler, c.repositories.BalanceReconcile),
+		CallbackReplay:     controller.NewCallbackReplayController(c.services.CallbackReplay),
 
 		// Handlers
 		Recharge:     handler.NewRechargeHandler(c.services.Recharge),
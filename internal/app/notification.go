@@ -31,12 +31,18 @@ func (n *NotificationApp) Initialize() error {
 	)
 
 	// 创建通知任务处理器
+	taskConfig, err := n.container.notificationTaskConfig()
+	if err != nil {
+		return err
+	}
 	queueInstance := queue.NewRedisQueue()
 	n.notificationTask = task.NewNotificationTask(
 		n.container.GetServices().Notification,
 		n.container.GetServices().Platform,
+		n.container.GetRepositories().ExternalAPIKey,
+		n.container.GetRepositories().NotifyTemplate,
 		queueInstance,
-		3, // 最大重试次数
+		taskConfig,
 	)
 
 	return nil
@@ -9,6 +9,15 @@ import (
 	"recharge-go/internal/service"
 )
 
+// spendReconcileInterval 平台账号额度计数器对账间隔
+const spendReconcileInterval = 5 * time.Minute
+
+// splitSettleInterval 拆单父订单汇总间隔
+const splitSettleInterval = time.Minute
+
+// outboxRelayInterval 发件箱补投间隔
+const outboxRelayInterval = 30 * time.Second
+
 // RechargeApp 充值应用
 type RechargeApp struct {
 	container       *Container
@@ -56,15 +65,36 @@ func (r *RechargeApp) Start(ctx context.Context) error {
 	// 创建充值工作器
 	r.rechargeWorker = service.NewRechargeWorker(
 		r.container.GetServices().Recharge,
-		time.Second*5, // 每5秒检查一次
-		batchSize,     // 从配置读取批量处理数量
+		batchSize, // 从配置读取并发处理数量
 	)
 
 	// 创建上下文
 	r.ctx, r.cancel = context.WithCancel(ctx)
 
 	// 启动充值工作器
-	go r.rechargeWorker.Start(r.ctx)
+	go func() {
+		if err := r.rechargeWorker.Start(r.ctx); err != nil {
+			log.Printf("充值工作器启动失败: %v", err)
+		}
+	}()
+
+	// 启动平台账号额度对账
+	go r.container.GetServices().Recharge.GetRoutingService().SpendLimiter().RunReconciler(r.ctx, spendReconcileInterval)
+
+	// 启动拆单父订单汇总
+	go r.container.GetServices().Recharge.GetSplitService().RunSettler(r.ctx, splitSettleInterval)
+
+	// 启动充值中订单主动查询
+	go r.container.GetServices().Recharge.GetStatusPoller().Run(r.ctx, 0)
+
+	// 启动滞留订单对账（间隔取自配置）
+	go r.container.GetServices().OrderReconciler.Run(r.ctx, 0)
+
+	// 启动资金对账（间隔取自配置）
+	go r.container.GetServices().BalanceReconciler.Run(r.ctx, 0)
+
+	// 启动发件箱补投
+	go r.container.GetServices().Recharge.GetOutbox().Run(r.ctx, outboxRelayInterval)
 
 	log.Println("充值应用启动成功")
 	return nil
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"recharge-go/internal/service"
 	"recharge-go/internal/utils"
+	"recharge-go/pkg/money"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -19,39 +20,39 @@ func NewBalanceController(service *service.BalanceService) *BalanceController {
 	return &BalanceController{service: service}
 }
 
-// Recharge 余额充值接口
+// Recharge 余额充值接口，请求头 Idempotency-Key 可选，携带时重复提交只入账一次
 func (c *BalanceController) Recharge(ctx *gin.Context) {
 	var req struct {
-		UserID   int64   `json:"user_id" binding:"required"`
-		Amount   float64 `json:"amount" binding:"required,gt=0"`
-		Remark   string  `json:"remark"`
-		Operator string  `json:"operator"`
+		UserID   int64       `json:"user_id" binding:"required"`
+		Amount   money.Money `json:"amount" binding:"required,gt=0"`
+		Remark   string      `json:"remark"`
+		Operator string      `json:"operator"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.Error(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := c.service.Recharge(ctx, req.UserID, req.Amount, req.Remark, req.Operator); err != nil {
+	if err := c.service.Recharge(service.WithIdempotencyKey(ctx, ctx.GetHeader("Idempotency-Key")), req.UserID, req.Amount, req.Remark, req.Operator); err != nil {
 		utils.Error(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 	utils.Success(ctx, nil)
 }
 
-// Deduct 余额扣款接口
+// Deduct 余额扣款接口，请求头 Idempotency-Key 可选，携带时重复提交只扣款一次
 func (c *BalanceController) Deduct(ctx *gin.Context) {
 	var req struct {
-		UserID   int64   `json:"user_id" binding:"required"`
-		Amount   float64 `json:"amount" binding:"required,gt=0"`
-		Style    int     `json:"style" binding:"required"`
-		Remark   string  `json:"remark"`
-		Operator string  `json:"operator"`
+		UserID   int64       `json:"user_id" binding:"required"`
+		Amount   money.Money `json:"amount" binding:"required,gt=0"`
+		Style    int         `json:"style" binding:"required"`
+		Remark   string      `json:"remark"`
+		Operator string      `json:"operator"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.Error(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := c.service.Deduct(ctx, req.UserID, req.Amount, req.Style, req.Remark, req.Operator); err != nil {
+	if err := c.service.Deduct(service.WithIdempotencyKey(ctx, ctx.GetHeader("Idempotency-Key")), req.UserID, req.Amount, req.Style, req.Remark, req.Operator); err != nil {
 		utils.Error(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -75,3 +76,36 @@ func (c *BalanceController) ListLogs(ctx *gin.Context) {
 	}
 	utils.Success(ctx, gin.H{"list": logs, "total": total})
 }
+
+// ListLedger 记账凭证查询接口，按 user_id 或 order_id 查询
+func (c *BalanceController) ListLedger(ctx *gin.Context) {
+	userID, _ := strconv.ParseInt(ctx.Query("user_id"), 10, 64)
+	orderID, _ := strconv.ParseInt(ctx.Query("order_id"), 10, 64)
+	if userID <= 0 && orderID <= 0 {
+		utils.Error(ctx, http.StatusBadRequest, "user_id or order_id is required")
+		return
+	}
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	txns, total, err := c.service.ListLedger(ctx, userID, orderID, offset, limit)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.Success(ctx, gin.H{"list": txns, "total": total})
+}
+
+// LedgerBalance 账本余额查询接口，返回由分录汇总的余额、授信额度与冻结金额
+func (c *BalanceController) LedgerBalance(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Query("user_id"), 10, 64)
+	if err != nil || userID <= 0 {
+		utils.Error(ctx, http.StatusBadRequest, "invalid user_id")
+		return
+	}
+	balance, credit, frozen, err := c.service.LedgerBalance(ctx, userID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.Success(ctx, gin.H{"user_id": userID, "balance": balance, "credit": credit, "frozen_balance": frozen})
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BalanceReconcileController 资金对账控制器
+type BalanceReconcileController struct {
+	reconciler *service.BalanceReconciler
+	reportRepo *repository.BalanceReconcileReportRepository
+}
+
+// NewBalanceReconcileController 创建资金对账控制器
+func NewBalanceReconcileController(reconciler *service.BalanceReconciler, reportRepo *repository.BalanceReconcileReportRepository) *BalanceReconcileController {
+	return &BalanceReconcileController{
+		reconciler: reconciler,
+		reportRepo: reportRepo,
+	}
+}
+
+// Run 手动执行一轮资金对账，auto_correct=true 时修正余额差异
+func (c *BalanceReconcileController) Run(ctx *gin.Context) {
+	autoCorrect, _ := strconv.ParseBool(ctx.DefaultQuery("auto_correct", "false"))
+	report, err := c.reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, autoCorrect)
+	if err != nil {
+		logger.Log.Error("执行资金对账失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "执行资金对账失败")
+		return
+	}
+	items, _ := report.ParseItems()
+	utils.Success(ctx, gin.H{"report": report, "items": items})
+}
+
+// ListReports 分页获取资金对账报告
+func (c *BalanceReconcileController) ListReports(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reports, total, err := c.reportRepo.List(ctx, page, pageSize)
+	if err != nil {
+		logger.Log.Error("获取资金对账报告列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取资金对账报告列表失败")
+		return
+	}
+	utils.Success(ctx, gin.H{"list": reports, "total": total})
+}
+
+// GetReport 获取资金对账报告及差异明细
+func (c *BalanceReconcileController) GetReport(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的报告ID")
+		return
+	}
+
+	report, err := c.reportRepo.GetByID(ctx, id)
+	if err != nil {
+		utils.Error(ctx, http.StatusNotFound, "对账报告不存在")
+		return
+	}
+	items, err := report.ParseItems()
+	if err != nil {
+		logger.Log.Error("解析资金对账明细失败", zap.Int64("report_id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "解析资金对账明细失败")
+		return
+	}
+	utils.Success(ctx, gin.H{"report": report, "items": items})
+}
@@ -2,16 +2,14 @@ package controller
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
-	"net/url"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/logger"
-	"recharge-go/pkg/signature"
-	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -56,244 +54,71 @@ type MishiCallbackRequest struct {
 
 // HandleKekebangCallback 处理客帮帮回调
 func (c *CallbackController) HandleKekebangCallback(ctx *gin.Context) {
-	// 从URL中获取userid
-	userID := ctx.Param("userid")
-	if userID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "missing userid"})
-		return
-	}
-
-	// 获取账号信息
-	account, err := c.platformRepo.GetPlatformAccountByAccountName(userID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get account info"})
-		return
-	}
-
-	// 读取请求体
-	body, err := io.ReadAll(ctx.Request.Body)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "failed to read request body"})
-		return
-	}
-
-	// 解析请求体
-	var data map[string]interface{}
-
-	if err := json.Unmarshal(body, &data); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid request body"})
-		return
-	}
-	// 获取签名
-	sign, ok := data["sign"].(string)
-	if !ok {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"code": "1001",
-			"msg":  "invalid sign",
-		})
-		return
-	}
-
-	// 使用账号的AppSecret验证签名
-	if !verifySignature(body, sign, account.AppSecret) {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"code": "1001",
-			"msg":  "invalid sign",
-		})
-		return
-	}
-
-	// 处理回调
-	if err := c.rechargeService.HandleCallback(ctx, "kekebang", body); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to process callback"})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{"code": 200, "message": "success"})
-}
-
-// verifySignature 验证签名
-func verifySignature(body []byte, sign string, secretKey string) bool {
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return false
-	}
-	return signature.VerifyKekebangSign(data, sign, secretKey)
+	c.handleCallback(ctx, "kekebang", ctx.Param("userid"))
 }
 
 // HandleMishiCallback 处理秘史平台回调
 func (c *CallbackController) HandleMishiCallback(ctx *gin.Context) {
-	fmt.Printf("[mishi] 处理秘史平台回调!!!\n")
-	// 1. 获取userid
-	userIDStr := ctx.Param("userid")
-	if userIDStr == "" {
-		utils.ErrorWithStatus(ctx, 500, 400, "缺少userid")
-		return
-	}
-
-	// 获取 appkey 等信息
-	account, err := c.platformRepo.GetPlatformAccountByAccountName(userIDStr)
-	if err != nil {
-		logger.Error("返回：401 平台账号不存在", zap.Error(err))
-		utils.ErrorWithStatus(ctx, 401, 400, "平台账号不存在")
-		return
-	}
-
-	// 读取原始请求体
-	body, err := io.ReadAll(ctx.Request.Body)
-	if err != nil {
-		utils.Error(ctx, 400, "读取请求体失败")
-		return
-	}
-
-	// 解析参数
-	form, err := url.ParseQuery(string(body))
-	if err != nil {
-		utils.Error(ctx, 400, "参数解析失败")
-		return
-	}
-	params := make(map[string]string)
-	for k, v := range form {
-		if len(v) > 0 {
-			params[k] = v[0]
-		}
-	}
-
-	// 签名校验
-	nDemo, _ := strconv.ParseFloat(params["nDemo"], 64)
-	fSalePrice, _ := strconv.ParseFloat(params["fSalePrice"], 64)
-	nFlag, _ := strconv.Atoi(params["nFlag"])
-
-	signStr := fmt.Sprintf(
-		"szAgentId=%s&szOrderId=%s&szPhoneNum=%s&nDemo=%v&fSalePrice=%.1f&nFlag=%d&szKey=%s",
-		params["szAgentId"],
-		params["szOrderId"],
-		params["szPhoneNum"],
-		nDemo,
-		fSalePrice,
-		nFlag,
-		account.AppSecret,
-	)
-	if signature.GetMD5(signStr) != params["szVerifyString"] {
-		logger.Error("返回：401 签名校验失败")
-		utils.ErrorWithStatus(ctx, 401, 401, "签名校验失败")
-		return
-	}
-
-	// 业务处理交给 service
-	if err := c.rechargeService.HandleCallback(ctx, "mishi", body); err != nil {
-		logger.Error("返回：500 处理回调失败", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to process callback"})
-		return
-	}
-	ctx.String(200, "success")
+	c.handleCallback(ctx, "mishi", ctx.Param("userid"))
 }
 
-// HandleChongzhiCallback 处理充值平台回调
+// HandleChongzhiCallback 处理充值平台回调（签名验证在ParseCallbackData中处理）
 func (c *CallbackController) HandleChongzhiCallback(ctx *gin.Context) {
-	// 1. 获取userid
-	logger.Info("处理充值平台回调!!!")
-	userIDStr := ctx.Param("userid")
-	if userIDStr == "" {
-		logger.Error("处理充值平台回调 返回：400 缺少userid")
-		utils.Error(ctx, 400, "缺少userid")
-		return
-	}
-
-	// 2. 验证平台账号是否存在
-	_, err := c.platformRepo.GetPlatformAccountByAccountName(userIDStr)
-	if err != nil {
-		logger.Error("处理充值平台回调 返回：400 平台账号不存在", zap.Error(err))
-		utils.Error(ctx, 400, "平台账号不存在")
-		return
-	}
-
-	// 3. 读取原始请求体
-	body, err := io.ReadAll(ctx.Request.Body)
-	if err != nil {
-		logger.Error("处理充值平台回调 返回：400 读取请求体失败", zap.Error(err))
-		utils.Error(ctx, 400, "读取请求体失败")
-		return
-	}
-
-	// 打印原始回调数据用于调试
-	logger.Info("收到充值平台回调数据", 
-		zap.String("userid", userIDStr),
-		zap.String("raw_body", string(body)),
-		zap.String("content_type", ctx.GetHeader("Content-Type")),
-		zap.String("user_agent", ctx.GetHeader("User-Agent")),
-	)
-
-	// 4. 调用 service 层处理业务（chongzhi平台的签名验证在ParseCallbackData中处理）
-	err = c.rechargeService.HandleCallback(ctx, "chongzhi", body)
-	if err != nil {
-		logger.Error("处理充值平台回调 返回：500 处理回调失败", zap.Error(err))
-		utils.Error(ctx, 500, err.Error())
-		return
-	}
-
-	// 5. 返回成功（根据文档要求返回OK字符）
-	logger.Info("处理充值平台回调 返回：200 成功")
-	ctx.String(200, "OK")
+	c.handleCallback(ctx, "chongzhi", ctx.Param("userid"))
 }
 
 // HandleDayuanrenCallback 处理大猿人平台回调
 func (c *CallbackController) HandleDayuanrenCallback(ctx *gin.Context) {
-	// 1. 获取userid
-	logger.Info("处理大猿人平台回调!!!")
-	userIDStr := ctx.Param("userid")
-	if userIDStr == "" {
-		logger.Error("处理大猿人平台回调 返回：400 缺少userid")
-		utils.Error(ctx, 400, "缺少userid")
-		return
-	}
+	c.handleCallback(ctx, "dayuanren", ctx.Param("userid"))
+}
 
-	// 2. 获取平台账号信息
-	account, err := c.platformRepo.GetPlatformAccountByAccountName(userIDStr)
-	if err != nil {
-		logger.Error("处理大猿人平台回调 返回：400 平台账号不存在", zap.Error(err))
-		utils.Error(ctx, 400, "平台账号不存在")
+// HandleGenericCallback 处理通用HTTP模板平台回调
+// 回调地址为 /callback/generic/:code，code 为平台接口代码，验签及字段解析由模板在 ParseCallbackData 中完成
+func (c *CallbackController) HandleGenericCallback(ctx *gin.Context) {
+	code := ctx.Param("code")
+	if code == "" {
+		utils.Error(ctx, 400, "缺少平台代码")
 		return
 	}
+	c.handleCallback(ctx, code, "")
+}
 
-	// 3. 读取原始请求体
+// handleCallback 读取原始请求体交给回调处理流程，按平台要求返回应答
+// 验签失败等无法处理的回调返回 400，处理出错返回 500 由平台重发，成功及重复回调返回平台约定的应答
+func (c *CallbackController) handleCallback(ctx *gin.Context, platformCode, accountName string) {
 	body, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
-		logger.Error("处理大猿人平台回调 返回：400 读取请求体失败", zap.Error(err))
+		logger.Error("处理平台回调 返回：400 读取请求体失败", zap.String("platform_code", platformCode), zap.Error(err))
 		utils.Error(ctx, 400, "读取请求体失败")
 		return
 	}
+	logger.Info("收到平台回调数据",
+		zap.String("platform_code", platformCode),
+		zap.String("userid", accountName),
+		zap.String("raw_body", string(body)),
+		zap.String("content_type", ctx.GetHeader("Content-Type")),
+	)
 
-	// 4. 解析表单参数
-	form, err := url.ParseQuery(string(body))
-	if err != nil {
-		logger.Error("处理大猿人平台回调 返回：400 解析参数失败", zap.Error(err))
-		utils.Error(ctx, 400, "解析参数失败")
-		return
-	}
-	params := make(map[string]string)
-	for k, v := range form {
-		if len(v) > 0 {
-			params[k] = v[0]
-		}
-	}
-
-	// 5. 签名校验
-	if !signature.VerifyDayuanrenSign(params, account.AppSecret) {
-		logger.Error("处理大猿人平台回调 返回：400 签名校验失败")
-		utils.Error(ctx, 400, "签名校验失败")
+	result, err := c.rechargeService.HandleCallback(ctx, &service.CallbackRequest{
+		PlatformCode: platformCode,
+		AccountName:  accountName,
+		SourceIP:     ctx.ClientIP(),
+		Body:         body,
+	})
+	if errors.Is(err, service.ErrCallbackRejected) {
+		logger.Error("处理平台回调 返回：400 回调校验失败", zap.String("platform_code", platformCode), zap.Error(err))
+		utils.Error(ctx, 400, err.Error())
 		return
 	}
-
-	// 6. 调用 service 层处理业务
-	err = c.rechargeService.HandleCallback(ctx, "dayuanren", body)
 	if err != nil {
-		logger.Error("处理大猿人平台回调 返回：500 处理回调失败", zap.Error(err))
+		logger.Error("处理平台回调 返回：500 处理回调失败", zap.String("platform_code", platformCode), zap.Error(err))
 		utils.Error(ctx, 500, err.Error())
 		return
 	}
 
-	// 7. 返回成功
-	logger.Info("处理大猿人平台回调 返回：200 成功")
-	ctx.String(200, "success")
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid([]byte(result.Ack)) && strings.HasPrefix(result.Ack, "{") {
+		contentType = "application/json; charset=utf-8"
+	}
+	ctx.Data(http.StatusOK, contentType, []byte(result.Ack))
 }
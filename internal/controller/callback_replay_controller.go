@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"recharge-go/internal/model"
+	"recharge-go/internal/service"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CallbackReplayController 回调日志查看与重放控制器
+type CallbackReplayController struct {
+	replayService *service.CallbackReplayService
+}
+
+// NewCallbackReplayController 创建回调日志查看与重放控制器
+func NewCallbackReplayController(replayService *service.CallbackReplayService) *CallbackReplayController {
+	return &CallbackReplayController{replayService: replayService}
+}
+
+// List 分页获取回调日志
+// status 为逗号分隔的处理状态，未指定时查询处理失败、未匹配与被拒绝的回调，all 查询全部；
+// unresolved=true 时只查询未被重放解决的原始回调
+func (c *CallbackReplayController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	unresolved, _ := strconv.ParseBool(ctx.DefaultQuery("unresolved", "false"))
+	query := &model.CallbackLogQuery{
+		PlatformCode: ctx.Query("platform_code"),
+		OrderNumber:  ctx.Query("order_number"),
+		Unresolved:   unresolved,
+		Page:         page,
+		PageSize:     pageSize,
+	}
+
+	switch status := ctx.Query("status"); status {
+	case "":
+		query.Statuses = []int{model.CallbackLogStatusFailed, model.CallbackLogStatusUnmatched, model.CallbackLogStatusRejected}
+	case "all":
+	default:
+		for _, s := range strings.Split(status, ",") {
+			v, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				utils.Error(ctx, http.StatusBadRequest, "无效的回调状态")
+				return
+			}
+			query.Statuses = append(query.Statuses, v)
+		}
+	}
+
+	logs, total, err := c.replayService.List(ctx, query)
+	if err != nil {
+		logger.Log.Error("获取回调日志列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取回调日志列表失败")
+		return
+	}
+	utils.Success(ctx, gin.H{"list": logs, "total": total})
+}
+
+// Get 获取回调日志及原始数据
+func (c *CallbackReplayController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的回调日志ID")
+		return
+	}
+
+	log, err := c.replayService.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrCallbackLogNotFound) {
+			utils.Error(ctx, http.StatusNotFound, "回调日志不存在")
+			return
+		}
+		logger.Log.Error("获取回调日志失败", zap.Int64("id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取回调日志失败")
+		return
+	}
+	utils.Success(ctx, log)
+}
+
+// Replay 重放一条回调
+func (c *CallbackReplayController) Replay(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的回调日志ID")
+		return
+	}
+
+	result, err := c.replayService.Replay(ctx, id, replayOperator(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCallbackLogNotFound):
+			utils.Error(ctx, http.StatusNotFound, "回调日志不存在")
+		case errors.Is(err, service.ErrCallbackNotReplayable):
+			utils.Error(ctx, http.StatusBadRequest, "回调已处理或已被重放解决，不能重放")
+		default:
+			logger.Log.Error("重放回调失败", zap.Int64("id", id), zap.Error(err))
+			utils.Error(ctx, http.StatusInternalServerError, "重放回调失败")
+		}
+		return
+	}
+	utils.Success(ctx, result)
+}
+
+// ReplayBatch 批量重放回调
+func (c *CallbackReplayController) ReplayBatch(ctx *gin.Context) {
+	var req struct {
+		IDs []int64 `json:"ids" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	results, err := c.replayService.ReplayBatch(ctx, req.IDs, replayOperator(ctx))
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(ctx, gin.H{"list": results})
+}
+
+// replayOperator 重放操作人，与订单状态历史中的操作人格式一致
+func replayOperator(ctx *gin.Context) string {
+	return fmt.Sprintf("user:%d", ctx.GetInt64("user_id"))
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"recharge-go/internal/model"
+	"recharge-go/internal/model/notification"
 	"recharge-go/internal/repository"
 	"recharge-go/pkg/utils/response"
 
@@ -275,6 +277,79 @@ func (c *ExternalAPIKeyController) UpdateAPIKeyStatus(ctx *gin.Context) {
 	response.Success(ctx, apiKey)
 }
 
+// UpdateNotifyRetrySchedule 更新回调通知重试表
+// @Summary 更新回调通知重试表
+// @Description 设置订单回调通知失败后的重试间隔，逗号分隔，如 0s,15s,1m,5m,30m,2h，为空时使用默认重试表
+// @Tags API密钥管理
+// @Accept json
+// @Produce json
+// @Param id path int true "API密钥ID"
+// @Param request body UpdateNotifyRetryScheduleRequest true "重试表更新请求"
+// @Success 200 {object} response.Response{data=model.ExternalAPIKey}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/external-api-keys/{id}/notify-retry-schedule [put]
+func (c *ExternalAPIKeyController) UpdateNotifyRetrySchedule(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的ID")
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Error(ctx, http.StatusUnauthorized, "用户未登录")
+		return
+	}
+	userIDInt, ok := userID.(int64)
+	if !ok {
+		response.Error(ctx, http.StatusInternalServerError, "用户ID格式错误")
+		return
+	}
+
+	var req UpdateNotifyRetryScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误")
+		return
+	}
+	schedule := strings.TrimSpace(req.NotifyRetrySchedule)
+	if schedule != "" {
+		parsed, err := notification.ParseRetrySchedule(schedule)
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, fmt.Sprintf("重试表格式错误: %v", err))
+			return
+		}
+		schedule = parsed.String()
+	}
+
+	userKeys, _, err := c.apiKeyRepo.GetByUserID(userIDInt, 0, 10)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "查询API密钥失败")
+		return
+	}
+	var apiKey *model.ExternalAPIKey
+	for _, key := range userKeys {
+		if key.ID == id {
+			apiKey = key
+			break
+		}
+	}
+	if apiKey == nil {
+		response.Error(ctx, http.StatusNotFound, "API密钥不存在")
+		return
+	}
+
+	apiKey.NotifyRetrySchedule = schedule
+	apiKey.UpdatedAt = time.Now()
+	if err := c.apiKeyRepo.Update(apiKey); err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "更新回调通知重试表失败")
+		return
+	}
+
+	response.Success(ctx, apiKey)
+}
+
 // 请求结构体
 type CreateAPIKeyRequest struct {
 	AppName     string `json:"app_name" binding:"max=128"`
@@ -285,6 +360,10 @@ type UpdateStatusRequest struct {
 	Status int `json:"status"`
 }
 
+type UpdateNotifyRetryScheduleRequest struct {
+	NotifyRetrySchedule string `json:"notify_retry_schedule" binding:"max=255"`
+}
+
 // 生成纯数字的AppID (10位)
 func generateNumericAppID() string {
 	return fmt.Sprintf("%010d", time.Now().Unix()%10000000000)
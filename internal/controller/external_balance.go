@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExternalBalanceController 接入方查询账户余额
+type ExternalBalanceController struct {
+	balanceService *service.BalanceService
+	userRepo       *repository.UserRepository
+}
+
+// NewExternalBalanceController 创建接入方余额查询控制器
+func NewExternalBalanceController(balanceService *service.BalanceService, userRepo *repository.UserRepository) *ExternalBalanceController {
+	return &ExternalBalanceController{
+		balanceService: balanceService,
+		userRepo:       userRepo,
+	}
+}
+
+// GetBalance 查询当前 API 密钥所属用户的可用余额、授信额度与处理中订单冻结的金额
+func (c *ExternalBalanceController) GetBalance(ctx *gin.Context) {
+	apiKey, ok := ctx.MustGet("api_key_info").(*model.ExternalAPIKey)
+	if !ok {
+		c.respond(ctx, http.StatusUnauthorized, "API Key information not found", nil)
+		return
+	}
+
+	user, err := c.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		logger.Error("查询用户余额失败", "error", err, "user_id", apiKey.UserID)
+		c.respond(ctx, http.StatusNotFound, "User not found", nil)
+		return
+	}
+	heldOrders, heldAmount, err := c.balanceService.HeldSummary(ctx, apiKey.UserID)
+	if err != nil {
+		logger.Error("汇总冻结金额失败", "error", err, "user_id", apiKey.UserID)
+		c.respond(ctx, http.StatusInternalServerError, "Database error", nil)
+		return
+	}
+
+	c.respond(ctx, http.StatusOK, "Success", gin.H{
+		"balance":        user.Balance,
+		"credit":         user.Credit,
+		"frozen_balance": user.FrozenBalance,
+		"held_orders":    heldOrders,
+		"held_amount":    heldAmount,
+	})
+}
+
+// respond 按外部订单接口的格式响应
+func (c *ExternalBalanceController) respond(ctx *gin.Context, statusCode int, message string, data interface{}) {
+	ctx.JSON(statusCode, gin.H{
+		"code":      statusCode,
+		"message":   message,
+		"data":      data,
+		"timestamp": time.Now().Unix(),
+	})
+}
@@ -155,8 +155,16 @@ func (c *ExternalCallbackController) HandleCallback(ctx *gin.Context) {
 		return
 	}
 
-	// 更新订单状态
-	if err := c.orderService.UpdateOrderStatus(ctx, order.ID, model.OrderStatus(req.Status)); err != nil {
+	// 更新订单状态，成功与失败经对应的处理流程扣下冻结款或退款
+	switch model.OrderStatus(req.Status) {
+	case model.OrderStatusSuccess:
+		err = c.orderService.ProcessOrderSuccess(ctx, order.ID)
+	case model.OrderStatusFailed:
+		err = c.orderService.ProcessOrderFail(ctx, order.ID, "外部回调订单失败")
+	default:
+		err = c.orderService.UpdateOrderStatus(ctx, order.ID, model.OrderStatus(req.Status))
+	}
+	if err != nil {
 		logData.ErrorMsg = fmt.Sprintf("Update order status failed: %v", err)
 		c.respondCallbackError(ctx, http.StatusInternalServerError, "Update order status failed", &logData, startTime)
 		return
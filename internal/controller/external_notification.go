@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/model"
+	notificationRepo "recharge-go/internal/repository/notification"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxExternalDeliveryAttempts 接入方查询投递记录的最大条数
+const maxExternalDeliveryAttempts = 50
+
+// ExternalNotificationController 接入方查询回调通知投递记录
+type ExternalNotificationController struct {
+	orderService service.OrderService
+	attemptRepo  *notificationRepo.AttemptRepository
+}
+
+// NewExternalNotificationController 创建接入方回调通知查询控制器
+func NewExternalNotificationController(orderService service.OrderService, attemptRepo *notificationRepo.AttemptRepository) *ExternalNotificationController {
+	return &ExternalNotificationController{
+		orderService: orderService,
+		attemptRepo:  attemptRepo,
+	}
+}
+
+// ExternalDeliveryAttempt 回调通知投递记录
+type ExternalDeliveryAttempt struct {
+	Attempt      int    `json:"attempt"`
+	URL          string `json:"url"`
+	RequestBody  string `json:"request_body"`
+	HTTPStatus   int    `json:"http_status"`
+	ResponseBody string `json:"response_body"`
+	LatencyMs    int64  `json:"latency_ms"`
+	Error        string `json:"error,omitempty"`
+	Success      bool   `json:"success"`
+	CreateTime   int64  `json:"create_time"`
+}
+
+// ListDeliveryAttempts 查询订单的回调通知投递记录
+// 按 out_trade_num 或 order_number 查询，只能查询当前 API 密钥所属用户的订单
+func (c *ExternalNotificationController) ListDeliveryAttempts(ctx *gin.Context) {
+	apiKey, ok := ctx.MustGet("api_key_info").(*model.ExternalAPIKey)
+	if !ok {
+		c.respond(ctx, http.StatusUnauthorized, "API Key information not found", nil)
+		return
+	}
+
+	outTradeNum := ctx.Query("out_trade_num")
+	orderNumber := ctx.Query("order_number")
+	if outTradeNum == "" && orderNumber == "" {
+		c.respond(ctx, http.StatusBadRequest, "out_trade_num or order_number is required", nil)
+		return
+	}
+
+	var order *model.Order
+	var err error
+	if outTradeNum != "" {
+		order, err = c.orderService.GetOrderByOutTradeNum(ctx, outTradeNum)
+	} else {
+		order, err = c.orderService.GetOrderByOrderNumber(ctx, orderNumber)
+	}
+	// 其他用户的订单按不存在处理
+	if err != nil || order.CustomerID != apiKey.UserID {
+		c.respond(ctx, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	attempts, err := c.attemptRepo.ListByOrderID(ctx, order.ID, maxExternalDeliveryAttempts)
+	if err != nil {
+		logger.Error("查询回调通知投递记录失败", "error", err, "order_id", order.ID)
+		c.respond(ctx, http.StatusInternalServerError, "Database error", nil)
+		return
+	}
+
+	list := make([]*ExternalDeliveryAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		list = append(list, &ExternalDeliveryAttempt{
+			Attempt:      a.Attempt,
+			URL:          a.URL,
+			RequestBody:  a.RequestBody,
+			HTTPStatus:   a.HTTPStatus,
+			ResponseBody: a.ResponseBody,
+			LatencyMs:    a.LatencyMs,
+			Error:        a.Error,
+			Success:      a.Success,
+			CreateTime:   a.CreatedAt.Unix(),
+		})
+	}
+	c.respond(ctx, http.StatusOK, "Success", gin.H{
+		"order_number":  order.OrderNumber,
+		"out_trade_num": order.OutTradeNum,
+		"attempts":      list,
+	})
+}
+
+// respond 按外部订单接口的格式响应
+func (c *ExternalNotificationController) respond(ctx *gin.Context, statusCode int, message string, data interface{}) {
+	code := statusCode
+	if statusCode == http.StatusOK {
+		code = 200
+	}
+	ctx.JSON(statusCode, gin.H{
+		"code":      code,
+		"message":   message,
+		"data":      data,
+		"timestamp": time.Now().Unix(),
+	})
+}
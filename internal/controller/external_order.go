@@ -9,6 +9,7 @@ import (
 	"recharge-go/internal/service"
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"strconv"
 	"strings"
 	"time"
@@ -62,12 +63,14 @@ type ExternalOrderCreateResponse struct {
 
 // ExternalOrderData 外部订单数据
 type ExternalOrderData struct {
-	OrderNumber string  `json:"order_number"`
-	OutTradeNum string  `json:"out_trade_num"`
-	Status      int     `json:"status"`
-	StatusDesc  string  `json:"status_desc"`
-	Amount      float64 `json:"amount"`
-	CreateTime  int64   `json:"create_time"`
+	OrderNumber string      `json:"order_number"`
+	OutTradeNum string      `json:"out_trade_num"`
+	Status      int         `json:"status"`
+	StatusDesc  string      `json:"status_desc"`
+	Amount      money.Money `json:"amount"`
+	// CreditedAmount 实际到账面值，部分充值时小于面值
+	CreditedAmount money.Money `json:"credited_amount,omitempty"`
+	CreateTime     int64       `json:"create_time"`
 }
 
 // ExternalOrderQueryRequest 外部订单查询请求
@@ -194,11 +197,11 @@ func (c *ExternalOrderController) CreateOrder(ctx *gin.Context) {
 	// }
 
 	// 从商品名称中提取面值
-	denom, err := utils.ExtractNumberFromProductName(product.Product.Name)
-	if err != nil {
+	denom := productPrice // 如果提取失败，使用商品价格作为面值
+	if value, err := utils.ExtractNumberFromProductName(product.Product.Name); err != nil {
 		logger.Warn("Failed to extract denom from product name", "product_name", product.Product.Name, "error", err)
-		// 如果提取失败，使用商品价格作为面值
-		denom = productPrice
+	} else {
+		denom = money.FromFloat(value).Round()
 	}
 
 	// 创建新订单
@@ -356,12 +359,13 @@ func (c *ExternalOrderController) GetOrder(ctx *gin.Context) {
 		Timestamp: time.Now().Unix(),
 		Data: &ExternalOrderData{
 
-			OrderNumber: order.OrderNumber,
-			OutTradeNum: order.OutTradeNum,
-			Status:      int(order.Status),
-			StatusDesc:  c.getStatusDesc(int(order.Status)),
-			Amount:      order.TotalPrice,
-			CreateTime:  order.CreateTime.Unix(),
+			OrderNumber:    order.OrderNumber,
+			OutTradeNum:    order.OutTradeNum,
+			Status:         int(order.Status),
+			StatusDesc:     c.getStatusDesc(int(order.Status)),
+			Amount:         order.TotalPrice,
+			CreditedAmount: order.ActualCredited(),
+			CreateTime:     order.CreateTime.Unix(),
 		},
 	}
 
@@ -411,6 +415,8 @@ func (c *ExternalOrderController) getStatusDesc(status int) string {
 		return "已取消"
 	case model.OrderStatusRefunded:
 		return "已退款"
+	case model.OrderStatusPartial:
+		return "部分充值"
 	default:
 		return "未知状态"
 	}
@@ -5,6 +5,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/service"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -36,10 +37,10 @@ type ExternalRefundResponse struct {
 }
 
 type ExternalRefundData struct {
-	OrderNumber string  `json:"order_number"`  // 系统订单号
-	OutTradeNum string  `json:"out_trade_num"` // 外部交易号
-	Amount      float64 `json:"amount"`        // 退款金额
-	Status      string  `json:"status"`        // 退款状态
+	OrderNumber string      `json:"order_number"`  // 系统订单号
+	OutTradeNum string      `json:"out_trade_num"` // 外部交易号
+	Amount      money.Money `json:"amount"`        // 退款金额
+	Status      string      `json:"status"`        // 退款状态
 }
 
 // ProcessRefund 处理外部订单退款
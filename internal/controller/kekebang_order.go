@@ -10,6 +10,7 @@ import (
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/database"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"recharge-go/pkg/signature"
 	"recharge-go/pkg/utils/response"
 	"strconv"
@@ -141,7 +142,7 @@ func (c *KekebangOrderController) CreateOrder(ctx *gin.Context) {
 	// 创建订单
 	order = &model.Order{
 		Mobile:            req.Target,
-		Denom:             req.Datas.Amount,
+		Denom:             money.FromFloat(req.Datas.Amount).Round(),
 		Price:             product.Price,
 		ProductID:         productID, // 需要根据 OuterGoodsCode 查询对应的商品ID
 		Status:            model.OrderStatusPendingRecharge,
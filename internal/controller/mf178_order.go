@@ -12,6 +12,7 @@ import (
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/database"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"strconv"
 	"time"
 
@@ -273,10 +274,10 @@ func (c *MF178OrderController) CreateOrder(ctx *gin.Context) {
 		Mobile:            req.Target,
 		ProductID:         productID,
 		OutTradeNum:       strconv.FormatInt(req.UserOrderID, 10),
-		Denom:             req.Datas.Amount,
-		OfficialPayment:   float64(req.OfficialPayment),
-		UserQuotePayment:  float64(req.UserQuotePayment),
-		UserPayment:       float64(req.UserPayment),
+		Denom:             money.FromFloat(req.Datas.Amount).Round(),
+		OfficialPayment:   money.Yuan(int64(req.OfficialPayment)),
+		UserQuotePayment:  money.Yuan(int64(req.UserQuotePayment)),
+		UserPayment:       money.Yuan(int64(req.UserPayment)),
 		Price:             product.Price,
 		Status:            model.OrderStatusPendingRecharge,
 		IsDel:             0,
@@ -6,6 +6,7 @@ import (
 	"recharge-go/internal/service"
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -54,6 +55,23 @@ func (c *OrderController) GetOrderByID(ctx *gin.Context) {
 	utils.Success(ctx, order)
 }
 
+// GetOrderStatusHistory 获取订单状态流转记录
+func (c *OrderController) GetOrderStatusHistory(ctx *gin.Context) {
+	orderID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	histories, err := c.orderService.GetOrderStatusHistory(ctx, orderID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.Success(ctx, histories)
+}
+
 // GetOrderByOrderNumber 根据订单号获取订单
 func (c *OrderController) GetOrderByOrderNumber(ctx *gin.Context) {
 	orderNumber := ctx.Param("order_number")
@@ -310,14 +328,15 @@ func (c *OrderController) ProcessOrderPartial(ctx *gin.Context) {
 	}
 
 	var req struct {
-		Remark string `json:"remark" binding:"required"`
+		CreditedAmount money.Money `json:"credited_amount" binding:"required,gt=0"`
+		Remark         string      `json:"remark" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.Error(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := c.orderService.ProcessOrderPartial(ctx, orderID, req.Remark); err != nil {
+	if err := c.orderService.ProcessOrderPartial(ctx, orderID, req.CreditedAmount, req.Remark); err != nil {
 		utils.Error(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
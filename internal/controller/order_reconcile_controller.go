@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OrderReconcileController 滞留订单对账控制器
+type OrderReconcileController struct {
+	reconciler *service.OrderReconciler
+	reportRepo *repository.OrderReconcileReportRepository
+}
+
+// NewOrderReconcileController 创建滞留订单对账控制器
+func NewOrderReconcileController(reconciler *service.OrderReconciler, reportRepo *repository.OrderReconcileReportRepository) *OrderReconcileController {
+	return &OrderReconcileController{
+		reconciler: reconciler,
+		reportRepo: reportRepo,
+	}
+}
+
+// Run 手动执行一轮对账，dry_run=true 时只生成报告不处置
+func (c *OrderReconcileController) Run(ctx *gin.Context) {
+	dryRun, _ := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	report, err := c.reconciler.Reconcile(ctx, service.OrderReconcileSourceManual, dryRun)
+	if err != nil {
+		logger.Log.Error("执行滞留订单对账失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "执行滞留订单对账失败")
+		return
+	}
+	items, _ := report.ParseItems()
+	utils.Success(ctx, gin.H{"report": report, "items": items})
+}
+
+// Policies 获取各状态的处置策略
+func (c *OrderReconcileController) Policies(ctx *gin.Context) {
+	utils.Success(ctx, c.reconciler.Policies())
+}
+
+// ListReports 分页获取对账报告
+func (c *OrderReconcileController) ListReports(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reports, total, err := c.reportRepo.List(ctx, page, pageSize)
+	if err != nil {
+		logger.Log.Error("获取对账报告列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取对账报告列表失败")
+		return
+	}
+	utils.Success(ctx, gin.H{"list": reports, "total": total})
+}
+
+// GetReport 获取对账报告及处置明细
+func (c *OrderReconcileController) GetReport(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的报告ID")
+		return
+	}
+
+	report, err := c.reportRepo.GetByID(ctx, id)
+	if err != nil {
+		utils.Error(ctx, http.StatusNotFound, "对账报告不存在")
+		return
+	}
+	items, err := report.ParseItems()
+	if err != nil {
+		logger.Log.Error("解析对账明细失败", zap.Int64("report_id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "解析对账明细失败")
+		return
+	}
+	utils.Success(ctx, gin.H{"report": report, "items": items})
+}
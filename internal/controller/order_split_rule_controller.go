@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OrderSplitRuleController 拆单规则控制器
+type OrderSplitRuleController struct {
+	ruleRepo     *repository.OrderSplitRuleRepository
+	splitService *service.OrderSplitService
+}
+
+// NewOrderSplitRuleController 创建拆单规则控制器
+func NewOrderSplitRuleController(ruleRepo *repository.OrderSplitRuleRepository, splitService *service.OrderSplitService) *OrderSplitRuleController {
+	return &OrderSplitRuleController{
+		ruleRepo:     ruleRepo,
+		splitService: splitService,
+	}
+}
+
+// OrderSplitRuleRequest 拆单规则请求
+type OrderSplitRuleRequest struct {
+	Name      string                 `json:"name"`
+	ProductID int64                  `json:"product_id" binding:"required"`
+	Parts     []model.OrderSplitPart `json:"parts" binding:"required"`
+	Sort      int                    `json:"sort"`
+	Status    *int                   `json:"status"`
+}
+
+// toRule 校验请求并填充到拆单规则
+func (req *OrderSplitRuleRequest) toRule(rule *model.OrderSplitRule) error {
+	parts, err := json.Marshal(req.Parts)
+	if err != nil {
+		return err
+	}
+	rule.Name = req.Name
+	rule.ProductID = req.ProductID
+	rule.Parts = string(parts)
+	rule.Sort = req.Sort
+	if req.Status != nil {
+		rule.Status = *req.Status
+	}
+	_, err = rule.ParseParts()
+	return err
+}
+
+// List 获取拆单规则列表
+func (c *OrderSplitRuleController) List(ctx *gin.Context) {
+	productID, _ := strconv.ParseInt(ctx.Query("product_id"), 10, 64)
+	rules, err := c.ruleRepo.List(ctx, productID)
+	if err != nil {
+		logger.Log.Error("获取拆单规则列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取拆单规则列表失败")
+		return
+	}
+	utils.Success(ctx, rules)
+}
+
+// Create 创建拆单规则
+func (c *OrderSplitRuleController) Create(ctx *gin.Context) {
+	var req OrderSplitRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	rule := &model.OrderSplitRule{Status: 1}
+	if err := req.toRule(rule); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "拆单规则无效: "+err.Error())
+		return
+	}
+	if err := c.ruleRepo.Create(ctx, rule); err != nil {
+		logger.Log.Error("创建拆单规则失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "创建拆单规则失败")
+		return
+	}
+	utils.Success(ctx, rule)
+}
+
+// Update 更新拆单规则
+func (c *OrderSplitRuleController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的规则ID")
+		return
+	}
+	var req OrderSplitRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	rule, err := c.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		utils.Error(ctx, http.StatusNotFound, "拆单规则不存在")
+		return
+	}
+	if err := req.toRule(rule); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "拆单规则无效: "+err.Error())
+		return
+	}
+	if err := c.ruleRepo.Update(ctx, rule); err != nil {
+		logger.Log.Error("更新拆单规则失败", zap.Int64("id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "更新拆单规则失败")
+		return
+	}
+	utils.Success(ctx, rule)
+}
+
+// Delete 删除拆单规则
+func (c *OrderSplitRuleController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的规则ID")
+		return
+	}
+	if err := c.ruleRepo.Delete(ctx, id); err != nil {
+		logger.Log.Error("删除拆单规则失败", zap.Int64("id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "删除拆单规则失败")
+		return
+	}
+	utils.Success(ctx, nil)
+}
+
+// Children 查看拆单父订单的子订单及汇总情况
+func (c *OrderSplitRuleController) Children(ctx *gin.Context) {
+	orderNumber := ctx.Param("order_number")
+	children, err := c.splitService.Children(ctx, orderNumber)
+	if err != nil {
+		logger.Log.Error("获取子订单失败", zap.String("order_number", orderNumber), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取子订单失败")
+		return
+	}
+	settlement, err := c.splitService.Summary(ctx, orderNumber)
+	if err != nil {
+		logger.Log.Error("汇总子订单失败", zap.String("order_number", orderNumber), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "汇总子订单失败")
+		return
+	}
+	utils.Success(ctx, gin.H{
+		"children":   children,
+		"settlement": settlement,
+	})
+}
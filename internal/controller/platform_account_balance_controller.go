@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -20,13 +21,13 @@ func NewPlatformAccountBalanceController(balanceService *service.PlatformAccount
 	}
 }
 
-// AdjustBalance 手动调整余额
+// AdjustBalance 手动调整余额，请求头 Idempotency-Key 可选，携带时重复提交只入账一次
 func (c *PlatformAccountBalanceController) AdjustBalance(ctx *gin.Context) {
 	var req struct {
-		AccountID int64   `json:"account_id" binding:"required"`
-		Amount    float64 `json:"amount" binding:"required"`
-		Style     int     `json:"style" binding:"required"`
-		Remark    string  `json:"remark"`
+		AccountID int64       `json:"account_id" binding:"required"`
+		Amount    money.Money `json:"amount" binding:"required"`
+		Style     int         `json:"style" binding:"required"`
+		Remark    string      `json:"remark"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -40,7 +41,7 @@ func (c *PlatformAccountBalanceController) AdjustBalance(ctx *gin.Context) {
 		operator = "system"
 	}
 
-	if err := c.balanceService.AdjustBalance(ctx, req.AccountID, req.Amount, req.Style, req.Remark, operator); err != nil {
+	if err := c.balanceService.AdjustBalance(service.WithIdempotencyKey(ctx, ctx.GetHeader("Idempotency-Key")), req.AccountID, req.Amount, req.Style, req.Remark, operator); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+// PlatformAdapterController 平台适配器控制器
+type PlatformAdapterController struct {
+	platformAPIRepo repository.PlatformAPIRepository
+}
+
+// NewPlatformAdapterController 创建平台适配器控制器
+func NewPlatformAdapterController(platformAPIRepo repository.PlatformAPIRepository) *PlatformAdapterController {
+	return &PlatformAdapterController{
+		platformAPIRepo: platformAPIRepo,
+	}
+}
+
+// UnsupportedAPI 未找到适配器的平台接口
+type UnsupportedAPI struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// GenericAPI 使用通用HTTP模板的平台接口
+type GenericAPI struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// ListAdapters 获取已注册的平台适配器、使用通用HTTP模板的接口及未被支持的接口代码
+func (c *PlatformAdapterController) ListAdapters(ctx *gin.Context) {
+	apis, _, err := c.platformAPIRepo.List(ctx, 1, 1000)
+	if err != nil {
+		logger.Log.Error("获取平台接口列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取平台接口列表失败")
+		return
+	}
+
+	generic := make([]GenericAPI, 0)
+	unsupported := make([]UnsupportedAPI, 0)
+	for _, api := range apis {
+		if recharge.IsAdapterRegistered(api.Code) {
+			continue
+		}
+		if recharge.HasGenericHTTPTemplate(api.ExtraParams) {
+			generic = append(generic, GenericAPI{
+				ID:   api.ID,
+				Name: api.Name,
+				Code: api.Code,
+			})
+		} else {
+			unsupported = append(unsupported, UnsupportedAPI{
+				ID:   api.ID,
+				Name: api.Name,
+				Code: api.Code,
+			})
+		}
+	}
+
+	utils.Success(ctx, gin.H{
+		"adapters":    recharge.ListAdapters(),
+		"generic":     generic,
+		"unsupported": unsupported,
+	})
+}
+
+// ValidateGenericTemplate 校验通用HTTP模板配置，请求体为 PlatformAPI.ExtraParams
+func (c *PlatformAdapterController) ValidateGenericTemplate(ctx *gin.Context) {
+	var extraParams datatypes.JSON
+	if err := ctx.ShouldBindJSON(&extraParams); err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	tpl, err := recharge.ParseGenericHTTPTemplate(extraParams)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if tpl == nil {
+		utils.Error(ctx, http.StatusBadRequest, "未配置 generic_http 模板")
+		return
+	}
+	utils.Success(ctx, tpl)
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"recharge-go/internal/service"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// RechargeQueueController 充值任务队列控制器
+type RechargeQueueController struct {
+	queue *service.RechargeQueue
+}
+
+// NewRechargeQueueController 创建充值任务队列控制器
+func NewRechargeQueueController(queue *service.RechargeQueue) *RechargeQueueController {
+	return &RechargeQueueController{queue: queue}
+}
+
+// available 队列未初始化时返回错误响应
+func (c *RechargeQueueController) available(ctx *gin.Context) bool {
+	if c.queue == nil {
+		utils.Error(ctx, http.StatusServiceUnavailable, "充值任务队列未初始化")
+		return false
+	}
+	return true
+}
+
+// Stats 获取充值队列深度
+func (c *RechargeQueueController) Stats(ctx *gin.Context) {
+	if !c.available(ctx) {
+		return
+	}
+	stats, err := c.queue.Stats()
+	if err != nil {
+		logger.Log.Error("获取充值队列状态失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取充值队列状态失败")
+		return
+	}
+	utils.Success(ctx, stats)
+}
+
+// ListDead 获取死信队列中的充值任务
+func (c *RechargeQueueController) ListDead(ctx *gin.Context) {
+	if !c.available(ctx) {
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	tasks, err := c.queue.ListDead(page, pageSize)
+	if err != nil {
+		logger.Log.Error("获取死信任务失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取死信任务失败")
+		return
+	}
+	utils.Success(ctx, tasks)
+}
+
+// RequeueDead 将死信任务重新放回队列
+func (c *RechargeQueueController) RequeueDead(ctx *gin.Context) {
+	c.handleDead(ctx, c.queue.RequeueDead, "重新入队")
+}
+
+// DeleteDead 删除死信任务
+func (c *RechargeQueueController) DeleteDead(ctx *gin.Context) {
+	c.handleDead(ctx, c.queue.DeleteDead, "删除")
+}
+
+// handleDead 按订单ID操作死信任务
+func (c *RechargeQueueController) handleDead(ctx *gin.Context, action func(orderID int64) error, name string) {
+	if !c.available(ctx) {
+		return
+	}
+	orderID, err := strconv.ParseInt(ctx.Param("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		utils.Error(ctx, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+
+	if err := action(orderID); err != nil {
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			utils.Error(ctx, http.StatusNotFound, "死信任务不存在")
+			return
+		}
+		logger.Log.Error(name+"死信任务失败", zap.Int64("order_id", orderID), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, name+"死信任务失败")
+		return
+	}
+	utils.Success(ctx, nil)
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/internal/utils"
+	"recharge-go/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SupplierHealthController 上游平台接口健康状况控制器
+type SupplierHealthController struct {
+	platformAPIRepo repository.PlatformAPIRepository
+}
+
+// NewSupplierHealthController 创建上游平台接口健康状况控制器
+func NewSupplierHealthController(platformAPIRepo repository.PlatformAPIRepository) *SupplierHealthController {
+	return &SupplierHealthController{
+		platformAPIRepo: platformAPIRepo,
+	}
+}
+
+// SupplierHealthItem 平台接口健康状况
+type SupplierHealthItem struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	*recharge.APIHealth
+}
+
+// List 获取所有平台接口的熔断状态及健康分
+func (c *SupplierHealthController) List(ctx *gin.Context) {
+	apis, _, err := c.platformAPIRepo.List(ctx, 1, 1000)
+	if err != nil {
+		logger.Log.Error("获取平台接口列表失败", zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "获取平台接口列表失败")
+		return
+	}
+
+	breaker := recharge.DefaultCircuitBreaker()
+	items := make([]SupplierHealthItem, 0, len(apis))
+	for _, api := range apis {
+		health, err := breaker.Health(ctx, api.ID)
+		if err != nil {
+			logger.Log.Error("获取平台接口健康状况失败", zap.Int64("api_id", api.ID), zap.Error(err))
+			utils.Error(ctx, http.StatusInternalServerError, "获取平台接口健康状况失败")
+			return
+		}
+		items = append(items, SupplierHealthItem{
+			ID:        api.ID,
+			Name:      api.Name,
+			Code:      api.Code,
+			Status:    api.Status,
+			APIHealth: health,
+		})
+	}
+
+	utils.Success(ctx, items)
+}
+
+// Reset 手动恢复平台接口熔断
+func (c *SupplierHealthController) Reset(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(ctx, http.StatusBadRequest, "无效的接口ID")
+		return
+	}
+	if _, err := c.platformAPIRepo.GetByID(ctx, id); err != nil {
+		utils.Error(ctx, http.StatusNotFound, "平台接口不存在")
+		return
+	}
+
+	if err := recharge.DefaultCircuitBreaker().Reset(ctx, id); err != nil {
+		logger.Log.Error("恢复平台接口熔断失败", zap.Int64("api_id", id), zap.Error(err))
+		utils.Error(ctx, http.StatusInternalServerError, "恢复平台接口熔断失败")
+		return
+	}
+	utils.Success(ctx, nil)
+}
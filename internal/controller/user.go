@@ -622,12 +622,13 @@ func (c *UserController) GetProfile(ctx *gin.Context) {
 		roleNames = append(roleNames, r.Code)
 	}
 	resp := map[string]interface{}{
-		"userId":   fmt.Sprintf("%d", user.ID),
-		"userName": user.Username,
-		"roles":    roleNames,
-		"buttons":  []string{},
-		"balance":  user.Balance,
-		"credit":   user.Credit,
+		"userId":         fmt.Sprintf("%d", user.ID),
+		"userName":       user.Username,
+		"roles":          roleNames,
+		"buttons":        []string{},
+		"balance":        user.Balance,
+		"credit":         user.Credit,
+		"frozen_balance": user.FrozenBalance,
 	}
 
 	utils.Success(ctx, resp)
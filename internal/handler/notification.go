@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"recharge-go/internal/model/notification"
 	notificationService "recharge-go/internal/service/notification"
@@ -8,6 +9,7 @@ import (
 	"recharge-go/pkg/logger"
 
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -88,7 +90,7 @@ func (h *NotificationHandler) ListNotifications(c *gin.Context) {
 		OrderID          int64  `form:"order_id"`
 		PlatformCode     string `form:"platform_code"`
 		NotificationType string `form:"notification_type"`
-		Status           int    `form:"status"`
+		Status           string `form:"status"` // 逗号分隔，如 4,5 查询待重试与已放弃的通知
 		Page             int    `form:"page" binding:"required,min=1"`
 		PageSize         int    `form:"page_size" binding:"required,min=1,max=100"`
 	}
@@ -108,8 +110,17 @@ func (h *NotificationHandler) ListNotifications(c *gin.Context) {
 	if req.NotificationType != "" {
 		params["notification_type"] = req.NotificationType
 	}
-	if req.Status > 0 {
-		params["status"] = req.Status
+	if req.Status != "" {
+		var statuses []int
+		for _, s := range strings.Split(req.Status, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				utils.Error(c, http.StatusBadRequest, "invalid notification status")
+				return
+			}
+			statuses = append(statuses, status)
+		}
+		params["status"] = statuses
 	}
 
 	records, total, err := h.notificationService.ListNotifications(c.Request.Context(), params, req.Page, req.PageSize)
@@ -127,7 +138,7 @@ func (h *NotificationHandler) ListNotifications(c *gin.Context) {
 	})
 }
 
-// RetryFailedNotification 重试失败的通知
+// RetryFailedNotification 手动重试失败待重试或已放弃的通知
 func (h *NotificationHandler) RetryFailedNotification(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -137,6 +148,10 @@ func (h *NotificationHandler) RetryFailedNotification(c *gin.Context) {
 	}
 
 	if err := h.notificationService.RetryFailedNotification(c.Request.Context(), id); err != nil {
+		if errors.Is(err, notificationService.ErrNotificationNotRetryable) {
+			utils.Error(c, http.StatusBadRequest, "only failed or gave up notifications can be retried")
+			return
+		}
 		logger.Error("retry failed notification failed", "error", err)
 		utils.Error(c, http.StatusInternalServerError, "retry failed notification failed")
 		return
@@ -145,6 +160,24 @@ func (h *NotificationHandler) RetryFailedNotification(c *gin.Context) {
 	utils.Success(c, nil)
 }
 
+// ListDeliveryAttempts 获取订单的通知投递记录
+func (h *NotificationHandler) ListDeliveryAttempts(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Query("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		utils.Error(c, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	attempts, err := h.notificationService.ListDeliveryAttempts(c.Request.Context(), orderID)
+	if err != nil {
+		logger.Error("list delivery attempts failed", "error", err, "order_id", orderID)
+		utils.Error(c, http.StatusInternalServerError, "list delivery attempts failed")
+		return
+	}
+
+	utils.Success(c, gin.H{"list": attempts})
+}
+
 // RegisterRoutes 注册路由
 func (h *NotificationHandler) RegisterRoutes(r *gin.RouterGroup) {
 	notification := r.Group("/notification")
@@ -42,7 +42,11 @@ func (h *RechargeHandler) HandleCallback(c *gin.Context) {
 	}
 
 	// 处理回调
-	if err := h.rechargeService.HandleCallback(c.Request.Context(), platform, data); err != nil {
+	if _, err := h.rechargeService.HandleCallback(c.Request.Context(), &service.CallbackRequest{
+		PlatformCode: platform,
+		SourceIP:     c.ClientIP(),
+		Body:         data,
+	}); err != nil {
 		logger.Error("处理回调失败: %v", err)
 		c.JSON(200, gin.H{
 			"code": "1002",
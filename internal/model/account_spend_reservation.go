@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"recharge-go/pkg/money"
+)
+
+// AccountSpendReservation 平台账号额度占用记录
+// 提交订单前按通道成本占用接口所属账号的日/月额度，提交失败、订单失败或退款时释放；
+// 额度计数器以未释放的占用记录为准重建，占用与对账使用同一金额和时间
+type AccountSpendReservation struct {
+	ID         int64       `json:"id" gorm:"primaryKey"`
+	OrderID    int64       `json:"order_id" gorm:"not null;index;comment:订单ID"`
+	AccountID  int64       `json:"account_id" gorm:"not null;index:idx_account_spend_reservations_account;comment:平台账号ID"`
+	Amount     money.Money `json:"amount" gorm:"type:decimal(10,4);not null;comment:占用金额"`
+	ReservedAt time.Time   `json:"reserved_at" gorm:"not null;index:idx_account_spend_reservations_account;comment:占用时间，决定计入哪一天、哪一月"`
+	ReleasedAt *time.Time  `json:"released_at" gorm:"comment:释放时间，为空表示占用中"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// TableName 指定表名
+func (AccountSpendReservation) TableName() string {
+	return "account_spend_reservations"
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"recharge-go/pkg/money"
+)
+
+// 冻结款状态
+const (
+	BalanceHoldStatusHeld     = 1 // 冻结中，订单处理中
+	BalanceHoldStatusCaptured = 2 // 已扣款，部分退回后扣下剩余部分也记为已扣款
+	BalanceHoldStatusReleased = 3 // 已全部退回
+)
+
+// BalanceHold 订单冻结款
+// 下单时从用户余额、授信额度冻结订单金额，充值成功时扣款，失败时退回；
+// 每个订单至多一笔冻结款，Amount = Captured + Released + 冻结中的剩余金额
+type BalanceHold struct {
+	ID            int64       `json:"id" gorm:"primaryKey"`
+	UserID        int64       `json:"user_id" gorm:"not null;index;comment:用户ID"`
+	OrderID       int64       `json:"order_id" gorm:"not null;uniqueIndex;comment:订单ID"`
+	Amount        money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:冻结金额"`
+	BalanceAmount money.Money `json:"balance_amount" gorm:"type:decimal(10,2);not null;comment:其中冻结的余额"`
+	CreditAmount  money.Money `json:"credit_amount" gorm:"type:decimal(10,2);not null;comment:其中冻结的授信额度"`
+	Captured      money.Money `json:"captured" gorm:"type:decimal(10,2);not null;default:0.00;comment:已扣款金额"`
+	Released      money.Money `json:"released" gorm:"type:decimal(10,2);not null;default:0.00;comment:已退回金额"`
+	Status        int         `json:"status" gorm:"type:tinyint;not null;index;comment:状态：1冻结中 2已扣款 3已退回"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (BalanceHold) TableName() string {
+	return "balance_holds"
+}
+
+// Remaining 仍处于冻结中的金额
+func (h *BalanceHold) Remaining() money.Money {
+	return h.Amount - h.Captured - h.Released
+}
+
+// CreditRemaining 冻结中的金额里属于授信额度的部分，退回时优先恢复授信
+func (h *BalanceHold) CreditRemaining() money.Money {
+	if h.Released >= h.CreditAmount {
+		return 0
+	}
+	return h.CreditAmount - h.Released
+}
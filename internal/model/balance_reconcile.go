@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"recharge-go/pkg/money"
+)
+
+// 资金对账差异类型
+const (
+	BalanceDriftUserBalance     = "user_balance"     // 用户余额与余额流水不符
+	BalanceDriftUserCredit      = "user_credit"      // 用户授信额度与授信日志不符
+	BalanceDriftUserFrozen      = "user_frozen"      // 用户冻结金额与冻结中的订单冻结款不符
+	BalanceDriftPlatformAccount = "platform_account" // 平台账号消耗与余额流水不符
+	BalanceDriftOrder           = "order"            // 终态订单扣款、退款异常
+)
+
+// BalanceReconcileReport 资金对账报告，记录一次对账发现的余额差异、订单扣退款异常及修正结果
+type BalanceReconcileReport struct {
+	ID              int64     `json:"id" gorm:"primaryKey"`
+	Source          string    `json:"source" gorm:"size:32;comment:触发方式：schedule定时 manual手动"`
+	AutoCorrect     bool      `json:"auto_correct" gorm:"default:false;comment:是否自动修正差异"`
+	UsersScanned    int       `json:"users_scanned" gorm:"default:0;comment:核对用户数"`
+	AccountsScanned int       `json:"accounts_scanned" gorm:"default:0;comment:核对平台账号数"`
+	OrdersScanned   int       `json:"orders_scanned" gorm:"default:0;comment:核对终态订单数"`
+	BalanceDrifts   int       `json:"balance_drifts" gorm:"default:0;comment:余额差异数"`
+	CreditDrifts    int       `json:"credit_drifts" gorm:"default:0;comment:授信差异数"`
+	AccountDrifts   int       `json:"account_drifts" gorm:"default:0;comment:平台账号差异数"`
+	OrderIssues     int       `json:"order_issues" gorm:"default:0;comment:订单扣退款异常数"`
+	Corrected       int       `json:"corrected" gorm:"default:0;comment:已修正数"`
+	Errors          int       `json:"errors" gorm:"default:0;comment:核对或修正出错数"`
+	Items           string    `json:"-" gorm:"type:longtext;comment:差异明细，JSON数组"`
+	StartedAt       time.Time `json:"started_at" gorm:"comment:开始时间"`
+	FinishedAt      time.Time `json:"finished_at" gorm:"comment:结束时间"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// BalanceDriftItem 单项资金差异
+// 余额类差异中 Stored 为当前存储值，Expected 为按流水重算的值，Ledger 为账本科目余额；
+// 订单异常中 Stored 为扣款减退款的净额，Expected 为订单金额
+type BalanceDriftItem struct {
+	Kind              string      `json:"kind"` // 差异类型，见 BalanceDrift*
+	UserID            int64       `json:"user_id,omitempty"`
+	PlatformAccountID int64       `json:"platform_account_id,omitempty"`
+	OrderID           int64       `json:"order_id,omitempty"`
+	OrderNumber       string      `json:"order_number,omitempty"`
+	Status            OrderStatus `json:"status,omitempty"` // 订单状态
+	Stored            money.Money `json:"stored"`
+	Expected          money.Money `json:"expected"`
+	Ledger            money.Money `json:"ledger"`
+	Drift             money.Money `json:"drift"`             // Stored - Expected
+	Deducts           int         `json:"deducts,omitempty"` // 订单扣款凭证数
+	Refunds           int         `json:"refunds,omitempty"` // 订单退款凭证数
+	Issue             string      `json:"issue"`
+	Corrected         bool        `json:"corrected"`
+	Error             string      `json:"error,omitempty"`
+}
+
+// TableName 指定表名
+func (BalanceReconcileReport) TableName() string {
+	return "balance_reconcile_reports"
+}
+
+// ParseItems 解析差异明细
+func (r *BalanceReconcileReport) ParseItems() ([]BalanceDriftItem, error) {
+	var items []BalanceDriftItem
+	if r.Items == "" {
+		return items, nil
+	}
+	err := json.Unmarshal([]byte(r.Items), &items)
+	return items, err
+}
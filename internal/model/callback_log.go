@@ -2,18 +2,60 @@ package model
 
 import "time"
 
+// 回调处理状态
+const (
+	CallbackLogStatusReceived   = 0 // 已接收，待处理
+	CallbackLogStatusProcessed  = 1 // 已处理
+	CallbackLogStatusDuplicated = 2 // 重复回调，未再处理
+	CallbackLogStatusFailed     = 3 // 处理失败
+	CallbackLogStatusUnmatched  = 4 // 未找到对应订单
+	CallbackLogStatusRejected   = 5 // 来源IP、签名或时间戳校验未通过，或数据无法解析
+)
+
 // CallbackLog 回调日志
+// 收到回调时先落库原始数据，处理完成后更新状态；DedupKey 唯一，同一结果只处理一次。
+// 后台重放时按原始数据新建一条日志，ReplayOfID 指向被重放的日志
 type CallbackLog struct {
-	ID           int64     `gorm:"primaryKey"`
-	OrderID      string    `gorm:"index"`     // 订单号
-	PlatformID   string    `gorm:"index"`     // 平台ID
-	CallbackType string    `gorm:"index"`     // 回调类型
-	Status       int       `gorm:"index"`     // 处理状态
-	RequestData  string    `gorm:"type:text"` // 请求数据
-	ResponseData string    `gorm:"type:text"` // 响应数据
-	ErrorMessage string    `gorm:"type:text"` // 错误信息
-	CreateTime   time.Time // 创建时间
-	UpdateTime   time.Time // 更新时间
+	ID           int64     `json:"id" gorm:"primaryKey"`
+	OrderID      string    `json:"order_id" gorm:"index"`                 // 订单号
+	PlatformID   string    `json:"platform_id" gorm:"index"`              // 平台ID
+	PlatformCode string    `json:"platform_code" gorm:"size:50;index"`    // 平台代码
+	AccountName  string    `json:"account_name" gorm:"size:64"`           // 回调地址中的平台账号
+	SourceIP     string    `json:"source_ip" gorm:"size:64"`              // 回调来源IP
+	CallbackType string    `json:"callback_type" gorm:"index"`            // 回调类型
+	DedupKey     *string   `json:"dedup_key" gorm:"size:191;uniqueIndex"` // 去重键：平台:订单号:状态
+	Status       int       `json:"status" gorm:"index"`                   // 处理状态
+	RequestData  string    `json:"request_data" gorm:"type:text"`         // 请求数据
+	ResponseData string    `json:"response_data" gorm:"type:text"`        // 响应数据
+	ErrorMessage string    `json:"error_message" gorm:"type:text"`        // 错误信息
+	ReplayOfID   int64     `json:"replay_of_id" gorm:"index;default:0"`   // 被重放的回调日志ID，0 为平台原始回调
+	Operator     string    `json:"operator" gorm:"size:64"`               // 重放操作人
+	ReplayCount  int       `json:"replay_count" gorm:"default:0"`         // 被重放次数
+	ResolvedByID int64     `json:"resolved_by_id" gorm:"default:0"`       // 处理成功的重放日志ID，0 为未解决
+	CreateTime   time.Time `json:"create_time"`                           // 创建时间
+	UpdateTime   time.Time `json:"update_time"`                           // 更新时间
+}
+
+// IsReplayable 回调是否可以重放：原始回调未处理成功且未被重放解决
+func (l *CallbackLog) IsReplayable() bool {
+	if l.ReplayOfID != 0 || l.ResolvedByID != 0 {
+		return false
+	}
+	switch l.Status {
+	case CallbackLogStatusReceived, CallbackLogStatusFailed, CallbackLogStatusUnmatched, CallbackLogStatusRejected:
+		return true
+	}
+	return false
+}
+
+// CallbackLogQuery 回调日志查询条件
+type CallbackLogQuery struct {
+	Statuses     []int  // 处理状态，为空时不限
+	PlatformCode string // 平台代码
+	OrderNumber  string // 回调中的订单号
+	Unresolved   bool   // 只查询未被重放解决的原始回调
+	Page         int
+	PageSize     int
 }
 
 // TableName 指定表名
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"recharge-go/pkg/money"
+)
+
+// 记账类型
+const (
+	LedgerKindRecharge      = "recharge"       // 余额充值
+	LedgerKindOrderDeduct   = "order_deduct"   // 订单扣款
+	LedgerKindRefund        = "refund"         // 订单退款
+	LedgerKindAdjust        = "adjust"         // 手动调整
+	LedgerKindCreditSet     = "credit_set"     // 设置授信额度
+	LedgerKindCreditUse     = "credit_use"     // 使用授信额度
+	LedgerKindCreditRestore = "credit_restore" // 恢复授信额度
+	LedgerKindCommission    = "commission"     // 佣金发放
+	LedgerKindOpening       = "opening"        // 期初余额，启用账本时由迁移写入
+	LedgerKindReconcile     = "reconcile"      // 资金对账修正
+	LedgerKindHold          = "hold"           // 下单冻结
+	LedgerKindCapture       = "capture"        // 冻结款扣款
+	LedgerKindRelease       = "release"        // 冻结款退回
+)
+
+// 订单资金变动的幂等动作，与订单ID组成幂等键
+const (
+	IdempotencyDeduct        = "deduct"         // 订单扣款
+	IdempotencyHold          = "hold"           // 下单冻结
+	IdempotencyCapture       = "capture"        // 冻结款扣款
+	IdempotencyRelease       = "release"        // 订单关闭退回冻结款
+	IdempotencyRefund        = "refund"         // 订单退款，含部分退款
+	IdempotencyCommission    = "commission"     // 佣金发放，按收款用户区分
+	IdempotencyCreditUse     = "credit_use"     // 使用授信额度
+	IdempotencyCreditRestore = "credit_restore" // 恢复授信额度
+)
+
+// OrderIdempotencyKey 订单资金变动的幂等键，如 order:123:refund；订单ID无效时返回空字符串
+func OrderIdempotencyKey(orderID int64, action string) string {
+	if orderID <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("order:%d:%s", orderID, action)
+}
+
+// 系统科目，与用户、平台账号科目对记
+const (
+	LedgerAccountSales      = "system:sales"       // 订单收入
+	LedgerAccountFunding    = "system:funding"     // 外部入金，充值与手动调整的对方科目
+	LedgerAccountCreditLine = "system:credit_line" // 授信额度发放
+	LedgerAccountCommission = "system:commission"  // 佣金支出
+	LedgerAccountOpening    = "system:opening"     // 期初余额
+	LedgerAccountReconcile  = "system:reconcile"   // 对账差异，资金对账修正的对方科目
+)
+
+// UserBalanceAccount 用户余额科目，科目余额即 users.balance
+func UserBalanceAccount(userID int64) string {
+	return fmt.Sprintf("user:%d:balance", userID)
+}
+
+// UserCreditAccount 用户授信科目，科目余额即 users.credit
+func UserCreditAccount(userID int64) string {
+	return fmt.Sprintf("user:%d:credit", userID)
+}
+
+// UserFrozenAccount 用户冻结科目，科目余额即 users.frozen_balance
+func UserFrozenAccount(userID int64) string {
+	return fmt.Sprintf("user:%d:frozen", userID)
+}
+
+// PlatformAccountSpendAccount 平台账号消耗科目，记录经该平台账号扣款的金额
+func PlatformAccountSpendAccount(accountID int64) string {
+	return fmt.Sprintf("platform_account:%d:spend", accountID)
+}
+
+// LedgerTransaction 记账凭证，一次资金变动对应一张凭证
+// 幂等键唯一，同一资金变动重复记账时被唯一索引拒绝；没有幂等键的凭证该列为 NULL
+type LedgerTransaction struct {
+	ID             int64          `json:"id" gorm:"primaryKey"`
+	Kind           string         `json:"kind" gorm:"size:32;index;comment:记账类型"`
+	UserID         int64          `json:"user_id" gorm:"index;comment:用户ID"`
+	OrderID        int64          `json:"order_id" gorm:"index;comment:关联订单ID"`
+	IdempotencyKey *string        `json:"idempotency_key,omitempty" gorm:"size:128;uniqueIndex;comment:幂等键"`
+	Remark         string         `json:"remark" gorm:"size:255;comment:备注"`
+	Operator       string         `json:"operator" gorm:"size:100;comment:操作人"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"index"`
+	Entries        []*LedgerEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+// TableName 指定表名
+func (LedgerTransaction) TableName() string {
+	return "ledger_transactions"
+}
+
+// LedgerEntry 记账分录，同一凭证下所有分录金额之和为0
+// 金额为正表示科目余额增加，为负表示减少
+type LedgerEntry struct {
+	ID                int64       `json:"id" gorm:"primaryKey"`
+	TransactionID     int64       `json:"transaction_id" gorm:"not null;index;comment:凭证ID"`
+	Account           string      `json:"account" gorm:"size:64;not null;index;comment:科目"`
+	UserID            int64       `json:"user_id" gorm:"index;comment:用户ID"`
+	PlatformAccountID int64       `json:"platform_account_id" gorm:"index;comment:平台账号ID"`
+	OrderID           int64       `json:"order_id" gorm:"index;comment:关联订单ID"`
+	Amount            money.Money `json:"amount" gorm:"type:decimal(12,2);not null;comment:金额"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// TableName 指定表名
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
@@ -0,0 +1,27 @@
+package notification
+
+import (
+	"time"
+)
+
+// DeliveryAttempt 通知投递记录，每次向下游发送通知记录一条
+type DeliveryAttempt struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	NotificationID int64     `json:"notification_id" gorm:"index"`
+	OrderID        int64     `json:"order_id" gorm:"index"`
+	Attempt        int       `json:"attempt"` // 第几次投递，从1开始
+	URL            string    `json:"url" gorm:"type:varchar(512)"`
+	RequestHeaders string    `json:"request_headers" gorm:"type:text"` // JSON，敏感请求头已脱敏
+	RequestBody    string    `json:"request_body" gorm:"type:text"`
+	HTTPStatus     int       `json:"http_status" gorm:"column:http_status"` // 未收到响应时为0
+	ResponseBody   string    `json:"response_body" gorm:"type:text"`        // 超出长度的部分被截断
+	LatencyMs      int64     `json:"latency_ms"`
+	Error          string    `json:"error" gorm:"type:text"`
+	Success        bool      `json:"success"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (DeliveryAttempt) TableName() string {
+	return "notification_delivery_attempts"
+}
@@ -4,6 +4,15 @@ import (
 	"time"
 )
 
+// 通知状态
+const (
+	StatusPending    = 1 // 待处理
+	StatusProcessing = 2 // 处理中
+	StatusSuccess    = 3 // 成功
+	StatusFailed     = 4 // 失败，等待重试
+	StatusGaveUp     = 5 // 重试表已用尽或遇到终态错误，放弃通知
+)
+
 // NotificationRecord 通知记录
 type NotificationRecord struct {
 	ID               int64     `json:"id" gorm:"primaryKey"`
@@ -11,9 +20,10 @@ type NotificationRecord struct {
 	PlatformCode     string    `json:"platform_code" gorm:"type:varchar(50)"`
 	NotificationType string    `json:"notification_type" gorm:"type:varchar(50)"`
 	Content          string    `json:"content" gorm:"type:text"`
-	Status           int       `json:"status" gorm:"type:tinyint;default:1"` // 1:待处理 2:处理中 3:成功 4:失败
-	RetryCount       int       `json:"retry_count" gorm:"type:int;default:0"`
-	NextRetryTime    time.Time `json:"next_retry_time"`
+	Status           int       `json:"status" gorm:"type:tinyint;default:1;index:idx_notification_records_status_next,priority:1"` // 1:待处理 2:处理中 3:成功 4:失败待重试 5:已放弃
+	RetryCount       int       `json:"retry_count" gorm:"type:int;default:0"`                                                      // 已失败的投递次数
+	NextRetryTime    time.Time `json:"next_retry_time" gorm:"index:idx_notification_records_status_next,priority:2"`
+	LastError        string    `json:"last_error" gorm:"type:text"`     // 最近一次投递失败的原因
 	SuccessAt        time.Time `json:"success_at" gorm:"type:datetime"` // 通知成功时间
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
@@ -23,3 +33,8 @@ type NotificationRecord struct {
 func (NotificationRecord) TableName() string {
 	return "notification_records"
 }
+
+// IsRetryable 失败待重试或已放弃的通知可由后台手动重试
+func (r *NotificationRecord) IsRetryable() bool {
+	return r.Status == StatusFailed || r.Status == StatusGaveUp
+}
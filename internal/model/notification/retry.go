@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultRetrySchedule 默认通知重试表
+const DefaultRetrySchedule = "0s,15s,1m,5m,30m,2h"
+
+// RetrySchedule 通知重试表
+// 第 i 项为第 i 次投递失败后到下一次投递的等待时长，首项为首次投递的延迟；
+// 投递次数达到重试表长度后放弃通知
+type RetrySchedule []time.Duration
+
+// ParseRetrySchedule 解析逗号分隔的重试表，如 "0s,15s,1m,5m,30m,2h"
+func ParseRetrySchedule(s string) (RetrySchedule, error) {
+	var schedule RetrySchedule
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry interval %q: %v", item, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("invalid retry interval %q: must not be negative", item)
+		}
+		schedule = append(schedule, d)
+	}
+	if len(schedule) == 0 {
+		return nil, fmt.Errorf("retry schedule is empty")
+	}
+	return schedule, nil
+}
+
+// MustParseRetrySchedule 解析重试表，格式错误时 panic，用于内置默认值
+func MustParseRetrySchedule(s string) RetrySchedule {
+	schedule, err := ParseRetrySchedule(s)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// InitialDelay 首次投递的延迟
+func (s RetrySchedule) InitialDelay() time.Duration {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[0]
+}
+
+// Next 第 failures 次投递失败后到下一次投递的等待时长，重试表已用尽时返回 false
+func (s RetrySchedule) Next(failures int) (time.Duration, bool) {
+	if failures < 1 || failures >= len(s) {
+		return 0, false
+	}
+	return s[failures], true
+}
+
+// String 按配置格式输出重试表
+func (s RetrySchedule) String() string {
+	items := make([]string, len(s))
+	for i, d := range s {
+		items[i] = d.String()
+	}
+	return strings.Join(items, ",")
+}
@@ -10,7 +10,8 @@ type Template struct {
 	PlatformCode     string    `json:"platform_code" gorm:"type:varchar(50);index:uk_platform_type,priority:1"`
 	NotificationType string    `json:"notification_type" gorm:"type:varchar(50);index:uk_platform_type,priority:2"`
 	Template         string    `json:"template" gorm:"type:text"`
-	Status           int       `json:"status"` // 1-启用 2-禁用
+	Status           int       `json:"status"`                                  // 1-启用 2-禁用
+	RetrySchedule    string    `json:"retry_schedule" gorm:"type:varchar(255)"` // 通知重试表，如 0s,15s,1m,5m,30m,2h，为空时使用默认重试表
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -3,6 +3,8 @@ package model
 import (
 	"time"
 
+	"recharge-go/pkg/money"
+
 	"gorm.io/gorm"
 )
 
@@ -58,12 +60,12 @@ type Order struct {
 	Mobile            string      `json:"mobile" gorm:"size:20;index;comment:手机号"`
 	ProductID         int64       `json:"product_id" gorm:"index;comment:产品ID"`
 	Status            OrderStatus `json:"status" gorm:"comment:订单状态"`
-	Denom             float64     `json:"denom" gorm:"type:decimal(10,2);comment:面值"`
-	TotalPrice        float64     `json:"total_price" gorm:"type:decimal(10,2);comment:总价"`
-	Price             float64     `json:"price" gorm:"type:decimal(10,2);comment:单价"`
-	OfficialPayment   float64     `json:"official_payment" gorm:"type:decimal(10,2);comment:官方支付金额"`
-	UserQuotePayment  float64     `json:"user_quote_payment" gorm:"type:decimal(10,2);comment:用户报价支付金额"`
-	UserPayment       float64     `json:"user_payment" gorm:"type:decimal(10,2);comment:用户支付金额"`
+	Denom             money.Money `json:"denom" gorm:"type:decimal(10,2);comment:面值"`
+	TotalPrice        money.Money `json:"total_price" gorm:"type:decimal(10,2);comment:总价"`
+	Price             money.Money `json:"price" gorm:"type:decimal(10,2);comment:单价"`
+	OfficialPayment   money.Money `json:"official_payment" gorm:"type:decimal(10,2);comment:官方支付金额"`
+	UserQuotePayment  money.Money `json:"user_quote_payment" gorm:"type:decimal(10,2);comment:用户报价支付金额"`
+	UserPayment       money.Money `json:"user_payment" gorm:"type:decimal(10,2);comment:用户支付金额"`
 	PayWay            int         `json:"pay_way" gorm:"comment:支付方式"`
 	SerialNumber      string      `json:"serial_number" gorm:"size:64;comment:支付流水号"`
 	IsPay             int         `json:"is_pay" gorm:"comment:是否支付"`
@@ -85,7 +87,7 @@ type Order struct {
 	APIOpen           int         `json:"api_open" gorm:"comment:API是否开启"`
 	APICurIndex       int         `json:"api_cur_index" gorm:"comment:API当前索引"`
 	IsApart           int         `json:"is_apart" gorm:"comment:是否拆单"`
-	ApartOrderNumber  string      `json:"apart_order_number" gorm:"size:32;comment:拆单订单号"`
+	ApartOrderNumber  string      `json:"apart_order_number" gorm:"size:32;index;comment:拆单父订单号"`
 	DelayTime         int         `json:"delay_time" gorm:"comment:延迟时间"`
 	ApplyRefund       int         `json:"apply_refund" gorm:"comment:申请退款"`
 	IsRebate          int         `json:"is_rebate" gorm:"comment:是否返利"`
@@ -99,7 +101,12 @@ type Order struct {
 	UserOrderId       string      `json:"user_order_id" gorm:"size:64;comment:用户订单ID"`
 	PlatformName      string      `json:"platform_name" gorm:"size:255;comment:平台名称"`
 	PlatformCode      string      `json:"platform_code" gorm:"size:50;comment:平台代码"`
-	ConstPrice        float64     `json:"const_price" gorm:"type:decimal(10,2);comment:成本价格"`
+	ConstPrice        money.Money `json:"const_price" gorm:"type:decimal(10,2);comment:成本价格"`
+	CreditedAmount    money.Money `json:"credited_amount" gorm:"type:decimal(10,2);default:0;comment:实际到账面值"`
+	RefundedAmount    money.Money `json:"refunded_amount" gorm:"type:decimal(10,2);default:0;comment:部分充值退款金额"`
+	QueryCount        int         `json:"query_count" gorm:"default:0;comment:主动查询上游次数"`
+	NextQueryTime     *time.Time  `json:"next_query_time" gorm:"comment:下次主动查询上游时间"`
+	Voucher           string      `json:"voucher" gorm:"size:128;comment:官方充值凭证/流水号"`
 	// 平台配置信息
 	PlatformAppKey      string         `json:"platform_app_key" gorm:"size:255;comment:平台AppKey"`
 	PlatformSecretKey   string         `json:"platform_secret_key" gorm:"size:255;comment:平台SecretKey"`
@@ -122,10 +129,35 @@ type StatisticsQuery struct {
 type OrderWithNotification struct {
 	*Order
 	NotificationTime   *time.Time `json:"notification_time"`   // 通知时间
-	NotificationStatus *int       `json:"notification_status"` // 通知状态 1:待处理 2:处理中 3:成功 4:失败
+	NotificationStatus *int       `json:"notification_status"` // 通知状态 1:待处理 2:处理中 3:成功 4:失败待重试 5:已放弃
 }
 
 // TableName 表名
 func (Order) TableName() string {
 	return "orders"
 }
+
+// ActualCredited 实际到账面值：成功订单未记录到账金额时按面值计，部分充值取上游返回值
+func (o *Order) ActualCredited() money.Money {
+	switch o.Status {
+	case OrderStatusSuccess:
+		if o.CreditedAmount > 0 {
+			return o.CreditedAmount
+		}
+		return o.Denom
+	case OrderStatusPartial:
+		return o.CreditedAmount
+	default:
+		return 0
+	}
+}
+
+// IsSplitParent 是否为已拆单的父订单
+func (o *Order) IsSplitParent() bool {
+	return o.IsApart == 1 && o.ApartOrderNumber == ""
+}
+
+// IsSplitChild 是否为拆单产生的子订单，ApartOrderNumber 为父订单号
+func (o *Order) IsSplitChild() bool {
+	return o.ApartOrderNumber != ""
+}
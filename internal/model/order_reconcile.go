@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 对账处置动作
+const (
+	OrderReconcileActionRequeue = "requeue" // 重新投递充值队列
+	OrderReconcileActionQuery   = "query"   // 主动查询上游
+	OrderReconcileActionFail    = "fail"    // 置为失败并退款
+	OrderReconcileActionCancel  = "cancel"  // 取消订单
+	OrderReconcileActionSettle  = "settle"  // 汇总拆单子订单
+	OrderReconcileActionManual  = "manual"  // 需人工处理
+)
+
+// OrderReconcileReport 超时订单对账报告，记录一次扫描发现的滞留订单及处置结果
+type OrderReconcileReport struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	Source     string    `json:"source" gorm:"size:32;comment:触发方式：schedule定时 manual手动"`
+	DryRun     bool      `json:"dry_run" gorm:"default:false;comment:是否仅预演不处置"`
+	Scanned    int       `json:"scanned" gorm:"default:0;comment:超过SLA的订单数"`
+	Requeued   int       `json:"requeued" gorm:"default:0;comment:重新投递数"`
+	Queried    int       `json:"queried" gorm:"default:0;comment:查询上游数"`
+	Resolved   int       `json:"resolved" gorm:"default:0;comment:查询后更新为终态数"`
+	Failed     int       `json:"failed" gorm:"default:0;comment:失败退款数"`
+	Cancelled  int       `json:"cancelled" gorm:"default:0;comment:取消数"`
+	Settled    int       `json:"settled" gorm:"default:0;comment:拆单汇总数"`
+	Manual     int       `json:"manual" gorm:"default:0;comment:需人工处理数"`
+	Errors     int       `json:"errors" gorm:"default:0;comment:处置出错数"`
+	Items      string    `json:"-" gorm:"type:longtext;comment:处置明细，JSON数组"`
+	StartedAt  time.Time `json:"started_at" gorm:"comment:开始时间"`
+	FinishedAt time.Time `json:"finished_at" gorm:"comment:结束时间"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// OrderReconcileItem 单笔滞留订单的处置结果
+type OrderReconcileItem struct {
+	OrderID     int64       `json:"order_id"`
+	OrderNumber string      `json:"order_number"`
+	Status      OrderStatus `json:"status"`    // 处置前状态
+	StuckFor    string      `json:"stuck_for"` // 停留在该状态的时长
+	Action      string      `json:"action"`    // 处置动作
+	Result      string      `json:"result"`    // 处置结果
+	Error       string      `json:"error,omitempty"`
+}
+
+// TableName 指定表名
+func (OrderReconcileReport) TableName() string {
+	return "order_reconcile_reports"
+}
+
+// ParseItems 解析处置明细
+func (r *OrderReconcileReport) ParseItems() ([]OrderReconcileItem, error) {
+	var items []OrderReconcileItem
+	if r.Items == "" {
+		return items, nil
+	}
+	err := json.Unmarshal([]byte(r.Items), &items)
+	return items, err
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"recharge-go/pkg/money"
+)
+
+// OrderSplitRule 拆单规则
+// 商品面值在所有通道都不可用时，按规则将订单拆分为多笔子订单分别充值
+type OrderSplitRule struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;comment:规则名称"`
+	ProductID int64     `json:"product_id" gorm:"not null;index;comment:被拆分的商品ID"`
+	Parts     string    `json:"parts" gorm:"type:text;not null;comment:子订单组成，JSON数组"`
+	Sort      int       `json:"sort" gorm:"default:0;comment:排序，越小越优先"`
+	Status    int       `json:"status" gorm:"default:1;comment:状态：1启用 0禁用"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// OrderSplitPart 拆单子订单组成
+type OrderSplitPart struct {
+	ProductID int64       `json:"product_id"` // 子订单商品ID
+	Denom     money.Money `json:"denom"`      // 子订单面值
+}
+
+// TableName 指定表名
+func (OrderSplitRule) TableName() string {
+	return "order_split_rules"
+}
+
+// ParseParts 解析子订单组成，如 [{"product_id":12,"denom":100},{"product_id":12,"denom":100}]
+func (r *OrderSplitRule) ParseParts() ([]OrderSplitPart, error) {
+	var parts []OrderSplitPart
+	if err := json.Unmarshal([]byte(r.Parts), &parts); err != nil {
+		return nil, fmt.Errorf("invalid split parts: %v", err)
+	}
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("split rule needs at least 2 parts")
+	}
+	for _, part := range parts {
+		if part.ProductID <= 0 || part.Denom <= 0 {
+			return nil, fmt.Errorf("invalid split part: %+v", part)
+		}
+	}
+	return parts, nil
+}
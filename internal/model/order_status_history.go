@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// OrderStatusHistory 订单状态流转记录
+type OrderStatusHistory struct {
+	ID         int64       `json:"id" gorm:"primaryKey"`
+	OrderID    int64       `json:"order_id" gorm:"not null;index"`
+	FromStatus OrderStatus `json:"from_status" gorm:"not null;comment:原状态"`
+	ToStatus   OrderStatus `json:"to_status" gorm:"not null;comment:新状态"`
+	Actor      string      `json:"actor" gorm:"size:64;comment:操作人"`
+	Reason     string      `json:"reason" gorm:"size:255;comment:变更原因"`
+	Source     string      `json:"source" gorm:"size:32;comment:变更来源"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
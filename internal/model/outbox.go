@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// 发件箱消息状态
+const (
+	OutboxStatusPending    = 0 // 待投递
+	OutboxStatusDispatched = 1 // 已投递
+)
+
+// OutboxMessage 事务发件箱消息
+// 与业务数据在同一事务内写入，提交后再投递到队列，保证已提交的状态变更一定会发出消息。
+// 中继只投递到达 NextAttemptAt 的消息，投递前按 Attempts 抢占，多个中继不会同时投递同一条消息
+type OutboxMessage struct {
+	ID            int64      `json:"id" gorm:"primaryKey"`
+	Topic         string     `json:"topic" gorm:"size:64;comment:投递的队列"`
+	AggregateID   string     `json:"aggregate_id" gorm:"size:64;index;comment:业务对象ID"`
+	Payload       string     `json:"payload" gorm:"type:longtext;comment:消息内容，JSON"`
+	Status        int        `json:"status" gorm:"default:0;index:idx_outbox_messages_status_next,priority:1;comment:状态 0待投递 1已投递"`
+	Attempts      int        `json:"attempts" gorm:"default:0;comment:投递次数"`
+	LastError     string     `json:"last_error" gorm:"type:text;comment:最近一次投递错误"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_messages_status_next,priority:2;comment:中继下次投递时间"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	DispatchedAt  *time.Time `json:"dispatched_at" gorm:"comment:投递时间"`
+}
+
+// TableName 指定表名
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
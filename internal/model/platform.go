@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"recharge-go/pkg/money"
+)
 
 // Platform 平台信息
 type Platform struct {
@@ -19,25 +23,25 @@ type Platform struct {
 
 // PlatformAccount 平台账号信息
 type PlatformAccount struct {
-	ID           int64      `json:"id" gorm:"primaryKey"`                                          // 主键ID
-	PlatformID   int64      `json:"platform_id" gorm:"not null;index"`                             // 平台ID
-	AccountName  string     `json:"account_name" gorm:"size:50;not null"`                          // 账号名称
-	Type         int        `json:"type" gorm:"type:tinyint;default:1;comment:账号类型：1-测试账号，2-正式账号"` // 账号类型：1-测试账号，2-正式账号
-	AppKey       string     `json:"app_key" gorm:"size:64;not null"`                               // AppKey
-	AppSecret    string     `json:"app_secret" gorm:"size:64;not null"`                            // AppSecret
-	Description  string     `json:"description" gorm:"size:255"`                                   // 描述
-	DailyLimit   float64    `json:"daily_limit" gorm:"type:decimal(10,2);default:0.00"`            // 每日限额
-	MonthlyLimit float64    `json:"monthly_limit" gorm:"type:decimal(10,2);default:0.00"`          // 每月限额
-	Balance      float64    `json:"balance" gorm:"type:decimal(10,2);default:0.00"`                // 余额
-	Priority     int        `json:"priority" gorm:"default:0"`                                     // 优先级
-	Status       int        `json:"status" gorm:"type:tinyint;default:1;comment:状态：1-启用，0-禁用"`     // 状态：1-启用，0-禁用
-	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`                              // 创建时间
-	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`                              // 更新时间
-	DeletedAt    *time.Time `json:"deleted_at" gorm:"index"`                                       // 删除时间
-	Platform     *Platform  `json:"platform,omitempty" gorm:"foreignKey:PlatformID"`               // 关联的平台信息
-	BindUserID   *int64     `json:"bind_user_id" gorm:"column:bind_user_id"`
-	BindUserName string     `json:"bind_user_name" gorm:"column:bind_user_name"`
-	PushStatus   int        `gorm:"column:push_status;default:2" json:"push_status"` // 推单状态(1:开启；2:关闭)
+	ID           int64       `json:"id" gorm:"primaryKey"`                                          // 主键ID
+	PlatformID   int64       `json:"platform_id" gorm:"not null;index"`                             // 平台ID
+	AccountName  string      `json:"account_name" gorm:"size:50;not null"`                          // 账号名称
+	Type         int         `json:"type" gorm:"type:tinyint;default:1;comment:账号类型：1-测试账号，2-正式账号"` // 账号类型：1-测试账号，2-正式账号
+	AppKey       string      `json:"app_key" gorm:"size:64;not null"`                               // AppKey
+	AppSecret    string      `json:"app_secret" gorm:"size:64;not null"`                            // AppSecret
+	Description  string      `json:"description" gorm:"size:255"`                                   // 描述
+	DailyLimit   money.Money `json:"daily_limit" gorm:"type:decimal(10,2);default:0.00"`            // 每日限额
+	MonthlyLimit money.Money `json:"monthly_limit" gorm:"type:decimal(10,2);default:0.00"`          // 每月限额
+	Balance      money.Money `json:"balance" gorm:"type:decimal(10,2);default:0.00"`                // 余额
+	Priority     int         `json:"priority" gorm:"default:0"`                                     // 优先级
+	Status       int         `json:"status" gorm:"type:tinyint;default:1;comment:状态：1-启用，0-禁用"`     // 状态：1-启用，0-禁用
+	CreatedAt    time.Time   `json:"created_at" gorm:"autoCreateTime"`                              // 创建时间
+	UpdatedAt    time.Time   `json:"updated_at" gorm:"autoUpdateTime"`                              // 更新时间
+	DeletedAt    *time.Time  `json:"deleted_at" gorm:"index"`                                       // 删除时间
+	Platform     *Platform   `json:"platform,omitempty" gorm:"foreignKey:PlatformID"`               // 关联的平台信息
+	BindUserID   *int64      `json:"bind_user_id" gorm:"column:bind_user_id"`
+	BindUserName string      `json:"bind_user_name" gorm:"column:bind_user_name"`
+	PushStatus   int         `gorm:"column:push_status;default:2" json:"push_status"` // 推单状态(1:开启；2:关闭)
 
 }
 
@@ -89,31 +93,31 @@ type PlatformUpdateRequest struct {
 
 // PlatformAccountCreateRequest 创建平台账号请求
 type PlatformAccountCreateRequest struct {
-	PlatformID   int64   `json:"platform_id" binding:"required"`         // 平台ID
-	AccountName  string  `json:"account_name" binding:"required,max=50"` // 账号名称
-	Type         int     `json:"type" binding:"required,oneof=1 2"`      // 账号类型：1-测试账号，2-正式账号
-	AppKey       string  `json:"app_key" binding:"required,max=64"`      // AppKey
-	AppSecret    string  `json:"app_secret" binding:"required,max=64"`   // AppSecret
-	Description  string  `json:"description" binding:"max=255"`          // 描述
-	DailyLimit   float64 `json:"daily_limit" binding:"min=0"`            // 每日限额
-	MonthlyLimit float64 `json:"monthly_limit" binding:"min=0"`          // 每月限额
-	Priority     int     `json:"priority" binding:"min=0"`               // 优先级
-	Status       *int    `json:"status" binding:"omitempty,oneof=0 1"`   // 状态：1-启用，0-禁用
+	PlatformID   int64       `json:"platform_id" binding:"required"`         // 平台ID
+	AccountName  string      `json:"account_name" binding:"required,max=50"` // 账号名称
+	Type         int         `json:"type" binding:"required,oneof=1 2"`      // 账号类型：1-测试账号，2-正式账号
+	AppKey       string      `json:"app_key" binding:"required,max=64"`      // AppKey
+	AppSecret    string      `json:"app_secret" binding:"required,max=64"`   // AppSecret
+	Description  string      `json:"description" binding:"max=255"`          // 描述
+	DailyLimit   money.Money `json:"daily_limit" binding:"min=0"`            // 每日限额
+	MonthlyLimit money.Money `json:"monthly_limit" binding:"min=0"`          // 每月限额
+	Priority     int         `json:"priority" binding:"min=0"`               // 优先级
+	Status       *int        `json:"status" binding:"omitempty,oneof=0 1"`   // 状态：1-启用，0-禁用
 }
 
 // PlatformAccountUpdateRequest 更新平台账号请求
 type PlatformAccountUpdateRequest struct {
-	AccountName  *string  `json:"account_name" binding:"max=50"`
-	Type         *int     `json:"type" binding:"oneof=1 2"`
-	AppKey       *string  `json:"app_key" binding:"max=64"`
-	AppSecret    *string  `json:"app_secret" binding:"max=64"`
-	Description  *string  `json:"description" binding:"max=255"`
-	DailyLimit   *float64 `json:"daily_limit" binding:"min=0"`
-	MonthlyLimit *float64 `json:"monthly_limit" binding:"min=0"`
-	Balance      *float64 `json:"balance" binding:"min=0"`
-	Priority     *int     `json:"priority" binding:"min=0"`
-	Status       *int     `json:"status" binding:"omitempty,oneof=0 1"`
-	PushStatus   *int     `json:"push_status"`
+	AccountName  *string      `json:"account_name" binding:"max=50"`
+	Type         *int         `json:"type" binding:"oneof=1 2"`
+	AppKey       *string      `json:"app_key" binding:"max=64"`
+	AppSecret    *string      `json:"app_secret" binding:"max=64"`
+	Description  *string      `json:"description" binding:"max=255"`
+	DailyLimit   *money.Money `json:"daily_limit" binding:"min=0"`
+	MonthlyLimit *money.Money `json:"monthly_limit" binding:"min=0"`
+	Balance      *money.Money `json:"balance" binding:"min=0"`
+	Priority     *int         `json:"priority" binding:"min=0"`
+	Status       *int         `json:"status" binding:"omitempty,oneof=0 1"`
+	PushStatus   *int         `json:"push_status"`
 }
 
 const PlatformCodeDayuanren = "dayuanren"
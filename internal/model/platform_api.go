@@ -1,8 +1,12 @@
 package model
 
 import (
+	"net"
+	"strings"
 	"time"
 
+	"recharge-go/pkg/money"
+
 	"gorm.io/datatypes"
 )
 
@@ -26,6 +30,8 @@ type PlatformAPI struct {
 	RetryDelay  int            `json:"retry_delay" gorm:"default:5"` // 重试延迟（分钟）
 	ExtraParams datatypes.JSON `json:"extra_params" gorm:"type:json"`
 	AccountID   int64          `json:"account_id" gorm:"not null;default:0;comment:账号ID"`
+	// CallbackAllowedCIDRs 允许回调的来源IP或网段，逗号或换行分隔，为空时不限制
+	CallbackAllowedCIDRs string `json:"callback_allowed_cidrs" gorm:"column:callback_allowed_cidrs;type:text;comment:允许回调的来源IP/CIDR"`
 }
 
 // TableName 表名
@@ -33,25 +39,73 @@ func (PlatformAPI) TableName() string {
 	return "platform_apis"
 }
 
+// CallbackCIDRs 解析回调来源白名单，单个IP按 /32 或 /128 处理
+func (a *PlatformAPI) CallbackCIDRs() ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range strings.FieldsFunc(a.CallbackAllowedCIDRs, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: item}
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// AllowsCallbackFrom 来源IP是否允许回调，未配置白名单时不限制，白名单无法解析时拒绝
+func (a *PlatformAPI) AllowsCallbackFrom(sourceIP string) bool {
+	nets, err := a.CallbackCIDRs()
+	if err != nil {
+		return false
+	}
+	if len(nets) == 0 {
+		return true
+	}
+	ip := net.ParseIP(sourceIP)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // PlatformAPIParam 接口套餐配置
 type PlatformAPIParam struct {
-	ID              int64     `json:"id" gorm:"primaryKey"`
-	APIID           int64     `json:"api_id" gorm:"not null;index" validate:"required"`
-	Name            string    `json:"name" gorm:"size:50;not null;comment:参数名称" validate:"required,max=50"`
-	ProductID       string    `json:"product_id" gorm:"size:128;not null;comment:产品ID" validate:"required"`
-	Description     string    `json:"description" gorm:"size:255;comment:参数描述"`
-	Cost            float64   `json:"cost" gorm:"type:decimal(10,4);default:0.0000;comment:产品成本" validate:"min=0"`
-	ParValue        float64   `json:"par_value" gorm:"type:decimal(10,4);default:0.0000;comment:套餐值" `
-	Price           float64   `json:"price" gorm:"type:decimal(10,4);default:0.0000;comment:价格" `
-	CallbackURL     string    `json:"callback_url" gorm:"size:255;comment:回调地址"`
-	AllowProvinces  string    `json:"allow_provinces" gorm:"type:text;comment:允许的省份"`
-	AllowCities     string    `json:"allow_cities" gorm:"type:text;comment:允许的城市"`
-	ForbidProvinces string    `json:"forbid_provinces" gorm:"type:text;comment:禁止的省份"`
-	ForbidCities    string    `json:"forbid_cities" gorm:"type:text;comment:禁止的城市"`
-	Sort            int       `json:"sort" gorm:"not null;default:0;comment:排序"`
-	Status          int       `json:"status" gorm:"not null;default:1;comment:状态：1-启用，0-禁用" validate:"oneof=0 1"`
-	CreatedAt       time.Time `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP;type:datetime"`
-	UpdatedAt       time.Time `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;type:datetime"`
+	ID              int64       `json:"id" gorm:"primaryKey"`
+	APIID           int64       `json:"api_id" gorm:"not null;index" validate:"required"`
+	Name            string      `json:"name" gorm:"size:50;not null;comment:参数名称" validate:"required,max=50"`
+	ProductID       string      `json:"product_id" gorm:"size:128;not null;comment:产品ID" validate:"required"`
+	Description     string      `json:"description" gorm:"size:255;comment:参数描述"`
+	Cost            money.Money `json:"cost" gorm:"type:decimal(10,4);default:0.0000;comment:产品成本" validate:"min=0"`
+	ParValue        float64     `json:"par_value" gorm:"type:decimal(10,4);default:0.0000;comment:套餐值" `
+	Price           money.Money `json:"price" gorm:"type:decimal(10,4);default:0.0000;comment:价格" `
+	CallbackURL     string      `json:"callback_url" gorm:"size:255;comment:回调地址"`
+	AllowProvinces  string      `json:"allow_provinces" gorm:"type:text;comment:允许的省份"`
+	AllowCities     string      `json:"allow_cities" gorm:"type:text;comment:允许的城市"`
+	ForbidProvinces string      `json:"forbid_provinces" gorm:"type:text;comment:禁止的省份"`
+	ForbidCities    string      `json:"forbid_cities" gorm:"type:text;comment:禁止的城市"`
+	Sort            int         `json:"sort" gorm:"not null;default:0;comment:排序"`
+	Status          int         `json:"status" gorm:"not null;default:1;comment:状态：1-启用，0-禁用" validate:"oneof=0 1"`
+	CreatedAt       time.Time   `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP;type:datetime"`
+	UpdatedAt       time.Time   `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;type:datetime"`
 }
 
 // APICallLog 接口调用日志
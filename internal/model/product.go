@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"recharge-go/pkg/money"
 )
 
 // ProductCategory 商品分类
@@ -19,7 +21,7 @@ type Product struct {
 	ID              int64            `json:"id" gorm:"primaryKey;type:bigint"`                                             // 主键ID
 	Name            string           `json:"name" gorm:"size:100;not null"`                                                // 商品名称
 	Description     string           `json:"description" gorm:"size:500;comment:商品描述"`                                     // 商品描述
-	Price           float64          `json:"price" gorm:"type:decimal(10,2);not null;comment:价格"`                          // 商品价格
+	Price           money.Money      `json:"price" gorm:"type:decimal(10,2);not null;comment:价格"`                          // 商品价格
 	Type            int64            `json:"type" gorm:"column:type;type:bigint;default:1;comment:'1话费 2流量'"`              // 商品类型ID
 	ISP             string           `json:"isp" gorm:"type:varchar(255);default:'1,2,3';comment:'支持运营商:1移动 2电信 3联通'"`     // 运营商
 	Status          int              `json:"status" gorm:"type:bigint;default:1;COMMENT:'是否上架'"`                           // 状态：1-启用，0-禁用
@@ -42,6 +44,7 @@ type Product struct {
 	APIID           int64            `json:"api_id" gorm:"type:bigint;comment:接码接口ID"`                                     // API接口ID
 	APIParamID      int64            `json:"api_param_id" gorm:"type:bigint;comment:接码接口参数ID"`                             // API参数ID
 	IsApi           bool             `json:"is_api" gorm:"default:false;comment:是否接码"`                                     // 是否需要解码
+	RouteStrategy   string           `json:"route_strategy" gorm:"size:20;default:'';comment:通道路由策略"`                      // 通道路由策略：priority/cheapest/fastest/weighted
 	CreatedAt       time.Time        `json:"created_at" gorm:"type:datetime;autoCreateTime"`                               // 创建时间
 	UpdatedAt       time.Time        `json:"updated_at" gorm:"type:datetime;autoUpdateTime"`                               // 更新时间
 	ProductType     *ProductType     `json:"product_type,omitempty" gorm:"foreignKey:Type;references:ID"`                  // 关联的商品类型
@@ -1,5 +1,7 @@
 package model
 
+import "recharge-go/pkg/money"
+
 // ProductListRequest 商品列表请求
 type ProductListRequest struct {
 	Page     int    `form:"page" binding:"required,min=1"`
@@ -32,61 +34,63 @@ type ProductDetailResponse struct {
 
 // ProductCreateRequest 创建商品请求
 type ProductCreateRequest struct {
-	Name            string  `json:"name" binding:"required"`
-	Description     string  `json:"description"`
-	Price           float64 `json:"price" binding:"required"`
-	Type            int     `json:"type" binding:"required"`
-	ISP             string  `json:"isp"`
-	Status          int     `json:"status"`
-	Sort            int     `json:"sort"`
-	APIEnabled      bool    `json:"api_enabled"`
-	Remark          string  `json:"remark"`
-	CategoryID      int64   `json:"category_id" binding:"required"`
-	OperatorTag     string  `json:"operator_tag"`
-	MaxPrice        float64 `json:"max_price"`
-	VoucherPrice    string  `json:"voucher_price"`
-	VoucherName     string  `json:"voucher_name"`
-	ShowStyle       int     `json:"show_style"`
-	APIFailStyle    int     `json:"api_fail_style"`
-	AllowProvinces  string  `json:"allow_provinces"`
-	AllowCities     string  `json:"allow_cities"`
-	ForbidProvinces string  `json:"forbid_provinces"`
-	ForbidCities    string  `json:"forbid_cities"`
-	APIDelay        string  `json:"api_delay"`
-	GradeIDs        string  `json:"grade_ids"`
-	APIID           int64   `json:"api_id"`
-	APIParamID      int64   `json:"api_param_id"`
-	IsApi           bool    `json:"is_api"`
+	Name            string      `json:"name" binding:"required"`
+	Description     string      `json:"description"`
+	Price           money.Money `json:"price" binding:"required"`
+	Type            int         `json:"type" binding:"required"`
+	ISP             string      `json:"isp"`
+	Status          int         `json:"status"`
+	Sort            int         `json:"sort"`
+	APIEnabled      bool        `json:"api_enabled"`
+	Remark          string      `json:"remark"`
+	CategoryID      int64       `json:"category_id" binding:"required"`
+	OperatorTag     string      `json:"operator_tag"`
+	MaxPrice        float64     `json:"max_price"`
+	VoucherPrice    string      `json:"voucher_price"`
+	VoucherName     string      `json:"voucher_name"`
+	ShowStyle       int         `json:"show_style"`
+	APIFailStyle    int         `json:"api_fail_style"`
+	AllowProvinces  string      `json:"allow_provinces"`
+	AllowCities     string      `json:"allow_cities"`
+	ForbidProvinces string      `json:"forbid_provinces"`
+	ForbidCities    string      `json:"forbid_cities"`
+	APIDelay        string      `json:"api_delay"`
+	GradeIDs        string      `json:"grade_ids"`
+	APIID           int64       `json:"api_id"`
+	APIParamID      int64       `json:"api_param_id"`
+	IsApi           bool        `json:"is_api"`
+	RouteStrategy   string      `json:"route_strategy"`
 }
 
 // ProductUpdateRequest 更新商品请求
 type ProductUpdateRequest struct {
-	ID              int64   `json:"id" binding:"required"`
-	Name            string  `json:"name" binding:"required"`
-	Description     string  `json:"description"`
-	Price           float64 `json:"price" binding:"required"`
-	Type            int     `json:"type" binding:"required"`
-	ISP             string  `json:"isp"`
-	Status          int     `json:"status"`
-	Sort            int     `json:"sort"`
-	APIEnabled      bool    `json:"api_enabled"`
-	Remark          string  `json:"remark"`
-	CategoryID      int64   `json:"category_id" binding:"required"`
-	OperatorTag     string  `json:"operator_tag"`
-	MaxPrice        float64 `json:"max_price"`
-	VoucherPrice    string  `json:"voucher_price"`
-	VoucherName     string  `json:"voucher_name"`
-	ShowStyle       int     `json:"show_style"`
-	APIFailStyle    int     `json:"api_fail_style"`
-	AllowProvinces  string  `json:"allow_provinces"`
-	AllowCities     string  `json:"allow_cities"`
-	ForbidProvinces string  `json:"forbid_provinces"`
-	ForbidCities    string  `json:"forbid_cities"`
-	APIDelay        string  `json:"api_delay"`
-	GradeIDs        string  `json:"grade_ids"`
-	APIID           int64   `json:"api_id"`
-	APIParamID      int64   `json:"api_param_id"`
-	IsApi           bool    `json:"is_api"`
+	ID              int64       `json:"id" binding:"required"`
+	Name            string      `json:"name" binding:"required"`
+	Description     string      `json:"description"`
+	Price           money.Money `json:"price" binding:"required"`
+	Type            int         `json:"type" binding:"required"`
+	ISP             string      `json:"isp"`
+	Status          int         `json:"status"`
+	Sort            int         `json:"sort"`
+	APIEnabled      bool        `json:"api_enabled"`
+	Remark          string      `json:"remark"`
+	CategoryID      int64       `json:"category_id" binding:"required"`
+	OperatorTag     string      `json:"operator_tag"`
+	MaxPrice        float64     `json:"max_price"`
+	VoucherPrice    string      `json:"voucher_price"`
+	VoucherName     string      `json:"voucher_name"`
+	ShowStyle       int         `json:"show_style"`
+	APIFailStyle    int         `json:"api_fail_style"`
+	AllowProvinces  string      `json:"allow_provinces"`
+	AllowCities     string      `json:"allow_cities"`
+	ForbidProvinces string      `json:"forbid_provinces"`
+	ForbidCities    string      `json:"forbid_cities"`
+	APIDelay        string      `json:"api_delay"`
+	GradeIDs        string      `json:"grade_ids"`
+	APIID           int64       `json:"api_id"`
+	APIParamID      int64       `json:"api_param_id"`
+	IsApi           bool        `json:"is_api"`
+	RouteStrategy   string      `json:"route_strategy"`
 }
 
 // ProductAPIRelationCreateRequest 创建商品接口关联请求
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"recharge-go/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// BalanceReconcileReportRepository 资金对账报告仓储
+type BalanceReconcileReportRepository struct {
+	db *gorm.DB
+}
+
+// NewBalanceReconcileReportRepository 创建资金对账报告仓储
+func NewBalanceReconcileReportRepository(db *gorm.DB) *BalanceReconcileReportRepository {
+	return &BalanceReconcileReportRepository{db: db}
+}
+
+// Create 保存对账报告
+func (r *BalanceReconcileReportRepository) Create(ctx context.Context, report *model.BalanceReconcileReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// GetByID 根据ID获取对账报告
+func (r *BalanceReconcileReportRepository) GetByID(ctx context.Context, id int64) (*model.BalanceReconcileReport, error) {
+	var report model.BalanceReconcileReport
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List 分页获取对账报告，按时间倒序，不含差异明细
+func (r *BalanceReconcileReportRepository) List(ctx context.Context, page, pageSize int) ([]*model.BalanceReconcileReport, int64, error) {
+	var reports []*model.BalanceReconcileReport
+	var total int64
+	query := r.db.WithContext(ctx).Model(&model.BalanceReconcileReport{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("items").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&reports).Error
+	return reports, total, err
+}
@@ -11,6 +11,14 @@ import (
 type CallbackLogRepository interface {
 	// Create 创建回调日志
 	Create(ctx context.Context, log *model.CallbackLog) error
+	// Update 更新回调日志的处理结果
+	Update(ctx context.Context, log *model.CallbackLog) error
+	// GetByID 根据ID获取回调日志
+	GetByID(ctx context.Context, id int64) (*model.CallbackLog, error)
+	// List 分页查询回调日志，不含原始数据
+	List(ctx context.Context, query *model.CallbackLogQuery) ([]*model.CallbackLog, int64, error)
+	// MarkReplayed 记录一次重放，resolvedByID 非 0 时标记为已被该重放日志解决
+	MarkReplayed(ctx context.Context, id, resolvedByID int64) error
 	// GetByOrderID 根据订单号获取回调日志
 	GetByOrderID(ctx context.Context, orderID string) ([]*model.CallbackLog, error)
 	// GetByOrderIDAndType 根据订单号和回调类型获取回调日志
@@ -32,6 +40,55 @@ func (r *CallbackLogRepositoryImpl) Create(ctx context.Context, log *model.Callb
 	return r.db.WithContext(ctx).Create(log).Error
 }
 
+// Update 更新回调日志的处理结果
+func (r *CallbackLogRepositoryImpl) Update(ctx context.Context, log *model.CallbackLog) error {
+	return r.db.WithContext(ctx).Model(log).Select("order_id", "platform_id", "callback_type", "status", "response_data", "error_message", "update_time").Updates(log).Error
+}
+
+// GetByID 根据ID获取回调日志
+func (r *CallbackLogRepositoryImpl) GetByID(ctx context.Context, id int64) (*model.CallbackLog, error) {
+	var log model.CallbackLog
+	if err := r.db.WithContext(ctx).First(&log, id).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// List 分页查询回调日志，按时间倒序，不含原始数据
+func (r *CallbackLogRepositoryImpl) List(ctx context.Context, query *model.CallbackLogQuery) ([]*model.CallbackLog, int64, error) {
+	var logs []*model.CallbackLog
+	var total int64
+	db := r.db.WithContext(ctx).Model(&model.CallbackLog{})
+	if len(query.Statuses) > 0 {
+		db = db.Where("status IN ?", query.Statuses)
+	}
+	if query.PlatformCode != "" {
+		db = db.Where("platform_code = ?", query.PlatformCode)
+	}
+	if query.OrderNumber != "" {
+		db = db.Where("platform_id = ? OR order_id = ?", query.OrderNumber, query.OrderNumber)
+	}
+	if query.Unresolved {
+		db = db.Where("replay_of_id = 0 AND resolved_by_id = 0")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Omit("request_data", "response_data").Order("id DESC").
+		Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).
+		Find(&logs).Error
+	return logs, total, err
+}
+
+// MarkReplayed 记录一次重放，resolvedByID 非 0 时标记为已被该重放日志解决
+func (r *CallbackLogRepositoryImpl) MarkReplayed(ctx context.Context, id, resolvedByID int64) error {
+	updates := map[string]interface{}{"replay_count": gorm.Expr("replay_count + 1")}
+	if resolvedByID != 0 {
+		updates["resolved_by_id"] = resolvedByID
+	}
+	return r.db.WithContext(ctx).Model(&model.CallbackLog{}).Where("id = ?", id).UpdateColumns(updates).Error
+}
+
 // GetByOrderID 根据订单号获取回调日志
 func (r *CallbackLogRepositoryImpl) GetByOrderID(ctx context.Context, orderID string) ([]*model.CallbackLog, error) {
 	var logs []*model.CallbackLog
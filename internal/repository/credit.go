@@ -2,6 +2,7 @@ package repository
 
 import (
 	"recharge-go/internal/model"
+	"recharge-go/pkg/money"
 
 	"gorm.io/gorm"
 )
@@ -52,8 +53,8 @@ func (r *CreditRepository) GetCreditLogs(userID int64, changeType string, orderI
 }
 
 // GetUserCreditStats 获取用户授信统计
-func (r *CreditRepository) GetUserCreditStats(userID int64) (float64, float64, error) {
-	var totalUsed, totalRestored float64
+func (r *CreditRepository) GetUserCreditStats(userID int64) (money.Money, money.Money, error) {
+	var totalUsed, totalRestored money.Money
 
 	// 统计已使用额度
 	err := r.db.Model(&model.CreditLog{}).
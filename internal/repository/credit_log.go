@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"recharge-go/internal/model"
+	"recharge-go/pkg/money"
 
 	"gorm.io/gorm"
 )
@@ -65,9 +66,9 @@ func (r *CreditLogRepository) List(ctx context.Context, req *model.CreditLogList
 }
 
 // GetUserCreditStats 获取用户授信统计
-func (r *CreditLogRepository) GetUserCreditStats(ctx context.Context, userID int64) (float64, float64, error) {
-	var totalUsed float64
-	var totalRestored float64
+func (r *CreditLogRepository) GetUserCreditStats(ctx context.Context, userID int64) (money.Money, money.Money, error) {
+	var totalUsed money.Money
+	var totalRestored money.Money
 
 	// 统计已使用额度
 	err := r.db.WithContext(ctx).Model(&model.CreditLog{}).
@@ -0,0 +1,37 @@
+package notification
+
+import (
+	"context"
+	"recharge-go/internal/model/notification"
+
+	"gorm.io/gorm"
+)
+
+// AttemptRepository 通知投递记录仓库
+type AttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewAttemptRepository 创建通知投递记录仓库
+func NewAttemptRepository(db *gorm.DB) *AttemptRepository {
+	return &AttemptRepository{db: db}
+}
+
+// Create 创建投递记录
+func (r *AttemptRepository) Create(ctx context.Context, attempt *notification.DeliveryAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// ListByOrderID 获取订单最近的投递记录，按投递时间倒序
+func (r *AttemptRepository) ListByOrderID(ctx context.Context, orderID int64, limit int) ([]*notification.DeliveryAttempt, error) {
+	var attempts []*notification.DeliveryAttempt
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -17,6 +17,18 @@ type Repository interface {
 	GetPendingRecords(ctx context.Context, limit int) ([]*notification.NotificationRecord, error)
 	List(ctx context.Context, params map[string]interface{}, page, pageSize int) ([]*notification.NotificationRecord, int64, error)
 	Update(ctx context.Context, record *notification.NotificationRecord) error
+	// Claim 抢占待处理或已到重试时间的通知，retryCount 作为版本防止同一次投递被重复处理
+	Claim(ctx context.Context, id int64, retryCount int) (bool, error)
+	// MarkRetry 记录投递失败并安排下次投递
+	MarkRetry(ctx context.Context, id int64, retryCount int, nextRetryTime time.Time, lastError string) error
+	// MarkGaveUp 放弃通知
+	MarkGaveUp(ctx context.Context, id int64, retryCount int, lastError string) error
+	// GetDueRecords 获取已到重试时间的通知
+	GetDueRecords(ctx context.Context, now time.Time, limit int) ([]*notification.NotificationRecord, error)
+	// ResetStale 将长时间停留在待处理或处理中的通知转为立即重试，返回转换的条数
+	ResetStale(ctx context.Context, before time.Time) (int64, error)
+	// Requeue 将失败待重试或已放弃的通知安排为立即重试
+	Requeue(ctx context.Context, id int64) (bool, error)
 }
 
 // RepositoryImpl 通知记录仓库实现
@@ -36,7 +48,7 @@ func (r *RepositoryImpl) Create(ctx context.Context, record *notification.Notifi
 
 // UpdateStatus 更新通知状态
 func (r *RepositoryImpl) UpdateStatus(ctx context.Context, id int64, status int) error {
-	if status == 3 {
+	if status == notification.StatusSuccess {
 		return r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
 			Where("id = ?", id).
 			Updates(map[string]interface{}{
@@ -73,7 +85,7 @@ func (r *RepositoryImpl) GetByOrderID(ctx context.Context, orderID int64) (*noti
 func (r *RepositoryImpl) GetPendingRecords(ctx context.Context, limit int) ([]*notification.NotificationRecord, error) {
 	var records []*notification.NotificationRecord
 	err := r.db.WithContext(ctx).
-		Where("status = ?", 1). // 待处理状态
+		Where("status = ?", notification.StatusPending).
 		Limit(limit).
 		Find(&records).Error
 	if err != nil {
@@ -91,6 +103,12 @@ func (r *RepositoryImpl) List(ctx context.Context, params map[string]interface{}
 
 	// 添加查询条件
 	for key, value := range params {
+		if key == "status" {
+			if statuses, ok := value.([]int); ok {
+				query = query.Where("status IN ?", statuses)
+				continue
+			}
+		}
 		query = query.Where(key+" = ?", value)
 	}
 
@@ -100,7 +118,8 @@ func (r *RepositoryImpl) List(ctx context.Context, params map[string]interface{}
 	}
 
 	// 分页查询
-	err := query.Offset((page - 1) * pageSize).
+	err := query.Order("id DESC").
+		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&records).Error
 	if err != nil {
@@ -114,3 +133,78 @@ func (r *RepositoryImpl) List(ctx context.Context, params map[string]interface{}
 func (r *RepositoryImpl) Update(ctx context.Context, record *notification.NotificationRecord) error {
 	return r.db.WithContext(ctx).Save(record).Error
 }
+
+// Claim 抢占待处理或已到重试时间的通知
+func (r *RepositoryImpl) Claim(ctx context.Context, id int64, retryCount int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
+		Where("id = ? AND retry_count = ?", id, retryCount).
+		Where("status = ? OR (status = ? AND next_retry_time <= ?)", notification.StatusPending, notification.StatusFailed, time.Now()).
+		Update("status", notification.StatusProcessing)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkRetry 记录投递失败并安排下次投递
+func (r *RepositoryImpl) MarkRetry(ctx context.Context, id int64, retryCount int, nextRetryTime time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          notification.StatusFailed,
+			"retry_count":     retryCount,
+			"next_retry_time": nextRetryTime,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MarkGaveUp 放弃通知
+func (r *RepositoryImpl) MarkGaveUp(ctx context.Context, id int64, retryCount int, lastError string) error {
+	return r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":      notification.StatusGaveUp,
+			"retry_count": retryCount,
+			"last_error":  lastError,
+		}).Error
+}
+
+// GetDueRecords 获取已到重试时间的通知，按重试时间先后排序
+func (r *RepositoryImpl) GetDueRecords(ctx context.Context, now time.Time, limit int) ([]*notification.NotificationRecord, error) {
+	var records []*notification.NotificationRecord
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_retry_time <= ?", notification.StatusFailed, now).
+		Order("next_retry_time ASC").
+		Limit(limit).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ResetStale 将长时间停留在待处理或处理中的通知转为立即重试
+// 队列消息丢失或工作协程在投递中途退出时，通知会停留在这两个状态
+func (r *RepositoryImpl) ResetStale(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
+		Where("status IN ? AND updated_at < ?", []int{notification.StatusPending, notification.StatusProcessing}, before).
+		Updates(map[string]interface{}{
+			"status":          notification.StatusFailed,
+			"next_retry_time": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Requeue 将失败待重试或已放弃的通知安排为立即重试
+func (r *RepositoryImpl) Requeue(ctx context.Context, id int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&notification.NotificationRecord{}).
+		Where("id = ? AND status IN ?", id, []int{notification.StatusFailed, notification.StatusGaveUp}).
+		Updates(map[string]interface{}{
+			"status":          notification.StatusFailed,
+			"next_retry_time": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -38,7 +38,7 @@ func (r *TemplateRepository) GetByID(ctx context.Context, id int64) (*notificati
 }
 
 // GetByPlatformAndType 根据平台和类型获取通知模板
-func (r *TemplateRepository) GetByPlatformAndType(ctx context.Context, platformCode string, notificationType int) (*notification.Template, error) {
+func (r *TemplateRepository) GetByPlatformAndType(ctx context.Context, platformCode, notificationType string) (*notification.Template, error) {
 	var template notification.Template
 	err := r.db.WithContext(ctx).
 		Where("platform_code = ? AND notification_type = ? AND status = ?", platformCode, notificationType, 1).
@@ -27,8 +27,6 @@ type OrderRepository interface {
 	GetByOrderNumber(ctx context.Context, orderNumber string) (*model.Order, error)
 	// GetByCustomerID 根据客户ID获取订单列表
 	GetByCustomerID(ctx context.Context, customerID int64, page, pageSize int) ([]*model.Order, int64, error)
-	// UpdateStatus 更新订单状态
-	UpdateStatus(ctx context.Context, id int64, status model.OrderStatus) error
 	// UpdatePayInfo 更新支付信息
 	UpdatePayInfo(ctx context.Context, id int64, payWay int, serialNumber string) error
 	// UpdateAPIInfo 更新API信息
@@ -65,10 +63,6 @@ type OrderRepository interface {
 	FindProductByPriceAndISPWithTolerance(price float64, isp int, status int, tolerance float64) (*model.Product, error)
 	// FindProductByNameValueAndISP 根据产品名称数字部分、ISP和状态获取产品
 	FindProductByNameValueAndISP(nameValue int, isp int, status int) (*model.Product, error)
-	// UpdateStatusCAS 原子性地将订单状态从 oldStatus 更新为 newStatus，同时写入 api_id
-	UpdateStatusCAS(ctx context.Context, id int64, oldStatus, newStatus model.OrderStatus, apiID int64) (bool, error)
-	// UpdateStatusAndAPIID 更新订单状态和API ID
-	UpdateStatusAndAPIID(ctx context.Context, id int64, status model.OrderStatus, apiID int64, usedAPIs string) error
 	// GetOrderRealtimeStatistics 获取实时统计
 	GetOrderRealtimeStatistics(ctx context.Context, userId int64) (*model.OrderStatisticsOverview, error)
 	// GetOperatorRealtimeStatistics 获取运营商实时统计
@@ -162,11 +156,6 @@ func (r *OrderRepositoryImpl) GetByCustomerID(ctx context.Context, customerID in
 	return orders, total, nil
 }
 
-// UpdateStatus 更新订单状态
-func (r *OrderRepositoryImpl) UpdateStatus(ctx context.Context, id int64, status model.OrderStatus) error {
-	return r.db.Model(&model.Order{}).Where("id = ?", id).Update("status", status).Error
-}
-
 // UpdatePayInfo 更新支付信息
 func (r *OrderRepositoryImpl) UpdatePayInfo(ctx context.Context, id int64, payWay int, serialNumber string) error {
 	return r.db.Model(&model.Order{}).Where("id = ?", id).Updates(map[string]interface{}{
@@ -393,35 +382,6 @@ func (r *OrderRepositoryImpl) FindProductByNameValueAndISP(nameValue int, isp in
 	return &product, nil
 }
 
-// UpdateStatusCAS 原子性地将订单状态从 oldStatus 更新为 newStatus，同时写入 api_id
-func (r *OrderRepositoryImpl) UpdateStatusCAS(ctx context.Context, id int64, oldStatus, newStatus model.OrderStatus, apiID int64) (bool, error) {
-	res := r.db.Model(&model.Order{}).
-		Where("id = ? AND status = ? AND (api_id = 0 OR api_id IS NULL OR api_id = ?)", id, oldStatus, apiID).
-		Updates(map[string]interface{}{
-			"status": newStatus,
-			"api_id": apiID,
-		})
-	if res.Error != nil {
-		return false, res.Error
-	}
-	return res.RowsAffected == 1, nil
-}
-
-// UpdateStatusAndAPIID 更新订单状态和API ID
-func (r *OrderRepositoryImpl) UpdateStatusAndAPIID(ctx context.Context, id int64, status model.OrderStatus, apiID int64, usedAPIs string) error {
-	fmt.Println("即将执行 Updates")
-	err := r.db.WithContext(ctx).
-		Model(&model.Order{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"status":    status,
-			"api_id":    apiID,
-			"used_apis": usedAPIs,
-		}).Error
-	fmt.Println("Updates 执行完毕，err =", err)
-	return err
-}
-
 // GetOrderRealtimeStatistics 获取实时统计
 func (r *OrderRepositoryImpl) GetOrderRealtimeStatistics(ctx context.Context, userId int64) (*model.OrderStatisticsOverview, error) {
 	var overview model.OrderStatisticsOverview
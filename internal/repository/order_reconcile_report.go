@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"recharge-go/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// OrderReconcileReportRepository 超时订单对账报告仓储
+type OrderReconcileReportRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderReconcileReportRepository 创建超时订单对账报告仓储
+func NewOrderReconcileReportRepository(db *gorm.DB) *OrderReconcileReportRepository {
+	return &OrderReconcileReportRepository{db: db}
+}
+
+// Create 保存对账报告
+func (r *OrderReconcileReportRepository) Create(ctx context.Context, report *model.OrderReconcileReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// GetByID 根据ID获取对账报告
+func (r *OrderReconcileReportRepository) GetByID(ctx context.Context, id int64) (*model.OrderReconcileReport, error) {
+	var report model.OrderReconcileReport
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List 分页获取对账报告，按时间倒序，不含处置明细
+func (r *OrderReconcileReportRepository) List(ctx context.Context, page, pageSize int) ([]*model.OrderReconcileReport, int64, error) {
+	var reports []*model.OrderReconcileReport
+	var total int64
+	query := r.db.WithContext(ctx).Model(&model.OrderReconcileReport{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("items").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&reports).Error
+	return reports, total, err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"recharge-go/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// OrderSplitRuleRepository 拆单规则仓储
+type OrderSplitRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderSplitRuleRepository 创建拆单规则仓储
+func NewOrderSplitRuleRepository(db *gorm.DB) *OrderSplitRuleRepository {
+	return &OrderSplitRuleRepository{db: db}
+}
+
+// Create 创建拆单规则
+func (r *OrderSplitRuleRepository) Create(ctx context.Context, rule *model.OrderSplitRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// Update 更新拆单规则
+func (r *OrderSplitRuleRepository) Update(ctx context.Context, rule *model.OrderSplitRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// Delete 删除拆单规则
+func (r *OrderSplitRuleRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&model.OrderSplitRule{}, id).Error
+}
+
+// GetByID 根据ID获取拆单规则
+func (r *OrderSplitRuleRepository) GetByID(ctx context.Context, id int64) (*model.OrderSplitRule, error) {
+	var rule model.OrderSplitRule
+	if err := r.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List 获取拆单规则列表，productID 为 0 时返回全部
+func (r *OrderSplitRuleRepository) List(ctx context.Context, productID int64) ([]*model.OrderSplitRule, error) {
+	var rules []*model.OrderSplitRule
+	query := r.db.WithContext(ctx).Model(&model.OrderSplitRule{})
+	if productID > 0 {
+		query = query.Where("product_id = ?", productID)
+	}
+	err := query.Order("product_id ASC, sort ASC, id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// ListEnabledByProductID 获取商品启用的拆单规则，按优先级排序
+func (r *OrderSplitRuleRepository) ListEnabledByProductID(ctx context.Context, productID int64) ([]*model.OrderSplitRule, error) {
+	var rules []*model.OrderSplitRule
+	err := r.db.WithContext(ctx).
+		Where("product_id = ? AND status = ?", productID, 1).
+		Order("sort ASC, id ASC").
+		Find(&rules).Error
+	return rules, err
+}
@@ -21,5 +21,7 @@ func RegisterBalanceRoutes(r *gin.RouterGroup, db *gorm.DB, userRepo *repository
 		api.POST("/recharge", balanceController.Recharge)
 		api.POST("/deduct", balanceController.Deduct)
 		api.GET("/logs", balanceController.ListLogs)
+		api.GET("/ledger", balanceController.ListLedger)
+		api.GET("/ledger/balance", balanceController.LedgerBalance)
 	}
 }
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterBalanceReconcileRoutes 注册资金对账路由（仅管理员可访问）
+func RegisterBalanceReconcileRoutes(r *gin.RouterGroup, controller *controller.BalanceReconcileController, userService *service.UserService) {
+	reconcile := r.Group("/balance-reconcile")
+	reconcile.Use(middleware.CheckSuperAdmin(userService))
+	{
+		reconcile.POST("/run", controller.Run)
+		reconcile.GET("/reports", controller.ListReports)
+		reconcile.GET("/reports/:id", controller.GetReport)
+	}
+}
@@ -14,5 +14,6 @@ func RegisterCallbackRoutes(r *gin.RouterGroup, callbackController *controller.C
 		callback.POST("/mishi/:userid", callbackController.HandleMishiCallback)
 		callback.POST("/dayuanren/:userid", callbackController.HandleDayuanrenCallback)
 		callback.POST("/chongzhi/:userid", callbackController.HandleChongzhiCallback)
+		callback.POST("/generic/:code", callbackController.HandleGenericCallback)
 	}
 }
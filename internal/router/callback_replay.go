@@ -0,0 +1,21 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCallbackReplayRoutes 注册回调日志查看与重放路由（仅管理员可访问）
+func RegisterCallbackReplayRoutes(r *gin.RouterGroup, controller *controller.CallbackReplayController, userService *service.UserService) {
+	callbacks := r.Group("/callback-logs")
+	callbacks.Use(middleware.CheckSuperAdmin(userService))
+	{
+		callbacks.GET("", controller.List)
+		callbacks.GET("/:id", controller.Get)
+		callbacks.POST("/:id/replay", controller.Replay)
+		callbacks.POST("/replay", controller.ReplayBatch)
+	}
+}
@@ -23,5 +23,8 @@ func RegisterExternalAPIKeyRoutes(r *gin.RouterGroup, controller *controller.Ext
 
 		// 更新API密钥状态
 		apiKeys.PUT("/:id/status", controller.UpdateAPIKeyStatus)
+
+		// 更新回调通知重试表
+		apiKeys.PUT("/:id/notify-retry-schedule", controller.UpdateNotifyRetrySchedule)
 	}
 }
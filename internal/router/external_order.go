@@ -20,6 +20,7 @@ func RegisterExternalOrderRoutes(r *gin.RouterGroup, db *gorm.DB) {
 	orderRepo := repository.NewOrderRepository(db)
 	platformRepo := repository.NewPlatformRepository(db)
 	callbackLogRepo := repository.NewCallbackLogRepository(db)
+	attemptRepo := notificationRepo.NewAttemptRepository(db)
 	notificationRepo := notificationRepo.NewRepository(db)
 	queueInstance := queue.NewRedisQueue()
 	balanceLogRepo := repository.NewBalanceLogRepository(db)
@@ -107,6 +108,8 @@ func RegisterExternalOrderRoutes(r *gin.RouterGroup, db *gorm.DB) {
 	externalOrderController := controller.NewExternalOrderController(orderService, productService, externalOrderLogRepo)
 	externalCallbackController := controller.NewExternalCallbackController(orderService, apiKeyRepo, externalOrderLogRepo)
 	externalRefundController := controller.NewExternalRefundController(orderService)
+	externalNotificationController := controller.NewExternalNotificationController(orderService, attemptRepo)
+	externalBalanceController := controller.NewExternalBalanceController(balanceService, userRepo)
 
 	// 注册外部订单API路由（需要认证）
 	externalOrder := r.Group("/external/order")
@@ -115,6 +118,14 @@ func RegisterExternalOrderRoutes(r *gin.RouterGroup, db *gorm.DB) {
 		externalOrder.POST("", externalOrderController.CreateOrder)
 		externalOrder.GET("/query", externalOrderController.GetOrder)
 		externalOrder.POST("/refund", externalRefundController.ProcessRefund)
+		externalOrder.GET("/notifications", externalNotificationController.ListDeliveryAttempts)
+	}
+
+	// 注册余额查询路由（需要认证）
+	externalBalance := r.Group("/external/balance")
+	externalBalance.Use(authMiddleware.ExternalAuth())
+	{
+		externalBalance.GET("", externalBalanceController.GetBalance)
 	}
 
 	// 注册回调路由（不需要认证中间件，但需要签名验证）
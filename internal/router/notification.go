@@ -0,0 +1,21 @@
+package router
+
+import (
+	"recharge-go/internal/handler"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterNotificationRoutes 注册下游通知查看与手动重试路由（仅管理员可访问）
+func RegisterNotificationRoutes(r *gin.RouterGroup, handler *handler.NotificationHandler, userService *service.UserService) {
+	notifications := r.Group("/notifications")
+	notifications.Use(middleware.CheckSuperAdmin(userService))
+	{
+		notifications.GET("", handler.ListNotifications)
+		notifications.GET("/attempts", handler.ListDeliveryAttempts)
+		notifications.GET("/:id", handler.GetNotificationStatus)
+		notifications.POST("/:id/retry", handler.RetryFailedNotification)
+	}
+}
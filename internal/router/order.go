@@ -103,6 +103,8 @@ func RegisterOrderRoutes(r *gin.RouterGroup, userService *service.UserService) {
 		order.GET("/list", orderController.GetOrders)   // 获取订单列表（管理员接口）
 		order.GET("/:id", orderController.GetOrderByID) // 获取订单详情
 		order.POST("", orderController.CreateOrder)     // 创建订单
+		// 获取订单状态流转记录（仅超级管理员）
+		order.GET("/:id/status-history", middleware.CheckSuperAdmin(userService), orderController.GetOrderStatusHistory)
 		// order.PUT("/:id/status", orderController.UpdateOrderStatus)                // 更新订单状态
 		order.GET("/customer/:customer_id", orderController.GetOrdersByCustomerID) // 获取客户订单列表
 		// order.POST("/:id/payment", orderController.ProcessOrderPayment)
@@ -112,7 +114,7 @@ func RegisterOrderRoutes(r *gin.RouterGroup, userService *service.UserService) {
 		// order.POST("/:id/refund", orderController.ProcessOrderRefund)
 		// order.POST("/:id/cancel", orderController.ProcessOrderCancel)
 		// order.POST("/:id/split", orderController.ProcessOrderSplit)
-		// order.POST("/:id/partial", orderController.ProcessOrderPartial)
+		order.POST("/:id/partial", orderController.ProcessOrderPartial)
 		order.POST("/:id/delete", orderController.DeleteOrder)
 
 		// 批量操作接口
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOrderReconcileRoutes 注册滞留订单对账路由（仅管理员可访问）
+func RegisterOrderReconcileRoutes(r *gin.RouterGroup, controller *controller.OrderReconcileController, userService *service.UserService) {
+	reconcile := r.Group("/order-reconcile")
+	reconcile.Use(middleware.CheckSuperAdmin(userService))
+	{
+		reconcile.POST("/run", controller.Run)
+		reconcile.GET("/policies", controller.Policies)
+		reconcile.GET("/reports", controller.ListReports)
+		reconcile.GET("/reports/:id", controller.GetReport)
+	}
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOrderSplitRuleRoutes 注册拆单规则路由（仅管理员可访问）
+func RegisterOrderSplitRuleRoutes(r *gin.RouterGroup, controller *controller.OrderSplitRuleController, userService *service.UserService) {
+	split := r.Group("/order-split")
+	split.Use(middleware.CheckSuperAdmin(userService))
+	{
+		split.GET("/rules", controller.List)
+		split.POST("/rules", controller.Create)
+		split.PUT("/rules/:id", controller.Update)
+		split.DELETE("/rules/:id", controller.Delete)
+		split.GET("/orders/:order_number/children", controller.Children)
+	}
+}
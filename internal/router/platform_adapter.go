@@ -0,0 +1,19 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPlatformAdapterRoutes 注册平台适配器相关路由（仅管理员可访问）
+func RegisterPlatformAdapterRoutes(r *gin.RouterGroup, controller *controller.PlatformAdapterController, userService *service.UserService) {
+	adapters := r.Group("/platform/adapters")
+	adapters.Use(middleware.CheckSuperAdmin(userService))
+	{
+		adapters.GET("", controller.ListAdapters)
+		adapters.POST("/generic/validate", controller.ValidateGenericTemplate)
+	}
+}
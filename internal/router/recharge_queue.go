@@ -0,0 +1,21 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRechargeQueueRoutes 注册充值任务队列路由（仅管理员可访问）
+func RegisterRechargeQueueRoutes(r *gin.RouterGroup, controller *controller.RechargeQueueController, userService *service.UserService) {
+	queue := r.Group("/recharge-queue")
+	queue.Use(middleware.CheckSuperAdmin(userService))
+	{
+		queue.GET("/stats", controller.Stats)
+		queue.GET("/dead", controller.ListDead)
+		queue.POST("/dead/:order_id/requeue", controller.RequeueDead)
+		queue.DELETE("/dead/:order_id", controller.DeleteDead)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"recharge-go/internal/controller"
+	"recharge-go/internal/handler"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
 	"recharge-go/internal/service/platform"
@@ -53,6 +54,14 @@ func SetupRouterV2(
 	statisticsController := getControllerByName(controllersValue, "Statistics")
 	systemConfigController := getControllerByName(controllersValue, "SystemConfig")
 	externalAPIKeyController := getControllerByName(controllersValue, "ExternalAPIKey")
+	platformAdapterController := getControllerByName(controllersValue, "PlatformAdapter")
+	supplierHealthController := getControllerByName(controllersValue, "SupplierHealth")
+	orderSplitRuleController := getControllerByName(controllersValue, "OrderSplitRule")
+	rechargeQueueController := getControllerByName(controllersValue, "RechargeQueue")
+	orderReconcileController := getControllerByName(controllersValue, "OrderReconcile")
+	balanceReconcileController := getControllerByName(controllersValue, "BalanceReconcile")
+	callbackReplayController := getControllerByName(controllersValue, "CallbackReplay")
+	notificationHandler := getControllerByName(controllersValue, "Notification")
 	// userLogController := getControllerByName(controllersValue, "UserLog") // 从参数获取
 
 	// 类型断言
@@ -107,6 +116,7 @@ func SetupRouterV2(
 				callback.POST("/mishi/:userid", cc.HandleMishiCallback)
 				callback.POST("/dayuanren/:userid", cc.HandleDayuanrenCallback)
 				callback.POST("/chongzhi/:userid", cc.HandleChongzhiCallback)
+				callback.POST("/generic/:code", cc.HandleGenericCallback)
 			}
 		}
 
@@ -166,6 +176,46 @@ func SetupRouterV2(
 				RegisterPlatformAPIRoutes(auth, pac, userSvc)
 			}
 
+			// Platform adapter routes
+			if padc := assertPlatformAdapterController(platformAdapterController); padc != nil {
+				RegisterPlatformAdapterRoutes(auth, padc, userSvc)
+			}
+
+			// Supplier health routes
+			if shc := assertSupplierHealthController(supplierHealthController); shc != nil {
+				RegisterSupplierHealthRoutes(auth, shc, userSvc)
+			}
+
+			// Order split rule routes
+			if osrc := assertOrderSplitRuleController(orderSplitRuleController); osrc != nil {
+				RegisterOrderSplitRuleRoutes(auth, osrc, userSvc)
+			}
+
+			// Recharge queue routes
+			if rqc := assertRechargeQueueController(rechargeQueueController); rqc != nil {
+				RegisterRechargeQueueRoutes(auth, rqc, userSvc)
+			}
+
+			// Order reconcile routes
+			if orc := assertOrderReconcileController(orderReconcileController); orc != nil {
+				RegisterOrderReconcileRoutes(auth, orc, userSvc)
+			}
+
+			// Balance reconcile routes
+			if brc := assertBalanceReconcileController(balanceReconcileController); brc != nil {
+				RegisterBalanceReconcileRoutes(auth, brc, userSvc)
+			}
+
+			// Callback replay routes
+			if crc := assertCallbackReplayController(callbackReplayController); crc != nil {
+				RegisterCallbackReplayRoutes(auth, crc, userSvc)
+			}
+
+			// Notification routes
+			if nh := assertNotificationHandler(notificationHandler); nh != nil {
+				RegisterNotificationRoutes(auth, nh, userSvc)
+			}
+
 			// Platform API param routes
 			if papc := assertPlatformAPIParamController(platformAPIParamController); papc != nil {
 				RegisterPlatformAPIParamRoutes(auth, papc, userSvc)
@@ -457,3 +507,83 @@ func assertExternalAPIKeyController(ctrl interface{}) *controller.ExternalAPIKey
 	}
 	return nil
 }
+
+func assertPlatformAdapterController(ctrl interface{}) *controller.PlatformAdapterController {
+	if ctrl == nil {
+		return nil
+	}
+	if padc, ok := ctrl.(*controller.PlatformAdapterController); ok {
+		return padc
+	}
+	return nil
+}
+
+func assertSupplierHealthController(ctrl interface{}) *controller.SupplierHealthController {
+	if ctrl == nil {
+		return nil
+	}
+	if shc, ok := ctrl.(*controller.SupplierHealthController); ok {
+		return shc
+	}
+	return nil
+}
+
+func assertOrderSplitRuleController(ctrl interface{}) *controller.OrderSplitRuleController {
+	if ctrl == nil {
+		return nil
+	}
+	if osrc, ok := ctrl.(*controller.OrderSplitRuleController); ok {
+		return osrc
+	}
+	return nil
+}
+
+func assertRechargeQueueController(ctrl interface{}) *controller.RechargeQueueController {
+	if ctrl == nil {
+		return nil
+	}
+	if rqc, ok := ctrl.(*controller.RechargeQueueController); ok {
+		return rqc
+	}
+	return nil
+}
+
+func assertOrderReconcileController(ctrl interface{}) *controller.OrderReconcileController {
+	if ctrl == nil {
+		return nil
+	}
+	if orc, ok := ctrl.(*controller.OrderReconcileController); ok {
+		return orc
+	}
+	return nil
+}
+
+func assertBalanceReconcileController(ctrl interface{}) *controller.BalanceReconcileController {
+	if ctrl == nil {
+		return nil
+	}
+	if brc, ok := ctrl.(*controller.BalanceReconcileController); ok {
+		return brc
+	}
+	return nil
+}
+
+func assertCallbackReplayController(ctrl interface{}) *controller.CallbackReplayController {
+	if ctrl == nil {
+		return nil
+	}
+	if crc, ok := ctrl.(*controller.CallbackReplayController); ok {
+		return crc
+	}
+	return nil
+}
+
+func assertNotificationHandler(ctrl interface{}) *handler.NotificationHandler {
+	if ctrl == nil {
+		return nil
+	}
+	if nh, ok := ctrl.(*handler.NotificationHandler); ok {
+		return nh
+	}
+	return nil
+}
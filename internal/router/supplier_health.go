@@ -0,0 +1,19 @@
+package router
+
+import (
+	"recharge-go/internal/controller"
+	"recharge-go/internal/middleware"
+	"recharge-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSupplierHealthRoutes 注册上游平台接口健康状况路由（仅管理员可访问）
+func RegisterSupplierHealthRoutes(r *gin.RouterGroup, controller *controller.SupplierHealthController, userService *service.UserService) {
+	health := r.Group("/platform/health")
+	health.Use(middleware.CheckSuperAdmin(userService))
+	{
+		health.GET("", controller.List)
+		health.POST("/:id/reset", controller.Reset)
+	}
+}
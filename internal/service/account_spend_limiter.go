@@ -0,0 +1,439 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"recharge-go/internal/model"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
+	"sync"
+	"time"
+
+	redisV8 "github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// ErrSpendLimitExceeded 平台账号日/月限额已用完
+var ErrSpendLimitExceeded = errors.New("平台账号限额已用完")
+
+// spendWindow 限额统计窗口
+type spendWindow string
+
+const (
+	spendWindowDay   spendWindow = "day"
+	spendWindowMonth spendWindow = "month"
+)
+
+// spendKeyPrefix 已用额度计数器键前缀，键中包含窗口日期，窗口切换后自动使用新计数器
+const spendKeyPrefix = "recharge:spend:"
+
+// reserveSpendScript 同时校验并累加日、月计数器，任一超限则都不累加
+// 返回 0 成功，1 超出日限额，2 超出月限额
+const reserveSpendScript = `
+local amount = tonumber(ARGV[1])
+local day = tonumber(redis.call('GET', KEYS[1]) or '0')
+local month = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[2]) > 0 and day + amount > tonumber(ARGV[2]) then
+	return 1
+end
+if tonumber(ARGV[3]) > 0 and month + amount > tonumber(ARGV[3]) then
+	return 2
+end
+redis.call('INCRBY', KEYS[1], amount)
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+redis.call('INCRBY', KEYS[2], amount)
+redis.call('EXPIRE', KEYS[2], ARGV[5])
+return 0
+`
+
+// releaseSpendScript 回退已累加的额度，计数器不存在时不处理
+const releaseSpendScript = `
+for i, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('DECRBY', key, ARGV[1])
+	end
+end
+return 0
+`
+
+// AccountSpendUsage 平台账号额度使用情况
+type AccountSpendUsage struct {
+	AccountID    int64       `json:"account_id"`
+	DailyLimit   money.Money `json:"daily_limit"`
+	DailySpent   money.Money `json:"daily_spent"`
+	MonthlyLimit money.Money `json:"monthly_limit"`
+	MonthlySpent money.Money `json:"monthly_spent"`
+}
+
+// Remaining 剩余可用额度（日、月中较小者），未设置限额时 limited 为 false
+func (u *AccountSpendUsage) Remaining() (remaining money.Money, limited bool) {
+	remaining = -1
+	if u.DailyLimit > 0 {
+		remaining = u.DailyLimit - u.DailySpent
+		limited = true
+	}
+	if u.MonthlyLimit > 0 {
+		monthly := u.MonthlyLimit - u.MonthlySpent
+		if !limited || monthly < remaining {
+			remaining = monthly
+		}
+		limited = true
+	}
+	return remaining, limited
+}
+
+// AccountSpendLimiter 平台账号日/月限额控制
+// 计数器保存在 Redis 中以保证多进程原子性，首次使用或定期对账时以未释放的额度占用记录为准重建
+type AccountSpendLimiter struct {
+	db     *gorm.DB
+	client *redisV8.Client
+
+	// 未配置 Redis 时使用进程内计数器
+	mu     sync.Mutex
+	memory map[string]int64
+}
+
+// NewAccountSpendLimiter 创建平台账号限额控制器，client 为 nil 时使用进程内计数器
+func NewAccountSpendLimiter(db *gorm.DB, client *redisV8.Client) *AccountSpendLimiter {
+	return &AccountSpendLimiter{
+		db:     db,
+		client: client,
+		memory: make(map[string]int64),
+	}
+}
+
+// Reserve 提交订单前占用账号额度，超出日或月限额时返回 ErrSpendLimitExceeded
+func (l *AccountSpendLimiter) Reserve(ctx context.Context, account *model.PlatformAccount, amount money.Money) error {
+	if !hasSpendLimit(account) || amount <= 0 {
+		return nil
+	}
+	now := time.Now()
+	dayKey, monthKey := spendKey(account.ID, spendWindowDay, now), spendKey(account.ID, spendWindowMonth, now)
+	if err := l.ensure(ctx, account.ID, spendWindowDay, now); err != nil {
+		return err
+	}
+	if err := l.ensure(ctx, account.ID, spendWindowMonth, now); err != nil {
+		return err
+	}
+
+	cents := amount.Cents()
+	dailyLimit, monthlyLimit := account.DailyLimit.Cents(), account.MonthlyLimit.Cents()
+
+	var result int64
+	if l.client != nil {
+		var err error
+		result, err = l.client.Eval(ctx, reserveSpendScript, []string{dayKey, monthKey},
+			cents, dailyLimit, monthlyLimit,
+			int64(spendTTL(spendWindowDay, now).Seconds()),
+			int64(spendTTL(spendWindowMonth, now).Seconds()),
+		).Int64()
+		if err != nil {
+			return fmt.Errorf("占用平台账号额度失败: %v", err)
+		}
+	} else {
+		l.mu.Lock()
+		switch {
+		case dailyLimit > 0 && l.memory[dayKey]+cents > dailyLimit:
+			result = 1
+		case monthlyLimit > 0 && l.memory[monthKey]+cents > monthlyLimit:
+			result = 2
+		default:
+			l.memory[dayKey] += cents
+			l.memory[monthKey] += cents
+		}
+		l.mu.Unlock()
+	}
+
+	switch result {
+	case 1:
+		return fmt.Errorf("%w: 账号 %d 超出每日限额 %s", ErrSpendLimitExceeded, account.ID, account.DailyLimit)
+	case 2:
+		return fmt.Errorf("%w: 账号 %d 超出每月限额 %s", ErrSpendLimitExceeded, account.ID, account.MonthlyLimit)
+	}
+	return nil
+}
+
+// ReserveOrder 为订单占用账号额度并记录占用，释放与对账都以该记录的金额和时间为准
+func (l *AccountSpendLimiter) ReserveOrder(ctx context.Context, account *model.PlatformAccount, orderID int64, amount money.Money) error {
+	if !hasSpendLimit(account) || amount <= 0 {
+		return nil
+	}
+	reservedAt := time.Now()
+	if err := l.Reserve(ctx, account, amount); err != nil {
+		return err
+	}
+	reservation := &model.AccountSpendReservation{
+		OrderID:    orderID,
+		AccountID:  account.ID,
+		Amount:     amount,
+		ReservedAt: reservedAt,
+	}
+	if err := l.db.WithContext(ctx).Create(reservation).Error; err != nil {
+		l.Release(ctx, account.ID, amount, reservedAt)
+		return fmt.Errorf("记录平台账号额度占用失败: %v", err)
+	}
+	return nil
+}
+
+// ReleaseOrder 在调用方事务内释放订单未释放的额度占用，并回退对应窗口的计数器
+// 用于提交失败、订单失败或退款；事务回滚时计数器已回退，由定期对账按占用记录修正
+func (l *AccountSpendLimiter) ReleaseOrder(ctx context.Context, tx *gorm.DB, orderID int64) error {
+	if tx == nil {
+		tx = l.db.WithContext(ctx)
+	}
+	var reservations []*model.AccountSpendReservation
+	if err := tx.Where("order_id = ? AND released_at IS NULL", orderID).Find(&reservations).Error; err != nil {
+		return fmt.Errorf("获取平台账号额度占用失败: %v", err)
+	}
+	now := time.Now()
+	for _, r := range reservations {
+		// 并发释放时只有一方更新成功并回退计数器
+		result := tx.Model(&model.AccountSpendReservation{}).
+			Where("id = ? AND released_at IS NULL", r.ID).
+			Update("released_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("释放平台账号额度占用失败: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			l.Release(ctx, r.AccountID, r.Amount, r.ReservedAt)
+		}
+	}
+	return nil
+}
+
+// Release 回退 at 时刻占用的额度计数
+func (l *AccountSpendLimiter) Release(ctx context.Context, accountID int64, amount money.Money, at time.Time) {
+	if accountID <= 0 || amount <= 0 {
+		return
+	}
+	cents := amount.Cents()
+	keys := []string{spendKey(accountID, spendWindowDay, at), spendKey(accountID, spendWindowMonth, at)}
+	if l.client != nil {
+		if err := l.client.Eval(ctx, releaseSpendScript, keys, cents).Err(); err != nil {
+			logger.Error("回退平台账号额度失败", "account_id", accountID, "amount", amount, "error", err)
+		}
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.memory[key]; ok {
+			l.memory[key] -= cents
+		}
+	}
+}
+
+// Usage 查询账号当前窗口的额度使用情况
+func (l *AccountSpendLimiter) Usage(ctx context.Context, account *model.PlatformAccount) (*AccountSpendUsage, error) {
+	usage := &AccountSpendUsage{
+		AccountID:    account.ID,
+		DailyLimit:   account.DailyLimit,
+		MonthlyLimit: account.MonthlyLimit,
+	}
+	now := time.Now()
+	var err error
+	if usage.DailySpent, err = l.spent(ctx, account.ID, spendWindowDay, now); err != nil {
+		return nil, err
+	}
+	if usage.MonthlySpent, err = l.spent(ctx, account.ID, spendWindowMonth, now); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// Remaining 账号剩余可用额度，查询失败时视为未限额，由提交时的 Reserve 兜底
+func (l *AccountSpendLimiter) Remaining(ctx context.Context, account *model.PlatformAccount) (remaining money.Money, limited bool) {
+	if !hasSpendLimit(account) {
+		return -1, false
+	}
+	usage, err := l.Usage(ctx, account)
+	if err != nil {
+		logger.Error("查询平台账号额度失败", "account_id", account.ID, "error", err)
+		return -1, false
+	}
+	return usage.Remaining()
+}
+
+// Reconcile 以未释放的额度占用记录重建所有设置了限额的账号当前窗口计数器
+func (l *AccountSpendLimiter) Reconcile(ctx context.Context) error {
+	var accounts []*model.PlatformAccount
+	if err := l.db.WithContext(ctx).
+		Where("daily_limit > 0 OR monthly_limit > 0").
+		Find(&accounts).Error; err != nil {
+		return fmt.Errorf("获取限额账号失败: %v", err)
+	}
+
+	now := time.Now()
+	for _, account := range accounts {
+		for _, window := range []spendWindow{spendWindowDay, spendWindowMonth} {
+			cents, err := l.dbSpend(ctx, account.ID, windowStart(window, now))
+			if err != nil {
+				logger.Error("统计平台账号已用额度失败", "account_id", account.ID, "window", window, "error", err)
+				continue
+			}
+			key := spendKey(account.ID, window, now)
+			current, err := l.counter(ctx, key)
+			if err != nil {
+				logger.Error("读取平台账号额度计数器失败", "account_id", account.ID, "window", window, "error", err)
+				continue
+			}
+			if current == cents {
+				continue
+			}
+			logger.Info("平台账号额度计数器对账修正",
+				"account_id", account.ID,
+				"window", window,
+				"counter", money.FromCents(current),
+				"reserved", money.FromCents(cents),
+			)
+			if err := l.setCounter(ctx, key, cents, spendTTL(window, now)); err != nil {
+				logger.Error("修正平台账号额度计数器失败", "account_id", account.ID, "window", window, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// RunReconciler 按间隔定期对账，直到 ctx 结束
+func (l *AccountSpendLimiter) RunReconciler(ctx context.Context, interval time.Duration) {
+	logger.Info("平台账号额度对账任务启动", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("平台账号额度对账任务停止")
+			return
+		case <-ticker.C:
+			if err := l.Reconcile(ctx); err != nil {
+				logger.Error("平台账号额度对账失败", "error", err)
+			}
+		}
+	}
+}
+
+// spent 读取计数器，不存在时先从数据库初始化
+func (l *AccountSpendLimiter) spent(ctx context.Context, accountID int64, window spendWindow, now time.Time) (money.Money, error) {
+	if err := l.ensure(ctx, accountID, window, now); err != nil {
+		return 0, err
+	}
+	cents, err := l.counter(ctx, spendKey(accountID, window, now))
+	if err != nil {
+		return 0, err
+	}
+	return money.FromCents(cents), nil
+}
+
+// ensure 计数器不存在时以数据库中未释放的额度占用初始化
+func (l *AccountSpendLimiter) ensure(ctx context.Context, accountID int64, window spendWindow, now time.Time) error {
+	key := spendKey(accountID, window, now)
+	if l.client != nil {
+		exists, err := l.client.Exists(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("读取平台账号额度计数器失败: %v", err)
+		}
+		if exists > 0 {
+			return nil
+		}
+	} else {
+		l.mu.Lock()
+		_, exists := l.memory[key]
+		l.mu.Unlock()
+		if exists {
+			return nil
+		}
+	}
+
+	cents, err := l.dbSpend(ctx, accountID, windowStart(window, now))
+	if err != nil {
+		return fmt.Errorf("统计平台账号已用额度失败: %v", err)
+	}
+	if l.client != nil {
+		// 多个进程同时初始化时只保留第一个
+		if err := l.client.SetNX(ctx, key, cents, spendTTL(window, now)).Err(); err != nil {
+			return fmt.Errorf("初始化平台账号额度计数器失败: %v", err)
+		}
+		return nil
+	}
+	l.mu.Lock()
+	if _, exists := l.memory[key]; !exists {
+		l.memory[key] = cents
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// counter 读取计数器当前值
+func (l *AccountSpendLimiter) counter(ctx context.Context, key string) (int64, error) {
+	if l.client != nil {
+		value, err := l.client.Get(ctx, key).Int64()
+		if err == redisV8.Nil {
+			return 0, nil
+		}
+		return value, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.memory[key], nil
+}
+
+// setCounter 覆盖计数器
+func (l *AccountSpendLimiter) setCounter(ctx context.Context, key string, cents int64, ttl time.Duration) error {
+	if l.client != nil {
+		return l.client.Set(ctx, key, cents, ttl).Err()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.memory[key] = cents
+	return nil
+}
+
+// dbSpend 统计账号自 since 起占用且未释放的额度（分）
+func (l *AccountSpendLimiter) dbSpend(ctx context.Context, accountID int64, since time.Time) (int64, error) {
+	var amounts []money.Money
+	err := l.db.WithContext(ctx).Model(&model.AccountSpendReservation{}).
+		Where("account_id = ? AND released_at IS NULL AND reserved_at >= ?", accountID, since).
+		Pluck("amount", &amounts).Error
+	if err != nil {
+		return 0, err
+	}
+	var cents int64
+	for _, amount := range amounts {
+		cents += amount.Cents()
+	}
+	return cents, nil
+}
+
+// hasSpendLimit 账号是否设置了日或月限额
+func hasSpendLimit(account *model.PlatformAccount) bool {
+	return account != nil && (account.DailyLimit > 0 || account.MonthlyLimit > 0)
+}
+
+// spendKey 计数器键，如 recharge:spend:12:day:20260101
+func spendKey(accountID int64, window spendWindow, at time.Time) string {
+	layout := "20060102"
+	if window == spendWindowMonth {
+		layout = "200601"
+	}
+	return fmt.Sprintf("%s%d:%s:%s", spendKeyPrefix, accountID, window, at.Format(layout))
+}
+
+// windowStart 窗口起始时间
+func windowStart(window spendWindow, at time.Time) time.Time {
+	if window == spendWindowMonth {
+		return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+	}
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+}
+
+// spendTTL 计数器过期时间，窗口结束后再保留一天便于排查
+func spendTTL(window spendWindow, at time.Time) time.Duration {
+	start := windowStart(window, at)
+	end := start.AddDate(0, 0, 1)
+	if window == spendWindowMonth {
+		end = start.AddDate(0, 1, 0)
+	}
+	return end.Sub(at) + 24*time.Hour
+}
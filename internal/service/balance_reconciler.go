@@ -0,0 +1,679 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 资金对账触发方式
+const (
+	BalanceReconcileSourceSchedule = "schedule" // 定时任务
+	BalanceReconcileSourceManual   = "manual"   // 后台手动触发
+)
+
+// balanceReconcileBatchSize 每批核对的用户、平台账号或订单数
+const balanceReconcileBatchSize = 200
+
+// BalanceReconcileConfig 资金对账配置
+type BalanceReconcileConfig struct {
+	Interval      time.Duration // 定时对账间隔
+	OrderLookback time.Duration // 核对最近该时长内更新的终态订单
+	AutoCorrect   bool          // 定时对账是否自动修正余额差异
+}
+
+// DefaultBalanceReconcileConfig 默认资金对账配置：每小时核对一次，核对近48小时完结的订单，只报告不修正
+func DefaultBalanceReconcileConfig() BalanceReconcileConfig {
+	return BalanceReconcileConfig{
+		Interval:      time.Hour,
+		OrderLookback: 48 * time.Hour,
+	}
+}
+
+// BalanceReconciler 资金对账
+// 按余额流水、授信日志重算每个用户的余额与授信额度，按冻结中的订单冻结款重算冻结金额，
+// 按余额流水重算每个平台账号的消耗，与存储值及账本科目余额比对；
+// 并核对终态订单恰有一笔扣款（或冻结）、至多一笔退款（或退回），冻结款均已结清。
+// 开启自动修正时，以流水重算值为准记一张对账修正凭证并重写余额缓存；订单异常只报告，需人工处理
+type BalanceReconciler struct {
+	db         *gorm.DB
+	ledger     *Ledger
+	reportRepo *repository.BalanceReconcileReportRepository
+	config     BalanceReconcileConfig
+}
+
+// NewBalanceReconciler 创建资金对账
+func NewBalanceReconciler(db *gorm.DB, reportRepo *repository.BalanceReconcileReportRepository, config BalanceReconcileConfig) *BalanceReconciler {
+	if config.OrderLookback <= 0 {
+		config.OrderLookback = DefaultBalanceReconcileConfig().OrderLookback
+	}
+	return &BalanceReconciler{
+		db:         db,
+		ledger:     NewLedger(db),
+		reportRepo: reportRepo,
+		config:     config,
+	}
+}
+
+// Run 按间隔定时对账，直到 ctx 结束
+func (r *BalanceReconciler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = r.config.Interval
+	}
+	logger.Info("资金对账任务启动", "interval", interval, "auto_correct", r.config.AutoCorrect)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("资金对账任务停止")
+			return
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx, BalanceReconcileSourceSchedule, r.config.AutoCorrect); err != nil {
+				logger.Error("资金对账失败", "error", err)
+			}
+		}
+	}
+}
+
+// Reconcile 执行一轮资金对账，autoCorrect 为 true 时修正余额类差异
+// 定时对账未发现差异时不保存报告
+func (r *BalanceReconciler) Reconcile(ctx context.Context, source string, autoCorrect bool) (*model.BalanceReconcileReport, error) {
+	report := &model.BalanceReconcileReport{Source: source, AutoCorrect: autoCorrect, StartedAt: time.Now()}
+
+	// 账本启用前的平台账号扣款、订单扣款没有凭证，只核对启用之后的部分
+	epoch, err := r.ledgerEpoch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []model.BalanceDriftItem
+	collect := func(found []model.BalanceDriftItem) {
+		for _, item := range found {
+			countBalanceDrift(report, item)
+		}
+		items = append(items, found...)
+	}
+
+	var users []model.User
+	err = r.db.WithContext(ctx).Select("id").FindInBatches(&users, balanceReconcileBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, user := range users {
+			report.UsersScanned++
+			collect(r.reconcileUser(ctx, user.ID, autoCorrect))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("scan users failed: %v", err)
+	}
+
+	var accounts []model.PlatformAccount
+	err = r.db.WithContext(ctx).Select("id").FindInBatches(&accounts, balanceReconcileBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, account := range accounts {
+			report.AccountsScanned++
+			collect(r.reconcileAccount(ctx, account.ID, epoch, autoCorrect))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("scan platform accounts failed: %v", err)
+	}
+
+	var orders []*model.Order
+	err = r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at >= ?", balanceReconcileOrderStatuses, report.StartedAt.Add(-r.config.OrderLookback)).
+		Where("apart_order_number = '' OR apart_order_number IS NULL").
+		FindInBatches(&orders, balanceReconcileBatchSize, func(_ *gorm.DB, _ int) error {
+			var checked []*model.Order
+			for _, order := range orders {
+				if !orderCreateTime(order).Before(epoch) {
+					checked = append(checked, order)
+				}
+			}
+			report.OrdersScanned += len(checked)
+			found, err := r.reconcileOrders(ctx, checked)
+			if err != nil {
+				return err
+			}
+			collect(found)
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("scan terminal orders failed: %v", err)
+	}
+
+	report.FinishedAt = time.Now()
+	if items == nil {
+		items = []model.BalanceDriftItem{}
+	}
+	data, _ := json.Marshal(items)
+	report.Items = string(data)
+
+	logger.Info("资金对账完成",
+		"source", source,
+		"auto_correct", autoCorrect,
+		"users", report.UsersScanned,
+		"accounts", report.AccountsScanned,
+		"orders", report.OrdersScanned,
+		"balance_drifts", report.BalanceDrifts,
+		"credit_drifts", report.CreditDrifts,
+		"account_drifts", report.AccountDrifts,
+		"order_issues", report.OrderIssues,
+		"corrected", report.Corrected,
+		"errors", report.Errors)
+
+	if source == BalanceReconcileSourceSchedule && len(items) == 0 {
+		return report, nil
+	}
+	if err := r.reportRepo.Create(ctx, report); err != nil {
+		return report, fmt.Errorf("save balance reconcile report failed: %v", err)
+	}
+	return report, nil
+}
+
+// ledgerEpoch 账本启用时间，取第一张凭证的记账时间；尚无凭证时取当前时间
+func (r *BalanceReconciler) ledgerEpoch(ctx context.Context) (time.Time, error) {
+	var first model.LedgerTransaction
+	if err := r.db.WithContext(ctx).Order("id").Limit(1).Find(&first).Error; err != nil {
+		return time.Time{}, fmt.Errorf("get first ledger transaction failed: %v", err)
+	}
+	if first.ID == 0 {
+		return time.Now(), nil
+	}
+	return first.CreatedAt, nil
+}
+
+// reconcileUser 在同一事务内读取用户余额、授信额度、流水与账本，保证比对基于一致的快照
+func (r *BalanceReconciler) reconcileUser(ctx context.Context, userID int64, autoCorrect bool) []model.BalanceDriftItem {
+	var items []model.BalanceDriftItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, balance, credit, frozen_balance").Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		expectedBalance, err := r.balanceFromLogs(tx, userID)
+		if err != nil {
+			return err
+		}
+		expectedCredit, err := r.creditFromLogs(tx, userID)
+		if err != nil {
+			return err
+		}
+		expectedFrozen, err := r.frozenFromHolds(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		checks := []struct {
+			kind     string
+			column   string
+			account  string
+			stored   money.Money
+			expected money.Money
+		}{
+			{model.BalanceDriftUserBalance, "balance", model.UserBalanceAccount(userID), user.Balance, expectedBalance},
+			{model.BalanceDriftUserCredit, "credit", model.UserCreditAccount(userID), user.Credit, expectedCredit},
+			{model.BalanceDriftUserFrozen, "frozen_balance", model.UserFrozenAccount(userID), user.FrozenBalance, expectedFrozen},
+		}
+		for _, c := range checks {
+			ledger, err := entrySum(tx.Where("account = ?", c.account))
+			if err != nil {
+				return err
+			}
+			if c.stored == c.expected && ledger == c.stored {
+				continue
+			}
+
+			item := model.BalanceDriftItem{
+				Kind:     c.kind,
+				UserID:   userID,
+				Stored:   c.stored,
+				Expected: c.expected,
+				Ledger:   ledger,
+				Drift:    c.stored - c.expected,
+				Issue:    driftIssue(c.stored, c.expected, ledger),
+			}
+			if autoCorrect {
+				// 单项修正失败只回滚该项，不影响同一用户的其他核对
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					return r.correctUser(tx, userID, c.column, c.account, c.expected, ledger)
+				})
+				if err != nil {
+					item.Error = err.Error()
+				} else {
+					item.Corrected = true
+				}
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("核对用户余额失败", "user_id", userID, "error", err)
+		return []model.BalanceDriftItem{{Kind: model.BalanceDriftUserBalance, UserID: userID, Issue: "核对失败", Error: err.Error()}}
+	}
+	for _, item := range items {
+		logger.Error("资金对账发现用户余额差异", "user_id", userID, "kind", item.Kind,
+			"stored", item.Stored, "expected", item.Expected, "ledger", item.Ledger, "corrected", item.Corrected)
+	}
+	return items
+}
+
+// correctUser 以流水重算值为准修正用户余额、授信额度或冻结金额：账本差额记对账修正凭证，再重写缓存列
+func (r *BalanceReconciler) correctUser(tx *gorm.DB, userID int64, column, account string, expected, ledger money.Money) error {
+	if delta := expected - ledger; delta != 0 {
+		if _, err := r.ledger.Post(tx, &LedgerJournal{
+			Kind:     model.LedgerKindReconcile,
+			UserID:   userID,
+			Remark:   "资金对账修正",
+			Operator: "system",
+			Postings: []LedgerPosting{
+				{Account: account, UserID: userID, Amount: delta},
+				{Account: model.LedgerAccountReconcile, Amount: -delta},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return tx.Model(&model.User{}).Where("id = ?", userID).Update(column, expected).Error
+}
+
+// balanceFromLogs 按余额流水重算余额：首条流水的变动前余额加全部变动金额；无流水时取期初凭证
+func (r *BalanceReconciler) balanceFromLogs(tx *gorm.DB, userID int64) (money.Money, error) {
+	var first model.BalanceLog
+	if err := tx.Where("user_id = ?", userID).Order("id").Limit(1).Find(&first).Error; err != nil {
+		return 0, err
+	}
+	if first.ID == 0 {
+		return r.openingBalance(tx, model.UserBalanceAccount(userID))
+	}
+
+	var sum money.Money
+	err := tx.Model(&model.BalanceLog{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return first.BalanceBefore + sum, err
+}
+
+// creditFromLogs 按授信日志重算授信额度：最近一次设置后的额度加其后的恢复、减其后的使用；
+// 从未设置过时以首条日志的变动前额度为起点，无日志时取期初凭证
+func (r *BalanceReconciler) creditFromLogs(tx *gorm.DB, userID int64) (money.Money, error) {
+	changes := tx.Model(&model.CreditLog{}).Where("user_id = ?", userID)
+
+	var start money.Money
+	var last model.CreditLog
+	if err := tx.Where("user_id = ? AND type = ?", userID, model.CreditTypeSet).Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return 0, err
+	}
+	if last.ID > 0 {
+		start = last.CreditAfter
+		changes = changes.Where("id > ?", last.ID)
+	} else {
+		var first model.CreditLog
+		if err := tx.Where("user_id = ?", userID).Order("id").Limit(1).Find(&first).Error; err != nil {
+			return 0, err
+		}
+		if first.ID == 0 {
+			return r.openingBalance(tx, model.UserCreditAccount(userID))
+		}
+		start = first.CreditBefore
+	}
+
+	var sum money.Money
+	err := changes.
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN amount WHEN type = ? THEN -amount ELSE 0 END), 0)", model.CreditTypeRestore, model.CreditTypeUse).
+		Scan(&sum).Error
+	return start + sum, err
+}
+
+// frozenFromHolds 按冻结中的订单冻结款重算冻结金额
+func (r *BalanceReconciler) frozenFromHolds(tx *gorm.DB, userID int64) (money.Money, error) {
+	var sum money.Money
+	err := tx.Model(&model.BalanceHold{}).
+		Where("user_id = ? AND status = ?", userID, model.BalanceHoldStatusHeld).
+		Select("COALESCE(SUM(amount - captured - released), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+// openingBalance 科目的期初余额
+func (r *BalanceReconciler) openingBalance(tx *gorm.DB, account string) (money.Money, error) {
+	return entrySum(tx.Joins("JOIN ledger_transactions t ON t.id = ledger_entries.transaction_id").
+		Where("t.kind = ? AND ledger_entries.account = ?", model.LedgerKindOpening, account))
+}
+
+// reconcileAccount 核对平台账号消耗：账本启用后经该账号的订单扣款减退款，应等于账本消耗科目余额
+func (r *BalanceReconciler) reconcileAccount(ctx context.Context, accountID int64, epoch time.Time, autoCorrect bool) []model.BalanceDriftItem {
+	var items []model.BalanceDriftItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var logged money.Money
+		err := tx.Model(&model.BalanceLog{}).
+			Where("platform_account_id = ? AND order_id > 0 AND style IN ? AND created_at >= ?",
+				accountID, []int{model.BalanceStyleOrderDeduct, model.BalanceStyleRefund}, epoch).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&logged).Error
+		if err != nil {
+			return err
+		}
+		// 扣款流水为负、退款流水为正，消耗取相反数
+		expected := -logged
+
+		account := model.PlatformAccountSpendAccount(accountID)
+		ledger, err := entrySum(tx.Where("account = ?", account))
+		if err != nil {
+			return err
+		}
+		if ledger == expected {
+			return nil
+		}
+
+		item := model.BalanceDriftItem{
+			Kind:              model.BalanceDriftPlatformAccount,
+			PlatformAccountID: accountID,
+			Stored:            ledger,
+			Expected:          expected,
+			Ledger:            ledger,
+			Drift:             ledger - expected,
+			Issue:             "平台账号消耗与余额流水不符",
+		}
+		if autoCorrect {
+			delta := expected - ledger
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				_, err := r.ledger.Post(tx, &LedgerJournal{
+					Kind:     model.LedgerKindReconcile,
+					Remark:   "资金对账修正",
+					Operator: "system",
+					Postings: []LedgerPosting{
+						{Account: account, PlatformAccountID: accountID, Amount: delta},
+						{Account: model.LedgerAccountReconcile, PlatformAccountID: accountID, Amount: -delta},
+					},
+				})
+				return err
+			})
+			if err != nil {
+				item.Error = err.Error()
+			} else {
+				item.Corrected = true
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		logger.Error("核对平台账号消耗失败", "platform_account_id", accountID, "error", err)
+		return []model.BalanceDriftItem{{Kind: model.BalanceDriftPlatformAccount, PlatformAccountID: accountID, Issue: "核对失败", Error: err.Error()}}
+	}
+	for _, item := range items {
+		logger.Error("资金对账发现平台账号消耗差异", "platform_account_id", accountID,
+			"ledger", item.Ledger, "expected", item.Expected, "corrected", item.Corrected)
+	}
+	return items
+}
+
+// balanceReconcileOrderStatuses 参与扣退款核对的终态
+var balanceReconcileOrderStatuses = []model.OrderStatus{
+	model.OrderStatusSuccess,
+	model.OrderStatusFailed,
+	model.OrderStatusRefunded,
+	model.OrderStatusCancelled,
+	model.OrderStatusPartial,
+}
+
+// orderPostingSummary 订单某类凭证的笔数与金额
+type orderPostingSummary struct {
+	OrderID int64
+	Kind    string
+	Txns    int
+	Amount  money.Money
+}
+
+// add 合并两类凭证的笔数与金额
+func (s orderPostingSummary) add(other orderPostingSummary) orderPostingSummary {
+	s.Txns += other.Txns
+	s.Amount += other.Amount
+	return s
+}
+
+// reconcileOrders 核对一批终态订单的扣款、退款凭证
+func (r *BalanceReconciler) reconcileOrders(ctx context.Context, orders []*model.Order) ([]model.BalanceDriftItem, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	children, err := r.splitChildren(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+		for _, child := range children[order.OrderNumber] {
+			ids = append(ids, child.ID)
+		}
+	}
+
+	// 凭证金额取借方合计：扣款为订单收入或平台账号消耗，冻结为冻结科目的增加，
+	// 退款与退回为用户余额、授信的增加，扣下冻结款为订单收入
+	var rows []orderPostingSummary
+	err = r.db.WithContext(ctx).Table("ledger_transactions t").
+		Select("t.order_id, t.kind, COUNT(DISTINCT t.id) AS txns, COALESCE(SUM(CASE WHEN e.amount > 0 THEN e.amount ELSE 0 END), 0) AS amount").
+		Joins("JOIN ledger_entries e ON e.transaction_id = t.id").
+		Where("t.order_id IN ? AND t.kind IN ?", ids, []string{
+			model.LedgerKindOrderDeduct, model.LedgerKindRefund,
+			model.LedgerKindHold, model.LedgerKindCapture, model.LedgerKindRelease,
+		}).
+		Group("t.order_id, t.kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("summarize order postings failed: %v", err)
+	}
+	postings := make(map[int64]map[string]orderPostingSummary)
+	for _, row := range rows {
+		if postings[row.OrderID] == nil {
+			postings[row.OrderID] = make(map[string]orderPostingSummary)
+		}
+		postings[row.OrderID][row.Kind] = row
+	}
+
+	var items []model.BalanceDriftItem
+	for _, order := range orders {
+		kinds := postings[order.ID]
+		var deduct, refund orderPostingSummary
+		var issue string
+		if order.IsSplitParent() {
+			deduct, refund, issue = splitPostingIssue(order, children[order.OrderNumber], postings)
+		} else {
+			deduct, refund = orderPostings(kinds)
+			issue = orderPostingIssue(order, deduct, refund)
+		}
+		if issue == "" {
+			issue = holdSettleIssue(kinds[model.LedgerKindHold], kinds[model.LedgerKindCapture], kinds[model.LedgerKindRelease])
+		}
+		if issue == "" {
+			continue
+		}
+		logger.Error("资金对账发现订单扣退款异常", "order_id", order.ID, "status", order.Status,
+			"deducts", deduct.Txns, "refunds", refund.Txns, "issue", issue)
+		items = append(items, model.BalanceDriftItem{
+			Kind:        model.BalanceDriftOrder,
+			UserID:      order.CustomerID,
+			OrderID:     order.ID,
+			OrderNumber: order.OrderNumber,
+			Status:      order.Status,
+			Stored:      deduct.Amount - refund.Amount,
+			Expected:    order.Price,
+			Deducts:     deduct.Txns,
+			Refunds:     refund.Txns,
+			Issue:       issue,
+		})
+	}
+	return items, nil
+}
+
+// splitChildren 查询一批订单中拆单父订单的子订单，按父订单号分组
+func (r *BalanceReconciler) splitChildren(ctx context.Context, orders []*model.Order) (map[string][]*model.Order, error) {
+	var parents []string
+	for _, order := range orders {
+		if order.IsSplitParent() {
+			parents = append(parents, order.OrderNumber)
+		}
+	}
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	var children []*model.Order
+	if err := r.db.WithContext(ctx).Where("apart_order_number IN ?", parents).Order("id ASC").Find(&children).Error; err != nil {
+		return nil, fmt.Errorf("get split children failed: %v", err)
+	}
+	grouped := make(map[string][]*model.Order, len(parents))
+	for _, child := range children {
+		grouped[child.ApartOrderNumber] = append(grouped[child.ApartOrderNumber], child)
+	}
+	return grouped, nil
+}
+
+// orderPostings 汇总订单的扣款与退款凭证，冻结视同扣款、退回视同退款，冻结款另行核对是否结清
+func orderPostings(kinds map[string]orderPostingSummary) (deduct, refund orderPostingSummary) {
+	deduct = kinds[model.LedgerKindOrderDeduct].add(kinds[model.LedgerKindHold])
+	refund = kinds[model.LedgerKindRefund].add(kinds[model.LedgerKindRelease])
+	return deduct, refund
+}
+
+// splitPostingIssue 合并核对拆单父订单与子订单的扣款、退款，返回合计的扣退款与异常描述
+// 外部订单的冻结款记在父订单上、拆单时扣下，平台订单由子订单提交时各自扣款；
+// 子订单失败或部分充值时各自退款，父订单本身不应退款
+func splitPostingIssue(parent *model.Order, children []*model.Order, postings map[int64]map[string]orderPostingSummary) (deduct, refund orderPostingSummary, issue string) {
+	deduct, refund = orderPostings(postings[parent.ID])
+	switch {
+	case deduct.Txns > 1:
+		return deduct, refund, fmt.Sprintf("重复扣款%d笔", deduct.Txns)
+	case deduct.Txns == 1 && deduct.Amount != parent.Price:
+		return deduct, refund, fmt.Sprintf("扣款金额%s与订单金额%s不符", deduct.Amount, parent.Price)
+	case refund.Txns > 0:
+		return deduct, refund, "拆单父订单存在退款"
+	case len(children) == 0:
+		return deduct, refund, "拆单父订单缺少子订单"
+	}
+
+	parentPaid := deduct.Txns == 1
+	for _, child := range children {
+		childDeduct, childRefund := orderPostings(postings[child.ID])
+		deduct = deduct.add(childDeduct)
+		refund = refund.add(childRefund)
+		if issue != "" {
+			continue
+		}
+		if parentPaid {
+			if childDeduct.Txns > 0 {
+				issue = fmt.Sprintf("父订单已扣款，子订单%s重复扣款", child.OrderNumber)
+				continue
+			}
+			// 子订单的款项已在父订单扣下，视同子订单扣款核对退款
+			childDeduct = orderPostingSummary{Txns: 1, Amount: child.Price}
+		}
+		if childIssue := orderPostingIssue(child, childDeduct, childRefund); childIssue != "" {
+			issue = fmt.Sprintf("子订单%s%s", child.OrderNumber, childIssue)
+		}
+	}
+	return deduct, refund, issue
+}
+
+// orderPostingIssue 判断终态订单的扣款、退款是否异常，正常时返回空字符串
+// 订单恰有一笔与订单金额一致的扣款、至多一笔不超过扣款金额的退款；
+// 失败、取消的订单可能未扣款，扣过款则必须退款；成功的订单不应退款
+func orderPostingIssue(order *model.Order, deduct, refund orderPostingSummary) string {
+	switch {
+	case deduct.Txns > 1:
+		return fmt.Sprintf("重复扣款%d笔", deduct.Txns)
+	case refund.Txns > 1:
+		return fmt.Sprintf("重复退款%d笔", refund.Txns)
+	case deduct.Txns == 0 && refund.Txns > 0:
+		return "未扣款却有退款"
+	case deduct.Txns == 0:
+		if order.Status == model.OrderStatusFailed || order.Status == model.OrderStatusCancelled {
+			return ""
+		}
+		return "缺少扣款"
+	case deduct.Amount != order.Price:
+		return fmt.Sprintf("扣款金额%s与订单金额%s不符", deduct.Amount, order.Price)
+	case refund.Amount > deduct.Amount:
+		return fmt.Sprintf("退款金额%s超过扣款金额%s", refund.Amount, deduct.Amount)
+	}
+
+	switch order.Status {
+	case model.OrderStatusFailed, model.OrderStatusCancelled, model.OrderStatusRefunded:
+		if refund.Txns == 0 {
+			return "已扣款但未退款"
+		}
+	case model.OrderStatusSuccess:
+		if refund.Txns > 0 {
+			return "充值成功的订单存在退款"
+		}
+	}
+	return ""
+}
+
+// holdSettleIssue 判断终态订单的冻结款是否已结清：扣下与退回的金额之和应等于冻结金额
+func holdSettleIssue(hold, capture, release orderPostingSummary) string {
+	if hold.Txns == 0 || capture.Amount+release.Amount == hold.Amount {
+		return ""
+	}
+	return fmt.Sprintf("冻结金额未结清：冻结%s，扣款%s，退回%s", hold.Amount, capture.Amount, release.Amount)
+}
+
+// entrySum 汇总满足条件的分录金额
+func entrySum(query *gorm.DB) (money.Money, error) {
+	var sum money.Money
+	err := query.Model(&model.LedgerEntry{}).
+		Select("COALESCE(SUM(ledger_entries.amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+// driftIssue 描述余额差异的来源
+func driftIssue(stored, expected, ledger money.Money) string {
+	switch {
+	case stored == expected:
+		return "账本与余额不符"
+	case ledger == stored:
+		return "余额与流水不符"
+	case ledger == expected:
+		return "余额被绕过账本修改"
+	default:
+		return "余额、流水与账本均不一致"
+	}
+}
+
+// countBalanceDrift 累计报告中各类差异的数量
+func countBalanceDrift(report *model.BalanceReconcileReport, item model.BalanceDriftItem) {
+	switch item.Kind {
+	case model.BalanceDriftUserBalance:
+		report.BalanceDrifts++
+	case model.BalanceDriftUserCredit:
+		report.CreditDrifts++
+	case model.BalanceDriftUserFrozen:
+		report.BalanceDrifts++
+	case model.BalanceDriftPlatformAccount:
+		report.AccountDrifts++
+	case model.BalanceDriftOrder:
+		report.OrderIssues++
+	}
+	if item.Error != "" {
+		report.Errors++
+	}
+	if item.Corrected {
+		report.Corrected++
+	}
+}
@@ -3,19 +3,24 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
+	"recharge-go/pkg/money"
 	"time"
-	
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // BalanceService 余额相关业务逻辑
+// 所有余额变动都通过账本记账，users.balance、users.credit、users.frozen_balance 由账本同步更新
 
 type BalanceService struct {
-	repo         *repository.BalanceLogRepository
-	userRepo     *repository.UserRepository
-	db           *gorm.DB
+	repo          *repository.BalanceLogRepository
+	userRepo      *repository.UserRepository
+	db            *gorm.DB
+	ledger        *Ledger
 	creditService *CreditService
 }
 
@@ -24,249 +29,536 @@ func NewBalanceService(repo *repository.BalanceLogRepository, userRepo *reposito
 		repo:     repo,
 		userRepo: userRepo,
 		db:       repo.GetDB(), // 需要添加GetDB方法
+		ledger:   NewLedger(repo.GetDB()),
 	}
 }
 
 // NewBalanceServiceWithCredit 创建带授信功能的余额服务
 func NewBalanceServiceWithCredit(repo *repository.BalanceLogRepository, userRepo *repository.UserRepository, creditService *CreditService) *BalanceService {
 	return &BalanceService{
-		repo:         repo,
-		userRepo:     userRepo,
-		db:           repo.GetDB(),
+		repo:          repo,
+		userRepo:      userRepo,
+		db:            repo.GetDB(),
+		ledger:        NewLedger(repo.GetDB()),
 		creditService: creditService,
 	}
 }
 
 // Recharge 余额充值
-func (s *BalanceService) Recharge(ctx context.Context, userID int64, amount float64, remark, operator string) error {
+func (s *BalanceService) Recharge(ctx context.Context, userID int64, amount money.Money, remark, operator string) error {
 	if amount <= 0 {
 		return errors.New("充值金额必须大于0")
 	}
-	// 获取充值前余额
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return err
-	}
-	before := user.Balance
-	// 增加余额
-	if err := s.repo.AddBalance(ctx, userID, amount); err != nil {
-		return err
-	}
-	// 写入流水
-	log := &model.BalanceLog{
-		UserID:        userID,
-		Amount:        amount,
-		Type:          1, // 收入
-		Style:         4, // 充值
-		Balance:       before + amount,
-		BalanceBefore: before,
-		Remark:        remark,
-		Operator:      operator,
-		CreatedAt:     time.Now(),
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.ledger.Post(tx, &LedgerJournal{
+			Kind:           model.LedgerKindRecharge,
+			UserID:         userID,
+			IdempotencyKey: requestIdempotencyKey(ctx, fmt.Sprintf("user:%d:recharge", userID)),
+			Remark:         remark,
+			Operator:       operator,
+			Postings: []LedgerPosting{
+				{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: amount},
+				{Account: model.LedgerAccountFunding, Amount: -amount},
+			},
+		}); err != nil {
+			return ignoreDuplicate(err)
+		}
+		return s.createLog(tx, userID, 0, amount, model.BalanceTypeIncome, model.BalanceStyleRecharge, remark, operator)
+	})
+}
+
+// CreditCommission 佣金入账到用户余额
+func (s *BalanceService) CreditCommission(ctx context.Context, userID int64, amount money.Money, orderID int64, remark, operator string) error {
+	if amount <= 0 {
+		return errors.New("佣金金额必须大于0")
 	}
-	return s.repo.CreateLog(ctx, log)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.ledger.Post(tx, &LedgerJournal{
+			Kind:           model.LedgerKindCommission,
+			UserID:         userID,
+			OrderID:        orderID,
+			IdempotencyKey: model.OrderIdempotencyKey(orderID, fmt.Sprintf("%s:%d", model.IdempotencyCommission, userID)),
+			Remark:         remark,
+			Operator:       operator,
+			Postings: []LedgerPosting{
+				{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: amount},
+				{Account: model.LedgerAccountCommission, Amount: -amount},
+			},
+		}); err != nil {
+			return ignoreDuplicate(err)
+		}
+		return s.createLog(tx, userID, orderID, amount, model.BalanceTypeIncome, model.BalanceStyleCommission, remark, operator)
+	})
 }
 
 // Deduct 余额扣款
-func (s *BalanceService) Deduct(ctx context.Context, userID int64, amount float64, style int, remark, operator string) error {
+func (s *BalanceService) Deduct(ctx context.Context, userID int64, amount money.Money, style int, remark, operator string) error {
 	if amount <= 0 {
 		return errors.New("扣款金额必须大于0")
 	}
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return err
+	// 订单扣款记入订单收入，其他扣款视为调减入金
+	kind, counterpart := model.LedgerKindAdjust, model.LedgerAccountFunding
+	if style == model.BalanceStyleOrderDeduct {
+		kind, counterpart = model.LedgerKindOrderDeduct, model.LedgerAccountSales
 	}
-	before := user.Balance
-	if before < amount {
-		return errors.New("余额不足")
-	}
-	if err := s.repo.SubBalance(ctx, userID, amount); err != nil {
-		return err
-	}
-	log := &model.BalanceLog{
-		UserID:        userID,
-		Amount:        -amount,
-		Type:          2,     // 支出
-		Style:         style, // 业务类型
-		Balance:       before - amount,
-		BalanceBefore: before,
-		Remark:        remark,
-		Operator:      operator,
-		CreatedAt:     time.Now(),
-	}
-	return s.repo.CreateLog(ctx, log)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.ledger.Post(tx, &LedgerJournal{
+			Kind:           kind,
+			UserID:         userID,
+			IdempotencyKey: requestIdempotencyKey(ctx, fmt.Sprintf("user:%d:deduct", userID)),
+			Remark:         remark,
+			Operator:       operator,
+			Postings: []LedgerPosting{
+				{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: -amount, NonNegative: true},
+				{Account: counterpart, Amount: amount},
+			},
+		}); err != nil {
+			return ignoreDuplicate(err)
+		}
+		return s.createLog(tx, userID, 0, -amount, model.BalanceTypeExpense, style, remark, operator)
+	})
 }
 
 // Refund 余额退款
-func (s *BalanceService) Refund(ctx context.Context, userID int64, amount float64, orderID int64, remark, operator string) error {
+func (s *BalanceService) Refund(ctx context.Context, userID int64, amount money.Money, orderID int64, remark, operator string) error {
 	if amount <= 0 {
 		return errors.New("退款金额必须大于0")
 	}
-	
+
 	// 使用事务确保余额更新和日志记录的原子性
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		return s.RefundWithTx(ctx, tx, userID, amount, orderID, remark, operator)
 	})
 }
 
-// RefundWithTx 在指定事务中进行余额退款（使用原子性更新避免竞态条件）
-func (s *BalanceService) RefundWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount float64, orderID int64, remark, operator string) error {
+// RefundWithTx 在指定事务中进行余额退款，冲回订单扣款时的对方科目
+// 订单金额尚在冻结中时改为退回冻结款，退回金额以冻结剩余金额为限；
+// 同一订单的退款凭证幂等键相同，重复退款不会入账
+func (s *BalanceService) RefundWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, remark, operator string) error {
+	_, err := s.refundWithTx(ctx, tx, userID, amount, orderID, remark, operator)
+	return err
+}
+
+// refundWithTx 执行退款，返回是否实际入账；订单已退款时返回 false
+func (s *BalanceService) refundWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, remark, operator string) (bool, error) {
 	if amount <= 0 {
-		return errors.New("退款金额必须大于0")
+		return false, errors.New("退款金额必须大于0")
+	}
+	key := model.OrderIdempotencyKey(orderID, model.IdempotencyRefund)
+
+	// 订单金额仍冻结时直接退回冻结款；冻结款已有退回时视为已退款
+	if orderID > 0 {
+		hold, err := s.lockHold(tx, orderID)
+		if err != nil {
+			return false, err
+		}
+		if hold != nil && hold.Status == model.BalanceHoldStatusHeld {
+			released, err := s.releaseHold(tx, hold, amount, key, remark, operator)
+			return released > 0, err
+		}
+		if hold != nil && hold.Released > 0 {
+			return false, nil
+		}
+	}
+
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindRefund,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: key,
+		Remark:         remark,
+		Operator:       operator,
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: amount},
+			s.ledger.RefundCounterpart(tx, orderID, userID, amount),
+		},
+	}); err != nil {
+		return false, ignoreDuplicate(err)
+	}
+	return true, s.createLog(tx, userID, orderID, amount, model.BalanceTypeIncome, model.BalanceStyleRefund, remark, operator)
+}
+
+// SmartDeduct 智能扣款（优先使用余额，不足时使用授信额度）
+func (s *BalanceService) SmartDeduct(ctx context.Context, userID int64, amount money.Money, orderID int64, style int, remark, operator string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.SmartDeductWithTx(ctx, tx, userID, amount, orderID, style, remark, operator)
+	})
+}
+
+// SmartDeductWithTx 在指定事务中智能扣款，余额与授信的扣减记在同一张订单扣款凭证下
+func (s *BalanceService) SmartDeductWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, style int, remark, operator string) error {
+	if amount <= 0 {
+		return errors.New("扣款金额必须大于0")
 	}
-	
-	// 幂等性校验：检查是否已存在该订单的退款记录
-	var existCount int64
-	if err := tx.Model(&model.BalanceLog{}).Where("order_id = ? AND user_id = ? AND style = ?", orderID, userID, 2).Count(&existCount).Error; err != nil {
+
+	// 订单已扣款时不再校验余额，直接按已处理返回
+	key := model.OrderIdempotencyKey(orderID, model.IdempotencyDeduct)
+	if posted, err := s.ledger.Posted(tx, key); err != nil || posted {
 		return err
 	}
-	if existCount > 0 {
-		// 已存在退款记录，跳过重复退款
-		return nil
-	}
-	
-	// 关键改进：使用原子性更新避免读取-计算-写入的竞态条件
-	// 先原子性更新余额
-	result := tx.Model(&model.User{}).
-		Where("id = ?", userID).
-		Update("balance", gorm.Expr("balance + ?", amount))
-	
-	if result.Error != nil {
-		return result.Error
+
+	// 获取用户信息
+	var user model.User
+	if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
 	}
-	
-	if result.RowsAffected == 0 {
-		return errors.New("用户不存在")
+	balanceDeduct, creditDeduct, err := s.splitDeduct(&user, amount)
+	if err != nil {
+		return err
+	}
+
+	// 余额与授信都不允许扣成负数，并发扣款时由条件更新兜底
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindOrderDeduct,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: key,
+		Remark:         remark,
+		Operator:       operator,
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: -balanceDeduct, NonNegative: true},
+			{Account: model.UserCreditAccount(userID), UserID: userID, Amount: -creditDeduct, NonNegative: true},
+			{Account: model.LedgerAccountSales, Amount: amount},
+		},
+	}); err != nil {
+		return ignoreDuplicate(err)
 	}
-	
-	// 获取更新后的余额（在同一事务中确保数据一致性）
+
+	return s.createDeductLogs(tx, &user, orderID, balanceDeduct, creditDeduct, style, remark, operator)
+}
+
+// splitDeduct 计算扣款中余额与授信各自承担的金额（优先使用余额，不足时使用授信额度）
+func (s *BalanceService) splitDeduct(user *model.User, amount money.Money) (balanceDeduct, creditDeduct money.Money, err error) {
+	// 没有授信服务时只扣余额
+	if s.creditService == nil {
+		if user.Balance < amount {
+			return 0, 0, ErrInsufficientBalance
+		}
+	} else if user.Balance+user.Credit < amount {
+		return 0, 0, errors.New("余额和授信额度总和不足")
+	}
+
+	balanceDeduct = amount
+	if s.creditService != nil && user.Balance < amount {
+		// 余额不足，需要使用授信；余额已为负数时全部使用授信
+		balanceDeduct = user.Balance
+		if balanceDeduct < 0 {
+			balanceDeduct = 0
+		}
+		creditDeduct = amount - balanceDeduct
+	}
+	return balanceDeduct, creditDeduct, nil
+}
+
+// createDeductLogs 写入扣款的余额流水与授信使用日志，user 为扣款前的用户信息
+func (s *BalanceService) createDeductLogs(tx *gorm.DB, user *model.User, orderID int64, balanceDeduct, creditDeduct money.Money, style int, remark, operator string) error {
+	// 1. 余额扣款日志
+	if balanceDeduct > 0 {
+		if err := s.createLog(tx, user.ID, orderID, -balanceDeduct, model.BalanceTypeExpense, style, remark+"(余额部分)", operator); err != nil {
+			return err
+		}
+	}
+
+	// 2. 授信使用日志
+	if creditDeduct > 0 {
+		creditLog := &model.CreditLog{
+			UserID:       user.ID,
+			Amount:       creditDeduct,
+			Type:         model.CreditTypeUse,
+			CreditBefore: user.Credit,
+			CreditAfter:  user.Credit - creditDeduct,
+			OrderID:      orderID,
+			Remark:       remark + "(授信部分)",
+			Operator:     operator,
+			CreatedAt:    time.Now(),
+		}
+		if err := tx.Create(creditLog).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Hold 下单冻结订单金额
+func (s *BalanceService) Hold(ctx context.Context, userID int64, amount money.Money, orderID int64, remark, operator string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.HoldWithTx(ctx, tx, userID, amount, orderID, remark, operator)
+	})
+}
+
+// HoldWithTx 在指定事务中冻结订单金额
+// 与智能扣款一样优先使用余额、不足时使用授信额度，但金额转入冻结科目而非订单收入，
+// 订单成功时由 CaptureWithTx 扣款，失败时由 ReleaseWithTx 退回原处
+func (s *BalanceService) HoldWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, remark, operator string) error {
+	if amount <= 0 {
+		return errors.New("冻结金额必须大于0")
+	}
+	key := model.OrderIdempotencyKey(orderID, model.IdempotencyHold)
+	if posted, err := s.ledger.Posted(tx, key); err != nil || posted {
+		return err
+	}
+
 	var user model.User
 	if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
 		return err
 	}
-	
-	afterBalance := user.Balance
-	beforeBalance := afterBalance - amount
-	
-	// 写入流水
-	log := &model.BalanceLog{
+	balanceHold, creditHold, err := s.splitDeduct(&user, amount)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindHold,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: key,
+		Remark:         remark,
+		Operator:       operator,
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: -balanceHold, NonNegative: true},
+			{Account: model.UserCreditAccount(userID), UserID: userID, Amount: -creditHold, NonNegative: true},
+			{Account: model.UserFrozenAccount(userID), UserID: userID, Amount: amount},
+		},
+	}); err != nil {
+		return ignoreDuplicate(err)
+	}
+
+	hold := &model.BalanceHold{
 		UserID:        userID,
-		Amount:        amount,
-		Type:          1, // 收入
-		Style:         2, // 退款
-		Balance:       afterBalance,
-		BalanceBefore: beforeBalance,
-		Remark:        remark,
-		Operator:      operator,
 		OrderID:       orderID,
-		CreatedAt:     time.Now(),
+		Amount:        amount,
+		BalanceAmount: balanceHold,
+		CreditAmount:  creditHold,
+		Status:        model.BalanceHoldStatusHeld,
 	}
-	return tx.Create(log).Error
+	if err := tx.Create(hold).Error; err != nil {
+		return fmt.Errorf("create balance hold failed: %v", err)
+	}
+
+	return s.createDeductLogs(tx, &user, orderID, balanceHold, creditHold, model.BalanceStyleHold, remark, operator)
 }
 
-// SmartDeduct 智能扣款（优先使用余额，不足时使用授信额度）
-func (s *BalanceService) SmartDeduct(ctx context.Context, userID int64, amount float64, style int, remark, operator string) error {
-	if amount <= 0 {
-		return errors.New("扣款金额必须大于0")
+// CaptureWithTx 在指定事务中扣下订单冻结款的剩余部分，订单没有冻结款或已结清时不做任何处理
+func (s *BalanceService) CaptureWithTx(ctx context.Context, tx *gorm.DB, orderID int64, remark, operator string) error {
+	hold, err := s.lockHold(tx, orderID)
+	if err != nil || hold == nil || hold.Status != model.BalanceHoldStatusHeld {
+		return err
 	}
+	return s.captureHold(tx, hold, remark, operator)
+}
 
-	// 如果没有授信服务，直接使用普通扣款
-	if s.creditService == nil {
-		return s.Deduct(ctx, userID, amount, style, remark, operator)
+// ReleaseWithTx 在指定事务中退回订单冻结款，amount 不大于0时退回全部剩余金额，超过剩余金额时按剩余金额退回
+// 返回实际退回的金额，订单没有冻结款或已结清时返回0
+func (s *BalanceService) ReleaseWithTx(ctx context.Context, tx *gorm.DB, orderID int64, amount money.Money, remark, operator string) (money.Money, error) {
+	hold, err := s.lockHold(tx, orderID)
+	if err != nil || hold == nil || hold.Status != model.BalanceHoldStatusHeld {
+		return 0, err
 	}
+	return s.releaseHold(tx, hold, amount, model.OrderIdempotencyKey(orderID, model.IdempotencyRelease), remark, operator)
+}
 
-	// 获取用户信息
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
+// SettleHoldWithTx 按订单流转到的状态结清冻结款：成功、部分成功与拆单时扣款，失败、取消与退款时退回
+func (s *BalanceService) SettleHoldWithTx(ctx context.Context, tx *gorm.DB, orderID int64, status model.OrderStatus) error {
+	switch status {
+	case model.OrderStatusSuccess, model.OrderStatusPartial, model.OrderStatusSplit:
+		return s.CaptureWithTx(ctx, tx, orderID, "订单完成扣款", "system")
+	case model.OrderStatusFailed, model.OrderStatusCancelled, model.OrderStatusRefunded:
+		_, err := s.ReleaseWithTx(ctx, tx, orderID, 0, "订单关闭退回冻结金额", "system")
 		return err
 	}
+	return nil
+}
+
+// GetHold 获取订单的冻结款，没有时返回 nil
+func (s *BalanceService) GetHold(ctx context.Context, orderID int64) (*model.BalanceHold, error) {
+	var holds []model.BalanceHold
+	if err := s.db.WithContext(ctx).Where("order_id = ?", orderID).Limit(1).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, nil
+	}
+	return &holds[0], nil
+}
 
-	// 计算总可用金额（余额 + 授信额度）
-	totalAvailable := user.Balance + user.Credit
-	if totalAvailable < amount {
-		return errors.New("余额和授信额度总和不足")
+// HeldSummary 汇总用户冻结中的订单数与金额
+func (s *BalanceService) HeldSummary(ctx context.Context, userID int64) (count int64, amount money.Money, err error) {
+	var row struct {
+		Count  int64
+		Amount money.Money
 	}
+	err = s.db.WithContext(ctx).Model(&model.BalanceHold{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount - captured - released), 0) AS amount").
+		Where("user_id = ? AND status = ?", userID, model.BalanceHoldStatusHeld).
+		Scan(&row).Error
+	return row.Count, row.Amount, err
+}
 
-	// 使用事务确保原子性
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 计算扣款策略
-		balanceDeduct := amount
-		creditDeduct := 0.0
+// lockHold 加行锁读取订单的冻结款，没有时返回 nil
+func (s *BalanceService) lockHold(tx *gorm.DB, orderID int64) (*model.BalanceHold, error) {
+	var holds []model.BalanceHold
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).Limit(1).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, nil
+	}
+	return &holds[0], nil
+}
 
-		if user.Balance < amount {
-			// 余额不足，需要使用授信
-			balanceDeduct = user.Balance
-			creditDeduct = amount - user.Balance
+// captureHold 将冻结款剩余部分转入订单收入
+func (s *BalanceService) captureHold(tx *gorm.DB, hold *model.BalanceHold, remark, operator string) error {
+	amount := hold.Remaining()
+	if amount > 0 {
+		if _, err := s.ledger.Post(tx, &LedgerJournal{
+			Kind:           model.LedgerKindCapture,
+			UserID:         hold.UserID,
+			OrderID:        hold.OrderID,
+			IdempotencyKey: model.OrderIdempotencyKey(hold.OrderID, model.IdempotencyCapture),
+			Remark:         remark,
+			Operator:       operator,
+			Postings: []LedgerPosting{
+				{Account: model.UserFrozenAccount(hold.UserID), UserID: hold.UserID, Amount: -amount, NonNegative: true},
+				{Account: model.LedgerAccountSales, Amount: amount},
+			},
+		}); err != nil {
+			return ignoreDuplicate(err)
 		}
+	}
+	return s.addHoldAmount(tx, hold, "captured", amount)
+}
 
-		// 1. 扣除余额（如果有需要扣除的余额）
-		if balanceDeduct > 0 {
-			// 使用原子性更新扣除余额
-			result := tx.Model(&model.User{}).
-				Where("id = ? AND balance >= ?", userID, balanceDeduct).
-				Update("balance", gorm.Expr("balance - ?", balanceDeduct))
-
-			if result.Error != nil {
-				return result.Error
-			}
-
-			if result.RowsAffected == 0 {
-				return errors.New("余额不足")
-			}
-
-			// 创建余额扣款日志
-			balanceLog := &model.BalanceLog{
-				UserID:        userID,
-				Amount:        -balanceDeduct,
-				Type:          2,     // 支出
-				Style:         style, // 业务类型
-				Balance:       user.Balance - balanceDeduct,
-				BalanceBefore: user.Balance,
-				Remark:        remark + "(余额部分)",
-				Operator:      operator,
-				CreatedAt:     time.Now(),
-			}
-			if err := tx.Create(balanceLog).Error; err != nil {
-				return err
-			}
+// releaseHold 退回冻结款，优先恢复冻结时使用的授信额度，其余退回余额
+// key 为本次退回凭证的幂等键，已记账时按未退回返回0
+func (s *BalanceService) releaseHold(tx *gorm.DB, hold *model.BalanceHold, amount money.Money, key, remark, operator string) (money.Money, error) {
+	if amount <= 0 || amount > hold.Remaining() {
+		amount = hold.Remaining()
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+	creditRestore := hold.CreditRemaining()
+	if creditRestore > amount {
+		creditRestore = amount
+	}
+	balanceRestore := amount - creditRestore
+
+	var user model.User
+	if err := tx.Where("id = ?", hold.UserID).First(&user).Error; err != nil {
+		return 0, err
+	}
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindRelease,
+		UserID:         hold.UserID,
+		OrderID:        hold.OrderID,
+		IdempotencyKey: key,
+		Remark:         remark,
+		Operator:       operator,
+		Postings: []LedgerPosting{
+			{Account: model.UserFrozenAccount(hold.UserID), UserID: hold.UserID, Amount: -amount, NonNegative: true},
+			{Account: model.UserBalanceAccount(hold.UserID), UserID: hold.UserID, Amount: balanceRestore},
+			{Account: model.UserCreditAccount(hold.UserID), UserID: hold.UserID, Amount: creditRestore},
+		},
+	}); err != nil {
+		return 0, ignoreDuplicate(err)
+	}
+
+	if balanceRestore > 0 {
+		if err := s.createLog(tx, hold.UserID, hold.OrderID, balanceRestore, model.BalanceTypeIncome, model.BalanceStyleRelease, remark, operator); err != nil {
+			return 0, err
+		}
+	}
+	if creditRestore > 0 {
+		creditLog := &model.CreditLog{
+			UserID:       hold.UserID,
+			Amount:       creditRestore,
+			Type:         model.CreditTypeRestore,
+			CreditBefore: user.Credit,
+			CreditAfter:  user.Credit + creditRestore,
+			OrderID:      hold.OrderID,
+			Remark:       remark + "(授信部分)",
+			Operator:     operator,
+			CreatedAt:    time.Now(),
 		}
+		if err := tx.Create(creditLog).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return amount, s.addHoldAmount(tx, hold, "released", amount)
+}
 
-		// 2. 扣除授信额度（如果需要）
-		if creditDeduct > 0 {
-			// 使用原子性更新扣除授信额度
-			result := tx.Model(&model.User{}).
-				Where("id = ? AND credit >= ?", userID, creditDeduct).
-				Update("credit", gorm.Expr("credit - ?", creditDeduct))
-
-			if result.Error != nil {
-				return result.Error
-			}
-
-			if result.RowsAffected == 0 {
-				return errors.New("授信额度不足")
-			}
-
-			// 创建授信使用日志
-			creditLog := &model.CreditLog{
-				UserID:       userID,
-				Amount:       creditDeduct,
-				Type:         model.CreditTypeUse,
-				CreditBefore: user.Credit,
-				CreditAfter:  user.Credit - creditDeduct,
-				Remark:       remark + "(授信部分)",
-				Operator:     operator,
-				CreatedAt:    time.Now(),
-			}
-			if err := tx.Create(creditLog).Error; err != nil {
-				return err
-			}
+// addHoldAmount 按增量累加冻结款的扣款（captured）或退回（released）金额，仅在剩余冻结金额足够时更新，
+// 剩余金额为0时按是否扣过款标记为已扣款或已退回
+func (s *BalanceService) addHoldAmount(tx *gorm.DB, hold *model.BalanceHold, column string, amount money.Money) error {
+	if column == "captured" {
+		hold.Captured += amount
+	} else {
+		hold.Released += amount
+	}
+	updates := map[string]interface{}{column: gorm.Expr(column+" + CAST(? AS DECIMAL(10,2))", amount)}
+	if hold.Remaining() == 0 {
+		hold.Status = model.BalanceHoldStatusReleased
+		if hold.Captured > 0 {
+			hold.Status = model.BalanceHoldStatusCaptured
 		}
+		updates["status"] = hold.Status
+	}
 
-		return nil
-	})
+	result := tx.Model(&model.BalanceHold{}).
+		Where("id = ? AND status = ? AND amount - captured - released >= CAST(? AS DECIMAL(10,2))", hold.ID, model.BalanceHoldStatusHeld, amount).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update balance hold failed: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("balance hold of order %d has less than %s remaining", hold.OrderID, amount)
+	}
+	return nil
+}
+
+// createLog 记账后写入余额流水，变动后余额取事务内的最新值
+func (s *BalanceService) createLog(tx *gorm.DB, userID, orderID int64, amount money.Money, balanceType, style int, remark, operator string) error {
+	var user model.User
+	if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	log := &model.BalanceLog{
+		UserID:        userID,
+		OrderID:       orderID,
+		Amount:        amount,
+		Type:          balanceType,
+		Style:         style,
+		Balance:       user.Balance,
+		BalanceBefore: user.Balance - amount,
+		Remark:        remark,
+		Operator:      operator,
+		CreatedAt:     time.Now(),
+	}
+	return tx.Create(log).Error
 }
 
 // ListLogs 查询余额流水
 func (s *BalanceService) ListLogs(ctx context.Context, userID int64, offset, limit int) ([]model.BalanceLog, int64, error) {
 	return s.repo.ListLogs(ctx, userID, offset, limit)
 }
+
+// ListLedger 按用户或订单查询记账凭证
+func (s *BalanceService) ListLedger(ctx context.Context, userID, orderID int64, offset, limit int) ([]*model.LedgerTransaction, int64, error) {
+	return s.ledger.ListTransactions(ctx, userID, orderID, offset, limit)
+}
+
+// LedgerBalance 由账本汇总用户的余额、授信额度与冻结金额
+func (s *BalanceService) LedgerBalance(ctx context.Context, userID int64) (balance, credit, frozen money.Money, err error) {
+	if balance, err = s.ledger.AccountBalance(ctx, model.UserBalanceAccount(userID)); err != nil {
+		return 0, 0, 0, err
+	}
+	if credit, err = s.ledger.AccountBalance(ctx, model.UserCreditAccount(userID)); err != nil {
+		return 0, 0, 0, err
+	}
+	if frozen, err = s.ledger.AccountBalance(ctx, model.UserFrozenAccount(userID)); err != nil {
+		return 0, 0, 0, err
+	}
+	return balance, credit, frozen, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCallbackRejected 回调验签失败、平台账号不存在或数据无法解析，平台重发也不会成功
+	ErrCallbackRejected = errors.New("callback rejected")
+	// ErrCallbackUnmatched 回调的订单不存在
+	ErrCallbackUnmatched = errors.New("callback order not found")
+	// errCallbackDuplicated 并发回调写入去重键时唯一索引冲突，回滚事务后按重复回调处理
+	errCallbackDuplicated = errors.New("callback duplicated")
+)
+
+// CallbackRequest 平台回调请求
+type CallbackRequest struct {
+	PlatformCode string // 平台代码，通用模板平台为接口代码
+	AccountName  string // 回调地址中的平台账号，为空时不查找账号
+	SourceIP     string // 回调来源IP
+	Body         []byte // 原始请求体
+
+	ReplayOfID int64     // 后台重放时为被重放的回调日志ID
+	Operator   string    // 后台重放的操作人
+	ReceivedAt time.Time // 原始接收时间，重放时按该时间校验回调时间戳
+}
+
+// CallbackResult 回调处理结果
+type CallbackResult struct {
+	LogID      int64             `json:"log_id"`
+	OrderID    int64             `json:"order_id"`
+	Status     model.OrderStatus `json:"status"`     // 回调中的订单状态
+	Duplicated bool              `json:"duplicated"` // 重复回调，未再处理
+	Ack        string            `json:"ack"`        // 返回给平台的应答内容
+}
+
+// HandleCallback 处理平台回调
+// 先落库原始回调，再验签、解析，在同一事务内锁定订单、按去重键去重、完成订单并写入通知发件箱，
+// 提交后投递通知并更新回调日志。重复回调返回 Duplicated，应答与首次处理相同；
+// 处理失败时返回的结果只含 LogID
+func (s *rechargeService) HandleCallback(ctx context.Context, req *CallbackRequest) (*CallbackResult, error) {
+	now := time.Now()
+	log := &model.CallbackLog{
+		PlatformCode: req.PlatformCode,
+		AccountName:  req.AccountName,
+		SourceIP:     req.SourceIP,
+		Status:       model.CallbackLogStatusReceived,
+		RequestData:  string(req.Body),
+		ReplayOfID:   req.ReplayOfID,
+		Operator:     req.Operator,
+		CreateTime:   now,
+		UpdateTime:   now,
+	}
+	if !req.ReceivedAt.IsZero() {
+		ctx = recharge.WithCallbackReceivedAt(ctx, req.ReceivedAt)
+	}
+	if err := s.callbackLogRepo.Create(ctx, log); err != nil {
+		logger.Error("记录回调日志失败", "platform_code", req.PlatformCode, "error", err)
+		return nil, fmt.Errorf("save callback log failed: %v", err)
+	}
+
+	result, err := s.ingestCallback(ctx, req, log)
+	switch {
+	case errors.Is(err, ErrCallbackUnmatched):
+		log.Status = model.CallbackLogStatusUnmatched
+		log.ErrorMessage = err.Error()
+	case errors.Is(err, ErrCallbackRejected):
+		log.Status = model.CallbackLogStatusRejected
+		log.ErrorMessage = err.Error()
+	case err != nil:
+		log.Status = model.CallbackLogStatusFailed
+		log.ErrorMessage = err.Error()
+	case result.Duplicated:
+		log.Status = model.CallbackLogStatusDuplicated
+		log.ResponseData = result.Ack
+	default:
+		log.Status = model.CallbackLogStatusProcessed
+		log.ResponseData = result.Ack
+	}
+	log.UpdateTime = time.Now()
+	if updateErr := s.callbackLogRepo.Update(ctx, log); updateErr != nil {
+		logger.Error("更新回调日志失败", "log_id", log.ID, "error", updateErr)
+	}
+
+	if err != nil {
+		logger.Error("处理回调失败", "platform_code", req.PlatformCode, "log_id", log.ID, "error", err)
+		return &CallbackResult{LogID: log.ID}, err
+	}
+	logger.Info("处理回调完成",
+		"platform_code", req.PlatformCode,
+		"log_id", log.ID,
+		"order_id", result.OrderID,
+		"status", result.Status,
+		"duplicated", result.Duplicated)
+	return result, nil
+}
+
+// ingestCallback 验签、解析并在事务内去重、完成订单
+func (s *rechargeService) ingestCallback(ctx context.Context, req *CallbackRequest, log *model.CallbackLog) (*CallbackResult, error) {
+	// 1. 校验来源IP、签名与时间戳
+	if err := s.verifyCallback(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// 2. 解析
+	platform, err := s.manager.GetPlatform(req.PlatformCode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCallbackRejected, err)
+	}
+	callbackData, err := platform.ParseCallbackData(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse callback data failed: %v", ErrCallbackRejected, err)
+	}
+	log.OrderID = callbackData.OrderID
+	log.PlatformID = callbackData.OrderNumber
+	log.CallbackType = callbackData.CallbackType
+
+	orderState, err := strconv.Atoi(callbackData.Status)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse order status failed: %v", ErrCallbackRejected, err)
+	}
+	reason := "上游回调"
+	actor, source := req.PlatformCode, OrderTransitionSourceCallback
+	if req.ReplayOfID != 0 {
+		reason = fmt.Sprintf("后台重放上游回调 #%d", req.ReplayOfID)
+		actor, source = req.Operator, OrderTransitionSourceManual
+	}
+	if callbackData.Message != "" {
+		reason = fmt.Sprintf("%s: %s", reason, callbackData.Message)
+	}
+	orderResult := OrderResult{
+		Status:         model.OrderStatus(orderState),
+		CreditedAmount: callbackData.Amount,
+		Voucher:        callbackData.TransactionID,
+		Actor:          actor,
+		Source:         source,
+		Reason:         reason,
+	}
+	result := &CallbackResult{
+		LogID:  log.ID,
+		Status: orderResult.Status,
+		Ack:    recharge.CallbackAck(ctx, platform),
+	}
+
+	// 3. 事务内锁定订单、去重并完成订单
+	var order model.Order
+	var settled bool
+	var msg *model.OutboxMessage
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_number = ?", callbackData.OrderNumber).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrCallbackUnmatched, callbackData.OrderNumber)
+			}
+			return fmt.Errorf("get order failed: %v", err)
+		}
+
+		// 订单行锁下同一订单的回调串行处理，去重键唯一索引兜底
+		dedupKey := CallbackDedupKey(req.PlatformCode, order.OrderNumber, orderResult.Status)
+		var count int64
+		if err := tx.Model(&model.CallbackLog{}).Where("dedup_key = ?", dedupKey).Count(&count).Error; err != nil {
+			return fmt.Errorf("check callback dedup key failed: %v", err)
+		}
+		if count > 0 {
+			result.Duplicated = true
+			return nil
+		}
+		if err := tx.Model(log).Update("dedup_key", dedupKey).Error; err != nil {
+			if isDuplicateKey(tx, err) {
+				return errCallbackDuplicated
+			}
+			return fmt.Errorf("save callback dedup key failed: %v", err)
+		}
+
+		var err error
+		settled, msg, err = s.settleLockedOrder(ctx, tx, &order, orderResult)
+		return err
+	})
+	if order.ID != 0 {
+		result.OrderID = order.ID
+	}
+	if errors.Is(err, errCallbackDuplicated) {
+		result.Duplicated, err = true, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.Duplicated {
+		logger.Info("回调已处理过", "order_id", order.ID, "platform_code", req.PlatformCode, "status", orderResult.Status)
+		return result, nil
+	}
+
+	// 4. 回调结果计入平台接口健康状况，通知下游并汇总拆单父订单
+	s.manager.RecordCallback(ctx, order.APICurID, orderResult.Status)
+	if settled {
+		s.afterSettle(ctx, &order, msg)
+	}
+	return result, nil
+}
+
+// verifyCallback 校验回调来源IP白名单，并由平台适配器用回调地址中的平台账号校验签名与时间戳
+func (s *rechargeService) verifyCallback(ctx context.Context, req *CallbackRequest) error {
+	var account *model.PlatformAccount
+	if req.AccountName != "" {
+		var err error
+		account, err = s.platformRepo.GetPlatformAccountByAccountName(req.AccountName)
+		if err != nil {
+			return fmt.Errorf("%w: platform account %s not found: %v", ErrCallbackRejected, req.AccountName, err)
+		}
+	}
+
+	// 平台接口未配置白名单时不限制来源
+	api, err := s.platformAPIRepo.GetByCode(ctx, req.PlatformCode)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("get platform api failed: %v", err)
+	}
+	if api != nil && !api.AllowsCallbackFrom(req.SourceIP) {
+		return fmt.Errorf("%w: 来源IP不在白名单: %s", ErrCallbackRejected, req.SourceIP)
+	}
+
+	if err := s.manager.VerifyCallback(ctx, req.PlatformCode, account, req.Body); err != nil {
+		return fmt.Errorf("%w: %v", ErrCallbackRejected, err)
+	}
+	return nil
+}
+
+// CallbackDedupKey 回调去重键，同一平台同一订单的同一状态只处理一次
+func CallbackDedupKey(platformCode, orderNumber string, status model.OrderStatus) string {
+	return fmt.Sprintf("%s:%s:%d", platformCode, orderNumber, status)
+}
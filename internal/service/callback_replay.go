@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// callbackReplayBatchLimit 单次批量重放的最大条数
+const callbackReplayBatchLimit = 100
+
+var (
+	// ErrCallbackLogNotFound 回调日志不存在
+	ErrCallbackLogNotFound = errors.New("callback log not found")
+	// ErrCallbackNotReplayable 回调已处理成功、已被重放解决或本身是重放日志
+	ErrCallbackNotReplayable = errors.New("callback not replayable")
+)
+
+// CallbackReplayResult 单条回调的重放结果
+type CallbackReplayResult struct {
+	LogID       int64  `json:"log_id"`        // 被重放的回调日志ID
+	ReplayLogID int64  `json:"replay_log_id"` // 本次重放生成的回调日志ID
+	OrderID     int64  `json:"order_id"`
+	Status      int    `json:"status"`     // 重放日志的处理状态
+	Duplicated  bool   `json:"duplicated"` // 订单已按同一结果处理过，本次未再处理
+	Error       string `json:"error,omitempty"`
+}
+
+// CallbackReplayService 回调重放
+// 后台查看处理失败、未匹配的回调并按原始数据重放，重放走与平台回调相同的处理流程，
+// 同样校验来源IP与签名、按去重键去重，每次重放都新建一条回调日志记录操作人
+type CallbackReplayService struct {
+	callbackLogRepo repository.CallbackLogRepository
+	rechargeService RechargeService
+}
+
+// NewCallbackReplayService 创建回调重放服务
+func NewCallbackReplayService(callbackLogRepo repository.CallbackLogRepository, rechargeService RechargeService) *CallbackReplayService {
+	return &CallbackReplayService{
+		callbackLogRepo: callbackLogRepo,
+		rechargeService: rechargeService,
+	}
+}
+
+// List 分页查询回调日志
+func (s *CallbackReplayService) List(ctx context.Context, query *model.CallbackLogQuery) ([]*model.CallbackLog, int64, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > 100 {
+		query.PageSize = 20
+	}
+	return s.callbackLogRepo.List(ctx, query)
+}
+
+// Get 获取回调日志及原始数据
+func (s *CallbackReplayService) Get(ctx context.Context, id int64) (*model.CallbackLog, error) {
+	log, err := s.callbackLogRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCallbackLogNotFound
+		}
+		return nil, fmt.Errorf("get callback log failed: %v", err)
+	}
+	return log, nil
+}
+
+// Replay 按原始数据重放一条回调
+// 重放处理成功或订单已按同一结果处理过时，原始回调标记为已解决
+func (s *CallbackReplayService) Replay(ctx context.Context, id int64, operator string) (*CallbackReplayResult, error) {
+	log, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !log.IsReplayable() {
+		return nil, fmt.Errorf("%w: log %d status %d", ErrCallbackNotReplayable, log.ID, log.Status)
+	}
+
+	result := &CallbackReplayResult{LogID: log.ID}
+	handled, err := s.rechargeService.HandleCallback(ctx, &CallbackRequest{
+		PlatformCode: log.PlatformCode,
+		AccountName:  log.AccountName,
+		SourceIP:     log.SourceIP,
+		Body:         []byte(log.RequestData),
+		ReplayOfID:   log.ID,
+		Operator:     operator,
+		ReceivedAt:   log.CreateTime,
+	})
+	if handled != nil {
+		result.ReplayLogID = handled.LogID
+		result.OrderID = handled.OrderID
+	}
+
+	var resolvedByID int64
+	switch {
+	case errors.Is(err, ErrCallbackUnmatched):
+		result.Status = model.CallbackLogStatusUnmatched
+	case errors.Is(err, ErrCallbackRejected):
+		result.Status = model.CallbackLogStatusRejected
+	case err != nil:
+		result.Status = model.CallbackLogStatusFailed
+	case handled.Duplicated:
+		result.Status = model.CallbackLogStatusDuplicated
+		result.Duplicated = true
+		resolvedByID = handled.LogID
+	default:
+		result.Status = model.CallbackLogStatusProcessed
+		resolvedByID = handled.LogID
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if markErr := s.callbackLogRepo.MarkReplayed(ctx, log.ID, resolvedByID); markErr != nil {
+		logger.Error("记录回调重放失败", "log_id", log.ID, "replay_log_id", result.ReplayLogID, "error", markErr)
+	}
+
+	logger.Info("后台重放回调",
+		"log_id", log.ID,
+		"replay_log_id", result.ReplayLogID,
+		"operator", operator,
+		"status", result.Status,
+		"error", result.Error)
+	return result, nil
+}
+
+// ReplayBatch 逐条重放多条回调，单条失败不影响其余回调
+func (s *CallbackReplayService) ReplayBatch(ctx context.Context, ids []int64, operator string) ([]*CallbackReplayResult, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("回调日志ID不能为空")
+	}
+	if len(ids) > callbackReplayBatchLimit {
+		return nil, fmt.Errorf("单次最多重放 %d 条回调", callbackReplayBatchLimit)
+	}
+
+	results := make([]*CallbackReplayResult, 0, len(ids))
+	for _, id := range ids {
+		result, err := s.Replay(ctx, id, operator)
+		if err != nil {
+			result = &CallbackReplayResult{LogID: id, Error: err.Error()}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
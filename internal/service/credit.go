@@ -5,6 +5,7 @@ import (
 	"errors"
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
+	"recharge-go/pkg/money"
 	"time"
 )
 
@@ -12,6 +13,7 @@ import (
 type CreditService struct {
 	userRepo      *repository.UserRepository
 	creditLogRepo *repository.CreditLogRepository
+	ledger        *Ledger
 }
 
 // NewCreditService 创建授信服务
@@ -19,6 +21,7 @@ func NewCreditService(userRepo *repository.UserRepository, creditLogRepo *reposi
 	return &CreditService{
 		userRepo:      userRepo,
 		creditLogRepo: creditLogRepo,
+		ledger:        NewLedger(userRepo.DB()),
 	}
 }
 
@@ -68,14 +71,25 @@ func (s *CreditService) SetCredit(ctx context.Context, req *model.CreditLogReque
 		}
 	}()
 
-	// 更新用户授信额度
-	if err := tx.Model(user).Update("credit", req.Amount).Error; err != nil {
-		tx.Rollback()
-		return err
+	// 记账调整用户授信额度，对方科目为授信额度发放；额度不变时无需记账
+	if delta := req.Amount - user.Credit; delta != 0 {
+		if _, err := s.ledger.Post(tx, &LedgerJournal{
+			Kind:     model.LedgerKindCreditSet,
+			UserID:   req.UserID,
+			Remark:   req.Remark,
+			Operator: req.Operator,
+			Postings: []LedgerPosting{
+				{Account: model.UserCreditAccount(req.UserID), UserID: req.UserID, Amount: delta},
+				{Account: model.LedgerAccountCreditLine, Amount: -delta},
+			},
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	// 创建授信日志
-	if err := s.creditLogRepo.Create(ctx, log); err != nil {
+	if err := tx.Create(log).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -84,7 +98,7 @@ func (s *CreditService) SetCredit(ctx context.Context, req *model.CreditLogReque
 }
 
 // UseCredit 使用授信额度
-func (s *CreditService) UseCredit(ctx context.Context, userID int64, amount float64, orderID int64, remark string) error {
+func (s *CreditService) UseCredit(ctx context.Context, userID int64, amount money.Money, orderID int64, remark string) error {
 	// 获取用户信息
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
@@ -120,14 +134,25 @@ func (s *CreditService) UseCredit(ctx context.Context, userID int64, amount floa
 		}
 	}()
 
-	// 更新用户授信额度
-	if err := tx.Model(user).Update("credit", user.Credit-amount).Error; err != nil {
+	// 记账扣减用户授信额度
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindCreditUse,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: model.OrderIdempotencyKey(orderID, model.IdempotencyCreditUse),
+		Remark:         remark,
+		Operator:       "system",
+		Postings: []LedgerPosting{
+			{Account: model.UserCreditAccount(userID), UserID: userID, Amount: -amount, NonNegative: true},
+			{Account: model.LedgerAccountSales, Amount: amount},
+		},
+	}); err != nil {
 		tx.Rollback()
-		return err
+		return ignoreDuplicate(err)
 	}
 
 	// 创建授信日志
-	if err := s.creditLogRepo.Create(ctx, log); err != nil {
+	if err := tx.Create(log).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -136,7 +161,7 @@ func (s *CreditService) UseCredit(ctx context.Context, userID int64, amount floa
 }
 
 // RestoreCredit 恢复授信额度
-func (s *CreditService) RestoreCredit(ctx context.Context, userID int64, amount float64, orderID int64, remark string) error {
+func (s *CreditService) RestoreCredit(ctx context.Context, userID int64, amount money.Money, orderID int64, remark string) error {
 	// 获取用户信息
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
@@ -167,14 +192,25 @@ func (s *CreditService) RestoreCredit(ctx context.Context, userID int64, amount
 		}
 	}()
 
-	// 更新用户授信额度
-	if err := tx.Model(user).Update("credit", user.Credit+amount).Error; err != nil {
+	// 记账恢复用户授信额度，冲回订单收入
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindCreditRestore,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: model.OrderIdempotencyKey(orderID, model.IdempotencyCreditRestore),
+		Remark:         remark,
+		Operator:       "system",
+		Postings: []LedgerPosting{
+			{Account: model.UserCreditAccount(userID), UserID: userID, Amount: amount},
+			{Account: model.LedgerAccountSales, Amount: -amount},
+		},
+	}); err != nil {
 		tx.Rollback()
-		return err
+		return ignoreDuplicate(err)
 	}
 
 	// 创建授信日志
-	if err := s.creditLogRepo.Create(ctx, log); err != nil {
+	if err := tx.Create(log).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -212,6 +248,6 @@ func (s *CreditService) GetCreditLogs(ctx context.Context, req *model.CreditLogL
 }
 
 // GetUserCreditStats 获取用户授信统计
-func (s *CreditService) GetUserCreditStats(ctx context.Context, userID int64) (float64, float64, error) {
+func (s *CreditService) GetUserCreditStats(ctx context.Context, userID int64) (money.Money, money.Money, error) {
 	return s.creditLogRepo.GetUserCreditStats(ctx, userID)
 }
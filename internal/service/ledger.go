@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLedgerUnbalanced 凭证分录金额之和不为0
+	ErrLedgerUnbalanced = errors.New("ledger entries are not balanced")
+	// ErrLedgerPrecision 分录金额不是整分，分录与余额列为2位小数，须由调用方先舍入到分
+	ErrLedgerPrecision = errors.New("ledger amount is not rounded to cents")
+	// ErrInsufficientBalance 余额不足
+	ErrInsufficientBalance = errors.New("余额不足")
+	// ErrInsufficientCredit 授信额度不足
+	ErrInsufficientCredit = errors.New("授信额度不足")
+	// ErrInsufficientFrozen 冻结金额不足
+	ErrInsufficientFrozen = errors.New("冻结金额不足")
+	// ErrLedgerDuplicate 相同幂等键的凭证已记账，调用方应按已处理返回
+	ErrLedgerDuplicate = errors.New("ledger transaction with the same idempotency key already posted")
+)
+
+// LedgerPosting 待记账的一条分录
+type LedgerPosting struct {
+	Account           string
+	UserID            int64
+	PlatformAccountID int64
+	Amount            money.Money // 为正表示科目余额增加
+	// NonNegative 为 true 时不允许本分录使科目余额变为负数，只对用户余额、授信与冻结科目生效
+	NonNegative bool
+}
+
+// LedgerJournal 一次资金变动，所有分录在同一张凭证下记账
+type LedgerJournal struct {
+	Kind    string
+	UserID  int64
+	OrderID int64
+	// IdempotencyKey 幂等键，如 order:123:refund，为空时不做幂等控制
+	IdempotencyKey string
+	Remark         string
+	Operator       string
+	Postings       []LedgerPosting
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey 在上下文中携带调用方提供的幂等键，用于充值、手动调整等不关联订单的余额操作
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// requestIdempotencyKey 由上下文中的幂等键生成凭证幂等键，scope 区分操作对象与类型，如 user:1:recharge；
+// 上下文没有幂等键时返回空字符串
+func requestIdempotencyKey(ctx context.Context, scope string) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	if key == "" {
+		return ""
+	}
+	return scope + ":" + key
+}
+
+// Ledger 复式记账账本
+// 每次资金变动在调用方事务内写入一张借贷平衡的凭证，并同步更新 users.balance、users.credit、
+// users.frozen_balance，这几列只是科目余额的缓存，可随时由分录汇总重新得出
+type Ledger struct {
+	db *gorm.DB
+}
+
+// NewLedger 创建账本
+func NewLedger(db *gorm.DB) *Ledger {
+	return &Ledger{db: db}
+}
+
+// Post 在事务内记账并更新用户余额、授信额度、冻结金额缓存
+// 幂等键已记账时返回 ErrLedgerDuplicate 且不做任何变更，并发记账由唯一索引兜底
+func (l *Ledger) Post(tx *gorm.DB, journal *LedgerJournal) (*model.LedgerTransaction, error) {
+	var sum money.Money
+	postings := make([]LedgerPosting, 0, len(journal.Postings))
+	for _, p := range journal.Postings {
+		if p.Amount == 0 {
+			continue
+		}
+		if p.Amount != p.Amount.Round() {
+			return nil, fmt.Errorf("%w: kind=%s order_id=%d account=%s amount=%s", ErrLedgerPrecision, journal.Kind, journal.OrderID, p.Account, p.Amount)
+		}
+		sum += p.Amount
+		postings = append(postings, p)
+	}
+	if len(postings) < 2 || sum != 0 {
+		return nil, fmt.Errorf("%w: kind=%s order_id=%d", ErrLedgerUnbalanced, journal.Kind, journal.OrderID)
+	}
+
+	now := time.Now()
+	txn := &model.LedgerTransaction{
+		Kind:      journal.Kind,
+		UserID:    journal.UserID,
+		OrderID:   journal.OrderID,
+		Remark:    journal.Remark,
+		Operator:  journal.Operator,
+		CreatedAt: now,
+	}
+	if key := journal.IdempotencyKey; key != "" {
+		posted, err := l.Posted(tx, key)
+		if err != nil {
+			return nil, err
+		}
+		if posted {
+			return nil, ErrLedgerDuplicate
+		}
+		txn.IdempotencyKey = &key
+	}
+	if err := tx.Create(txn).Error; err != nil {
+		if isDuplicateKey(tx, err) {
+			return nil, ErrLedgerDuplicate
+		}
+		return nil, fmt.Errorf("create ledger transaction failed: %v", err)
+	}
+
+	for _, p := range postings {
+		if err := l.applyCache(tx, p); err != nil {
+			return nil, err
+		}
+		entry := &model.LedgerEntry{
+			TransactionID:     txn.ID,
+			Account:           p.Account,
+			UserID:            p.UserID,
+			PlatformAccountID: p.PlatformAccountID,
+			OrderID:           journal.OrderID,
+			Amount:            p.Amount,
+			CreatedAt:         now,
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return nil, fmt.Errorf("create ledger entry failed: %v", err)
+		}
+		txn.Entries = append(txn.Entries, entry)
+	}
+	return txn, nil
+}
+
+// Posted 幂等键对应的凭证是否已记账，用于在余额校验等前置检查之前识别重复请求
+func (l *Ledger) Posted(tx *gorm.DB, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	var count int64
+	if err := tx.Model(&model.LedgerTransaction{}).Where("idempotency_key = ?", key).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("check ledger idempotency key failed: %v", err)
+	}
+	return count > 0, nil
+}
+
+// isDuplicateKey 判断写入错误是否为唯一索引冲突，由数据库驱动翻译各自的错误码
+func isDuplicateKey(tx *gorm.DB, err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// ignoreDuplicate 重复记账按已处理返回 nil，其他错误原样返回
+func ignoreDuplicate(err error) error {
+	if errors.Is(err, ErrLedgerDuplicate) {
+		return nil
+	}
+	return err
+}
+
+// applyCache 用户余额、授信与冻结科目的分录同步更新 users 表中的缓存列
+func (l *Ledger) applyCache(tx *gorm.DB, p LedgerPosting) error {
+	var column string
+	var insufficient error
+	switch p.Account {
+	case model.UserBalanceAccount(p.UserID):
+		column, insufficient = "balance", ErrInsufficientBalance
+	case model.UserCreditAccount(p.UserID):
+		column, insufficient = "credit", ErrInsufficientCredit
+	case model.UserFrozenAccount(p.UserID):
+		column, insufficient = "frozen_balance", ErrInsufficientFrozen
+	default:
+		return nil
+	}
+
+	query := tx.Model(&model.User{}).Where("id = ?", p.UserID)
+	if p.NonNegative && p.Amount < 0 {
+		query = query.Where(column+" >= ?", -p.Amount)
+	}
+	result := query.Update(column, gorm.Expr(column+" + CAST(? AS DECIMAL(10,2))", p.Amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if p.NonNegative && p.Amount < 0 {
+			return insufficient
+		}
+		return errors.New("用户不存在")
+	}
+	return nil
+}
+
+// RefundCounterpart 退款分录的对方科目：冲回订单扣款时记入的科目，找不到扣款凭证时记入订单收入
+func (l *Ledger) RefundCounterpart(tx *gorm.DB, orderID, userID int64, amount money.Money) LedgerPosting {
+	counterpart := LedgerPosting{Account: model.LedgerAccountSales, Amount: -amount}
+	if orderID <= 0 {
+		return counterpart
+	}
+
+	var entry model.LedgerEntry
+	err := tx.Table("ledger_entries e").
+		Select("e.account, e.platform_account_id").
+		Joins("JOIN ledger_transactions t ON t.id = e.transaction_id").
+		Where("t.kind = ? AND t.order_id = ? AND t.user_id = ? AND e.amount > 0", model.LedgerKindOrderDeduct, orderID, userID).
+		Order("e.id").
+		Limit(1).
+		Scan(&entry).Error
+	if err == nil && entry.Account != "" {
+		counterpart.Account = entry.Account
+		counterpart.PlatformAccountID = entry.PlatformAccountID
+	}
+	return counterpart
+}
+
+// AccountBalance 由分录汇总科目余额
+func (l *Ledger) AccountBalance(ctx context.Context, account string) (money.Money, error) {
+	var sum money.Money
+	err := l.db.WithContext(ctx).Model(&model.LedgerEntry{}).
+		Where("account = ?", account).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+// ListTransactions 按用户或订单查询凭证及分录，按记账时间倒序
+func (l *Ledger) ListTransactions(ctx context.Context, userID, orderID int64, offset, limit int) ([]*model.LedgerTransaction, int64, error) {
+	db := l.db.WithContext(ctx).Model(&model.LedgerTransaction{})
+	if userID > 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if orderID > 0 {
+		db = db.Where("order_id = ?", orderID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var txns []*model.LedgerTransaction
+	err := db.Preload("Entries").Order("id DESC").Offset(offset).Limit(limit).Find(&txns).Error
+	return txns, total, err
+}
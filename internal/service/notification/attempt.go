@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"recharge-go/internal/model/notification"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAttemptRequestBody 投递记录保存的最大请求体长度
+	maxAttemptRequestBody = 16 << 10
+	// maxAttemptResponseBody 投递记录保存的最大响应体长度
+	maxAttemptResponseBody = 4 << 10
+)
+
+// sensitiveHeaders 投递记录中需要脱敏的请求头，按小写比较
+var sensitiveHeaders = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"x-api-key":     true,
+	"auth_token":    true,
+}
+
+type deliveryAttemptKey struct{}
+
+// WithDeliveryAttempt 在 ctx 中挂载投递记录，发送方通过 CaptureRequest、CaptureResponse 填充请求与响应
+func WithDeliveryAttempt(ctx context.Context, attempt *notification.DeliveryAttempt) context.Context {
+	return context.WithValue(ctx, deliveryAttemptKey{}, attempt)
+}
+
+// CaptureRequest 记录发送的请求，ctx 中没有投递记录时忽略
+func CaptureRequest(ctx context.Context, req *http.Request, body []byte) {
+	attempt, ok := ctx.Value(deliveryAttemptKey{}).(*notification.DeliveryAttempt)
+	if !ok || attempt == nil {
+		return
+	}
+
+	headers := make(map[string]string, len(req.Header))
+	for k, v := range req.Header {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			headers[k] = "***"
+			continue
+		}
+		headers[k] = strings.Join(v, ",")
+	}
+	data, _ := json.Marshal(headers)
+
+	attempt.URL = req.URL.String()
+	attempt.RequestHeaders = string(data)
+	attempt.RequestBody = truncateBody(body, maxAttemptRequestBody)
+}
+
+// CaptureResponse 记录收到的响应与耗时，ctx 中没有投递记录时忽略
+func CaptureResponse(ctx context.Context, statusCode int, body []byte, latency time.Duration) {
+	attempt, ok := ctx.Value(deliveryAttemptKey{}).(*notification.DeliveryAttempt)
+	if !ok || attempt == nil {
+		return
+	}
+	attempt.HTTPStatus = statusCode
+	attempt.ResponseBody = truncateBody(body, maxAttemptResponseBody)
+	attempt.LatencyMs = latency.Milliseconds()
+}
+
+// truncateBody 按字节截断并去掉被截断的不完整字符
+func truncateBody(body []byte, max int) string {
+	if len(body) <= max {
+		return string(body)
+	}
+	return strings.ToValidUTF8(string(body[:max]), "") + "...(truncated)"
+}
@@ -9,6 +9,7 @@ import (
 	"recharge-go/internal/model/notification"
 	notificationModel "recharge-go/internal/model/notification"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/signature/webhook"
 	"time"
 )
 
@@ -59,9 +60,9 @@ func (s *HTTPSender) Send(ctx context.Context, record *notification.Notification
 	// 构建请求体
 	requestBody := make(map[string]interface{})
 
-	// 合并模板参数和通知内容
+	// 合并模板参数和通知内容，签名密钥不随请求体发送
 	for k, v := range templateData {
-		if k != "url" && k != "method" {
+		if k != "url" && k != "method" && k != "secret" {
 			requestBody[k] = v
 		}
 	}
@@ -90,6 +91,9 @@ func (s *HTTPSender) Send(ctx context.Context, record *notification.Notification
 			}
 		}
 	}
+	if secret, ok := templateData["secret"].(string); ok && secret != "" {
+		webhook.SetHeaders(req.Header, secret, bodyBytes, time.Now())
+	}
 
 	// 发送请求
 	resp, err := s.client.Do(req)
@@ -164,9 +168,8 @@ func (s *WebhookSender) Send(ctx context.Context, record *notification.Notificat
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	if secret, ok := templateData["secret"].(string); ok {
-		// TODO: 实现签名逻辑
-		req.Header.Set("X-Webhook-Signature", secret)
+	if secret, ok := templateData["secret"].(string); ok && secret != "" {
+		webhook.SetHeaders(req.Header, secret, bodyBytes, time.Now())
 	}
 
 	// 发送请求
@@ -2,12 +2,21 @@ package notification
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"recharge-go/internal/model/notification"
 	notificationRepo "recharge-go/internal/repository/notification"
+	"recharge-go/pkg/logger"
 	"recharge-go/pkg/queue"
 	"time"
 )
 
+// ErrNotificationNotRetryable 通知不是失败待重试或已放弃状态，不能手动重试
+var ErrNotificationNotRetryable = errors.New("notification not retryable")
+
+// maxDeliveryAttempts 查询订单投递记录的最大条数
+const maxDeliveryAttempts = 100
+
 // NotificationService 通知服务接口
 type NotificationService interface {
 	// CreateNotification 创建通知
@@ -16,25 +25,41 @@ type NotificationService interface {
 	GetNotificationStatus(ctx context.Context, id int64) (*notification.NotificationRecord, error)
 	// ListNotifications 获取通知列表
 	ListNotifications(ctx context.Context, params map[string]interface{}, page, pageSize int) ([]*notification.NotificationRecord, int64, error)
-	// RetryFailedNotification 重试失败的通知
+	// RetryFailedNotification 手动重试失败待重试或已放弃的通知
 	RetryFailedNotification(ctx context.Context, id int64) error
 	// UpdateNotificationStatus 更新通知状态
 	UpdateNotificationStatus(ctx context.Context, id int64, status int) error
 	// GetNotification 获取通知记录
 	GetNotification(ctx context.Context, id int64) (*notification.NotificationRecord, error)
+	// ClaimNotification 抢占通知，返回 false 表示通知已被其他协程处理或尚未到重试时间
+	ClaimNotification(ctx context.Context, record *notification.NotificationRecord) (bool, error)
+	// ScheduleRetry 记录投递失败并安排下次投递
+	ScheduleRetry(ctx context.Context, id int64, retryCount int, nextRetryTime time.Time, lastError string) error
+	// GiveUpNotification 放弃通知
+	GiveUpNotification(ctx context.Context, id int64, retryCount int, lastError string) error
+	// GetDueNotifications 获取已到重试时间的通知
+	GetDueNotifications(ctx context.Context, limit int) ([]*notification.NotificationRecord, error)
+	// ResetStaleNotifications 将停留在待处理或处理中超过 before 的通知转为立即重试
+	ResetStaleNotifications(ctx context.Context, before time.Time) (int64, error)
+	// RecordDeliveryAttempt 保存一次投递记录
+	RecordDeliveryAttempt(ctx context.Context, attempt *notification.DeliveryAttempt) error
+	// ListDeliveryAttempts 获取订单最近的投递记录
+	ListDeliveryAttempts(ctx context.Context, orderID int64) ([]*notification.DeliveryAttempt, error)
 }
 
 // notificationService 通知服务实现
 type notificationService struct {
-	recordRepo notificationRepo.Repository
-	queue      queue.Queue
+	recordRepo  notificationRepo.Repository
+	attemptRepo *notificationRepo.AttemptRepository
+	queue       queue.Queue
 }
 
 // NewNotificationService 创建通知服务实例
-func NewNotificationService(recordRepo notificationRepo.Repository, queue queue.Queue) NotificationService {
+func NewNotificationService(recordRepo notificationRepo.Repository, attemptRepo *notificationRepo.AttemptRepository, queue queue.Queue) NotificationService {
 	return &notificationService{
-		recordRepo: recordRepo,
-		queue:      queue,
+		recordRepo:  recordRepo,
+		attemptRepo: attemptRepo,
+		queue:       queue,
 	}
 }
 
@@ -53,27 +78,24 @@ func (s *notificationService) ListNotifications(ctx context.Context, params map[
 	return s.recordRepo.List(ctx, params, page, pageSize)
 }
 
-// RetryFailedNotification 重试失败的通知
+// RetryFailedNotification 手动重试失败待重试或已放弃的通知
+// 通知转为立即重试后推送到队列，推送失败时由重试调度补投
 func (s *notificationService) RetryFailedNotification(ctx context.Context, id int64) error {
-	record, err := s.recordRepo.GetByID(ctx, id)
+	ok, err := s.recordRepo.Requeue(ctx, id)
 	if err != nil {
 		return err
 	}
+	if !ok {
+		return fmt.Errorf("%w: notification %d", ErrNotificationNotRetryable, id)
+	}
 
-	// 更新重试次数和下次重试时间
-	record.RetryCount++
-	record.NextRetryTime = time.Now().Add(time.Minute * 5) // 5分钟后重试
-	record.Status = 1                                      // 重置为待处理状态
-
-	if err := s.recordRepo.Update(ctx, record); err != nil {
+	record, err := s.recordRepo.GetByID(ctx, id)
+	if err != nil {
 		return err
 	}
-
-	// 推送到队列，确保异步任务能处理
 	if err := s.queue.Push(ctx, "notification_queue", record); err != nil {
-		return err
+		logger.Error("手动重试通知入队失败，等待重试调度", "notification_id", id, "error", err)
 	}
-
 	return nil
 }
 
@@ -86,3 +108,38 @@ func (s *notificationService) UpdateNotificationStatus(ctx context.Context, id i
 func (s *notificationService) GetNotification(ctx context.Context, id int64) (*notification.NotificationRecord, error) {
 	return s.recordRepo.GetByID(ctx, id)
 }
+
+// ClaimNotification 抢占通知
+func (s *notificationService) ClaimNotification(ctx context.Context, record *notification.NotificationRecord) (bool, error) {
+	return s.recordRepo.Claim(ctx, record.ID, record.RetryCount)
+}
+
+// ScheduleRetry 记录投递失败并安排下次投递
+func (s *notificationService) ScheduleRetry(ctx context.Context, id int64, retryCount int, nextRetryTime time.Time, lastError string) error {
+	return s.recordRepo.MarkRetry(ctx, id, retryCount, nextRetryTime, lastError)
+}
+
+// GiveUpNotification 放弃通知
+func (s *notificationService) GiveUpNotification(ctx context.Context, id int64, retryCount int, lastError string) error {
+	return s.recordRepo.MarkGaveUp(ctx, id, retryCount, lastError)
+}
+
+// GetDueNotifications 获取已到重试时间的通知
+func (s *notificationService) GetDueNotifications(ctx context.Context, limit int) ([]*notification.NotificationRecord, error) {
+	return s.recordRepo.GetDueRecords(ctx, time.Now(), limit)
+}
+
+// ResetStaleNotifications 将停留在待处理或处理中超过 before 的通知转为立即重试
+func (s *notificationService) ResetStaleNotifications(ctx context.Context, before time.Time) (int64, error) {
+	return s.recordRepo.ResetStale(ctx, before)
+}
+
+// RecordDeliveryAttempt 保存一次投递记录
+func (s *notificationService) RecordDeliveryAttempt(ctx context.Context, attempt *notification.DeliveryAttempt) error {
+	return s.attemptRepo.Create(ctx, attempt)
+}
+
+// ListDeliveryAttempts 获取订单最近的投递记录
+func (s *notificationService) ListDeliveryAttempts(ctx context.Context, orderID int64) ([]*notification.DeliveryAttempt, error) {
+	return s.attemptRepo.ListByOrderID(ctx, orderID, maxDeliveryAttempts)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCreditedAmount 部分充值的到账金额无效
+var ErrInvalidCreditedAmount = errors.New("invalid credited amount")
+
+// PartialSettlement 部分充值结算结果
+type PartialSettlement struct {
+	OrderID        int64       `json:"order_id"`
+	Denom          money.Money `json:"denom"`           // 订单面值
+	CreditedAmount money.Money `json:"credited_amount"` // 实际到账面值
+	RefundAmount   money.Money `json:"refund_amount"`   // 退还金额
+	Duplicated     bool        `json:"duplicated"`      // 订单此前已按部分充值结算
+}
+
+// OrderPartialService 部分充值结算服务
+// 上游只到账部分面值时，按未到账面值占比退还扣款，并将订单置为部分充值
+type OrderPartialService struct {
+	db                   *gorm.DB
+	stateMachine         *OrderStateMachine
+	unifiedRefundService *UnifiedRefundService
+}
+
+// NewOrderPartialService 创建部分充值结算服务
+func NewOrderPartialService(db *gorm.DB, stateMachine *OrderStateMachine, unifiedRefundService *UnifiedRefundService) *OrderPartialService {
+	return &OrderPartialService{
+		db:                   db,
+		stateMachine:         stateMachine,
+		unifiedRefundService: unifiedRefundService,
+	}
+}
+
+// ParseCreditedAmount 解析上游返回的到账金额，为空时返回 ErrInvalidCreditedAmount
+func ParseCreditedAmount(value string) (money.Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("%w: empty", ErrInvalidCreditedAmount)
+	}
+	amount, err := money.Parse(value)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCreditedAmount, value)
+	}
+	return amount, nil
+}
+
+// PartialRefundAmount 按未到账面值占比计算退款金额（按分整数运算，四舍五入到分）
+// 退款基数为订单扣款金额 Price，未记录时取用户实付 UserPayment
+func PartialRefundAmount(order *model.Order, credited money.Money) (money.Money, error) {
+	denom := order.Denom.Cents()
+	got := credited.Cents()
+	if denom <= 0 {
+		return 0, fmt.Errorf("%w: order %d has no denom", ErrInvalidCreditedAmount, order.ID)
+	}
+	if got <= 0 || got >= denom {
+		return 0, fmt.Errorf("%w: %s not in (0, %s)", ErrInvalidCreditedAmount, credited, order.Denom)
+	}
+
+	base := order.Price
+	if base <= 0 {
+		base = order.UserPayment
+	}
+	refund := (base.Cents()*(denom-got)*2 + denom) / (2 * denom)
+	return money.FromCents(refund), nil
+}
+
+// Settle 按实际到账面值结算部分充值订单：退还未到账部分并流转为部分充值
+// 订单已是部分充值时视为重复结算，直接返回已记录的结果
+func (s *OrderPartialService) Settle(ctx context.Context, orderID int64, credited money.Money, t OrderTransition) (*PartialSettlement, error) {
+	var settlement *PartialSettlement
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order model.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderID).First(&order).Error; err != nil {
+			return fmt.Errorf("get order failed: %v", err)
+		}
+		var err error
+		settlement, err = s.SettleWithTx(ctx, tx, &order, credited, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// SettleWithTx 在调用方事务内结算已加行锁的部分充值订单
+func (s *OrderPartialService) SettleWithTx(ctx context.Context, tx *gorm.DB, order *model.Order, credited money.Money, t OrderTransition) (*PartialSettlement, error) {
+	if order.Status == model.OrderStatusPartial {
+		return &PartialSettlement{
+			OrderID:        order.ID,
+			Denom:          order.Denom,
+			CreditedAmount: order.CreditedAmount,
+			RefundAmount:   order.RefundedAmount,
+			Duplicated:     true,
+		}, nil
+	}
+	if !CanTransitOrder(order.Status, model.OrderStatusPartial) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrIllegalOrderTransition, order.Status, model.OrderStatusPartial)
+	}
+
+	refund, err := PartialRefundAmount(order, credited)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund > 0 {
+		if err := s.refund(ctx, tx, order, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Reason == "" {
+		t.Reason = fmt.Sprintf("部分充值: 面值%s, 到账%s, 退款%s", order.Denom, credited, refund)
+	}
+	if t.Fields == nil {
+		t.Fields = map[string]interface{}{}
+	}
+	t.Fields["credited_amount"] = credited.Round()
+	t.Fields["refunded_amount"] = refund
+	t.Fields["finish_time"] = time.Now()
+	t.SettleHold = true
+	if err := s.stateMachine.Transit(ctx, tx, order.ID, order.Status, model.OrderStatusPartial, t); err != nil {
+		return nil, err
+	}
+
+	logger.Info("部分充值结算完成",
+		"order_id", order.ID,
+		"denom", order.Denom,
+		"credited_amount", credited,
+		"refund_amount", refund)
+	return &PartialSettlement{
+		OrderID:        order.ID,
+		Denom:          order.Denom,
+		CreditedAmount: credited,
+		RefundAmount:   refund,
+	}, nil
+}
+
+// refund 通过统一退款服务退还未到账部分，外部订单退到用户余额，平台订单退到平台账号
+func (s *OrderPartialService) refund(ctx context.Context, tx *gorm.DB, order *model.Order, amount money.Money) error {
+	req := &RefundRequest{
+		UserID:   order.CustomerID,
+		OrderID:  order.ID,
+		Amount:   amount,
+		Remark:   fmt.Sprintf("部分充值退还未到账部分 %s", amount),
+		Operator: "system",
+		Type:     RefundTypeUser,
+		Tx:       tx,
+	}
+	if order.Client != 2 {
+		req.Type = RefundTypePlatform
+		req.AccountID = &order.PlatformAccountID
+	}
+
+	resp, err := s.unifiedRefundService.ProcessRefund(ctx, req)
+	if err != nil {
+		return fmt.Errorf("partial refund failed: %v", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("partial refund failed: %s", resp.Message)
+	}
+	return nil
+}
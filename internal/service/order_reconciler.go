@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"recharge-go/configs"
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 对账触发方式
+const (
+	OrderReconcileSourceSchedule = "schedule" // 定时任务
+	OrderReconcileSourceManual   = "manual"   // 后台手动触发
+)
+
+// orderManualAfter 不自动失败的订单超过该时长仍未完成时标记为需人工处理
+const orderManualAfter = 24 * time.Hour
+
+// OrderSLAPolicy 订单在某一非终态下的超时处置策略
+type OrderSLAPolicy struct {
+	Status    model.OrderStatus `json:"status"`
+	SLA       time.Duration     `json:"sla"`        // 停留超过该时长视为滞留
+	Action    string            `json:"action"`     // 滞留时的处置动作，见 model.OrderReconcileAction*
+	FailAfter time.Duration     `json:"fail_after"` // 订单创建超过该时长仍未完成时失败退款，0 表示不自动失败
+}
+
+// OrderReconcileConfig 滞留订单对账配置
+type OrderReconcileConfig struct {
+	Interval  time.Duration    // 定时对账间隔
+	BatchSize int              // 每种状态每轮最多处置的订单数
+	Policies  []OrderSLAPolicy // 各非终态的处置策略
+}
+
+// DefaultOrderReconcileConfig 默认对账配置
+// 待充值重新投递，处理中/充值中查询上游，24小时仍未提交成功的订单失败退款；
+// 充值中订单可能已在上游到账，不自动失败
+func DefaultOrderReconcileConfig() OrderReconcileConfig {
+	return OrderReconcileConfig{
+		Interval:  5 * time.Minute,
+		BatchSize: 200,
+		Policies: []OrderSLAPolicy{
+			{Status: model.OrderStatusPendingPayment, SLA: 30 * time.Minute, Action: model.OrderReconcileActionCancel},
+			{Status: model.OrderStatusPendingRecharge, SLA: 10 * time.Minute, Action: model.OrderReconcileActionRequeue, FailAfter: 24 * time.Hour},
+			{Status: model.OrderStatusProcessing, SLA: 10 * time.Minute, Action: model.OrderReconcileActionQuery, FailAfter: 24 * time.Hour},
+			{Status: model.OrderStatusRecharging, SLA: 5 * time.Minute, Action: model.OrderReconcileActionQuery},
+			{Status: model.OrderStatusSplit, SLA: 10 * time.Minute, Action: model.OrderReconcileActionSettle},
+		},
+	}
+}
+
+// OrderReconcileConfigFromSettings 由配置文件的对账配置生成滞留订单对账配置，未配置的项使用默认值
+func OrderReconcileConfigFromSettings(rc configs.ReconcileConfig) OrderReconcileConfig {
+	cfg := DefaultOrderReconcileConfig()
+	if rc.IntervalSeconds > 0 {
+		cfg.Interval = time.Duration(rc.IntervalSeconds) * time.Second
+	}
+	if rc.BatchSize > 0 {
+		cfg.BatchSize = rc.BatchSize
+	}
+
+	minutes := func(v int, d *time.Duration) {
+		switch {
+		case v > 0:
+			*d = time.Duration(v) * time.Minute
+		case v < 0:
+			*d = 0
+		}
+	}
+	for i := range cfg.Policies {
+		policy := &cfg.Policies[i]
+		switch policy.Status {
+		case model.OrderStatusPendingPayment:
+			minutes(rc.PendingPaymentSLA, &policy.SLA)
+		case model.OrderStatusPendingRecharge:
+			minutes(rc.PendingRechargeSLA, &policy.SLA)
+			minutes(rc.PendingRechargeFailAfter, &policy.FailAfter)
+		case model.OrderStatusProcessing:
+			minutes(rc.ProcessingSLA, &policy.SLA)
+			minutes(rc.ProcessingFailAfter, &policy.FailAfter)
+		case model.OrderStatusRecharging:
+			minutes(rc.RechargingSLA, &policy.SLA)
+			minutes(rc.RechargingFailAfter, &policy.FailAfter)
+		case model.OrderStatusSplit:
+			minutes(rc.SplitSLA, &policy.SLA)
+		}
+	}
+	return cfg
+}
+
+// OrderReconciler 滞留订单对账
+// 扫描停留在非终态超过 SLA 的订单，按策略重新投递、查询上游、失败退款或取消，并生成对账报告
+type OrderReconciler struct {
+	db              *gorm.DB
+	rechargeService RechargeService
+	orderService    OrderService
+	reportRepo      *repository.OrderReconcileReportRepository
+	config          OrderReconcileConfig
+}
+
+// NewOrderReconciler 创建滞留订单对账
+func NewOrderReconciler(db *gorm.DB, rechargeService RechargeService, orderService OrderService, reportRepo *repository.OrderReconcileReportRepository, config OrderReconcileConfig) *OrderReconciler {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultOrderReconcileConfig().BatchSize
+	}
+	return &OrderReconciler{
+		db:              db,
+		rechargeService: rechargeService,
+		orderService:    orderService,
+		reportRepo:      reportRepo,
+		config:          config,
+	}
+}
+
+// Policies 获取处置策略
+func (r *OrderReconciler) Policies() []OrderSLAPolicy {
+	return r.config.Policies
+}
+
+// Run 按间隔定时对账，直到 ctx 结束
+func (r *OrderReconciler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = r.config.Interval
+	}
+	logger.Info("滞留订单对账任务启动", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("滞留订单对账任务停止")
+			return
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx, OrderReconcileSourceSchedule, false); err != nil {
+				logger.Error("滞留订单对账失败", "error", err)
+			}
+		}
+	}
+}
+
+// Reconcile 执行一轮对账，dryRun 为 true 时只生成报告不处置
+// 定时对账未发现滞留订单时不保存报告
+func (r *OrderReconciler) Reconcile(ctx context.Context, source string, dryRun bool) (*model.OrderReconcileReport, error) {
+	now := time.Now()
+	report := &model.OrderReconcileReport{Source: source, DryRun: dryRun, StartedAt: now}
+
+	var items []model.OrderReconcileItem
+	for _, policy := range r.config.Policies {
+		if policy.SLA <= 0 {
+			continue
+		}
+		var orders []*model.Order
+		err := r.db.WithContext(ctx).
+			Where("status = ? AND updated_at < ?", policy.Status, now.Add(-policy.SLA)).
+			Order("updated_at ASC").
+			Limit(r.config.BatchSize).
+			Find(&orders).Error
+		if err != nil {
+			return nil, fmt.Errorf("get stuck orders failed: %v", err)
+		}
+
+		for _, order := range orders {
+			item, outcome := r.reconcileOrder(ctx, order, policy, now, dryRun)
+			countReconcileItem(report, item, outcome)
+			items = append(items, item)
+		}
+	}
+
+	report.Scanned = len(items)
+	report.FinishedAt = time.Now()
+	if items == nil {
+		items = []model.OrderReconcileItem{}
+	}
+	data, _ := json.Marshal(items)
+	report.Items = string(data)
+
+	logger.Info("滞留订单对账完成",
+		"source", source,
+		"dry_run", dryRun,
+		"scanned", report.Scanned,
+		"requeued", report.Requeued,
+		"queried", report.Queried,
+		"resolved", report.Resolved,
+		"failed", report.Failed,
+		"cancelled", report.Cancelled,
+		"settled", report.Settled,
+		"manual", report.Manual,
+		"errors", report.Errors)
+
+	if source == OrderReconcileSourceSchedule && report.Scanned == 0 {
+		return report, nil
+	}
+	if err := r.reportRepo.Create(ctx, report); err != nil {
+		return report, fmt.Errorf("save reconcile report failed: %v", err)
+	}
+	return report, nil
+}
+
+// requeue 重新投递充值任务
+// 任务已进入死信时投递会被当作已存在而跳过，需先删除死信再投递，重试次数重新计算
+func (r *OrderReconciler) requeue(ctx context.Context, orderID int64) (string, error) {
+	result := "已重新投递充值队列"
+	if queue := r.rechargeService.GetRechargeQueue(); queue != nil {
+		dead, err := queue.IsDead(orderID)
+		if err != nil {
+			return "", fmt.Errorf("get recharge task failed: %v", err)
+		}
+		if dead {
+			if err := queue.DeleteDead(orderID); err != nil {
+				return "", fmt.Errorf("delete dead recharge task failed: %v", err)
+			}
+			result = "已删除死信任务并重新投递充值队列"
+		}
+	}
+	if err := r.rechargeService.PushToRechargeQueue(ctx, orderID); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// reconcileOutcome 单笔订单处置过程
+type reconcileOutcome struct {
+	queried  bool // 查询过上游
+	resolved bool // 查询或汇总后订单已到终态
+}
+
+// reconcileOrder 按策略处置单笔滞留订单
+func (r *OrderReconciler) reconcileOrder(ctx context.Context, order *model.Order, policy OrderSLAPolicy, now time.Time, dryRun bool) (model.OrderReconcileItem, reconcileOutcome) {
+	item := model.OrderReconcileItem{
+		OrderID:     order.ID,
+		OrderNumber: order.OrderNumber,
+		Status:      order.Status,
+		StuckFor:    now.Sub(order.UpdatedAt).Truncate(time.Second).String(),
+		Action:      policy.Action,
+	}
+
+	age := now.Sub(orderCreateTime(order))
+	failDue := policy.FailAfter > 0 && age >= policy.FailAfter
+	if failDue && policy.Action == model.OrderReconcileActionRequeue {
+		item.Action = model.OrderReconcileActionFail
+	}
+	if dryRun {
+		item.Result = "预演，未处置"
+		return item, reconcileOutcome{}
+	}
+
+	var err error
+	var outcome reconcileOutcome
+	switch item.Action {
+	case model.OrderReconcileActionRequeue:
+		item.Result, err = r.requeue(ctx, order.ID)
+	case model.OrderReconcileActionFail:
+		err = r.orderService.ProcessOrderFail(ctx, order.ID, fmt.Sprintf("超过%s未完成，对账失败退款", policy.FailAfter))
+		item.Result = "已失败退款"
+	case model.OrderReconcileActionCancel:
+		err = r.orderService.ProcessOrderCancel(ctx, order.ID, "超时未支付，对账自动取消")
+		item.Result = "已取消"
+	case model.OrderReconcileActionSettle:
+		split := r.rechargeService.GetSplitService()
+		if split == nil {
+			err = fmt.Errorf("split service is nil")
+			break
+		}
+		if err = split.Settle(ctx, order.OrderNumber); err == nil {
+			item.Result = "子订单未全部完成"
+			if current, getErr := r.rechargeService.GetOrderByID(ctx, order.ID); getErr == nil && current.Status != model.OrderStatusSplit {
+				outcome.resolved = true
+				item.Result = fmt.Sprintf("已汇总为%s", current.Status)
+			}
+		}
+	case model.OrderReconcileActionQuery:
+		outcome.queried = true
+		outcome.resolved, item.Result, err = r.queryAndApply(ctx, order)
+		if err != nil || outcome.resolved {
+			break
+		}
+		if failDue {
+			item.Action = model.OrderReconcileActionFail
+			err = r.orderService.ProcessOrderFail(ctx, order.ID, fmt.Sprintf("超过%s未完成，对账失败退款", policy.FailAfter))
+			item.Result = "上游未完成，已失败退款"
+		} else if policy.FailAfter == 0 && age >= orderManualAfter {
+			item.Action = model.OrderReconcileActionManual
+			item.Result = fmt.Sprintf("超过%s上游仍未完成，需人工处理", orderManualAfter)
+		}
+	default:
+		err = fmt.Errorf("unknown reconcile action: %s", item.Action)
+	}
+
+	if err != nil {
+		item.Error = err.Error()
+		item.Result = "处置失败"
+		logger.Error("滞留订单处置失败", "order_id", order.ID, "action", item.Action, "error", err)
+	}
+	return item, outcome
+}
+
+// queryAndApply 查询上游订单状态，已到终态时按回调流程完成订单
+func (r *OrderReconciler) queryAndApply(ctx context.Context, order *model.Order) (bool, string, error) {
+	queried, err := r.rechargeService.QueryOrderStatus(ctx, order)
+	if err != nil {
+		return false, "", fmt.Errorf("query order status failed: %v", err)
+	}
+
+	result := NewQueryOrderResult(order, queried, "对账查询上游")
+	if !result.IsFinal() {
+		return false, "上游处理中", nil
+	}
+	if result.Status == model.OrderStatusPartial && result.CreditedAmount == "" {
+		return false, "上游部分充值但未返回到账金额", nil
+	}
+
+	if _, err := r.rechargeService.SettleOrderResult(ctx, order, result); err != nil {
+		return false, "", err
+	}
+	return true, fmt.Sprintf("上游已%s，订单已更新", queried.Status), nil
+}
+
+// countReconcileItem 累计报告中各处置动作的数量
+func countReconcileItem(report *model.OrderReconcileReport, item model.OrderReconcileItem, outcome reconcileOutcome) {
+	if outcome.queried {
+		report.Queried++
+	}
+	if item.Error != "" {
+		report.Errors++
+		return
+	}
+	if report.DryRun {
+		return
+	}
+	switch item.Action {
+	case model.OrderReconcileActionRequeue:
+		report.Requeued++
+	case model.OrderReconcileActionFail:
+		report.Failed++
+	case model.OrderReconcileActionCancel:
+		report.Cancelled++
+	case model.OrderReconcileActionSettle:
+		if outcome.resolved {
+			report.Settled++
+		}
+	case model.OrderReconcileActionManual:
+		report.Manual++
+	case model.OrderReconcileActionQuery:
+		if outcome.resolved {
+			report.Resolved++
+		}
+	}
+}
+
+// orderCreateTime 订单创建时间，优先取 CreateTime
+func orderCreateTime(order *model.Order) time.Time {
+	if !order.CreateTime.IsZero() {
+		return order.CreateTime
+	}
+	return order.CreatedAt
+}
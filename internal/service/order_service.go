@@ -10,10 +10,12 @@ import (
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/lock"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"recharge-go/pkg/queue"
 	"strconv"
 	"time"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrderService 订单服务接口
@@ -38,6 +40,8 @@ type OrderService interface {
 	ProcessOrderSuccess(ctx context.Context, orderID int64) error
 	// ProcessOrderFail 处理订单失败
 	ProcessOrderFail(ctx context.Context, orderID int64, remark string) error
+	// ProcessOrderFailWithTx 在调用方事务内将已加行锁的订单置为失败并退款，不发送通知
+	ProcessOrderFailWithTx(ctx context.Context, tx *gorm.DB, order *model.Order, t OrderTransition) error
 	// ProcessOrderRefund 处理订单退款
 	ProcessOrderRefund(ctx context.Context, orderID int64, remark string) error
 	// ProcessExternalRefund 处理外部订单退款
@@ -48,8 +52,8 @@ type OrderService interface {
 	ProcessOrderCancel(ctx context.Context, orderID int64, remark string) error
 	// ProcessOrderSplit 处理订单拆单
 	ProcessOrderSplit(ctx context.Context, orderID int64, remark string) error
-	// ProcessOrderPartial 处理订单部分充值
-	ProcessOrderPartial(ctx context.Context, orderID int64, remark string) error
+	// ProcessOrderPartial 处理订单部分充值，按实际到账面值退还未到账部分
+	ProcessOrderPartial(ctx context.Context, orderID int64, creditedAmount money.Money, remark string) error
 	// GetOrders 获取订单列表
 	GetOrders(ctx context.Context, params map[string]interface{}, page, pageSize int) ([]*model.Order, int64, error)
 	// GetOrdersWithNotification 获取包含通知信息的订单列表
@@ -70,6 +74,10 @@ type OrderService interface {
 	GetOrdersByUserID(ctx context.Context, userID int64, params map[string]interface{}, page, pageSize int) ([]*model.Order, int64, error)
 	// SendNotification 发送订单回调通知
 	SendNotification(ctx context.Context, orderID int64) error
+	// GetOrderStatusHistory 获取订单状态流转记录
+	GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error)
+	// StateMachine 获取订单状态机
+	StateMachine() *OrderStateMachine
 }
 
 type OrderStatistics struct {
@@ -93,6 +101,9 @@ type orderService struct {
 	lockManager      *lock.RefundLockManager
 	db               *gorm.DB
 	creditService    *CreditService
+	stateMachine     *OrderStateMachine
+	partialService   *OrderPartialService
+	outbox           *Outbox
 }
 
 // NewOrderService 创建订单服务实例
@@ -109,6 +120,7 @@ func NewOrderService(
 	productRepo repository.ProductRepository,
 	creditService *CreditService,
 ) OrderService {
+	stateMachine := NewOrderStateMachine(db)
 	return &orderService{
 		orderRepo:        orderRepo,
 		rechargeService:  rechargeService,
@@ -121,6 +133,9 @@ func NewOrderService(
 		lockManager:      lockManager,
 		db:               db,
 		creditService:    creditService,
+		stateMachine:     stateMachine,
+		partialService:   NewOrderPartialService(db, stateMachine, unifiedRefundService),
+		outbox:           NewOutbox(db, queue),
 	}
 }
 
@@ -183,8 +198,29 @@ func isSuperAdmin(ctx context.Context) bool {
 	return false
 }
 
+// fundsOrderStatuses 需经对应处理流程结清冻结款或退款的订单状态，通用的状态修改不能直接置为这些状态
+var fundsOrderStatuses = []model.OrderStatus{
+	model.OrderStatusSuccess,
+	model.OrderStatusFailed,
+	model.OrderStatusRefunded,
+	model.OrderStatusCancelled,
+	model.OrderStatusSplit,
+	model.OrderStatusPartial,
+}
+
 // UpdateOrderStatus 更新订单状态
+// 成功、失败、退款等涉及资金变动的状态需经对应的处理方法，此处拒绝
 func (s *orderService) UpdateOrderStatus(ctx context.Context, id int64, status model.OrderStatus) error {
+	for _, funds := range fundsOrderStatuses {
+		if status == funds {
+			return fmt.Errorf("%w: 订单状态%s涉及资金变动，请使用对应的处理接口", ErrIllegalOrderTransition, status)
+		}
+	}
+	return s.updateOrderStatus(ctx, id, status, false)
+}
+
+// updateOrderStatus 经状态机更新订单状态并写入通知，settleHold 为 true 时同时结清下单冻结款
+func (s *orderService) updateOrderStatus(ctx context.Context, id int64, status model.OrderStatus, settleHold bool) error {
 	// 安全获取用户ID，如果不存在则使用0（系统操作）
 	var userID int64
 	if uid := ctx.Value("user_id"); uid != nil {
@@ -243,8 +279,14 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, id int64, status m
 		return nil
 	}
 
-	// 更新订单状态
-	if err := tx.Model(&model.Order{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+	// 已拆单的父订单状态由子订单汇总得出
+	if order.Status == model.OrderStatusSplit {
+		tx.Rollback()
+		return fmt.Errorf("订单已拆单，状态由子订单汇总")
+	}
+
+	// 经状态机更新订单状态，非法流转或并发修改时失败
+	if err := s.stateMachine.Transit(ctx, tx, id, order.Status, status, OrderTransition{SettleHold: settleHold}); err != nil {
 		tx.Rollback()
 		logger.Error("更新订单状态失败",
 			"error", err,
@@ -252,28 +294,25 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, id int64, status m
 			"old_status", order.Status,
 			"new_status", status,
 		)
-		return fmt.Errorf("update order status failed: %v", err)
+		return fmt.Errorf("update order status failed: %w", err)
 	}
 
-	// 创建通知记录
-	notification := &notificationModel.NotificationRecord{
+	// 通知记录与状态变更同事务写入发件箱
+	msg, err := s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
 		OrderID:          id,
 		PlatformCode:     order.PlatformCode,
 		NotificationType: "order_status_changed",
 		Content:          fmt.Sprintf("订单状态已更新为: %d", status),
 		Status:           1, // 待处理
-	}
-
-	// 保存通知记录到数据库
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	})
+	if err != nil {
 		tx.Rollback()
 		logger.Error("创建通知记录失败",
 			"error", err,
 			"order_id", id,
 			"platform_code", order.PlatformCode,
-			"notification_type", notification.NotificationType,
 		)
-		return fmt.Errorf("create notification record failed: %v", err)
+		return err
 	}
 
 	// 提交事务
@@ -290,36 +329,10 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, id int64, status m
 		"old_status", order.Status,
 		"new_status", status,
 	)
+	s.settleSplitParent(ctx, order)
 
-	// 事务提交成功后，重新获取订单信息并推送通知到队列
-	updatedOrder, getErr := s.orderRepo.GetByID(ctx, id)
-	if getErr != nil {
-		logger.Error("获取更新后的订单信息失败", "order_id", id, "error", getErr)
-		return nil // 订单状态已更新成功，通知推送失败不影响主流程
-	}
-
-	// 重新创建通知记录，确保包含最新的订单信息
-	updatedNotification := &notificationModel.NotificationRecord{
-		OrderID:          id,
-		PlatformCode:     updatedOrder.PlatformCode,
-		NotificationType: "order_status_changed",
-		Content:          fmt.Sprintf("订单状态已更新为: %d", status),
-		Status:           1, // 待处理
-	}
-
-	// 保存新的通知记录到数据库
-	if createErr := s.notificationRepo.Create(ctx, updatedNotification); createErr != nil {
-		logger.Error("创建更新后的通知记录失败", "order_id", id, "error", createErr)
-		return nil // 订单状态已更新成功，通知推送失败不影响主流程
-	}
-
-	// 推送通知到队列
-	logger.Info("准备推送通知到队列", "order_id", id, "new_status", status)
-	if pushErr := s.queue.Push(ctx, "notification_queue", updatedNotification); pushErr != nil {
-		logger.Error("推送通知到队列失败", "order_id", id, "error", pushErr)
-	} else {
-		logger.Info("推送通知到队列成功", "order_id", id, "status", status)
-	}
+	// 事务提交成功后投递通知，投递失败由发件箱中继补投
+	s.outbox.PublishAll(ctx, msg)
 	return nil
 }
 
@@ -355,8 +368,8 @@ func (s *orderService) ProcessOrderSuccess(ctx context.Context, orderID int64) e
 		return err
 	}
 
-	// 更新订单状态为成功
-	return s.UpdateOrderStatus(ctx, orderID, model.OrderStatusSuccess)
+	// 更新订单状态为成功，扣下冻结款
+	return s.updateOrderStatus(ctx, orderID, model.OrderStatusSuccess, true)
 }
 
 // ProcessOrderFail 处理订单失败
@@ -385,6 +398,7 @@ func (s *orderService) ProcessOrderFail(ctx context.Context, orderID int64, rema
 
 	// 3. 在锁保护下执行事务
 	logger.Info("开始执行事务", "order_id", orderID)
+	var msg *model.OutboxMessage
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		logger.Info("事务内部开始执行", "order_id", orderID)
 		// 使用行锁防止同一订单的并发处理
@@ -401,104 +415,30 @@ func (s *orderService) ProcessOrderFail(ctx context.Context, orderID int64, rema
 			logger.Info("订单已经是失败状态，跳过重复处理", "order_id", orderID)
 			return nil
 		}
-
-		// 如果订单已经支付，需要退还余额
-		if lockedOrder.Status == model.OrderStatusPendingRecharge || lockedOrder.Status == model.OrderStatusRecharging || lockedOrder.Status == model.OrderStatusProcessing {
-			// 使用统一退款服务处理退款
-			var refundReq *RefundRequest
-			if lockedOrder.Client == 2 {
-				// 外部订单直接退款到用户余额
-				logger.Info("外部订单失败，使用统一退款服务退款到用户余额",
-					"order_id", orderID,
-					"customer_id", lockedOrder.CustomerID,
-					"amount", lockedOrder.Price)
-
-				refundReq = &RefundRequest{
-					UserID:   lockedOrder.CustomerID,
-					OrderID:  orderID,
-					Amount:   lockedOrder.Price,
-					Remark:   "外部订单失败退款",
-					Operator: "system",
-					Type:     RefundTypeUser,
-					Tx:       tx,
-				}
-			} else {
-				// 平台订单退款
-				logger.Info("平台订单失败，使用统一退款服务退款",
-					"order_id", orderID,
-					"customer_id", lockedOrder.CustomerID,
-					"platform_account_id", lockedOrder.PlatformAccountID,
-					"amount", lockedOrder.Price)
-
-				refundReq = &RefundRequest{
-					UserID:    lockedOrder.CustomerID,
-					OrderID:   orderID,
-					Amount:    lockedOrder.Price,
-					Remark:    "订单失败退还余额",
-					Operator:  "system",
-					Type:      RefundTypePlatform,
-					AccountID: &lockedOrder.PlatformAccountID,
-					Tx:        tx,
-				}
-			}
-
-			// 执行统一退款
-			refundResp, err := s.unifiedRefundService.ProcessRefund(ctx, refundReq)
-			if err != nil || !refundResp.Success {
-				logger.Error("统一退款服务退款失败",
-					"error", err,
-					"order_id", orderID,
-					"customer_id", lockedOrder.CustomerID,
-					"amount", lockedOrder.Price,
-					"response", refundResp)
-				if err != nil {
-					return fmt.Errorf("统一退款失败: %v", err)
-				}
-				return fmt.Errorf("统一退款失败: %s", refundResp.Message)
-			}
-
-			logger.Info("统一退款服务退款成功",
-				"order_id", orderID,
-				"customer_id", lockedOrder.CustomerID,
-				"amount", refundResp.RefundAmount,
-				"balance_after", refundResp.BalanceAfter,
-				"already_refund", refundResp.AlreadyRefund)
-		}
-
-		// 更新备注
-		if err := tx.Model(&model.Order{}).Where("id = ?", orderID).Update("remark", remark).Error; err != nil {
+		if err := s.ProcessOrderFailWithTx(ctx, tx, &lockedOrder, OrderTransition{Reason: remark}); err != nil {
 			return err
 		}
-
-		// 更新订单状态为失败
-		if err := tx.Model(&model.Order{}).Where("id = ?", orderID).Update("status", model.OrderStatusFailed).Error; err != nil {
-			return err
-		}
-
 		logger.Info("订单失败处理完成",
 			"order_id", orderID,
 			"status", model.OrderStatusFailed)
 
-		// 创建通知记录
-		notification := &notificationModel.NotificationRecord{
+		// 通知记录与订单失败同事务写入发件箱
+		var err error
+		msg, err = s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
 			OrderID:          orderID,
 			PlatformCode:     lockedOrder.PlatformCode,
 			NotificationType: "order_status_changed",
 			Content:          fmt.Sprintf("订单失败: %s", remark),
 			Status:           1, // 待处理
-		}
-
-		// 保存通知记录到数据库
-		logger.Info("准备创建通知记录", "order_id", orderID, "platform_code", lockedOrder.PlatformCode)
-		if err := s.notificationRepo.Create(ctx, notification); err != nil {
+		})
+		if err != nil {
 			logger.Error("创建通知记录失败",
 				"error", err,
 				"order_id", orderID,
-				"platform_code", lockedOrder.PlatformCode,
-				"notification_type", notification.NotificationType)
-			return fmt.Errorf("create notification record failed: %v", err)
+				"platform_code", lockedOrder.PlatformCode)
+			return err
 		}
-		logger.Info("创建通知记录成功", "order_id", orderID, "notification_id", notification.ID)
+		logger.Info("创建通知记录成功", "order_id", orderID, "outbox_id", msg.ID)
 
 		logger.Info("事务内部执行完成", "order_id", orderID)
 		return nil
@@ -506,22 +446,14 @@ func (s *orderService) ProcessOrderFail(ctx context.Context, orderID int64, rema
 	
 	logger.Info("事务执行结果", "order_id", orderID, "error", err)
 
-	// 事务提交成功后，异步推送通知到队列
+	// 事务提交成功后投递通知，投递失败由发件箱中继补投
 	if err == nil {
-		logger.Info("事务提交成功，开始推送通知到队列", "order_id", orderID)
-		// 重新获取已创建的通知记录
-		notification, getErr := s.notificationRepo.GetByOrderID(ctx, orderID)
-		if getErr != nil {
-			logger.Error("获取通知记录失败", "error", getErr, "order_id", orderID)
+		s.settleSplitParent(ctx, order)
+		// 订单此前已是失败状态时没有新的通知
+		if msg == nil {
+			logger.Info("订单已是失败状态，无需推送通知", "order_id", orderID)
 		} else {
-			logger.Info("成功获取通知记录", "order_id", orderID, "notification_id", notification.ID)
-			// 推送通知到队列
-			logger.Info("准备推送订单失败通知到队列", "order_id", orderID, "remark", remark)
-			if pushErr := s.queue.Push(ctx, "notification_queue", notification); pushErr != nil {
-				logger.Error("推送订单失败通知到队列失败", "order_id", orderID, "error", pushErr)
-			} else {
-				logger.Info("推送订单失败通知到队列成功", "order_id", orderID)
-			}
+			s.outbox.PublishAll(ctx, msg)
 		}
 	} else {
 		logger.Error("事务执行失败，跳过推送通知", "order_id", orderID, "error", err)
@@ -530,6 +462,111 @@ func (s *orderService) ProcessOrderFail(ctx context.Context, orderID int64, rema
 	return err
 }
 
+// ProcessOrderFailWithTx 在调用方事务内将已加行锁的订单置为失败并退款，不发送通知
+// t.Reason 同时写入订单备注
+func (s *orderService) ProcessOrderFailWithTx(ctx context.Context, tx *gorm.DB, order *model.Order, t OrderTransition) error {
+	if order.Status == model.OrderStatusSplit {
+		logger.Error("订单已拆单，状态由子订单汇总", "order_id", order.ID)
+		return fmt.Errorf("订单已拆单，状态由子订单汇总")
+	}
+	if !CanTransitOrder(order.Status, model.OrderStatusFailed) {
+		logger.Error("订单状态不允许置为失败", "order_id", order.ID, "status", order.Status)
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalOrderTransition, order.Status, model.OrderStatusFailed)
+	}
+
+	// 如果订单已经支付，需要退还余额
+	if order.Status == model.OrderStatusPendingRecharge || order.Status == model.OrderStatusRecharging || order.Status == model.OrderStatusProcessing {
+		// 使用统一退款服务处理退款
+		var refundReq *RefundRequest
+		if order.Client == 2 {
+			// 外部订单直接退款到用户余额
+			logger.Info("外部订单失败，使用统一退款服务退款到用户余额",
+				"order_id", order.ID,
+				"customer_id", order.CustomerID,
+				"amount", order.Price)
+
+			refundReq = &RefundRequest{
+				UserID:   order.CustomerID,
+				OrderID:  order.ID,
+				Amount:   order.Price,
+				Remark:   "外部订单失败退款",
+				Operator: "system",
+				Type:     RefundTypeUser,
+				Tx:       tx,
+			}
+		} else {
+			// 平台订单退款
+			logger.Info("平台订单失败，使用统一退款服务退款",
+				"order_id", order.ID,
+				"customer_id", order.CustomerID,
+				"platform_account_id", order.PlatformAccountID,
+				"amount", order.Price)
+
+			refundReq = &RefundRequest{
+				UserID:    order.CustomerID,
+				OrderID:   order.ID,
+				Amount:    order.Price,
+				Remark:    "订单失败退还余额",
+				Operator:  "system",
+				Type:      RefundTypePlatform,
+				AccountID: &order.PlatformAccountID,
+				Tx:        tx,
+			}
+		}
+
+		// 执行统一退款
+		refundResp, err := s.unifiedRefundService.ProcessRefund(ctx, refundReq)
+		if err != nil || !refundResp.Success {
+			logger.Error("统一退款服务退款失败",
+				"error", err,
+				"order_id", order.ID,
+				"customer_id", order.CustomerID,
+				"amount", order.Price,
+				"response", refundResp)
+			if err != nil {
+				return fmt.Errorf("统一退款失败: %v", err)
+			}
+			return fmt.Errorf("统一退款失败: %s", refundResp.Message)
+		}
+
+		logger.Info("统一退款服务退款成功",
+			"order_id", order.ID,
+			"customer_id", order.CustomerID,
+			"amount", refundResp.RefundAmount,
+			"balance_after", refundResp.BalanceAfter,
+			"already_refund", refundResp.AlreadyRefund)
+	}
+
+	// 订单未在上游完成，释放提交时占用的平台账号额度
+	if err := s.releaseSpend(ctx, tx, order.ID); err != nil {
+		return err
+	}
+
+	// 更新订单状态为失败并写入备注
+	if t.Fields == nil {
+		t.Fields = map[string]interface{}{}
+	}
+	t.Fields["remark"] = t.Reason
+	t.SettleHold = true
+	return s.stateMachine.Transit(ctx, tx, order.ID, order.Status, model.OrderStatusFailed, t)
+}
+
+// releaseSpend 在事务内释放订单占用的平台账号额度，未设置充值服务或路由服务时跳过
+func (s *orderService) releaseSpend(ctx context.Context, tx *gorm.DB, orderID int64) error {
+	if s.rechargeService == nil {
+		return nil
+	}
+	routing := s.rechargeService.GetRoutingService()
+	if routing == nil || routing.SpendLimiter() == nil {
+		return nil
+	}
+	if err := routing.SpendLimiter().ReleaseOrder(ctx, tx, orderID); err != nil {
+		logger.Error("释放平台账号额度失败", "order_id", orderID, "error", err)
+		return err
+	}
+	return nil
+}
+
 // ProcessOrderRefund 处理订单退款
 func (s *orderService) ProcessOrderRefund(ctx context.Context, orderID int64, remark string) error {
 	// 使用事务确保订单状态更新和退款操作的原子性
@@ -547,14 +584,19 @@ func (s *orderService) ProcessOrderRefund(ctx context.Context, orderID int64, re
 			return fmt.Errorf("订单已退款")
 		}
 
-		// 只有成功、失败、待充值状态的订单可以退款
+		// 只有成功、失败状态的订单可以退款；待充值订单可能正在提交上游，需先置为失败
 		if lockedOrder.Status != model.OrderStatusSuccess &&
-			lockedOrder.Status != model.OrderStatusFailed &&
-			lockedOrder.Status != model.OrderStatusPendingRecharge {
+			lockedOrder.Status != model.OrderStatusFailed {
 			logger.Error("订单状态不允许退款", "order_id", orderID, "status", lockedOrder.Status)
 			return fmt.Errorf("订单状态不允许退款")
 		}
 
+		// 拆单父订单的款项由子订单承担，需对子订单退款
+		if lockedOrder.IsSplitParent() {
+			logger.Error("拆单父订单不能直接退款", "order_id", orderID)
+			return fmt.Errorf("拆单订单请对子订单退款")
+		}
+
 		// 3. 执行退款逻辑
 		if lockedOrder.Client == 2 {
 			// 外部订单退款到用户余额（使用当前事务）
@@ -572,14 +614,15 @@ func (s *orderService) ProcessOrderRefund(ctx context.Context, orderID int64, re
 			}
 			logger.Info("平台订单退款成功", "order_id", orderID, "amount", lockedOrder.Price)
 		}
-
-		// 4. 更新备注
-		if err := tx.Model(&model.Order{}).Where("id = ?", orderID).Update("remark", remark).Error; err != nil {
+		if err := s.releaseSpend(ctx, tx, orderID); err != nil {
 			return err
 		}
 
-		// 5. 更新订单状态为已退款
-		if err := tx.Model(&model.Order{}).Where("id = ?", orderID).Update("status", model.OrderStatusRefunded).Error; err != nil {
+		// 4. 更新订单状态为已退款并写入备注
+		if err := s.stateMachine.Transit(ctx, tx, orderID, lockedOrder.Status, model.OrderStatusRefunded, OrderTransition{
+			Reason: remark,
+			Fields: map[string]interface{}{"remark": remark},
+		}); err != nil {
 			return err
 		}
 
@@ -621,10 +664,9 @@ func (s *orderService) ProcessExternalRefund(ctx context.Context, outTradeNum st
 			return fmt.Errorf("订单已退款")
 		}
 
-		// 只有成功、失败、待充值状态的订单可以退款
+		// 只有成功、失败状态的订单可以退款；待充值订单可能正在提交上游，需先置为失败
 		if lockedOrder.Status != model.OrderStatusSuccess &&
-			lockedOrder.Status != model.OrderStatusFailed &&
-			lockedOrder.Status != model.OrderStatusPendingRecharge {
+			lockedOrder.Status != model.OrderStatusFailed {
 			logger.Error("订单状态不允许退款",
 				"order_id", lockedOrder.ID,
 				"status", lockedOrder.Status,
@@ -632,6 +674,12 @@ func (s *orderService) ProcessExternalRefund(ctx context.Context, outTradeNum st
 			return fmt.Errorf("订单状态不允许退款")
 		}
 
+		// 拆单父订单的款项由子订单承担，需对子订单退款
+		if lockedOrder.IsSplitParent() {
+			logger.Error("拆单父订单不能直接退款", "order_id", lockedOrder.ID, "out_trade_num", outTradeNum)
+			return fmt.Errorf("拆单订单请对子订单退款")
+		}
+
 		// 3. 检查是否为外部订单
 		if lockedOrder.Client != 2 {
 			logger.Error("非外部订单，不能使用此退款方法",
@@ -656,17 +704,18 @@ func (s *orderService) ProcessExternalRefund(ctx context.Context, outTradeNum st
 			"order_id", lockedOrder.ID,
 			"customer_id", lockedOrder.CustomerID,
 			"amount", lockedOrder.Price)
-
-		// 5. 更新订单备注
-		if err := tx.Model(&model.Order{}).Where("id = ?", lockedOrder.ID).Update("remark", fmt.Sprintf("外部订单退款: %s", reason)).Error; err != nil {
-			logger.Error("更新订单备注失败", "error", err, "order_id", lockedOrder.ID)
-			return fmt.Errorf("更新订单备注失败: %v", err)
+		if err := s.releaseSpend(ctx, tx, lockedOrder.ID); err != nil {
+			return err
 		}
 
-		// 6. 更新订单状态为已退款
-		if err := tx.Model(&model.Order{}).Where("id = ?", lockedOrder.ID).Update("status", model.OrderStatusRefunded).Error; err != nil {
+		// 5. 更新订单状态为已退款并写入备注
+		if err := s.stateMachine.Transit(ctx, tx, lockedOrder.ID, lockedOrder.Status, model.OrderStatusRefunded, OrderTransition{
+			Reason: reason,
+			Source: OrderTransitionSourceExternal,
+			Fields: map[string]interface{}{"remark": fmt.Sprintf("外部订单退款: %s", reason)},
+		}); err != nil {
 			logger.Error("更新订单状态失败", "error", err, "order_id", lockedOrder.ID)
-			return fmt.Errorf("更新订单状态失败: %v", err)
+			return fmt.Errorf("更新订单状态失败: %w", err)
 		}
 
 		logger.Info("外部订单退款完成",
@@ -692,8 +741,8 @@ func (s *orderService) ProcessOrderCancel(ctx context.Context, orderID int64, re
 		return err
 	}
 
-	// 更新订单状态为已取消
-	return s.UpdateOrderStatus(ctx, orderID, model.OrderStatusCancelled)
+	// 更新订单状态为已取消，退回冻结款
+	return s.updateOrderStatus(ctx, orderID, model.OrderStatusCancelled, true)
 }
 
 // ProcessOrderSplit 处理订单拆单
@@ -704,20 +753,67 @@ func (s *orderService) ProcessOrderSplit(ctx context.Context, orderID int64, rem
 		return err
 	}
 
-	// 更新订单状态为已拆单
-	return s.UpdateOrderStatus(ctx, orderID, model.OrderStatusSplit)
+	// 更新订单状态为已拆单，扣下冻结款
+	return s.updateOrderStatus(ctx, orderID, model.OrderStatusSplit, true)
 }
 
 // ProcessOrderPartial 处理订单部分充值
-func (s *orderService) ProcessOrderPartial(ctx context.Context, orderID int64, remark string) error {
-	// 更新备注
-	err := s.orderRepo.UpdateRemark(ctx, orderID, remark)
+func (s *orderService) ProcessOrderPartial(ctx context.Context, orderID int64, creditedAmount money.Money, remark string) error {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
+		return fmt.Errorf("获取订单信息失败: %v", err)
+	}
+	if order.Status == model.OrderStatusSplit {
+		return fmt.Errorf("订单已拆单，状态由子订单汇总")
+	}
+
+	// 与失败退款共用用户级退款锁
+	lockValue, err := s.lockManager.LockUserRefund(ctx, order.CustomerID)
+	if err != nil {
+		return fmt.Errorf("获取退款锁失败: %v", err)
+	}
+	defer func() {
+		if unlockErr := s.lockManager.UnlockUserRefund(ctx, order.CustomerID, lockValue); unlockErr != nil {
+			logger.Error("释放用户退款锁失败", "user_id", order.CustomerID, "order_id", orderID, "error", unlockErr)
+		}
+	}()
+
+	// 结算与通知同事务提交，提交后再投递
+	var settlement *PartialSettlement
+	var msg *model.OutboxMessage
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lockedOrder model.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderID).First(&lockedOrder).Error; err != nil {
+			return fmt.Errorf("get order failed: %v", err)
+		}
+		var err error
+		settlement, err = s.partialService.SettleWithTx(ctx, tx, &lockedOrder, creditedAmount, OrderTransition{
+			Reason: remark,
+			Fields: map[string]interface{}{"remark": remark},
+		})
+		if err != nil || settlement.Duplicated {
+			return err
+		}
+		msg, err = s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
+			OrderID:          orderID,
+			PlatformCode:     order.PlatformCode,
+			NotificationType: "order_status_changed",
+			Content:          fmt.Sprintf("订单部分充值: 实际到账%s, 退款%s", settlement.CreditedAmount, settlement.RefundAmount),
+			Status:           1, // 待处理
+		})
+		return err
+	})
+	if err != nil {
+		logger.Error("部分充值结算失败", "order_id", orderID, "error", err)
 		return err
 	}
+	if settlement.Duplicated {
+		return nil
+	}
 
-	// 更新订单状态为部分充值
-	return s.UpdateOrderStatus(ctx, orderID, model.OrderStatusPartial)
+	s.settleSplitParent(ctx, order)
+	s.outbox.PublishAll(ctx, msg)
+	return nil
 }
 
 // GetOrders 获取订单列表
@@ -737,7 +833,7 @@ func (s *orderService) GetOrdersWithNotification(ctx context.Context, params map
 	return s.orderRepo.GetOrdersWithNotification(ctx, params, page, pageSize)
 }
 
-// CreateExternalOrder 创建外部订单（事务性处理：先验证商品再扣款创建订单）
+// CreateExternalOrder 创建外部订单（事务性处理：先验证商品再冻结订单金额创建订单）
 func (s *orderService) CreateExternalOrder(ctx context.Context, order *model.Order, userID int64) error {
 	logger.Info("开始创建外部订单",
 		"out_trade_num", order.OutTradeNum,
@@ -768,64 +864,33 @@ func (s *orderService) CreateExternalOrder(ctx context.Context, order *model.Ord
 		"product_name", product.Name,
 		"actual_price", actualPrice)
 
-	// 开启事务
-	tx := s.orderRepo.(*repository.OrderRepositoryImpl).DB().Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			logger.Error("创建外部订单发生panic，事务回滚", "panic", r)
-		}
-	}()
-
-	if tx.Error != nil {
-		logger.Error("开启事务失败", "error", tx.Error)
-		return fmt.Errorf("开启事务失败: %v", tx.Error)
-	}
-
-	// 2. 智能扣款（优先使用余额，不足时使用授信额度）
-	logger.Info("开始智能扣款",
-		"user_id", userID,
-		"amount", actualPrice)
-
-	// 创建带授信功能的余额服务实例
+	// 2. 同一事务内创建订单并冻结订单金额（优先使用余额，不足时使用授信额度），
+	// 订单成功、失败的处理流程经状态机扣下或退回冻结款
 	balanceService := NewBalanceServiceWithCredit(s.balanceLogRepo, s.userRepo, s.creditService)
-	if err := balanceService.SmartDeduct(ctx, userID, actualPrice, model.BalanceStyleOrderDeduct, "外部订单智能扣款", "system"); err != nil {
-		tx.Rollback()
-		logger.Error("智能扣款失败",
-			"error", err,
-			"user_id", userID,
-			"amount", actualPrice)
-		return fmt.Errorf("余额和授信额度均不足: %v", err)
-	}
-
-	logger.Info("智能扣款成功",
-		"user_id", userID,
-		"amount", actualPrice)
-
-	// 3. 创建订单（直接设置为待充值状态，使用商品表价格）
-	order.OrderNumber = generateOrderNumber()
-	order.CreateTime = time.Now()
-	order.UpdatedAt = time.Now()
-	order.Status = model.OrderStatusPendingRecharge // 直接设置为待充值状态
-	order.CustomerID = userID
-	order.IsDel = 0
-	order.Price = actualPrice // 使用商品表的价格
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 创建订单（直接设置为待充值状态，使用商品表价格）
+		order.OrderNumber = generateOrderNumber()
+		order.CreateTime = time.Now()
+		order.UpdatedAt = time.Now()
+		order.Status = model.OrderStatusPendingRecharge // 直接设置为待充值状态
+		order.CustomerID = userID
+		order.IsDel = 0
+		order.Price = actualPrice // 使用商品表的价格
+		if err := tx.Create(order).Error; err != nil {
+			return fmt.Errorf("创建订单失败: %v", err)
+		}
 
-	if err := s.orderRepo.Create(ctx, order); err != nil {
-		tx.Rollback()
-		// 回滚扣款
-		if refundErr := balanceService.Refund(ctx, userID, actualPrice, 0, "订单创建失败退款", "system"); refundErr != nil {
-			logger.Error("订单创建失败，退款也失败",
-				"create_error", err,
-				"refund_error", refundErr,
-				"user_id", userID,
-				"amount", actualPrice)
-		} else {
-			logger.Info("订单创建失败，已自动退款",
+		if err := balanceService.HoldWithTx(ctx, tx, userID, actualPrice, order.ID, "外部订单冻结", "system"); err != nil {
+			logger.Error("冻结订单金额失败",
+				"error", err,
 				"user_id", userID,
 				"amount", actualPrice)
+			return fmt.Errorf("余额和授信额度均不足: %v", err)
 		}
-		return fmt.Errorf("创建订单失败: %v", err)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	logger.Info("订单创建成功",
@@ -834,24 +899,12 @@ func (s *orderService) CreateExternalOrder(ctx context.Context, order *model.Ord
 		"status", order.Status,
 		"actual_price", actualPrice)
 
-	// 4. 更新扣款记录的订单ID（将之前的临时扣款记录关联到具体订单）
-	if err := s.updateUserBalanceLogOrderID(ctx, userID, actualPrice, order.ID); err != nil {
-		logger.Error("更新扣款记录订单ID失败", "error", err, "order_id", order.ID)
-		// 这个错误不影响主流程，只记录日志
-	}
-
-	// 5. 推送到充值队列
+	// 3. 推送到充值队列
 	if err := s.rechargeService.PushToRechargeQueue(ctx, order.ID); err != nil {
 		logger.Error("推送到充值队列失败", "error", err, "order_id", order.ID)
 		// 这个错误不影响主流程，只记录日志
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		logger.Error("提交事务失败", "error", err)
-		return fmt.Errorf("提交事务失败: %v", err)
-	}
-
 	logger.Info("外部订单创建完成",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber,
@@ -861,62 +914,6 @@ func (s *orderService) CreateExternalOrder(ctx context.Context, order *model.Ord
 	return nil
 }
 
-// updateBalanceLogOrderID 更新余额日志的订单ID
-func (s *orderService) updateBalanceLogOrderID(ctx context.Context, platformAccountID int64, amount float64, orderID int64) error {
-	// 查找最近的扣款记录（订单ID为0的记录）
-	db := s.orderRepo.(*repository.OrderRepositoryImpl).DB()
-	err := db.Model(&model.BalanceLog{}).
-		Where("platform_account_id = ? AND amount = ? AND order_id = ? AND style = ?",
-			platformAccountID, amount, 0, model.BalanceStyleOrderDeduct).
-		Order("created_at DESC").
-		Limit(1).
-		Update("order_id", orderID).Error
-
-	if err != nil {
-		logger.Error("更新余额日志订单ID失败",
-			"error", err,
-			"platform_account_id", platformAccountID,
-			"amount", amount,
-			"order_id", orderID)
-		return err
-	}
-
-	logger.Info("更新余额日志订单ID成功",
-		"platform_account_id", platformAccountID,
-		"amount", amount,
-		"order_id", orderID)
-
-	return nil
-}
-
-// updateUserBalanceLogOrderID 更新用户余额日志的订单ID
-func (s *orderService) updateUserBalanceLogOrderID(ctx context.Context, userID int64, amount float64, orderID int64) error {
-	// 查找最近的扣款记录（订单ID为0的记录）
-	db := s.orderRepo.(*repository.OrderRepositoryImpl).DB()
-	err := db.Model(&model.BalanceLog{}).
-		Where("user_id = ? AND amount = ? AND order_id = ? AND style = ?",
-			userID, -amount, 0, model.BalanceStyleOrderDeduct).
-		Order("created_at DESC").
-		Limit(1).
-		Update("order_id", orderID).Error
-
-	if err != nil {
-		logger.Error("更新用户余额日志订单ID失败",
-			"error", err,
-			"user_id", userID,
-			"amount", amount,
-			"order_id", orderID)
-		return err
-	}
-
-	logger.Info("更新用户余额日志订单ID成功",
-		"user_id", userID,
-		"amount", amount,
-		"order_id", orderID)
-
-	return nil
-}
-
 // generateOrderNumber 生成订单号
 func generateOrderNumber() string {
 	return "P" + time.Now().Format("20060102150405") + utils.RandString(6)
@@ -1079,17 +1076,18 @@ func (s *orderService) SendNotification(ctx context.Context, orderID int64) erro
 		UpdatedAt:        time.Now(),
 	}
 
-	// 保存通知记录
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	// 保存通知记录并写入发件箱，投递失败由发件箱中继补投
+	var msg *model.OutboxMessage
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		msg, err = s.outbox.AddNotification(tx, notification)
+		return err
+	}); err != nil {
 		return fmt.Errorf("创建通知记录失败: %w", err)
 	}
+	s.outbox.PublishAll(ctx, msg)
 
-	// 推送到通知队列
-	if err := s.queue.Push(ctx, "notification_queue", notification); err != nil {
-		return fmt.Errorf("推送到通知队列失败: %w", err)
-	}
-
-	logger.Info("订单回调通知已推送到队列", "order_id", orderID, "order_number", order.OrderNumber)
+	logger.Info("订单回调通知已提交", "order_id", orderID, "order_number", order.OrderNumber)
 	return nil
 }
 
@@ -1097,3 +1095,23 @@ func (s *orderService) SendNotification(ctx context.Context, orderID int64) erro
 func (s *orderService) GetOrdersByUserID(ctx context.Context, userID int64, params map[string]interface{}, page, pageSize int) ([]*model.Order, int64, error) {
 	return s.orderRepo.GetByUserID(ctx, userID, params, page, pageSize)
 }
+
+// GetOrderStatusHistory 获取订单状态流转记录
+func (s *orderService) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error) {
+	return s.stateMachine.History(ctx, orderID)
+}
+
+// StateMachine 获取订单状态机
+func (s *orderService) StateMachine() *OrderStateMachine {
+	return s.stateMachine
+}
+
+// settleSplitParent 拆单子订单完成后汇总父订单
+func (s *orderService) settleSplitParent(ctx context.Context, order *model.Order) {
+	if s.rechargeService == nil || !order.IsSplitChild() {
+		return
+	}
+	if splitService := s.rechargeService.GetSplitService(); splitService != nil {
+		splitService.OnChildFinished(ctx, order)
+	}
+}
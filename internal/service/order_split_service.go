@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"recharge-go/internal/model"
+	notificationModel "recharge-go/internal/model/notification"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// ErrOrderSplit 订单已拆分为子订单，父订单不再直接充值
+var ErrOrderSplit = errors.New("order has been split into child orders")
+
+// SplitSettlement 拆单子订单汇总结果
+type SplitSettlement struct {
+	Total        int         `json:"total"`         // 子订单数
+	Succeeded    int         `json:"succeeded"`     // 成功笔数
+	Partial      int         `json:"partial"`       // 部分成功笔数
+	Failed       int         `json:"failed"`        // 失败（含取消、已退款）笔数
+	Pending      int         `json:"pending"`       // 未完成笔数
+	FilledDenom  money.Money `json:"filled_denom"`  // 已到账面值
+	RefundAmount money.Money `json:"refund_amount"` // 未到账部分的退款金额
+}
+
+// Finished 子订单是否全部进入终态
+func (s *SplitSettlement) Finished() bool {
+	return s.Total > 0 && s.Pending == 0
+}
+
+// Status 父订单汇总状态：全部成功为成功，全部失败为失败，其余为部分充值
+func (s *SplitSettlement) Status() model.OrderStatus {
+	switch {
+	case s.Succeeded == s.Total:
+		return model.OrderStatusSuccess
+	case s.Succeeded == 0 && s.Partial == 0:
+		return model.OrderStatusFailed
+	default:
+		return model.OrderStatusPartial
+	}
+}
+
+// OrderSplitService 拆单服务
+// 商品面值在任何通道都不可用时按拆单规则拆分为子订单，子订单走正常路由充值，
+// 全部完成后汇总到父订单。子订单各自承担扣款与失败退款，因此父订单累计退款恰好等于未到账部分
+type OrderSplitService struct {
+	db             *gorm.DB
+	ruleRepo       *repository.OrderSplitRuleRepository
+	routingService *RoutingService
+	stateMachine   *OrderStateMachine
+	outbox         *Outbox
+}
+
+// NewOrderSplitService 创建拆单服务
+func NewOrderSplitService(
+	db *gorm.DB,
+	routingService *RoutingService,
+	stateMachine *OrderStateMachine,
+	outbox *Outbox,
+) *OrderSplitService {
+	return &OrderSplitService{
+		db:             db,
+		ruleRepo:       repository.NewOrderSplitRuleRepository(db),
+		routingService: routingService,
+		stateMachine:   stateMachine,
+		outbox:         outbox,
+	}
+}
+
+// Split 按拆单规则拆分订单，返回创建的子订单；没有可用规则时返回 nil
+func (s *OrderSplitService) Split(ctx context.Context, order *model.Order) ([]*model.Order, error) {
+	if order.IsSplitChild() || order.Status != model.OrderStatusPendingRecharge {
+		return nil, nil
+	}
+
+	rule, parts, err := s.matchRule(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, nil
+	}
+
+	children := buildSplitChildren(order, parts)
+	reason := fmt.Sprintf("按拆单规则[%d]拆分为%d笔子订单", rule.ID, len(children))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 父订单的冻结款在拆单时扣下，子订单失败时各自退款
+		if err := s.stateMachine.Transit(ctx, tx, order.ID, model.OrderStatusPendingRecharge, model.OrderStatusSplit, OrderTransition{
+			Reason:     reason,
+			Source:     OrderTransitionSourceWorker,
+			Fields:     map[string]interface{}{"is_apart": 1, "remark": reason},
+			SettleHold: true,
+		}); err != nil {
+			return err
+		}
+		return tx.Create(&children).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("split order failed: %w", err)
+	}
+
+	logger.Info("订单拆单成功",
+		"order_id", order.ID,
+		"order_number", order.OrderNumber,
+		"rule_id", rule.ID,
+		"children", len(children))
+	return children, nil
+}
+
+// OnChildFinished 子订单进入终态后尝试汇总父订单，非子订单直接忽略
+func (s *OrderSplitService) OnChildFinished(ctx context.Context, order *model.Order) {
+	if order == nil || !order.IsSplitChild() {
+		return
+	}
+	if err := s.Settle(ctx, order.ApartOrderNumber); err != nil {
+		logger.Error("拆单父订单汇总失败",
+			"child_order_id", order.ID,
+			"parent_order_number", order.ApartOrderNumber,
+			"error", err)
+	}
+}
+
+// Settle 汇总子订单结果到父订单，子订单未全部完成时不做处理
+func (s *OrderSplitService) Settle(ctx context.Context, parentOrderNumber string) error {
+	var parent model.Order
+	if err := s.db.WithContext(ctx).Where("order_number = ?", parentOrderNumber).First(&parent).Error; err != nil {
+		return fmt.Errorf("get parent order failed: %v", err)
+	}
+	if parent.Status != model.OrderStatusSplit {
+		return nil
+	}
+
+	settlement, err := s.Summary(ctx, parent.OrderNumber)
+	if err != nil {
+		return err
+	}
+	if !settlement.Finished() {
+		return nil
+	}
+
+	status := settlement.Status()
+	remark := fmt.Sprintf("拆单完成: 成功%d笔, 部分成功%d笔, 失败%d笔, 到账面值%s, 退款%s",
+		settlement.Succeeded, settlement.Partial, settlement.Failed, settlement.FilledDenom, settlement.RefundAmount)
+	// 父订单汇总后通知下游，子订单不单独通知；通知与汇总同事务提交
+	var msg *model.OutboxMessage
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.stateMachine.Transit(ctx, tx, parent.ID, model.OrderStatusSplit, status, OrderTransition{
+			Reason: remark,
+			Source: OrderTransitionSourceSystem,
+			Fields: map[string]interface{}{
+				"remark":          remark,
+				"finish_time":     time.Now(),
+				"credited_amount": settlement.FilledDenom,
+				"refunded_amount": settlement.RefundAmount,
+			},
+		}); err != nil {
+			return err
+		}
+		var err error
+		msg, err = s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
+			OrderID:          parent.ID,
+			PlatformCode:     parent.PlatformCode,
+			NotificationType: "order_status_changed",
+			Content:          remark,
+			Status:           1, // 待处理
+		})
+		return err
+	})
+	if errors.Is(err, ErrOrderStatusConflict) {
+		// 已被其他协程汇总
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("拆单父订单汇总完成",
+		"order_id", parent.ID,
+		"order_number", parent.OrderNumber,
+		"status", status,
+		"filled_denom", settlement.FilledDenom,
+		"refund_amount", settlement.RefundAmount)
+
+	s.outbox.PublishAll(ctx, msg)
+	return nil
+}
+
+// Summary 统计父订单下子订单的完成情况
+func (s *OrderSplitService) Summary(ctx context.Context, parentOrderNumber string) (*SplitSettlement, error) {
+	children, err := s.Children(ctx, parentOrderNumber)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeSplitChildren(children), nil
+}
+
+// Children 获取父订单的子订单
+func (s *OrderSplitService) Children(ctx context.Context, parentOrderNumber string) ([]*model.Order, error) {
+	var children []*model.Order
+	err := s.db.WithContext(ctx).
+		Where("apart_order_number = ?", parentOrderNumber).
+		Order("id ASC").
+		Find(&children).Error
+	if err != nil {
+		return nil, fmt.Errorf("get child orders failed: %v", err)
+	}
+	return children, nil
+}
+
+// SettlePending 汇总所有子订单已完成但父订单仍为已拆单状态的订单
+func (s *OrderSplitService) SettlePending(ctx context.Context) error {
+	var parents []string
+	err := s.db.WithContext(ctx).Model(&model.Order{}).
+		Where("status = ? AND is_apart = ?", model.OrderStatusSplit, 1).
+		Pluck("order_number", &parents).Error
+	if err != nil {
+		return fmt.Errorf("get split orders failed: %v", err)
+	}
+	for _, orderNumber := range parents {
+		if err := s.Settle(ctx, orderNumber); err != nil {
+			logger.Error("拆单父订单汇总失败", "order_number", orderNumber, "error", err)
+		}
+	}
+	return nil
+}
+
+// RunSettler 定时汇总拆单父订单，兜底子订单完成时未能及时汇总的情况
+func (s *OrderSplitService) RunSettler(ctx context.Context, interval time.Duration) {
+	logger.Info("拆单汇总任务启动", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("拆单汇总任务停止")
+			return
+		case <-ticker.C:
+			if err := s.SettlePending(ctx); err != nil {
+				logger.Error("拆单汇总失败", "error", err)
+			}
+		}
+	}
+}
+
+// matchRule 按优先级找到第一条所有子面值都有可用通道的规则
+func (s *OrderSplitService) matchRule(ctx context.Context, order *model.Order) (*model.OrderSplitRule, []model.OrderSplitPart, error) {
+	rules, err := s.ruleRepo.ListEnabledByProductID(ctx, order.ProductID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get split rules failed: %v", err)
+	}
+
+	routable := make(map[int64]bool)
+	for _, rule := range rules {
+		parts, err := rule.ParseParts()
+		if err != nil {
+			logger.Error("拆单规则配置错误", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		if order.Denom > 0 && sumSplitDenom(parts).Round() != order.Denom.Round() {
+			logger.Error("拆单规则面值与订单不一致", "rule_id", rule.ID, "order_id", order.ID, "denom", order.Denom)
+			continue
+		}
+
+		usable := true
+		for _, part := range parts {
+			ok, checked := routable[part.ProductID]
+			if !checked {
+				probe := &model.Order{ProductID: part.ProductID, ISP: order.ISP, AccountLocation: order.AccountLocation}
+				candidate, err := s.routingService.RouteFirst(ctx, probe, nil)
+				ok = err == nil && candidate != nil
+				routable[part.ProductID] = ok
+			}
+			if !ok {
+				usable = false
+				break
+			}
+		}
+		if usable {
+			return rule, parts, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// buildSplitChildren 按子面值占比分摊父订单金额生成子订单，尾差计入最后一笔
+func buildSplitChildren(parent *model.Order, parts []model.OrderSplitPart) []*model.Order {
+	weights := make([]money.Money, len(parts))
+	for i, part := range parts {
+		weights[i] = part.Denom
+	}
+	prices := allocateByWeight(parent.Price, weights)
+	payments := allocateByWeight(parent.UserPayment, weights)
+
+	now := time.Now()
+	children := make([]*model.Order, 0, len(parts))
+	for i, part := range parts {
+		orderNumber := fmt.Sprintf("%sS%02d", parent.OrderNumber, i+1)
+		children = append(children, &model.Order{
+			OrderNumber:       orderNumber,
+			OutTradeNum:       orderNumber,
+			CustomerID:        parent.CustomerID,
+			Mobile:            parent.Mobile,
+			ProductID:         part.ProductID,
+			Status:            model.OrderStatusPendingRecharge,
+			Denom:             part.Denom,
+			TotalPrice:        prices[i],
+			Price:             prices[i],
+			UserPayment:       payments[i],
+			ISP:               parent.ISP,
+			AccountLocation:   parent.AccountLocation,
+			Guishu:            parent.Guishu,
+			Param1:            parent.Param1,
+			Param2:            parent.Param2,
+			Param3:            parent.Param3,
+			Client:            parent.Client,
+			IsApart:           1,
+			ApartOrderNumber:  parent.OrderNumber,
+			PlatformId:        parent.PlatformId,
+			PlatformAccountID: parent.PlatformAccountID,
+			PlatformName:      parent.PlatformName,
+			PlatformCode:      parent.PlatformCode,
+			CreateTime:        now,
+			UpdatedAt:         now,
+			Remark:            fmt.Sprintf("拆单子订单 %d/%d", i+1, len(parts)),
+		})
+	}
+	return children
+}
+
+// summarizeSplitChildren 汇总子订单状态与金额
+func summarizeSplitChildren(children []*model.Order) *SplitSettlement {
+	settlement := &SplitSettlement{Total: len(children)}
+	var filled, refund money.Money
+	for _, child := range children {
+		switch child.Status {
+		case model.OrderStatusSuccess:
+			settlement.Succeeded++
+			filled += child.ActualCredited()
+		case model.OrderStatusPartial:
+			settlement.Partial++
+			filled += child.CreditedAmount
+			refund += child.RefundedAmount
+		case model.OrderStatusFailed, model.OrderStatusCancelled, model.OrderStatusRefunded:
+			settlement.Failed++
+			refund += child.Price
+		default:
+			settlement.Pending++
+		}
+	}
+	settlement.FilledDenom = filled
+	settlement.RefundAmount = refund
+	return settlement
+}
+
+// sumSplitDenom 计算子订单面值合计
+func sumSplitDenom(parts []model.OrderSplitPart) money.Money {
+	var total money.Money
+	for _, part := range parts {
+		total += part.Denom
+	}
+	return total
+}
+
+// allocateByWeight 按权重分摊金额（精确到分），尾差计入最后一项
+func allocateByWeight(amount money.Money, weights []money.Money) []money.Money {
+	result := make([]money.Money, len(weights))
+	var totalWeight money.Money
+	for _, w := range weights {
+		totalWeight += w
+	}
+	if totalWeight <= 0 || len(weights) == 0 {
+		return result
+	}
+
+	total := amount.Cents()
+	var allocated int64
+	for i, w := range weights {
+		if i == len(weights)-1 {
+			result[i] = money.FromCents(total - allocated)
+			break
+		}
+		cents := (total*int64(w)*2 + int64(totalWeight)) / (2 * int64(totalWeight))
+		allocated += cents
+		result[i] = money.FromCents(cents)
+	}
+	return result
+}
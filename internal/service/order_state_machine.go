@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 订单状态变更来源
+const (
+	OrderTransitionSourceSystem   = "system"   // 系统内部流程
+	OrderTransitionSourceWorker   = "worker"   // 充值任务处理
+	OrderTransitionSourceRetry    = "retry"    // 重试任务
+	OrderTransitionSourceCallback = "callback" // 上游平台回调
+	OrderTransitionSourceQuery    = "query"    // 主动查询上游
+	OrderTransitionSourceManual   = "manual"   // 后台人工操作
+	OrderTransitionSourceExternal = "external" // 外部API调用
+)
+
+var (
+	// ErrIllegalOrderTransition 订单状态流转不合法
+	ErrIllegalOrderTransition = errors.New("illegal order status transition")
+	// ErrOrderStatusConflict 订单状态已被并发修改
+	ErrOrderStatusConflict = errors.New("order status changed concurrently")
+)
+
+// orderTransitions 合法的订单状态流转，已退款和已取消为终态
+// 待充值订单必须先提交上游（充值中/处理中）才能成功；提交与状态更新之间订单仍为待充值，
+// 因此待充值不能直接退款，需先置为失败（失败时退款）
+var orderTransitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderStatusPendingPayment: {
+		model.OrderStatusPendingRecharge,
+		model.OrderStatusCancelled,
+		model.OrderStatusFailed,
+	},
+	model.OrderStatusPendingRecharge: {
+		model.OrderStatusProcessing,
+		model.OrderStatusRecharging,
+		model.OrderStatusFailed,
+		model.OrderStatusCancelled,
+		model.OrderStatusSplit,
+	},
+	model.OrderStatusProcessing: {
+		model.OrderStatusPendingRecharge,
+		model.OrderStatusRecharging,
+		model.OrderStatusSuccess,
+		model.OrderStatusFailed,
+		model.OrderStatusPartial,
+	},
+	model.OrderStatusRecharging: {
+		model.OrderStatusPendingRecharge,
+		model.OrderStatusSuccess,
+		model.OrderStatusFailed,
+		model.OrderStatusPartial,
+	},
+	model.OrderStatusSplit: {
+		model.OrderStatusSuccess,
+		model.OrderStatusFailed,
+		model.OrderStatusPartial,
+	},
+	model.OrderStatusSuccess: {model.OrderStatusRefunded},
+	model.OrderStatusFailed:  {model.OrderStatusRefunded},
+	model.OrderStatusPartial: {model.OrderStatusRefunded},
+}
+
+// CanTransitOrder 判断订单状态能否从 from 流转到 to
+func CanTransitOrder(from, to model.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderTransition 一次状态变更的附加信息
+type OrderTransition struct {
+	Actor      string                 // 操作人，为空时取上下文中的用户
+	Reason     string                 // 变更原因
+	Source     string                 // 变更来源，见 OrderTransitionSource*
+	Fields     map[string]interface{} // 随状态一同更新的订单字段
+	SettleHold bool                   // 是否同时结清下单冻结款，只由成功、失败、部分充值、拆单、取消等显式处理流程设置
+}
+
+// OrderStateMachine 订单状态机，所有订单状态变更都应经由此处
+type OrderStateMachine struct {
+	db      *gorm.DB
+	balance *BalanceService
+}
+
+// NewOrderStateMachine 创建订单状态机
+func NewOrderStateMachine(db *gorm.DB) *OrderStateMachine {
+	return &OrderStateMachine{
+		db:      db,
+		balance: NewBalanceService(repository.NewBalanceLogRepository(db), repository.NewUserRepository(db)),
+	}
+}
+
+// Transit 将订单从 from 流转到 to
+// 以 status = from 作为乐观锁条件更新，状态已被修改时返回 ErrOrderStatusConflict；
+// tx 为空时在独立事务中执行，状态更新与流转记录同时提交
+func (m *OrderStateMachine) Transit(ctx context.Context, tx *gorm.DB, orderID int64, from, to model.OrderStatus, t OrderTransition) error {
+	if !CanTransitOrder(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalOrderTransition, from, to)
+	}
+	if tx == nil {
+		return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return m.apply(ctx, tx, orderID, from, to, t)
+		})
+	}
+	return m.apply(ctx, tx, orderID, from, to, t)
+}
+
+// TransitCurrent 读取订单当前状态并流转到 to，返回流转前的状态
+// 当前状态已是 to 时不做任何变更
+func (m *OrderStateMachine) TransitCurrent(ctx context.Context, tx *gorm.DB, orderID int64, to model.OrderStatus, t OrderTransition) (model.OrderStatus, error) {
+	db := tx
+	if db == nil {
+		db = m.db
+	}
+	var order model.Order
+	if err := db.WithContext(ctx).Select("id", "status").Where("id = ?", orderID).First(&order).Error; err != nil {
+		return 0, fmt.Errorf("get order status failed: %v", err)
+	}
+	if order.Status == to {
+		return order.Status, nil
+	}
+	return order.Status, m.Transit(ctx, tx, orderID, order.Status, to, t)
+}
+
+// History 获取订单的状态流转记录
+func (m *OrderStateMachine) History(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error) {
+	var histories []*model.OrderStatusHistory
+	err := m.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("id ASC").
+		Find(&histories).Error
+	return histories, err
+}
+
+// apply 执行状态更新、按需结清订单冻结款并写入流转记录
+func (m *OrderStateMachine) apply(ctx context.Context, tx *gorm.DB, orderID int64, from, to model.OrderStatus, t OrderTransition) error {
+	updates := map[string]interface{}{"status": to}
+	for k, v := range t.Fields {
+		updates[k] = v
+	}
+
+	result := tx.WithContext(ctx).Model(&model.Order{}).
+		Where("id = ? AND status = ?", orderID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update order status failed: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: order %d is no longer %s", ErrOrderStatusConflict, orderID, from)
+	}
+
+	// 下单时冻结的金额随订单完成扣款、随订单失败退回，与状态在同一事务内提交；
+	// 通用的状态修改不结清，避免绕过退款流程改动资金
+	if t.SettleHold {
+		if err := m.balance.SettleHoldWithTx(ctx, tx, orderID, to); err != nil {
+			return fmt.Errorf("settle balance hold failed: %v", err)
+		}
+	}
+
+	history := &model.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      transitionActor(ctx, t.Actor),
+		Reason:     t.Reason,
+		Source:     transitionSource(ctx, t.Source),
+	}
+	if err := tx.WithContext(ctx).Create(history).Error; err != nil {
+		return fmt.Errorf("create order status history failed: %v", err)
+	}
+
+	logger.Info("订单状态流转",
+		"order_id", orderID,
+		"from", from,
+		"to", to,
+		"actor", history.Actor,
+		"source", history.Source,
+		"reason", t.Reason)
+	return nil
+}
+
+// transitionActor 确定操作人：显式指定优先，其次为上下文中的登录用户
+func transitionActor(ctx context.Context, actor string) string {
+	if actor != "" {
+		return actor
+	}
+	if uid, ok := ctx.Value("user_id").(int64); ok && uid > 0 {
+		return fmt.Sprintf("user:%d", uid)
+	}
+	return "system"
+}
+
+// transitionSource 确定变更来源：未指定时有登录用户视为人工操作
+func transitionSource(ctx context.Context, source string) string {
+	if source != "" {
+		return source
+	}
+	if uid, ok := ctx.Value("user_id").(int64); ok && uid > 0 {
+		return OrderTransitionSourceManual
+	}
+	return OrderTransitionSourceSystem
+}
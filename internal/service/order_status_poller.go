@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// OrderResult 上游返回的订单结果，来自回调或主动查询
+type OrderResult struct {
+	Status          model.OrderStatus // 上游订单状态
+	CreditedAmount  string            // 部分充值时的实际到账面值
+	SupplierOrderID string            // 上游订单号，为空时不更新
+	Voucher         string            // 官方充值凭证/流水号，为空时不更新
+	Actor           string            // 上游平台
+	Source          string            // 结果来源，见 OrderTransitionSource*
+	Reason          string            // 状态变更原因
+}
+
+// IsFinal 上游结果是否为终态
+func (r OrderResult) IsFinal() bool {
+	switch r.Status {
+	case model.OrderStatusSuccess, model.OrderStatusFailed, model.OrderStatusPartial:
+		return true
+	}
+	return false
+}
+
+// supplierFields 随状态一起落库的上游订单信息
+func (r OrderResult) supplierFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if r.SupplierOrderID != "" {
+		fields["api_order_number"] = r.SupplierOrderID
+	}
+	if r.Voucher != "" {
+		fields["voucher"] = r.Voucher
+	}
+	return fields
+}
+
+// NewQueryOrderResult 将主动查询结果转换为上游订单结果
+func NewQueryOrderResult(order *model.Order, queried *recharge.QueryResult, reason string) OrderResult {
+	result := OrderResult{
+		Status:          queried.Status,
+		SupplierOrderID: queried.SupplierOrderID,
+		Voucher:         queried.Voucher,
+		Actor:           order.PlatformCode,
+		Source:          OrderTransitionSourceQuery,
+		Reason:          reason,
+	}
+	if queried.CreditedAmount > 0 {
+		result.CreditedAmount = strconv.FormatFloat(queried.CreditedAmount, 'f', 2, 64)
+	}
+	if queried.Message != "" {
+		result.Reason = fmt.Sprintf("%s: %s", reason, queried.Message)
+	}
+	return result
+}
+
+// OrderPollConfig 充值中订单主动查询配置
+type OrderPollConfig struct {
+	Interval         time.Duration // 扫描间隔
+	ExpectedCallback time.Duration // 进入充值中后预期收到回调的时间，超过后开始主动查询
+	BaseBackoff      time.Duration // 首次查询未出结果后的查询间隔，之后按次数翻倍
+	MaxBackoff       time.Duration // 最大查询间隔
+	BatchSize        int           // 每轮最多查询的订单数
+}
+
+// DefaultOrderPollConfig 默认主动查询配置
+func DefaultOrderPollConfig() OrderPollConfig {
+	return OrderPollConfig{
+		Interval:         time.Minute,
+		ExpectedCallback: 3 * time.Minute,
+		BaseBackoff:      time.Minute,
+		MaxBackoff:       30 * time.Minute,
+		BatchSize:        100,
+	}
+}
+
+// PollBackoff 第 count 次查询未出结果后到下次查询的间隔
+func PollBackoff(count int, base, max time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < count && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// OrderStatusPoller 充值中订单状态轮询
+// 超过预期回调时间仍在充值中的订单按退避间隔主动查询上游，查到终态后按回调流程完成订单，
+// 避免回调丢失的订单一直停留在充值中
+type OrderStatusPoller struct {
+	db              *gorm.DB
+	rechargeService RechargeService
+	config          OrderPollConfig
+}
+
+// NewOrderStatusPoller 创建充值中订单状态轮询
+func NewOrderStatusPoller(db *gorm.DB, rechargeService RechargeService, config OrderPollConfig) *OrderStatusPoller {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultOrderPollConfig().BatchSize
+	}
+	return &OrderStatusPoller{
+		db:              db,
+		rechargeService: rechargeService,
+		config:          config,
+	}
+}
+
+// Run 按间隔轮询，直到 ctx 结束
+func (p *OrderStatusPoller) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = p.config.Interval
+	}
+	logger.Info("充值中订单查询任务启动", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("充值中订单查询任务停止")
+			return
+		case <-ticker.C:
+			if _, err := p.Poll(ctx); err != nil {
+				logger.Error("充值中订单查询失败", "error", err)
+			}
+		}
+	}
+}
+
+// Poll 查询一轮到期的充值中订单，按平台账号分组批量查询，返回已完成的订单数
+func (p *OrderStatusPoller) Poll(ctx context.Context) (int, error) {
+	now := time.Now()
+	var orders []*model.Order
+	err := p.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ? AND (next_query_time IS NULL OR next_query_time <= ?)",
+			model.OrderStatusRecharging, now.Add(-p.config.ExpectedCallback), now).
+		Order("updated_at ASC").
+		Limit(p.config.BatchSize).
+		Find(&orders).Error
+	if err != nil {
+		return 0, fmt.Errorf("get recharging orders failed: %v", err)
+	}
+
+	settled := 0
+	for _, group := range groupPollOrders(orders) {
+		results, err := p.rechargeService.BatchQueryOrderStatus(ctx, group[0].PlatformCode, group)
+		if err != nil {
+			logger.Error("主动查询订单失败", "platform_code", group[0].PlatformCode, "count", len(group), "error", err)
+		}
+		for _, order := range group {
+			if p.settleOrder(ctx, order, results[order.ID]) {
+				settled++
+				continue
+			}
+
+			// 未查到终态，按退避安排下次查询（不更新 updated_at，保留进入充值中的时间）
+			count := order.QueryCount + 1
+			next := now.Add(PollBackoff(count, p.config.BaseBackoff, p.config.MaxBackoff))
+			if err := p.db.WithContext(ctx).Model(&model.Order{}).Where("id = ?", order.ID).
+				UpdateColumns(map[string]interface{}{"query_count": count, "next_query_time": next}).Error; err != nil {
+				logger.Error("更新订单下次查询时间失败", "order_id", order.ID, "error", err)
+			}
+		}
+	}
+
+	if len(orders) > 0 {
+		logger.Info("充值中订单查询完成", "queried", len(orders), "settled", settled)
+	}
+	return settled, nil
+}
+
+// groupPollOrders 按平台、平台账号与平台接口分组，同组订单可批量查询，组间保持原有顺序
+// 同一账号可挂在多个接口下，按接口分组使查询超时与熔断统计落在各自的接口上
+func groupPollOrders(orders []*model.Order) [][]*model.Order {
+	type groupKey struct {
+		platformCode string
+		accountID    int64
+		apiID        int64
+	}
+	index := make(map[groupKey]int)
+	var groups [][]*model.Order
+	for _, order := range orders {
+		key := groupKey{order.PlatformCode, order.PlatformAccountID, order.APICurID}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], order)
+	}
+	return groups
+}
+
+// settleOrder 按查询结果完成订单，未查到结果或未到终态时返回 false
+func (p *OrderStatusPoller) settleOrder(ctx context.Context, order *model.Order, queried *recharge.QueryResult) bool {
+	if queried == nil {
+		return false
+	}
+	settled, err := p.rechargeService.SettleOrderResult(ctx, order, NewQueryOrderResult(order, queried, "主动查询上游"))
+	if err != nil {
+		logger.Error("主动查询订单失败", "order_id", order.ID, "order_number", order.OrderNumber, "error", err)
+	}
+	return settled
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"recharge-go/internal/model"
+	notificationModel "recharge-go/internal/model/notification"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/queue"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// OutboxTopicNotification 下游通知队列
+	OutboxTopicNotification = "notification_queue"
+
+	// outboxRelayBatch 中继每轮最多投递的消息数
+	outboxRelayBatch = 200
+	// outboxRelayDelay 消息写入后先由提交方直接投递，超过该时长仍未投递的才由中继补投；也是中继抢占消息的租期
+	outboxRelayDelay = 10 * time.Second
+	// outboxBaseBackoff 投递失败后首次重试的间隔，之后按投递次数翻倍
+	outboxBaseBackoff = 10 * time.Second
+	// outboxMaxBackoff 投递失败后的最大重试间隔
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxRetention 已投递消息的保留时长
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// Outbox 事务发件箱
+// 业务事务内通过 Add 写入消息，提交后 Publish 立即投递；投递失败或进程退出时由 Run 定时补投，
+// 保证已提交的状态变更至少投递一次。消费方需按消息内容幂等处理
+type Outbox struct {
+	db    *gorm.DB
+	queue queue.Queue
+}
+
+// NewOutbox 创建事务发件箱
+func NewOutbox(db *gorm.DB, queue queue.Queue) *Outbox {
+	return &Outbox{db: db, queue: queue}
+}
+
+// Add 在事务内写入待投递消息，payload 按 JSON 序列化
+func (o *Outbox) Add(tx *gorm.DB, topic, aggregateID string, payload interface{}) (*model.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal outbox payload failed: %v", err)
+	}
+	msg := &model.OutboxMessage{
+		Topic:         topic,
+		AggregateID:   aggregateID,
+		Payload:       string(data),
+		Status:        model.OutboxStatusPending,
+		NextAttemptAt: time.Now().Add(outboxRelayDelay),
+	}
+	if err := tx.Create(msg).Error; err != nil {
+		return nil, fmt.Errorf("create outbox message failed: %v", err)
+	}
+	return msg, nil
+}
+
+// AddNotification 在事务内创建下游通知记录并写入发件箱，与订单状态变更一起提交
+func (o *Outbox) AddNotification(tx *gorm.DB, notification *notificationModel.NotificationRecord) (*model.OutboxMessage, error) {
+	if err := tx.Create(notification).Error; err != nil {
+		return nil, fmt.Errorf("create notification record failed: %v", err)
+	}
+	return o.Add(tx, OutboxTopicNotification, strconv.FormatInt(notification.OrderID, 10), notification)
+}
+
+// Publish 投递一条消息并标记为已投递，失败时记录错误并按投递次数退避，留待中继重试
+func (o *Outbox) Publish(ctx context.Context, msg *model.OutboxMessage) error {
+	pushErr := o.queue.Push(ctx, msg.Topic, json.RawMessage(msg.Payload))
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1")}
+	if pushErr != nil {
+		updates["last_error"] = pushErr.Error()
+		updates["next_attempt_at"] = now.Add(PollBackoff(msg.Attempts+1, outboxBaseBackoff, outboxMaxBackoff))
+	} else {
+		updates["status"] = model.OutboxStatusDispatched
+		updates["dispatched_at"] = &now
+	}
+	if err := o.db.WithContext(ctx).Model(&model.OutboxMessage{}).
+		Where("id = ? AND status = ?", msg.ID, model.OutboxStatusPending).
+		Updates(updates).Error; err != nil {
+		logger.Error("更新发件箱消息状态失败", "id", msg.ID, "error", err)
+	}
+	if pushErr != nil {
+		return fmt.Errorf("push outbox message %d failed: %v", msg.ID, pushErr)
+	}
+	return nil
+}
+
+// PublishAll 事务提交后逐条投递，失败的消息由中继补投，msgs 中的 nil 会被忽略
+func (o *Outbox) PublishAll(ctx context.Context, msgs ...*model.OutboxMessage) {
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		if err := o.Publish(ctx, msg); err != nil {
+			logger.Error("发件箱消息投递失败，等待中继补投", "id", msg.ID, "topic", msg.Topic, "aggregate_id", msg.AggregateID, "error", err)
+		}
+	}
+}
+
+// Relay 补投一轮到期的待投递消息，返回投递成功的条数
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	var msgs []*model.OutboxMessage
+	if err := o.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(outboxRelayBatch).
+		Find(&msgs).Error; err != nil {
+		return 0, fmt.Errorf("get pending outbox messages failed: %v", err)
+	}
+
+	published := 0
+	for _, msg := range msgs {
+		claimed, err := o.claim(ctx, msg)
+		if err != nil {
+			logger.Error("抢占发件箱消息失败", "id", msg.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			// 已被其他中继或提交方处理
+			continue
+		}
+		if err := o.Publish(ctx, msg); err != nil {
+			logger.Error("发件箱消息投递失败", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts+1, "error", err)
+			continue
+		}
+		published++
+	}
+	return published, nil
+}
+
+// claim 以投递次数为版本抢占消息，抢占期间其他中继不会再投递该消息
+func (o *Outbox) claim(ctx context.Context, msg *model.OutboxMessage) (bool, error) {
+	result := o.db.WithContext(ctx).Model(&model.OutboxMessage{}).
+		Where("id = ? AND status = ? AND attempts = ?", msg.ID, model.OutboxStatusPending, msg.Attempts).
+		Update("next_attempt_at", time.Now().Add(outboxRelayDelay))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Purge 清理超过保留时长的已投递消息，返回清理的条数
+func (o *Outbox) Purge(ctx context.Context) (int64, error) {
+	result := o.db.WithContext(ctx).
+		Where("status = ? AND dispatched_at < ?", model.OutboxStatusDispatched, time.Now().Add(-outboxRetention)).
+		Delete(&model.OutboxMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("purge outbox messages failed: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Run 按间隔补投待投递消息，每小时清理一次过期的已投递消息，直到 ctx 结束
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	logger.Info("发件箱中继任务启动", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("发件箱中继任务停止")
+			return
+		case <-ticker.C:
+			if n, err := o.Relay(ctx); err != nil {
+				logger.Error("发件箱中继失败", "error", err)
+			} else if n > 0 {
+				logger.Info("发件箱中继完成", "published", n)
+			}
+
+			if time.Since(lastPurge) < time.Hour {
+				continue
+			}
+			lastPurge = time.Now()
+			if n, err := o.Purge(ctx); err != nil {
+				logger.Error("清理发件箱消息失败", "error", err)
+			} else if n > 0 {
+				logger.Info("清理发件箱消息完成", "deleted", n)
+			}
+		}
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"net/http"
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
+	notificationService "recharge-go/internal/service/notification"
 	"recharge-go/pkg/logger"
 	"recharge-go/pkg/signature"
+	"recharge-go/pkg/signature/webhook"
 	"strconv"
 	"time"
 )
@@ -196,7 +198,7 @@ func (s *PlatformService) SendNotification(ctx context.Context, order *model.Ord
 		params = s.buildKekebangParams(order, account)
 	case "xianzhuanxia":
 		// 闲赚侠一般直接调用 ReportTask 方法，不需要拼接 URL
-		err := s.buildXianzhuanxiaParams(order, account, platform.ApiURL)
+		err := s.buildXianzhuanxiaParams(ctx, order, account, platform.ApiURL)
 		if err != nil {
 			return fmt.Errorf("上报订单结果失败: %w", err)
 		}
@@ -307,8 +309,11 @@ func (s *PlatformService) sendRequest(ctx context.Context, url string, params ma
 		Timeout: 10 * time.Second,
 	}
 	logger.Info(fmt.Sprintf("发送通知发送请求: %+v", req))
+	notificationService.CaptureRequest(ctx, req, jsonData)
+	startTime := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		notificationService.CaptureResponse(ctx, 0, nil, time.Since(startTime))
 		return nil, fmt.Errorf("发送请求失败1: %w", err)
 	}
 	defer resp.Body.Close()
@@ -318,6 +323,7 @@ func (s *PlatformService) sendRequest(ctx context.Context, url string, params ma
 	if err != nil {
 		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
+	notificationService.CaptureResponse(ctx, resp.StatusCode, body, time.Since(startTime))
 	// 打印原始响应
 	logger.Info(fmt.Sprintf("发送通知返回原始响应: %s\n", string(body)))
 	fmt.Printf("原始响应: %s\n", string(body))
@@ -380,7 +386,7 @@ func (s *PlatformService) buildMf178Params(order *model.Order, account *model.Pl
 		"data": map[string]interface{}{
 			"user_order_id": order.OutTradeNum,
 			"status":        s.getPlatformStatus(order.Status),
-			"rsp_info":      s.getStatusText(order.Status),
+			"rsp_info":      s.getStatusText(order),
 		},
 	}
 	jsonData, err := json.Marshal(data["data"])
@@ -421,15 +427,17 @@ func (s *PlatformService) getKekebangStatus(orderStatus model.OrderStatus) int {
 	}
 }
 
-func (s *PlatformService) getStatusText(orderStatus model.OrderStatus) string {
+func (s *PlatformService) getStatusText(order *model.Order) string {
 	// 根据订单状态返回对应的文本信息
-	switch orderStatus {
+	switch order.Status {
 	case model.OrderStatusSuccess:
 		return "充值成功"
 	case model.OrderStatusFailed:
 		return "充值失败"
 	case model.OrderStatusProcessing:
 		return "充值中"
+	case model.OrderStatusPartial:
+		return fmt.Sprintf("部分充值，实际到账%s元", order.ActualCredited().Round())
 	default:
 		return "未知状态"
 	}
@@ -446,7 +454,7 @@ func (s *PlatformService) generateSign(platformCode string, params map[string]in
 	}
 }
 
-func (s *PlatformService) buildXianzhuanxiaParams(order *model.Order, account *model.PlatformAccount, apiURL string) error {
+func (s *PlatformService) buildXianzhuanxiaParams(ctx context.Context, order *model.Order, account *model.PlatformAccount, apiURL string) error {
 
 	params := map[string]interface{}{
 		"orderNumber": order.OutTradeNum,
@@ -464,7 +472,7 @@ func (s *PlatformService) buildXianzhuanxiaParams(order *model.Order, account *m
 	}
 
 	url := fmt.Sprintf("%s/api/task/recharge/reported", apiURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -475,8 +483,11 @@ func (s *PlatformService) buildXianzhuanxiaParams(order *model.Order, account *m
 	fmt.Printf("req: %v\n", req)
 	logger.Info(fmt.Sprintf("发送闲赚侠上报订单结果请求: %v\n", req))
 	client := &http.Client{Timeout: 10 * time.Second}
+	notificationService.CaptureRequest(ctx, req, jsonData)
+	startTime := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		notificationService.CaptureResponse(ctx, 0, nil, time.Since(startTime))
 		return fmt.Errorf("发送请求失败: %v", err)
 	}
 	defer resp.Body.Close()
@@ -485,6 +496,7 @@ func (s *PlatformService) buildXianzhuanxiaParams(order *model.Order, account *m
 	if err != nil {
 		return fmt.Errorf("读取响应失败: %v", err)
 	}
+	notificationService.CaptureResponse(ctx, resp.StatusCode, body, time.Since(startTime))
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("请求失败: %s", string(body))
@@ -528,8 +540,8 @@ func (s *PlatformService) sendExternalAPINotification(ctx context.Context, order
 		"callback_url", order.PlatformCallbackURL,
 	)
 
-	// 只发送成功和失败状态的通知，其他状态不发送
-	if order.Status != model.OrderStatusSuccess && order.Status != model.OrderStatusFailed {
+	// 只发送成功、失败和部分充值状态的通知，其他状态不发送
+	if order.Status != model.OrderStatusSuccess && order.Status != model.OrderStatusFailed && order.Status != model.OrderStatusPartial {
 		logger.Info("订单状态不需要发送通知，跳过",
 			"order_id", order.ID,
 			"order_number", order.OrderNumber,
@@ -548,12 +560,18 @@ func (s *PlatformService) sendExternalAPINotification(ctx context.Context, order
 		return fmt.Errorf("外部API订单缺少回调URL")
 	}
 
+	// 获取客户的外部API密钥，通知参数签名与请求头签名都使用其 AppSecret
+	apiKey, err := s.getExternalAPIKey(order)
+	if err != nil {
+		return err
+	}
+
 	// 构建通知参数
 	logger.Info("开始构建外部API通知参数",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber,
 	)
-	params := s.buildExternalAPIParams(order)
+	params := s.buildExternalAPIParams(order, apiKey)
 	logger.Info("外部API通知参数构建完成",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber,
@@ -566,7 +584,7 @@ func (s *PlatformService) sendExternalAPINotification(ctx context.Context, order
 		"order_number", order.OrderNumber,
 		"customer_id", order.CustomerID,
 	)
-	sign := s.generateExternalAPISign(params, order)
+	sign := s.generateExternalAPISign(params, order, apiKey)
 	if sign == "" {
 		logger.Error("外部API签名生成失败",
 			"order_id", order.ID,
@@ -588,7 +606,7 @@ func (s *PlatformService) sendExternalAPINotification(ctx context.Context, order
 		"order_number", order.OrderNumber,
 		"callback_url", order.PlatformCallbackURL,
 	)
-	err := s.sendExternalAPIHTTPNotification(ctx, order.PlatformCallbackURL, params)
+	err = s.sendExternalAPIHTTPNotification(ctx, order.PlatformCallbackURL, params, apiKey.AppSecret)
 	if err != nil {
 		logger.Error("外部API HTTP通知发送失败",
 			"order_id", order.ID,
@@ -608,25 +626,28 @@ func (s *PlatformService) sendExternalAPINotification(ctx context.Context, order
 }
 
 // buildExternalAPIParams 构建外部API通知参数
-func (s *PlatformService) buildExternalAPIParams(order *model.Order) map[string]interface{} {
+func (s *PlatformService) buildExternalAPIParams(order *model.Order, apiKey *model.ExternalAPIKey) map[string]interface{} {
 	params := map[string]interface{}{
 		"out_trade_num": order.OutTradeNum,
 		"status":        s.getExternalAPIStatus(order.Status),
 		"timestamp":     time.Now().Unix(),
 		"nonce":         fmt.Sprintf("%d", time.Now().UnixNano()),
+		"app_id":        apiKey.AppID,
 	}
 
-	// 添加app_id参数（从订单的客户ID获取）
-	apiKeys, _, err := s.externalAPIKeyRepo.GetByUserID(order.CustomerID, 0, 1)
-	if err == nil && len(apiKeys) > 0 {
-		params["app_id"] = apiKeys[0].AppID
+	// 成功和部分充值附带实际到账面值，部分充值另附退款金额
+	if order.Status == model.OrderStatusSuccess || order.Status == model.OrderStatusPartial {
+		params["credited_amount"] = order.ActualCredited().Round().String()
+	}
+	if order.Status == model.OrderStatusPartial {
+		params["refund_amount"] = order.RefundedAmount.Round().String()
 	}
 
 	return params
 }
 
-// generateExternalAPISign 生成外部API签名
-func (s *PlatformService) generateExternalAPISign(params map[string]interface{}, order *model.Order) string {
+// getExternalAPIKey 获取订单客户的外部API密钥
+func (s *PlatformService) getExternalAPIKey(order *model.Order) (*model.ExternalAPIKey, error) {
 	logger.Info("开始获取外部API密钥",
 		"customer_id", order.CustomerID,
 		"order_id", order.ID,
@@ -636,14 +657,13 @@ func (s *PlatformService) generateExternalAPISign(params map[string]interface{},
 	// 根据订单的客户ID获取外部API密钥信息
 	apiKeys, total, err := s.externalAPIKeyRepo.GetByUserID(order.CustomerID, 0, 1)
 	if err != nil {
-		// 如果无法获取API密钥，记录日志并返回空签名
 		logger.Error("获取外部API密钥失败",
 			"error", err,
 			"customer_id", order.CustomerID,
 			"order_id", order.ID,
 			"order_number", order.OrderNumber,
 		)
-		return ""
+		return nil, fmt.Errorf("获取外部API密钥失败: %v", err)
 	}
 
 	logger.Info("外部API密钥查询结果",
@@ -662,11 +682,15 @@ func (s *PlatformService) generateExternalAPISign(params map[string]interface{},
 			"order_number", order.OrderNumber,
 			"total_keys", total,
 		)
-		return ""
+		return nil, fmt.Errorf("用户没有配置外部API密钥")
 	}
 
-	// 使用第一个API密钥生成签名
-	apiKey := apiKeys[0]
+	// 每个用户只有一个API密钥
+	return apiKeys[0], nil
+}
+
+// generateExternalAPISign 生成外部API签名
+func (s *PlatformService) generateExternalAPISign(params map[string]interface{}, order *model.Order, apiKey *model.ExternalAPIKey) string {
 	logger.Info("发送端签名生成参数",
 		"customer_id", order.CustomerID,
 		"order_id", order.ID,
@@ -711,6 +735,8 @@ func (s *PlatformService) getExternalAPIStatus(orderStatus model.OrderStatus) in
 		return 5 // 失败
 	case model.OrderStatusRecharging:
 		return 3 // 处理中
+	case model.OrderStatusPartial:
+		return 8 // 部分充值
 	default:
 		return 0 // 未知状态
 	}
@@ -725,13 +751,16 @@ func (s *PlatformService) getExternalAPIStatusDesc(orderStatus model.OrderStatus
 		return "充值失败"
 	case model.OrderStatusRecharging:
 		return "充值中"
+	case model.OrderStatusPartial:
+		return "部分充值"
 	default:
 		return "未知状态"
 	}
 }
 
 // sendExternalAPIHTTPNotification 发送外部API HTTP通知
-func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, callbackURL string, params map[string]interface{}) error {
+// 请求头按 webhook 包的格式以 appSecret 对请求体签名，接入方可据此校验通知来源
+func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, callbackURL string, params map[string]interface{}, appSecret string) error {
 	logger.Info("开始构建HTTP请求",
 		"callback_url", callbackURL,
 		"params_count", len(params),
@@ -775,6 +804,7 @@ func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, c
 	if sign, ok := params["sign"].(string); ok {
 		req.Header.Set("X-Signature", sign)
 	}
+	webhook.SetHeaders(req.Header, appSecret, jsonData, time.Now())
 
 	logger.Info("HTTP请求头设置完成",
 		"callback_url", callbackURL,
@@ -782,6 +812,8 @@ func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, c
 		"user_agent", req.Header.Get("User-Agent"),
 		"x_api_key", req.Header.Get("X-API-Key"),
 		"x_signature_length", len(req.Header.Get("X-Signature")),
+		"webhook_timestamp", req.Header.Get(webhook.TimestampHeader),
+		"webhook_nonce", req.Header.Get(webhook.NonceHeader),
 	)
 
 	// 发送请求
@@ -795,6 +827,7 @@ func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, c
 		"timeout", "30s",
 	)
 
+	notificationService.CaptureRequest(ctx, req, jsonData)
 	startTime := time.Now()
 	resp, err := client.Do(req)
 	duration := time.Since(startTime)
@@ -805,6 +838,7 @@ func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, c
 			"callback_url", callbackURL,
 			"duration", duration,
 		)
+		notificationService.CaptureResponse(ctx, 0, nil, duration)
 		return fmt.Errorf("发送请求失败: %v", err)
 	}
 	defer resp.Body.Close()
@@ -826,6 +860,7 @@ func (s *PlatformService) sendExternalAPIHTTPNotification(ctx context.Context, c
 		)
 		return fmt.Errorf("读取响应失败: %v", err)
 	}
+	notificationService.CaptureResponse(ctx, resp.StatusCode, body, duration)
 
 	logger.Info("HTTP响应读取完成",
 		"callback_url", callbackURL,
@@ -7,6 +7,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,6 +19,7 @@ type PlatformAccountBalanceService struct {
 	platformAccountRepo *repository.PlatformAccountRepository
 	userRepo            *repository.UserRepository
 	balanceLogRepo      *repository.BalanceLogRepository
+	ledger              *Ledger
 }
 
 // NewPlatformAccountBalanceService 创建平台账号余额服务实例
@@ -32,11 +34,12 @@ func NewPlatformAccountBalanceService(
 		platformAccountRepo: platformAccountRepo,
 		userRepo:            userRepo,
 		balanceLogRepo:      balanceLogRepo,
+		ledger:              NewLedger(db),
 	}
 }
 
 // DeductBalance 扣除余额，支持授信额度
-func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accountID int64, amount float64, orderID int64, remark string) error {
+func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accountID int64, amount money.Money, orderID int64, remark string) error {
 	logger.Info("开始扣除本地账号余额",
 		"platform_account_id", accountID,
 		"amount", amount,
@@ -56,19 +59,17 @@ func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accou
 		}
 	}()
 
-	// 1. 在事务中进行幂等性校验：同一用户、同一订单、同一扣款操作只允许扣款一次
-	var existCount int64
-	err := tx.Model(&model.BalanceLog{}).
-		Where("order_id = ? AND platform_account_id = ? AND style = ?", orderID, accountID, model.BalanceStyleOrderDeduct).
-		Count(&existCount).Error
+	// 1. 幂等性校验：同一订单只扣款一次，订单已扣款时不再校验余额；并发扣款由凭证幂等键的唯一索引兜底
+	key := model.OrderIdempotencyKey(orderID, model.IdempotencyDeduct)
+	posted, err := s.ledger.Posted(tx, key)
 	if err != nil {
 		tx.Rollback()
 		logger.Error("幂等性校验失败", "error", err, "order_id", orderID)
 		return err
 	}
-	if existCount > 0 {
+	if posted {
 		tx.Rollback()
-		logger.Info("已存在扣款日志，跳过重复扣款", "order_id", orderID, "account_id", accountID)
+		logger.Info("订单已扣款，跳过重复扣款", "order_id", orderID, "account_id", accountID)
 		return nil
 	}
 
@@ -105,17 +106,32 @@ func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accou
 		return errors.New("余额和授信额度均不足")
 	}
 
-	// 6. 扣减余额（余额优先，余额不足自动用授信额度）
+	// 6. 记账扣减余额（余额优先，余额不足时余额记为负数，即使用授信额度）
 	before := user.Balance
 	user.Balance -= amount
-	if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("balance", user.Balance).Error; err != nil {
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindOrderDeduct,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: key,
+		Remark:         remark,
+		Operator:       "system",
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, PlatformAccountID: accountID, Amount: -amount},
+			{Account: model.PlatformAccountSpendAccount(accountID), PlatformAccountID: accountID, Amount: amount},
+		},
+	}); err != nil {
 		tx.Rollback()
+		if errors.Is(err, ErrLedgerDuplicate) {
+			logger.Info("订单已扣款，跳过重复扣款", "order_id", orderID, "account_id", accountID)
+			return nil
+		}
 		logger.Error("更新本地用户余额失败", "error", err, "user_id", userID)
 		return err
 	}
 
 	// 7. 计算本次用掉的授信额度
-	creditUsed := 0.0
+	creditUsed := money.Zero
 	if user.Balance < 0 {
 		creditUsed = -user.Balance
 	}
@@ -133,7 +149,7 @@ func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accou
 		Style:             model.BalanceStyleOrderDeduct,
 		Balance:           user.Balance,
 		BalanceBefore:     before,
-		Remark:            fmt.Sprintf("%s（本次用掉授信额度：%.2f）", remark, creditUsed),
+		Remark:            fmt.Sprintf("%s（本次用掉授信额度：%s）", remark, creditUsed),
 		Operator:          "system",
 		CreatedAt:         time.Now(),
 	}
@@ -154,60 +170,65 @@ func (s *PlatformAccountBalanceService) DeductBalance(ctx context.Context, accou
 }
 
 // RefundBalance 退款到用户余额（使用原子性更新避免竞态条件）
-func (s *PlatformAccountBalanceService) RefundBalance(ctx context.Context, userID int64, amount float64, orderID int64, remark string) error {
+func (s *PlatformAccountBalanceService) RefundBalance(ctx context.Context, userID int64, amount money.Money, orderID int64, remark string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.RefundBalanceWithTx(ctx, tx, userID, amount, orderID, remark)
+	})
+}
+
+// RefundBalanceWithTx 在调用方事务内退款到用户余额，同一订单重复退款不会入账
+func (s *PlatformAccountBalanceService) RefundBalanceWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, remark string) error {
+	_, err := s.refundBalanceWithTx(ctx, tx, userID, amount, orderID, remark)
+	return err
+}
+
+// refundBalanceWithTx 执行退款，返回是否实际入账；订单已退款时返回 false
+func (s *PlatformAccountBalanceService) refundBalanceWithTx(ctx context.Context, tx *gorm.DB, userID int64, amount money.Money, orderID int64, remark string) (bool, error) {
 	if amount <= 0 {
-		return errors.New("退款金额必须大于0")
+		return false, errors.New("退款金额必须大于0")
 	}
-	
-	// 基于用户ID和订单ID的幂等性校验，防止重复退款
-	var existCount int64
-	if err := s.db.Model(&model.BalanceLog{}).Where("user_id = ? AND order_id = ? AND style = ?", userID, orderID, 2).Count(&existCount).Error; err != nil {
-		return err
+
+	// 记账增加余额，冲回订单扣款时记入的平台账号消耗科目；凭证幂等键防止重复退款
+	counterpart := s.ledger.RefundCounterpart(tx, orderID, userID, amount)
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindRefund,
+		UserID:         userID,
+		OrderID:        orderID,
+		IdempotencyKey: model.OrderIdempotencyKey(orderID, model.IdempotencyRefund),
+		Remark:         remark,
+		Operator:       "system",
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, PlatformAccountID: counterpart.PlatformAccountID, Amount: amount},
+			counterpart,
+		},
+	}); err != nil {
+		return false, ignoreDuplicate(err)
 	}
-	if existCount > 0 {
-		// 已存在退款记录，跳过重复退款
-		return nil
+
+	// 获取更新后的余额（在同一事务中确保数据一致性）
+	var user model.User
+	if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+		return false, err
 	}
-	
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 关键改进：使用原子性更新避免读取-计算-写入的竞态条件
-		// 先原子性更新余额
-		result := tx.Model(&model.User{}).
-			Where("id = ?", userID).
-			Update("balance", gorm.Expr("balance + ?", amount))
-		
-		if result.Error != nil {
-			return result.Error
-		}
-		
-		if result.RowsAffected == 0 {
-			return errors.New("用户不存在")
-		}
-		
-		// 获取更新后的余额（在同一事务中确保数据一致性）
-		var user model.User
-		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
-			return err
-		}
-		
-		afterBalance := user.Balance
-		beforeBalance := afterBalance - amount
-		
-		// 记录用户余额变动日志
-		log := &model.BalanceLog{
-			UserID:        userID,
-			Amount:        amount,
-			Type:          1, // 收入
-			Style:         2, // 退款
-			Balance:       afterBalance,
-			BalanceBefore: beforeBalance,
-			Remark:        remark,
-			Operator:      "system",
-			OrderID:       orderID,
-			CreatedAt:     time.Now(),
-		}
-		return tx.Create(log).Error
-	})
+
+	afterBalance := user.Balance
+	beforeBalance := afterBalance - amount
+
+	// 记录用户余额变动日志
+	log := &model.BalanceLog{
+		UserID:            userID,
+		PlatformAccountID: counterpart.PlatformAccountID,
+		Amount:            amount,
+		Type:              1, // 收入
+		Style:             2, // 退款
+		Balance:           afterBalance,
+		BalanceBefore:     beforeBalance,
+		Remark:            remark,
+		Operator:          "system",
+		OrderID:           orderID,
+		CreatedAt:         time.Now(),
+	}
+	return true, tx.Create(log).Error
 }
 
 // GetBalanceLogs 获取余额变动记录
@@ -245,7 +266,7 @@ func (s *PlatformAccountBalanceService) GetBalanceLogs(ctx context.Context, acco
 }
 
 // AdjustBalance 手动调整余额
-func (s *PlatformAccountBalanceService) AdjustBalance(ctx context.Context, accountID int64, amount float64, style int, remark string, operator string) error {
+func (s *PlatformAccountBalanceService) AdjustBalance(ctx context.Context, accountID int64, amount money.Money, style int, remark string, operator string) error {
 	logger.Info("开始手动调整余额",
 		"account_id", accountID,
 		"amount", amount,
@@ -285,25 +306,29 @@ func (s *PlatformAccountBalanceService) AdjustBalance(ctx context.Context, accou
 	}
 	userID := *account.BindUserID
 
-	// 3. 调整余额（使用原子性更新避免竞态条件）
-	result := tx.Model(&model.User{}).
-		Where("id = ?", userID).
-		Update("balance", gorm.Expr("balance + ?", amount))
-	
-	if result.Error != nil {
+	// 3. 记账调整余额，对方科目为外部入金
+	if _, err := s.ledger.Post(tx, &LedgerJournal{
+		Kind:           model.LedgerKindAdjust,
+		UserID:         userID,
+		IdempotencyKey: requestIdempotencyKey(ctx, fmt.Sprintf("platform_account:%d:adjust", accountID)),
+		Remark:         remark,
+		Operator:       operator,
+		Postings: []LedgerPosting{
+			{Account: model.UserBalanceAccount(userID), UserID: userID, PlatformAccountID: accountID, Amount: amount},
+			{Account: model.LedgerAccountFunding, PlatformAccountID: accountID, Amount: -amount},
+		},
+	}); err != nil {
 		tx.Rollback()
+		if errors.Is(err, ErrLedgerDuplicate) {
+			logger.Info("重复的手动调整请求，跳过", "account_id", accountID)
+			return nil
+		}
 		logger.Error("更新本地用户余额失败",
-			"error", result.Error,
+			"error", err,
 			"user_id", userID)
-		return result.Error
-	}
-	
-	if result.RowsAffected == 0 {
-		tx.Rollback()
-		logger.Error("用户不存在", "user_id", userID)
-		return errors.New("用户不存在")
+		return err
 	}
-	
+
 	// 获取更新后的余额（在同一事务中确保数据一致性）
 	var updatedUser model.User
 	if err := tx.Where("id = ?", userID).First(&updatedUser).Error; err != nil {
@@ -311,7 +336,7 @@ func (s *PlatformAccountBalanceService) AdjustBalance(ctx context.Context, accou
 		logger.Error("获取更新后用户信息失败", "error", err, "user_id", userID)
 		return err
 	}
-	
+
 	afterBalance := updatedUser.Balance
 	beforeBalance := afterBalance - amount
 
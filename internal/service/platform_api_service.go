@@ -37,6 +37,10 @@ func NewPlatformAPIService(repo repository.PlatformAPIRepository) PlatformAPISer
 }
 
 func (s *platformAPIService) CreateAPI(ctx context.Context, api *model.PlatformAPI) error {
+	if _, err := api.CallbackCIDRs(); err != nil {
+		return fmt.Errorf("回调IP白名单格式错误: %v", err)
+	}
+
 	// 检查接口代码是否已存在
 	existing, err := s.repo.GetByCode(ctx, api.Code)
 	fmt.Println(api.Code, "#######")
@@ -51,6 +55,10 @@ func (s *platformAPIService) CreateAPI(ctx context.Context, api *model.PlatformA
 }
 
 func (s *platformAPIService) UpdateAPI(ctx context.Context, api *model.PlatformAPI) error {
+	if _, err := api.CallbackCIDRs(); err != nil {
+		return fmt.Errorf("回调IP白名单格式错误: %v", err)
+	}
+
 	// 检查接口是否存在
 	existing, err := s.repo.GetByID(ctx, api.ID)
 	if err != nil {
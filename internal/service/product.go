@@ -68,6 +68,9 @@ func (s *ProductService) GetByID(ctx context.Context, id int64) (*model.ProductD
 
 // Create 创建商品
 func (s *ProductService) Create(ctx context.Context, req *model.ProductCreateRequest) (*model.Product, error) {
+	if !IsRouteStrategyRegistered(req.RouteStrategy) {
+		return nil, fmt.Errorf("不支持的路由策略: %s", req.RouteStrategy)
+	}
 	product := &model.Product{
 		Name:            req.Name,
 		Description:     req.Description,
@@ -94,6 +97,7 @@ func (s *ProductService) Create(ctx context.Context, req *model.ProductCreateReq
 		APIID:           req.APIID,
 		APIParamID:      req.APIParamID,
 		IsApi:           req.IsApi,
+		RouteStrategy:   req.RouteStrategy,
 	}
 
 	err := s.productRepo.Create(ctx, product)
@@ -106,6 +110,9 @@ func (s *ProductService) Create(ctx context.Context, req *model.ProductCreateReq
 
 // Update 更新商品
 func (s *ProductService) Update(ctx context.Context, req *model.ProductUpdateRequest) (*model.Product, error) {
+	if !IsRouteStrategyRegistered(req.RouteStrategy) {
+		return nil, fmt.Errorf("不支持的路由策略: %s", req.RouteStrategy)
+	}
 	product, err := s.productRepo.GetByID(ctx, req.ID)
 	if err != nil {
 		return nil, err
@@ -136,6 +143,7 @@ func (s *ProductService) Update(ctx context.Context, req *model.ProductUpdateReq
 	product.APIID = req.APIID
 	product.APIParamID = req.APIParamID
 	product.IsApi = req.IsApi
+	product.RouteStrategy = req.RouteStrategy
 
 	fmt.Println("更新商品分类ID：", req.CategoryID)
 
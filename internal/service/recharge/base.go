@@ -7,6 +7,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 )
 
 // rechargeService 充值服务实现
@@ -14,8 +15,8 @@ type rechargeService struct {
 	platformAccountRepo repository.PlatformAccountRepository
 	platformRepo        repository.PlatformRepository
 	balanceService      interface { // 只用接口，避免循环依赖
-		DeductBalance(ctx context.Context, accountID int64, amount float64, orderID int64, remark string) error
-		RefundBalance(ctx context.Context, tx interface{}, accountID int64, amount float64, orderID int64, remark string) error
+		DeductBalance(ctx context.Context, accountID int64, amount money.Money, orderID int64, remark string) error
+		RefundBalance(ctx context.Context, tx interface{}, accountID int64, amount money.Money, orderID int64, remark string) error
 	}
 	orderRepo repository.OrderRepository
 	manager   *Manager
@@ -26,8 +27,8 @@ func NewRechargeService(
 	platformAccountRepo repository.PlatformAccountRepository,
 	platformRepo repository.PlatformRepository,
 	balanceService interface {
-		DeductBalance(ctx context.Context, accountID int64, amount float64, orderID int64, remark string) error
-		RefundBalance(ctx context.Context, tx interface{}, accountID int64, amount float64, orderID int64, remark string) error
+		DeductBalance(ctx context.Context, accountID int64, amount money.Money, orderID int64, remark string) error
+		RefundBalance(ctx context.Context, tx interface{}, accountID int64, amount money.Money, orderID int64, remark string) error
 	},
 	orderRepo repository.OrderRepository,
 	manager *Manager,
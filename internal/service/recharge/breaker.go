@@ -0,0 +1,607 @@
+package recharge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"recharge-go/configs"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/redis"
+	"strconv"
+	"sync"
+	"time"
+
+	redisV8 "github.com/go-redis/redis/v8"
+)
+
+// ErrCircuitOpen 平台接口熔断中
+var ErrCircuitOpen = errors.New("platform api circuit open")
+
+// CircuitState 熔断器状态
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常
+	CircuitOpen     CircuitState = "open"      // 熔断，路由跳过该接口
+	CircuitHalfOpen CircuitState = "half_open" // 半开，放行少量探测请求
+)
+
+// HealthEventKind 健康事件来源
+type HealthEventKind string
+
+const (
+	HealthEventSubmit   HealthEventKind = "submit"   // 提交订单
+	HealthEventQuery    HealthEventKind = "query"    // 查询订单
+	HealthEventCallback HealthEventKind = "callback" // 回调结果
+)
+
+var healthEventKinds = []HealthEventKind{HealthEventSubmit, HealthEventQuery, HealthEventCallback}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	Window               time.Duration // 滚动统计窗口
+	MinRequests          int64         // 窗口内最少请求数，未达到时不熔断
+	FailureRateThreshold float64       // 加权失败率阈值，达到后熔断
+	OpenDuration         time.Duration // 熔断持续时间，到期后进入半开
+	HalfOpenProbes       int           // 半开状态允许的探测请求数，全部成功后恢复
+	CallbackWeight       float64       // 回调失败的权重（回调失败多为号码/业务原因，默认低于提交失败）
+}
+
+// DefaultBreakerConfig 默认熔断器配置
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:               5 * time.Minute,
+		MinRequests:          10,
+		FailureRateThreshold: 0.5,
+		OpenDuration:         time.Minute,
+		HalfOpenProbes:       3,
+		CallbackWeight:       0.5,
+	}
+}
+
+// BreakerConfigFromSettings 由配置文件的熔断配置生成熔断器配置，未配置的项使用默认值
+func BreakerConfigFromSettings(bc configs.BreakerConfig) BreakerConfig {
+	cfg := DefaultBreakerConfig()
+	if bc.WindowSeconds > 0 {
+		cfg.Window = time.Duration(bc.WindowSeconds) * time.Second
+	}
+	if bc.MinRequests > 0 {
+		cfg.MinRequests = int64(bc.MinRequests)
+	}
+	if bc.FailureRate > 0 {
+		cfg.FailureRateThreshold = bc.FailureRate
+	}
+	if bc.OpenSeconds > 0 {
+		cfg.OpenDuration = time.Duration(bc.OpenSeconds) * time.Second
+	}
+	if bc.HalfOpenProbes > 0 {
+		cfg.HalfOpenProbes = bc.HalfOpenProbes
+	}
+	if bc.CallbackWeight > 0 {
+		cfg.CallbackWeight = bc.CallbackWeight
+	}
+	return cfg
+}
+
+// HealthCounts 成功/失败次数
+type HealthCounts struct {
+	Success int64 `json:"success"`
+	Failure int64 `json:"failure"`
+}
+
+// APIHealth 平台接口健康状况
+type APIHealth struct {
+	APIID       int64                            `json:"api_id"`
+	State       CircuitState                     `json:"state"`
+	Score       float64                          `json:"score"`        // 健康分 0-100
+	FailureRate float64                          `json:"failure_rate"` // 加权失败率
+	Events      map[HealthEventKind]HealthCounts `json:"events"`       // 窗口内各来源统计
+	OpenedAt    *time.Time                       `json:"opened_at,omitempty"`
+}
+
+// HealthObserver 健康状况观察者，用于上报监控指标
+type HealthObserver interface {
+	ObserveSupplierCall(apiID int64, kind string, success bool)
+	ObserveSupplierHealth(apiID int64, state string, score float64)
+}
+
+// breakerState 熔断器持久化状态
+type breakerState struct {
+	State             CircuitState
+	OpenedAt          time.Time
+	Generation        int64 // 每次恢复后递增，统计只取当前代的事件，恢复前的失败不再参与判断
+	HalfOpenSuccesses int
+}
+
+// healthStore 健康数据存储
+type healthStore interface {
+	record(ctx context.Context, apiID, gen int64, kind HealthEventKind, success bool, now time.Time, window time.Duration) error
+	counts(ctx context.Context, apiID, gen int64, since, now time.Time) (map[HealthEventKind]HealthCounts, error)
+	loadState(ctx context.Context, apiID int64) (breakerState, error)
+	saveState(ctx context.Context, apiID int64, state breakerState) error
+	acquireProbe(ctx context.Context, apiID int64, limit int, ttl time.Duration) (bool, error)
+	resetProbes(ctx context.Context, apiID int64) error
+}
+
+// CircuitBreaker 按平台接口（PlatformAPI）维度的熔断器
+// 有 Redis 时状态保存在 Redis 中，多个进程（API、充值worker、任务）共享；否则保存在进程内存中
+type CircuitBreaker struct {
+	config   BreakerConfig
+	store    healthStore
+	observer HealthObserver
+}
+
+// NewCircuitBreaker 创建熔断器，client 为 nil 时使用内存存储
+func NewCircuitBreaker(client *redisV8.Client, config BreakerConfig, observer HealthObserver) *CircuitBreaker {
+	var store healthStore
+	if client != nil {
+		store = &redisHealthStore{client: client}
+	} else {
+		store = newMemoryHealthStore()
+	}
+	return &CircuitBreaker{
+		config:   config,
+		store:    store,
+		observer: observer,
+	}
+}
+
+var (
+	defaultBreakerMu sync.Mutex
+	defaultBreaker   *CircuitBreaker
+)
+
+// DefaultCircuitBreaker 获取全局熔断器
+func DefaultCircuitBreaker() *CircuitBreaker {
+	defaultBreakerMu.Lock()
+	defer defaultBreakerMu.Unlock()
+
+	if defaultBreaker == nil {
+		defaultBreaker = NewCircuitBreaker(redis.GetClient(), DefaultBreakerConfig(), nil)
+	}
+	return defaultBreaker
+}
+
+// ConfigureCircuitBreaker 设置全局熔断器配置及指标观察者，需在创建 Manager 之前调用
+func ConfigureCircuitBreaker(config BreakerConfig, observer HealthObserver) {
+	defaultBreakerMu.Lock()
+	defer defaultBreakerMu.Unlock()
+
+	defaultBreaker = NewCircuitBreaker(redis.GetClient(), config, observer)
+}
+
+// Allow 判断是否允许向该接口提交请求，半开状态会占用一个探测名额
+func (b *CircuitBreaker) Allow(ctx context.Context, apiID int64) bool {
+	now := time.Now()
+	state, err := b.store.loadState(ctx, apiID)
+	if err != nil {
+		// 存储异常时放行，避免熔断器本身成为故障点
+		logger.Error("读取熔断状态失败", "api_id", apiID, "error", err)
+		return true
+	}
+
+	switch state.State {
+	case CircuitOpen:
+		if now.Before(state.OpenedAt.Add(b.config.OpenDuration)) {
+			return false
+		}
+		state.State = CircuitHalfOpen
+		state.HalfOpenSuccesses = 0
+		if err := b.store.resetProbes(ctx, apiID); err != nil {
+			logger.Error("重置熔断探测名额失败", "api_id", apiID, "error", err)
+		}
+		if err := b.store.saveState(ctx, apiID, state); err != nil {
+			logger.Error("保存熔断状态失败", "api_id", apiID, "error", err)
+		}
+		logger.Info("平台接口熔断到期，进入半开状态", "api_id", apiID)
+		fallthrough
+	case CircuitHalfOpen:
+		ok, err := b.store.acquireProbe(ctx, apiID, b.config.HalfOpenProbes, b.config.OpenDuration)
+		if err != nil {
+			logger.Error("获取熔断探测名额失败", "api_id", apiID, "error", err)
+			return true
+		}
+		return ok
+	default:
+		return true
+	}
+}
+
+// Available 判断接口当前是否可被路由选中，不占用探测名额
+func (b *CircuitBreaker) Available(ctx context.Context, apiID int64) bool {
+	state, err := b.store.loadState(ctx, apiID)
+	if err != nil {
+		logger.Error("读取熔断状态失败", "api_id", apiID, "error", err)
+		return true
+	}
+	if state.State != CircuitOpen {
+		return true
+	}
+	return !time.Now().Before(state.OpenedAt.Add(b.config.OpenDuration))
+}
+
+// Record 记录一次调用结果并更新熔断状态
+func (b *CircuitBreaker) Record(ctx context.Context, apiID int64, kind HealthEventKind, success bool) {
+	if apiID <= 0 {
+		return
+	}
+	now := time.Now()
+	state, err := b.store.loadState(ctx, apiID)
+	if err != nil {
+		logger.Error("读取熔断状态失败", "api_id", apiID, "error", err)
+		return
+	}
+	if err := b.store.record(ctx, apiID, state.Generation, kind, success, now, b.config.Window); err != nil {
+		logger.Error("记录平台接口健康事件失败", "api_id", apiID, "kind", kind, "error", err)
+		return
+	}
+	if b.observer != nil {
+		b.observer.ObserveSupplierCall(apiID, string(kind), success)
+	}
+
+	switch state.State {
+	case CircuitHalfOpen:
+		if !success {
+			b.open(ctx, apiID, state, now, "半开探测失败")
+		} else if kind != HealthEventCallback {
+			state.HalfOpenSuccesses++
+			if state.HalfOpenSuccesses >= b.config.HalfOpenProbes {
+				b.close(ctx, apiID, state)
+			} else if err := b.store.saveState(ctx, apiID, state); err != nil {
+				logger.Error("保存熔断状态失败", "api_id", apiID, "error", err)
+			}
+		}
+	case CircuitOpen:
+		// 熔断期间的迟到事件只做统计
+	default:
+		if !success {
+			counts, err := b.store.counts(ctx, apiID, state.Generation, now.Add(-b.config.Window), now)
+			if err != nil {
+				logger.Error("计算平台接口健康状况失败", "api_id", apiID, "error", err)
+				return
+			}
+			result := b.weigh(counts)
+			if result.total >= b.config.MinRequests && result.failureRate >= b.config.FailureRateThreshold {
+				b.open(ctx, apiID, state, now, fmt.Sprintf("失败率 %.2f 超过阈值", result.failureRate))
+				return
+			}
+		}
+	}
+	b.publish(ctx, apiID)
+}
+
+// Health 获取接口健康状况，统计范围为最近一次恢复之后的滚动窗口
+func (b *CircuitBreaker) Health(ctx context.Context, apiID int64) (*APIHealth, error) {
+	now := time.Now()
+	state, err := b.store.loadState(ctx, apiID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := b.store.counts(ctx, apiID, state.Generation, now.Add(-b.config.Window), now)
+	if err != nil {
+		return nil, err
+	}
+	result := b.weigh(counts)
+
+	health := &APIHealth{
+		APIID:       apiID,
+		State:       state.State,
+		FailureRate: result.failureRate,
+		Events:      counts,
+		Score:       b.score(state.State, result),
+	}
+	if state.State == "" {
+		health.State = CircuitClosed
+	}
+	if state.State == CircuitOpen {
+		openedAt := state.OpenedAt
+		health.OpenedAt = &openedAt
+	}
+	return health, nil
+}
+
+// Reset 手动恢复接口，清除熔断状态
+func (b *CircuitBreaker) Reset(ctx context.Context, apiID int64) error {
+	state, err := b.store.loadState(ctx, apiID)
+	if err != nil {
+		return err
+	}
+	if err := b.store.saveState(ctx, apiID, breakerState{State: CircuitClosed, Generation: state.Generation + 1}); err != nil {
+		return err
+	}
+	if err := b.store.resetProbes(ctx, apiID); err != nil {
+		return err
+	}
+	logger.Info("平台接口熔断已手动恢复", "api_id", apiID)
+	b.publish(ctx, apiID)
+	return nil
+}
+
+func (b *CircuitBreaker) open(ctx context.Context, apiID int64, state breakerState, now time.Time, reason string) {
+	if err := b.store.saveState(ctx, apiID, breakerState{State: CircuitOpen, OpenedAt: now, Generation: state.Generation}); err != nil {
+		logger.Error("保存熔断状态失败", "api_id", apiID, "error", err)
+		return
+	}
+	logger.Error("平台接口熔断", "api_id", apiID, "reason", reason, "open_duration", b.config.OpenDuration.String())
+	b.publish(ctx, apiID)
+}
+
+func (b *CircuitBreaker) close(ctx context.Context, apiID int64, state breakerState) {
+	if err := b.store.saveState(ctx, apiID, breakerState{State: CircuitClosed, Generation: state.Generation + 1}); err != nil {
+		logger.Error("保存熔断状态失败", "api_id", apiID, "error", err)
+		return
+	}
+	logger.Info("平台接口探测成功，熔断恢复", "api_id", apiID)
+}
+
+// publish 上报健康分指标
+func (b *CircuitBreaker) publish(ctx context.Context, apiID int64) {
+	if b.observer == nil {
+		return
+	}
+	health, err := b.Health(ctx, apiID)
+	if err != nil {
+		return
+	}
+	b.observer.ObserveSupplierHealth(apiID, string(health.State), health.Score)
+}
+
+// weighted 加权统计结果
+type weighted struct {
+	total       int64
+	failureRate float64
+}
+
+func (b *CircuitBreaker) weigh(counts map[HealthEventKind]HealthCounts) weighted {
+	var total int64
+	var weightedTotal, weightedFailure float64
+	for kind, c := range counts {
+		weight := 1.0
+		if kind == HealthEventCallback {
+			weight = b.config.CallbackWeight
+		}
+		total += c.Success + c.Failure
+		weightedTotal += weight * float64(c.Success+c.Failure)
+		weightedFailure += weight * float64(c.Failure)
+	}
+	result := weighted{total: total}
+	if weightedTotal > 0 {
+		result.failureRate = weightedFailure / weightedTotal
+	}
+	return result
+}
+
+// score 健康分：加权成功率*100，熔断时为0，半开时最高50
+func (b *CircuitBreaker) score(state CircuitState, result weighted) float64 {
+	score := 100 * (1 - result.failureRate)
+	switch state {
+	case CircuitOpen:
+		return 0
+	case CircuitHalfOpen:
+		if score > 50 {
+			return 50
+		}
+	}
+	return score
+}
+
+// ---------------------------------------------------------------------------
+// Redis 存储：按分钟分桶计数，key 在窗口过期后自动删除
+
+const (
+	healthBucketKeyPrefix = "recharge:health:"
+	breakerStateKeyPrefix = "recharge:breaker:"
+)
+
+type redisHealthStore struct {
+	client *redisV8.Client
+}
+
+func healthBucketKey(apiID, gen, minute int64) string {
+	return fmt.Sprintf("%s%d:%d:%d", healthBucketKeyPrefix, apiID, gen, minute)
+}
+
+func breakerStateKey(apiID int64) string {
+	return fmt.Sprintf("%s%d", breakerStateKeyPrefix, apiID)
+}
+
+func breakerProbeKey(apiID int64) string {
+	return fmt.Sprintf("%s%d:probes", breakerStateKeyPrefix, apiID)
+}
+
+func healthField(kind HealthEventKind, success bool) string {
+	if success {
+		return string(kind) + ":ok"
+	}
+	return string(kind) + ":fail"
+}
+
+func (s *redisHealthStore) record(ctx context.Context, apiID, gen int64, kind HealthEventKind, success bool, now time.Time, window time.Duration) error {
+	key := healthBucketKey(apiID, gen, now.Unix()/60)
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, healthField(kind, success), 1)
+	pipe.Expire(ctx, key, window+2*time.Minute)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisHealthStore) counts(ctx context.Context, apiID, gen int64, since, now time.Time) (map[HealthEventKind]HealthCounts, error) {
+	pipe := s.client.Pipeline()
+	var cmds []*redisV8.StringStringMapCmd
+	for minute := since.Unix() / 60; minute <= now.Unix()/60; minute++ {
+		cmds = append(cmds, pipe.HGetAll(ctx, healthBucketKey(apiID, gen, minute)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redisV8.Nil {
+		return nil, err
+	}
+
+	counts := make(map[HealthEventKind]HealthCounts, len(healthEventKinds))
+	for _, kind := range healthEventKinds {
+		var c HealthCounts
+		for _, cmd := range cmds {
+			fields := cmd.Val()
+			ok, _ := strconv.ParseInt(fields[healthField(kind, true)], 10, 64)
+			fail, _ := strconv.ParseInt(fields[healthField(kind, false)], 10, 64)
+			c.Success += ok
+			c.Failure += fail
+		}
+		counts[kind] = c
+	}
+	return counts, nil
+}
+
+func (s *redisHealthStore) loadState(ctx context.Context, apiID int64) (breakerState, error) {
+	fields, err := s.client.HGetAll(ctx, breakerStateKey(apiID)).Result()
+	if err != nil {
+		return breakerState{}, err
+	}
+	state := breakerState{State: CircuitState(fields["state"])}
+	if state.State == "" {
+		state.State = CircuitClosed
+	}
+	if v, err := strconv.ParseInt(fields["opened_at"], 10, 64); err == nil && v > 0 {
+		state.OpenedAt = time.Unix(v, 0)
+	}
+	state.Generation, _ = strconv.ParseInt(fields["generation"], 10, 64)
+	state.HalfOpenSuccesses, _ = strconv.Atoi(fields["half_open_successes"])
+	return state, nil
+}
+
+func (s *redisHealthStore) saveState(ctx context.Context, apiID int64, state breakerState) error {
+	fields := map[string]interface{}{
+		"state":               string(state.State),
+		"opened_at":           unixOrZero(state.OpenedAt),
+		"generation":          state.Generation,
+		"half_open_successes": state.HalfOpenSuccesses,
+	}
+	return s.client.HSet(ctx, breakerStateKey(apiID), fields).Err()
+}
+
+func (s *redisHealthStore) acquireProbe(ctx context.Context, apiID int64, limit int, ttl time.Duration) (bool, error) {
+	key := breakerProbeKey(apiID)
+	n, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if n == 1 {
+		s.client.Expire(ctx, key, ttl)
+	}
+	return n <= int64(limit), nil
+}
+
+func (s *redisHealthStore) resetProbes(ctx context.Context, apiID int64) error {
+	return s.client.Del(ctx, breakerProbeKey(apiID)).Err()
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// ---------------------------------------------------------------------------
+// 内存存储：未配置 Redis 时使用，仅在当前进程内生效
+
+type memoryAPIHealth struct {
+	buckets      map[[2]int64]map[string]int64 // [代, 分钟] -> 计数
+	state        breakerState
+	probes       int
+	probesExpire time.Time
+}
+
+type memoryHealthStore struct {
+	mu   sync.Mutex
+	apis map[int64]*memoryAPIHealth
+}
+
+func newMemoryHealthStore() *memoryHealthStore {
+	return &memoryHealthStore{apis: make(map[int64]*memoryAPIHealth)}
+}
+
+func (s *memoryHealthStore) get(apiID int64) *memoryAPIHealth {
+	h, exists := s.apis[apiID]
+	if !exists {
+		h = &memoryAPIHealth{
+			buckets: make(map[[2]int64]map[string]int64),
+			state:   breakerState{State: CircuitClosed},
+		}
+		s.apis[apiID] = h
+	}
+	return h
+}
+
+func (s *memoryHealthStore) record(ctx context.Context, apiID, gen int64, kind HealthEventKind, success bool, now time.Time, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(apiID)
+	bucket := [2]int64{gen, now.Unix() / 60}
+	if h.buckets[bucket] == nil {
+		h.buckets[bucket] = make(map[string]int64)
+	}
+	h.buckets[bucket][healthField(kind, success)]++
+
+	// 清理过期及旧代分桶
+	expired := now.Add(-window-2*time.Minute).Unix() / 60
+	for b := range h.buckets {
+		if b[0] != gen || b[1] < expired {
+			delete(h.buckets, b)
+		}
+	}
+	return nil
+}
+
+func (s *memoryHealthStore) counts(ctx context.Context, apiID, gen int64, since, now time.Time) (map[HealthEventKind]HealthCounts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(apiID)
+	counts := make(map[HealthEventKind]HealthCounts, len(healthEventKinds))
+	for _, kind := range healthEventKinds {
+		var c HealthCounts
+		for minute := since.Unix() / 60; minute <= now.Unix()/60; minute++ {
+			bucket := [2]int64{gen, minute}
+			c.Success += h.buckets[bucket][healthField(kind, true)]
+			c.Failure += h.buckets[bucket][healthField(kind, false)]
+		}
+		counts[kind] = c
+	}
+	return counts, nil
+}
+
+func (s *memoryHealthStore) loadState(ctx context.Context, apiID int64) (breakerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(apiID).state, nil
+}
+
+func (s *memoryHealthStore) saveState(ctx context.Context, apiID int64, state breakerState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(apiID).state = state
+	return nil
+}
+
+func (s *memoryHealthStore) acquireProbe(ctx context.Context, apiID int64, limit int, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(apiID)
+	now := time.Now()
+	if now.After(h.probesExpire) {
+		h.probes = 0
+		h.probesExpire = now.Add(ttl)
+	}
+	h.probes++
+	return h.probes <= limit, nil
+}
+
+func (s *memoryHealthStore) resetProbes(ctx context.Context, apiID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.get(apiID)
+	h.probes = 0
+	h.probesExpire = time.Time{}
+	return nil
+}
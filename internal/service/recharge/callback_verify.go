@@ -0,0 +1,78 @@
+package recharge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultCallbackTolerance 回调时间戳与当前时间允许的最大偏差
+const DefaultCallbackTolerance = 10 * time.Minute
+
+// ErrCallbackAccountRequired 平台按账号密钥验签，回调地址中缺少平台账号
+var ErrCallbackAccountRequired = errors.New("callback platform account required")
+
+type callbackReceivedAtKey struct{}
+
+// WithCallbackReceivedAt 指定回调的接收时间，重放历史回调时按原始接收时间校验时间戳
+func WithCallbackReceivedAt(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, callbackReceivedAtKey{}, t)
+}
+
+// CallbackReceivedAt 回调的接收时间，未指定时为当前时间
+func CallbackReceivedAt(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(callbackReceivedAtKey{}).(time.Time); ok && !t.IsZero() {
+		return t
+	}
+	return time.Now()
+}
+
+// CheckCallbackTimestamp 校验回调时间与当前时间的偏差
+// 支持秒、毫秒时间戳及 2006-01-02 15:04:05 格式，tolerance 为 0 时不校验
+func CheckCallbackTimestamp(value string, now time.Time, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		return nil
+	}
+	if value == "" {
+		return errors.New("missing callback timestamp")
+	}
+	t, err := parseCallbackTime(value)
+	if err != nil {
+		return err
+	}
+	if diff := now.Sub(t); diff > tolerance || diff < -tolerance {
+		return fmt.Errorf("callback timestamp %s out of tolerance %s", value, tolerance)
+	}
+	return nil
+}
+
+// parseCallbackTime 解析回调时间
+func parseCallbackTime(value string) (time.Time, error) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid callback timestamp: %s", value)
+	}
+	return t, nil
+}
+
+// callbackParamString JSON 回调参数转为字符串，数字不使用科学计数法
+func callbackParamString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
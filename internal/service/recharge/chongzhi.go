@@ -53,11 +53,29 @@ func NewChongzhiPlatform(db *gorm.DB) *ChongzhiPlatform {
 	}
 }
 
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code: "chongzhi",
+		Name: "充值平台",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: false, // 查询接口尚未对接
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewChongzhiPlatform(db) })
+}
+
 // GetName 获取平台名称
 func (p *ChongzhiPlatform) GetName() string {
 	return "chongzhi"
 }
 
+// CallbackAck 回调应答，文档要求返回 OK
+func (p *ChongzhiPlatform) CallbackAck(ctx context.Context) string {
+	return "OK"
+}
+
 // getAPIKeyAndSecret 获取API密钥和密钥
 func (p *ChongzhiPlatform) getAPIKeyAndSecret(accountID int64) (string, string, error) {
 	account, err := p.platformRepo.GetAccountByID(context.Background(), accountID)
@@ -79,7 +97,7 @@ func (p *ChongzhiPlatform) SubmitOrder(ctx context.Context, order *model.Order,
 
 	// 构造请求参数
 	productid := apiParam.ProductID
-	price := fmt.Sprintf("%v", order.Denom) // 转换为字符串
+	price := fmt.Sprintf("%v", order.Denom.Float64()) // 转换为字符串
 	num := "1"
 	mobile := order.Mobile
 	spordertime := time.Now().Format("20060102150405")
@@ -207,12 +225,12 @@ func (p *ChongzhiPlatform) SubmitOrder(ctx context.Context, order *model.Order,
 }
 
 // QueryOrderStatus 查询订单状态
-func (p *ChongzhiPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
+func (p *ChongzhiPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	logger.Info(fmt.Sprintf("【开始查询订单状态】order_id: %d, order_number: %s", order.ID, order.OrderNumber))
 
 	// 这里需要根据实际的查询接口实现
 	// 暂时返回处理中状态
-	return model.OrderStatusRecharging, nil
+	return &QueryResult{Status: model.OrderStatusRecharging}, nil
 }
 
 // CallbackRequest 回调请求参数结构
@@ -228,27 +246,56 @@ type CallbackRequest struct {
 	FundBalance        string `form:"fundbalance" json:"fundbalance"`
 }
 
-// ParseCallbackData 解析回调数据
-func (p *ChongzhiPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
-	logger.Info("开始解析充值平台回调数据", "原始数据长度", len(data), "原始数据", string(data))
+// VerifyCallback 回调验签：sign=MD5(userid=xxxx&orderid=xxxxxxx&sporderid=xxxxx&merchantsubmittime=xxxxx&resultno=xxxxx&key=xxxxxxx).toUpperCase()
+// merchantsubmittime 为下单时间不做时效校验，回调中的 userid 须与回调地址中的账号一致
+func (p *ChongzhiPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
+	}
+	form, err := parseChongzhiCallbackForm(data)
+	if err != nil {
+		return err
+	}
+	if form.Get("userid") != account.AccountName {
+		return fmt.Errorf("callback userid %s does not match account %s", form.Get("userid"), account.AccountName)
+	}
 
-	// 先尝试解析为GBK编码的表单数据
+	signStr := fmt.Sprintf("userid=%s&orderid=%s&sporderid=%s&merchantsubmittime=%s&resultno=%s&key=%s",
+		form.Get("userid"), form.Get("orderid"), form.Get("sporderid"),
+		form.Get("merchantsubmittime"), form.Get("resultno"), account.AppKey)
+	expectedSign := strings.ToUpper(signature.GetMD5(signStr))
+	if expectedSign != form.Get("sign") {
+		logger.Error("签名验证失败", "期望签名", expectedSign, "实际签名", form.Get("sign"))
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// parseChongzhiCallbackForm 解析回调表单，平台按GBK编码提交，解码失败时按原始数据解析
+func parseChongzhiCallbackForm(data []byte) (url.Values, error) {
 	utf8Reader := transform.NewReader(bytes.NewReader(data), simplifiedchinese.GBK.NewDecoder())
 	utf8Data, err := ioutil.ReadAll(utf8Reader)
 	if err != nil {
-		// 如果GBK解码失败，直接使用原始数据
 		logger.Info("GBK解码失败，使用原始数据", "错误", err)
 		utf8Data = data
-	} else {
-		logger.Info("GBK解码成功", "解码后数据", string(utf8Data))
 	}
 
-	// 解析表单参数
 	form, err := url.ParseQuery(string(utf8Data))
 	if err != nil {
 		logger.Error("解析表单参数失败", "错误", err, "数据", string(utf8Data))
 		return nil, fmt.Errorf("parse callback form data failed: %v", err)
 	}
+	return form, nil
+}
+
+// ParseCallbackData 解析回调数据
+func (p *ChongzhiPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
+	logger.Info("开始解析充值平台回调数据", "原始数据长度", len(data), "原始数据", string(data))
+
+	form, err := parseChongzhiCallbackForm(data)
+	if err != nil {
+		return nil, err
+	}
 
 	// 打印所有解析到的参数
 	logger.Info("解析到的表单参数:")
@@ -277,31 +324,6 @@ func (p *ChongzhiPlatform) ParseCallbackData(data []byte) (*model.CallbackData,
 		return nil, fmt.Errorf("missing required callback parameters")
 	}
 
-	// 签名验证
-	// 根据文档：sign=MD5(userid=xxxx&orderid=xxxxxxx&sporderid=xxxxx&merchantsubmittime=xxxxx&resultno=xxxxx&key=xxxxxxx).toUpperCase()
-	// 获取对应账号的key进行验证
-	logger.Info("开始签名验证", "userid", callbackReq.UserID)
-	account, err := p.platformRepo.GetPlatformAccountByAccountName(callbackReq.UserID)
-	if err != nil {
-		logger.Error("获取平台账号失败", "错误", err, "userid", callbackReq.UserID)
-		return nil, fmt.Errorf("get platform account failed: %v", err)
-	}
-	logger.Info("获取到平台账号", "account_name", account.AccountName, "app_secret长度", len(account.AppSecret))
-
-	signStr := fmt.Sprintf("userid=%s&orderid=%s&sporderid=%s&merchantsubmittime=%s&resultno=%s&key=%s",
-		callbackReq.UserID, callbackReq.OrderID, callbackReq.SpOrderID,
-		callbackReq.MerchantSubmitTime, callbackReq.ResultNo, account.AppKey)
-	logger.Info("构建签名字符串", "signStr", signStr)
-
-	expectedSign := strings.ToUpper(signature.GetMD5(signStr))
-	logger.Info("签名验证结果", "期望签名", expectedSign, "实际签名", callbackReq.Sign, "是否匹配", expectedSign == callbackReq.Sign)
-
-	if expectedSign != callbackReq.Sign {
-		logger.Error("签名验证失败", "期望签名", expectedSign, "实际签名", callbackReq.Sign, "签名字符串", signStr)
-		return nil, fmt.Errorf("invalid signature: expected %s, got %s", expectedSign, callbackReq.Sign)
-	}
-	logger.Info("签名验证成功")
-
 	// 映射订单状态（根据文档：1=成功，9=失败）
 	status := model.OrderStatusFailed
 	if callbackReq.ResultNo == "1" {
@@ -13,6 +13,7 @@ import (
 	"recharge-go/pkg/logger"
 	"recharge-go/pkg/signature"
 	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -29,6 +30,20 @@ func NewDayuanrenPlatform(db *gorm.DB) *DayuanrenPlatform {
 	}
 }
 
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code: "dayuanren",
+		Name: "大猿人",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			BatchQuery:       true,
+			QueryBalance:     false, // 大猿人平台暂不支持余额查询
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewDayuanrenPlatform(db) })
+}
+
 func (p *DayuanrenPlatform) GetName() string {
 	return "dayuanren"
 }
@@ -172,22 +187,55 @@ func (p *DayuanrenPlatform) SubmitOrder(ctx context.Context, order *model.Order,
 	return nil
 }
 
+// dayuanrenCheckMaxOrders 大猿人查询接口单次最多查询的订单数
+const dayuanrenCheckMaxOrders = 50
+
 // QueryOrderStatus 查询订单状态
-func (p *DayuanrenPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
-	_, appSecret, accountName, err := p.getAPIKeyAndSecret(order.PlatformAccountID)
+func (p *DayuanrenPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
+	results, err := p.check(ctx, order.PlatformAccountID, []string{order.OrderNumber})
+	if err != nil {
+		return nil, err
+	}
+	result, ok := results[order.OrderNumber]
+	if !ok {
+		return nil, errors.New("未查询到订单")
+	}
+	return result, nil
+}
+
+// MaxBatchSize 单次批量查询的最大订单数
+func (p *DayuanrenPlatform) MaxBatchSize() int {
+	return dayuanrenCheckMaxOrders
+}
+
+// BatchQueryOrderStatus 批量查询订单状态，订单号以逗号分隔提交到查询接口
+func (p *DayuanrenPlatform) BatchQueryOrderStatus(ctx context.Context, orders []*model.Order) (map[string]*QueryResult, error) {
+	if len(orders) == 0 {
+		return map[string]*QueryResult{}, nil
+	}
+	orderNumbers := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderNumbers = append(orderNumbers, order.OrderNumber)
+	}
+	return p.check(ctx, orders[0].PlatformAccountID, orderNumbers)
+}
+
+// check 调用查询接口，返回以我方订单号为键的结果
+func (p *DayuanrenPlatform) check(ctx context.Context, accountID int64, orderNumbers []string) (map[string]*QueryResult, error) {
+	_, appSecret, accountName, err := p.getAPIKeyAndSecret(accountID)
 	if err != nil {
-		return 0, fmt.Errorf("获取API密钥失败: %v", err)
+		return nil, fmt.Errorf("获取API密钥失败: %v", err)
 	}
 
 	// 获取平台API信息
-	api, err := p.platformRepo.GetPlatformByCode(context.Background(), "dayuanren")
+	api, err := p.platformRepo.GetPlatformByCode(ctx, "dayuanren")
 	if err != nil {
-		return 0, fmt.Errorf("获取平台API信息失败: %v", err)
+		return nil, fmt.Errorf("获取平台API信息失败: %v", err)
 	}
 
 	params := map[string]string{
 		"userid":         accountName,
-		"out_trade_nums": order.OrderNumber,
+		"out_trade_nums": strings.Join(orderNumbers, ","),
 	}
 	sign := signature.GenerateDayuanrenSign(params, appSecret)
 	params["sign"] = sign
@@ -197,48 +245,84 @@ func (p *DayuanrenPlatform) QueryOrderStatus(order *model.Order) (model.OrderSta
 		form.Add(k, v)
 	}
 
-	resp, err := http.PostForm(api.URL+"/index/check", form)
+	req, err := http.NewRequestWithContext(ctx, "POST", api.URL+"/index/check", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("请求失败: %v", err)
+		return nil, fmt.Errorf("请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
 	var respData struct {
 		Errno  int             `json:"errno"`
 		Errmsg string          `json:"errmsg"`
 		Data   json.RawMessage `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
-		return 0, fmt.Errorf("解析响应失败: %v", err)
+	if err := json.Unmarshal(body, &respData); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
 	if respData.Errno != 0 {
-		return 0, fmt.Errorf("API错误: %s", respData.Errmsg)
+		return nil, fmt.Errorf("API错误: %s", respData.Errmsg)
 	}
 
-	var orders []struct {
-		State int `json:"state"`
-	}
-	if err := json.Unmarshal(respData.Data, &orders); err != nil {
-		return 0, fmt.Errorf("解析订单状态失败: %v", err)
-	}
-	if len(orders) == 0 {
-		return 0, errors.New("未查询到订单")
+	var items []json.RawMessage
+	if err := json.Unmarshal(respData.Data, &items); err != nil {
+		return nil, fmt.Errorf("解析订单状态失败: %v", err)
 	}
 
-	switch orders[0].State {
-	case -1:
-		return model.OrderStatusFailed, nil
-	case 0:
-		return model.OrderStatusRecharging, nil
-	case 1:
-		return model.OrderStatusSuccess, nil
-	case 2:
-		return model.OrderStatusFailed, nil
-	case 3:
-		return model.OrderStatusRecharging, nil // 没有 PartialSuccess，暂用 Recharging
-	default:
-		return 0, nil // 没有 OrderStatusUnknown，返回 0
+	results := make(map[string]*QueryResult, len(items))
+	for _, item := range items {
+		var data struct {
+			OutTradeNum string  `json:"out_trade_num"`
+			State       int     `json:"state"`
+			OrderNumber string  `json:"order_number"`
+			Voucher     string  `json:"voucher"`
+			Charged     float64 `json:"charge_amount"`
+			Remark      string  `json:"remark"`
+		}
+		if err := json.Unmarshal(item, &data); err != nil {
+			return nil, fmt.Errorf("解析订单状态失败: %v", err)
+		}
+		// 单笔查询时上游可能不返回订单号
+		if data.OutTradeNum == "" && len(orderNumbers) == 1 {
+			data.OutTradeNum = orderNumbers[0]
+		}
+
+		result := &QueryResult{
+			SupplierOrderID: data.OrderNumber,
+			Voucher:         data.Voucher,
+			Message:         data.Remark,
+			Raw:             string(item),
+		}
+		switch data.State {
+		case -1, 2:
+			result.Status = model.OrderStatusFailed
+		case 0:
+			result.Status = model.OrderStatusRecharging
+		case 1:
+			result.Status = model.OrderStatusSuccess
+		case 3:
+			// 部分充值需要实际到账金额才能结算，未返回时继续等待
+			result.Status = model.OrderStatusRecharging
+			if data.Charged > 0 {
+				result.Status = model.OrderStatusPartial
+				result.CreditedAmount = data.Charged
+			}
+		default:
+			logger.Error("【大猿人订单状态】未知状态", "order_id", data.OutTradeNum, "state", data.State)
+			continue
+		}
+		results[data.OutTradeNum] = result
 	}
+	return results, nil
 }
 
 // dayuanren 平台订单状态映射
@@ -275,6 +359,27 @@ func (p *DayuanrenPlatform) mapOrderState(state int, orderID string) (int, strin
 	return status, statusStr
 }
 
+// VerifyCallback 回调验签：表单参数按大猿人规则用账号密钥签名，otime 为下单时间不做时效校验
+func (p *DayuanrenPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid callback form: %v", err)
+	}
+	params := make(map[string]string)
+	for k, v := range form {
+		if len(v) > 0 {
+			params[k] = v[0]
+		}
+	}
+	if !signature.VerifyDayuanrenSign(params, account.AppSecret) {
+		return errors.New("invalid sign")
+	}
+	return nil
+}
+
 // ParseCallbackData 解析回调数据
 func (p *DayuanrenPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
 	logger.Info("开始解析大猿人回调数据", "data", string(data))
@@ -32,6 +32,27 @@ func NewExternalAPIPlatform(db *gorm.DB) *ExternalAPIPlatform {
 	}
 }
 
+func init() {
+	capabilities := Capabilities{
+		SubmitOrder:      true,
+		QueryOrderStatus: true,
+		QueryBalance:     true,
+		Callback:         true,
+	}
+	RegisterAdapter(AdapterDescriptor{
+		Code:         "external_api",
+		Name:         "外部API",
+		Description:  "对接使用本系统外部API的上游",
+		Capabilities: capabilities,
+	}, func(db *gorm.DB) Platform { return NewExternalAPIPlatform(db) })
+	RegisterAdapter(AdapterDescriptor{
+		Code:         "internal_api",
+		Name:         "内部API",
+		Description:  "复用外部API平台实现",
+		Capabilities: capabilities,
+	}, func(db *gorm.DB) Platform { return NewExternalAPIPlatform(db) })
+}
+
 // GetName 获取平台名称
 func (p *ExternalAPIPlatform) GetName() string {
 	return "external_api"
@@ -116,11 +137,11 @@ func (p *ExternalAPIPlatform) SubmitOrder(ctx context.Context, order *model.Orde
 }
 
 // QueryOrderStatus 查询订单状态
-func (p *ExternalAPIPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
+func (p *ExternalAPIPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	// 获取API密钥和URL
 	appID, appKey, appSecret, apiURL, err := p.getAPIKeyAndSecret(order.PlatformAccountID)
 	if err != nil {
-		return model.OrderStatusFailed, fmt.Errorf("get api key and secret failed: %v", err)
+		return nil, fmt.Errorf("get api key and secret failed: %v", err)
 	}
 
 	// 构建查询参数
@@ -137,28 +158,58 @@ func (p *ExternalAPIPlatform) QueryOrderStatus(order *model.Order) (model.OrderS
 	params["sign"] = sign
 
 	// 发送查询请求
-	resp, err := p.sendQueryRequest(context.Background(), appKey, apiURL+"/external/order/query", params)
+	resp, err := p.sendQueryRequest(ctx, appKey, apiURL+"/external/order/query", params)
 	if err != nil {
-		return model.OrderStatusFailed, fmt.Errorf("query order status failed: %v", err)
+		return nil, fmt.Errorf("query order status failed: %v", err)
 	}
 
 	// 检查响应
 	if resp.Code != 200 {
-		return model.OrderStatusFailed, fmt.Errorf("query order status failed: %s", resp.Message)
+		return nil, fmt.Errorf("query order status failed: %s", resp.Message)
 	}
 
 	// 解析订单状态
-	if resp.Data != nil {
-		if data, ok := resp.Data.(map[string]interface{}); ok {
-			if status, exists := data["status"]; exists {
-				if statusInt, ok := status.(float64); ok {
-					return p.mapOrderStatus(int(statusInt)), nil
-				}
-			}
-		}
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response data")
+	}
+	status, ok := data["status"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid response data")
 	}
 
-	return model.OrderStatusFailed, fmt.Errorf("invalid response data")
+	raw, _ := json.Marshal(resp)
+	result := &QueryResult{
+		Status:  p.mapOrderStatus(int(status)),
+		Message: resp.Message,
+		Raw:     string(raw),
+	}
+	if orderNumber, ok := data["order_number"].(string); ok {
+		result.SupplierOrderID = orderNumber
+	}
+	if credited, ok := data["credited_amount"].(float64); ok {
+		result.CreditedAmount = credited
+	}
+	return result, nil
+}
+
+// VerifyCallback 回调验签：按外部API签名规则用账号密钥签名，timestamp 为秒级回调时间
+func (p *ExternalAPIPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return fmt.Errorf("invalid callback body: %v", err)
+	}
+	sign, _ := params["sign"].(string)
+	if sign == "" {
+		return fmt.Errorf("missing sign")
+	}
+	validator := signature.NewExternalAPISignatureValidator()
+	validator.TimeWindow = int64(DefaultCallbackTolerance / time.Second)
+	validator.Now = CallbackReceivedAt(ctx)
+	return validator.ValidateExternalAPISignature(params, sign, account.AppSecret)
 }
 
 // ParseCallbackData 解析回调数据
@@ -0,0 +1,834 @@
+package recharge
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/signature"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// GenericHTTPAdapterCode 通用HTTP适配器代码
+// 平台接口的 Code 为该值，或 ExtraParams 中包含 generic_http 模板时，使用通用HTTP适配器
+const GenericHTTPAdapterCode = "generic_http"
+
+// genericHTTPTemplateKey 模板在 PlatformAPI.ExtraParams 中的键
+const genericHTTPTemplateKey = "generic_http"
+
+// 请求体格式
+const (
+	GenericContentTypeForm = "form"
+	GenericContentTypeJSON = "json"
+)
+
+// 签名算法，均复用 pkg/signature 中的实现
+const (
+	GenericSignKekebang      = "kekebang"       // 参数升序 k=v&...&secret=密钥，小写MD5
+	GenericSignKekebangUpper = "kekebang_upper" // 参数升序 k=v&...&key=密钥，大写MD5
+	GenericSignDayuanren     = "dayuanren"      // 参数升序 k=v&...&apikey=密钥，大写MD5
+	GenericSignSortedConcat  = "sorted_concat"  // 参数升序 kv 直接拼接 + 密钥，小写MD5
+	GenericSignMD5Template   = "md5_template"   // 按 template 渲染签名原文后小写MD5
+)
+
+// GenericHTTPTemplate 通用HTTP适配器模板，存放在 PlatformAPI.ExtraParams.generic_http 中
+//
+// 字段值支持 {{来源.字段}} 占位符，可用来源：
+//
+//	order:   id, order_number, out_trade_num, mobile, denom, price, total_price, isp, account_location, param1, param2, param3
+//	param:   id, product_id, name, par_value, price, cost
+//	api:     id, code, url, callback_url, app_id, app_key, app_secret, merchant_id, secret_key
+//	account: id, account_name, app_key, app_secret
+//	now:     unix, unix_milli, datetime(yyyyMMddHHmmss)
+//	req:     已渲染的请求字段（仅 md5_template 签名可用）
+type GenericHTTPTemplate struct {
+	Submit        GenericRequestTemplate   `json:"submit"`         // 下单请求
+	Query         *GenericRequestTemplate  `json:"query"`          // 查询请求，为空表示不支持主动查询
+	Balance       *GenericRequestTemplate  `json:"balance"`        // 余额查询请求，为空表示不支持
+	Callback      *GenericCallbackTemplate `json:"callback"`       // 回调解析，为空表示不支持回调
+	StatusMapping map[string]int           `json:"status_mapping"` // 平台状态值 -> model.OrderStatus
+}
+
+// GenericRequestTemplate 请求模板
+type GenericRequestTemplate struct {
+	Path         string                  `json:"path"`           // 接口路径，拼接在 PlatformAPI.URL 之后；以 http 开头时作为完整地址
+	Method       string                  `json:"method"`         // 请求方法，默认 POST
+	ContentType  string                  `json:"content_type"`   // form 或 json，默认 form
+	Headers      map[string]string       `json:"headers"`        // 额外请求头，支持占位符
+	Fields       map[string]string       `json:"fields"`         // 请求字段 -> 取值表达式
+	Sign         *GenericSignRecipe      `json:"sign"`           // 签名方式
+	Success      GenericSuccessPredicate `json:"success"`        // 成功判断
+	MessageField string                  `json:"message_field"`  // 错误信息字段路径
+	StatusField  string                  `json:"status_field"`   // 订单状态字段路径（查询用）
+	AmountField  string                  `json:"amount_field"`   // 实际到账金额字段路径（查询用，部分充值时必填）
+	OrderIDField string                  `json:"order_id_field"` // 平台订单号字段路径（查询用）
+	VoucherField string                  `json:"voucher_field"`  // 凭证/流水号字段路径（查询用）
+	ValueField   string                  `json:"value_field"`    // 数值字段路径（余额查询用）
+}
+
+// GenericSignRecipe 签名配置
+type GenericSignRecipe struct {
+	Algorithm string   `json:"algorithm"` // 签名算法，见 GenericSign* 常量
+	Field     string   `json:"field"`     // 签名字段名，默认 sign
+	Secret    string   `json:"secret"`    // 密钥表达式，默认 {{account.app_secret}}
+	Template  string   `json:"template"`  // md5_template 使用的签名原文模板
+	Exclude   []string `json:"exclude"`   // 不参与签名的字段
+	Upper     bool     `json:"upper"`     // 结果转大写（sorted_concat / md5_template 有效）
+}
+
+// GenericSuccessPredicate 响应成功判断，字段值命中 Values 中任一值即视为成功
+type GenericSuccessPredicate struct {
+	Field  string   `json:"field"`  // 字段路径，如 code 或 data.state，为空时仅判断HTTP状态码
+	Values []string `json:"values"` // 成功值
+}
+
+// GenericCallbackTemplate 回调解析模板
+type GenericCallbackTemplate struct {
+	ContentType        string             `json:"content_type"`         // form 或 json，默认 form
+	OrderNumberField   string             `json:"order_number_field"`   // 我方订单号字段
+	OrderIDField       string             `json:"order_id_field"`       // 平台订单号字段，默认同 order_number_field
+	StatusField        string             `json:"status_field"`         // 状态字段，结合 status_mapping 转换
+	AmountField        string             `json:"amount_field"`         // 金额字段
+	MessageField       string             `json:"message_field"`        // 描述字段
+	TimestampField     string             `json:"timestamp_field"`      // 时间字段
+	TimestampTolerance int                `json:"timestamp_tolerance"`  // 回调时间与当前时间允许的偏差（秒），0 表示不校验
+	TransactionIDField string             `json:"transaction_id_field"` // 凭证/流水号字段
+	Sign               *GenericSignRecipe `json:"sign"`                 // 回调验签方式，为空时不验签
+	Ack                string             `json:"ack"`                  // 处理成功后返回给平台的内容，默认 success
+}
+
+// ParseGenericHTTPTemplate 从 ExtraParams 中解析通用HTTP模板，未配置时返回 nil
+func ParseGenericHTTPTemplate(extraParams datatypes.JSON) (*GenericHTTPTemplate, error) {
+	if len(extraParams) == 0 {
+		return nil, nil
+	}
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(extraParams, &wrapper); err != nil {
+		return nil, fmt.Errorf("解析 extra_params 失败: %v", err)
+	}
+	raw, exists := wrapper[genericHTTPTemplateKey]
+	if !exists || len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var tpl GenericHTTPTemplate
+	if err := json.Unmarshal(raw, &tpl); err != nil {
+		return nil, fmt.Errorf("解析 generic_http 模板失败: %v", err)
+	}
+	if err := tpl.Validate(); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// HasGenericHTTPTemplate 判断 ExtraParams 中是否配置了可用的通用HTTP模板
+func HasGenericHTTPTemplate(extraParams datatypes.JSON) bool {
+	tpl, err := ParseGenericHTTPTemplate(extraParams)
+	return err == nil && tpl != nil
+}
+
+// Validate 校验模板
+func (t *GenericHTTPTemplate) Validate() error {
+	if len(t.Submit.Fields) == 0 {
+		return errors.New("generic_http 模板缺少 submit.fields")
+	}
+	requests := []*GenericRequestTemplate{&t.Submit, t.Query, t.Balance}
+	for _, req := range requests {
+		if req == nil {
+			continue
+		}
+		if err := validateContentType(req.ContentType); err != nil {
+			return err
+		}
+		if err := req.Sign.validate(); err != nil {
+			return err
+		}
+	}
+	if t.Query != nil && t.Query.StatusField == "" {
+		return errors.New("generic_http 模板 query.status_field 不能为空")
+	}
+	if t.Balance != nil && t.Balance.ValueField == "" {
+		return errors.New("generic_http 模板 balance.value_field 不能为空")
+	}
+	if t.Callback != nil {
+		if err := validateContentType(t.Callback.ContentType); err != nil {
+			return err
+		}
+		if t.Callback.OrderNumberField == "" || t.Callback.StatusField == "" {
+			return errors.New("generic_http 模板 callback 需配置 order_number_field 和 status_field")
+		}
+		if err := t.Callback.Sign.validate(); err != nil {
+			return err
+		}
+	}
+	for value, status := range t.StatusMapping {
+		if status < int(model.OrderStatusPendingPayment) || status > int(model.OrderStatusProcessing) {
+			return fmt.Errorf("generic_http 模板 status_mapping[%s] 状态值非法: %d", value, status)
+		}
+	}
+	return nil
+}
+
+// MapStatus 根据状态映射表转换平台状态
+func (t *GenericHTTPTemplate) MapStatus(value string) (model.OrderStatus, bool) {
+	status, exists := t.StatusMapping[value]
+	if !exists {
+		return 0, false
+	}
+	return model.OrderStatus(status), true
+}
+
+func validateContentType(contentType string) error {
+	switch contentType {
+	case "", GenericContentTypeForm, GenericContentTypeJSON:
+		return nil
+	default:
+		return fmt.Errorf("generic_http 模板 content_type 非法: %s", contentType)
+	}
+}
+
+func (r *GenericSignRecipe) validate() error {
+	if r == nil {
+		return nil
+	}
+	switch r.Algorithm {
+	case GenericSignKekebang, GenericSignKekebangUpper, GenericSignDayuanren, GenericSignSortedConcat:
+		return nil
+	case GenericSignMD5Template:
+		if r.Template == "" {
+			return errors.New("generic_http 模板 md5_template 签名缺少 template")
+		}
+		return nil
+	default:
+		return fmt.Errorf("generic_http 模板签名算法不支持: %s", r.Algorithm)
+	}
+}
+
+func (r *GenericSignRecipe) field() string {
+	if r.Field == "" {
+		return "sign"
+	}
+	return r.Field
+}
+
+func (r *GenericSignRecipe) secretExpr() string {
+	if r.Secret == "" {
+		return "{{account.app_secret}}"
+	}
+	return r.Secret
+}
+
+// sign 计算签名，params 为参与签名的字段（不包含签名字段本身）
+func (r *GenericSignRecipe) sign(params map[string]string, vars genericVars) string {
+	signParams := make(map[string]string, len(params))
+	for k, v := range params {
+		signParams[k] = v
+	}
+	delete(signParams, r.field())
+	for _, k := range r.Exclude {
+		delete(signParams, k)
+	}
+	secret := vars.render(r.secretExpr())
+
+	switch r.Algorithm {
+	case GenericSignKekebang:
+		ifaceParams := make(map[string]interface{}, len(signParams))
+		for k, v := range signParams {
+			ifaceParams[k] = v
+		}
+		return signature.GenerateKekebangSign(ifaceParams, secret)
+	case GenericSignKekebangUpper:
+		return signature.GenerateKekebangSignature(signParams, secret)
+	case GenericSignDayuanren:
+		return signature.GenerateDayuanrenSign(signParams, secret)
+	case GenericSignSortedConcat:
+		ifaceParams := make(map[string]interface{}, len(signParams))
+		for k, v := range signParams {
+			ifaceParams[k] = v
+		}
+		return r.applyCase(signature.GenerateSign(ifaceParams, secret))
+	case GenericSignMD5Template:
+		signVars := vars.with("req", signParams)
+		signVars["secret"] = map[string]string{"value": secret}
+		return r.applyCase(signature.GetMD5(signVars.render(r.Template)))
+	}
+	return ""
+}
+
+func (r *GenericSignRecipe) applyCase(sign string) string {
+	if r.Upper {
+		return strings.ToUpper(sign)
+	}
+	return sign
+}
+
+// verify 校验签名，大小写不敏感，统一转小写后按常量时间比较
+func (r *GenericSignRecipe) verify(params map[string]string, vars genericVars) bool {
+	sign := params[r.field()]
+	if sign == "" {
+		return false
+	}
+	expected := r.sign(params, vars)
+	return hmac.Equal([]byte(strings.ToLower(sign)), []byte(strings.ToLower(expected)))
+}
+
+// genericVars 占位符取值表，来源 -> 字段 -> 值
+type genericVars map[string]map[string]string
+
+var genericPlaceholder = regexp.MustCompile(`\{\{\s*([a-z_]+)\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// render 渲染表达式中的占位符，未知占位符渲染为空字符串
+func (v genericVars) render(expr string) string {
+	return genericPlaceholder.ReplaceAllStringFunc(expr, func(match string) string {
+		parts := genericPlaceholder.FindStringSubmatch(match)
+		if source, exists := v[parts[1]]; exists {
+			return source[parts[2]]
+		}
+		return ""
+	})
+}
+
+// with 返回追加了一个来源的新取值表
+func (v genericVars) with(source string, values map[string]string) genericVars {
+	merged := make(genericVars, len(v)+1)
+	for k, val := range v {
+		merged[k] = val
+	}
+	merged[source] = values
+	return merged
+}
+
+func formatGenericFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// newGenericVars 构建占位符取值表
+func newGenericVars(order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam, account *model.PlatformAccount) genericVars {
+	now := time.Now()
+	vars := genericVars{
+		"now": {
+			"unix":       strconv.FormatInt(now.Unix(), 10),
+			"unix_milli": strconv.FormatInt(now.UnixMilli(), 10),
+			"datetime":   now.Format("20060102150405"),
+		},
+	}
+	if order != nil {
+		vars["order"] = map[string]string{
+			"id":               strconv.FormatInt(order.ID, 10),
+			"order_number":     order.OrderNumber,
+			"out_trade_num":    order.OutTradeNum,
+			"mobile":           order.Mobile,
+			"denom":            formatGenericFloat(order.Denom.Float64()),
+			"price":            formatGenericFloat(order.Price.Float64()),
+			"total_price":      formatGenericFloat(order.TotalPrice.Float64()),
+			"isp":              strconv.Itoa(order.ISP),
+			"account_location": order.AccountLocation,
+			"param1":           order.Param1,
+			"param2":           order.Param2,
+			"param3":           order.Param3,
+		}
+	}
+	if apiParam != nil {
+		vars["param"] = map[string]string{
+			"id":         strconv.FormatInt(apiParam.ID, 10),
+			"product_id": apiParam.ProductID,
+			"name":       apiParam.Name,
+			"par_value":  formatGenericFloat(apiParam.ParValue),
+			"price":      formatGenericFloat(apiParam.Price.Float64()),
+			"cost":       formatGenericFloat(apiParam.Cost.Float64()),
+		}
+	}
+	if api != nil {
+		vars["api"] = map[string]string{
+			"id":           strconv.FormatInt(api.ID, 10),
+			"code":         api.Code,
+			"url":          api.URL,
+			"callback_url": api.CallbackURL,
+			"app_id":       api.AppID,
+			"app_key":      api.AppKey,
+			"app_secret":   api.AppSecret,
+			"merchant_id":  api.MerchantID,
+			"secret_key":   api.SecretKey,
+		}
+	}
+	if account != nil {
+		vars["account"] = map[string]string{
+			"id":           strconv.FormatInt(account.ID, 10),
+			"account_name": account.AccountName,
+			"app_key":      account.AppKey,
+			"app_secret":   account.AppSecret,
+		}
+	}
+	return vars
+}
+
+// lookupGenericField 按点分路径从解析后的响应中取值
+func lookupGenericField(data interface{}, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	current := data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, exists := node[key]
+			if !exists {
+				return "", false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", false
+			}
+			current = node[index]
+		default:
+			return "", false
+		}
+	}
+	switch value := current.(type) {
+	case nil:
+		return "", true
+	case string:
+		return value, true
+	case float64:
+		return formatGenericFloat(value), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case json.Number:
+		return value.String(), true
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded), true
+	}
+}
+
+// GenericHTTPPlatform 模板驱动的通用HTTP平台适配器
+// 对接新平台时只需在 PlatformAPI.ExtraParams 中配置 generic_http 模板，无需新增代码
+type GenericHTTPPlatform struct {
+	code            string
+	apiID           int64 // 绑定的平台接口ID，为0时按 code 查找接口
+	platformRepo    repository.PlatformRepository
+	platformAPIRepo repository.PlatformAPIRepository
+}
+
+// NewGenericHTTPPlatform 创建通用HTTP平台适配器，code 为其服务的 PlatformAPI.Code
+func NewGenericHTTPPlatform(db *gorm.DB, code string) *GenericHTTPPlatform {
+	return &GenericHTTPPlatform{
+		code:            code,
+		platformRepo:    repository.NewPlatformRepository(db),
+		platformAPIRepo: repository.NewPlatformAPIRepository(db),
+	}
+}
+
+// NewGenericHTTPPlatformForAPI 创建绑定单个平台接口的通用HTTP平台适配器，模板始终取该接口的配置
+func NewGenericHTTPPlatformForAPI(db *gorm.DB, api *model.PlatformAPI) *GenericHTTPPlatform {
+	p := NewGenericHTTPPlatform(db, api.Code)
+	p.apiID = api.ID
+	return p
+}
+
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code:        GenericHTTPAdapterCode,
+		Name:        "通用HTTP",
+		Description: "根据 extra_params.generic_http 模板对接平台，任意接口代码配置模板后自动使用",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewGenericHTTPPlatform(db, GenericHTTPAdapterCode) })
+}
+
+func (p *GenericHTTPPlatform) GetName() string {
+	return p.code
+}
+
+// CallbackAck 回调应答，取模板配置
+func (p *GenericHTTPPlatform) CallbackAck(ctx context.Context) string {
+	_, tpl, err := p.loadAPI(ctx)
+	if err != nil {
+		return "success"
+	}
+	return tpl.CallbackAck()
+}
+
+// loadAPI 加载平台接口及模板
+func (p *GenericHTTPPlatform) loadAPI(ctx context.Context) (*model.PlatformAPI, *GenericHTTPTemplate, error) {
+	var api *model.PlatformAPI
+	var err error
+	if p.apiID != 0 {
+		api, err = p.platformAPIRepo.GetByID(ctx, p.apiID)
+	} else {
+		api, err = p.platformAPIRepo.GetByCode(ctx, p.code)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取平台接口失败: %v", err)
+	}
+	tpl, err := p.template(api)
+	if err != nil {
+		return nil, nil, err
+	}
+	return api, tpl, nil
+}
+
+func (p *GenericHTTPPlatform) template(api *model.PlatformAPI) (*GenericHTTPTemplate, error) {
+	tpl, err := ParseGenericHTTPTemplate(api.ExtraParams)
+	if err != nil {
+		return nil, err
+	}
+	if tpl == nil {
+		return nil, fmt.Errorf("平台接口 %s 未配置 generic_http 模板", api.Code)
+	}
+	return tpl, nil
+}
+
+// getAccount 获取平台账号，accountID 为0时返回 nil
+func (p *GenericHTTPPlatform) getAccount(accountID int64) (*model.PlatformAccount, error) {
+	if accountID == 0 {
+		return nil, nil
+	}
+	account, err := p.platformRepo.GetPlatformAccountByID(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("获取平台账号信息失败: %v", err)
+	}
+	return account, nil
+}
+
+// SubmitOrder 提交订单
+func (p *GenericHTTPPlatform) SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error {
+	logger.Info("开始提交通用HTTP订单", "code", p.code, "order_id", order.ID, "order_number", order.OrderNumber)
+
+	tpl, err := p.template(api)
+	if err != nil {
+		return err
+	}
+	account, err := p.getAccount(api.AccountID)
+	if err != nil {
+		return err
+	}
+
+	vars := newGenericVars(order, api, apiParam, account)
+	if _, _, err := p.do(ctx, api, &tpl.Submit, vars); err != nil {
+		logger.Error("提交通用HTTP订单失败", "code", p.code, "order_id", order.ID, "order_number", order.OrderNumber, "error", err)
+		return err
+	}
+
+	logger.Info("提交通用HTTP订单成功", "code", p.code, "order_id", order.ID, "order_number", order.OrderNumber)
+	return nil
+}
+
+// QueryOrderStatus 查询订单状态
+func (p *GenericHTTPPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
+	api, tpl, err := p.loadAPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tpl.Query == nil {
+		return nil, fmt.Errorf("平台接口 %s 未配置查询模板", p.code)
+	}
+
+	accountID := order.PlatformAccountID
+	if accountID == 0 {
+		accountID = api.AccountID
+	}
+	account, err := p.getAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, result, err := p.do(ctx, api, tpl.Query, newGenericVars(order, api, nil, account))
+	if err != nil {
+		return nil, err
+	}
+	value, _ := lookupGenericField(result, tpl.Query.StatusField)
+	status, exists := tpl.MapStatus(value)
+	if !exists {
+		return nil, fmt.Errorf("未知的平台订单状态: %s", value)
+	}
+
+	queryResult := &QueryResult{Status: status, Raw: string(body)}
+	if tpl.Query.AmountField != "" {
+		if amount, ok := lookupGenericField(result, tpl.Query.AmountField); ok {
+			if credited, err := strconv.ParseFloat(amount, 64); err == nil {
+				queryResult.CreditedAmount = credited
+			}
+		}
+	}
+	if tpl.Query.MessageField != "" {
+		queryResult.Message, _ = lookupGenericField(result, tpl.Query.MessageField)
+	}
+	if tpl.Query.OrderIDField != "" {
+		queryResult.SupplierOrderID, _ = lookupGenericField(result, tpl.Query.OrderIDField)
+	}
+	if tpl.Query.VoucherField != "" {
+		queryResult.Voucher, _ = lookupGenericField(result, tpl.Query.VoucherField)
+	}
+	return queryResult, nil
+}
+
+// VerifyCallback 按模板的回调验签方式用平台接口绑定账号的密钥验签，并按模板校验回调时间
+// 通用模板平台的回调地址不带账号，忽略传入的 account
+func (p *GenericHTTPPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	api, tpl, err := p.loadAPI(ctx)
+	if err != nil {
+		return err
+	}
+	if tpl.Callback == nil {
+		return fmt.Errorf("平台接口 %s 未配置回调模板", p.code)
+	}
+	cb := tpl.Callback
+
+	params, err := parseGenericCallbackParams(cb.ContentType, data)
+	if err != nil {
+		return err
+	}
+	if cb.Sign != nil {
+		signAccount, err := p.getAccount(api.AccountID)
+		if err != nil {
+			return err
+		}
+		if !cb.Sign.verify(params, newGenericVars(nil, api, nil, signAccount)) {
+			return errors.New("回调签名校验失败")
+		}
+	}
+	if cb.TimestampField != "" {
+		tolerance := time.Duration(cb.TimestampTolerance) * time.Second
+		return CheckCallbackTimestamp(params[cb.TimestampField], CallbackReceivedAt(ctx), tolerance)
+	}
+	return nil
+}
+
+// ParseCallbackData 解析回调数据
+func (p *GenericHTTPPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
+	_, tpl, err := p.loadAPI(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if tpl.Callback == nil {
+		return nil, fmt.Errorf("平台接口 %s 未配置回调模板", p.code)
+	}
+	cb := tpl.Callback
+
+	params, err := parseGenericCallbackParams(cb.ContentType, data)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("通用HTTP回调参数", "code", p.code, "params", params)
+
+	status, exists := tpl.MapStatus(params[cb.StatusField])
+	if !exists {
+		return nil, fmt.Errorf("未知的回调订单状态: %s", params[cb.StatusField])
+	}
+
+	orderNumber := params[cb.OrderNumberField]
+	orderID := orderNumber
+	if cb.OrderIDField != "" {
+		orderID = params[cb.OrderIDField]
+	}
+	callbackData := &model.CallbackData{
+		OrderID:       orderID,
+		OrderNumber:   orderNumber,
+		Status:        strconv.Itoa(int(status)),
+		CallbackType:  "order_status",
+		Message:       params[cb.MessageField],
+		Amount:        params[cb.AmountField],
+		Timestamp:     params[cb.TimestampField],
+		TransactionID: params[cb.TransactionIDField],
+	}
+	if cb.Sign != nil {
+		callbackData.Sign = params[cb.Sign.field()]
+	}
+	return callbackData, nil
+}
+
+// CallbackAck 处理成功后返回给平台的内容
+func (t *GenericHTTPTemplate) CallbackAck() string {
+	if t.Callback == nil || t.Callback.Ack == "" {
+		return "success"
+	}
+	return t.Callback.Ack
+}
+
+// QueryBalance 查询账户余额
+func (p *GenericHTTPPlatform) QueryBalance(ctx context.Context, accountID int64) (float64, error) {
+	api, tpl, err := p.loadAPI(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if tpl.Balance == nil {
+		return 0, fmt.Errorf("平台接口 %s 未配置余额查询模板", p.code)
+	}
+	account, err := p.getAccount(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, result, err := p.do(ctx, api, tpl.Balance, newGenericVars(nil, api, nil, account))
+	if err != nil {
+		return 0, err
+	}
+	value, _ := lookupGenericField(result, tpl.Balance.ValueField)
+	balance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析余额失败: %v", err)
+	}
+	return balance, nil
+}
+
+// parseGenericCallbackParams 将回调内容解析为扁平参数表
+func parseGenericCallbackParams(contentType string, data []byte) (map[string]string, error) {
+	params := make(map[string]string)
+	if contentType == GenericContentTypeJSON {
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("回调参数解析失败: %v", err)
+		}
+		for k := range body {
+			params[k], _ = lookupGenericField(body, k)
+		}
+		return params, nil
+	}
+
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("回调参数解析失败: %v", err)
+	}
+	for k, v := range form {
+		if len(v) > 0 {
+			params[k] = v[0]
+		}
+	}
+	return params, nil
+}
+
+// buildRequest 根据模板渲染请求
+func (p *GenericHTTPPlatform) buildRequest(ctx context.Context, api *model.PlatformAPI, reqTpl *GenericRequestTemplate, vars genericVars) (*http.Request, error) {
+	params := make(map[string]string, len(reqTpl.Fields)+1)
+	for name, expr := range reqTpl.Fields {
+		params[name] = vars.render(expr)
+	}
+	if reqTpl.Sign != nil {
+		params[reqTpl.Sign.field()] = reqTpl.Sign.sign(params, vars)
+	}
+
+	target := vars.render(reqTpl.Path)
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = strings.TrimRight(api.URL, "/") + "/" + strings.TrimLeft(target, "/")
+	}
+	method := strings.ToUpper(reqTpl.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	var contentType string
+	switch {
+	case method == http.MethodGet:
+		form := url.Values{}
+		for k, v := range params {
+			form.Set(k, v)
+		}
+		if strings.Contains(target, "?") {
+			target += "&" + form.Encode()
+		} else {
+			target += "?" + form.Encode()
+		}
+	case reqTpl.ContentType == GenericContentTypeJSON:
+		jsonData, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("marshal params failed: %v", err)
+		}
+		body = bytes.NewReader(jsonData)
+		contentType = "application/json"
+	default:
+		form := url.Values{}
+		for k, v := range params {
+			form.Set(k, v)
+		}
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range reqTpl.Headers {
+		req.Header.Set(k, vars.render(v))
+	}
+	return req, nil
+}
+
+// do 发送请求并按成功条件判断响应，返回原始响应和解析后的JSON
+func (p *GenericHTTPPlatform) do(ctx context.Context, api *model.PlatformAPI, reqTpl *GenericRequestTemplate, vars genericVars) ([]byte, interface{}, error) {
+	req, err := p.buildRequest(ctx, api, reqTpl, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeout := time.Duration(api.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	logger.Info("通用HTTP请求", "code", p.code, "method", req.Method, "url", req.URL.String())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	logger.Info("通用HTTP响应原文", "code", p.code, "http_status", resp.StatusCode, "body", string(body))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, nil, fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		if reqTpl.Success.Field == "" && reqTpl.StatusField == "" && reqTpl.ValueField == "" {
+			return body, nil, nil
+		}
+		return body, nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if reqTpl.Success.Field != "" {
+		value, _ := lookupGenericField(result, reqTpl.Success.Field)
+		matched := false
+		for _, expected := range reqTpl.Success.Values {
+			if value == expected {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			message, _ := lookupGenericField(result, reqTpl.MessageField)
+			return body, result, fmt.Errorf("API错误: %s=%s %s", reqTpl.Success.Field, value, message)
+		}
+	}
+	return body, result, nil
+}
@@ -30,11 +30,42 @@ func NewKekebangPlatform(db *gorm.DB) *KekebangPlatform {
 	}
 }
 
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code: "kekebang",
+		Name: "可客帮",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewKekebangPlatform(db) })
+
+	// 蜜蜂平台暂时使用可客帮平台的实现
+	RegisterAdapter(AdapterDescriptor{
+		Code:        "mifeng",
+		Name:        "蜜蜂",
+		Description: "复用可客帮平台实现",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewKekebangPlatform(db) })
+}
+
 // GetName 获取平台名称
 func (p *KekebangPlatform) GetName() string {
 	return "kekebang"
 }
 
+// CallbackAck 回调应答
+func (p *KekebangPlatform) CallbackAck(ctx context.Context) string {
+	return `{"code":200,"message":"success"}`
+}
+
 // getAPIKeyAndSecret 获取API密钥和密钥
 func (p *KekebangPlatform) getAPIKeyAndSecret(accountID int64) (string, string, error) {
 	account, err := p.platformRepo.GetAccountByID(context.Background(), accountID)
@@ -129,7 +160,7 @@ func (p *KekebangPlatform) mapOrderState(orderState int, orderID int64, orderNum
 }
 
 // QueryOrderStatus 查询订单状态
-func (p *KekebangPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
+func (p *KekebangPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	logger.Info("【开始查询可客帮订单状态】order_id: %d, order_number: %s", order.ID, order.OrderNumber)
 
 	// 构建请求参数
@@ -145,11 +176,11 @@ func (p *KekebangPlatform) QueryOrderStatus(order *model.Order) (model.OrderStat
 	params["sign"] = sign
 
 	// 发送请求
-	resp, err := p.sendRequest(context.Background(), order.PlatformURL+"/query-order", params)
+	resp, err := p.sendRequest(ctx, order.PlatformURL+"/query-order", params)
 	if err != nil {
 		logger.Error("【查询订单状态失败】order_id: %d, order_number: %s, error: %v",
 			order.ID, order.OrderNumber, err)
-		return 0, fmt.Errorf("query order status failed: %v", err)
+		return nil, fmt.Errorf("query order status failed: %v", err)
 	}
 
 	// 确保 Code 是字符串类型
@@ -157,20 +188,46 @@ func (p *KekebangPlatform) QueryOrderStatus(order *model.Order) (model.OrderStat
 	if code != "00000" {
 		logger.Error("【查询订单状态失败】order_id: %d, order_number: %s, code: %s, message: %s",
 			order.ID, order.OrderNumber, code, resp.Message)
-		return 0, fmt.Errorf("query order status failed: %s", resp.Message)
+		return nil, fmt.Errorf("query order status failed: %s", resp.Message)
 	}
 
 	// 转换状态
 	status, err := strconv.Atoi(resp.Status)
 	if err != nil {
-		return 0, fmt.Errorf("invalid status: %s", resp.Status)
+		return nil, fmt.Errorf("invalid status: %s", resp.Status)
 	}
 
 	status, _ = p.mapOrderState(status, order.ID, order.OrderNumber)
 
 	logger.Info("【查询订单状态完成】order_id: %d, order_number: %s, status: %d",
 		order.ID, order.OrderNumber, status)
-	return model.OrderStatus(status), nil
+
+	raw, _ := json.Marshal(resp)
+	return &QueryResult{
+		Status:          model.OrderStatus(status),
+		SupplierOrderID: resp.OrderID,
+		Message:         resp.Message,
+		Raw:             string(raw),
+	}, nil
+}
+
+// VerifyCallback 回调验签：JSON 请求体中的 sign 为按账号密钥生成的签名，time 为回调时间
+func (p *KekebangPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return fmt.Errorf("invalid callback body: %v", err)
+	}
+	sign, ok := params["sign"].(string)
+	if !ok || sign == "" {
+		return fmt.Errorf("missing sign")
+	}
+	if !signature.VerifyKekebangSign(params, sign, account.AppSecret) {
+		return fmt.Errorf("invalid sign")
+	}
+	return CheckCallbackTimestamp(callbackParamString(params["time"]), CallbackReceivedAt(ctx), DefaultCallbackTolerance)
 }
 
 // ParseCallbackData 解析回调数据
@@ -6,35 +6,57 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/pkg/logger"
-	"reflect"
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// defaultQueryTimeout 平台接口未配置超时时间时的查询超时
+const defaultQueryTimeout = 30 * time.Second
+
 // Manager 平台管理器
+// 平台实例由适配器注册表（见 registry.go）构建：下单、查询按平台接口（PlatformAPI）逐条缓存，
+// 共用同一适配器代码的多个接口各有独立实例；回调等只知道平台代码的场景按代码缓存
 type Manager struct {
+	db              *gorm.DB
 	platformRepo    repository.PlatformRepository
 	platformAPIRepo repository.PlatformAPIRepository
 	platforms       map[string]Platform
-	platformTypes   map[string]reflect.Type
+	apiPlatforms    map[int64]Platform
+	breaker         *CircuitBreaker
 	mu              sync.RWMutex
 }
 
 // NewManager 创建平台管理器
 func NewManager(db *gorm.DB) *Manager {
 	return &Manager{
+		db:              db,
 		platformRepo:    repository.NewPlatformRepository(db),
 		platformAPIRepo: repository.NewPlatformAPIRepository(db),
 		platforms:       make(map[string]Platform),
-		platformTypes: map[string]reflect.Type{
-			"kekebang":     reflect.TypeOf((*KekebangPlatform)(nil)).Elem(),
-			"xianzhuanxia": reflect.TypeOf((*XianzhuanxiaPlatform)(nil)).Elem(),
-			"mishi":        reflect.TypeOf((*MishiPlatform)(nil)).Elem(),
-			"external_api": reflect.TypeOf((*ExternalAPIPlatform)(nil)).Elem(),
-			"internal_api": reflect.TypeOf((*ExternalAPIPlatform)(nil)).Elem(),
-			"chongzhi":     reflect.TypeOf((*ChongzhiPlatform)(nil)).Elem(),
-		},
+		apiPlatforms:    make(map[int64]Platform),
+		breaker:         DefaultCircuitBreaker(),
+	}
+}
+
+// Breaker 获取平台接口熔断器
+func (m *Manager) Breaker() *CircuitBreaker {
+	return m.breaker
+}
+
+// IsAPIAvailable 判断平台接口是否可被路由选中（未熔断）
+func (m *Manager) IsAPIAvailable(ctx context.Context, apiID int64) bool {
+	return m.breaker.Available(ctx, apiID)
+}
+
+// RecordCallback 根据回调结果更新平台接口健康状况，仅成功/失败两种终态计入
+func (m *Manager) RecordCallback(ctx context.Context, apiID int64, status model.OrderStatus) {
+	switch status {
+	case model.OrderStatusSuccess:
+		m.breaker.Record(ctx, apiID, HealthEventCallback, true)
+	case model.OrderStatusFailed:
+		m.breaker.Record(ctx, apiID, HealthEventCallback, false)
 	}
 }
 
@@ -54,79 +76,122 @@ func (m *Manager) GetPlatform(platformCode string) (Platform, error) {
 		return nil, fmt.Errorf("failed to create platform instance for %s: %v", platformCode, err)
 	}
 
-	// 缓存平台实例
+	// 缓存平台实例，并发创建时以先写入的为准
 	m.mu.Lock()
-	m.platforms[platformCode] = platform
+	if existing, exists := m.platforms[platformCode]; exists {
+		platform = existing
+	} else {
+		m.platforms[platformCode] = platform
+	}
 	m.mu.Unlock()
 
 	return platform, nil
 }
 
-// createPlatform 创建平台实例
-func (m *Manager) createPlatform(code string) (Platform, error) {
-	// 创建平台实例
-	var platform Platform
-	switch code {
-	case "kekebang":
-		platform = NewKekebangPlatform(m.platformRepo.GetDB())
-	case "xianzhuanxia":
-		platform = NewXianzhuanxiaPlatform(m.platformRepo.GetDB())
-	case "mifeng":
-		platform = NewKekebangPlatform(m.platformRepo.GetDB()) // 暂时使用可客帮平台的实现
-	case "mishi":
-		platform = NewMishiPlatform(m.platformRepo.GetDB())
-	case "dayuanren":
-		platform = NewDayuanrenPlatform(m.platformRepo.GetDB())
-	case "external_api":
-		platform = NewExternalAPIPlatform(m.platformRepo.GetDB())
-	case "internal_api":
-		platform = NewExternalAPIPlatform(m.platformRepo.GetDB())
-	case "chongzhi":
-		platform = NewChongzhiPlatform(m.platformRepo.GetDB())
-	default:
-		return nil, fmt.Errorf("unsupported platform code: %s", code)
+// GetPlatformForAPI 根据平台接口配置获取平台实例，实例按接口ID缓存
+func (m *Manager) GetPlatformForAPI(api *model.PlatformAPI) (Platform, error) {
+	if api == nil {
+		return nil, fmt.Errorf("platform api is nil")
+	}
+	// 未入库的接口配置没有ID，不缓存
+	if api.ID == 0 {
+		return m.createPlatformForAPI(api)
+	}
+
+	m.mu.RLock()
+	if platform, exists := m.apiPlatforms[api.ID]; exists {
+		m.mu.RUnlock()
+		return platform, nil
+	}
+	m.mu.RUnlock()
+
+	platform, err := m.createPlatformForAPI(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create platform instance for api %d(%s): %v", api.ID, api.Code, err)
 	}
 
+	m.mu.Lock()
+	if existing, exists := m.apiPlatforms[api.ID]; exists {
+		platform = existing
+	} else {
+		m.apiPlatforms[api.ID] = platform
+	}
+	m.mu.Unlock()
+
 	return platform, nil
 }
 
-// RegisterPlatform 注册新的平台类型
-func (m *Manager) RegisterPlatform(code string, platformType reflect.Type) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// platformForAPIID 获取平台接口的平台实例，apiID 为0（订单未记录接口）时按平台代码获取
+func (m *Manager) platformForAPIID(ctx context.Context, apiID int64, platformCode string) (Platform, error) {
+	if apiID == 0 {
+		return m.GetPlatform(platformCode)
+	}
+	api, err := m.platformAPIRepo.GetByID(ctx, apiID)
+	if err != nil {
+		return nil, fmt.Errorf("get platform api %d failed: %v", apiID, err)
+	}
+	return m.GetPlatformForAPI(api)
+}
 
-	// 确保platformType实现了Platform接口
-	if !reflect.TypeOf((*Platform)(nil)).Elem().Implements(platformType) {
-		logger.Error(fmt.Sprintf("平台类型 %s 未实现 Platform 接口", code))
-		return
+// createPlatform 通过适配器注册表创建平台实例
+// 未注册的平台代码，若对应接口配置了 generic_http 模板，则使用通用HTTP适配器
+func (m *Manager) createPlatform(code string) (Platform, error) {
+	if entry, exists := lookupAdapter(code); exists {
+		return entry.factory(m.db), nil
 	}
 
-	// 注册平台类型
-	m.platformTypes[code] = platformType
-	logger.Info(fmt.Sprintf("注册平台成功: %s", code))
+	api, err := m.platformAPIRepo.GetByCode(context.Background(), code)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported platform code: %s", code)
+	}
+	if err := checkGenericHTTPTemplate(api); err != nil {
+		return nil, err
+	}
+	return NewGenericHTTPPlatform(m.db, code), nil
+}
+
+// createPlatformForAPI 为单个平台接口创建平台实例
+// 通用HTTP实例绑定该接口，按接口ID读取模板，同代码的其他接口不会互相影响
+func (m *Manager) createPlatformForAPI(api *model.PlatformAPI) (Platform, error) {
+	if entry, exists := lookupAdapter(api.Code); exists {
+		return entry.factory(m.db), nil
+	}
+	if err := checkGenericHTTPTemplate(api); err != nil {
+		return nil, err
+	}
+	return NewGenericHTTPPlatformForAPI(m.db, api), nil
+}
+
+// checkGenericHTTPTemplate 校验未注册适配器的接口是否配置了可用的 generic_http 模板
+func checkGenericHTTPTemplate(api *model.PlatformAPI) error {
+	tpl, err := ParseGenericHTTPTemplate(api.ExtraParams)
+	if err != nil {
+		return fmt.Errorf("invalid generic_http template for %s: %v", api.Code, err)
+	}
+	if tpl == nil {
+		return fmt.Errorf("unsupported platform code: %s", api.Code)
+	}
+	return nil
 }
 
 // LoadPlatforms 加载所有平台
 func (m *Manager) LoadPlatforms() error {
-	// 获取所有启用的平台
-	platforms, _, err := m.platformRepo.ListPlatforms(&model.PlatformListRequest{
-		Page:     1,
-		PageSize: 100,
-		Status:   &[]int{1}[0],
-	})
+	// 获取所有启用的平台接口，接口的 Code 决定使用哪个适配器
+	apis, _, err := m.platformAPIRepo.List(context.Background(), 1, 1000)
 	if err != nil {
-		return fmt.Errorf("failed to list platforms: %v", err)
+		return fmt.Errorf("failed to list platform apis: %v", err)
 	}
 
-	// 遍历平台列表，创建对应的平台实例
-	for _, platform := range platforms {
-		platformInstance, err := m.createPlatform(platform.Code)
-		if err != nil {
-			logger.Error(fmt.Sprintf("创建平台实例失败: %v, code: %s", err, platform.Code))
+	// 遍历接口列表，创建对应的平台实例
+	for _, api := range apis {
+		if api.Status != 1 {
+			continue
+		}
+		if _, err := m.GetPlatformForAPI(api); err != nil {
+			logger.Error(fmt.Sprintf("创建平台实例失败: %v, api_id: %d, code: %s", err, api.ID, api.Code))
 			continue
 		}
-		m.platforms[platform.Code] = platformInstance
-		logger.Info(fmt.Sprintf("加载平台成功: %s", platform.Code))
+		logger.Info(fmt.Sprintf("加载平台成功: %s", api.Code))
 	}
 
 	return nil
@@ -135,58 +200,108 @@ func (m *Manager) LoadPlatforms() error {
 // SubmitOrder 提交订单到平台
 func (m *Manager) SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error {
 	// 获取平台实例
-	platform, err := m.GetPlatform(api.Code)
+	platform, err := m.GetPlatformForAPI(api)
 	if err != nil {
-		// 如果平台不存在，尝试动态注册
-		platformType := m.getPlatformTypeByName(api.Code)
-		if platformType != nil {
-			m.RegisterPlatform(api.Code, platformType)
-			platform, err = m.GetPlatform(api.Code)
-			if err != nil {
-				return fmt.Errorf("failed to get platform: %v", err)
-			}
-		} else {
-			return fmt.Errorf("platform not found: %s", api.Code)
-		}
+		return fmt.Errorf("platform not found: %s: %v", api.Code, err)
 	}
-	return platform.SubmitOrder(ctx, order, api, apiParam)
-}
 
-// getPlatformTypeByName 根据平台名称获取平台类型
-func (m *Manager) getPlatformTypeByName(name string) reflect.Type {
-	switch name {
-	case "kekebang":
-		return reflect.TypeOf((*KekebangPlatform)(nil)).Elem()
-	case "xianzhuanxia":
-		return reflect.TypeOf((*XianzhuanxiaPlatform)(nil)).Elem()
-	case "mifeng":
-		return reflect.TypeOf((*KekebangPlatform)(nil)).Elem() // 暂时使用可客帮平台的实现
-	case "mishi":
-		return reflect.TypeOf((*MishiPlatform)(nil)).Elem()
-	case "chongzhi":
-		return reflect.TypeOf((*ChongzhiPlatform)(nil)).Elem()
-	default:
-		return nil
+	// 熔断中的接口直接拒绝，由调用方切换到下一个接口
+	if !m.breaker.Allow(ctx, api.ID) {
+		return fmt.Errorf("%w: %s(%d)", ErrCircuitOpen, api.Code, api.ID)
 	}
+
+	err = platform.SubmitOrder(ctx, order, api, apiParam)
+	m.breaker.Record(ctx, api.ID, HealthEventSubmit, err == nil)
+	return err
 }
 
 // QueryOrderStatus 查询订单状态
-func (m *Manager) QueryOrderStatus(ctx context.Context, order *model.Order) error {
+// 单次查询的超时取订单当前接口配置的 Timeout，未配置时使用 defaultQueryTimeout
+func (m *Manager) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	// 获取平台实例
-	platform, err := m.GetPlatform(order.PlatformCode)
+	platform, err := m.platformForAPIID(ctx, order.APICurID, order.PlatformCode)
 	if err != nil {
-		return fmt.Errorf("failed to get platform: %v", err)
+		return nil, fmt.Errorf("failed to get platform: %v", err)
 	}
+	return m.queryOrderStatus(ctx, platform, order)
+}
+
+// queryOrderStatus 按接口超时时间查询单笔订单
+func (m *Manager) queryOrderStatus(ctx context.Context, platform Platform, order *model.Order) (*QueryResult, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.queryTimeout(ctx, order.APICurID))
+	defer cancel()
 
 	// 查询订单状态
-	status, err := platform.QueryOrderStatus(order)
+	result, err := platform.QueryOrderStatus(queryCtx, order)
+	m.breaker.Record(ctx, order.APICurID, HealthEventQuery, err == nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return result, nil
+}
 
-	// 更新订单状态
-	order.Status = model.OrderStatus(status)
-	return nil
+// BatchQueryOrderStatus 查询同一平台接口下多笔订单的状态，返回以订单ID为键的结果
+// 平台实例、查询超时与熔断统计均取自首笔订单的接口，调用方需按 APICurID 分组
+// 平台实现了 BatchQuerier 时按 MaxBatchSize 分批查询，否则逐笔查询；
+// 查询失败或上游未返回的订单不在结果中，由调用方下次再查
+func (m *Manager) BatchQueryOrderStatus(ctx context.Context, platformCode string, orders []*model.Order) (map[int64]*QueryResult, error) {
+	if len(orders) == 0 {
+		return map[int64]*QueryResult{}, nil
+	}
+	platform, err := m.platformForAPIID(ctx, orders[0].APICurID, platformCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platform: %v", err)
+	}
+
+	results := make(map[int64]*QueryResult, len(orders))
+	batcher, ok := platform.(BatchQuerier)
+	if !ok || batcher.MaxBatchSize() <= 1 {
+		for _, order := range orders {
+			result, err := m.queryOrderStatus(ctx, platform, order)
+			if err != nil {
+				logger.Error("查询订单状态失败", "order_id", order.ID, "platform_code", platformCode, "error", err)
+				continue
+			}
+			results[order.ID] = result
+		}
+		return results, nil
+	}
+
+	size := batcher.MaxBatchSize()
+	for start := 0; start < len(orders); start += size {
+		end := start + size
+		if end > len(orders) {
+			end = len(orders)
+		}
+		chunk := orders[start:end]
+
+		queryCtx, cancel := context.WithTimeout(ctx, m.queryTimeout(ctx, chunk[0].APICurID))
+		batch, err := batcher.BatchQueryOrderStatus(queryCtx, chunk)
+		cancel()
+		m.breaker.Record(ctx, chunk[0].APICurID, HealthEventQuery, err == nil)
+		if err != nil {
+			logger.Error("批量查询订单状态失败", "platform_code", platformCode, "count", len(chunk), "error", err)
+			continue
+		}
+		for _, order := range chunk {
+			if result, ok := batch[order.OrderNumber]; ok {
+				results[order.ID] = result
+			}
+		}
+	}
+	return results, nil
+}
+
+// queryTimeout 获取平台接口的查询超时时间
+func (m *Manager) queryTimeout(ctx context.Context, apiID int64) time.Duration {
+	if apiID == 0 {
+		return defaultQueryTimeout
+	}
+	api, err := m.platformAPIRepo.GetByID(ctx, apiID)
+	if err != nil || api.Timeout <= 0 {
+		return defaultQueryTimeout
+	}
+	return time.Duration(api.Timeout) * time.Second
 }
 
 // HandleCallback 处理平台回调
@@ -208,6 +323,19 @@ func (m *Manager) HandleCallback(ctx context.Context, platformCode string, data
 	return nil
 }
 
+// VerifyCallback 按平台适配器声明的规则校验回调签名及时间戳，未声明验签规则的平台拒绝回调
+func (m *Manager) VerifyCallback(ctx context.Context, platformCode string, account *model.PlatformAccount, data []byte) error {
+	platform, err := m.GetPlatform(platformCode)
+	if err != nil {
+		return fmt.Errorf("get platform failed: %v", err)
+	}
+	verifier, ok := platform.(CallbackVerifier)
+	if !ok {
+		return fmt.Errorf("platform %s does not declare callback verification", platformCode)
+	}
+	return verifier.VerifyCallback(ctx, account, data)
+}
+
 // ParseCallbackData 解析回调数据
 func (m *Manager) ParseCallbackData(platformCode string, data []byte) (*model.CallbackData, error) {
 	// 获取平台实例
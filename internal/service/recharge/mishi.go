@@ -31,6 +31,19 @@ func NewMishiPlatform(db *gorm.DB) *MishiPlatform {
 	}
 }
 
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code: "mishi",
+		Name: "秘史",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewMishiPlatform(db) })
+}
+
 // GetName 获取平台名称
 func (p *MishiPlatform) GetName() string {
 	return "mishi"
@@ -96,19 +109,19 @@ func (p *MishiPlatform) SubmitOrder(ctx context.Context, order *model.Order, api
 	// 构建请求参数
 	szTimeStamp := time.Now().Format("2006-01-02 15:04:05")
 	params := url.Values{}
-	params.Add("szAgentId", accountName)                                  // 客户id
-	params.Add("szOrderId", order.OrderNumber)                            // 订单号
-	params.Add("szPhoneNum", order.Mobile)                                // 充值手机号
-	params.Add("nMoney", strconv.FormatInt(int64(order.Denom), 10))       // 充值金额
-	params.Add("nSortType", convertOperatorCode(strconv.Itoa(order.ISP))) // 运营商编码
-	params.Add("nProductClass", "1")                                      // 充值产品分类
-	params.Add("nProductType", "1")                                       // 充值产品类型
+	params.Add("szAgentId", accountName)                                      // 客户id
+	params.Add("szOrderId", order.OrderNumber)                                // 订单号
+	params.Add("szPhoneNum", order.Mobile)                                    // 充值手机号
+	params.Add("nMoney", strconv.FormatInt(int64(order.Denom.Float64()), 10)) // 充值金额
+	params.Add("nSortType", convertOperatorCode(strconv.Itoa(order.ISP)))     // 运营商编码
+	params.Add("nProductClass", "1")                                          // 充值产品分类
+	params.Add("nProductType", "1")                                           // 充值产品类型
 	params.Add("szProductId", apiParam.ProductID)
 	params.Add("szTimeStamp", szTimeStamp)
 
 	// 生成签名
 	signStr := fmt.Sprintf("szAgentId=%s&szOrderId=%s&szPhoneNum=%s&nMoney=%s&nSortType=%s&nProductClass=%s&nProductType=%s&szTimeStamp=%s&szKey=%s",
-		accountName, order.OrderNumber, order.Mobile, strconv.FormatInt(int64(order.Denom), 10),
+		accountName, order.OrderNumber, order.Mobile, strconv.FormatInt(int64(order.Denom.Float64()), 10),
 		convertOperatorCode(strconv.Itoa(order.ISP)), "1", "1", szTimeStamp, appSecret)
 
 	logger.Info("meishi 生成签名前: ", "signStr", signStr)
@@ -151,7 +164,7 @@ func (p *MishiPlatform) SubmitOrder(ctx context.Context, order *model.Order, api
 }
 
 // QueryOrderStatus 查询订单状态
-func (p *MishiPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
+func (p *MishiPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	logger.Info("开始查询秘史订单状态",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber,
@@ -161,7 +174,7 @@ func (p *MishiPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus,
 	// 获取API密钥和密钥
 	_, appSecret, accountName, err := p.getAPIKeyAndSecret(order.PlatformAccountID)
 	if err != nil {
-		return 0, fmt.Errorf("获取API密钥失败: %v", err)
+		return nil, fmt.Errorf("获取API密钥失败: %v", err)
 	}
 
 	// 构建请求参数
@@ -176,20 +189,20 @@ func (p *MishiPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus,
 	params.Add("szVerifyString", sign)
 
 	// 发送请求
-	respStr, err := p.sendRequest(context.Background(), order.PlatformURL+"/query", params)
+	respStr, err := p.sendRequest(ctx, order.PlatformURL+"/query", params)
 	if err != nil {
-		return 0, fmt.Errorf("查询订单状态失败: %v", err)
+		return nil, fmt.Errorf("查询订单状态失败: %v", err)
 	}
 
 	// 解析响应
 	var result MishiOrderResponseQuery
 	if err := json.Unmarshal([]byte(respStr), &result); err != nil {
-		return 0, fmt.Errorf("解析响应失败: %v", err)
+		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
 
 	// 处理响应
 	if result.SzRtnCode != "success" {
-		return 0, fmt.Errorf("查询订单状态失败: %s", result.SzRtnMsg)
+		return nil, fmt.Errorf("查询订单状态失败: %s", result.SzRtnMsg)
 	}
 
 	// 转换状态
@@ -205,7 +218,11 @@ func (p *MishiPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus,
 		status = model.OrderStatusProcessing
 	}
 
-	return status, nil
+	return &QueryResult{
+		Status:          status,
+		SupplierOrderID: result.SzOrderId,
+		Raw:             respStr,
+	}, nil
 }
 
 // mapOrderState 返回本地订单状态码和字符串
@@ -229,6 +246,35 @@ func (p *MishiPlatform) mapOrderState(nFlag string, orderID, orderNumber string)
 	return status, statusStr
 }
 
+// VerifyCallback 回调验签：表单参数按固定顺序拼接账号密钥后 MD5，平台回调不带时间戳
+func (p *MishiPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid callback form: %v", err)
+	}
+	nDemo, _ := strconv.ParseFloat(form.Get("nDemo"), 64)
+	fSalePrice, _ := strconv.ParseFloat(form.Get("fSalePrice"), 64)
+	nFlag, _ := strconv.Atoi(form.Get("nFlag"))
+
+	signStr := fmt.Sprintf(
+		"szAgentId=%s&szOrderId=%s&szPhoneNum=%s&nDemo=%v&fSalePrice=%.1f&nFlag=%d&szKey=%s",
+		form.Get("szAgentId"),
+		form.Get("szOrderId"),
+		form.Get("szPhoneNum"),
+		nDemo,
+		fSalePrice,
+		nFlag,
+		account.AppSecret,
+	)
+	if signature.GetMD5(signStr) != form.Get("szVerifyString") {
+		return errors.New("invalid sign")
+	}
+	return nil
+}
+
 // ParseCallbackData 解析回调数据
 func (p *MishiPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
 	// 先尝试 url.ParseQuery 解析表单格式
@@ -11,14 +11,54 @@ type Platform interface {
 	GetName() string
 	// SubmitOrder 提交订单
 	SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error
-	// QueryOrderStatus 查询订单状态
-	QueryOrderStatus(order *model.Order) (model.OrderStatus, error)
+	// QueryOrderStatus 查询订单状态，超时由 ctx 控制
+	QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error)
 	// ParseCallbackData 解析回调数据
 	ParseCallbackData(data []byte) (*model.CallbackData, error)
 	// QueryBalance 查询账户余额
 	QueryBalance(ctx context.Context, accountID int64) (float64, error)
 }
 
+// BatchQuerier 支持批量查询订单状态的平台，适配器可选实现
+// 传入的订单属于同一平台账号，数量不超过 MaxBatchSize
+type BatchQuerier interface {
+	// MaxBatchSize 单次批量查询的最大订单数
+	MaxBatchSize() int
+	// BatchQueryOrderStatus 批量查询订单状态，返回以我方订单号为键的结果，上游未返回的订单不在结果中
+	BatchQueryOrderStatus(ctx context.Context, orders []*model.Order) (map[string]*QueryResult, error)
+}
+
+// CallbackVerifier 回调验签，各适配器按平台的签名规则用平台账号密钥验签，并校验回调时间戳
+// account 为回调地址中的平台账号，回调地址不带账号时为 nil
+type CallbackVerifier interface {
+	// VerifyCallback 校验回调签名及时间戳，不通过时返回原因
+	VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error
+}
+
+// CallbackResponder 回调处理成功后返回给平台的应答内容，适配器可选实现，未实现时应答 success
+type CallbackResponder interface {
+	// CallbackAck 回调处理成功（含重复回调）后的应答内容
+	CallbackAck(ctx context.Context) string
+}
+
+// CallbackAck 平台的回调应答内容
+func CallbackAck(ctx context.Context, platform Platform) string {
+	if responder, ok := platform.(CallbackResponder); ok {
+		return responder.CallbackAck(ctx)
+	}
+	return "success"
+}
+
+// QueryResult 上游订单查询结果
+type QueryResult struct {
+	Status          model.OrderStatus `json:"status"`            // 映射后的订单状态
+	SupplierOrderID string            `json:"supplier_order_id"` // 上游订单号
+	Voucher         string            `json:"voucher"`           // 官方凭证/流水号
+	CreditedAmount  float64           `json:"credited_amount"`   // 实际到账面值，0 表示上游未返回
+	Message         string            `json:"message"`           // 上游返回的描述
+	Raw             string            `json:"raw"`               // 上游原始响应
+}
+
 // PlatformConfig 平台配置
 type PlatformConfig struct {
 	AppKey    string
@@ -0,0 +1,101 @@
+package recharge
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PlatformFactory 平台实例构造函数
+type PlatformFactory func(db *gorm.DB) Platform
+
+// Capabilities 平台能力描述
+type Capabilities struct {
+	SubmitOrder      bool `json:"submit_order"`       // 支持提交订单
+	QueryOrderStatus bool `json:"query_order_status"` // 支持主动查询订单状态
+	BatchQuery       bool `json:"batch_query"`        // 支持批量查询订单状态，见 BatchQuerier
+	QueryBalance     bool `json:"query_balance"`      // 支持查询账户余额
+	Callback         bool `json:"callback"`           // 支持异步回调
+}
+
+// AdapterDescriptor 平台适配器描述
+type AdapterDescriptor struct {
+	Code         string       `json:"code"`         // 平台代码，对应 PlatformAPI.Code
+	Name         string       `json:"name"`         // 平台名称
+	Description  string       `json:"description"`  // 描述
+	Capabilities Capabilities `json:"capabilities"` // 能力
+}
+
+// adapterEntry 注册表条目
+type adapterEntry struct {
+	descriptor AdapterDescriptor
+	factory    PlatformFactory
+}
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = make(map[string]*adapterEntry)
+)
+
+// RegisterAdapter 注册平台适配器，通常在适配器文件的 init 中调用
+// 重复注册同一平台代码会 panic，避免两个适配器静默覆盖
+func RegisterAdapter(descriptor AdapterDescriptor, factory PlatformFactory) {
+	if descriptor.Code == "" {
+		panic("recharge: adapter code is empty")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("recharge: adapter %s factory is nil", descriptor.Code))
+	}
+
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+
+	if _, exists := adapters[descriptor.Code]; exists {
+		panic(fmt.Sprintf("recharge: adapter %s registered twice", descriptor.Code))
+	}
+	adapters[descriptor.Code] = &adapterEntry{
+		descriptor: descriptor,
+		factory:    factory,
+	}
+}
+
+// lookupAdapter 根据平台代码查找适配器
+func lookupAdapter(code string) (*adapterEntry, bool) {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+
+	entry, exists := adapters[code]
+	return entry, exists
+}
+
+// IsAdapterRegistered 判断平台代码是否已注册适配器
+func IsAdapterRegistered(code string) bool {
+	_, exists := lookupAdapter(code)
+	return exists
+}
+
+// GetAdapterDescriptor 获取平台适配器描述
+func GetAdapterDescriptor(code string) (AdapterDescriptor, bool) {
+	entry, exists := lookupAdapter(code)
+	if !exists {
+		return AdapterDescriptor{}, false
+	}
+	return entry.descriptor, true
+}
+
+// ListAdapters 列出所有已注册的平台适配器，按平台代码排序
+func ListAdapters() []AdapterDescriptor {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+
+	list := make([]AdapterDescriptor, 0, len(adapters))
+	for _, entry := range adapters {
+		list = append(list, entry.descriptor)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
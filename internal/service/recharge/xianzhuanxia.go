@@ -3,6 +3,7 @@ package recharge
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"recharge-go/pkg/logger"
 	"recharge-go/pkg/signature"
 	"strconv"
+	"strings"
 	"time"
 
 	"recharge-go/internal/repository"
@@ -30,6 +32,19 @@ func NewXianzhuanxiaPlatform(db *gorm.DB) *XianzhuanxiaPlatform {
 	}
 }
 
+func init() {
+	RegisterAdapter(AdapterDescriptor{
+		Code: "xianzhuanxia",
+		Name: "闲赚侠",
+		Capabilities: Capabilities{
+			SubmitOrder:      true,
+			QueryOrderStatus: true,
+			QueryBalance:     true,
+			Callback:         true,
+		},
+	}, func(db *gorm.DB) Platform { return NewXianzhuanxiaPlatform(db) })
+}
+
 // GetName 获取平台名称
 func (p *XianzhuanxiaPlatform) GetName() string {
 	return "xianzhuanxia"
@@ -180,7 +195,7 @@ func (p *XianzhuanxiaPlatform) SubmitOrder(ctx context.Context, order *model.Ord
 }
 
 // QueryOrderStatus 查询订单状态
-func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.OrderStatus, error) {
+func (p *XianzhuanxiaPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*QueryResult, error) {
 	logger.Info("开始查询闲赚侠订单状态",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber,
@@ -190,7 +205,7 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 	// 获取API密钥和密钥
 	appKey, appSecret, _, err := p.getAPIKeyAndSecret(uint(order.APICurID))
 	if err != nil {
-		return 0, fmt.Errorf("获取API密钥失败: %v", err)
+		return nil, fmt.Errorf("获取API密钥失败: %v", err)
 	}
 
 	// 构建请求参数
@@ -207,7 +222,7 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 			"error", err,
 			"params", params,
 		)
-		return 0, fmt.Errorf("生成签名失败: %v", err)
+		return nil, fmt.Errorf("生成签名失败: %v", err)
 	}
 
 	// 发送请求
@@ -217,29 +232,28 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 			"error", err,
 			"params", params,
 		)
-		return 0, fmt.Errorf("序列化请求参数失败: %v", err)
+		return nil, fmt.Errorf("序列化请求参数失败: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", order.PlatformURL+"/query", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", order.PlatformURL+"/query", bytes.NewBuffer(jsonData))
 	if err != nil {
 		logger.Error("创建HTTP请求失败",
 			"error", err,
 			"url", order.PlatformURL+"/query",
 		)
-		return 0, fmt.Errorf("创建HTTP请求失败: %v", err)
+		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+authToken)
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logger.Error("发送HTTP请求失败",
 			"error", err,
 			"url", req.URL.String(),
 		)
-		return 0, fmt.Errorf("发送HTTP请求失败: %v", err)
+		return nil, fmt.Errorf("发送HTTP请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -249,7 +263,7 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 			"error", err,
 			"status_code", resp.StatusCode,
 		)
-		return 0, fmt.Errorf("读取响应内容失败: %v", err)
+		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
 	var result QueryOrderStatusResult
@@ -258,7 +272,7 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 			"error", err,
 			"body", string(body),
 		)
-		return 0, fmt.Errorf("解析响应内容失败: %v", err)
+		return nil, fmt.Errorf("解析响应内容失败: %v", err)
 	}
 
 	if result.Code != 0 {
@@ -266,7 +280,7 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 			"code", result.Code,
 			"message", result.Message,
 		)
-		return 0, fmt.Errorf("查询订单状态失败: %s", result.Message)
+		return nil, fmt.Errorf("查询订单状态失败: %s", result.Message)
 	}
 
 	logger.Info("查询订单状态成功",
@@ -275,39 +289,66 @@ func (p *XianzhuanxiaPlatform) QueryOrderStatus(order *model.Order) (model.Order
 		"status", result.Data.Status,
 	)
 
-	return model.OrderStatus(result.Data.Status), nil
+	return &QueryResult{
+		Status:          model.OrderStatus(result.Data.Status),
+		SupplierOrderID: order.APIOrderNumber,
+		Message:         result.Message,
+		Raw:             string(body),
+	}, nil
 }
 
-// ParseCallbackData 解析回调数据
-func (p *XianzhuanxiaPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
-	var callback struct {
-		OrderID   string  `json:"order_id"`
-		Status    int     `json:"status"`
-		Message   string  `json:"message"`
-		Amount    float64 `json:"amount"`
-		Sign      string  `json:"sign"`
-		Timestamp string  `json:"timestamp"`
-	}
+// xianzhuanxiaCallback 闲赚侠回调数据
+type xianzhuanxiaCallback struct {
+	OrderID   string  `json:"order_id"`
+	Status    int     `json:"status"`
+	Message   string  `json:"message"`
+	Amount    float64 `json:"amount"`
+	Sign      string  `json:"sign"`
+	Timestamp string  `json:"timestamp"`
+}
 
-	if err := json.Unmarshal(data, &callback); err != nil {
-		return nil, fmt.Errorf("解析回调数据失败: %v", err)
+// signParams 参与签名的回调参数
+func (c *xianzhuanxiaCallback) signParams() map[string]string {
+	return map[string]string{
+		"order_id":  c.OrderID,
+		"status":    strconv.Itoa(c.Status),
+		"amount":    strconv.FormatFloat(c.Amount, 'f', 2, 64),
+		"timestamp": c.Timestamp,
 	}
+}
 
-	// 验证签名
-	params := map[string]string{
-		"order_id":  callback.OrderID,
-		"status":    strconv.Itoa(callback.Status),
-		"amount":    strconv.FormatFloat(callback.Amount, 'f', 2, 64),
-		"timestamp": callback.Timestamp,
+// VerifyCallback 回调验签：sign 为与下单相同规则的 Auth_Token，即 Base64(md5,账号,queryTime)，
+// 其中 queryTime 为毫秒时间戳，按回调时间校验时效
+func (p *XianzhuanxiaPlatform) VerifyCallback(ctx context.Context, account *model.PlatformAccount, data []byte) error {
+	if account == nil {
+		return ErrCallbackAccountRequired
 	}
-
-	authToken, _, err := signature.GenerateXianzhuanxiaSignature(params, "", "") // 这里需要从订单中获取
+	var callback xianzhuanxiaCallback
+	if err := json.Unmarshal(data, &callback); err != nil {
+		return fmt.Errorf("invalid callback body: %v", err)
+	}
+	token, err := base64.StdEncoding.DecodeString(callback.Sign)
 	if err != nil {
-		return nil, fmt.Errorf("生成签名失败: %v", err)
+		return fmt.Errorf("invalid sign: %v", err)
+	}
+	parts := strings.Split(string(token), ",")
+	if len(parts) != 3 || parts[1] != account.AccountName {
+		return fmt.Errorf("invalid sign")
 	}
+	if err := CheckCallbackTimestamp(parts[2], CallbackReceivedAt(ctx), DefaultCallbackTolerance); err != nil {
+		return err
+	}
+	if signature.GenerateXianzhuanxiaSignatureAt(callback.signParams(), account.AppKey, account.AccountName, parts[2]) != callback.Sign {
+		return fmt.Errorf("invalid sign")
+	}
+	return nil
+}
 
-	if authToken != callback.Sign {
-		return nil, fmt.Errorf("签名验证失败")
+// ParseCallbackData 解析回调数据
+func (p *XianzhuanxiaPlatform) ParseCallbackData(data []byte) (*model.CallbackData, error) {
+	var callback xianzhuanxiaCallback
+	if err := json.Unmarshal(data, &callback); err != nil {
+		return nil, fmt.Errorf("解析回调数据失败: %v", err)
 	}
 
 	// 转换状态
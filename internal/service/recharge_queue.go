@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/pkg/logger"
+
+	redisV8 "github.com/go-redis/redis/v8"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// TaskTypeRecharge 订单充值任务类型
+	TaskTypeRecharge = "recharge:order"
+	// RechargeQueueName 充值任务队列名
+	RechargeQueueName = "recharge"
+
+	// rechargeTaskTimeout 单个充值任务的可见性超时，worker 崩溃后任务在租约过期后自动恢复
+	rechargeTaskTimeout = 2 * time.Minute
+	// rechargeTaskMaxRetry 充值任务最大重试次数，超过后进入死信队列
+	rechargeTaskMaxRetry = 5
+	// rechargeCooldown 提交失败切换通道后的冷却时间
+	rechargeCooldown = time.Minute
+	// rechargeOrderMaxAge 超过该时长的订单不再自动充值
+	rechargeOrderMaxAge = 24 * time.Hour
+)
+
+// ErrRechargeCooldown 订单切换通道后需冷却再处理，不计入失败重试次数
+var ErrRechargeCooldown = errors.New("recharge order cooling down")
+
+// RechargeTaskPayload 充值任务数据
+type RechargeTaskPayload struct {
+	OrderID int64 `json:"order_id"`
+}
+
+// RechargeTaskID 订单对应的任务ID，同一订单同一时刻只存在一个充值任务
+func RechargeTaskID(orderID int64) string {
+	return fmt.Sprintf("recharge:%d", orderID)
+}
+
+// RechargeRetryDelay 冷却中的订单按冷却时间重新调度，其余错误按指数退避
+func RechargeRetryDelay(n int, err error, task *asynq.Task) time.Duration {
+	if errors.Is(err, ErrRechargeCooldown) {
+		return rechargeCooldown
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, task)
+}
+
+// isRechargeFailure 冷却不视为失败，不消耗重试次数
+func isRechargeFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrRechargeCooldown)
+}
+
+// RechargeQueueStats 充值队列深度
+type RechargeQueueStats struct {
+	Pending   int  `json:"pending"`   // 待处理
+	Active    int  `json:"active"`    // 处理中
+	Scheduled int  `json:"scheduled"` // 延迟执行
+	Retry     int  `json:"retry"`     // 等待重试
+	Archived  int  `json:"archived"`  // 死信
+	Processed int  `json:"processed"` // 今日处理数
+	Failed    int  `json:"failed"`    // 今日失败数
+	Paused    bool `json:"paused"`    // 是否暂停
+}
+
+// RechargeDeadTask 死信队列中的充值任务
+type RechargeDeadTask struct {
+	TaskID       string    `json:"task_id"`
+	OrderID      int64     `json:"order_id"`
+	Retried      int       `json:"retried"`
+	LastErr      string    `json:"last_err"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+}
+
+// RechargeQueue 基于 asynq 的充值任务队列
+type RechargeQueue struct {
+	redisOpt  asynq.RedisClientOpt
+	client    *asynq.Client
+	inspector *asynq.Inspector
+}
+
+// NewRechargeQueue 创建充值任务队列
+func NewRechargeQueue(redisOpt asynq.RedisClientOpt) *RechargeQueue {
+	return &RechargeQueue{
+		redisOpt:  redisOpt,
+		client:    asynq.NewClient(redisOpt),
+		inspector: asynq.NewInspector(redisOpt),
+	}
+}
+
+// newRechargeQueueFromRedis 复用业务 Redis 的连接配置创建充值任务队列
+func newRechargeQueueFromRedis(client *redisV8.Client) *RechargeQueue {
+	if client == nil {
+		return nil
+	}
+	opt := client.Options()
+	return NewRechargeQueue(asynq.RedisClientOpt{
+		Addr:     opt.Addr,
+		Password: opt.Password,
+		DB:       opt.DB,
+	})
+}
+
+// Enqueue 投递订单充值任务，delay 大于0时延迟执行
+// 订单已有未完成的任务（含死信）时视为已投递
+func (q *RechargeQueue) Enqueue(ctx context.Context, orderID int64, delay time.Duration) error {
+	payload, err := json.Marshal(RechargeTaskPayload{OrderID: orderID})
+	if err != nil {
+		return err
+	}
+	opts := []asynq.Option{
+		asynq.TaskID(RechargeTaskID(orderID)),
+		asynq.Queue(RechargeQueueName),
+		asynq.MaxRetry(rechargeTaskMaxRetry),
+		asynq.Timeout(rechargeTaskTimeout),
+	}
+	if delay > 0 {
+		opts = append(opts, asynq.ProcessIn(delay))
+	}
+
+	_, err = q.client.EnqueueContext(ctx, asynq.NewTask(TaskTypeRecharge, payload), opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		logger.Info("【订单已有充值任务，跳过投递】", "order_id", orderID)
+		return nil
+	}
+	return err
+}
+
+// NewServer 创建消费充值队列的 asynq 服务
+func (q *RechargeQueue) NewServer(concurrency int) *asynq.Server {
+	return asynq.NewServer(q.redisOpt, asynq.Config{
+		Concurrency:    concurrency,
+		Queues:         map[string]int{RechargeQueueName: 1},
+		RetryDelayFunc: RechargeRetryDelay,
+		IsFailure:      isRechargeFailure,
+		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
+			retried, _ := asynq.GetRetryCount(ctx)
+			maxRetry, _ := asynq.GetMaxRetry(ctx)
+			if isRechargeFailure(err) && retried >= maxRetry {
+				logger.Error("【充值任务重试耗尽，进入死信队列】",
+					"payload", string(task.Payload()),
+					"retried", retried,
+					"error", err)
+			}
+		}),
+	})
+}
+
+// Stats 获取充值队列深度
+func (q *RechargeQueue) Stats() (*RechargeQueueStats, error) {
+	info, err := q.inspector.GetQueueInfo(RechargeQueueName)
+	if errors.Is(err, asynq.ErrQueueNotFound) {
+		// 队列还没有投递过任务
+		return &RechargeQueueStats{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &RechargeQueueStats{
+		Pending:   info.Pending,
+		Active:    info.Active,
+		Scheduled: info.Scheduled,
+		Retry:     info.Retry,
+		Archived:  info.Archived,
+		Processed: info.Processed,
+		Failed:    info.Failed,
+		Paused:    info.Paused,
+	}, nil
+}
+
+// ListDead 分页获取死信队列中的充值任务
+func (q *RechargeQueue) ListDead(page, pageSize int) ([]*RechargeDeadTask, error) {
+	tasks, err := q.inspector.ListArchivedTasks(RechargeQueueName, asynq.Page(page), asynq.PageSize(pageSize))
+	if errors.Is(err, asynq.ErrQueueNotFound) {
+		return []*RechargeDeadTask{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*RechargeDeadTask, 0, len(tasks))
+	for _, task := range tasks {
+		var payload RechargeTaskPayload
+		_ = json.Unmarshal(task.Payload, &payload)
+		items = append(items, &RechargeDeadTask{
+			TaskID:       task.ID,
+			OrderID:      payload.OrderID,
+			Retried:      task.Retried,
+			LastErr:      task.LastErr,
+			LastFailedAt: task.LastFailedAt,
+		})
+	}
+	return items, nil
+}
+
+// IsDead 订单的充值任务是否在死信队列中
+func (q *RechargeQueue) IsDead(orderID int64) (bool, error) {
+	info, err := q.inspector.GetTaskInfo(RechargeQueueName, RechargeTaskID(orderID))
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.State == asynq.TaskStateArchived, nil
+}
+
+// RequeueDead 将死信任务重新放回队列立即执行
+func (q *RechargeQueue) RequeueDead(orderID int64) error {
+	return q.inspector.RunTask(RechargeQueueName, RechargeTaskID(orderID))
+}
+
+// DeleteDead 删除死信任务，之后该订单可重新投递
+func (q *RechargeQueue) DeleteDead(orderID int64) error {
+	return q.inspector.DeleteTask(RechargeQueueName, RechargeTaskID(orderID))
+}
+
+// Close 关闭队列连接
+func (q *RechargeQueue) Close() error {
+	if err := q.inspector.Close(); err != nil {
+		return err
+	}
+	return q.client.Close()
+}
+
+// RechargeTaskHandler 充值任务处理器
+type RechargeTaskHandler struct {
+	rechargeService RechargeService
+}
+
+// NewRechargeTaskHandler 创建充值任务处理器
+func NewRechargeTaskHandler(rechargeService RechargeService) *RechargeTaskHandler {
+	return &RechargeTaskHandler{rechargeService: rechargeService}
+}
+
+// ProcessTask 处理订单充值任务
+// 订单不再是待充值时直接完成；提交失败切换通道后返回 ErrRechargeCooldown，由队列冷却后重新调度
+func (h *RechargeTaskHandler) ProcessTask(ctx context.Context, task *asynq.Task) error {
+	var payload RechargeTaskPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil || payload.OrderID == 0 {
+		return fmt.Errorf("invalid recharge task payload %q: %w", task.Payload(), asynq.SkipRetry)
+	}
+
+	order, err := h.rechargeService.GetOrderByID(ctx, payload.OrderID)
+	if err != nil {
+		return fmt.Errorf("get order %d failed: %v", payload.OrderID, err)
+	}
+	if order.Status != model.OrderStatusPendingRecharge {
+		logger.Info("【订单状态不是待充值，任务结束】", "order_id", order.ID, "status", order.Status)
+		return nil
+	}
+
+	createTime := order.CreateTime
+	if createTime.IsZero() {
+		createTime = order.CreatedAt
+	}
+	if !createTime.IsZero() && createTime.Add(rechargeOrderMaxAge).Before(time.Now()) {
+		logger.Info("【订单创建时间超过24小时，不再自动充值】", "order_id", order.ID, "create_time", createTime)
+		return nil
+	}
+
+	processErr := h.rechargeService.ProcessRechargeTask(ctx, order)
+
+	// 提交失败切换了通道，订单回到待充值，冷却后再处理
+	current, err := h.rechargeService.GetOrderByID(ctx, order.ID)
+	if err == nil && current.Status == model.OrderStatusPendingRecharge {
+		return fmt.Errorf("%w: %v", ErrRechargeCooldown, processErr)
+	}
+	if processErr != nil {
+		logger.Error("【处理充值任务失败】", "order_id", order.ID, "error", processErr)
+		return processErr
+	}
+	return nil
+}
+
+// legacyRechargeLists 旧版 Redis LIST 充值队列
+var legacyRechargeLists = []string{"recharge_queue", "recharge_processing"}
+
+// MigrateLegacyQueue 将旧版 LIST 队列中遗留的订单转投到任务队列，迁移完成后删除旧队列
+func (q *RechargeQueue) MigrateLegacyQueue(ctx context.Context, client *redisV8.Client) error {
+	if client == nil {
+		return nil
+	}
+	for _, key := range legacyRechargeLists {
+		orderIDs, err := client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("read legacy queue %s failed: %v", key, err)
+		}
+		for _, orderIDStr := range orderIDs {
+			orderID, err := strconv.ParseInt(orderIDStr, 10, 64)
+			if err != nil {
+				logger.Error("【解析旧队列订单ID失败】", "queue", key, "order_id_str", orderIDStr, "error", err)
+				continue
+			}
+			if err := q.Enqueue(ctx, orderID, 0); err != nil {
+				return fmt.Errorf("migrate order %d failed: %v", orderID, err)
+			}
+		}
+		if err := client.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("delete legacy queue %s failed: %v", key, err)
+		}
+		if len(orderIDs) > 0 {
+			logger.Info("【旧充值队列迁移完成】", "queue", key, "count", len(orderIDs))
+		}
+	}
+	return nil
+}
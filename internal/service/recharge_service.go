@@ -13,22 +13,20 @@ import (
 	"recharge-go/pkg/logger"
 	"recharge-go/pkg/queue"
 	"recharge-go/pkg/redis"
-	"strconv"
 	"sync"
 	"time"
 
 	redisV8 "github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RechargeService 充值服务接口
 type RechargeService interface {
 	// Recharge 执行充值
 	Recharge(ctx context.Context, orderID int64) error
-	// HandleCallback 处理平台回调
-	HandleCallback(ctx context.Context, platformName string, data []byte) error
-	// GetPendingTasks 获取待处理的充值任务
-	GetPendingTasks(ctx context.Context, limit int) ([]*model.Order, error)
+	// HandleCallback 处理平台回调：落库、验签、去重、完成订单并返回平台应答
+	HandleCallback(ctx context.Context, req *CallbackRequest) (*CallbackResult, error)
 	// ProcessRechargeTask 处理充值任务
 	ProcessRechargeTask(ctx context.Context, order *model.Order) error
 	// CreateRechargeTask 创建充值任务
@@ -37,14 +35,16 @@ type RechargeService interface {
 	GetPlatformAPIByOrderID(ctx context.Context, orderID string) (*model.PlatformAPI, *model.PlatformAPIParam, error)
 	// PushToRechargeQueue 将订单推送到充值队列
 	PushToRechargeQueue(ctx context.Context, orderID int64) error
-	// PopFromRechargeQueue 从充值队列获取订单
-	PopFromRechargeQueue(ctx context.Context) (int64, error)
 	// GetOrderByID 根据ID获取订单
 	GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error)
-	// RemoveFromProcessingQueue 从处理中队列移除任务
-	RemoveFromProcessingQueue(ctx context.Context, orderID int64) error
-	// CheckRechargingOrders 检查充值中订单
+	// CheckRechargingOrders 主动查询超过预期回调时间的充值中订单并按结果完成订单
 	CheckRechargingOrders(ctx context.Context) error
+	// SettleOrderResult 按上游结果（回调或主动查询）完成订单
+	SettleOrderResult(ctx context.Context, order *model.Order, result OrderResult) (bool, error)
+	// QueryOrderStatus 主动查询上游订单状态，不修改订单
+	QueryOrderStatus(ctx context.Context, order *model.Order) (*recharge.QueryResult, error)
+	// BatchQueryOrderStatus 查询同一平台接口下多笔订单的状态，平台支持时批量查询
+	BatchQueryOrderStatus(ctx context.Context, platformCode string, orders []*model.Order) (map[int64]*recharge.QueryResult, error)
 	// SubmitOrder 提交订单到平台
 	SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error
 	// ProcessRetryTask 处理重试任务
@@ -55,6 +55,16 @@ type RechargeService interface {
 	GetUserBalanceService() *BalanceService
 	// SetOrderService 设置订单服务
 	SetOrderService(orderService OrderService)
+	// GetRoutingService 获取通道路由服务
+	GetRoutingService() *RoutingService
+	// GetSplitService 获取拆单服务
+	GetSplitService() *OrderSplitService
+	// GetRechargeQueue 获取充值任务队列
+	GetRechargeQueue() *RechargeQueue
+	// GetStatusPoller 获取充值中订单状态轮询
+	GetStatusPoller() *OrderStatusPoller
+	// GetOutbox 获取事务发件箱
+	GetOutbox() *Outbox
 }
 
 // rechargeService 充值服务
@@ -71,7 +81,15 @@ type rechargeService struct {
 	balanceService         *PlatformAccountBalanceService
 	userBalanceService     *BalanceService
 	manager                *recharge.Manager
+	routingService         *RoutingService
+	spendLimiter           *AccountSpendLimiter
+	stateMachine           *OrderStateMachine
+	splitService           *OrderSplitService
+	partialService         *OrderPartialService
 	redisClient            *redisV8.Client
+	rechargeQueue          *RechargeQueue
+	statusPoller           *OrderStatusPoller
+	outbox                 *Outbox
 	processingOrders       map[int64]bool
 	processingOrdersMu     sync.Mutex
 	notificationRepo       notificationRepo.Repository
@@ -95,7 +113,13 @@ func NewRechargeService(
 	notificationRepo notificationRepo.Repository,
 	queue queue.Queue,
 ) *rechargeService {
-	return &rechargeService{
+	spendLimiter := NewAccountSpendLimiter(db, redis.GetClient())
+	routingService := NewRoutingService(db, productRepo, productAPIRelationRepo, platformRepo, spendLimiter)
+	stateMachine := NewOrderStateMachine(db)
+	unifiedRefundService := NewUnifiedRefundService(db, repository.NewUserRepository(db), orderRepo,
+		repository.NewBalanceLogRepository(db), nil, userBalanceService, balanceService)
+	outbox := NewOutbox(db, queue)
+	s := &rechargeService{
 		db:                     db,
 		orderRepo:              orderRepo,
 		platformRepo:           platformRepo,
@@ -108,11 +132,20 @@ func NewRechargeService(
 		balanceService:         balanceService,
 		userBalanceService:     userBalanceService,
 		manager:                recharge.NewManager(db),
+		routingService:         routingService,
+		spendLimiter:           spendLimiter,
+		stateMachine:           stateMachine,
+		splitService:           NewOrderSplitService(db, routingService, stateMachine, outbox),
+		partialService:         NewOrderPartialService(db, stateMachine, unifiedRefundService),
 		redisClient:            redis.GetClient(),
+		rechargeQueue:          newRechargeQueueFromRedis(redis.GetClient()),
 		processingOrders:       make(map[int64]bool),
 		notificationRepo:       notificationRepo,
 		queue:                  queue,
+		outbox:                 outbox,
 	}
+	s.statusPoller = NewOrderStatusPoller(db, s, DefaultOrderPollConfig())
+	return s
 }
 
 // Recharge 执行充值
@@ -131,8 +164,6 @@ func (s *rechargeService) Recharge(ctx context.Context, orderID int64) error {
 	// 检查订单状态，如果已经是充值中或已完成，则不再处理
 	if order.Status == model.OrderStatusRecharging || order.Status == model.OrderStatusSuccess {
 		logger.Info(fmt.Sprintf("【订单状态异常，跳过处理】order_id: %d, status: %d", orderID, order.Status))
-		// 从处理中队列移除
-		_ = s.RemoveFromProcessingQueue(ctx, orderID)
 		return nil
 	}
 
@@ -213,23 +244,20 @@ func (s *rechargeService) Recharge(ctx context.Context, orderID int64) error {
 	// 5. 更新订单状态
 	logger.Info("【开始更新订单状态】order_id: %d, old_status: %d, new_status: %d",
 		orderID, order.Status, model.OrderStatusRecharging)
-	result := tx.Model(&model.Order{}).Where("id = ?", orderID).Update("status", model.OrderStatusRecharging)
-	if result.Error != nil {
-		tx.Rollback()
-		logger.Error("【更新订单状态失败】order_id: %d, error: %v", orderID, result.Error)
-		return fmt.Errorf("update order status failed: %v", result.Error)
-	}
-	if result.RowsAffected == 0 {
+	if err := s.stateMachine.Transit(ctx, tx, orderID, order.Status, model.OrderStatusRecharging, OrderTransition{
+		Reason: "提交上游成功",
+		Source: OrderTransitionSourceWorker,
+	}); err != nil {
 		tx.Rollback()
-		logger.Error("【更新订单状态失败】order_id: %d, 没有记录被更新", orderID)
-		return fmt.Errorf("no record updated")
+		logger.Error("【更新订单状态失败】order_id: %d, error: %v", orderID, err)
+		return fmt.Errorf("update order status failed: %w", err)
 	}
-	logger.Info("【更新订单状态成功】order_id: %d, rows_affected: %d", orderID, result.RowsAffected)
+	logger.Info("【更新订单状态成功】order_id: %d", orderID)
 
 	// 6. 更新平台信息
 	logger.Info("【开始更新平台信息】order_id: %d, platform_id: %d, api_id: %d, param_id: %d",
 		orderID, api.ID, api.ID, apiParam.ID)
-	result = tx.Model(&model.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+	result := tx.Model(&model.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
 		"api_cur_id":       api.ID,
 		"api_cur_param_id": apiParam.ID,
 	})
@@ -252,13 +280,7 @@ func (s *rechargeService) Recharge(ctx context.Context, orderID int64) error {
 	}
 	logger.Info("【提交事务成功】order_id: %d", orderID)
 
-	// 8. 从处理中队列移除
-	logger.Info("【从处理中队列移除】order_id: %d", orderID)
-	if err := s.RemoveFromProcessingQueue(ctx, orderID); err != nil {
-		logger.Error("【从处理中队列移除失败】order_id: %d, error: %v", orderID, err)
-	}
-
-	// 9. 验证更新结果
+	// 8. 验证更新结果
 	updatedOrder, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		logger.Error("【验证更新结果失败】order_id: %d, error: %v", orderID, err)
@@ -270,22 +292,35 @@ func (s *rechargeService) Recharge(ctx context.Context, orderID int64) error {
 	// 提交成功后，更新订单的 const_price 字段为 apiParam.Price
 	err = s.orderRepo.DB().Model(&model.Order{}).
 		Where("id = ?", order.ID).
-		Update("const_price", apiParam.Price).Error
+		Update("const_price", apiParam.Price.Round()).Error
 	if err != nil {
 		logger.Error("【更新订单成本价失败】", "order_id", order.ID, "error", err)
-		// 新增：将订单状态设置为失败，并写入备注
-		_ = s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed)
-		_ = s.orderRepo.UpdateRemark(ctx, order.ID, "余额不足，订单失败")
-		// 新增：推送订单失败通知
-		notification := &notificationModel.NotificationRecord{
-			OrderID:          order.ID,
-			PlatformCode:     order.PlatformCode,
-			NotificationType: "order_status_changed",
-			Content:          "订单失败：余额不足",
-			Status:           1, // 待处理
+		// 将订单状态设置为失败并写入备注，失败通知与状态变更同事务写入发件箱
+		var msg *model.OutboxMessage
+		txErr := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if _, err := s.stateMachine.TransitCurrent(ctx, tx, order.ID, model.OrderStatusFailed, OrderTransition{
+				Reason:     "余额不足，订单失败",
+				Source:     OrderTransitionSourceWorker,
+				Fields:     map[string]interface{}{"remark": "余额不足，订单失败"},
+				SettleHold: true,
+			}); err != nil {
+				return err
+			}
+			var err error
+			msg, err = s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
+				OrderID:          order.ID,
+				PlatformCode:     order.PlatformCode,
+				NotificationType: "order_status_changed",
+				Content:          "订单失败：余额不足",
+				Status:           1, // 待处理
+			})
+			return err
+		})
+		if txErr != nil {
+			logger.Error("更新订单失败状态失败", "order_id", order.ID, "error", txErr)
+		} else {
+			s.outbox.PublishAll(ctx, msg)
 		}
-		_ = s.notificationRepo.Create(ctx, notification)
-		_ = s.queue.Push(ctx, "notification_queue", notification)
 	} else {
 		logger.Info("【更新订单成本价成功】", "order_id", order.ID, "const_price", apiParam.Price)
 	}
@@ -294,241 +329,123 @@ func (s *rechargeService) Recharge(ctx context.Context, orderID int64) error {
 	return nil
 }
 
-// HandleCallback 处理平台回调
-func (s *rechargeService) HandleCallback(ctx context.Context, platformName string, data []byte) error {
-	// 1. 解析回调数据
-	callbackData, err := s.manager.ParseCallbackData(platformName, data)
-	if err != nil {
-		logger.Error(fmt.Sprintf("解析回调数据失败: %v", err))
-		return fmt.Errorf("parse callback data failed service 层: %v", err)
-	}
-	logger.Info(fmt.Sprintf("收到秘史回调，解析回调数据成功: %+v", callbackData))
-
-	// 2. 检查是否已处理过该回调
-	exists, err := s.callbackLogRepo.GetByOrderIDAndType(ctx, callbackData.OrderID, callbackData.CallbackType)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		logger.Error(fmt.Sprintf("检查回调记录失败: %v", err))
-		tx := s.db.Begin()
-		defer func() {
-			if r := recover(); r != nil {
-				tx.Rollback()
-			}
-		}()
-		return err
-	}
-	if exists != nil {
-		logger.Info(fmt.Sprintf("回调已处理过: order_id: %s, callback_type: %s", callbackData.OrderID, callbackData.CallbackType))
-		return nil
+// SettleOrderResult 按上游结果（回调或主动查询）完成订单
+// 只处理成功、失败、部分充值三种终态：失败退款，部分充值退还未到账部分，成功记录到账面值；
+// 完成后通知下游并汇总拆单父订单。订单已是该状态或结果非终态时返回 false
+func (s *rechargeService) SettleOrderResult(ctx context.Context, order *model.Order, result OrderResult) (bool, error) {
+	if !result.IsFinal() || order.Status == result.Status {
+		return false, nil
 	}
 
-	// 3. 开启事务
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	var settled bool
+	var msg *model.OutboxMessage
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked model.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.ID).First(&locked).Error; err != nil {
+			return fmt.Errorf("get order failed: %v", err)
 		}
-	}()
-
-	// 4. 处理回调
-	if err := s.manager.HandleCallback(ctx, platformName, data); err != nil {
-		tx.Rollback()
-		logger.Error("处理回调失败: %v", err)
-		return fmt.Errorf("handle callback failed: %v", err)
-	}
-
-	// 4.1 更新订单状态
-	orderState, err := strconv.Atoi(callbackData.Status)
+		var err error
+		settled, msg, err = s.settleLockedOrder(ctx, tx, &locked, result)
+		return err
+	})
 	if err != nil {
-		tx.Rollback()
-		logger.Error("解析订单状态失败1111: %v", err)
-		return fmt.Errorf("parse order status failed: %v", err)
+		return false, err
 	}
-
-	// 获取订单信息
-	order, err := s.orderRepo.GetByOrderID(ctx, callbackData.OrderNumber)
-	if err != nil {
-		tx.Rollback()
-		logger.Error("获取订单信息失败: %v", err)
-		return fmt.Errorf("get order failed: %v", err)
+	if settled {
+		s.afterSettle(ctx, order, msg)
 	}
+	return settled, nil
+}
 
-	// === 新增：订单失败自动退款 ===
-	if model.OrderStatus(orderState) == model.OrderStatusFailed {
-		// 检查是否为外部订单
-		if order.Client == 2 {
-			// 外部订单直接退款到用户余额
-			// 注意：这里需要通过依赖注入获取余额服务，暂时记录日志
-			logger.Info("外部订单失败，需要退款到用户余额",
-				"order_id", order.ID,
-				"customer_id", order.CustomerID,
-				"amount", order.Price)
-			// TODO: 实现外部订单退款逻辑
-		} else {
-			// 平台订单使用原有的退款方法
-			err := s.balanceService.RefundBalance(ctx, order.CustomerID, order.Price, order.ID, "订单失败退还余额")
-			if err != nil {
-				tx.Rollback()
-				logger.Error("订单失败退款失败: %v", err)
-				return fmt.Errorf("订单失败退款失败: %v", err)
-			}
+// settleLockedOrder 在事务内按上游结果完成已加行锁的订单，并将下游通知写入发件箱
+func (s *rechargeService) settleLockedOrder(ctx context.Context, tx *gorm.DB, order *model.Order, result OrderResult) (bool, *model.OutboxMessage, error) {
+	newStatus := result.Status
+	if !result.IsFinal() || order.Status == newStatus {
+		return false, nil, nil
+	}
+	if !CanTransitOrder(order.Status, newStatus) {
+		logger.Error("上游结果状态流转不合法", "order_id", order.ID, "from", order.Status, "to", newStatus, "source", result.Source)
+		return false, nil, fmt.Errorf("%w: %s -> %s", ErrIllegalOrderTransition, order.Status, newStatus)
+	}
+
+	content := fmt.Sprintf("订单状态已更新为: %d", newStatus)
+	switch newStatus {
+	case model.OrderStatusFailed:
+		if err := s.orderService.ProcessOrderFailWithTx(ctx, tx, order, OrderTransition{
+			Actor:  result.Actor,
+			Reason: result.Reason,
+			Source: result.Source,
+			Fields: result.supplierFields(),
+		}); err != nil {
+			logger.Error("订单失败处理失败", "order_id", order.ID, "source", result.Source, "error", err)
+			return false, nil, fmt.Errorf("process order fail failed: %w", err)
 		}
-	}
-	// === 新增 END ===
+		content = fmt.Sprintf("订单失败: %s", result.Reason)
 
-	// 更新订单状态
-	if err := s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatus(orderState)); err != nil {
-		tx.Rollback()
-		logger.Error("更新订单状态失败: %v", err)
-		return fmt.Errorf("update order status failed: %v", err)
+	case model.OrderStatusPartial:
+		// 部分充值需要上游返回实际到账金额，据此退还未到账部分
+		credited, err := ParseCreditedAmount(result.CreditedAmount)
+		if err != nil {
+			logger.Error("部分充值到账金额无效", "order_id", order.ID, "amount", result.CreditedAmount, "error", err)
+			return false, nil, fmt.Errorf("parse credited amount failed: %w", err)
+		}
+		settlement, err := s.partialService.SettleWithTx(ctx, tx, order, credited, OrderTransition{
+			Actor:  result.Actor,
+			Source: result.Source,
+			Fields: result.supplierFields(),
+		})
+		if err != nil {
+			logger.Error("部分充值结算失败", "order_id", order.ID, "error", err)
+			return false, nil, fmt.Errorf("settle partial order failed: %w", err)
+		}
+		if settlement.Duplicated {
+			return false, nil, nil
+		}
+		content = fmt.Sprintf("%s, 实际到账: %s, 退款: %s", content, settlement.CreditedAmount, settlement.RefundAmount)
+
+	case model.OrderStatusSuccess:
+		fields := result.supplierFields()
+		fields["credited_amount"] = order.Denom
+		fields["finish_time"] = time.Now()
+		if err := s.stateMachine.Transit(ctx, tx, order.ID, order.Status, newStatus, OrderTransition{
+			Actor:      result.Actor,
+			Reason:     result.Reason,
+			Source:     result.Source,
+			Fields:     fields,
+			SettleHold: true,
+		}); err != nil {
+			logger.Error("更新订单状态失败", "order_id", order.ID, "error", err)
+			return false, nil, fmt.Errorf("update order status failed: %w", err)
+		}
 	}
-	logger.Info(fmt.Sprintf("订单回调更新订单状态成功: 订单号%s, 订单id%d, 状态%s", order.OrderNumber, order.ID, orderState))
+	logger.Info(fmt.Sprintf("上游结果更新订单状态成功: 订单号%s, 订单id%d, 状态%s", order.OrderNumber, order.ID, newStatus))
 
-	// 创建通知记录
-	notification := &notificationModel.NotificationRecord{
+	// 通知与状态变更同事务提交，提交后再投递
+	msg, err := s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
 		OrderID:          order.ID,
 		PlatformCode:     order.PlatformCode,
 		NotificationType: "order_status_changed",
-		Content:          fmt.Sprintf("订单状态已更新为: %d", orderState),
+		Content:          content,
 		Status:           1, // 待处理
-	}
-
-	// 保存通知记录到数据库
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
-		tx.Rollback()
-		logger.Error("创建通知记录失败",
-			"error", err,
-			"order_id", order.ID,
-			"platform_code", order.PlatformCode,
-			"notification_type", notification.NotificationType,
-		)
-		return fmt.Errorf("create notification record failed: %v", err)
-	}
-	logger.Info(fmt.Sprintf("秘史创建通知记录成功: 订单号%s, 订单id%d, 状态%s", order.OrderNumber, order.ID, orderState))
-
-	// 推送通知到队列
-	logger.Info("准备推送通知到队列", "order_id", order.ID, "status", orderState)
-	err = s.queue.Push(ctx, "notification_queue", notification)
+	})
 	if err != nil {
-		tx.Rollback()
-		logger.Error("推送通知到队列失败", "order_id", order.ID, "error", err)
-		return fmt.Errorf("push notification to queue failed: %v", err)
+		return false, nil, err
 	}
-	logger.Info("订单推送通知到队列成功", "order_id", order.ID)
-
-	// 5. 记录回调日志
-	log := &model.CallbackLog{
-		OrderID:      callbackData.OrderID,
-		PlatformID:   callbackData.OrderNumber,
-		CallbackType: callbackData.CallbackType,
-		Status:       1,
-		RequestData:  string(data),
-		ResponseData: "success",
-		CreateTime:   time.Now(),
-		UpdateTime:   time.Now(),
-	}
-	if err := s.callbackLogRepo.Create(ctx, log); err != nil {
-		tx.Rollback()
-		logger.Error("记录回调日志失败: %v", err)
-		return err
-	}
-
-	// 6. 提交事务
-	if err := tx.Commit().Error; err != nil {
-		logger.Error("提交事务失败: %v", err)
-		return fmt.Errorf("commit transaction failed: %v", err)
-	}
-
-	return nil
+	return true, msg, nil
 }
 
-// GetPendingTasks 获取待处理的充值任务
-func (s *rechargeService) GetPendingTasks(ctx context.Context, limit int) ([]*model.Order, error) {
-	// 从Redis队列中获取待处理的订单ID
-	if s.redisClient == nil {
-		logger.Error("【Redis客户端为空】")
-		return nil, fmt.Errorf("redis client is nil")
-	}
-
-	// 获取队列中的订单ID列表
-	orderIDs, err := s.redisClient.LRange(ctx, "recharge_queue", 0, int64(limit-1)).Result()
-	if err != nil {
-		logger.Error("【从Redis队列获取订单ID失败】", "error", err)
-		return nil, fmt.Errorf("get order IDs from queue failed: %v", err)
-	}
-
-	logger.Info("【调试：从Redis获取的订单ID列表】", "order_ids", orderIDs, "limit", limit)
-
-	if len(orderIDs) == 0 {
-		logger.Info("【Redis队列中没有待处理订单】")
-		return []*model.Order{}, nil
-	}
-
-	// 将字符串ID转换为int64并查询订单信息
-	var orders []*model.Order
-	now := time.Now()
-	for _, orderIDStr := range orderIDs {
-		orderID, err := strconv.ParseInt(orderIDStr, 10, 64)
-		if err != nil {
-			logger.Error("【解析订单ID失败】", "order_id_str", orderIDStr, "error", err)
-			continue
-		}
-
-		// 获取订单信息
-		order, err := s.orderRepo.GetByID(ctx, orderID)
-		if err != nil {
-			logger.Error("【获取订单信息失败】", "order_id", orderID, "error", err)
-			// 从Redis队列中移除该订单
-			if removeErr := s.redisClient.LRem(ctx, "recharge_queue", 0, orderIDStr).Err(); removeErr != nil {
-				logger.Error("【从队列移除失效订单失败】", "order_id", orderID, "error", removeErr)
-			} else {
-				logger.Info("【成功从队列移除失效订单】", "order_id", orderID)
-			}
-			continue
-		}
-
-		logger.Info("【调试：检查订单】", "order_id", orderID, "status", order.Status, "created_at", order.CreatedAt, "updated_at", order.UpdatedAt)
-
-		// 检查订单状态和时间过滤条件
-		if order.Status != model.OrderStatusPendingRecharge {
-			logger.Info("【订单状态不是待充值，从队列中移除】", "order_id", orderID, "status", order.Status)
-			// 从Redis队列中移除该订单
-			if err := s.redisClient.LRem(ctx, "recharge_queue", 0, orderIDStr).Err(); err != nil {
-				logger.Error("【从队列移除订单失败】", "order_id", orderID, "error", err)
-			} else {
-				logger.Info("【成功从队列移除订单】", "order_id", orderID)
-			}
-			continue
-		}
-
-		// 只对非新订单应用1分钟冷却机制
-		// 新订单的创建时间和更新时间相差很小（通常在几毫秒内），不应该被冷却机制拦截
-		timeDiff := order.UpdatedAt.Sub(order.CreatedAt)
-		isNewOrder := timeDiff < 5*time.Second // 5秒内的时间差认为是新订单
-
-		logger.Info("【调试：时间检查】", "order_id", orderID, "time_diff", timeDiff, "is_new_order", isNewOrder)
-
-		if !isNewOrder && order.UpdatedAt.Add(1*time.Minute).After(now) {
-			logger.Info("【订单最近1分钟内被处理过，跳过】", "order_id", orderID)
-			continue
-		}
-
-		// 如果订单创建时间超过24小时，跳过
-		// 优先使用CreateTime字段，如果为空则使用CreatedAt字段
-		createTime := order.CreateTime
-		if createTime.IsZero() && !order.CreatedAt.IsZero() {
-			createTime = order.CreatedAt
-		}
-
-		if !createTime.IsZero() && createTime.Add(24*time.Hour).Before(now) {
-			logger.Info("【订单创建时间超过24小时，跳过】", "order_id", orderID, "create_time", createTime)
-			continue
+// afterSettle 订单完成事务提交后投递通知并汇总拆单父订单，投递失败由发件箱中继补投
+func (s *rechargeService) afterSettle(ctx context.Context, order *model.Order, msg *model.OutboxMessage) {
+	if msg != nil {
+		if err := s.outbox.Publish(ctx, msg); err != nil {
+			logger.Error("推送通知到队列失败，等待发件箱补投", "order_id", order.ID, "error", err)
+		} else {
+			logger.Info("订单推送通知到队列成功", "order_id", order.ID)
 		}
-
-		orders = append(orders, order)
 	}
 
-	logger.Info("【获取到待处理订单】", "count", len(orders))
-	return orders, nil
+	// 拆单子订单完成后汇总父订单
+	s.splitService.OnChildFinished(ctx, order)
 }
 
 // ProcessRechargeTask 处理充值任务
@@ -540,6 +457,10 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 
 	// 获取平台API信息 - 直接使用传入的订单对象，避免通过order_number重新查询
 	api, apiParam, err := s.getPlatformAPIByOrder(ctx, order)
+	if errors.Is(err, ErrOrderSplit) {
+		logger.Info("【订单已拆单，由子订单继续充值】", "order_id", order.ID)
+		return nil
+	}
 	if err != nil {
 		logger.Error("【获取API信息失败】",
 			"error", err,
@@ -554,17 +475,21 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 
 	// 原子性锁定订单，防止并发重复处理
 	// 只有当前 api_id 对应的订单状态为 'pending_recharge' 时，才允许锁定
-	locked, err := s.orderRepo.UpdateStatusCAS(ctx, order.ID, model.OrderStatusPendingRecharge, model.OrderStatusProcessing, api.ID)
+	err = s.stateMachine.Transit(ctx, nil, order.ID, model.OrderStatusPendingRecharge, model.OrderStatusProcessing, OrderTransition{
+		Reason: "开始处理充值任务",
+		Source: OrderTransitionSourceWorker,
+		Fields: map[string]interface{}{"api_id": api.ID},
+	})
+	if errors.Is(err, ErrOrderStatusConflict) {
+		logger.Info("【订单已被其他worker处理，跳过】", "order_id", order.ID)
+		return nil
+	}
 	if err != nil {
 		logger.Error("【订单状态原子更新失败】",
 			"error", err,
 			"order_id", order.ID)
 		return err
 	}
-	if !locked {
-		logger.Info("【订单已被其他worker处理，跳过】", "order_id", order.ID)
-		return nil
-	}
 
 	// 获取订单信息
 	order, err = s.orderRepo.GetByID(ctx, order.ID)
@@ -603,38 +528,33 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 				"platform_account_id", order.PlatformAccountID,
 				"amount", order.Price)
 			
-			// 使用事务处理订单状态更新
+			// 订单失败与失败通知同事务提交，提交后再投递
+			var msg *model.OutboxMessage
 			txErr := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-				// 将订单状态设置为失败，并写入备注
-				if err := tx.Model(&model.Order{}).Where("id = ?", order.ID).Update("status", model.OrderStatusFailed).Error; err != nil {
-					return err
-				}
-				if err := tx.Model(&model.Order{}).Where("id = ?", order.ID).Update("remark", "平台账号余额和授信额度均不足，订单失败").Error; err != nil {
+				if err := s.stateMachine.Transit(ctx, tx, order.ID, order.Status, model.OrderStatusFailed, OrderTransition{
+					Reason:     "平台账号余额和授信额度均不足",
+					Source:     OrderTransitionSourceWorker,
+					Fields:     map[string]interface{}{"remark": "平台账号余额和授信额度均不足，订单失败"},
+					SettleHold: true,
+				}); err != nil {
 					return err
 				}
-				return nil
-			})
-			
-			if txErr != nil {
-				logger.Error("更新订单状态失败", "error", txErr, "order_id", order.ID)
-			} else {
-				// 事务提交成功后，创建并推送通知
-				notification := &notificationModel.NotificationRecord{
+				var err error
+				msg, err = s.outbox.AddNotification(tx, &notificationModel.NotificationRecord{
 					OrderID:          order.ID,
 					PlatformCode:     order.PlatformCode,
 					NotificationType: "order_status_changed",
 					Content:          "订单失败：平台账号余额和授信额度均不足",
 					Status:           1, // 待处理
-				}
-				if createErr := s.notificationRepo.Create(ctx, notification); createErr != nil {
-					logger.Error("创建通知记录失败", "error", createErr, "order_id", order.ID)
-				} else {
-					if pushErr := s.queue.Push(ctx, "notification_queue", notification); pushErr != nil {
-						logger.Error("推送通知到队列失败", "error", pushErr, "order_id", order.ID)
-					} else {
-						logger.Info("推送扣款失败通知到队列成功", "order_id", order.ID)
-					}
-				}
+				})
+				return err
+			})
+			
+			if txErr != nil {
+				logger.Error("更新订单状态失败", "error", txErr, "order_id", order.ID)
+			} else {
+				s.outbox.PublishAll(ctx, msg)
+				s.splitService.OnChildFinished(ctx, order)
 			}
 			
 			return fmt.Errorf("deduct platform account balance failed: %v", err)
@@ -650,15 +570,6 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 			"error", err,
 			"order_id", order.ID)
 
-		// 获取所有可用的API关系
-		relations, err2 := s.productRepo.GetAPIRelationsByProductID(ctx, order.ProductID)
-		if err2 != nil {
-			logger.Error("【获取API关系失败】",
-				"error", err2,
-				"order_id", order.ID)
-			return fmt.Errorf("get API relations failed: %v", err2)
-		}
-
 		// 解析已使用的API列表
 		var usedAPIs []map[string]interface{}
 		if order.UsedAPIs != "" {
@@ -676,22 +587,18 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 		})
 		usedAPIsJSON, _ := json.Marshal(usedAPIs)
 
-		// 找到下一个可用的API
+		// 按路由策略找到下一个可用的通道
 		var nextAPIID, nextParamID int64
-		for _, relation := range relations {
-			// 检查API是否已使用
-			alreadyUsed := false
-			for _, usedAPI := range usedAPIs {
-				if usedAPI["api_id"] == relation.APIID {
-					alreadyUsed = true
-					break
-				}
-			}
-			if !alreadyUsed {
-				nextAPIID = relation.APIID
-				nextParamID = relation.ParamID
-				break
-			}
+		next, err2 := s.routingService.RouteFirst(ctx, order, usedAPIIDs(string(usedAPIsJSON)))
+		if err2 != nil {
+			logger.Error("【通道路由失败】",
+				"error", err2,
+				"order_id", order.ID)
+			return fmt.Errorf("route next API failed: %v", err2)
+		}
+		if next != nil {
+			nextAPIID = next.API.ID
+			nextParamID = next.Param.ID
 		}
 
 		if nextAPIID == 0 {
@@ -704,15 +611,19 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 			return fmt.Errorf("no available API")
 		}
 
-		fmt.Println("调用 UpdateStatusAndAPIID 之前")
-		if err2 := s.orderRepo.UpdateStatusAndAPIID(ctx, order.ID, model.OrderStatusPendingRecharge, nextAPIID, string(usedAPIsJSON)); err2 != nil {
-			fmt.Println("UpdateStatusAndAPIID 执行出错，err =", err2)
+		if err2 := s.stateMachine.Transit(ctx, nil, order.ID, order.Status, model.OrderStatusPendingRecharge, OrderTransition{
+			Reason: fmt.Sprintf("提交失败切换接口: %v", err),
+			Source: OrderTransitionSourceWorker,
+			Fields: map[string]interface{}{
+				"api_id":    nextAPIID,
+				"used_apis": string(usedAPIsJSON),
+			},
+		}); err2 != nil {
 			logger.Error("【更新订单状态和API ID失败】",
 				"error", err2,
 				"order_id", order.ID)
-			return fmt.Errorf("update order status and API ID failed: %v", err2)
+			return fmt.Errorf("update order status and API ID failed: %w", err2)
 		}
-		fmt.Println("UpdateStatusAndAPIID 执行完毕")
 
 		fmt.Println("准备创建重试记录 retryParams")
 		submitErr := err // 保存 SubmitOrder 的错误
@@ -767,25 +678,21 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 			"order_id", order.ID,
 			"retry_id", retryRecord.ID)
 
-		// 从处理队列中移除
-		if err := s.RemoveFromProcessingQueue(ctx, order.ID); err != nil {
-			logger.Error("【从处理队列移除失败】",
-				"error", err,
-				"order_id", order.ID)
-		}
-
 		logger.Info("【充值任务处理完成】",
 			"order_id", order.ID,
 			"order_number", order.OrderNumber)
 		return fmt.Errorf("submit order failed: %v", err)
 	}
 
-	// 更新订单状态为充值中
-	if err := s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusRecharging); err != nil {
+	// 更新订单状态为充值中（SubmitOrder 已流转时不再重复变更）
+	if _, err := s.stateMachine.TransitCurrent(ctx, nil, order.ID, model.OrderStatusRecharging, OrderTransition{
+		Reason: "提交上游成功",
+		Source: OrderTransitionSourceWorker,
+	}); err != nil {
 		logger.Error("【更新订单状态失败】",
 			"error", err,
 			"order_id", order.ID)
-		return fmt.Errorf("update order status failed: %v", err)
+		return fmt.Errorf("update order status failed: %w", err)
 	}
 	logger.Info("【订单状态更新成功】",
 		"order_id", order.ID,
@@ -794,20 +701,13 @@ func (s *rechargeService) ProcessRechargeTask(ctx context.Context, order *model.
 	// 更新订单成本价
 	err = s.orderRepo.DB().Model(&model.Order{}).
 		Where("id = ?", order.ID).
-		Update("const_price", apiParam.Price).Error
+		Update("const_price", apiParam.Price.Round()).Error
 	if err != nil {
 		logger.Error("【更新订单成本价失败】", "order_id", order.ID, "error", err)
 	} else {
 		logger.Info("【更新订单成本价成功】", "order_id", order.ID, "const_price", apiParam.Price)
 	}
 
-	// 从处理队列中移除
-	if err := s.RemoveFromProcessingQueue(ctx, order.ID); err != nil {
-		logger.Error("【从处理队列移除失败】",
-			"error", err,
-			"order_id", order.ID)
-	}
-
 	logger.Info("【充值任务处理完成】",
 		"order_id", order.ID,
 		"order_number", order.OrderNumber)
@@ -868,66 +768,93 @@ func (s *rechargeService) getPlatformAPIByOrder(ctx context.Context, order *mode
 	logger.Info("【获取平台API信息】", "order_id", order.ID, "product_id", order.ProductID)
 
 	//product_api_relations
-	r, err := s.productAPIRelationRepo.GetByProductID(ctx, order.ProductID)
-	if err != nil {
-		// 将订单设置为失败状态
-		if err := s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed); err != nil {
-			logger.Error("【更新订单状态失败】",
-				"error", err,
-				"order_id", order.ID)
+	if _, err := s.productAPIRelationRepo.GetByProductID(ctx, order.ProductID); err != nil {
+		// 面值未绑定接口时先尝试拆单
+		if splitErr := s.splitOrder(ctx, order); splitErr != nil {
+			return nil, nil, splitErr
 		}
-		// 更新订单备注
-		if err := s.orderRepo.UpdateRemark(ctx, order.ID, "商品未绑定接口"); err != nil {
-			logger.Error("【更新订单备注失败】",
+		// 将订单设置为失败状态并写入备注
+		if _, err := s.stateMachine.TransitCurrent(ctx, nil, order.ID, model.OrderStatusFailed, OrderTransition{
+			Reason:     "商品未绑定接口",
+			Source:     OrderTransitionSourceWorker,
+			Fields:     map[string]interface{}{"remark": "商品未绑定接口"},
+			SettleHold: true,
+		}); err != nil {
+			logger.Error("【更新订单状态失败】",
 				"error", err,
 				"order_id", order.ID)
 		}
+		s.splitService.OnChildFinished(ctx, order)
 		return nil, nil, fmt.Errorf("商品未绑定接口: %v", err)
 	}
 
-	//获取api套餐 platform_api_params
-	apiParam, err := s.platformAPIParamRepo.GetByID(ctx, r.ParamID)
+	// 按商品路由策略选择通道，已尝试过的接口不再参与
+	candidate, err := s.routingService.RouteFirst(ctx, order, usedAPIIDs(order.UsedAPIs))
 	if err != nil {
-		if errors.Is(err, repository.ErrNoAPIForProduct) {
-			// 将订单设置为失败状态
-			if err := s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed); err != nil {
-				logger.Error("【更新订单状态失败】",
-					"error", err,
-					"order_id", order.ID)
+		return nil, nil, fmt.Errorf("通道路由失败: %v", err)
+	}
+	if candidate == nil {
+		logger.Error("【没有满足条件的通道】", "order_id", order.ID, "product_id", order.ProductID)
+		// 首次路由即无可用通道时尝试拆单，已尝试过其他接口的订单不再拆分
+		if order.UsedAPIs == "" {
+			if splitErr := s.splitOrder(ctx, order); splitErr != nil {
+				return nil, nil, splitErr
 			}
-			// 更新订单备注
-			if err := s.orderRepo.UpdateRemark(ctx, order.ID, "商品未绑定接口"); err != nil {
-				logger.Error("【更新订单备注失败】",
-					"error", err,
-					"order_id", order.ID)
+		}
+		if s.orderService != nil {
+			if err := s.orderService.ProcessOrderFail(ctx, order.ID, "无可用接口"); err != nil {
+				logger.Error("处理订单失败时出错", "error", err, "order_id", order.ID)
 			}
-			return nil, nil, fmt.Errorf("商品未绑定接口")
 		}
-		return nil, nil, fmt.Errorf("获取API参数信息失败: %v", err)
+		return nil, nil, fmt.Errorf("no available API")
 	}
 
-	// 获取平台API信息 PlatformAPI
-	api, err := s.platformRepo.GetAPIByID(ctx, r.APIID)
+	logger.Info("【通道路由结果】",
+		"order_id", order.ID,
+		"api_id", candidate.API.ID,
+		"param_id", candidate.Param.ID,
+		"score", candidate.Score)
+	return candidate.API, candidate.Param, nil
+}
+
+// splitOrder 面值无可用通道时按拆单规则拆分订单，并将子订单推入充值队列
+// 没有可用拆单规则时返回 nil，拆单成功返回 ErrOrderSplit
+func (s *rechargeService) splitOrder(ctx context.Context, order *model.Order) error {
+	children, err := s.splitService.Split(ctx, order)
 	if err != nil {
-		if errors.Is(err, repository.ErrNoAPIForProduct) {
-			// 将订单设置为失败状态
-			if err := s.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed); err != nil {
-				logger.Error("【更新订单状态失败】",
-					"error", err,
-					"order_id", order.ID)
-			}
-			// 更新订单备注
-			if err := s.orderRepo.UpdateRemark(ctx, order.ID, "商品未绑定接口"); err != nil {
-				logger.Error("【更新订单备注失败】",
-					"error", err,
-					"order_id", order.ID)
-			}
-			return nil, nil, fmt.Errorf("商品未绑定接口")
+		logger.Error("【订单拆单失败】", "error", err, "order_id", order.ID)
+		return err
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	for _, child := range children {
+		if err := s.PushToRechargeQueue(ctx, child.ID); err != nil {
+			logger.Error("【推送子订单到充值队列失败】",
+				"error", err,
+				"order_id", order.ID,
+				"child_order_id", child.ID)
 		}
-		return nil, nil, fmt.Errorf("获取平台API信息失败: %v", err)
 	}
+	return ErrOrderSplit
+}
 
-	return api, apiParam, nil
+// usedAPIIDs 解析订单已使用的接口列表
+func usedAPIIDs(usedAPIs string) map[int64]bool {
+	used := make(map[int64]bool)
+	if usedAPIs == "" {
+		return used
+	}
+	var list []struct {
+		APIID int64 `json:"api_id"`
+	}
+	if err := json.Unmarshal([]byte(usedAPIs), &list); err != nil {
+		return used
+	}
+	for _, item := range list {
+		used[item.APIID] = true
+	}
+	return used
 }
 
 func (s *rechargeService) GetPlatformAPIByOrderID(ctx context.Context, orderID string) (*model.PlatformAPI, *model.PlatformAPIParam, error) {
@@ -940,36 +867,19 @@ func (s *rechargeService) GetPlatformAPIByOrderID(ctx context.Context, orderID s
 	return s.getPlatformAPIByOrder(ctx, order)
 }
 
-// PushToRechargeQueue 将订单推送到充值队列
+// PushToRechargeQueue 将订单投递到充值任务队列
+// 同一订单已有未完成的任务时不会重复投递
 func (s *rechargeService) PushToRechargeQueue(ctx context.Context, orderID int64) error {
 	logger.Info("【准备推送订单到充值队列】",
 		"order_id", orderID)
 
-	if s.redisClient == nil {
-		logger.Error("【Redis客户端为空】",
+	if s.rechargeQueue == nil {
+		logger.Error("【充值任务队列未初始化】",
 			"order_id", orderID)
-		return fmt.Errorf("redis client is nil")
+		return fmt.Errorf("recharge queue is nil")
 	}
 
-	// 检查订单是否已经在队列中，避免重复推送
-	orderIDStr := strconv.FormatInt(orderID, 10)
-	exists, err := s.redisClient.LPos(ctx, "recharge_queue", orderIDStr, redisV8.LPosArgs{}).Result()
-	if err == nil {
-		logger.Info("【订单已在充值队列中，跳过推送】",
-			"order_id", orderID,
-			"position", exists)
-		return nil
-	}
-	// 如果是redis.Nil错误，说明订单不在队列中，可以继续推送
-	if err != redisV8.Nil {
-		logger.Error("【检查订单是否在队列中失败】",
-			"error", err,
-			"order_id", orderID)
-		// 即使检查失败，也继续推送，避免丢失订单
-	}
-
-	err = s.redisClient.LPush(ctx, "recharge_queue", orderID).Err()
-	if err != nil {
+	if err := s.rechargeQueue.Enqueue(ctx, orderID, 0); err != nil {
 		logger.Error("【推送订单到充值队列失败】",
 			"error", err,
 			"order_id", orderID)
@@ -981,43 +891,14 @@ func (s *rechargeService) PushToRechargeQueue(ctx context.Context, orderID int64
 	return nil
 }
 
-// PopFromRechargeQueue 从充值队列获取订单
-func (s *rechargeService) PopFromRechargeQueue(ctx context.Context) (int64, error) {
-	logger.Debug("【准备从充值队列获取订单】")
-
-	if s.redisClient == nil {
-		logger.Error("【Redis客户端为空】")
-		return 0, fmt.Errorf("redis client is nil")
-	}
-
-	// 使用 BRPOPLPUSH 命令，将任务从队列中移除并放入处理中队列
-	result, err := s.redisClient.BRPopLPush(ctx, "recharge_queue", "recharge_processing", 0).Result()
-	if err != nil {
-		if err == redisV8.Nil {
-			logger.Debug("【充值队列为空】")
-			return 0, nil
-		}
-		logger.Error("【从充值队列获取订单失败】",
-			"error", err)
-		return 0, err
-	}
-
-	orderID, err := strconv.ParseInt(result, 10, 64)
-	if err != nil {
-		logger.Error("【解析订单ID失败】",
-			"error", err,
-			"result", result)
-		return 0, fmt.Errorf("parse order id failed: %v", err)
-	}
-
-	logger.Info("【从充值队列获取订单成功】",
-		"order_id", orderID)
-	return orderID, nil
+// GetRechargeQueue 获取充值任务队列
+func (s *rechargeService) GetRechargeQueue() *RechargeQueue {
+	return s.rechargeQueue
 }
 
-// RemoveFromProcessingQueue 从处理中队列移除任务
-func (s *rechargeService) RemoveFromProcessingQueue(ctx context.Context, orderID int64) error {
-	return s.redisClient.LRem(ctx, "recharge_processing", 0, orderID).Err()
+// GetOutbox 获取事务发件箱
+func (s *rechargeService) GetOutbox() *Outbox {
+	return s.outbox
 }
 
 // GetOrderByID 根据ID获取订单
@@ -1025,56 +906,32 @@ func (s *rechargeService) GetOrderByID(ctx context.Context, orderID int64) (*mod
 	return s.orderRepo.GetByID(ctx, orderID)
 }
 
-// CheckRechargingOrders 检查充值中订单
+// CheckRechargingOrders 主动查询超过预期回调时间的充值中订单并按结果完成订单
 func (s *rechargeService) CheckRechargingOrders(ctx context.Context) error {
-	logger.Info("【开始检查充值中订单】开始执行定时检查任务")
-
-	// 获取所有充值中的订单
-	orders, err := s.orderRepo.GetByStatus(ctx, model.OrderStatusRecharging)
-	if err != nil {
-		logger.Error("【获取充值中订单失败】error: %v", err)
-		return fmt.Errorf("get recharging orders failed: %v", err)
-	}
+	_, err := s.statusPoller.Poll(ctx)
+	return err
+}
 
-	logger.Info("【获取充值中订单成功】共获取到 %d 个订单", len(orders))
-
-	now := time.Now()
-	checkedCount := 0
-	for _, order := range orders {
-		// 检查订单是否超过5分钟
-		if order.UpdatedAt.Add(5 * time.Minute).Before(now) {
-			logger.Info("【发现超时订单】order_id: %d, order_number: %s, 最后更新时间: %s, 已超时: %v",
-				order.ID, order.OrderNumber, order.UpdatedAt.Format("2006-01-02 15:04:05"), now.Sub(order.UpdatedAt))
-
-			// 查询订单状态
-			if err := s.manager.QueryOrderStatus(ctx, order); err != nil {
-				logger.Error("【查询订单状态失败】order_id: %d, order_number: %s, error: %v",
-					order.ID, order.OrderNumber, err)
-				continue
-			}
+// GetStatusPoller 获取充值中订单状态轮询
+func (s *rechargeService) GetStatusPoller() *OrderStatusPoller {
+	return s.statusPoller
+}
 
-			logger.Info("【订单状态查询完成】order_id: %d, order_number: %s",
-				order.ID, order.OrderNumber)
-			checkedCount++
-		}
-	}
+// QueryOrderStatus 主动查询上游订单状态，不修改订单
+func (s *rechargeService) QueryOrderStatus(ctx context.Context, order *model.Order) (*recharge.QueryResult, error) {
+	return s.manager.QueryOrderStatus(ctx, order)
+}
 
-	logger.Info("【充值中订单检查完成】共检查 %d 个订单，其中 %d 个订单需要查询状态",
-		len(orders), checkedCount)
-	return nil
+// BatchQueryOrderStatus 查询同一平台账号下多笔订单的状态，平台支持时批量查询
+func (s *rechargeService) BatchQueryOrderStatus(ctx context.Context, platformCode string, orders []*model.Order) (map[int64]*recharge.QueryResult, error) {
+	return s.manager.BatchQueryOrderStatus(ctx, platformCode, orders)
 }
 
 // SubmitOrder 提交订单到平台
 func (s *rechargeService) SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error {
-	// 获取平台实例
-	platform, err := s.manager.GetPlatform(api.Code)
-	if err != nil {
-		return fmt.Errorf("通过 %s 获取平台失败: %v", api.Code, err)
-	}
-	// 提交订单到平台
-	err = platform.SubmitOrder(ctx, order, api, apiParam)
-	if err != nil {
-		return fmt.Errorf("submit order failed: %v", err)
+	// 提交订单到平台（经由 Manager，熔断中或额度用完的接口会被直接拒绝）
+	if err := s.submitToPlatform(ctx, order, api, apiParam); err != nil {
+		return fmt.Errorf("submit order failed: %w", err)
 	}
 
 	// 开启事务
@@ -1087,19 +944,14 @@ func (s *rechargeService) SubmitOrder(ctx context.Context, order *model.Order, a
 	}()
 
 	// 更新订单状态和成本价
-	result := tx.Model(&model.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
-		"status":      model.OrderStatusRecharging,
-		"const_price": apiParam.Price,
-	})
-	if result.Error != nil {
+	if _, err := s.stateMachine.TransitCurrent(ctx, tx, order.ID, model.OrderStatusRecharging, OrderTransition{
+		Reason: "提交上游成功",
+		Source: OrderTransitionSourceWorker,
+		Fields: map[string]interface{}{"const_price": apiParam.Price},
+	}); err != nil {
 		tx.Rollback()
-		logger.Error("【更新订单状态和成本价失败】order_id: %d, error: %v", order.ID, result.Error)
-		return fmt.Errorf("update order status and cost price failed: %v", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		tx.Rollback()
-		logger.Error("【更新订单状态和成本价失败】order_id: %d, 没有记录被更新", order.ID)
-		return fmt.Errorf("no record updated")
+		logger.Error("【更新订单状态和成本价失败】order_id: %d, error: %v", order.ID, err)
+		return fmt.Errorf("update order status and cost price failed: %w", err)
 	}
 
 	// 提交事务
@@ -1108,7 +960,7 @@ func (s *rechargeService) SubmitOrder(ctx context.Context, order *model.Order, a
 		return fmt.Errorf("commit transaction failed: %v", err)
 	}
 
-	logger.Info(fmt.Sprintf("【订单状态和成本价更新成功】order_id: %d, status: %d, const_price: %f",
+	logger.Info(fmt.Sprintf("【订单状态和成本价更新成功】order_id: %d, status: %d, const_price: %s",
 		order.ID, model.OrderStatusRecharging, apiParam.Price))
 	return nil
 }
@@ -1150,7 +1002,7 @@ func (s *rechargeService) ProcessRetryTask(ctx context.Context, retryRecord *mod
 	// 4. 提交订单到平台
 	logger.Info("【开始提交订单到平台】retry_id: %d, order_id: %d, order_number: %s",
 		retryRecord.ID, retryRecord.OrderID, order.OrderNumber)
-	if err := s.manager.SubmitOrder(ctx, order, api, apiParam); err != nil {
+	if err := s.submitToPlatform(ctx, order, api, apiParam); err != nil {
 		logger.Error("【提交订单到平台失败】retry_id: %d, order_id: %d, error: %v",
 			retryRecord.ID, retryRecord.OrderID, err)
 		return fmt.Errorf("submit order failed: %v", err)
@@ -1173,29 +1025,23 @@ func (s *rechargeService) ProcessRetryTask(ctx context.Context, retryRecord *mod
 	// 6. 更新订单状态
 	logger.Info("【开始更新订单状态】retry_id: %d, order_id: %d, order_number: %s, old_status: %d, new_status: %d",
 		retryRecord.ID, retryRecord.OrderID, order.OrderNumber, order.Status, model.OrderStatusRecharging)
-	result := tx.Model(&model.Order{}).Where("id = ?", retryRecord.OrderID).Updates(map[string]interface{}{
-		"status":      model.OrderStatusRecharging,
-		"const_price": apiParam.Price,
-	})
-	if result.Error != nil {
+	if err := s.stateMachine.Transit(ctx, tx, retryRecord.OrderID, order.Status, model.OrderStatusRecharging, OrderTransition{
+		Reason: fmt.Sprintf("重试提交成功, retry_id: %d", retryRecord.ID),
+		Source: OrderTransitionSourceRetry,
+		Fields: map[string]interface{}{"const_price": apiParam.Price},
+	}); err != nil {
 		tx.Rollback()
 		logger.Error("【更新订单状态失败】retry_id: %d, order_id: %d, error: %v",
-			retryRecord.ID, retryRecord.OrderID, result.Error)
-		return fmt.Errorf("update order status failed: %v", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		tx.Rollback()
-		logger.Error("【更新订单状态失败】retry_id: %d, order_id: %d, 没有记录被更新",
-			retryRecord.ID, retryRecord.OrderID)
-		return fmt.Errorf("no record updated")
+			retryRecord.ID, retryRecord.OrderID, err)
+		return fmt.Errorf("update order status failed: %w", err)
 	}
-	logger.Info("【更新订单状态和成本价成功】retry_id: %d, order_id: %d, rows_affected: %d, const_price: %f",
-		retryRecord.ID, retryRecord.OrderID, result.RowsAffected, apiParam.Price)
+	logger.Info("【更新订单状态和成本价成功】retry_id: %d, order_id: %d, const_price: %f",
+		retryRecord.ID, retryRecord.OrderID, apiParam.Price)
 
 	// 7. 更新平台信息
 	logger.Info("【开始更新平台信息】retry_id: %d, order_id: %d, platform_id: %d, api_id: %d, param_id: %d",
 		retryRecord.ID, retryRecord.OrderID, api.ID, api.ID, apiParam.ID)
-	result = tx.Model(&model.Order{}).Where("id = ?", retryRecord.OrderID).Updates(map[string]interface{}{
+	result := tx.Model(&model.Order{}).Where("id = ?", retryRecord.OrderID).Updates(map[string]interface{}{
 		"platform_id":      api.ID,
 		"api_cur_id":       api.ID,
 		"api_cur_param_id": apiParam.ID,
@@ -1242,7 +1088,7 @@ func (s *rechargeService) ProcessRetryTask(ctx context.Context, retryRecord *mod
 	logger.Info("【开始更新订单成本价】retry_id: %d, order_id: %d, const_price: %f", retryRecord.ID, retryRecord.OrderID, apiParam.Price)
 	err = s.orderRepo.DB().Model(&model.Order{}).
 		Where("id = ?", retryRecord.OrderID).
-		Update("const_price", apiParam.Price).Error
+		Update("const_price", apiParam.Price.Round()).Error
 	if err != nil {
 		logger.Error("【更新订单成本价失败】retry_id: %d, order_id: %d, error: %v", retryRecord.ID, retryRecord.OrderID, err)
 	} else {
@@ -1278,3 +1124,41 @@ func (s *rechargeService) GetBalanceService() *PlatformAccountBalanceService {
 func (s *rechargeService) GetUserBalanceService() *BalanceService {
 	return s.userBalanceService
 }
+
+// GetRoutingService 获取通道路由服务
+func (s *rechargeService) GetRoutingService() *RoutingService {
+	return s.routingService
+}
+
+// GetSplitService 获取拆单服务
+func (s *rechargeService) GetSplitService() *OrderSplitService {
+	return s.splitService
+}
+
+// submitToPlatform 按通道成本占用接口所属账号的日/月额度后提交订单，提交失败时释放占用；
+// 订单之后失败或退款时由 ProcessOrderFailWithTx 等结算路径释放
+func (s *rechargeService) submitToPlatform(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error {
+	var account *model.PlatformAccount
+	if api.AccountID > 0 {
+		var err error
+		account, err = s.platformRepo.GetAccountByID(ctx, api.AccountID)
+		if err != nil {
+			return fmt.Errorf("获取平台账号失败: %v", err)
+		}
+	}
+
+	if err := s.spendLimiter.ReserveOrder(ctx, account, order.ID, paramCost(apiParam)); err != nil {
+		logger.Error("【平台账号额度不足】", "order_id", order.ID, "api_id", api.ID, "account_id", api.AccountID, "error", err)
+		return err
+	}
+
+	if err := s.manager.SubmitOrder(ctx, order, api, apiParam); err != nil {
+		if hasSpendLimit(account) {
+			if releaseErr := s.spendLimiter.ReleaseOrder(ctx, nil, order.ID); releaseErr != nil {
+				logger.Error("【释放平台账号额度失败】", "order_id", order.ID, "error", releaseErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -2,69 +2,54 @@ package service
 
 import (
 	"context"
+	"fmt"
+
 	"recharge-go/pkg/logger"
-	"time"
+	"recharge-go/pkg/redis"
+
+	"github.com/hibiken/asynq"
 )
 
-// RechargeWorker 充值工作器
+// RechargeWorker 充值工作器，消费充值任务队列
+// 任务在处理超时后自动回到队列，失败按退避重试，重试耗尽进入死信队列
 type RechargeWorker struct {
 	rechargeService RechargeService
-	stopChan        chan struct{}
-	interval        time.Duration
-	batchSize       int
+	concurrency     int
 }
 
-// NewRechargeWorker 创建充值工作器
-func NewRechargeWorker(rechargeService RechargeService, interval time.Duration, batchSize int) *RechargeWorker {
+// NewRechargeWorker 创建充值工作器，concurrency 为并发处理的任务数
+func NewRechargeWorker(rechargeService RechargeService, concurrency int) *RechargeWorker {
+	if concurrency <= 0 {
+		concurrency = 10
+	}
 	return &RechargeWorker{
 		rechargeService: rechargeService,
-		stopChan:        make(chan struct{}),
-		interval:        interval,
-		batchSize:       batchSize,
+		concurrency:     concurrency,
 	}
 }
 
-// Start 启动充值工作器
-func (w *RechargeWorker) Start(ctx context.Context) {
-	logger.Info("充值工作器启动")
-
-	ticker := time.NewTicker(w.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("充值工作器停止")
-			return
-		case <-ticker.C:
-			// 获取待处理的充值任务
-			tasks, err := w.rechargeService.GetPendingTasks(ctx, w.batchSize)
-			if err != nil {
-				logger.Error("获取待处理任务失败: %v", err)
-				continue
-			}
-
-			if len(tasks) == 0 {
-				logger.Info("没有待处理的充值任务")
-				continue
-			}
+// Start 启动充值工作器，阻塞直到 ctx 结束
+func (w *RechargeWorker) Start(ctx context.Context) error {
+	queue := w.rechargeService.GetRechargeQueue()
+	if queue == nil {
+		return fmt.Errorf("recharge queue is nil")
+	}
 
-			logger.Info("开始处理充值任务", "任务数量", len(tasks))
+	if err := queue.MigrateLegacyQueue(ctx, redis.GetClient()); err != nil {
+		logger.Error("【迁移旧充值队列失败】", "error", err)
+	}
 
-			// 处理每个任务
-			for _, task := range tasks {
-				if err := w.rechargeService.ProcessRechargeTask(ctx, task); err != nil {
-					logger.Error("处理充值任务失败", "order_id", task.ID, "error", err)
-					continue
-				}
-			}
+	mux := asynq.NewServeMux()
+	mux.Handle(TaskTypeRecharge, NewRechargeTaskHandler(w.rechargeService))
 
-			logger.Info("本轮充值任务处理完成", "处理数量", len(tasks))
-		}
+	srv := queue.NewServer(w.concurrency)
+	if err := srv.Start(mux); err != nil {
+		return fmt.Errorf("start recharge worker failed: %v", err)
 	}
-}
+	logger.Info("充值工作器启动", "concurrency", w.concurrency)
 
-// Stop 停止工作器
-func (w *RechargeWorker) Stop() {
-	close(w.stopChan)
+	<-ctx.Done()
+	srv.Shutdown()
+	logger.Info("充值工作器停止")
+	return nil
 }
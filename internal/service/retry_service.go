@@ -8,7 +8,6 @@ import (
 	"recharge-go/internal/repository"
 	"recharge-go/internal/signature"
 	"recharge-go/pkg/logger"
-	"sort"
 	"time"
 
 	"go.uber.org/zap"
@@ -64,7 +63,7 @@ func NewRetryService(
 // HandleRetry 处理重试
 func (s *RetryService) HandleRetry(ctx context.Context, order *model.Order, retryType int) error {
 	// 1. 获取可用的API关系列表
-	relations, err := s.getAvailableAPIRelations(ctx, order.ID, order.ProductID)
+	relations, err := s.getAvailableAPIRelations(ctx, order)
 	if err != nil {
 		return fmt.Errorf("获取可用API失败: %v", err)
 	}
@@ -309,7 +308,7 @@ func (s *RetryService) executeRetry(ctx context.Context, record *model.OrderRetr
 		record.ID, record.OrderID, order.Status, order.OrderNumber))
 	fmt.Println(order, "order+@@@@@@!!!!!!!!!!!!!!!!!!1+++++++")
 	// 2. 获取可用的API关系列表
-	relations, err := s.getAvailableAPIRelations(ctx, record.OrderID, order.ProductID)
+	relations, err := s.getAvailableAPIRelations(ctx, order)
 	if err != nil {
 		logger.Error(fmt.Sprintf("【获取可用API关系失败】record_id: %d, order_id: %d, error: %v",
 			record.ID, record.OrderID, err))
@@ -359,61 +358,26 @@ func (s *RetryService) executeRetry(ctx context.Context, record *model.OrderRetr
 	logger.Info(fmt.Sprintf("【更新重试记录API信息成功】record_id: %d, order_id: %d, api_id: %d, param_id: %d",
 		record.ID, record.OrderID, record.APIID, record.ParamID))
 
-	// 7. 开启事务
-	tx := s.orderRepo.(*repository.OrderRepositoryImpl).DB().Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			logger.Error("【事务回滚】record_id: %d, order_id: %d, panic: %v",
-				record.ID, record.OrderID, r)
-		}
-	}()
-
-	// 8. 调用 RechargeService 的 SubmitOrder 方法
+	// 7. 调用 RechargeService 的 SubmitOrder 方法，提交成功后由状态机流转为充值中
 	logger.Info("【开始提交订单】record_id: %d, order_id: %d, order_number: %s",
 		record.ID, record.OrderID, order.OrderNumber)
 	if err := s.rechargeService.SubmitOrder(ctx, order, api, param); err != nil {
-		tx.Rollback()
 		logger.Error("【提交订单失败】record_id: %d, order_id: %d, error: %v",
 			record.ID, record.OrderID, err)
 		return fmt.Errorf("提交订单失败: %v", err)
 	}
-	fmt.Println("-----------")
 	logger.Info(fmt.Sprintf("【提交订单成功】record_id: %d, order_id: %d, order_number: %s",
 		record.ID, record.OrderID, order.OrderNumber))
 
-	// 9. 更新订单状态
-	logger.Info(fmt.Sprintf("【开始更新订单状态】record_id: %d, order_id: %d, order_number: %s, old_status: %d, new_status: %d",
-		record.ID, record.OrderID, order.OrderNumber, order.Status, model.OrderStatusRecharging))
-	result := tx.Model(&model.Order{}).Where("id = ?", record.OrderID).Update("status", model.OrderStatusRecharging)
-	if result.Error != nil {
-		tx.Rollback()
-		logger.Error("【更新订单状态失败】record_id: %d, order_id: %d, error: %v",
-			record.ID, record.OrderID, result.Error)
-		return fmt.Errorf("更新订单状态失败: %v", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		tx.Rollback()
-		logger.Error("【更新订单状态失败】record_id: %d, order_id: %d, 没有记录被更新",
-			record.ID, record.OrderID)
-		return fmt.Errorf("没有记录被更新")
-	}
-
-	// 10. 提交事务
-	if err := tx.Commit().Error; err != nil {
-		logger.Error("【提交事务失败】record_id: %d, order_id: %d, error: %v",
-			record.ID, record.OrderID, err)
-		return fmt.Errorf("提交事务失败: %v", err)
-	}
-
 	logger.Info("【订单状态更新成功】record_id: %d, order_id: %d, order_number: %s",
 		record.ID, record.OrderID, order.OrderNumber)
 
 	return nil
 }
 
-// getAvailableAPIRelations 获取可用的API关系列表
-func (s *RetryService) getAvailableAPIRelations(ctx context.Context, orderID int64, productID int64) ([]*model.ProductAPIRelation, error) {
+// getAvailableAPIRelations 获取可用的API关系列表，按商品路由策略排序
+func (s *RetryService) getAvailableAPIRelations(ctx context.Context, order *model.Order) ([]*model.ProductAPIRelation, error) {
+	orderID, productID := order.ID, order.ProductID
 	logger.Info("开始获取可用的API关系列表",
 		"order_id", orderID,
 		"product_id", productID,
@@ -431,6 +395,9 @@ func (s *RetryService) getAvailableAPIRelations(ctx context.Context, orderID int
 
 	// 收集已使用的API ID
 	usedAPIs := make([]int64, 0)
+	for apiID := range usedAPIIDs(order.UsedAPIs) {
+		usedAPIs = append(usedAPIs, apiID)
+	}
 	for _, record := range records {
 		// 解析 UsedAPIs 字段
 		var usedAPIList []struct {
@@ -454,8 +421,12 @@ func (s *RetryService) getAvailableAPIRelations(ctx context.Context, orderID int
 		"used_apis", usedAPIs,
 	)
 
-	// 2. 获取可用的API关系列表
-	relations, _, err := s.productAPIRelationRepo.List(ctx, productID, 0, 1, 1, 100)
+	// 2. 按路由策略获取可用通道，已使用、未启用、熔断中和不满足条件的接口会被过滤
+	exclude := make(map[int64]bool, len(usedAPIs))
+	for _, apiID := range usedAPIs {
+		exclude[apiID] = true
+	}
+	candidates, err := s.rechargeService.GetRoutingService().Route(ctx, order, exclude)
 	if err != nil {
 		logger.Error("获取API关系列表失败",
 			"error", err,
@@ -464,53 +435,14 @@ func (s *RetryService) getAvailableAPIRelations(ctx context.Context, orderID int
 		return nil, fmt.Errorf("获取API关系列表失败: %v", err)
 	}
 
-	// 3. 过滤和排序可用的API
-	availableRelations := make([]*model.ProductAPIRelation, 0)
-	for _, relation := range relations {
-		// 3.1 检查API是否已使用
-		isUsed := false
-		for _, usedAPI := range usedAPIs {
-			if relation.APIID == usedAPI {
-				isUsed = true
-				break
-			}
-		}
-		if isUsed {
-			continue
-		}
-
-		// 3.2 获取API信息
-		api, err := s.platformRepo.GetAPIByID(ctx, relation.APIID)
-		if err != nil {
-			logger.Error("获取API信息失败",
-				"error", err,
-				"api_id", relation.APIID,
-			)
-			continue
-		}
-
-		// 3.3 检查API状态
-		if api.Status != 1 { // 1 表示启用
-			logger.Info("API未启用，跳过",
-				"api_id", relation.APIID,
-				"status", api.Status,
-			)
-			continue
-		}
-
-		// 3.4 添加到可用列表
-		availableRelations = append(availableRelations, relation)
+	availableRelations := make([]*model.ProductAPIRelation, 0, len(candidates))
+	for _, candidate := range candidates {
+		availableRelations = append(availableRelations, candidate.Relation)
 	}
 
-	// 4. 按排序字段排序
-	sort.Slice(availableRelations, func(i, j int) bool {
-		return availableRelations[i].Sort < availableRelations[j].Sort
-	})
-
 	logger.Info("获取到可用的API关系列表",
 		"order_id", orderID,
 		"product_id", productID,
-		"total", len(relations),
 		"available", len(availableRelations),
 	)
 
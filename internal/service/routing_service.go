@@ -0,0 +1,670 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 内置路由策略
+const (
+	RouteStrategyPriority = "priority" // 按接口关联排序（默认）
+	RouteStrategyCheapest = "cheapest" // 成本最低优先
+	RouteStrategyFastest  = "fastest"  // 平均完成时间最短优先
+	RouteStrategyWeighted = "weighted" // 按综合评分加权分流
+)
+
+const (
+	// routeStatsTTL 通道统计缓存时间
+	routeStatsTTL = time.Minute
+	// routeStatsWindow 平均完成时间、成功率统计窗口
+	routeStatsWindow = 24 * time.Hour
+	// routeStatsSamples 平均完成时间、成功率最大样本数
+	routeStatsSamples = 200
+	// routeSuccessMinSamples 按订单统计成功率所需的最少已完结订单数，不足时取熔断器健康分
+	routeSuccessMinSamples = 10
+)
+
+// RouteWeights 综合评分权重
+type RouteWeights struct {
+	Cost     float64 `json:"cost"`     // 成本
+	Success  float64 `json:"success"`  // 成功率
+	Speed    float64 `json:"speed"`    // 完成速度
+	Location float64 `json:"location"` // 归属地匹配
+	Quota    float64 `json:"quota"`    // 剩余额度
+}
+
+// DefaultRouteWeights 默认评分权重
+func DefaultRouteWeights() RouteWeights {
+	return RouteWeights{
+		Cost:     0.35,
+		Success:  0.3,
+		Speed:    0.15,
+		Location: 0.1,
+		Quota:    0.1,
+	}
+}
+
+// RouteScores 各维度归一化评分，取值 0-1
+type RouteScores struct {
+	Cost     float64 `json:"cost"`
+	Success  float64 `json:"success"`
+	Speed    float64 `json:"speed"`
+	Location float64 `json:"location"`
+	Quota    float64 `json:"quota"`
+}
+
+// RouteCandidate 路由候选通道，即一个可用的(接口, 套餐)组合
+type RouteCandidate struct {
+	Relation       *model.ProductAPIRelation `json:"relation"`
+	API            *model.PlatformAPI        `json:"api"`
+	Param          *model.PlatformAPIParam   `json:"param"`
+	Account        *model.PlatformAccount    `json:"account,omitempty"`
+	Cost           money.Money               `json:"cost"`            // 通道成本
+	SuccessRate    float64                   `json:"success_rate"`    // 近期成功率
+	AvgDuration    time.Duration             `json:"avg_duration"`    // 平均完成时间，0 表示无数据
+	LocationMatch  bool                      `json:"location_match"`  // 是否命中允许地区
+	QuotaLimited   bool                      `json:"quota_limited"`   // 账号是否设置了限额
+	QuotaRemaining money.Money               `json:"quota_remaining"` // 账号剩余额度
+	Scores         RouteScores               `json:"scores"`
+	Score          float64                   `json:"score"` // 加权综合评分
+}
+
+// RouteStrategy 路由策略，对候选通道排序，排在前面的优先使用
+type RouteStrategy interface {
+	Rank(order *model.Order, candidates []*RouteCandidate) []*RouteCandidate
+}
+
+// RouteStrategyFunc 函数形式的路由策略
+type RouteStrategyFunc func(order *model.Order, candidates []*RouteCandidate) []*RouteCandidate
+
+// Rank 实现 RouteStrategy
+func (f RouteStrategyFunc) Rank(order *model.Order, candidates []*RouteCandidate) []*RouteCandidate {
+	return f(order, candidates)
+}
+
+var (
+	routeStrategiesMu sync.RWMutex
+	routeStrategies   = make(map[string]RouteStrategy)
+)
+
+// RegisterRouteStrategy 注册路由策略，重复注册会 panic
+func RegisterRouteStrategy(name string, strategy RouteStrategy) {
+	if name == "" || strategy == nil {
+		panic("service: invalid route strategy")
+	}
+	routeStrategiesMu.Lock()
+	defer routeStrategiesMu.Unlock()
+	if _, exists := routeStrategies[name]; exists {
+		panic(fmt.Sprintf("service: route strategy %s registered twice", name))
+	}
+	routeStrategies[name] = strategy
+}
+
+// IsRouteStrategyRegistered 判断路由策略是否已注册，空值表示默认策略
+func IsRouteStrategyRegistered(name string) bool {
+	if name == "" {
+		return true
+	}
+	routeStrategiesMu.RLock()
+	defer routeStrategiesMu.RUnlock()
+	_, exists := routeStrategies[name]
+	return exists
+}
+
+// ListRouteStrategies 列出已注册的路由策略
+func ListRouteStrategies() []string {
+	routeStrategiesMu.RLock()
+	defer routeStrategiesMu.RUnlock()
+	names := make([]string, 0, len(routeStrategies))
+	for name := range routeStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupRouteStrategy 查找路由策略，未知策略回退到默认策略
+func lookupRouteStrategy(name string) RouteStrategy {
+	routeStrategiesMu.RLock()
+	defer routeStrategiesMu.RUnlock()
+	if strategy, exists := routeStrategies[name]; exists {
+		return strategy
+	}
+	return routeStrategies[RouteStrategyPriority]
+}
+
+func init() {
+	RegisterRouteStrategy(RouteStrategyPriority, RouteStrategyFunc(rankByPriority))
+	RegisterRouteStrategy(RouteStrategyCheapest, RouteStrategyFunc(rankByCost))
+	RegisterRouteStrategy(RouteStrategyFastest, RouteStrategyFunc(rankBySpeed))
+	RegisterRouteStrategy(RouteStrategyWeighted, RouteStrategyFunc(rankByWeightedSplit))
+}
+
+// rankByPriority 按接口关联排序，排序相同时按综合评分
+func rankByPriority(_ *model.Order, candidates []*RouteCandidate) []*RouteCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Relation.Sort != candidates[j].Relation.Sort {
+			return candidates[i].Relation.Sort < candidates[j].Relation.Sort
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// rankByCost 按成本从低到高，成本相同时按综合评分
+func rankByCost(_ *model.Order, candidates []*RouteCandidate) []*RouteCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Cost != candidates[j].Cost {
+			return candidates[i].Cost < candidates[j].Cost
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// rankBySpeed 按平均完成时间从短到长，无数据的排在最后
+func rankBySpeed(_ *model.Order, candidates []*RouteCandidate) []*RouteCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := candidates[i].AvgDuration, candidates[j].AvgDuration
+		if (di == 0) != (dj == 0) {
+			return dj == 0
+		}
+		if di != dj {
+			return di < dj
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// rankByWeightedSplit 按综合评分加权随机排序，评分越高被选为首选的概率越大
+func rankByWeightedSplit(_ *model.Order, candidates []*RouteCandidate) []*RouteCandidate {
+	remaining := append([]*RouteCandidate(nil), candidates...)
+	ranked := make([]*RouteCandidate, 0, len(candidates))
+	for len(remaining) > 0 {
+		total := 0.0
+		for _, c := range remaining {
+			total += routeWeight(c)
+		}
+		pick := rand.Float64() * total
+		idx := len(remaining) - 1
+		for i, c := range remaining {
+			pick -= routeWeight(c)
+			if pick < 0 {
+				idx = i
+				break
+			}
+		}
+		ranked = append(ranked, remaining[idx])
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+	}
+	return ranked
+}
+
+// routeWeight 分流权重，保证评分为 0 的通道仍有极小概率被选中
+func routeWeight(c *RouteCandidate) float64 {
+	if c.Score < 0.01 {
+		return 0.01
+	}
+	return c.Score
+}
+
+// routeStat 带过期时间的统计缓存
+type routeStat struct {
+	value     float64
+	expiresAt time.Time
+}
+
+// RoutingService 通道路由服务，为订单挑选可用的(接口, 套餐)组合
+type RoutingService struct {
+	db                     *gorm.DB
+	productRepo            repository.ProductRepository
+	productAPIRelationRepo repository.ProductAPIRelationRepository
+	platformRepo           repository.PlatformRepository
+	spendLimiter           *AccountSpendLimiter
+	weights                RouteWeights
+
+	statsMu sync.Mutex
+	stats   map[string]routeStat // 平均完成时间缓存
+}
+
+// NewRoutingService 创建通道路由服务
+func NewRoutingService(
+	db *gorm.DB,
+	productRepo repository.ProductRepository,
+	productAPIRelationRepo repository.ProductAPIRelationRepository,
+	platformRepo repository.PlatformRepository,
+	spendLimiter *AccountSpendLimiter,
+) *RoutingService {
+	return &RoutingService{
+		db:                     db,
+		productRepo:            productRepo,
+		productAPIRelationRepo: productAPIRelationRepo,
+		platformRepo:           platformRepo,
+		spendLimiter:           spendLimiter,
+		weights:                DefaultRouteWeights(),
+		stats:                  make(map[string]routeStat),
+	}
+}
+
+// Route 返回订单可用的候选通道，按商品配置的路由策略排序
+// excludeAPIs 为已尝试过的接口ID，不参与路由
+func (s *RoutingService) Route(ctx context.Context, order *model.Order, excludeAPIs map[int64]bool) ([]*RouteCandidate, error) {
+	relations, _, err := s.productAPIRelationRepo.List(ctx, order.ProductID, 0, 1, 1, 100)
+	if err != nil {
+		return nil, fmt.Errorf("获取API关系列表失败: %v", err)
+	}
+
+	candidates := make([]*RouteCandidate, 0, len(relations))
+	for _, relation := range relations {
+		if excludeAPIs[relation.APIID] {
+			continue
+		}
+		candidate, reason := s.buildCandidate(ctx, order, relation)
+		if candidate == nil {
+			logger.Info("通道不满足路由条件，跳过",
+				"order_id", order.ID,
+				"api_id", relation.APIID,
+				"param_id", relation.ParamID,
+				"reason", reason,
+			)
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	s.score(candidates)
+
+	strategyName := s.productStrategy(ctx, order.ProductID)
+	ranked := lookupRouteStrategy(strategyName).Rank(order, candidates)
+
+	logger.Info("订单路由完成",
+		"order_id", order.ID,
+		"product_id", order.ProductID,
+		"strategy", strategyName,
+		"relations", len(relations),
+		"candidates", len(ranked),
+		"first_api_id", ranked[0].API.ID,
+	)
+	return ranked, nil
+}
+
+// SpendLimiter 获取平台账号限额控制器
+func (s *RoutingService) SpendLimiter() *AccountSpendLimiter {
+	return s.spendLimiter
+}
+
+// RouteFirst 返回订单的首选通道，没有可用通道时返回 nil
+func (s *RoutingService) RouteFirst(ctx context.Context, order *model.Order, excludeAPIs map[int64]bool) (*RouteCandidate, error) {
+	candidates, err := s.Route(ctx, order, excludeAPIs)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	return candidates[0], nil
+}
+
+// productStrategy 获取商品配置的路由策略
+func (s *RoutingService) productStrategy(ctx context.Context, productID int64) string {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil || product == nil || product.RouteStrategy == "" {
+		return RouteStrategyPriority
+	}
+	return product.RouteStrategy
+}
+
+// buildCandidate 校验通道是否可用并收集评分所需数据，不可用时返回原因
+func (s *RoutingService) buildCandidate(ctx context.Context, order *model.Order, relation *model.ProductAPIRelation) (*RouteCandidate, string) {
+	if !matchISP(relation.ISP, order.ISP) {
+		return nil, "运营商不匹配"
+	}
+
+	api, err := s.platformRepo.GetAPIByID(ctx, relation.APIID)
+	if err != nil || api == nil {
+		return nil, "接口不存在"
+	}
+	if api.Status != 1 {
+		return nil, "接口未启用"
+	}
+	if !recharge.DefaultCircuitBreaker().Available(ctx, api.ID) {
+		return nil, "接口熔断中"
+	}
+
+	param, err := s.platformRepo.GetAPIParamByID(ctx, relation.ParamID)
+	if err != nil || param == nil {
+		return nil, "接口套餐不存在"
+	}
+	if param.Status != 1 {
+		return nil, "接口套餐未启用"
+	}
+
+	locationMatch, allowed := matchLocation(param, order.AccountLocation)
+	if !allowed {
+		return nil, "归属地不在通道允许范围"
+	}
+
+	candidate := &RouteCandidate{
+		Relation:       relation,
+		API:            api,
+		Param:          param,
+		Cost:           paramCost(param),
+		SuccessRate:    1,
+		LocationMatch:  locationMatch,
+		QuotaRemaining: -1,
+	}
+
+	if api.AccountID > 0 {
+		account, err := s.platformRepo.GetAccountByID(ctx, api.AccountID)
+		if err == nil && account != nil {
+			if account.Status != 1 {
+				return nil, "平台账号未启用"
+			}
+			candidate.Account = account
+			remaining, limited := s.spendLimiter.Remaining(ctx, account)
+			if limited && remaining < candidate.Cost {
+				return nil, "平台账号额度不足"
+			}
+			candidate.QuotaLimited = limited
+			candidate.QuotaRemaining = remaining
+		}
+	}
+
+	candidate.SuccessRate = s.successRate(ctx, api.ID)
+	candidate.AvgDuration = s.avgDuration(ctx, api.ID)
+
+	return candidate, ""
+}
+
+// score 计算各候选通道的归一化评分和加权综合评分
+func (s *RoutingService) score(candidates []*RouteCandidate) {
+	minCost, minDuration := money.Zero, time.Duration(0)
+	for _, c := range candidates {
+		if c.Cost > 0 && (minCost == 0 || c.Cost < minCost) {
+			minCost = c.Cost
+		}
+		if c.AvgDuration > 0 && (minDuration == 0 || c.AvgDuration < minDuration) {
+			minDuration = c.AvgDuration
+		}
+	}
+
+	w := s.weights
+	for _, c := range candidates {
+		c.Scores.Cost = 1
+		if c.Cost > 0 && minCost > 0 {
+			c.Scores.Cost = minCost.Float64() / c.Cost.Float64()
+		}
+		c.Scores.Success = c.SuccessRate
+		// 没有完成时间数据时给中间分，避免新接口永远排在最后
+		c.Scores.Speed = 0.5
+		if c.AvgDuration > 0 {
+			c.Scores.Speed = float64(minDuration) / float64(c.AvgDuration)
+		}
+		c.Scores.Location = 0.5
+		if c.LocationMatch {
+			c.Scores.Location = 1
+		}
+		c.Scores.Quota = 1
+		if c.QuotaLimited && c.Account != nil {
+			c.Scores.Quota = quotaRatio(c.Account, c.QuotaRemaining)
+		}
+
+		c.Score = w.Cost*c.Scores.Cost +
+			w.Success*c.Scores.Success +
+			w.Speed*c.Scores.Speed +
+			w.Location*c.Scores.Location +
+			w.Quota*c.Scores.Quota
+	}
+}
+
+// avgDuration 统计接口近期成功订单的平均完成时间
+func (s *RoutingService) avgDuration(ctx context.Context, apiID int64) time.Duration {
+	key := fmt.Sprintf("duration:%d", apiID)
+	if value, ok := s.cachedStat(key); ok {
+		return time.Duration(value * float64(time.Second))
+	}
+
+	var orders []struct {
+		CreatedAt  time.Time
+		FinishTime *time.Time
+	}
+	err := s.db.WithContext(ctx).Model(&model.Order{}).
+		Select("created_at, finish_time").
+		Where("api_cur_id = ? AND status = ? AND finish_time IS NOT NULL AND created_at >= ?",
+			apiID, model.OrderStatusSuccess, time.Now().Add(-routeStatsWindow)).
+		Order("id DESC").
+		Limit(routeStatsSamples).
+		Find(&orders).Error
+	if err != nil {
+		logger.Error("统计接口平均完成时间失败", "api_id", apiID, "error", err)
+		return 0
+	}
+
+	var total float64
+	var count int
+	for _, o := range orders {
+		if o.FinishTime == nil || o.FinishTime.Before(o.CreatedAt) {
+			continue
+		}
+		total += o.FinishTime.Sub(o.CreatedAt).Seconds()
+		count++
+	}
+	avg := 0.0
+	if count > 0 {
+		avg = total / float64(count)
+	}
+
+	s.storeStat(key, avg)
+	return time.Duration(avg * float64(time.Second))
+}
+
+// successRate 统计接口近期已完结订单的成功率，部分充值计为成功；
+// 样本不足时取熔断器健康分，仍无数据时视为 1
+func (s *RoutingService) successRate(ctx context.Context, apiID int64) float64 {
+	key := fmt.Sprintf("success:%d", apiID)
+	if value, ok := s.cachedStat(key); ok {
+		return value
+	}
+
+	var statuses []model.OrderStatus
+	err := s.db.WithContext(ctx).Model(&model.Order{}).
+		Where("api_cur_id = ? AND status IN ? AND created_at >= ?", apiID,
+			[]model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusPartial, model.OrderStatusFailed, model.OrderStatusRefunded},
+			time.Now().Add(-routeStatsWindow)).
+		Order("id DESC").
+		Limit(routeStatsSamples).
+		Pluck("status", &statuses).Error
+	if err != nil {
+		logger.Error("统计接口成功率失败", "api_id", apiID, "error", err)
+	}
+
+	rate := 1.0
+	if len(statuses) >= routeSuccessMinSamples {
+		var success int
+		for _, status := range statuses {
+			if status == model.OrderStatusSuccess || status == model.OrderStatusPartial {
+				success++
+			}
+		}
+		rate = float64(success) / float64(len(statuses))
+	} else if health, err := recharge.DefaultCircuitBreaker().Health(ctx, apiID); err == nil {
+		rate = health.Score / 100
+	}
+
+	s.storeStat(key, rate)
+	return rate
+}
+
+// cachedStat 读取未过期的统计缓存
+func (s *RoutingService) cachedStat(key string) (float64, bool) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	stat, ok := s.stats[key]
+	if !ok || time.Now().After(stat.expiresAt) {
+		return 0, false
+	}
+	return stat.value, true
+}
+
+// storeStat 写入统计缓存
+func (s *RoutingService) storeStat(key string, value float64) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.stats[key] = routeStat{value: value, expiresAt: time.Now().Add(routeStatsTTL)}
+}
+
+// paramCost 通道成本，取上游套餐成本，未配置成本时退回套餐价格
+func paramCost(param *model.PlatformAPIParam) money.Money {
+	if param.Cost > 0 {
+		return param.Cost
+	}
+	return param.Price
+}
+
+// quotaRatio 剩余额度占限额的比例
+func quotaRatio(account *model.PlatformAccount, remaining money.Money) float64 {
+	limit := account.DailyLimit
+	if limit <= 0 || (account.MonthlyLimit > 0 && account.MonthlyLimit < limit) {
+		limit = account.MonthlyLimit
+	}
+	if limit <= 0 {
+		return 1
+	}
+	ratio := remaining.Float64() / limit.Float64()
+	if ratio > 1 {
+		return 1
+	}
+	if ratio < 0 {
+		return 0
+	}
+	return ratio
+}
+
+// matchISP 判断通道支持的运营商（如 "1,2,3"）是否包含订单运营商
+func matchISP(supported string, isp int) bool {
+	if isp <= 0 || strings.TrimSpace(supported) == "" {
+		return true
+	}
+	for _, item := range splitList(supported) {
+		if item == strconv.Itoa(isp) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLocation 按套餐的省份/城市黑白名单校验订单归属地
+// matched 表示命中了允许名单，allowed 为 false 表示通道不可用于该归属地
+// 归属地未知时不做限制
+func matchLocation(param *model.PlatformAPIParam, location string) (matched bool, allowed bool) {
+	province, city := parseLocation(location)
+	if province == "" {
+		return false, true
+	}
+	if containsLocation(param.ForbidProvinces, province) || containsLocation(param.ForbidCities, city) {
+		return false, false
+	}
+
+	allowProvinces, allowCities := splitList(param.AllowProvinces), splitList(param.AllowCities)
+	if len(allowProvinces) == 0 && len(allowCities) == 0 {
+		return false, true
+	}
+	if containsLocation(param.AllowProvinces, province) || containsLocation(param.AllowCities, city) {
+		return true, true
+	}
+	return false, false
+}
+
+// routeProvinces 省级行政区简称，用于从"广东深圳"、"广东省 深圳市"等归属地中拆出省份
+var routeProvinces = []string{
+	"北京", "天津", "河北", "山西", "内蒙古", "辽宁", "吉林", "黑龙江", "上海", "江苏", "浙江", "安徽",
+	"福建", "江西", "山东", "河南", "湖北", "湖南", "广东", "广西", "海南", "重庆", "四川", "贵州",
+	"云南", "西藏", "陕西", "甘肃", "青海", "宁夏", "新疆", "台湾", "香港", "澳门",
+}
+
+// regionSuffixes 地区名称后缀，较长的在前
+var regionSuffixes = []string{"维吾尔自治区", "壮族自治区", "回族自治区", "特别行政区", "自治区", "自治州", "地区", "省", "市", "盟"}
+
+// parseLocation 将归属地拆为规范化的省份和城市，直辖市的城市即省份；
+// 无法识别省份时整体作为省份，城市为空
+func parseLocation(location string) (province, city string) {
+	location = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '/', '|', '·', ',', '，':
+			return -1
+		}
+		return r
+	}, location)
+	if location == "" {
+		return "", ""
+	}
+
+	for _, name := range routeProvinces {
+		if !strings.HasPrefix(location, name) {
+			continue
+		}
+		rest := strings.TrimPrefix(location, name)
+		for _, suffix := range regionSuffixes {
+			if strings.HasPrefix(rest, suffix) {
+				rest = strings.TrimPrefix(rest, suffix)
+				break
+			}
+		}
+		city = normalizeRegion(rest)
+		if city == "" && (name == "北京" || name == "天津" || name == "上海" || name == "重庆") {
+			city = name
+		}
+		return name, city
+	}
+	return normalizeRegion(location), ""
+}
+
+// normalizeRegion 去除地区名称的行政区后缀，如"广东省"为"广东"、"深圳市"为"深圳"
+func normalizeRegion(name string) string {
+	name = strings.TrimSpace(name)
+	for _, suffix := range regionSuffixes {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			return trimmed
+		}
+	}
+	return name
+}
+
+// containsLocation 判断逗号分隔的地区列表中是否有与规范化后的地区名完全相同的项
+func containsLocation(list string, region string) bool {
+	if region == "" {
+		return false
+	}
+	for _, item := range splitList(list) {
+		if normalizeRegion(item) == region {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 拆分逗号分隔的列表，兼容中文逗号并去除空项
+func splitList(list string) []string {
+	list = strings.ReplaceAll(list, "，", ",")
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
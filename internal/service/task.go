@@ -9,6 +9,7 @@ import (
 	"recharge-go/internal/service/platform"
 	"recharge-go/internal/utils"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 	"strings"
 	"sync"
 	"time"
@@ -804,10 +805,10 @@ func (s *TaskService) createNewOrder(order *platform.PlatformOrder, cfg *model.T
 	orderRecord := &model.Order{
 		Mobile:            order.AccountNum,
 		ProductID:         productObject.ID,
-		Denom:             order.FaceValue,
-		OfficialPayment:   order.SettlementAmount,
-		UserQuotePayment:  order.SettlementAmount,
-		UserPayment:       order.SettlementAmount,
+		Denom:             money.FromFloat(order.FaceValue).Round(),
+		OfficialPayment:   money.FromFloat(order.SettlementAmount).Round(),
+		UserQuotePayment:  money.FromFloat(order.SettlementAmount).Round(),
+		UserPayment:       money.FromFloat(order.SettlementAmount).Round(),
 		Price:             productObject.Price,
 		Status:            initialStatus,
 		IsDel:             0,
@@ -886,10 +887,10 @@ func (s *TaskService) handleMatchedOrder(order *platform.PlatformOrder, cfg *mod
 	orderRecord := &model.Order{
 		Mobile:            order.AccountNum,
 		ProductID:         productObject.ID,
-		Denom:             order.FaceValue,
-		OfficialPayment:   order.SettlementAmount,
-		UserQuotePayment:  order.SettlementAmount,
-		UserPayment:       order.SettlementAmount,
+		Denom:             money.FromFloat(order.FaceValue).Round(),
+		OfficialPayment:   money.FromFloat(order.SettlementAmount).Round(),
+		UserQuotePayment:  money.FromFloat(order.SettlementAmount).Round(),
+		UserPayment:       money.FromFloat(order.SettlementAmount).Round(),
 		Price:             productObject.Price,
 		Status:            model.OrderStatusPendingRecharge,
 		IsDel:             0,
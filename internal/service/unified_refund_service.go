@@ -7,6 +7,7 @@ import (
 	"recharge-go/internal/repository"
 	"recharge-go/pkg/lock"
 	"recharge-go/pkg/logger"
+	"recharge-go/pkg/money"
 
 	"gorm.io/gorm"
 )
@@ -25,7 +26,7 @@ const (
 type RefundRequest struct {
 	UserID    int64       `json:"user_id"`    // 用户ID
 	OrderID   int64       `json:"order_id"`   // 订单ID
-	Amount    float64     `json:"amount"`     // 退款金额
+	Amount    money.Money `json:"amount"`     // 退款金额
 	Remark    string      `json:"remark"`     // 退款备注
 	Operator  string      `json:"operator"`   // 操作员
 	Type      RefundType  `json:"type"`       // 退款类型
@@ -35,11 +36,11 @@ type RefundRequest struct {
 
 // RefundResponse 退款响应
 type RefundResponse struct {
-	Success       bool    `json:"success"`        // 是否成功
-	Message       string  `json:"message"`        // 消息
-	RefundAmount  float64 `json:"refund_amount"`  // 实际退款金额
-	BalanceAfter  float64 `json:"balance_after"`  // 退款后余额
-	AlreadyRefund bool    `json:"already_refund"` // 是否已退款（幂等性）
+	Success       bool        `json:"success"`        // 是否成功
+	Message       string      `json:"message"`        // 消息
+	RefundAmount  money.Money `json:"refund_amount"`  // 实际退款金额
+	BalanceAfter  money.Money `json:"balance_after"`  // 退款后余额
+	AlreadyRefund bool        `json:"already_refund"` // 是否已退款（幂等性）
 }
 
 // UnifiedRefundService 统一退款服务
@@ -127,15 +128,23 @@ func (s *UnifiedRefundService) validateRefundRequest(req *RefundRequest) error {
 func (s *UnifiedRefundService) processUserRefund(ctx context.Context, req *RefundRequest) (*RefundResponse, error) {
 	logger.Info("处理用户余额退款", "user_id", req.UserID, "order_id", req.OrderID, "amount", req.Amount)
 
-	// 检查是否已退款（幂等性）
-	alreadyRefund, err := s.checkAlreadyRefund(ctx, req.UserID, req.OrderID, model.BalanceStyleRefund)
-	if err != nil {
+	// 执行退款，同一订单的退款凭证幂等键相同，重复或并发的退款不会再次入账
+	var refunded bool
+	refundErr := s.inTx(ctx, req.Tx, func(tx *gorm.DB) error {
+		var err error
+		refunded, err = s.balanceService.refundWithTx(ctx, tx, req.UserID, req.Amount, req.OrderID, req.Remark, req.Operator)
+		return err
+	})
+
+	if refundErr != nil {
+		logger.Error("用户余额退款失败", "user_id", req.UserID, "order_id", req.OrderID, "error", refundErr)
 		return &RefundResponse{
 			Success: false,
-			Message: "检查退款状态失败",
-		}, err
+			Message: "退款失败: " + refundErr.Error(),
+		}, refundErr
 	}
-	if alreadyRefund {
+
+	if !refunded {
 		logger.Info("订单已退款，跳过重复操作", "user_id", req.UserID, "order_id", req.OrderID)
 		return &RefundResponse{
 			Success:       true,
@@ -144,26 +153,8 @@ func (s *UnifiedRefundService) processUserRefund(ctx context.Context, req *Refun
 		}, nil
 	}
 
-	// 执行退款
-	var refundErr error
-	if req.Tx != nil {
-		// 使用传入的事务
-		refundErr = s.balanceService.RefundWithTx(ctx, req.Tx, req.UserID, req.Amount, req.OrderID, req.Remark, req.Operator)
-	} else {
-		// 创建新事务
-		refundErr = s.balanceService.Refund(ctx, req.UserID, req.Amount, req.OrderID, req.Remark, req.Operator)
-	}
-
-	if refundErr != nil {
-		logger.Error("用户余额退款失败", "user_id", req.UserID, "order_id", req.OrderID, "error", refundErr)
-		return &RefundResponse{
-			Success: false,
-			Message: "退款失败: " + refundErr.Error(),
-		}, refundErr
-	}
-
 	// 获取退款后余额 - 在事务内查询确保数据一致性
-	var balanceAfter float64
+	var balanceAfter money.Money
 	if req.Tx != nil {
 		// 使用传入的事务查询最新余额
 		var user model.User
@@ -206,15 +197,23 @@ func (s *UnifiedRefundService) processPlatformRefund(ctx context.Context, req *R
 		}, errors.New("平台账户余额服务未初始化")
 	}
 
-	// 检查是否已退款（幂等性）
-	alreadyRefund, err := s.checkAlreadyRefund(ctx, req.UserID, req.OrderID, model.BalanceStyleRefund)
-	if err != nil {
+	// 执行退款，同一订单的退款凭证幂等键相同，重复或并发的退款不会再次入账
+	var refunded bool
+	refundErr := s.inTx(ctx, req.Tx, func(tx *gorm.DB) error {
+		var err error
+		refunded, err = s.platformAccountBalanceService.refundBalanceWithTx(ctx, tx, req.UserID, req.Amount, req.OrderID, req.Remark)
+		return err
+	})
+
+	if refundErr != nil {
+		logger.Error("平台账号退款失败", "user_id", req.UserID, "order_id", req.OrderID, "account_id", *req.AccountID, "error", refundErr)
 		return &RefundResponse{
 			Success: false,
-			Message: "检查退款状态失败",
-		}, err
+			Message: "退款失败: " + refundErr.Error(),
+		}, refundErr
 	}
-	if alreadyRefund {
+
+	if !refunded {
 		logger.Info("订单已退款，跳过重复操作", "user_id", req.UserID, "order_id", req.OrderID)
 		return &RefundResponse{
 			Success:       true,
@@ -223,21 +222,8 @@ func (s *UnifiedRefundService) processPlatformRefund(ctx context.Context, req *R
 		}, nil
 	}
 
-	// 执行退款
-	var refundErr error
-	// 注意：RefundBalance方法现在不再接受事务参数，内部自己管理事务
-	refundErr = s.platformAccountBalanceService.RefundBalance(ctx, req.UserID, req.Amount, req.OrderID, req.Remark)
-
-	if refundErr != nil {
-		logger.Error("平台账号退款失败", "user_id", req.UserID, "order_id", req.OrderID, "account_id", *req.AccountID, "error", refundErr)
-		return &RefundResponse{
-			Success: false,
-			Message: "退款失败: " + refundErr.Error(),
-		}, refundErr
-	}
-
 	// 获取退款后余额 - 在事务内查询确保数据一致性
-	var balanceAfter float64
+	var balanceAfter money.Money
 	if req.Tx != nil {
 		// 使用传入的事务查询最新余额
 		var user model.User
@@ -267,17 +253,12 @@ func (s *UnifiedRefundService) processPlatformRefund(ctx context.Context, req *R
 	}, nil
 }
 
-// checkAlreadyRefund 检查是否已退款
-func (s *UnifiedRefundService) checkAlreadyRefund(ctx context.Context, userID, orderID int64, style int) (bool, error) {
-	var count int64
-	err := s.db.Model(&model.BalanceLog{}).
-		Where("user_id = ? AND order_id = ? AND style = ?", userID, orderID, style).
-		Count(&count).Error
-	if err != nil {
-		logger.Error("检查退款记录失败", "user_id", userID, "order_id", orderID, "error", err)
-		return false, err
+// inTx 在调用方事务内执行，未传入事务时开启新事务
+func (s *UnifiedRefundService) inTx(ctx context.Context, tx *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if tx != nil {
+		return fn(tx)
 	}
-	return count > 0, nil
+	return s.db.WithContext(ctx).Transaction(fn)
 }
 
 // BatchRefund 批量退款
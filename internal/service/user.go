@@ -259,18 +259,19 @@ func (s *UserService) GetUserList(ctx context.Context, req *model.UserListReques
 	var userResponses []model.UserResponse
 	for _, user := range users {
 		userResponses = append(userResponses, model.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Nickname:  user.Nickname,
-			Phone:     user.Phone,
-			Email:     user.Email,
-			Avatar:    user.Avatar,
-			Status:    user.Status,
-			Credit:    user.Credit,
-			Balance:   user.Balance,
-			LastLogin: user.LastLogin,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:            user.ID,
+			Username:      user.Username,
+			Nickname:      user.Nickname,
+			Phone:         user.Phone,
+			Email:         user.Email,
+			Avatar:        user.Avatar,
+			Status:        user.Status,
+			Credit:        user.Credit,
+			Balance:       user.Balance,
+			FrozenBalance: user.FrozenBalance,
+			LastLogin:     user.LastLogin,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
 		})
 	}
 
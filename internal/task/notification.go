@@ -3,57 +3,122 @@ package task
 import (
 	"context"
 	"encoding/json"
-	model "recharge-go/internal/model/notification"
+	"errors"
+	"fmt"
+	"recharge-go/internal/repository"
+	notificationRepo "recharge-go/internal/repository/notification"
 	"recharge-go/internal/service"
 	svc "recharge-go/internal/service/notification"
 	"recharge-go/pkg/logger"
 	"recharge-go/pkg/queue"
 	"strings"
+	"sync"
 	"time"
+
+	orderModel "recharge-go/internal/model"
+	model "recharge-go/internal/model/notification"
+
+	"gorm.io/gorm"
 )
 
+// NotificationTaskConfig 通知任务配置
+type NotificationTaskConfig struct {
+	WorkerCount   int                 // 工作协程数量
+	BatchSize     int                 // 重试调度每轮最多分发的通知数
+	ScanInterval  time.Duration       // 重试调度扫描间隔
+	StaleTimeout  time.Duration       // 待处理或处理中超过该时长视为丢失，转为立即重试
+	RetrySchedule model.RetrySchedule // 客户与通知模板均未配置重试表时使用的默认重试表
+}
+
+// DefaultNotificationTaskConfig 默认通知任务配置
+func DefaultNotificationTaskConfig() NotificationTaskConfig {
+	return NotificationTaskConfig{
+		WorkerCount:   5,
+		BatchSize:     100,
+		ScanInterval:  5 * time.Second,
+		StaleTimeout:  10 * time.Minute,
+		RetrySchedule: model.MustParseRetrySchedule(model.DefaultRetrySchedule),
+	}
+}
+
 // NotificationTask 通知任务处理器
+// 新通知由队列分发到工作协程，投递失败的通知按重试表记录下次投递时间，由重试调度到期后重新分发；
+// 重试表依次取客户 API 密钥、通知模板上的配置，均未配置时使用默认重试表，用尽后通知标记为已放弃
 type NotificationTask struct {
 	notificationService svc.NotificationService
 	platformService     *service.PlatformService
+	apiKeyRepo          repository.ExternalAPIKeyRepository
+	templateRepo        *notificationRepo.TemplateRepository
 	queue               queue.Queue
 	queueName           string
-	maxRetries          int
-	batchSize           int
-	workerCount         int                            // 工作协程数量
+	config              NotificationTaskConfig
 	jobChan             chan *model.NotificationRecord // 任务通道
+	stop                chan struct{}
+	stopOnce            sync.Once
 }
 
 // NewNotificationTask 创建通知任务处理器
 func NewNotificationTask(
 	notificationService svc.NotificationService,
 	platformService *service.PlatformService,
+	apiKeyRepo repository.ExternalAPIKeyRepository,
+	templateRepo *notificationRepo.TemplateRepository,
 	queue queue.Queue,
-	maxRetries int,
+	config NotificationTaskConfig,
 ) *NotificationTask {
+	defaults := DefaultNotificationTaskConfig()
+	if config.WorkerCount <= 0 {
+		config.WorkerCount = defaults.WorkerCount
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.ScanInterval <= 0 {
+		config.ScanInterval = defaults.ScanInterval
+	}
+	if config.StaleTimeout <= 0 {
+		config.StaleTimeout = defaults.StaleTimeout
+	}
+	if len(config.RetrySchedule) == 0 {
+		config.RetrySchedule = defaults.RetrySchedule
+	}
 	return &NotificationTask{
 		notificationService: notificationService,
 		platformService:     platformService,
+		apiKeyRepo:          apiKeyRepo,
+		templateRepo:        templateRepo,
 		queue:               queue,
 		queueName:           "notification_queue",
-		maxRetries:          maxRetries,
-		batchSize:           10,                                        // 每次处理的通知数量
-		workerCount:         1,                                         // 默认5个工作协程
-		jobChan:             make(chan *model.NotificationRecord, 100), // 任务通道缓冲区大小100
+		config:              config,
+		jobChan:             make(chan *model.NotificationRecord, config.WorkerCount*20),
+		stop:                make(chan struct{}),
 	}
 }
 
 // Start 启动通知任务处理器
 func (t *NotificationTask) Start(ctx context.Context) error {
-	logger.Info("starting notification task processor")
+	logger.Info("starting notification task processor",
+		"worker_count", t.config.WorkerCount,
+		"retry_schedule", t.config.RetrySchedule.String(),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-t.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
 	// 启动工作协程池
-	for i := 0; i < t.workerCount; i++ {
+	for i := 0; i < t.config.WorkerCount; i++ {
 		go t.worker(ctx, i)
 	}
 
-	// 启动重试任务（可选，混合模式下重试直接由worker处理）
-	// go t.startRetryTask(ctx)
+	// 启动重试调度
+	go t.startRetryTask(ctx)
 
 	for {
 		select {
@@ -125,47 +190,52 @@ func (t *NotificationTask) worker(ctx context.Context, id int) {
 
 // processSingleNotification 处理单个通知
 func (t *NotificationTask) processSingleNotification(ctx context.Context, record *model.NotificationRecord, workerID int) error {
-	// 处理前查数据库最新状态，只有status=1才处理
+	// 以数据库最新状态为准，抢占成功才处理，队列重复投递或重试调度重复分发的通知会被跳过
 	dbRecord, err := t.notificationService.GetNotification(ctx, record.ID)
 	if err != nil {
-		logger.Error("获取通知记录失败",
-			"error", err,
-			"notification_id", record.ID,
-			"order_id", record.OrderID,
-			"retry_count", record.RetryCount,
-			"platform_code", record.PlatformCode,
-		)
-		return err
+		return fmt.Errorf("获取通知记录失败: %v", err)
+	}
+	claimed, err := t.notificationService.ClaimNotification(ctx, dbRecord)
+	if err != nil {
+		return fmt.Errorf("抢占通知失败: %v", err)
 	}
-	if dbRecord.Status != 1 {
-		logger.Info("通知已被处理，跳过", "notification_id", record.ID, "order_id", record.OrderID, "status", dbRecord.Status)
+	if !claimed {
+		logger.Info("通知已被处理或未到重试时间，跳过", "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID, "status", dbRecord.Status)
 		return nil
 	}
+
 	// 获取订单信息
 	order, err := t.platformService.GetOrder(ctx, dbRecord.OrderID)
 	if err != nil {
-		logger.Error("获取订单信息失败",
-			"error", err,
-			"notification_id", dbRecord.ID,
-			"order_id", dbRecord.OrderID,
-			"retry_count", dbRecord.RetryCount,
-			"platform_code", dbRecord.PlatformCode,
-		)
-		// 如果是 record not found，可以直接标记为失败，避免无意义重试
-		if strings.Contains(err.Error(), "record not found") {
-			err2 := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, 3)
-			if err2 != nil {
-				logger.Error("更新通知状态失败", "error", err2, "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID, "retry_count", dbRecord.RetryCount, "platform_code", dbRecord.PlatformCode)
-			}
-			logger.Info("订单不存在，通知已标记为失败", "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID, "retry_count", dbRecord.RetryCount, "platform_code", dbRecord.PlatformCode)
+		// 订单不存在时重试没有意义，直接放弃
+		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "record not found") {
+			return t.giveUp(ctx, dbRecord, dbRecord.RetryCount, fmt.Sprintf("订单不存在: %v", err))
+		}
+		return t.retryLater(ctx, dbRecord, t.config.RetrySchedule, fmt.Errorf("获取订单信息失败: %v", err))
+	}
+	// 拆单子订单不通知下游，由父订单汇总后统一通知
+	if order.IsSplitChild() {
+		if err := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, model.StatusSuccess); err != nil {
+			logger.Error("更新通知状态失败", "error", err, "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID)
+		}
+		logger.Info("拆单子订单不通知下游，跳过", "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID, "parent_order_number", order.ApartOrderNumber)
+		return nil
+	}
+
+	schedule := t.retrySchedule(ctx, dbRecord, order)
+
+	// 首次投递按重试表首项延迟
+	if dbRecord.Status == model.StatusPending {
+		if due := dbRecord.CreatedAt.Add(schedule.InitialDelay()); time.Now().Before(due) {
+			return t.notificationService.ScheduleRetry(ctx, dbRecord.ID, dbRecord.RetryCount, due, "")
 		}
-		return err
 	}
+
 	// 发送通知
-	if err := t.platformService.SendNotification(ctx, order); err != nil {
-		// 记录通知发送失败的详细错误信息
+	if err := t.send(ctx, dbRecord, order); err != nil {
 		logger.Error("通知发送失败",
 			"error", err,
+			"worker_id", workerID,
 			"notification_id", dbRecord.ID,
 			"order_id", dbRecord.OrderID,
 			"order_number", order.OrderNumber,
@@ -175,171 +245,121 @@ func (t *NotificationTask) processSingleNotification(ctx context.Context, record
 			"callback_url", order.PlatformCallbackURL,
 		)
 
-		// 业务终态错误关键字
+		// 业务终态错误关键字，重试不会成功
 		if strings.Contains(err.Error(), "此订单已做单失败") {
-			logger.Error("遇到终态业务错误，不再重试",
-				"error", err,
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"platform_code", dbRecord.PlatformCode,
-				"notification_type", dbRecord.NotificationType,
-				"retry_count", dbRecord.RetryCount,
-			)
-			// 标记为失败
-			if err := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, 3); err != nil {
-				logger.Error("更新通知状态失败", "error", err, "notification_id", dbRecord.ID, "order_id", dbRecord.OrderID, "order_number", order.OrderNumber, "retry_count", dbRecord.RetryCount, "platform_code", dbRecord.PlatformCode)
-			}
-			return nil
-		}
-		// 如果处理失败且未超过最大重试次数，则重试
-		if dbRecord.RetryCount < t.maxRetries {
-			// 使用指数退避策略计算重试间隔
-			retryInterval := time.Duration(1<<uint(dbRecord.RetryCount)) * time.Minute
-			nextRetryTime := time.Now().Add(retryInterval)
-			logger.Info("准备重试通知",
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"retry_count", dbRecord.RetryCount,
-				"next_retry_time", nextRetryTime,
-				"retry_interval", retryInterval,
-				"platform_code", dbRecord.PlatformCode,
-			)
-			// 更新通知记录状态和重试时间
-			if err := t.notificationService.RetryFailedNotification(ctx, dbRecord.ID); err != nil {
-				logger.Error("重试通知失败",
-					"error", err,
-					"notification_id", dbRecord.ID,
-					"order_id", dbRecord.OrderID,
-					"order_number", order.OrderNumber,
-					"retry_count", dbRecord.RetryCount,
-					"platform_code", dbRecord.PlatformCode,
-				)
-				return err
-			}
-			// 重新入队
-			if err := t.queue.Push(ctx, t.queueName, dbRecord); err != nil {
-				logger.Error("重新入队失败",
-					"error", err,
-					"notification_id", dbRecord.ID,
-					"order_id", dbRecord.OrderID,
-					"order_number", order.OrderNumber,
-					"retry_count", dbRecord.RetryCount,
-					"platform_code", dbRecord.PlatformCode,
-					"queue_name", t.queueName,
-				)
-				return err
-			}
-			logger.Info("通知已重新入队",
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"retry_count", dbRecord.RetryCount,
-				"queue_name", t.queueName,
-				"next_retry_time", nextRetryTime,
-				"platform_code", dbRecord.PlatformCode,
-			)
-		} else {
-			logger.Info("通知已达到最大重试次数，不再重试",
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"retry_count", dbRecord.RetryCount,
-				"max_retries", t.maxRetries,
-				"platform_code", dbRecord.PlatformCode,
-			)
-			// 更新通知状态为失败
-			if err := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, 3); err != nil {
-				logger.Error("更新通知状态失败",
-					"error", err,
-					"notification_id", dbRecord.ID,
-					"order_id", dbRecord.OrderID,
-					"order_number", order.OrderNumber,
-					"retry_count", dbRecord.RetryCount,
-					"platform_code", dbRecord.PlatformCode,
-				)
-				return err
-			}
-			logger.Info("通知已从队列中移除",
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"retry_count", dbRecord.RetryCount,
-				"queue_name", t.queueName,
-				"platform_code", dbRecord.PlatformCode,
-			)
-		}
-	} else {
-		logger.Info("发送通知成功",
-			"notification_id", dbRecord.ID,
-			"order_id", dbRecord.OrderID,
-			"order_number", order.OrderNumber,
-			"platform_code", dbRecord.PlatformCode,
-			"notification_type", dbRecord.NotificationType,
-			"retry_count", dbRecord.RetryCount,
-		)
-		// 更新通知状态为成功
-		if err := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, 3); err != nil {
-			logger.Error("更新通知状态失败",
-				"error", err,
-				"notification_id", dbRecord.ID,
-				"order_id", dbRecord.OrderID,
-				"order_number", order.OrderNumber,
-				"retry_count", dbRecord.RetryCount,
-				"platform_code", dbRecord.PlatformCode,
-			)
-			return err
+			return t.giveUp(ctx, dbRecord, dbRecord.RetryCount+1, err.Error())
 		}
-		logger.Info("通知处理成功",
-			"notification_id", dbRecord.ID,
-			"order_id", dbRecord.OrderID,
-			"order_number", order.OrderNumber,
-			"platform_code", dbRecord.PlatformCode,
-			"notification_type", dbRecord.NotificationType,
-			"retry_count", dbRecord.RetryCount,
-		)
+		return t.retryLater(ctx, dbRecord, schedule, err)
+	}
+
+	// 更新通知状态为成功
+	if err := t.notificationService.UpdateNotificationStatus(ctx, dbRecord.ID, model.StatusSuccess); err != nil {
+		return fmt.Errorf("更新通知状态失败: %v", err)
 	}
+	logger.Info("通知处理成功",
+		"worker_id", workerID,
+		"notification_id", dbRecord.ID,
+		"order_id", dbRecord.OrderID,
+		"order_number", order.OrderNumber,
+		"platform_code", dbRecord.PlatformCode,
+		"notification_type", dbRecord.NotificationType,
+		"retry_count", dbRecord.RetryCount,
+	)
 	return nil
 }
 
-// processNotifications 处理通知
-func (t *NotificationTask) processNotifications(ctx context.Context) (bool, error) {
-	// 批量获取待处理的通知
-	records, _, err := t.notificationService.ListNotifications(ctx, map[string]interface{}{
-		"status": 1, // 待处理状态
-	}, 1, t.batchSize)
+// send 发送通知并保存投递记录，未发出请求且没有错误的（如订单状态无需通知）不记录
+func (t *NotificationTask) send(ctx context.Context, record *model.NotificationRecord, order *orderModel.Order) error {
+	attempt := &model.DeliveryAttempt{
+		NotificationID: record.ID,
+		OrderID:        record.OrderID,
+		Attempt:        record.RetryCount + 1,
+	}
+	err := t.platformService.SendNotification(svc.WithDeliveryAttempt(ctx, attempt), order)
 	if err != nil {
-		logger.Error("获取待处理通知失败", "error", err)
-		return false, err
+		attempt.Error = err.Error()
+	}
+	attempt.Success = err == nil
+	if attempt.URL != "" || err != nil {
+		if recordErr := t.notificationService.RecordDeliveryAttempt(ctx, attempt); recordErr != nil {
+			logger.Error("保存通知投递记录失败", "error", recordErr, "notification_id", record.ID, "order_id", record.OrderID)
+		}
 	}
+	return err
+}
 
-	if len(records) == 0 {
-		// logger.Info("没有待处理的通知") // 可以注释掉或降低为 debug 级别
-		return false, nil
+// retryLater 按重试表安排下次投递，重试表已用尽时放弃通知
+func (t *NotificationTask) retryLater(ctx context.Context, record *model.NotificationRecord, schedule model.RetrySchedule, cause error) error {
+	failures := record.RetryCount + 1
+	delay, ok := schedule.Next(failures)
+	if !ok {
+		return t.giveUp(ctx, record, failures, cause.Error())
 	}
 
-	// 分发任务到工作协程
-	for _, record := range records {
-		select {
-		case t.jobChan <- record:
-			logger.Info("通知已分发到工作协程",
-				"notification_id", record.ID,
-				"order_id", record.OrderID,
-				"retry_count", record.RetryCount,
-				"platform_code", record.PlatformCode,
-			)
-		case <-ctx.Done():
-			return false, ctx.Err()
+	nextRetryTime := time.Now().Add(delay)
+	if err := t.notificationService.ScheduleRetry(ctx, record.ID, failures, nextRetryTime, cause.Error()); err != nil {
+		return fmt.Errorf("安排通知重试失败: %v", err)
+	}
+	logger.Info("通知投递失败，等待重试",
+		"notification_id", record.ID,
+		"order_id", record.OrderID,
+		"retry_count", failures,
+		"retry_interval", delay,
+		"next_retry_time", nextRetryTime,
+		"platform_code", record.PlatformCode,
+	)
+	return nil
+}
+
+// giveUp 放弃通知，等待后台手动重试
+func (t *NotificationTask) giveUp(ctx context.Context, record *model.NotificationRecord, retryCount int, reason string) error {
+	if err := t.notificationService.GiveUpNotification(ctx, record.ID, retryCount, reason); err != nil {
+		return fmt.Errorf("标记通知放弃失败: %v", err)
+	}
+	logger.Error("通知已放弃",
+		"notification_id", record.ID,
+		"order_id", record.OrderID,
+		"retry_count", retryCount,
+		"platform_code", record.PlatformCode,
+		"reason", reason,
+	)
+	return nil
+}
+
+// retrySchedule 获取通知的重试表，依次取客户 API 密钥、通知模板上的配置，均未配置或格式错误时使用默认重试表
+func (t *NotificationTask) retrySchedule(ctx context.Context, record *model.NotificationRecord, order *orderModel.Order) model.RetrySchedule {
+	if t.apiKeyRepo != nil && order.CustomerID > 0 {
+		keys, _, err := t.apiKeyRepo.GetByUserID(order.CustomerID, 0, 1)
+		if err != nil {
+			logger.Error("获取客户API密钥失败", "error", err, "customer_id", order.CustomerID)
+		} else if len(keys) > 0 && keys[0].NotifyRetrySchedule != "" {
+			schedule, err := model.ParseRetrySchedule(keys[0].NotifyRetrySchedule)
+			if err == nil {
+				return schedule
+			}
+			logger.Error("客户通知重试表格式错误", "error", err, "customer_id", order.CustomerID)
+		}
+	}
+
+	if t.templateRepo != nil {
+		template, err := t.templateRepo.GetByPlatformAndType(ctx, record.PlatformCode, record.NotificationType)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("获取通知模板失败", "error", err, "platform_code", record.PlatformCode, "notification_type", record.NotificationType)
+		} else if err == nil && template.RetrySchedule != "" {
+			schedule, err := model.ParseRetrySchedule(template.RetrySchedule)
+			if err == nil {
+				return schedule
+			}
+			logger.Error("通知模板重试表格式错误", "error", err, "template_id", template.ID)
 		}
 	}
 
-	return true, nil
+	return t.config.RetrySchedule
 }
 
-// startRetryTask 启动重试任务
+// startRetryTask 重试调度，按间隔将已到重试时间的通知分发到工作协程
 func (t *NotificationTask) startRetryTask(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(t.config.ScanInterval)
 	defer ticker.Stop()
 
 	for {
@@ -347,38 +367,44 @@ func (t *NotificationTask) startRetryTask(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// 获取所有待重试的通知记录
-			records, _, err := t.notificationService.ListNotifications(ctx, map[string]interface{}{
-				"status": 4, // 失败状态
-			}, 1, t.batchSize)
-			if err != nil {
-				logger.Error("get failed notifications failed", "error", err)
-				continue
+			if err := t.dispatchDueNotifications(ctx); err != nil {
+				logger.Error("分发待重试通知失败", "error", err)
 			}
+		}
+	}
+}
 
-			// 分发重试任务到工作协程
-			for _, record := range records {
-				if record.RetryCount < t.maxRetries {
-					select {
-					case t.jobChan <- record:
-						logger.Info("重试通知已分发到工作协程",
-							"notification_id", record.ID,
-							"order_id", record.OrderID,
-							"retry_count", record.RetryCount,
-							"platform_code", record.PlatformCode,
-						)
-					case <-ctx.Done():
-						return
-					}
-				}
-			}
+// dispatchDueNotifications 分发一轮已到重试时间的通知
+func (t *NotificationTask) dispatchDueNotifications(ctx context.Context) error {
+	// 队列消息丢失或工作协程中途退出的通知转为立即重试
+	if n, err := t.notificationService.ResetStaleNotifications(ctx, time.Now().Add(-t.config.StaleTimeout)); err != nil {
+		logger.Error("恢复滞留通知失败", "error", err)
+	} else if n > 0 {
+		logger.Info("滞留通知已转为立即重试", "count", n)
+	}
+
+	records, err := t.notificationService.GetDueNotifications(ctx, t.config.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		select {
+		case t.jobChan <- record:
+			logger.Info("重试通知已分发到工作协程",
+				"notification_id", record.ID,
+				"order_id", record.OrderID,
+				"retry_count", record.RetryCount,
+				"platform_code", record.PlatformCode,
+			)
+		case <-ctx.Done():
+			return nil
 		}
 	}
+	return nil
 }
 
-// Stop 停止通知任务处理器
+// Stop 停止通知任务处理器，工作协程与重试调度随之退出
 func (t *NotificationTask) Stop() {
-	// 清理资源
-	close(t.jobChan)
+	t.stopOnce.Do(func() { close(t.stop) })
 	logger.Info("notification task processor stopped")
 }
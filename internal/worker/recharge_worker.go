@@ -34,42 +34,16 @@ func (w *RechargeWorker) Stop() {
 	close(w.stopChan)
 }
 
-// processQueue 处理队列
+// processQueue 消费充值任务队列，收到停止信号后退出
 func (w *RechargeWorker) processQueue() {
-	ctx := context.Background()
-	for {
-		select {
-		case <-w.stopChan:
-			return
-		default:
-			// 从队列中获取订单ID
-			orderID, err := w.rechargeService.PopFromRechargeQueue(ctx)
-			if err != nil {
-				logger.Error("从队列获取订单失败: %v", err)
-				time.Sleep(time.Second)
-				continue
-			}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-w.stopChan
+		cancel()
+	}()
 
-			// 获取订单信息
-			order, err := w.rechargeService.GetOrderByID(ctx, orderID)
-			if err != nil {
-				logger.Error("获取订单信息失败, order_id: %d, error: %v", orderID, err)
-				continue
-			}
-
-			// 处理充值任务
-			if err := w.rechargeService.ProcessRechargeTask(ctx, order); err != nil {
-				logger.Error("处理充值任务失败, order_id: %d, error: %v", orderID, err)
-				// 如果处理失败，将订单重新放入队列
-				if err := w.rechargeService.PushToRechargeQueue(ctx, orderID); err != nil {
-					logger.Error("重新放入队列失败, order_id: %d, error: %v", orderID, err)
-				}
-				time.Sleep(time.Second)
-				continue
-			}
-
-			logger.Info("充值任务处理完成, order_id: %d", orderID)
-		}
+	if err := service.NewRechargeWorker(w.rechargeService, 0).Start(ctx); err != nil {
+		logger.Error("充值队列消费失败", "error", err)
 	}
 }
 
@@ -0,0 +1,3 @@
+-- 移除products表的route_strategy字段
+ALTER TABLE `products`
+DROP COLUMN `route_strategy`;
@@ -0,0 +1,3 @@
+-- 为products表添加通道路由策略字段
+ALTER TABLE `products`
+ADD COLUMN `route_strategy` varchar(20) NOT NULL DEFAULT '' COMMENT '通道路由策略：priority/cheapest/fastest/weighted' AFTER `is_api`;
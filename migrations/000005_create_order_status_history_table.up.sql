@@ -0,0 +1,13 @@
+-- 创建订单状态流转记录表
+CREATE TABLE IF NOT EXISTS `order_status_history` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `order_id` bigint(20) NOT NULL COMMENT '订单ID',
+  `from_status` bigint(20) NOT NULL COMMENT '原状态',
+  `to_status` bigint(20) NOT NULL COMMENT '新状态',
+  `actor` varchar(64) DEFAULT NULL COMMENT '操作人',
+  `reason` varchar(255) DEFAULT NULL COMMENT '变更原因',
+  `source` varchar(32) DEFAULT NULL COMMENT '变更来源',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_order_status_history_order_id` (`order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单状态流转记录表';
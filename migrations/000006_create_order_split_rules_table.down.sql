@@ -0,0 +1,2 @@
+ALTER TABLE `orders` DROP INDEX `idx_orders_apart_order_number`;
+DROP TABLE IF EXISTS `order_split_rules`;
@@ -0,0 +1,16 @@
+-- 创建拆单规则表
+CREATE TABLE IF NOT EXISTS `order_split_rules` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) DEFAULT NULL COMMENT '规则名称',
+  `product_id` bigint(20) NOT NULL COMMENT '被拆分的商品ID',
+  `parts` text NOT NULL COMMENT '子订单组成，JSON数组',
+  `sort` bigint(20) DEFAULT 0 COMMENT '排序，越小越优先',
+  `status` bigint(20) DEFAULT 1 COMMENT '状态：1启用 0禁用',
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_order_split_rules_product_id` (`product_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='拆单规则表';
+
+-- 子订单通过 apart_order_number 关联父订单
+ALTER TABLE `orders` ADD INDEX `idx_orders_apart_order_number` (`apart_order_number`);
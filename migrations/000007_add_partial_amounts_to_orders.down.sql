@@ -0,0 +1 @@
+ALTER TABLE `orders` DROP COLUMN `refunded_amount`, DROP COLUMN `credited_amount`;
@@ -0,0 +1,4 @@
+-- 部分充值：记录实际到账面值与按未到账部分退还的金额
+ALTER TABLE `orders`
+  ADD COLUMN `credited_amount` decimal(10,2) DEFAULT 0 COMMENT '实际到账面值' AFTER `const_price`,
+  ADD COLUMN `refunded_amount` decimal(10,2) DEFAULT 0 COMMENT '部分充值退款金额' AFTER `credited_amount`;
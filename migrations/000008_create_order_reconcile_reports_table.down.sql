@@ -0,0 +1,2 @@
+ALTER TABLE `orders` DROP INDEX `idx_orders_status_updated_at`;
+DROP TABLE IF EXISTS `order_reconcile_reports`;
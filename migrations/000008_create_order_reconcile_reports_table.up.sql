@@ -0,0 +1,24 @@
+-- 创建超时订单对账报告表
+CREATE TABLE IF NOT EXISTS `order_reconcile_reports` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `source` varchar(32) DEFAULT NULL COMMENT '触发方式：schedule定时 manual手动',
+  `dry_run` tinyint(1) DEFAULT 0 COMMENT '是否仅预演不处置',
+  `scanned` bigint(20) DEFAULT 0 COMMENT '超过SLA的订单数',
+  `requeued` bigint(20) DEFAULT 0 COMMENT '重新投递数',
+  `queried` bigint(20) DEFAULT 0 COMMENT '查询上游数',
+  `resolved` bigint(20) DEFAULT 0 COMMENT '查询后更新为终态数',
+  `failed` bigint(20) DEFAULT 0 COMMENT '失败退款数',
+  `cancelled` bigint(20) DEFAULT 0 COMMENT '取消数',
+  `settled` bigint(20) DEFAULT 0 COMMENT '拆单汇总数',
+  `manual` bigint(20) DEFAULT 0 COMMENT '需人工处理数',
+  `errors` bigint(20) DEFAULT 0 COMMENT '处置出错数',
+  `items` longtext COMMENT '处置明细，JSON数组',
+  `started_at` datetime(3) DEFAULT NULL COMMENT '开始时间',
+  `finished_at` datetime(3) DEFAULT NULL COMMENT '结束时间',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_order_reconcile_reports_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='超时订单对账报告表';
+
+-- 按状态和更新时间扫描滞留订单
+ALTER TABLE `orders` ADD INDEX `idx_orders_status_updated_at` (`status`, `updated_at`);
@@ -0,0 +1 @@
+ALTER TABLE `orders` DROP COLUMN `next_query_time`, DROP COLUMN `query_count`;
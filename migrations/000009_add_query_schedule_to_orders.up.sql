@@ -0,0 +1,4 @@
+-- 充值中订单主动查询上游：记录查询次数与按退避计算的下次查询时间
+ALTER TABLE `orders`
+  ADD COLUMN `query_count` bigint(20) DEFAULT 0 COMMENT '主动查询上游次数' AFTER `refunded_amount`,
+  ADD COLUMN `next_query_time` datetime(3) DEFAULT NULL COMMENT '下次主动查询上游时间' AFTER `query_count`;
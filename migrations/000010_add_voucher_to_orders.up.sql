@@ -0,0 +1,3 @@
+-- 记录上游返回的官方充值凭证/流水号，来自回调或主动查询
+ALTER TABLE `orders`
+  ADD COLUMN `voucher` varchar(128) DEFAULT NULL COMMENT '官方充值凭证/流水号' AFTER `next_query_time`;
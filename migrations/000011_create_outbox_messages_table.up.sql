@@ -0,0 +1,15 @@
+-- 创建事务发件箱表，业务事务内写入，提交后由中继投递到队列
+CREATE TABLE IF NOT EXISTS `outbox_messages` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `topic` varchar(64) DEFAULT NULL COMMENT '投递的队列',
+  `aggregate_id` varchar(64) DEFAULT NULL COMMENT '业务对象ID',
+  `payload` longtext COMMENT '消息内容，JSON',
+  `status` bigint(20) DEFAULT 0 COMMENT '状态 0待投递 1已投递',
+  `attempts` bigint(20) DEFAULT 0 COMMENT '投递次数',
+  `last_error` text COMMENT '最近一次投递错误',
+  `created_at` datetime(3) DEFAULT NULL,
+  `dispatched_at` datetime(3) DEFAULT NULL COMMENT '投递时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_outbox_messages_aggregate_id` (`aggregate_id`),
+  KEY `idx_outbox_messages_status_id` (`status`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='事务发件箱表';
@@ -0,0 +1,6 @@
+ALTER TABLE `callback_logs`
+  DROP INDEX `idx_callback_logs_dedup_key`,
+  DROP INDEX `idx_callback_logs_platform_code`,
+  DROP COLUMN `dedup_key`,
+  DROP COLUMN `account_name`,
+  DROP COLUMN `platform_code`;
@@ -0,0 +1,7 @@
+-- 回调先落库原始数据再处理，dedup_key 唯一保证同一上游结果只处理一次
+ALTER TABLE `callback_logs`
+  ADD COLUMN `platform_code` varchar(50) DEFAULT NULL COMMENT '平台代码' AFTER `platform_id`,
+  ADD COLUMN `account_name` varchar(64) DEFAULT NULL COMMENT '回调地址中的平台账号' AFTER `platform_code`,
+  ADD COLUMN `dedup_key` varchar(191) DEFAULT NULL COMMENT '去重键：平台:订单号:状态' AFTER `callback_type`,
+  ADD INDEX `idx_callback_logs_platform_code` (`platform_code`),
+  ADD UNIQUE INDEX `idx_callback_logs_dedup_key` (`dedup_key`);
@@ -0,0 +1,2 @@
+ALTER TABLE `callback_logs` DROP COLUMN `source_ip`;
+ALTER TABLE `platform_apis` DROP COLUMN `callback_allowed_cidrs`;
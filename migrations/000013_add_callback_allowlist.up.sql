@@ -0,0 +1,7 @@
+-- 回调来源IP白名单，为空时不限制来源
+ALTER TABLE `platform_apis`
+  ADD COLUMN `callback_allowed_cidrs` text COMMENT '允许回调的来源IP/CIDR';
+
+-- 记录回调来源IP，便于排查被拒绝的回调
+ALTER TABLE `callback_logs`
+  ADD COLUMN `source_ip` varchar(64) DEFAULT NULL COMMENT '回调来源IP' AFTER `account_name`;
@@ -0,0 +1,6 @@
+ALTER TABLE `callback_logs`
+  DROP INDEX `idx_callback_logs_replay_of_id`,
+  DROP COLUMN `resolved_by_id`,
+  DROP COLUMN `replay_count`,
+  DROP COLUMN `operator`,
+  DROP COLUMN `replay_of_id`;
@@ -0,0 +1,7 @@
+-- 后台重放回调：重放日志指向被重放的日志，原始日志记录重放次数与解决它的重放日志
+ALTER TABLE `callback_logs`
+  ADD COLUMN `replay_of_id` bigint NOT NULL DEFAULT 0 COMMENT '被重放的回调日志ID，0为平台原始回调' AFTER `error_message`,
+  ADD COLUMN `operator` varchar(64) DEFAULT NULL COMMENT '重放操作人' AFTER `replay_of_id`,
+  ADD COLUMN `replay_count` int NOT NULL DEFAULT 0 COMMENT '被重放次数' AFTER `operator`,
+  ADD COLUMN `resolved_by_id` bigint NOT NULL DEFAULT 0 COMMENT '处理成功的重放日志ID' AFTER `replay_count`,
+  ADD INDEX `idx_callback_logs_replay_of_id` (`replay_of_id`);
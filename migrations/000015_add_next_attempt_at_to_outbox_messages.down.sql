@@ -0,0 +1,4 @@
+ALTER TABLE `outbox_messages`
+  DROP INDEX `idx_outbox_messages_status_next`,
+  ADD INDEX `idx_outbox_messages_status_id` (`status`, `id`),
+  DROP COLUMN `next_attempt_at`;
@@ -0,0 +1,7 @@
+-- 发件箱中继按下次投递时间退避重试，并按投递次数抢占消息
+ALTER TABLE `outbox_messages`
+  ADD COLUMN `next_attempt_at` datetime(3) DEFAULT NULL COMMENT '中继下次投递时间' AFTER `last_error`,
+  DROP INDEX `idx_outbox_messages_status_id`,
+  ADD INDEX `idx_outbox_messages_status_next` (`status`, `next_attempt_at`);
+
+UPDATE `outbox_messages` SET `next_attempt_at` = `created_at` WHERE `next_attempt_at` IS NULL;
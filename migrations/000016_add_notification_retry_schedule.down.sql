@@ -0,0 +1,9 @@
+ALTER TABLE `external_api_keys`
+  DROP COLUMN `notify_retry_schedule`;
+
+ALTER TABLE `notification_templates`
+  DROP COLUMN `retry_schedule`;
+
+ALTER TABLE `notification_records`
+  DROP INDEX `idx_notification_records_status_next`,
+  DROP COLUMN `last_error`;
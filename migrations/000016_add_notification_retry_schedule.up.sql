@@ -0,0 +1,10 @@
+-- 下游通知按重试表退避重试，重试表用尽后标记为已放弃
+ALTER TABLE `notification_records`
+  ADD COLUMN `last_error` text COMMENT '最近一次投递失败的原因' AFTER `next_retry_time`,
+  ADD INDEX `idx_notification_records_status_next` (`status`, `next_retry_time`);
+
+ALTER TABLE `notification_templates`
+  ADD COLUMN `retry_schedule` varchar(255) DEFAULT NULL COMMENT '通知重试表,逗号分隔,为空时使用默认' AFTER `status`;
+
+ALTER TABLE `external_api_keys`
+  ADD COLUMN `notify_retry_schedule` varchar(255) DEFAULT NULL COMMENT '回调通知重试表,逗号分隔,为空时使用默认' AFTER `notify_url`;
@@ -0,0 +1,20 @@
+-- 创建通知投递记录表，每次向下游发送通知记录一条
+CREATE TABLE IF NOT EXISTS `notification_delivery_attempts` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `notification_id` bigint(20) DEFAULT NULL COMMENT '通知记录ID',
+  `order_id` bigint(20) DEFAULT NULL COMMENT '订单ID',
+  `attempt` bigint(20) DEFAULT 0 COMMENT '第几次投递',
+  `url` varchar(512) DEFAULT NULL COMMENT '通知地址',
+  `request_headers` text COMMENT '请求头，JSON，敏感请求头已脱敏',
+  `request_body` text COMMENT '请求体',
+  `http_status` bigint(20) DEFAULT 0 COMMENT 'HTTP状态码，未收到响应时为0',
+  `response_body` text COMMENT '响应体，超出长度的部分被截断',
+  `latency_ms` bigint(20) DEFAULT 0 COMMENT '耗时（毫秒）',
+  `error` text COMMENT '投递错误',
+  `success` tinyint(1) DEFAULT 0 COMMENT '是否投递成功',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_notification_delivery_attempts_notification_id` (`notification_id`),
+  KEY `idx_notification_delivery_attempts_order_id` (`order_id`),
+  KEY `idx_notification_delivery_attempts_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='通知投递记录表';
@@ -0,0 +1,53 @@
+-- 创建复式记账凭证与分录表，用户余额与授信额度由分录汇总得出
+CREATE TABLE IF NOT EXISTS `ledger_transactions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `kind` varchar(32) DEFAULT NULL COMMENT '记账类型',
+  `user_id` bigint(20) DEFAULT NULL COMMENT '用户ID',
+  `order_id` bigint(20) DEFAULT NULL COMMENT '关联订单ID',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `operator` varchar(100) DEFAULT NULL COMMENT '操作人',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_ledger_transactions_kind` (`kind`),
+  KEY `idx_ledger_transactions_user_id` (`user_id`),
+  KEY `idx_ledger_transactions_order_id` (`order_id`),
+  KEY `idx_ledger_transactions_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='记账凭证表';
+
+CREATE TABLE IF NOT EXISTS `ledger_entries` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `transaction_id` bigint(20) NOT NULL COMMENT '凭证ID',
+  `account` varchar(64) NOT NULL COMMENT '科目',
+  `user_id` bigint(20) DEFAULT NULL COMMENT '用户ID',
+  `platform_account_id` bigint(20) DEFAULT NULL COMMENT '平台账号ID',
+  `order_id` bigint(20) DEFAULT NULL COMMENT '关联订单ID',
+  `amount` decimal(12,2) NOT NULL COMMENT '金额',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_ledger_entries_transaction_id` (`transaction_id`),
+  KEY `idx_ledger_entries_account` (`account`),
+  KEY `idx_ledger_entries_user_id` (`user_id`),
+  KEY `idx_ledger_entries_platform_account_id` (`platform_account_id`),
+  KEY `idx_ledger_entries_order_id` (`order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='记账分录表';
+
+-- 以启用账本时的余额与授信额度写入期初凭证，对方科目为期初余额
+INSERT INTO `ledger_transactions` (`kind`, `user_id`, `order_id`, `remark`, `operator`, `created_at`)
+SELECT 'opening', `id`, 0, '期初余额', 'system', NOW(3)
+FROM `users`
+WHERE `balance` <> 0 OR `credit` <> 0;
+
+INSERT INTO `ledger_entries` (`transaction_id`, `account`, `user_id`, `platform_account_id`, `order_id`, `amount`, `created_at`)
+SELECT t.`id`, CONCAT('user:', u.`id`, ':balance'), u.`id`, 0, 0, u.`balance`, t.`created_at`
+FROM `ledger_transactions` t JOIN `users` u ON u.`id` = t.`user_id`
+WHERE t.`kind` = 'opening' AND u.`balance` <> 0;
+
+INSERT INTO `ledger_entries` (`transaction_id`, `account`, `user_id`, `platform_account_id`, `order_id`, `amount`, `created_at`)
+SELECT t.`id`, CONCAT('user:', u.`id`, ':credit'), u.`id`, 0, 0, u.`credit`, t.`created_at`
+FROM `ledger_transactions` t JOIN `users` u ON u.`id` = t.`user_id`
+WHERE t.`kind` = 'opening' AND u.`credit` <> 0;
+
+INSERT INTO `ledger_entries` (`transaction_id`, `account`, `user_id`, `platform_account_id`, `order_id`, `amount`, `created_at`)
+SELECT t.`id`, 'system:opening', 0, 0, 0, -(u.`balance` + u.`credit`), t.`created_at`
+FROM `ledger_transactions` t JOIN `users` u ON u.`id` = t.`user_id`
+WHERE t.`kind` = 'opening';
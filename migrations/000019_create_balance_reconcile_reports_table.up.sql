@@ -0,0 +1,21 @@
+-- 创建资金对账报告表
+CREATE TABLE IF NOT EXISTS `balance_reconcile_reports` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `source` varchar(32) DEFAULT NULL COMMENT '触发方式：schedule定时 manual手动',
+  `auto_correct` tinyint(1) DEFAULT 0 COMMENT '是否自动修正差异',
+  `users_scanned` bigint(20) DEFAULT 0 COMMENT '核对用户数',
+  `accounts_scanned` bigint(20) DEFAULT 0 COMMENT '核对平台账号数',
+  `orders_scanned` bigint(20) DEFAULT 0 COMMENT '核对终态订单数',
+  `balance_drifts` bigint(20) DEFAULT 0 COMMENT '余额差异数',
+  `credit_drifts` bigint(20) DEFAULT 0 COMMENT '授信差异数',
+  `account_drifts` bigint(20) DEFAULT 0 COMMENT '平台账号差异数',
+  `order_issues` bigint(20) DEFAULT 0 COMMENT '订单扣退款异常数',
+  `corrected` bigint(20) DEFAULT 0 COMMENT '已修正数',
+  `errors` bigint(20) DEFAULT 0 COMMENT '核对或修正出错数',
+  `items` longtext COMMENT '差异明细，JSON数组',
+  `started_at` datetime(3) DEFAULT NULL COMMENT '开始时间',
+  `finished_at` datetime(3) DEFAULT NULL COMMENT '结束时间',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_balance_reconcile_reports_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='资金对账报告表';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `balance_holds`;
+
+ALTER TABLE `users`
+  DROP COLUMN `frozen_balance`;
@@ -0,0 +1,21 @@
+-- 外部订单下单时冻结金额，充值成功扣款、失败退回
+ALTER TABLE `users`
+  ADD COLUMN `frozen_balance` decimal(10,2) DEFAULT 0.00 COMMENT '冻结金额' AFTER `balance`;
+
+CREATE TABLE IF NOT EXISTS `balance_holds` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT '用户ID',
+  `order_id` bigint(20) NOT NULL COMMENT '订单ID',
+  `amount` decimal(10,2) NOT NULL COMMENT '冻结金额',
+  `balance_amount` decimal(10,2) NOT NULL COMMENT '其中冻结的余额',
+  `credit_amount` decimal(10,2) NOT NULL COMMENT '其中冻结的授信额度',
+  `captured` decimal(10,2) NOT NULL DEFAULT 0.00 COMMENT '已扣款金额',
+  `released` decimal(10,2) NOT NULL DEFAULT 0.00 COMMENT '已退回金额',
+  `status` tinyint(4) NOT NULL COMMENT '状态：1冻结中 2已扣款 3已退回',
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_balance_holds_order_id` (`order_id`),
+  KEY `idx_balance_holds_user_id` (`user_id`),
+  KEY `idx_balance_holds_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单冻结款表';
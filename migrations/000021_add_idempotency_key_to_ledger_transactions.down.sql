@@ -0,0 +1,2 @@
+ALTER TABLE `ledger_transactions` DROP INDEX `idx_ledger_transactions_idempotency_key`;
+ALTER TABLE `ledger_transactions` DROP COLUMN `idempotency_key`;
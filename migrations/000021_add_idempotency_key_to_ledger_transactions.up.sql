@@ -0,0 +1,27 @@
+-- 记账凭证增加幂等键，同一资金变动（如 order:123:refund）只能记账一次，由唯一索引保证
+ALTER TABLE `ledger_transactions`
+  ADD COLUMN `idempotency_key` varchar(128) DEFAULT NULL COMMENT '幂等键' AFTER `order_id`;
+
+-- 为已有的订单资金变动补写幂等键，避免上线前已退款的订单被再次退款；
+-- 同一订单同类凭证有多张时只有最早一张获得幂等键
+UPDATE `ledger_transactions` t
+JOIN (
+  SELECT MIN(`id`) AS `id`
+  FROM `ledger_transactions`
+  WHERE `order_id` > 0 AND `kind` IN ('order_deduct', 'refund', 'hold', 'capture', 'release', 'credit_use', 'credit_restore')
+  GROUP BY `order_id`, `kind`
+) f ON f.`id` = t.`id`
+SET t.`idempotency_key` = CONCAT('order:', t.`order_id`, ':', CASE t.`kind` WHEN 'order_deduct' THEN 'deduct' ELSE t.`kind` END);
+
+-- 佣金按收款用户区分
+UPDATE `ledger_transactions` t
+JOIN (
+  SELECT MIN(`id`) AS `id`
+  FROM `ledger_transactions`
+  WHERE `order_id` > 0 AND `kind` = 'commission'
+  GROUP BY `order_id`, `user_id`
+) f ON f.`id` = t.`id`
+SET t.`idempotency_key` = CONCAT('order:', t.`order_id`, ':commission:', t.`user_id`);
+
+ALTER TABLE `ledger_transactions`
+  ADD UNIQUE KEY `idx_ledger_transactions_idempotency_key` (`idempotency_key`);
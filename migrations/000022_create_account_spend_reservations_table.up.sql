@@ -0,0 +1,23 @@
+-- 平台账号额度占用记录，额度计数器以未释放的占用记录重建
+CREATE TABLE IF NOT EXISTS `account_spend_reservations` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `order_id` bigint(20) NOT NULL COMMENT '订单ID',
+  `account_id` bigint(20) NOT NULL COMMENT '平台账号ID',
+  `amount` decimal(10,4) NOT NULL COMMENT '占用金额',
+  `reserved_at` datetime(3) NOT NULL COMMENT '占用时间，决定计入哪一天、哪一月',
+  `released_at` datetime(3) DEFAULT NULL COMMENT '释放时间，为空表示占用中',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_account_spend_reservations_order_id` (`order_id`),
+  KEY `idx_account_spend_reservations_account` (`account_id`, `reserved_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='平台账号额度占用记录表';
+
+-- 本月已提交订单按原先的统计口径（成本价）补记占用，避免上线后计数器被重建为0
+INSERT INTO `account_spend_reservations` (`order_id`, `account_id`, `amount`, `reserved_at`, `created_at`)
+SELECT o.`id`, a.`account_id`, o.`const_price`, o.`created_at`, NOW(3)
+FROM `orders` o
+JOIN `platform_apis` a ON a.`id` = o.`api_cur_id`
+WHERE a.`account_id` > 0
+  AND o.`const_price` > 0
+  AND o.`status` IN (3, 4, 8, 10)
+  AND o.`created_at` >= DATE_FORMAT(NOW(), '%Y-%m-01');
@@ -75,7 +75,10 @@ func InitDB() error {
 		&model.CallbackLog{},
 		&notification.NotificationRecord{},
 		&notification.Template{},
+		&notification.DeliveryAttempt{},
 		&model.BalanceLog{},
+		&model.LedgerTransaction{},
+		&model.LedgerEntry{},
 		&model.CreditLog{},
 		&model.TaskConfig{},
 		&model.TaskOrder{},
@@ -89,6 +92,13 @@ func InitDB() error {
 		&model.PlatformAccount{},
 		&model.ExternalAPIKey{},
 		&model.ExternalOrderLog{},
+		&model.OrderStatusHistory{},
+		&model.OrderSplitRule{},
+		&model.OrderReconcileReport{},
+		&model.BalanceReconcileReport{},
+		&model.BalanceHold{},
+		&model.AccountSpendReservation{},
+		&model.OutboxMessage{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate tables: %v", err)
 	}
@@ -35,6 +35,11 @@ type MetricsManager struct {
 	businessOperationsTotal   *prometheus.CounterVec
 	businessOperationDuration *prometheus.HistogramVec
 
+	// 上游平台接口指标
+	supplierCallsTotal   *prometheus.CounterVec
+	supplierHealthScore  *prometheus.GaugeVec
+	supplierCircuitState *prometheus.GaugeVec
+
 	// 系统指标
 	goroutinesCount prometheus.Gauge
 	memoryUsage     prometheus.Gauge
@@ -151,6 +156,31 @@ func (mm *MetricsManager) initMetrics() {
 		[]string{"operation"},
 	)
 
+	// 上游平台接口指标
+	mm.supplierCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "supplier_calls_total",
+			Help: "Total number of supplier API calls by kind",
+		},
+		[]string{"api_id", "kind", "status"},
+	)
+
+	mm.supplierHealthScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "supplier_health_score",
+			Help: "Rolling health score (0-100) of supplier APIs",
+		},
+		[]string{"api_id"},
+	)
+
+	mm.supplierCircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "supplier_circuit_state",
+			Help: "Circuit breaker state of supplier APIs (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"api_id"},
+	)
+
 	// 系统指标
 	mm.goroutinesCount = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -193,6 +223,11 @@ func (mm *MetricsManager) registerMetrics() {
 	mm.registry.MustRegister(mm.businessOperationsTotal)
 	mm.registry.MustRegister(mm.businessOperationDuration)
 
+	// 上游平台接口指标
+	mm.registry.MustRegister(mm.supplierCallsTotal)
+	mm.registry.MustRegister(mm.supplierHealthScore)
+	mm.registry.MustRegister(mm.supplierCircuitState)
+
 	// 系统指标
 	mm.registry.MustRegister(mm.goroutinesCount)
 	mm.registry.MustRegister(mm.memoryUsage)
@@ -252,6 +287,30 @@ func (mm *MetricsManager) RecordBusinessOperation(operation string, duration tim
 	mm.businessOperationDuration.WithLabelValues(operation).Observe(duration.Seconds())
 }
 
+// ObserveSupplierCall 记录上游平台接口调用结果
+func (mm *MetricsManager) ObserveSupplierCall(apiID int64, kind string, success bool) {
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	mm.supplierCallsTotal.WithLabelValues(strconv.FormatInt(apiID, 10), kind, status).Inc()
+}
+
+// ObserveSupplierHealth 更新上游平台接口健康分及熔断状态
+func (mm *MetricsManager) ObserveSupplierHealth(apiID int64, state string, score float64) {
+	label := strconv.FormatInt(apiID, 10)
+	mm.supplierHealthScore.WithLabelValues(label).Set(score)
+
+	stateValue := 0.0
+	switch state {
+	case "half_open":
+		stateValue = 1
+	case "open":
+		stateValue = 2
+	}
+	mm.supplierCircuitState.WithLabelValues(label).Set(stateValue)
+}
+
 // UpdateSystemMetrics 更新系统指标
 func (mm *MetricsManager) UpdateSystemMetrics(goroutines int, memoryBytes uint64, cpuPercent float64) {
 	mm.goroutinesCount.Set(float64(goroutines))
@@ -0,0 +1,223 @@
+// Package money 定点金额类型
+//
+// 金额以万分之一元为单位的整数保存，接口单价 decimal(10,4) 与余额、订单金额 decimal(10,2)
+// 都能精确表示；同类金额可直接用 + - 与 < > == 运算比较，不会产生浮点误差。
+// 数据库沿用 decimal 列，JSON 编解码使用十进制数字，表结构与接口格式保持不变
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale 1元对应的最小单位数
+const Scale = 10000
+
+// Zero 零金额
+const Zero Money = 0
+
+// Money 金额，单位为万分之一元
+type Money int64
+
+// FromFloat 由浮点金额转换，四舍五入到万分之一元，只用于与第三方接口交互等边界处
+func FromFloat(f float64) Money {
+	return Money(math.Round(f * Scale))
+}
+
+// FromCents 由分转换
+func FromCents(cents int64) Money {
+	return Money(cents * (Scale / 100))
+}
+
+// Yuan 由整元转换
+func Yuan(yuan int64) Money {
+	return Money(yuan * Scale)
+}
+
+// Parse 解析十进制金额字符串，如 "12.34"、"-0.5"，超过4位小数的部分四舍五入
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("money: empty amount")
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("money: invalid amount %q", s)
+		}
+		return FromFloat(f), nil
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("money: invalid amount %q", s)
+	}
+
+	var units int64
+	for _, c := range whole {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("money: invalid amount %q", s)
+		}
+		units = units*10 + int64(c-'0')
+		if units > math.MaxInt64/(Scale*10) {
+			return 0, fmt.Errorf("money: amount %q out of range", s)
+		}
+	}
+	units *= Scale
+
+	unit := int64(Scale)
+	for i, c := range frac {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("money: invalid amount %q", s)
+		}
+		if unit == 1 {
+			// 第5位小数决定进位，其余忽略
+			if i == 4 && c >= '5' {
+				units++
+			}
+			continue
+		}
+		unit /= 10
+		units += int64(c-'0') * unit
+	}
+
+	if negative {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+// MustParse 解析金额字符串，格式错误时 panic，用于常量与测试
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Float64 转换为浮点数，只用于展示或与要求浮点的第三方接口交互
+func (m Money) Float64() float64 {
+	return float64(m) / Scale
+}
+
+// Cents 四舍五入到分
+func (m Money) Cents() int64 {
+	const unit = Scale / 100
+	if m < 0 {
+		return -int64((-m + unit/2) / unit)
+	}
+	return int64((m + unit/2) / unit)
+}
+
+// Round 四舍五入到分
+func (m Money) Round() Money {
+	return FromCents(m.Cents())
+}
+
+// Mul 乘以系数（如折扣、费率），结果四舍五入到万分之一元
+func (m Money) Mul(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// MulInt 乘以整数（如数量）
+func (m Money) MulInt(n int64) Money {
+	return m * Money(n)
+}
+
+// Abs 绝对值
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String 十进制表示，至少保留2位小数，如 12.30、1.2345
+func (m Money) String() string {
+	v := int64(m)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%04d", v%Scale), "0")
+	if len(frac) < 2 {
+		frac += strings.Repeat("0", 2-len(frac))
+	}
+	return fmt.Sprintf("%s%d.%s", sign, v/Scale, frac)
+}
+
+// MarshalJSON 编码为 JSON 数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 解码 JSON 数字或数字字符串
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	if s == "" {
+		*m = 0
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 写入数据库，以十进制字符串精确写入，不经浮点转换
+// 金额最多4位小数，可原样写入 decimal(10,4) 列；写入2位小数的列前须先 Round 到分，不依赖数据库按列精度舍入。
+// 零值返回浮点 0：gorm 按零值的 Value 类型推断字段类型并解析 default 标签，字符串类型的默认值无法写回整数字段
+func (m Money) Value() (driver.Value, error) {
+	if m == 0 {
+		return float64(0), nil
+	}
+	return m.String(), nil
+}
+
+// Scan 从数据库读取 decimal、整数或浮点列
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Yuan(v)
+	case float64:
+		*m = FromFloat(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -21,7 +21,8 @@ type ExternalAPISignatureConfig struct {
 
 // ExternalAPISignatureValidator 外部API签名验证器
 type ExternalAPISignatureValidator struct {
-	TimeWindow int64 // 时间窗口，单位秒，默认300秒(5分钟)
+	TimeWindow int64     // 时间窗口，单位秒，默认300秒(5分钟)
+	Now        time.Time // 校验时间戳的参考时间，零值时取当前时间
 }
 
 // NewExternalAPISignatureValidator 创建外部API签名验证器
@@ -99,6 +100,9 @@ func (sv *ExternalAPISignatureValidator) ValidateExternalAPISignature(params map
 
 	// 检查时间戳是否在有效范围内
 	now := time.Now().Unix()
+	if !sv.Now.IsZero() {
+		now = sv.Now.Unix()
+	}
 	if abs(now-timestamp) > sv.TimeWindow {
 		return fmt.Errorf("timestamp expired")
 	}
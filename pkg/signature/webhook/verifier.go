@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxBodySize 校验时读取的最大请求体
+const maxBodySize = 1 << 20
+
+// NonceStore 已使用随机串的存储，用于拒绝重放的通知
+type NonceStore interface {
+	// Seen 记录随机串，在 expireAt 之前重复出现时返回 true
+	Seen(nonce string, expireAt time.Time) bool
+}
+
+// MemoryNonceStore 进程内随机串存储，多实例部署时应换成共享存储
+type MemoryNonceStore struct {
+	mu    sync.Mutex
+	nonce map[string]time.Time
+}
+
+// NewMemoryNonceStore 创建进程内随机串存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonce: make(map[string]time.Time)}
+}
+
+// Seen 记录随机串并清理已过期的记录
+func (s *MemoryNonceStore) Seen(nonce string, expireAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for n, exp := range s.nonce {
+		if exp.Before(now) {
+			delete(s.nonce, n)
+		}
+	}
+	if exp, ok := s.nonce[nonce]; ok && exp.After(now) {
+		return true
+	}
+	s.nonce[nonce] = expireAt
+	return false
+}
+
+// Verifier 接入方校验平台回调通知
+type Verifier struct {
+	Secret    string        // 平台分配的 AppSecret
+	Tolerance time.Duration // 允许的时间戳偏差，不大于0时使用 DefaultTolerance
+	Nonces    NonceStore    // 为 nil 时不检查随机串重复
+}
+
+// NewVerifier 创建校验器，使用进程内随机串存储拒绝重放
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{
+		Secret:    secret,
+		Tolerance: DefaultTolerance,
+		Nonces:    NewMemoryNonceStore(),
+	}
+}
+
+// VerifyRequest 校验请求并返回原始请求体，r.Body 会被替换为可再次读取的副本
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("read webhook body failed: %v", err)
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(v.Secret, r.Header, body, v.Tolerance, time.Time{}); err != nil {
+		return nil, err
+	}
+	if v.Nonces != nil {
+		tolerance := v.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultTolerance
+		}
+		// 超出时间戳偏差的通知已被拒绝，随机串只需保留到偏差窗口结束
+		if v.Nonces.Seen(r.Header.Get(NonceHeader), time.Now().Add(2*tolerance)) {
+			return nil, ErrNonceReused
+		}
+	}
+	return body, nil
+}
+
+// Middleware 校验失败时返回 401，校验通过后交给 next 处理
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := v.VerifyRequest(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
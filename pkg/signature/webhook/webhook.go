@@ -0,0 +1,117 @@
+// Package webhook 下游回调通知签名与验签
+//
+// 平台向接入方回调地址发送的每条通知都带有以下请求头：
+//
+//	X-Recharge-Timestamp: 发送时的 Unix 时间戳（秒）
+//	X-Recharge-Nonce:     随机串，每条通知（含重试）都不相同
+//	X-Recharge-Signature: v1=<签名>
+//
+// 待签名串为 时间戳 + "\n" + 随机串 + "\n" + 原始请求体，签名为以 AppSecret 为密钥
+// 对待签名串做 HMAC-SHA256 后的小写十六进制。签名头中的版本号便于日后更换算法。
+//
+// 本包只依赖标准库，接入方可直接复制到自己的项目中使用 Verifier 校验回调通知。
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名请求头
+const (
+	TimestampHeader = "X-Recharge-Timestamp"
+	NonceHeader     = "X-Recharge-Nonce"
+	SignatureHeader = "X-Recharge-Signature"
+
+	// SignatureVersion 当前签名版本
+	SignatureVersion = "v1"
+	// DefaultTolerance 默认允许的时间戳偏差
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrHeaderMissing 缺少签名请求头
+	ErrHeaderMissing = errors.New("webhook signature headers missing")
+	// ErrTimestampInvalid 时间戳格式错误或超出允许偏差
+	ErrTimestampInvalid = errors.New("webhook timestamp invalid or expired")
+	// ErrSignatureMismatch 签名不匹配
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+	// ErrNonceReused 随机串重复，通知被重放
+	ErrNonceReused = errors.New("webhook nonce reused")
+)
+
+// CanonicalString 生成待签名串
+func CanonicalString(timestamp, nonce string, body []byte) []byte {
+	buf := make([]byte, 0, len(timestamp)+len(nonce)+len(body)+2)
+	buf = append(buf, timestamp...)
+	buf = append(buf, '\n')
+	buf = append(buf, nonce...)
+	buf = append(buf, '\n')
+	return append(buf, body...)
+}
+
+// Sign 计算签名，返回签名头的值
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(CanonicalString(timestamp, nonce, body))
+	return SignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce 生成随机串
+func NewNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// SetHeaders 以 now 和新随机串为通知设置签名请求头
+func SetHeaders(header http.Header, secret string, body []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce := NewNonce()
+	header.Set(TimestampHeader, timestamp)
+	header.Set(NonceHeader, nonce)
+	header.Set(SignatureHeader, Sign(secret, timestamp, nonce, body))
+}
+
+// Verify 校验签名与时间戳，随机串是否重复由调用方判断
+// tolerance 不大于0时使用 DefaultTolerance，now 为零值时取当前时间
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp := header.Get(TimestampHeader)
+	nonce := header.Get(NonceHeader)
+	sign := header.Get(SignatureHeader)
+	if timestamp == "" || nonce == "" || sign == "" {
+		return ErrHeaderMissing
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTimestampInvalid, timestamp)
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if diff := now.Sub(time.Unix(ts, 0)); diff > tolerance || diff < -tolerance {
+		return fmt.Errorf("%w: %s", ErrTimestampInvalid, timestamp)
+	}
+
+	if !strings.HasPrefix(sign, SignatureVersion+"=") {
+		return fmt.Errorf("%w: unsupported version", ErrSignatureMismatch)
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, nonce, body)), []byte(sign)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
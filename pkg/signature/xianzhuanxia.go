@@ -13,6 +13,12 @@ import (
 
 // GenerateXianzhuanxiaSignature 生成闲赚侠签名
 func GenerateXianzhuanxiaSignature(params map[string]string, apiKey, userID string) (string, string, error) {
+	queryTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return GenerateXianzhuanxiaSignatureAt(params, apiKey, userID, queryTime), queryTime, nil
+}
+
+// GenerateXianzhuanxiaSignatureAt 按指定的 queryTime 生成闲赚侠签名，用于校验回调中的 Auth_Token
+func GenerateXianzhuanxiaSignatureAt(params map[string]string, apiKey, userID, queryTime string) string {
 	// 第一步：排序并拼接非空参数
 	var keys []string
 	for k, v := range params {
@@ -37,7 +43,6 @@ func GenerateXianzhuanxiaSignature(params map[string]string, apiKey, userID stri
 	}
 
 	// 第二步：添加 queryTime 和 key
-	queryTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
 	sb.WriteString("queryTime=")
 	sb.WriteString(queryTime)
 	sb.WriteString("key=")
@@ -53,7 +58,7 @@ func GenerateXianzhuanxiaSignature(params map[string]string, apiKey, userID stri
 	// Base64 编码
 	base64Token := base64.StdEncoding.EncodeToString([]byte(authToken))
 
-	return base64Token, queryTime, nil
+	return base64Token
 }
 func GenerateXianzhuanxiaSignature2(params map[string]interface{}, apiKey, userID string) (string, string, error) {
 	// 第一步：排序并拼接非空参数
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service/recharge"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TestAdapterRegistry 测试适配器注册、重复注册 panic 与按代码排序列出
+func TestAdapterRegistry(t *testing.T) {
+	factory := func(db *gorm.DB) recharge.Platform { return recharge.NewGenericHTTPPlatform(db, "registry_test") }
+	recharge.RegisterAdapter(recharge.AdapterDescriptor{
+		Code:         "registry_test",
+		Name:         "注册测试",
+		Capabilities: recharge.Capabilities{SubmitOrder: true},
+	}, factory)
+
+	if !recharge.IsAdapterRegistered("registry_test") {
+		t.Fatal("注册后应能查到适配器")
+	}
+	descriptor, ok := recharge.GetAdapterDescriptor("registry_test")
+	if !ok || descriptor.Name != "注册测试" || !descriptor.Capabilities.SubmitOrder {
+		t.Fatalf("适配器描述错误: %+v", descriptor)
+	}
+
+	list := recharge.ListAdapters()
+	found := false
+	for i, d := range list {
+		if i > 0 && list[i-1].Code >= d.Code {
+			t.Fatalf("适配器应按代码排序: %s %s", list[i-1].Code, d.Code)
+		}
+		found = found || d.Code == "registry_test"
+	}
+	if !found || !recharge.IsAdapterRegistered(recharge.GenericHTTPAdapterCode) {
+		t.Fatalf("列表缺少适配器: %+v", list)
+	}
+
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("%s应 panic", name)
+			}
+		}()
+		fn()
+	}
+	expectPanic("重复注册", func() {
+		recharge.RegisterAdapter(recharge.AdapterDescriptor{Code: "registry_test"}, factory)
+	})
+	expectPanic("空代码", func() {
+		recharge.RegisterAdapter(recharge.AdapterDescriptor{}, factory)
+	})
+	expectPanic("空构造函数", func() {
+		recharge.RegisterAdapter(recharge.AdapterDescriptor{Code: "registry_nil"}, nil)
+	})
+}
+
+// TestManagerPlatformPerAPI 测试平台实例按接口缓存，同代码的两个接口各用自己的配置
+func TestManagerPlatformPerAPI(t *testing.T) {
+	db, api := setupGenericHTTPTest(t, "http://127.0.0.1")
+	other := *api
+	other.ID = 0
+	other.ExtraParams = datatypes.JSON(strings.Replace(genericTestTemplate, `"ack": "OK"`, `"ack": "SUCCESS"`, 1))
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("创建平台接口失败: %v", err)
+	}
+	manager := recharge.NewManager(db)
+
+	first, err := manager.GetPlatformForAPI(api)
+	if err != nil {
+		t.Fatalf("获取平台实例失败: %v", err)
+	}
+	second, err := manager.GetPlatformForAPI(&other)
+	if err != nil {
+		t.Fatalf("获取平台实例失败: %v", err)
+	}
+	again, _ := manager.GetPlatformForAPI(api)
+	if first == second || first != again {
+		t.Fatal("平台实例应按接口ID缓存")
+	}
+
+	ackFirst := first.(*recharge.GenericHTTPPlatform).CallbackAck(context.Background())
+	ackSecond := second.(*recharge.GenericHTTPPlatform).CallbackAck(context.Background())
+	if ackFirst != "OK" || ackSecond != "SUCCESS" {
+		t.Fatalf("接口应使用各自的模板: %s %s", ackFirst, ackSecond)
+	}
+
+	// 注册适配器的接口同样按ID各自缓存
+	dayuanren := &model.PlatformAPI{ID: 101, Code: "dayuanren"}
+	shared := &model.PlatformAPI{ID: 102, Code: "dayuanren"}
+	p1, err := manager.GetPlatformForAPI(dayuanren)
+	if err != nil {
+		t.Fatalf("获取平台实例失败: %v", err)
+	}
+	p2, _ := manager.GetPlatformForAPI(shared)
+	if p1 == p2 {
+		t.Fatal("共用适配器代码的接口不应共用实例")
+	}
+}
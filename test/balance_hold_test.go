@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+)
+
+// TestBalanceHoldLifecycle 测试下单冻结、成功扣款、失败退回与部分退回，冻结金额与账本、对账始终一致
+func TestBalanceHoldLifecycle(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderStatusHistory{}, &model.BalanceReconcileReport{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	if err := db.Create(&model.User{ID: 1, Username: "hold", Password: "x"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	creditService := service.NewCreditService(userRepo, repository.NewCreditLogRepository(db))
+	balanceService := service.NewBalanceServiceWithCredit(repository.NewBalanceLogRepository(db), userRepo, creditService)
+	sm := service.NewOrderStateMachine(db)
+	if err := balanceService.Recharge(ctx, 1, money.Yuan(30), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+	if err := creditService.SetCredit(ctx, &model.CreditLogRequest{UserID: 1, Amount: money.Yuan(50), Operator: "admin"}); err != nil {
+		t.Fatalf("设置授信失败: %v", err)
+	}
+
+	placeOrder := func(id int64, price money.Money) {
+		t.Helper()
+		order := &model.Order{ID: id, OrderNumber: fmt.Sprintf("BH%d", id), OutTradeNum: fmt.Sprintf("OUT%d", id), CustomerID: 1,
+			Price: price, Status: model.OrderStatusPendingRecharge, CreateTime: time.Now(), UpdatedAt: time.Now()}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+		if err := balanceService.Hold(ctx, 1, price, id, "外部订单冻结", "system"); err != nil {
+			t.Fatalf("订单%d冻结失败: %v", id, err)
+		}
+	}
+	expectUser := func(balance, credit, frozen money.Money) {
+		t.Helper()
+		var user model.User
+		db.First(&user, 1)
+		if user.Balance != balance || user.Credit != credit || user.FrozenBalance != frozen {
+			t.Fatalf("余额%s 授信%s 冻结%s，期望 %s %s %s", user.Balance, user.Credit, user.FrozenBalance, balance, credit, frozen)
+		}
+		assertLedgerConsistent(t, db, 1)
+	}
+
+	// 订单1冻结后充值成功，冻结款转为扣款
+	placeOrder(1, money.Yuan(20))
+	expectUser(money.Yuan(10), money.Yuan(50), money.Yuan(20))
+	if err := sm.Transit(ctx, nil, 1, model.OrderStatusPendingRecharge, model.OrderStatusRecharging, service.OrderTransition{}); err != nil {
+		t.Fatalf("订单1置为充值中失败: %v", err)
+	}
+	expectUser(money.Yuan(10), money.Yuan(50), money.Yuan(20))
+	if err := sm.Transit(ctx, nil, 1, model.OrderStatusRecharging, model.OrderStatusSuccess, service.OrderTransition{SettleHold: true}); err != nil {
+		t.Fatalf("订单1置为成功失败: %v", err)
+	}
+	expectUser(money.Yuan(10), money.Yuan(50), 0)
+
+	// 订单2冻结余额10与授信15，失败时先恢复授信再退回余额，重复退款不再入账
+	placeOrder(2, money.Yuan(25))
+	expectUser(0, money.Yuan(35), money.Yuan(25))
+	count, held, err := balanceService.HeldSummary(ctx, 1)
+	if err != nil || count != 1 || held != money.Yuan(25) {
+		t.Fatalf("冻结汇总错误: %d %s %v", count, held, err)
+	}
+	if err := sm.Transit(ctx, nil, 2, model.OrderStatusPendingRecharge, model.OrderStatusFailed, service.OrderTransition{SettleHold: true}); err != nil {
+		t.Fatalf("订单2置为失败失败: %v", err)
+	}
+	expectUser(money.Yuan(10), money.Yuan(50), 0)
+	if err := balanceService.Refund(ctx, 1, money.Yuan(25), 2, "订单退款", "system"); err != nil {
+		t.Fatalf("重复退款失败: %v", err)
+	}
+	expectUser(money.Yuan(10), money.Yuan(50), 0)
+
+	// 订单3部分到账：先退回未到账的3元，部分成功时扣下其余5元
+	placeOrder(3, money.Yuan(8))
+	if err := balanceService.Refund(ctx, 1, money.Yuan(3), 3, "部分充值退款", "system"); err != nil {
+		t.Fatalf("部分退回失败: %v", err)
+	}
+	expectUser(money.Yuan(5), money.Yuan(50), money.Yuan(5))
+	if err := sm.Transit(ctx, nil, 3, model.OrderStatusPendingRecharge, model.OrderStatusRecharging, service.OrderTransition{}); err != nil {
+		t.Fatalf("订单3置为充值中失败: %v", err)
+	}
+	if err := sm.Transit(ctx, nil, 3, model.OrderStatusRecharging, model.OrderStatusPartial, service.OrderTransition{SettleHold: true}); err != nil {
+		t.Fatalf("订单3置为部分成功失败: %v", err)
+	}
+	expectUser(money.Yuan(5), money.Yuan(50), 0)
+	hold, err := balanceService.GetHold(ctx, 3)
+	if err != nil || hold.Status != model.BalanceHoldStatusCaptured || hold.Captured != money.Yuan(5) || hold.Released != money.Yuan(3) {
+		t.Fatalf("订单3冻结款结算错误: %+v %v", hold, err)
+	}
+
+	// 冻结、扣款、退回的凭证与冻结金额都能对平
+	reconciler := service.NewBalanceReconciler(db, repository.NewBalanceReconcileReportRepository(db), service.DefaultBalanceReconcileConfig())
+	report, err := reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	items, _ := report.ParseItems()
+	if report.OrdersScanned != 3 || len(items) != 0 {
+		t.Fatalf("冻结款应全部对平: %+v %+v", report, items)
+	}
+
+	// 冻结金额被绕过账本修改时对账能发现
+	db.Model(&model.User{}).Where("id = ?", 1).Update("frozen_balance", 7)
+	report, err = reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	items, _ = report.ParseItems()
+	if report.BalanceDrifts != 1 || items[0].Kind != model.BalanceDriftUserFrozen || items[0].Drift != money.Yuan(7) {
+		t.Fatalf("应发现冻结金额差异: %+v %+v", report, items)
+	}
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+)
+
+// TestBalanceReconcileDriftAndCorrection 测试资金对账发现绕过账本修改的余额与未退款的失败订单，并按流水修正余额
+func TestBalanceReconcileDriftAndCorrection(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&model.Order{}, &model.BalanceReconcileReport{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	if err := db.Create(&model.User{ID: 1, Username: "reconcile", Password: "x"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	creditService := service.NewCreditService(userRepo, repository.NewCreditLogRepository(db))
+	balanceService := service.NewBalanceServiceWithCredit(repository.NewBalanceLogRepository(db), userRepo, creditService)
+	if err := balanceService.Recharge(ctx, 1, money.Yuan(40), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+	if err := creditService.SetCredit(ctx, &model.CreditLogRequest{UserID: 1, Amount: money.Yuan(50), Operator: "admin"}); err != nil {
+		t.Fatalf("设置授信失败: %v", err)
+	}
+
+	// 成功订单扣款一次；失败订单11已退款；失败订单12扣款后漏了退款
+	orders := []struct {
+		id     int64
+		status model.OrderStatus
+		refund bool
+	}{
+		{10, model.OrderStatusSuccess, false},
+		{11, model.OrderStatusFailed, true},
+		{12, model.OrderStatusFailed, false},
+	}
+	for _, o := range orders {
+		order := &model.Order{ID: o.id, OrderNumber: fmt.Sprintf("BR%d", o.id), OutTradeNum: fmt.Sprintf("OUT%d", o.id),
+			CustomerID: 1, Price: money.Yuan(25), Status: o.status, CreateTime: time.Now(), UpdatedAt: time.Now()}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+		if err := balanceService.SmartDeduct(ctx, 1, money.Yuan(25), o.id, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err != nil {
+			t.Fatalf("订单%d扣款失败: %v", o.id, err)
+		}
+		if o.refund {
+			if err := balanceService.Refund(ctx, 1, money.Yuan(25), o.id, "订单退款", "system"); err != nil {
+				t.Fatalf("订单%d退款失败: %v", o.id, err)
+			}
+		}
+	}
+
+	reconciler := service.NewBalanceReconciler(db, repository.NewBalanceReconcileReportRepository(db), service.DefaultBalanceReconcileConfig())
+	report, err := reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	items, _ := report.ParseItems()
+	if report.BalanceDrifts != 0 || report.CreditDrifts != 0 || report.OrderIssues != 1 || report.OrdersScanned != 3 {
+		t.Fatalf("对账结果错误: %+v %+v", report, items)
+	}
+	if items[0].OrderID != 12 || items[0].Deducts != 1 || items[0].Refunds != 0 {
+		t.Fatalf("应报告订单12未退款: %+v", items[0])
+	}
+
+	// 绕过账本直接改余额，对账以流水重算值为准修正
+	var before model.User
+	db.First(&before, 1)
+	db.Model(&model.User{}).Where("id = ?", 1).Update("balance", 999)
+	report, err = reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, true)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	items, _ = report.ParseItems()
+	if report.BalanceDrifts != 1 || report.Corrected != 1 || items[0].Kind != model.BalanceDriftUserBalance ||
+		items[0].Drift != money.Yuan(999)-before.Balance {
+		t.Fatalf("应发现并修正余额差异: %+v %+v", report, items)
+	}
+	var after model.User
+	db.First(&after, 1)
+	if after.Balance != before.Balance {
+		t.Fatalf("余额应修正为流水重算值: %s != %s", after.Balance, before.Balance)
+	}
+	assertLedgerConsistent(t, db, 1)
+
+	// 修正后只剩订单异常
+	report, err = reconciler.Reconcile(ctx, service.BalanceReconcileSourceSchedule, false)
+	if err != nil || report.BalanceDrifts != 0 || report.OrderIssues != 1 {
+		t.Fatalf("修正后余额应一致: %+v %v", report, err)
+	}
+	var saved int64
+	db.Model(&model.BalanceReconcileReport{}).Count(&saved)
+	if saved != 3 {
+		t.Fatalf("发现差异的对账报告都应保存: %d", saved)
+	}
+}
+
+// TestBalanceReconcileSplitOrder 测试拆单父订单扣款、子订单退款时合并核对，子订单漏退款时报告在父订单上
+func TestBalanceReconcileSplitOrder(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&model.Order{}, &model.BalanceReconcileReport{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	if err := db.Create(&model.User{ID: 1, Username: "split", Password: "x"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	balanceService := service.NewBalanceService(repository.NewBalanceLogRepository(db), repository.NewUserRepository(db))
+	if err := balanceService.Recharge(ctx, 1, money.Yuan(100), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+
+	// 父订单扣款30元，拆为20元与10元两笔子订单，10元子订单失败退款
+	now := time.Now()
+	orders := []*model.Order{
+		{ID: 20, OrderNumber: "SP20", Price: money.Yuan(30), Status: model.OrderStatusPartial, IsApart: 1},
+		{ID: 21, OrderNumber: "SP20S01", Price: money.Yuan(20), Status: model.OrderStatusSuccess, IsApart: 1, ApartOrderNumber: "SP20"},
+		{ID: 22, OrderNumber: "SP20S02", Price: money.Yuan(10), Status: model.OrderStatusFailed, IsApart: 1, ApartOrderNumber: "SP20"},
+	}
+	for _, order := range orders {
+		order.OutTradeNum, order.CustomerID, order.CreateTime, order.UpdatedAt = order.OrderNumber, 1, now, now
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+	}
+	if err := balanceService.SmartDeduct(ctx, 1, money.Yuan(30), 20, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err != nil {
+		t.Fatalf("父订单扣款失败: %v", err)
+	}
+	if err := balanceService.Refund(ctx, 1, money.Yuan(10), 22, "订单退款", "system"); err != nil {
+		t.Fatalf("子订单退款失败: %v", err)
+	}
+
+	reconciler := service.NewBalanceReconciler(db, repository.NewBalanceReconcileReportRepository(db), service.DefaultBalanceReconcileConfig())
+	report, err := reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	if report.OrderIssues != 0 || report.OrdersScanned != 1 {
+		items, _ := report.ParseItems()
+		t.Fatalf("拆单订单不应报告异常: %+v %+v", report, items)
+	}
+
+	// 成功的子订单被置为失败却未退款
+	db.Model(&model.Order{}).Where("id = ?", 21).Update("status", model.OrderStatusFailed)
+	report, err = reconciler.Reconcile(ctx, service.BalanceReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("资金对账失败: %v", err)
+	}
+	items, _ := report.ParseItems()
+	if report.OrderIssues != 1 || items[0].OrderID != 20 || items[0].Deducts != 1 || items[0].Refunds != 1 ||
+		!strings.Contains(items[0].Issue, "SP20S01") {
+		t.Fatalf("应在父订单上报告子订单未退款: %+v %+v", report, items)
+	}
+}
@@ -0,0 +1,237 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	notificationModel "recharge-go/internal/model/notification"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+	"recharge-go/pkg/signature"
+
+	"gorm.io/gorm"
+)
+
+// recordingQueue 记录投递的消息，fail 为 true 时投递失败
+type recordingQueue struct {
+	MockQueue
+	fail   bool
+	pushed []interface{}
+}
+
+func (q *recordingQueue) Push(ctx context.Context, key string, value interface{}) error {
+	if q.fail {
+		return errors.New("queue unavailable")
+	}
+	q.pushed = append(q.pushed, value)
+	return nil
+}
+
+// genericCallbackBody 按测试模板生成签名后的回调表单
+func genericCallbackBody(orderNumber, state, secret string) []byte {
+	params := map[string]string{
+		"out_trade_num": orderNumber,
+		"state":         state,
+	}
+	params["sign"] = signature.GenerateDayuanrenSign(params, secret)
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	return []byte(form.Encode())
+}
+
+// setupCallbackPipelineTest 创建通用模板平台与回调处理所需的表和充值服务
+func setupCallbackPipelineTest(t *testing.T) (*gorm.DB, *model.PlatformAPI, service.RechargeService, *recordingQueue) {
+	db, api := setupGenericHTTPTest(t, "http://127.0.0.1")
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderStatusHistory{}, &model.BalanceHold{}, &model.CallbackLog{},
+		&model.OutboxMessage{}, &notificationModel.NotificationRecord{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	q := &recordingQueue{}
+	rechargeService := service.NewRechargeService(db,
+		repository.NewOrderRepository(db),
+		repository.NewPlatformRepository(db),
+		repository.NewPlatformAPIRepository(db),
+		repository.NewRetryRepository(db),
+		repository.NewCallbackLogRepository(db),
+		repository.NewProductAPIRelationRepository(db),
+		repository.NewProductRepository(db),
+		repository.NewPlatformAPIParamRepository(db),
+		nil, nil, &MockNotificationRepo{}, q)
+	return db, api, rechargeService, q
+}
+
+// TestCallbackPipeline 测试回调先落库、验签、去重后完成订单，并通过发件箱投递通知
+func TestCallbackPipeline(t *testing.T) {
+	db, api, rechargeService, q := setupCallbackPipelineTest(t)
+
+	for i, no := range []string{"T202601010001", "T202601010002"} {
+		order := &model.Order{ID: int64(i + 1), OrderNumber: no, OutTradeNum: no, Status: model.OrderStatusRecharging, Denom: money.Yuan(100), PlatformCode: "acme"}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+	}
+	ctx := context.Background()
+	handle := func(body []byte) (*service.CallbackResult, error) {
+		return rechargeService.HandleCallback(ctx, &service.CallbackRequest{PlatformCode: "acme", SourceIP: "127.0.0.1", Body: body})
+	}
+	countLogs := func(status int) int64 {
+		var n int64
+		db.Model(&model.CallbackLog{}).Where("status = ?", status).Count(&n)
+		return n
+	}
+
+	// 首次回调完成订单，返回模板应答并投递一条通知
+	body := genericCallbackBody("T202601010001", "1", "secret")
+	result, err := handle(body)
+	if err != nil {
+		t.Fatalf("处理回调失败: %v", err)
+	}
+	if result.Duplicated || result.Ack != "OK" || result.OrderID != 1 {
+		t.Fatalf("回调结果错误: %+v", result)
+	}
+	var order model.Order
+	db.First(&order, 1)
+	if order.Status != model.OrderStatusSuccess {
+		t.Fatalf("订单状态错误: %s", order.Status)
+	}
+	if len(q.pushed) != 1 {
+		t.Fatalf("应投递1条通知，实际 %d", len(q.pushed))
+	}
+
+	// 重复回调只记录不再处理
+	result, err = handle(body)
+	if err != nil {
+		t.Fatalf("处理重复回调失败: %v", err)
+	}
+	if !result.Duplicated || result.Ack != "OK" {
+		t.Fatalf("重复回调结果错误: %+v", result)
+	}
+	var notifications int64
+	db.Model(&notificationModel.NotificationRecord{}).Where("order_id = ?", 1).Count(&notifications)
+	if len(q.pushed) != 1 || notifications != 1 {
+		t.Fatalf("重复回调不应再次通知: pushed=%d, records=%d", len(q.pushed), notifications)
+	}
+	if countLogs(model.CallbackLogStatusProcessed) != 1 || countLogs(model.CallbackLogStatusDuplicated) != 1 {
+		t.Fatal("回调日志状态错误")
+	}
+
+	// 验签失败与订单不存在都先落库再拒绝
+	if _, err := handle(genericCallbackBody("T202601010002", "1", "wrong")); !errors.Is(err, service.ErrCallbackRejected) {
+		t.Fatalf("签名错误应拒绝回调，实际 %v", err)
+	}
+	if _, err := handle(genericCallbackBody("T209901010001", "1", "secret")); !errors.Is(err, service.ErrCallbackUnmatched) {
+		t.Fatalf("订单不存在应返回未匹配，实际 %v", err)
+	}
+	if countLogs(model.CallbackLogStatusRejected) != 1 || countLogs(model.CallbackLogStatusUnmatched) != 1 {
+		t.Fatal("拒绝的回调应记录日志")
+	}
+
+	// 来源IP不在白名单时即使签名正确也拒绝
+	if err := db.Model(api).Update("callback_allowed_cidrs", "10.0.0.0/8, 192.168.1.10").Error; err != nil {
+		t.Fatalf("更新回调白名单失败: %v", err)
+	}
+	if _, err := handle(genericCallbackBody("T202601010002", "1", "secret")); !errors.Is(err, service.ErrCallbackRejected) {
+		t.Fatalf("来源IP不在白名单应拒绝回调，实际 %v", err)
+	}
+	var rejected model.CallbackLog
+	db.Where("status = ?", model.CallbackLogStatusRejected).Order("id DESC").First(&rejected)
+	if rejected.SourceIP != "127.0.0.1" {
+		t.Fatalf("回调日志应记录来源IP: %+v", rejected)
+	}
+	db.Model(api).Update("callback_allowed_cidrs", "127.0.0.0/8")
+
+	// 队列不可用时订单照常完成，通知留在发件箱由中继补投
+	q.fail = true
+	if _, err := handle(genericCallbackBody("T202601010002", "1", "secret")); err != nil {
+		t.Fatalf("处理回调失败: %v", err)
+	}
+	var pending model.OutboxMessage
+	if err := db.Where("aggregate_id = ? AND status = ?", "2", model.OutboxStatusPending).First(&pending).Error; err != nil {
+		t.Fatalf("发件箱应保留待投递消息: %v", err)
+	}
+	if pending.Attempts != 1 || pending.LastError == "" || !pending.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("发件箱消息投递记录错误: %+v", pending)
+	}
+
+	// 未到重试时间的消息不补投，到期后补投一次
+	q.fail = false
+	outbox := rechargeService.GetOutbox()
+	if published, _ := outbox.Relay(ctx); published != 0 {
+		t.Fatalf("未到重试时间不应补投: published=%d", published)
+	}
+	db.Model(&pending).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second))
+	published, err := outbox.Relay(ctx)
+	if err != nil || published != 1 || len(q.pushed) != 2 {
+		t.Fatalf("中继补投错误: published=%d, pushed=%d, err=%v", published, len(q.pushed), err)
+	}
+	if published, _ := outbox.Relay(ctx); published != 0 || len(q.pushed) != 2 {
+		t.Fatalf("已投递的消息不应重复补投: published=%d", published)
+	}
+}
+
+// TestOutboxRelayBackoff 测试通知记录与发件箱消息同事务写入，中继投递失败后按投递次数退避
+func TestOutboxRelayBackoff(t *testing.T) {
+	db, _, _, q := setupCallbackPipelineTest(t)
+	outbox := service.NewOutbox(db, q)
+	ctx := context.Background()
+
+	// 事务回滚时通知记录与消息都不落库
+	rollback := errors.New("rollback")
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := outbox.AddNotification(tx, &notificationModel.NotificationRecord{OrderID: 9, PlatformCode: "acme", Status: 1}); err != nil {
+			t.Fatalf("写入发件箱失败: %v", err)
+		}
+		return rollback
+	})
+	var records, msgs int64
+	db.Model(&notificationModel.NotificationRecord{}).Count(&records)
+	db.Model(&model.OutboxMessage{}).Count(&msgs)
+	if records != 0 || msgs != 0 {
+		t.Fatalf("回滚后不应留下通知: records=%d, messages=%d", records, msgs)
+	}
+
+	var msg *model.OutboxMessage
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		msg, err = outbox.AddNotification(tx, &notificationModel.NotificationRecord{OrderID: 1, PlatformCode: "acme", Status: 1})
+		return err
+	}); err != nil {
+		t.Fatalf("写入发件箱失败: %v", err)
+	}
+
+	// 提交方尚未投递时中继不抢先投递
+	if published, _ := outbox.Relay(ctx); published != 0 {
+		t.Fatalf("写入后应先由提交方投递: published=%d", published)
+	}
+
+	// 连续投递失败，重试间隔随投递次数增长
+	q.fail = true
+	var last time.Duration
+	for i := 1; i <= 3; i++ {
+		db.Model(msg).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second))
+		if published, _ := outbox.Relay(ctx); published != 0 {
+			t.Fatal("队列不可用时不应投递成功")
+		}
+		var loaded model.OutboxMessage
+		db.First(&loaded, msg.ID)
+		backoff := time.Until(loaded.NextAttemptAt)
+		if loaded.Attempts != i || backoff <= last {
+			t.Fatalf("第%d次投递失败后退避错误: attempts=%d, backoff=%s", i, loaded.Attempts, backoff)
+		}
+		last = backoff
+	}
+
+	q.fail = false
+	db.Model(msg).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second))
+	if published, err := outbox.Relay(ctx); err != nil || published != 1 || len(q.pushed) != 1 {
+		t.Fatalf("恢复后应补投: published=%d, pushed=%d, err=%v", published, len(q.pushed), err)
+	}
+}
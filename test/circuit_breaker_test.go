@@ -0,0 +1,100 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"recharge-go/internal/service/recharge"
+)
+
+// TestCircuitBreakerLifecycle 测试熔断器 关闭->熔断->半开->恢复 的状态流转
+func TestCircuitBreakerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	config := recharge.BreakerConfig{
+		Window:               time.Minute,
+		MinRequests:          4,
+		FailureRateThreshold: 0.5,
+		OpenDuration:         50 * time.Millisecond,
+		HalfOpenProbes:       2,
+		CallbackWeight:       0.5,
+	}
+	breaker := recharge.NewCircuitBreaker(nil, config, nil)
+	const apiID = 1
+
+	// 请求数未达到阈值时不熔断
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	if !breaker.Allow(ctx, apiID) {
+		t.Fatal("请求数不足时不应熔断")
+	}
+
+	// 失败率超过阈值后熔断
+	breaker.Record(ctx, apiID, recharge.HealthEventQuery, true)
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	if breaker.Allow(ctx, apiID) || breaker.Available(ctx, apiID) {
+		t.Fatal("失败率超过阈值后应熔断")
+	}
+	health, err := breaker.Health(ctx, apiID)
+	if err != nil {
+		t.Fatalf("获取健康状况失败: %v", err)
+	}
+	if health.State != recharge.CircuitOpen || health.Score != 0 {
+		t.Fatalf("熔断状态错误: %+v", health)
+	}
+
+	// 熔断到期后进入半开，只放行限定数量的探测请求
+	time.Sleep(60 * time.Millisecond)
+	if !breaker.Available(ctx, apiID) {
+		t.Fatal("熔断到期后应可被路由选中")
+	}
+	if !breaker.Allow(ctx, apiID) || !breaker.Allow(ctx, apiID) {
+		t.Fatal("半开状态应放行探测请求")
+	}
+	if breaker.Allow(ctx, apiID) {
+		t.Fatal("半开状态探测名额用尽后应拒绝")
+	}
+
+	// 探测全部成功后恢复，恢复前的失败不再参与判断
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, true)
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, true)
+	health, _ = breaker.Health(ctx, apiID)
+	if health.State != recharge.CircuitClosed {
+		t.Fatalf("探测成功后应恢复: %+v", health)
+	}
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	if !breaker.Allow(ctx, apiID) {
+		t.Fatal("恢复后单次失败不应立即熔断")
+	}
+}
+
+// TestCircuitBreakerHalfOpenFailure 测试半开探测失败重新熔断
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	ctx := context.Background()
+	config := recharge.DefaultBreakerConfig()
+	config.MinRequests = 1
+	config.OpenDuration = 20 * time.Millisecond
+	breaker := recharge.NewCircuitBreaker(nil, config, nil)
+	const apiID = 2
+
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	if breaker.Allow(ctx, apiID) {
+		t.Fatal("应熔断")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !breaker.Allow(ctx, apiID) {
+		t.Fatal("熔断到期后应放行探测请求")
+	}
+	breaker.Record(ctx, apiID, recharge.HealthEventSubmit, false)
+	if breaker.Allow(ctx, apiID) {
+		t.Fatal("探测失败后应重新熔断")
+	}
+
+	if err := breaker.Reset(ctx, apiID); err != nil {
+		t.Fatalf("手动恢复失败: %v", err)
+	}
+	if !breaker.Allow(ctx, apiID) {
+		t.Fatal("手动恢复后应放行")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"sync"
 	"testing"
 
@@ -25,9 +26,13 @@ func TestConcurrentDeductBalance(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to connect database: %v", err)
 	}
+	// 内存数据库每个连接相互独立，限制为单连接保证并发扣款访问同一个库
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	// 2. 自动迁移表结构
-	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -58,8 +63,8 @@ func TestConcurrentDeductBalance(t *testing.T) {
 	// 创建用户，初始余额1000元
 	user := &model.User{
 		ID:      userID,
-		Balance: 1000.0,
-		Credit:  0.0,
+		Balance: money.Yuan(1000),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -83,13 +88,14 @@ func TestConcurrentDeductBalance(t *testing.T) {
 
 	// 5. 并发测试参数
 	concurrentCount := 20 // 并发数
-	deductAmount := 100.0 // 每次扣款金额
+	deductAmount := money.Yuan(100) // 每次扣款金额
 	expectedSuccessCount := 10 // 预期成功次数（1000/100=10）
 
 	// 6. 执行并发扣款测试
 	var wg sync.WaitGroup
 	var successCount int64
 	var failureCount int64
+	var succeededOrderID int64
 	var mu sync.Mutex
 
 	for i := 0; i < concurrentCount; i++ {
@@ -103,6 +109,7 @@ func TestConcurrentDeductBalance(t *testing.T) {
 				t.Logf("订单%d扣款失败: %v", orderID, err)
 			} else {
 				successCount++
+				succeededOrderID = int64(orderID)
 				t.Logf("订单%d扣款成功", orderID)
 			}
 			mu.Unlock()
@@ -123,9 +130,9 @@ func TestConcurrentDeductBalance(t *testing.T) {
 	// 检查用户最终余额
 	var finalUser model.User
 	db.First(&finalUser, userID)
-	expectedBalance := 1000.0 - float64(successCount)*deductAmount
+	expectedBalance := money.Yuan(1000) - deductAmount.MulInt(successCount)
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("预期最终余额%.2f，实际余额%.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("预期最终余额%s，实际余额%s", expectedBalance, finalUser.Balance)
 	}
 
 	// 检查余额日志数量
@@ -137,8 +144,8 @@ func TestConcurrentDeductBalance(t *testing.T) {
 
 	// 8. 测试幂等性
 	t.Log("开始测试幂等性...")
-	// 重复执行第一个订单的扣款，应该被跳过
-	err = balanceService.DeductBalance(ctx, accountID, deductAmount, 1, "重复测试订单1")
+	// 重复执行已扣款成功的订单，应该被跳过（并发下哪些订单成功取决于调度顺序）
+	err = balanceService.DeductBalance(ctx, accountID, deductAmount, succeededOrderID, "重复测试订单")
 	if err != nil {
 		t.Errorf("幂等性测试失败: %v", err)
 	}
@@ -147,7 +154,7 @@ func TestConcurrentDeductBalance(t *testing.T) {
 	var idempotentUser model.User
 	db.First(&idempotentUser, userID)
 	if idempotentUser.Balance != finalUser.Balance {
-		t.Errorf("幂等性测试失败，余额发生了变化: %.2f -> %.2f", finalUser.Balance, idempotentUser.Balance)
+		t.Errorf("幂等性测试失败，余额发生了变化: %s -> %s", finalUser.Balance, idempotentUser.Balance)
 	}
 
 	t.Log("并发扣款安全性测试通过！")
@@ -162,9 +169,13 @@ func TestConcurrentDeductWithCredit(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to connect database: %v", err)
 	}
+	// 内存数据库每个连接相互独立，限制为单连接保证并发扣款访问同一个库
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	// 2. 自动迁移表结构
-	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -188,8 +199,8 @@ func TestConcurrentDeductWithCredit(t *testing.T) {
 	// 创建用户，余额500元，授信额度1000元
 	user := &model.User{
 		ID:      userID,
-		Balance: 500.0,
-		Credit:  1000.0,
+		Balance: money.Yuan(500),
+		Credit:  money.Yuan(1000),
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -213,7 +224,7 @@ func TestConcurrentDeductWithCredit(t *testing.T) {
 
 	// 5. 并发测试参数
 	concurrentCount := 30 // 并发数
-	deductAmount := 100.0 // 每次扣款金额
+	deductAmount := money.Yuan(100) // 每次扣款金额
 	expectedSuccessCount := 15 // 预期成功次数（(500+1000)/100=15）
 
 	// 6. 执行并发扣款测试
@@ -253,17 +264,17 @@ func TestConcurrentDeductWithCredit(t *testing.T) {
 	// 检查用户最终余额（应该为负数，使用了授信额度）
 	var finalUser model.User
 	db.First(&finalUser, userID)
-	expectedBalance := 500.0 - float64(successCount)*deductAmount
+	expectedBalance := money.Yuan(500) - deductAmount.MulInt(successCount)
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("预期最终余额%.2f，实际余额%.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("预期最终余额%s，实际余额%s", expectedBalance, finalUser.Balance)
 	}
 
 	// 验证授信额度使用情况
 	if finalUser.Balance < 0 {
 		creditUsed := -finalUser.Balance
-		t.Logf("使用授信额度: %.2f", creditUsed)
+		t.Logf("使用授信额度: %s", creditUsed)
 		if creditUsed > finalUser.Credit {
-			t.Errorf("使用的授信额度%.2f超过了可用额度%.2f", creditUsed, finalUser.Credit)
+			t.Errorf("使用的授信额度%s超过了可用额度%s", creditUsed, finalUser.Credit)
 		}
 	}
 
@@ -2,7 +2,9 @@ package test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -12,10 +14,12 @@ import (
 	"gorm.io/gorm/logger"
 
 	"recharge-go/internal/model"
-	notificationModel "recharge-go/internal/model/notification"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/internal/service/recharge"
 	"recharge-go/pkg/lock"
+	"recharge-go/pkg/money"
+	notificationModel "recharge-go/internal/model/notification"
 )
 
 // TestConcurrentOrderFailRefund 测试并发订单失败退款的修复
@@ -29,9 +33,14 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 	if err != nil {
 		t.Fatalf("连接数据库失败: %v", err)
 	}
+	t.Cleanup(func() { os.Remove(dbPath) })
+	// sqlite 不支持并发写事务，限制为单连接让两个失败处理排队执行，避免 database is locked
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	// 2. 自动迁移
-	err = db.AutoMigrate(&model.User{}, &model.Order{}, &model.BalanceLog{}, &model.Platform{}, &model.PlatformAccount{})
+	err = db.AutoMigrate(&model.User{}, &model.Order{}, &model.OrderStatusHistory{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{}, &model.Platform{}, &model.PlatformAccount{}, &model.OutboxMessage{}, &notificationModel.NotificationRecord{})
 	if err != nil {
 		t.Fatalf("数据库迁移失败: %v", err)
 	}
@@ -44,7 +53,7 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 		ID:       1,
 		Username: "testuser",
 		Password: "password123",
-		Balance:  9813628.00, // 初始余额
+		Balance:  money.MustParse("9813628.00"), // 初始余额
 		Status:   1,
 	}
 	if err := db.Create(user).Error; err != nil {
@@ -64,7 +73,7 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 	account := &model.PlatformAccount{
 		PlatformID:  platform.ID,
 		AccountName: "测试账户",
-		Balance:     10000.00,
+		Balance:     money.Yuan(10000),
 	}
 	if err := db.Create(account).Error; err != nil {
 		t.Fatalf("创建测试平台账户失败: %v", err)
@@ -77,7 +86,7 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 		OutTradeNum:       "OUT001",
 		CustomerID:        user.ID,
 		PlatformAccountID: account.ID,
-		Price:             96.50,
+		Price:             money.MustParse("96.50"),
 		Status:            model.OrderStatusRecharging, // 充值中状态
 		Client:            1,                           // 平台订单
 	}
@@ -91,7 +100,7 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 		OutTradeNum:       "OUT002",
 		CustomerID:        user.ID,
 		PlatformAccountID: account.ID,
-		Price:             96.50,
+		Price:             money.MustParse("96.50"),
 		Status:            model.OrderStatusRecharging, // 充值中状态
 		Client:            1,                           // 平台订单
 	}
@@ -182,11 +191,11 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 	}
 
 	// 预期余额：初始余额 + 两笔退款
-	expectedBalance := 9813628.00 + 96.50 + 96.50
+	expectedBalance := money.MustParse("9813628.00") + money.MustParse("96.50") + money.MustParse("96.50")
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("用户最终余额不正确，期望: %.2f，实际: %.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("用户最终余额不正确，期望: %s，实际: %s", expectedBalance, finalUser.Balance)
 	} else {
-		t.Logf("✅ 用户最终余额正确: %.2f", finalUser.Balance)
+		t.Logf("✅ 用户最终余额正确: %s", finalUser.Balance)
 	}
 
 	// 检查余额日志数量
@@ -217,6 +226,11 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 		t.Errorf("订单2状态不正确，期望: %d，实际: %d", model.OrderStatusFailed, finalOrder2.Status)
 	}
 
+	// 通用的状态修改不能绕过退款流程把失败订单置为已退款
+	if err := orderService.UpdateOrderStatus(ctx, order1.ID, model.OrderStatusRefunded); !errors.Is(err, service.ErrIllegalOrderTransition) {
+		t.Errorf("通用状态修改应拒绝涉及资金的状态，实际: %v", err)
+	}
+
 	// 检查余额日志的详细信息
 	var logs []model.BalanceLog
 	if err := db.Where("user_id = ? AND style = ?", user.ID, model.BalanceStyleRefund).Order("created_at").Find(&logs).Error; err != nil {
@@ -225,24 +239,24 @@ func TestConcurrentOrderFailRefund(t *testing.T) {
 
 	if len(logs) == 2 {
 		// 验证第一笔退款
-		if logs[0].BalanceBefore != 9813628.00 {
-			t.Errorf("第一笔退款前余额不正确，期望: 9813628.00，实际: %.2f", logs[0].BalanceBefore)
+		if logs[0].BalanceBefore != money.MustParse("9813628.00") {
+			t.Errorf("第一笔退款前余额不正确，期望: 9813628.00，实际: %s", logs[0].BalanceBefore)
 		}
-		if logs[0].Balance != 9813724.50 {
-			t.Errorf("第一笔退款后余额不正确，期望: 9813724.50，实际: %.2f", logs[0].Balance)
+		if logs[0].Balance != money.MustParse("9813724.50") {
+			t.Errorf("第一笔退款后余额不正确，期望: 9813724.50，实际: %s", logs[0].Balance)
 		}
 
 		// 验证第二笔退款
-		if logs[1].BalanceBefore != 9813724.50 {
-			t.Errorf("第二笔退款前余额不正确，期望: 9813724.50，实际: %.2f", logs[1].BalanceBefore)
+		if logs[1].BalanceBefore != money.MustParse("9813724.50") {
+			t.Errorf("第二笔退款前余额不正确，期望: 9813724.50，实际: %s", logs[1].BalanceBefore)
 		}
-		if logs[1].Balance != 9813821.00 {
-			t.Errorf("第二笔退款后余额不正确，期望: 9813821.00，实际: %.2f", logs[1].Balance)
+		if logs[1].Balance != money.MustParse("9813821.00") {
+			t.Errorf("第二笔退款后余额不正确，期望: 9813821.00，实际: %s", logs[1].Balance)
 		}
 
 		t.Logf("✅ 余额变化记录正确:")
-		t.Logf("   第一笔: %.2f -> %.2f (+%.2f)", logs[0].BalanceBefore, logs[0].Balance, logs[0].Amount)
-		t.Logf("   第二笔: %.2f -> %.2f (+%.2f)", logs[1].BalanceBefore, logs[1].Balance, logs[1].Amount)
+		t.Logf("   第一笔: %s -> %s (+%s)", logs[0].BalanceBefore, logs[0].Balance, logs[0].Amount)
+		t.Logf("   第二笔: %s -> %s (+%s)", logs[1].BalanceBefore, logs[1].Balance, logs[1].Amount)
 	}
 
 	t.Logf("✅ 并发订单失败退款测试通过！")
@@ -262,18 +276,44 @@ func (m *MockRechargeService) GetUserBalanceService() *service.BalanceService {
 	return m.userBalanceService
 }
 
+func (m *MockRechargeService) GetRoutingService() *service.RoutingService {
+	return nil
+}
+
+func (m *MockRechargeService) GetSplitService() *service.OrderSplitService {
+	return nil
+}
+
+func (m *MockRechargeService) GetRechargeQueue() *service.RechargeQueue {
+	return nil
+}
+
+func (m *MockRechargeService) GetStatusPoller() *service.OrderStatusPoller {
+	return nil
+}
+
+func (m *MockRechargeService) GetOutbox() *service.Outbox {
+	return nil
+}
+
 // 实现RechargeService接口的其他方法（空实现）
 func (m *MockRechargeService) Recharge(ctx context.Context, orderID int64) error { return nil }
-func (m *MockRechargeService) HandleCallback(ctx context.Context, platformName string, data []byte) error { return nil }
-func (m *MockRechargeService) GetPendingTasks(ctx context.Context, limit int) ([]*model.Order, error) { return nil, nil }
+func (m *MockRechargeService) HandleCallback(ctx context.Context, req *service.CallbackRequest) (*service.CallbackResult, error) {
+	return &service.CallbackResult{}, nil
+}
 func (m *MockRechargeService) ProcessRechargeTask(ctx context.Context, order *model.Order) error { return nil }
 func (m *MockRechargeService) CreateRechargeTask(ctx context.Context, orderID int64) error { return nil }
 func (m *MockRechargeService) GetPlatformAPIByOrderID(ctx context.Context, orderID string) (*model.PlatformAPI, *model.PlatformAPIParam, error) { return nil, nil, nil }
 func (m *MockRechargeService) PushToRechargeQueue(ctx context.Context, orderID int64) error { return nil }
-func (m *MockRechargeService) PopFromRechargeQueue(ctx context.Context) (int64, error) { return 0, nil }
 func (m *MockRechargeService) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) { return nil, nil }
-func (m *MockRechargeService) RemoveFromProcessingQueue(ctx context.Context, orderID int64) error { return nil }
 func (m *MockRechargeService) CheckRechargingOrders(ctx context.Context) error { return nil }
+func (m *MockRechargeService) QueryOrderStatus(ctx context.Context, order *model.Order) (*recharge.QueryResult, error) {
+	return &recharge.QueryResult{Status: order.Status}, nil
+}
+func (m *MockRechargeService) BatchQueryOrderStatus(ctx context.Context, platformCode string, orders []*model.Order) (map[int64]*recharge.QueryResult, error) {
+	return map[int64]*recharge.QueryResult{}, nil
+}
+func (m *MockRechargeService) SettleOrderResult(ctx context.Context, order *model.Order, result service.OrderResult) (bool, error) { return false, nil }
 func (m *MockRechargeService) SubmitOrder(ctx context.Context, order *model.Order, api *model.PlatformAPI, apiParam *model.PlatformAPIParam) error { return nil }
 
 // MockNotificationRepo 模拟通知仓库
@@ -281,12 +321,19 @@ type MockNotificationRepo struct{}
 
 func (m *MockNotificationRepo) Create(ctx context.Context, notification *notificationModel.NotificationRecord) error { return nil }
 func (m *MockNotificationRepo) GetByID(ctx context.Context, id int64) (*notificationModel.NotificationRecord, error) { return nil, nil }
+func (m *MockNotificationRepo) GetByOrderID(ctx context.Context, orderID int64) (*notificationModel.NotificationRecord, error) { return nil, nil }
 func (m *MockNotificationRepo) Update(ctx context.Context, notification *notificationModel.NotificationRecord) error { return nil }
 func (m *MockNotificationRepo) Delete(ctx context.Context, id int64) error { return nil }
 func (m *MockNotificationRepo) List(ctx context.Context, params map[string]interface{}, page, pageSize int) ([]*notificationModel.NotificationRecord, int64, error) { return nil, 0, nil }
 func (m *MockNotificationRepo) GetPendingRecords(ctx context.Context, limit int) ([]*notificationModel.NotificationRecord, error) { return nil, nil }
 func (m *MockNotificationRepo) UpdateStatus(ctx context.Context, id int64, status int) error { return nil }
 func (m *MockNotificationRepo) IncrementRetryCount(ctx context.Context, id int64) error { return nil }
+func (m *MockNotificationRepo) Claim(ctx context.Context, id int64, retryCount int) (bool, error) { return true, nil }
+func (m *MockNotificationRepo) MarkRetry(ctx context.Context, id int64, retryCount int, nextRetryTime time.Time, lastError string) error { return nil }
+func (m *MockNotificationRepo) MarkGaveUp(ctx context.Context, id int64, retryCount int, lastError string) error { return nil }
+func (m *MockNotificationRepo) GetDueRecords(ctx context.Context, now time.Time, limit int) ([]*notificationModel.NotificationRecord, error) { return nil, nil }
+func (m *MockNotificationRepo) ResetStale(ctx context.Context, before time.Time) (int64, error) { return 0, nil }
+func (m *MockNotificationRepo) Requeue(ctx context.Context, id int64) (bool, error) { return false, nil }
 
 // MockQueue 模拟队列
 type MockQueue struct{}
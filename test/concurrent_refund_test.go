@@ -8,6 +8,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"sync"
 	"testing"
 	"time"
@@ -32,10 +33,13 @@ func TestConcurrentRefundBalance(t *testing.T) {
 		db.Exec("DROP TABLE IF EXISTS platform_accounts")
 		db.Exec("DROP TABLE IF EXISTS platforms")
 		db.Exec("DROP TABLE IF EXISTS users")
+		db.Exec("DROP TABLE IF EXISTS ledger_transactions")
+		db.Exec("DROP TABLE IF EXISTS ledger_entries")
+		db.Exec("DROP TABLE IF EXISTS balance_holds")
 	}()
 
 	// 2. 自动迁移表结构
-	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -60,8 +64,8 @@ func TestConcurrentRefundBalance(t *testing.T) {
 	// 创建用户，初始余额100元
 	user := &model.User{
 		ID:      userID,
-		Balance: 100.0,
-		Credit:  0.0,
+		Balance: money.Yuan(100),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -77,8 +81,8 @@ func TestConcurrentRefundBalance(t *testing.T) {
 		AppKey:       "test_key",
 		AppSecret:    "test_secret",
 		Description:  "测试账号",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
@@ -106,7 +110,7 @@ func TestConcurrentRefundBalance(t *testing.T) {
 	balanceService := service.NewPlatformAccountBalanceService(db, platformAccountRepo, userRepo, balanceLogRepo)
 
 	// 5. 先执行一次扣款，创建扣款记录
-	deductAmount := 50.0
+	deductAmount := money.Yuan(50)
 	err = balanceService.DeductBalance(ctx, accountID, deductAmount, orderID, "测试订单扣款")
 	if err != nil {
 		t.Fatalf("扣款失败: %v", err)
@@ -115,11 +119,11 @@ func TestConcurrentRefundBalance(t *testing.T) {
 	// 验证扣款后余额
 	var userAfterDeduct model.User
 	db.First(&userAfterDeduct, userID)
-	t.Logf("扣款后余额: %.2f", userAfterDeduct.Balance)
+	t.Logf("扣款后余额: %s", userAfterDeduct.Balance)
 
 	// 6. 并发退款测试参数
 	concurrentCount := 10 // 并发数
-	refundAmount := 50.0  // 退款金额
+	refundAmount := money.Yuan(50) // 退款金额
 
 	// 7. 执行并发退款测试
 	var wg sync.WaitGroup
@@ -154,11 +158,11 @@ func TestConcurrentRefundBalance(t *testing.T) {
 	// 检查用户最终余额 - 应该只退款一次
 	var finalUser model.User
 	db.First(&finalUser, userID)
-	expectedBalance := 100.0 // 初始余额100 - 扣款50 + 退款50 = 100
-	t.Logf("最终余额: %.2f, 预期余额: %.2f", finalUser.Balance, expectedBalance)
+	expectedBalance := money.Yuan(100) // 初始余额100 - 扣款50 + 退款50 = 100
+	t.Logf("最终余额: %s, 预期余额: %s", finalUser.Balance, expectedBalance)
 
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("余额异常！预期最终余额%.2f，实际余额%.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("余额异常！预期最终余额%s，实际余额%s", expectedBalance, finalUser.Balance)
 	}
 
 	// 检查退款日志数量 - 应该只有一条退款记录
@@ -179,14 +183,14 @@ func TestConcurrentRefundBalance(t *testing.T) {
 		if log.Style == model.BalanceStyleRefund {
 			logType = "退款"
 		}
-		t.Logf("  %s: 金额%.2f, 余额变化%.2f->%.2f, 时间%v", logType, log.Amount, log.BalanceBefore, log.Balance, log.CreatedAt.Format("15:04:05.000"))
+		t.Logf("  %s: 金额%s, 余额变化%s->%s, 时间%v", logType, log.Amount, log.BalanceBefore, log.Balance, log.CreatedAt.Format("15:04:05.000"))
 	}
 
 	// 检查业务逻辑的正确性：余额正确且只有一条退款日志
 	if finalUser.Balance == expectedBalance && refundLogCount == 1 {
 		t.Log("✅ 并发退款安全性测试通过！幂等性正常工作，余额正确，只有一条退款记录")
 	} else {
-		t.Errorf("❌ 并发退款安全性测试失败！余额%.2f(期望%.2f)，退款日志%d条(期望1条)", finalUser.Balance, expectedBalance, refundLogCount)
+		t.Errorf("❌ 并发退款安全性测试失败！余额%s(期望%s)，退款日志%d条(期望1条)", finalUser.Balance, expectedBalance, refundLogCount)
 	}
 }
 
@@ -201,7 +205,7 @@ func TestRefundWithoutRowLock(t *testing.T) {
 	}
 
 	// 2. 自动迁移表结构
-	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -225,8 +229,8 @@ func TestRefundWithoutRowLock(t *testing.T) {
 	// 创建用户，初始余额200元
 	user := &model.User{
 		ID:      userID,
-		Balance: 200.0,
-		Credit:  0.0,
+		Balance: money.Yuan(200),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -242,8 +246,8 @@ func TestRefundWithoutRowLock(t *testing.T) {
 		AppKey:       "test_key2",
 		AppSecret:    "test_secret2",
 		Description:  "测试账号2",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
@@ -271,7 +275,7 @@ func TestRefundWithoutRowLock(t *testing.T) {
 	balanceService := service.NewPlatformAccountBalanceService(db, platformAccountRepo, userRepo, balanceLogRepo)
 
 	// 5. 先执行两次扣款，模拟用户反馈的场景
-	deductAmount := 30.0
+	deductAmount := money.Yuan(30)
 	orderID1 := int64(201)
 	orderID2 := int64(202)
 
@@ -290,7 +294,7 @@ func TestRefundWithoutRowLock(t *testing.T) {
 	// 验证扣款后余额
 	var userAfterDeduct model.User
 	db.First(&userAfterDeduct, userID)
-	t.Logf("两次扣款后余额: %.2f (200 - 30 - 30 = 140)", userAfterDeduct.Balance)
+	t.Logf("两次扣款后余额: %s (200 - 30 - 30 = 140)", userAfterDeduct.Balance)
 
 	// 6. 模拟用户反馈的场景：获取到了2个同样的金额导致退款少退了
 	// 这里我们测试如果退款逻辑有问题，是否会出现少退的情况
@@ -337,11 +341,11 @@ func TestRefundWithoutRowLock(t *testing.T) {
 	// 检查最终余额
 	var finalUser model.User
 	db.First(&finalUser, userID)
-	expectedBalance := 200.0 // 初始200 - 扣款30 - 扣款30 + 退款30 + 退款30 = 200
-	t.Logf("最终余额: %.2f, 预期余额: %.2f", finalUser.Balance, expectedBalance)
+	expectedBalance := money.Yuan(200) // 初始200 - 扣款30 - 扣款30 + 退款30 + 退款30 = 200
+	t.Logf("最终余额: %s, 预期余额: %s", finalUser.Balance, expectedBalance)
 
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("❌ 余额异常！预期最终余额%.2f，实际余额%.2f，可能存在退款少退问题", expectedBalance, finalUser.Balance)
+		t.Errorf("❌ 余额异常！预期最终余额%s，实际余额%s，可能存在退款少退问题", expectedBalance, finalUser.Balance)
 	} else {
 		t.Log("✅ 余额正确，退款逻辑正常")
 	}
@@ -364,6 +368,6 @@ func TestRefundWithoutRowLock(t *testing.T) {
 		if log.Style == model.BalanceStyleRefund {
 			logType = "退款"
 		}
-		t.Logf("  订单%d %s: 金额%.2f, 余额变化%.2f->%.2f", log.OrderID, logType, log.Amount, log.BalanceBefore, log.Balance)
+		t.Logf("  订单%d %s: 金额%s, 余额变化%s->%s", log.OrderID, logType, log.Amount, log.BalanceBefore, log.Balance)
 	}
 }
\ No newline at end of file
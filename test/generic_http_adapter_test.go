@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/pkg/signature"
+
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const genericTestTemplate = `{
+	"generic_http": {
+		"submit": {
+			"path": "/recharge",
+			"content_type": "form",
+			"fields": {
+				"userid": "{{account.account_name}}",
+				"out_trade_num": "{{order.order_number}}",
+				"mobile": "{{order.mobile}}",
+				"product_id": "{{param.product_id}}",
+				"notify_url": "{{api.callback_url}}"
+			},
+			"sign": {"algorithm": "dayuanren"},
+			"success": {"field": "errno", "values": ["0"]},
+			"message_field": "errmsg"
+		},
+		"query": {
+			"path": "/check",
+			"content_type": "form",
+			"fields": {"out_trade_num": "{{order.order_number}}"},
+			"success": {"field": "errno", "values": ["0"]},
+			"status_field": "data.state",
+			"amount_field": "data.charge_amount",
+			"order_id_field": "data.order_number",
+			"voucher_field": "data.voucher",
+			"message_field": "data.remark"
+		},
+		"callback": {
+			"order_number_field": "out_trade_num",
+			"status_field": "state",
+			"amount_field": "charge_amount",
+			"sign": {"algorithm": "dayuanren"},
+			"ack": "OK"
+		},
+		"status_mapping": {"1": 4, "2": 5, "0": 3}
+	}
+}`
+
+// setupGenericHTTPTest 创建通用HTTP适配器测试数据
+func setupGenericHTTPTest(t *testing.T, serverURL string) (*gorm.DB, *model.PlatformAPI) {
+	dbPath := fmt.Sprintf("test_generic_http_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.PlatformAccount{}, &model.PlatformAPI{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	account := &model.PlatformAccount{
+		PlatformID:  1,
+		AccountName: "generic_user",
+		AppKey:      "key",
+		AppSecret:   "secret",
+		Status:      1,
+	}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("创建平台账号失败: %v", err)
+	}
+
+	api := &model.PlatformAPI{
+		PlatformID:  1,
+		Name:        "通用平台",
+		Code:        "acme",
+		URL:         serverURL,
+		Method:      "POST",
+		CallbackURL: "https://example.com/api/v1/callback/generic/acme",
+		Timeout:     5,
+		Status:      1,
+		ExtraParams: datatypes.JSON(genericTestTemplate),
+		AccountID:   account.ID,
+	}
+	if err := db.Create(api).Error; err != nil {
+		t.Fatalf("创建平台接口失败: %v", err)
+	}
+	return db, api
+}
+
+// TestGenericHTTPSubmitOrder 测试通用HTTP适配器按模板渲染字段、签名并判断响应
+func TestGenericHTTPSubmitOrder(t *testing.T) {
+	var received url.Values
+	errno := "0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		received = r.PostForm
+		w.Write([]byte(`{"errno":` + errno + `,"errmsg":"余额不足"}`))
+	}))
+	defer server.Close()
+
+	db, api := setupGenericHTTPTest(t, server.URL)
+	manager := recharge.NewManager(db)
+
+	order := &model.Order{ID: 1, OrderNumber: "T202601010001", Mobile: "13800138000"}
+	apiParam := &model.PlatformAPIParam{ProductID: "P100"}
+
+	if err := manager.SubmitOrder(context.Background(), order, api, apiParam); err != nil {
+		t.Fatalf("提交订单失败: %v", err)
+	}
+
+	if received.Get("out_trade_num") != order.OrderNumber || received.Get("product_id") != "P100" || received.Get("userid") != "generic_user" {
+		t.Fatalf("请求字段渲染错误: %v", received)
+	}
+	params := make(map[string]string)
+	for k := range received {
+		params[k] = received.Get(k)
+	}
+	if !signature.VerifyDayuanrenSign(params, "secret") {
+		t.Fatalf("请求签名错误: %v", received)
+	}
+
+	// 成功条件未命中时返回错误
+	errno = "1"
+	if err := manager.SubmitOrder(context.Background(), order, api, apiParam); err == nil {
+		t.Fatal("响应失败时应返回错误")
+	}
+}
+
+// TestGenericHTTPQueryOrderStatus 测试通用HTTP适配器查询返回完整结果，并按接口超时时间中止查询
+func TestGenericHTTPQueryOrderStatus(t *testing.T) {
+	delay := time.Duration(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte(`{"errno":0,"data":{"state":"1","charge_amount":"50.00","order_number":"S1001","voucher":"V2001","remark":"充值成功"}}`))
+	}))
+	defer server.Close()
+
+	db, api := setupGenericHTTPTest(t, server.URL)
+	manager := recharge.NewManager(db)
+	order := &model.Order{ID: 1, OrderNumber: "T202601010001", PlatformCode: "acme", APICurID: api.ID, Status: model.OrderStatusRecharging}
+
+	result, err := manager.QueryOrderStatus(context.Background(), order)
+	if err != nil {
+		t.Fatalf("查询订单状态失败: %v", err)
+	}
+	if result.Status != model.OrderStatusSuccess || result.SupplierOrderID != "S1001" || result.Voucher != "V2001" ||
+		result.CreditedAmount != 50 || result.Message != "充值成功" || result.Raw == "" {
+		t.Fatalf("查询结果错误: %+v", result)
+	}
+	if order.Status != model.OrderStatusRecharging {
+		t.Fatalf("查询不应修改订单状态: %d", order.Status)
+	}
+
+	// 上游响应超过接口配置的超时时间
+	db.Model(api).Update("timeout", 1)
+	delay = 1500 * time.Millisecond
+	start := time.Now()
+	if _, err := manager.QueryOrderStatus(context.Background(), order); err == nil {
+		t.Fatal("查询超时应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Fatalf("查询未按接口超时时间中止: %v", elapsed)
+	}
+}
+
+// TestGenericHTTPParseCallback 测试通用HTTP适配器回调验签与状态映射
+func TestGenericHTTPParseCallback(t *testing.T) {
+	db, _ := setupGenericHTTPTest(t, "http://127.0.0.1")
+	manager := recharge.NewManager(db)
+
+	params := map[string]string{
+		"out_trade_num": "T202601010001",
+		"state":         "1",
+		"charge_amount": "50.00",
+	}
+	params["sign"] = signature.GenerateDayuanrenSign(params, "secret")
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+
+	data, err := manager.ParseCallbackData("acme", []byte(form.Encode()))
+	if err != nil {
+		t.Fatalf("解析回调失败: %v", err)
+	}
+	if data.OrderNumber != "T202601010001" || data.Status != fmt.Sprint(int(model.OrderStatusSuccess)) || data.Amount != "50.00" {
+		t.Fatalf("回调解析结果错误: %+v", data)
+	}
+
+	if err := manager.VerifyCallback(context.Background(), "acme", nil, []byte(form.Encode())); err != nil {
+		t.Fatalf("回调验签失败: %v", err)
+	}
+
+	// 签名错误时拒绝回调
+	form.Set("sign", "bad")
+	if err := manager.VerifyCallback(context.Background(), "acme", nil, []byte(form.Encode())); err == nil {
+		t.Fatal("签名错误时应返回错误")
+	}
+}
+
+// TestCheckCallbackTimestamp 测试回调时间戳在允许偏差内才通过
+func TestCheckCallbackTimestamp(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	cases := []struct {
+		value string
+		ok    bool
+	}{
+		{fmt.Sprint(now.Unix()), true},
+		{fmt.Sprint(now.Add(-5 * time.Minute).UnixMilli()), true},
+		{now.Add(3 * time.Minute).Format("2006-01-02 15:04:05"), true},
+		{fmt.Sprint(now.Add(-11 * time.Minute).Unix()), false},
+		{fmt.Sprint(now.Add(11 * time.Minute).Unix()), false},
+		{"", false},
+		{"yesterday", false},
+	}
+	for _, c := range cases {
+		err := recharge.CheckCallbackTimestamp(c.value, now, recharge.DefaultCallbackTolerance)
+		if (err == nil) != c.ok {
+			t.Errorf("时间戳 %q 校验结果错误: %v", c.value, err)
+		}
+	}
+	if err := recharge.CheckCallbackTimestamp("", now, 0); err != nil {
+		t.Errorf("未设置允许偏差时不应校验: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+)
+
+// TestLedgerIdempotencyKey 测试重复的扣款、退款与携带相同幂等键的充值只记账一次
+func TestLedgerIdempotencyKey(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	userID := int64(1)
+	db.Create(&model.Platform{ID: 1, Code: "test", Name: "测试平台"})
+	db.Create(&model.User{ID: userID, Username: "idem", Password: "x"})
+	db.Create(&model.PlatformAccount{ID: 7, PlatformID: 1, AccountName: "acc", BindUserID: &userID})
+
+	userRepo := repository.NewUserRepository(db)
+	balanceService := service.NewBalanceService(repository.NewBalanceLogRepository(db), userRepo)
+	accountService := service.NewPlatformAccountBalanceService(db, repository.NewPlatformAccountRepository(db),
+		userRepo, repository.NewBalanceLogRepository(db))
+	expectBalance := func(want money.Money) {
+		t.Helper()
+		var user model.User
+		db.First(&user, userID)
+		if user.Balance != want {
+			t.Fatalf("余额%s，期望%s", user.Balance, want)
+		}
+		assertLedgerConsistent(t, db, userID)
+	}
+
+	// 客户端重试充值请求，相同幂等键只入账一次，不同幂等键各自入账
+	keyed := service.WithIdempotencyKey(ctx, "req-1")
+	for i := 0; i < 2; i++ {
+		if err := balanceService.Recharge(keyed, userID, money.Yuan(100), "充值", "admin"); err != nil {
+			t.Fatalf("第%d次充值失败: %v", i+1, err)
+		}
+	}
+	expectBalance(money.Yuan(100))
+	if err := balanceService.Recharge(service.WithIdempotencyKey(ctx, "req-2"), userID, money.Yuan(10), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+	expectBalance(money.Yuan(110))
+
+	// 同一订单重复扣款、重复退款均为空操作
+	for i := 0; i < 2; i++ {
+		if err := balanceService.SmartDeduct(ctx, userID, money.Yuan(30), 300, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err != nil {
+			t.Fatalf("第%d次扣款失败: %v", i+1, err)
+		}
+	}
+	expectBalance(money.Yuan(80))
+	for i := 0; i < 2; i++ {
+		if err := balanceService.Refund(ctx, userID, money.Yuan(30), 300, "订单退款", "system"); err != nil {
+			t.Fatalf("第%d次退款失败: %v", i+1, err)
+		}
+	}
+	expectBalance(money.Yuan(110))
+
+	// 平台账号扣款回调与退款回调重复到达
+	for i := 0; i < 2; i++ {
+		if err := accountService.DeductBalance(ctx, 7, money.Yuan(20), 400, "订单充值扣除"); err != nil {
+			t.Fatalf("第%d次平台账号扣款失败: %v", i+1, err)
+		}
+	}
+	expectBalance(money.Yuan(90))
+	for i := 0; i < 2; i++ {
+		if err := accountService.RefundBalance(ctx, userID, money.Yuan(20), 400, "充值失败退还"); err != nil {
+			t.Fatalf("第%d次平台账号退款失败: %v", i+1, err)
+		}
+	}
+	expectBalance(money.Yuan(110))
+
+	var refunds int64
+	db.Model(&model.LedgerTransaction{}).Where("kind = ?", model.LedgerKindRefund).Count(&refunds)
+	if refunds != 2 {
+		t.Fatalf("两个订单应各有一张退款凭证: %d", refunds)
+	}
+
+	// 绕过前置检查并发写入相同幂等键时由唯一索引拦截
+	key := model.OrderIdempotencyKey(300, model.IdempotencyRefund)
+	if err := db.Create(&model.LedgerTransaction{Kind: model.LedgerKindRefund, UserID: userID, OrderID: 300, IdempotencyKey: &key}).Error; err == nil {
+		t.Fatal("重复的幂等键应违反唯一索引")
+	}
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupLedgerDB 创建账本测试数据库
+func setupLedgerDB(t *testing.T) *gorm.DB {
+	dbPath := fmt.Sprintf("test_ledger_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{},
+		&model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	// credit_logs 的 bigint 主键在 SQLite 下不会自增，手动建表
+	if err := db.Exec(`CREATE TABLE credit_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id bigint, amount decimal(10,2),
+		type tinyint, credit_before decimal(10,2), credit_after decimal(10,2), order_id bigint, remark varchar(255),
+		operator varchar(50), created_at datetime)`).Error; err != nil {
+		t.Fatalf("创建授信日志表失败: %v", err)
+	}
+	return db
+}
+
+// assertLedgerConsistent 校验每张凭证借贷平衡，且用户余额、授信额度、冻结金额与分录汇总一致
+func assertLedgerConsistent(t *testing.T, db *gorm.DB, userID int64) {
+	t.Helper()
+
+	var unbalanced int64
+	db.Raw("SELECT COUNT(*) FROM (SELECT transaction_id FROM ledger_entries GROUP BY transaction_id HAVING ROUND(SUM(amount), 2) <> 0) t").Scan(&unbalanced)
+	if unbalanced != 0 {
+		t.Fatalf("存在借贷不平衡的凭证: %d", unbalanced)
+	}
+
+	var user model.User
+	db.First(&user, userID)
+	ledger := service.NewLedger(db)
+	balance, _ := ledger.AccountBalance(context.Background(), model.UserBalanceAccount(userID))
+	credit, _ := ledger.AccountBalance(context.Background(), model.UserCreditAccount(userID))
+	frozen, _ := ledger.AccountBalance(context.Background(), model.UserFrozenAccount(userID))
+	if balance != user.Balance || credit != user.Credit || frozen != user.FrozenBalance {
+		t.Fatalf("账本与缓存不一致: 账本余额%s 缓存%s，账本授信%s 缓存%s，账本冻结%s 缓存%s",
+			balance, user.Balance, credit, user.Credit, frozen, user.FrozenBalance)
+	}
+}
+
+// TestLedgerUserOrderFlow 测试充值、余额加授信扣款、退款均按凭证记账，缓存余额由账本得出
+func TestLedgerUserOrderFlow(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	if err := db.Create(&model.User{ID: 1, Username: "ledger", Password: "x"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	creditService := service.NewCreditService(userRepo, repository.NewCreditLogRepository(db))
+	balanceService := service.NewBalanceServiceWithCredit(repository.NewBalanceLogRepository(db), userRepo, creditService)
+
+	if err := balanceService.Recharge(ctx, 1, money.MustParse("30.10"), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+	if err := creditService.SetCredit(ctx, &model.CreditLogRequest{UserID: 1, Amount: money.Yuan(50), Operator: "admin"}); err != nil {
+		t.Fatalf("设置授信失败: %v", err)
+	}
+
+	// 余额不足部分使用授信，扣款凭证直接关联订单
+	if err := balanceService.SmartDeduct(ctx, 1, money.MustParse("49.90"), 100, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err != nil {
+		t.Fatalf("智能扣款失败: %v", err)
+	}
+	var user model.User
+	db.First(&user, 1)
+	if user.Balance != 0 || user.Credit != money.MustParse("30.20") {
+		t.Fatalf("扣款后余额或授信错误: %s %s", user.Balance, user.Credit)
+	}
+	var deductLogs int64
+	db.Model(&model.BalanceLog{}).Where("order_id = ? AND style = ?", 100, model.BalanceStyleOrderDeduct).Count(&deductLogs)
+	if deductLogs != 1 {
+		t.Fatalf("扣款流水应直接关联订单: %d", deductLogs)
+	}
+
+	// 余额与授信都不足时整笔失败，不留下任何记账
+	if err := balanceService.SmartDeduct(ctx, 1, money.Yuan(100), 101, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err == nil {
+		t.Fatal("余额与授信都不足时应扣款失败")
+	}
+	var txCount int64
+	db.Model(&model.LedgerTransaction{}).Where("order_id = ?", 101).Count(&txCount)
+	if txCount != 0 {
+		t.Fatalf("扣款失败不应留下凭证: %d", txCount)
+	}
+
+	if err := balanceService.Refund(ctx, 1, money.MustParse("49.90"), 100, "订单退款", "system"); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	assertLedgerConsistent(t, db, 1)
+
+	// 订单扣款与退款在订单收入科目上相互抵消
+	sales, _ := service.NewLedger(db).AccountBalance(ctx, model.LedgerAccountSales)
+	if sales != 0 {
+		t.Fatalf("订单收入科目应为0: %s", sales)
+	}
+	txns, total, err := balanceService.ListLedger(ctx, 0, 100, 0, 10)
+	if err != nil || total != 2 || txns[0].Kind != model.LedgerKindRefund || len(txns[1].Entries) != 3 {
+		t.Fatalf("按订单查询凭证错误: %d %v", total, err)
+	}
+}
+
+// TestLedgerPlatformAccountRefund 测试平台账号扣款记入平台账号消耗科目，退款时冲回同一科目
+func TestLedgerPlatformAccountRefund(t *testing.T) {
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	userID := int64(1)
+	db.Create(&model.Platform{ID: 1, Code: "test", Name: "测试平台"})
+	db.Create(&model.User{ID: userID, Username: "ledger", Password: "x", Balance: money.Yuan(100)})
+	db.Create(&model.PlatformAccount{ID: 7, PlatformID: 1, AccountName: "acc", BindUserID: &userID})
+	// 测试数据直接写入余额，补一张期初凭证
+	db.Create(&model.LedgerTransaction{ID: 1, Kind: model.LedgerKindOpening, UserID: userID})
+	db.Create(&model.LedgerEntry{TransactionID: 1, Account: model.UserBalanceAccount(userID), UserID: userID, Amount: money.Yuan(100)})
+	db.Create(&model.LedgerEntry{TransactionID: 1, Account: model.LedgerAccountOpening, Amount: -money.Yuan(100)})
+
+	balanceService := service.NewPlatformAccountBalanceService(db, repository.NewPlatformAccountRepository(db),
+		repository.NewUserRepository(db), repository.NewBalanceLogRepository(db))
+	if err := balanceService.DeductBalance(ctx, 7, money.MustParse("12.34"), 200, "订单充值扣除"); err != nil {
+		t.Fatalf("扣款失败: %v", err)
+	}
+	if err := balanceService.RefundBalance(ctx, userID, money.MustParse("12.34"), 200, "充值失败退还"); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	assertLedgerConsistent(t, db, userID)
+
+	spend, _ := service.NewLedger(db).AccountBalance(ctx, model.PlatformAccountSpendAccount(7))
+	if spend != 0 {
+		t.Fatalf("退款应冲回平台账号消耗科目: %s", spend)
+	}
+
+	// 借贷不平衡的凭证被拒绝
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := service.NewLedger(db).Post(tx, &service.LedgerJournal{
+			Kind:     model.LedgerKindAdjust,
+			UserID:   userID,
+			Postings: []service.LedgerPosting{{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: money.Yuan(1)}},
+		})
+		return err
+	})
+	if !errors.Is(err, service.ErrLedgerUnbalanced) {
+		t.Fatalf("不平衡的凭证应被拒绝: %v", err)
+	}
+
+	// 分录金额不足一分的部分须由调用方舍入，账本不代为舍入
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := service.NewLedger(db).Post(tx, &service.LedgerJournal{
+			Kind:   model.LedgerKindAdjust,
+			UserID: userID,
+			Postings: []service.LedgerPosting{
+				{Account: model.UserBalanceAccount(userID), UserID: userID, Amount: money.MustParse("0.0150")},
+				{Account: model.LedgerAccountSales, Amount: -money.MustParse("0.0150")},
+			},
+		})
+		return err
+	})
+	if !errors.Is(err, service.ErrLedgerPrecision) {
+		t.Fatalf("不足一分的分录应被拒绝: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+)
+
+// TestMoneyParseAndEncode 测试金额解析、格式化、JSON 编解码与数据库读取
+func TestMoneyParseAndEncode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want money.Money
+		str  string
+	}{
+		{"12.34", money.FromCents(1234), "12.34"},
+		{"-0.5", -money.FromCents(50), "-0.50"},
+		{"98.7654", money.Money(987654), "98.7654"},
+		{"0.00005", money.Money(1), "0.0001"},
+		{"100", money.Yuan(100), "100.00"},
+	}
+	for _, c := range cases {
+		got, err := money.Parse(c.in)
+		if err != nil || got != c.want || got.String() != c.str {
+			t.Fatalf("解析 %q 错误: %v %s, %v", c.in, int64(got), got, err)
+		}
+	}
+	if _, err := money.Parse("1.2.3"); err == nil {
+		t.Fatal("非法金额应解析失败")
+	}
+
+	var req struct {
+		Amount money.Money `json:"amount"`
+		Price  money.Money `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount":30.10,"price":"49.90"}`), &req); err != nil {
+		t.Fatalf("JSON 解码失败: %v", err)
+	}
+	if req.Amount != money.MustParse("30.1") || req.Price != money.MustParse("49.9") {
+		t.Fatalf("JSON 解码金额错误: %s %s", req.Amount, req.Price)
+	}
+	data, _ := json.Marshal(req)
+	if string(data) != `{"amount":30.10,"price":49.90}` {
+		t.Fatalf("JSON 编码错误: %s", data)
+	}
+
+	var m money.Money
+	for _, src := range []interface{}{[]byte("12.34"), "12.34", 12.34} {
+		if err := m.Scan(src); err != nil || m != money.FromCents(1234) {
+			t.Fatalf("读取 %v 错误: %s, %v", src, m, err)
+		}
+	}
+	if money.MustParse("0.005").Round() != money.FromCents(1) || money.MustParse("-0.005").Round() != -money.FromCents(1) {
+		t.Fatal("四舍五入到分错误")
+	}
+
+	// 写入数据库使用十进制字符串，不经浮点转换
+	if v, err := money.MustParse("98.7654").Value(); err != nil || v != "98.7654" {
+		t.Fatalf("写入数据库的值错误: %v %v", v, err)
+	}
+}
+
+// TestMoneyNoDrift 测试反复扣款、退款与部分退款后余额与账本分毫不差
+func TestMoneyNoDrift(t *testing.T) {
+	// 浮点累加 0.1 十次不等于 1，定点金额必须相等
+	var sum money.Money
+	for i := 0; i < 10; i++ {
+		sum += money.MustParse("0.1")
+	}
+	if sum != money.Yuan(1) {
+		t.Fatalf("累加结果错误: %s", sum)
+	}
+
+	db := setupLedgerDB(t)
+	ctx := context.Background()
+	if err := db.Create(&model.User{ID: 1, Username: "money", Password: "x"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	userRepo := repository.NewUserRepository(db)
+	balanceService := service.NewBalanceService(repository.NewBalanceLogRepository(db), userRepo)
+	if err := balanceService.Recharge(ctx, 1, money.MustParse("100.03"), "充值", "admin"); err != nil {
+		t.Fatalf("充值失败: %v", err)
+	}
+
+	amounts := []money.Money{money.MustParse("0.10"), money.MustParse("0.20"), money.MustParse("0.33"), money.MustParse("19.99")}
+	for i := 0; i < 40; i++ {
+		orderID := int64(i + 1)
+		amount := amounts[i%len(amounts)]
+		if err := balanceService.SmartDeduct(ctx, 1, amount, orderID, model.BalanceStyleOrderDeduct, "订单扣款", "system"); err != nil {
+			t.Fatalf("订单%d扣款失败: %v", orderID, err)
+		}
+		if err := balanceService.Refund(ctx, 1, amount, orderID, "订单退款", "system"); err != nil {
+			t.Fatalf("订单%d退款失败: %v", orderID, err)
+		}
+	}
+	var user model.User
+	db.First(&user, 1)
+	if user.Balance != money.MustParse("100.03") {
+		t.Fatalf("扣款退款后余额应不变: %s", user.Balance)
+	}
+	assertLedgerConsistent(t, db, 1)
+
+	// 部分充值退款与实际消耗之和等于扣款金额
+	order := &model.Order{ID: 1, Denom: money.Yuan(3), Price: money.MustParse("2.99")}
+	for _, credited := range []money.Money{money.Yuan(1), money.MustParse("1.5"), money.Yuan(2), money.MustParse("2.99")} {
+		refund, err := service.PartialRefundAmount(order, credited)
+		if err != nil {
+			t.Fatalf("计算部分退款失败: %v", err)
+		}
+		if refund != refund.Round() || refund > order.Price {
+			t.Fatalf("到账%s时退款金额错误: %s", credited, refund)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -36,6 +37,8 @@ func TestMultiPlatformRefund(t *testing.T) {
 		&model.User{},
 		&model.PlatformAccount{},
 		&model.BalanceLog{},
+		&model.LedgerTransaction{},
+		&model.LedgerEntry{}, &model.BalanceHold{},
 	)
 	if err != nil {
 		t.Fatalf("迁移表结构失败: %v", err)
@@ -61,7 +64,7 @@ func TestMultiPlatformRefund(t *testing.T) {
 		ID:       1,
 		Username: "testuser",
 		Password: "password123",
-		Balance:  200.0,
+		Balance:  money.Yuan(200),
 	}
 	if err := db.Create(&user).Error; err != nil {
 		t.Fatalf("创建用户失败: %v", err)
@@ -104,7 +107,7 @@ func TestMultiPlatformRefund(t *testing.T) {
 	// 4. 先进行扣款操作
 	ctx := context.Background()
 	orderID := int64(36097) // 使用生产环境中出现问题的订单ID
-	amount := 95.0
+	amount := money.Yuan(95)
 
 	// 通过平台账号1扣款
 	err = service.DeductBalance(ctx, account1.ID, amount, orderID, "测试扣款")
@@ -114,10 +117,10 @@ func TestMultiPlatformRefund(t *testing.T) {
 
 	// 验证扣款后余额
 	db.First(&user, user.ID)
-	if user.Balance != 105.0 {
-		t.Fatalf("扣款后余额错误，期望105.0，实际%.2f", user.Balance)
+	if user.Balance != money.Yuan(105) {
+		t.Fatalf("扣款后余额错误，期望105.0，实际%s", user.Balance)
 	}
-	t.Logf("扣款成功，余额从200.00变为%.2f", user.Balance)
+	t.Logf("扣款成功，余额从200.00变为%s", user.Balance)
 
 	// 5. 并发通过不同平台账号退款同一订单
 	var wg sync.WaitGroup
@@ -161,11 +164,11 @@ func TestMultiPlatformRefund(t *testing.T) {
 	// 6. 验证最终结果
 	// 检查用户余额
 	db.First(&user, user.ID)
-	expectedBalance := 200.0 // 应该恢复到原始余额
-	t.Logf("最终余额: %.2f, 预期余额: %.2f", user.Balance, expectedBalance)
+	expectedBalance := money.Yuan(200) // 应该恢复到原始余额
+	t.Logf("最终余额: %s, 预期余额: %s", user.Balance, expectedBalance)
 
 	if user.Balance != expectedBalance {
-		t.Errorf("❌ 余额错误！期望%.2f，实际%.2f", expectedBalance, user.Balance)
+		t.Errorf("❌ 余额错误！期望%s，实际%s", expectedBalance, user.Balance)
 		return
 	}
 
@@ -188,7 +191,7 @@ func TestMultiPlatformRefund(t *testing.T) {
 		if log.Style == model.BalanceStyleRefund {
 			styleStr = "退款"
 		}
-		t.Logf("  %s: 金额%.2f, 余额变化%.2f->%.2f, 时间%s", styleStr, log.Amount, log.BalanceBefore, log.Balance, log.CreatedAt.Format("15:04:05.000"))
+		t.Logf("  %s: 金额%s, 余额变化%s->%s, 时间%s", styleStr, log.Amount, log.BalanceBefore, log.Balance, log.CreatedAt.Format("15:04:05.000"))
 	}
 
 	t.Log("✅ 多平台退款幂等性测试通过！基于用户ID的幂等性校验正常工作，防止了同一订单通过不同平台账号重复退款")
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model/notification"
+	notificationRepo "recharge-go/internal/repository/notification"
+	notificationService "recharge-go/internal/service/notification"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestDeliveryAttemptCapture 测试投递记录的请求脱敏与响应截断
+func TestDeliveryAttemptCapture(t *testing.T) {
+	body := []byte(`{"out_trade_num":"T001","status":4}`)
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/callback", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Auth_Token", "secret-token")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// 未挂载投递记录时忽略
+	notificationService.CaptureRequest(context.Background(), req, body)
+
+	attempt := &notification.DeliveryAttempt{}
+	ctx := notificationService.WithDeliveryAttempt(context.Background(), attempt)
+	notificationService.CaptureRequest(ctx, req, body)
+	if attempt.URL != "https://example.com/callback" || attempt.RequestBody != string(body) {
+		t.Fatalf("请求记录错误: %+v", attempt)
+	}
+	if strings.Contains(attempt.RequestHeaders, "secret") || !strings.Contains(attempt.RequestHeaders, "application/json") {
+		t.Fatalf("敏感请求头应脱敏: %s", attempt.RequestHeaders)
+	}
+
+	response := strings.Repeat("中", 2000)
+	notificationService.CaptureResponse(ctx, http.StatusBadGateway, []byte(response), 1500*time.Millisecond)
+	if attempt.HTTPStatus != http.StatusBadGateway || attempt.LatencyMs != 1500 {
+		t.Fatalf("响应记录错误: status=%d latency=%d", attempt.HTTPStatus, attempt.LatencyMs)
+	}
+	if !strings.HasSuffix(attempt.ResponseBody, "...(truncated)") || len(attempt.ResponseBody) > 4<<10+len("...(truncated)") {
+		t.Fatalf("超长响应体应被截断: %d", len(attempt.ResponseBody))
+	}
+	if !strings.HasPrefix(response, strings.TrimSuffix(attempt.ResponseBody, "...(truncated)")) {
+		t.Fatal("截断不应留下不完整的字符")
+	}
+}
+
+// TestDeliveryAttemptRepository 测试按订单查询投递记录
+func TestDeliveryAttemptRepository(t *testing.T) {
+	dbPath := fmt.Sprintf("test_notification_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&notification.DeliveryAttempt{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	repo := notificationRepo.NewAttemptRepository(db)
+	ctx := context.Background()
+
+	attempts := []*notification.DeliveryAttempt{
+		{ID: 1, NotificationID: 10, OrderID: 100, Attempt: 1, Error: "timeout"},
+		{ID: 2, NotificationID: 11, OrderID: 200, Attempt: 1, HTTPStatus: 200, Success: true},
+		{ID: 3, NotificationID: 10, OrderID: 100, Attempt: 2, HTTPStatus: 200, Success: true},
+	}
+	for _, a := range attempts {
+		if err := repo.Create(ctx, a); err != nil {
+			t.Fatalf("保存投递记录失败: %v", err)
+		}
+	}
+
+	list, err := repo.ListByOrderID(ctx, 100, 10)
+	if err != nil || len(list) != 2 || list[0].Attempt != 2 || !list[0].Success || list[1].Error != "timeout" {
+		t.Fatalf("按订单查询投递记录错误: %+v %v", list, err)
+	}
+	if list, _ := repo.ListByOrderID(ctx, 100, 1); len(list) != 1 || list[0].ID != 3 {
+		t.Fatalf("查询条数限制错误: %+v", list)
+	}
+}
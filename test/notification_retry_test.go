@@ -0,0 +1,141 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model/notification"
+	notificationRepo "recharge-go/internal/repository/notification"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRetrySchedule 测试通知重试表解析与取值
+func TestRetrySchedule(t *testing.T) {
+	schedule, err := notification.ParseRetrySchedule(" 0s, 15s,1m ,5m,30m,2h")
+	if err != nil {
+		t.Fatalf("解析重试表失败: %v", err)
+	}
+	if schedule.String() != "0s,15s,1m0s,5m0s,30m0s,2h0m0s" {
+		t.Fatalf("重试表输出错误: %s", schedule.String())
+	}
+	if schedule.InitialDelay() != 0 {
+		t.Fatalf("首次投递延迟错误: %v", schedule.InitialDelay())
+	}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+		ok       bool
+	}{
+		{1, 15 * time.Second, true},
+		{2, time.Minute, true},
+		{5, 2 * time.Hour, true},
+		{6, 0, false},
+		{0, 0, false},
+	}
+	for _, c := range cases {
+		got, ok := schedule.Next(c.failures)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("第%d次失败后的重试间隔错误: 期望 %v/%v，实际 %v/%v", c.failures, c.want, c.ok, got, ok)
+		}
+	}
+
+	for _, s := range []string{"", " , ", "15s,abc", "15s,-1m"} {
+		if _, err := notification.ParseRetrySchedule(s); err == nil {
+			t.Fatalf("重试表 %q 应解析失败", s)
+		}
+	}
+}
+
+// TestNotificationRetryRepository 测试通知抢占、按重试时间调度、滞留恢复与放弃后手动重试
+func TestNotificationRetryRepository(t *testing.T) {
+	dbPath := fmt.Sprintf("test_notification_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&notification.NotificationRecord{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	repo := notificationRepo.NewRepository(db)
+	ctx := context.Background()
+
+	record := &notification.NotificationRecord{ID: 1, OrderID: 100, PlatformCode: "test", NotificationType: "order_status_changed", Status: notification.StatusPending}
+	if err := repo.Create(ctx, record); err != nil {
+		t.Fatalf("创建通知失败: %v", err)
+	}
+
+	// 同一次投递只能被抢占一次
+	if ok, err := repo.Claim(ctx, 1, 0); err != nil || !ok {
+		t.Fatalf("首次抢占应成功: %v %v", ok, err)
+	}
+	if ok, _ := repo.Claim(ctx, 1, 0); ok {
+		t.Fatal("处理中的通知不应被重复抢占")
+	}
+
+	// 投递失败后未到重试时间不会被调度或抢占
+	if err := repo.MarkRetry(ctx, 1, 1, time.Now().Add(time.Minute), "timeout"); err != nil {
+		t.Fatalf("安排重试失败: %v", err)
+	}
+	if due, _ := repo.GetDueRecords(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("未到重试时间的通知不应被调度: %d", len(due))
+	}
+	if ok, _ := repo.Claim(ctx, 1, 1); ok {
+		t.Fatal("未到重试时间的通知不应被抢占")
+	}
+
+	// 到期后可调度，旧版本的重复消息不能抢占
+	due, err := repo.GetDueRecords(ctx, time.Now().Add(2*time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].RetryCount != 1 || due[0].LastError != "timeout" {
+		t.Fatalf("到期通知调度错误: %+v %v", due, err)
+	}
+	db.Model(&notification.NotificationRecord{}).Where("id = ?", 1).UpdateColumn("next_retry_time", time.Now().Add(-time.Second))
+	if ok, _ := repo.Claim(ctx, 1, 0); ok {
+		t.Fatal("旧版本的重复消息不应抢占通知")
+	}
+	if ok, _ := repo.Claim(ctx, 1, 1); !ok {
+		t.Fatal("到期的通知应能被抢占")
+	}
+
+	// 处理中滞留超时转为立即重试
+	db.Model(&notification.NotificationRecord{}).Where("id = ?", 1).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+	if n, err := repo.ResetStale(ctx, time.Now().Add(-10*time.Minute)); err != nil || n != 1 {
+		t.Fatalf("滞留通知恢复错误: %d %v", n, err)
+	}
+	var stale notification.NotificationRecord
+	db.First(&stale, 1)
+	if stale.Status != notification.StatusFailed || stale.NextRetryTime.After(time.Now()) {
+		t.Fatalf("滞留通知应转为立即重试: status=%d next=%v", stale.Status, stale.NextRetryTime)
+	}
+
+	// 放弃后不再被调度，手动重试后恢复调度
+	if ok, _ := repo.Requeue(ctx, 2); ok {
+		t.Fatal("不存在的通知不应能手动重试")
+	}
+	if err := repo.MarkGaveUp(ctx, 1, 6, "retry schedule exhausted"); err != nil {
+		t.Fatalf("放弃通知失败: %v", err)
+	}
+	if due, _ := repo.GetDueRecords(ctx, time.Now().Add(time.Hour), 10); len(due) != 0 {
+		t.Fatalf("已放弃的通知不应被调度: %d", len(due))
+	}
+	records, total, err := repo.List(ctx, map[string]interface{}{"status": []int{notification.StatusFailed, notification.StatusGaveUp}}, 1, 10)
+	if err != nil || total != 1 || records[0].Status != notification.StatusGaveUp {
+		t.Fatalf("按状态查询已放弃的通知错误: %+v %d %v", records, total, err)
+	}
+	if ok, err := repo.Requeue(ctx, 1); err != nil || !ok {
+		t.Fatalf("已放弃的通知应能手动重试: %v %v", ok, err)
+	}
+	if ok, _ := repo.Claim(ctx, 1, 6); !ok {
+		t.Fatal("手动重试的通知应能被抢占")
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestPartialRefundAmount 测试部分充值退款金额计算
+func TestPartialRefundAmount(t *testing.T) {
+	order := &model.Order{ID: 1, Denom: money.Yuan(100), Price: money.MustParse("98.5"), UserPayment: money.Yuan(99)}
+
+	cases := []struct {
+		credited money.Money
+		want     money.Money
+	}{
+		{money.Yuan(30), money.MustParse("68.95")},
+		{money.Yuan(50), money.MustParse("49.25")},
+		{money.MustParse("99.99"), money.MustParse("0.01")},
+	}
+	for _, c := range cases {
+		got, err := service.PartialRefundAmount(order, c.credited)
+		if err != nil || got != c.want {
+			t.Fatalf("到账%s时退款金额错误: 期望 %s，实际 %s, %v", c.credited, c.want, got, err)
+		}
+	}
+
+	// 到账为0或不少于面值不属于部分充值
+	for _, credited := range []money.Money{0, money.Yuan(100), money.Yuan(120)} {
+		if _, err := service.PartialRefundAmount(order, credited); !errors.Is(err, service.ErrInvalidCreditedAmount) {
+			t.Fatalf("到账%s应返回 ErrInvalidCreditedAmount，实际: %v", credited, err)
+		}
+	}
+
+	// 未记录扣款金额时按用户实付计算
+	order.Price = money.Zero
+	if got, _ := service.PartialRefundAmount(order, money.Yuan(50)); got != money.MustParse("49.5") {
+		t.Fatalf("按实付计算退款金额错误: %s", got)
+	}
+
+	if _, err := service.ParseCreditedAmount(""); !errors.Is(err, service.ErrInvalidCreditedAmount) {
+		t.Fatalf("空到账金额应返回 ErrInvalidCreditedAmount，实际: %v", err)
+	}
+	if amount, err := service.ParseCreditedAmount(" 30.00 "); err != nil || amount != money.Yuan(30) {
+		t.Fatalf("解析到账金额错误: %v, %v", amount, err)
+	}
+}
+
+// TestOrderPartialSettle 测试部分充值结算退款、状态流转与重复结算
+func TestOrderPartialSettle(t *testing.T) {
+	dbPath := fmt.Sprintf("test_partial_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{}, &model.Order{}, &model.OrderStatusHistory{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	user := &model.User{ID: 1, Username: "partial", Password: "x", Balance: money.Yuan(100)}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	order := &model.Order{OrderNumber: "PT1", OutTradeNum: "PT1", CustomerID: user.ID, Denom: money.Yuan(100), Price: money.MustParse("98.5"),
+		Client: 2, Status: model.OrderStatusRecharging}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	balanceLogRepo := repository.NewBalanceLogRepository(db)
+	refundService := service.NewUnifiedRefundService(db, userRepo, repository.NewOrderRepository(db), balanceLogRepo,
+		nil, service.NewBalanceService(balanceLogRepo, userRepo), nil)
+	partial := service.NewOrderPartialService(db, service.NewOrderStateMachine(db), refundService)
+	ctx := context.Background()
+
+	settlement, err := partial.Settle(ctx, order.ID, money.Yuan(30), service.OrderTransition{Actor: "mf178", Source: service.OrderTransitionSourceCallback})
+	if err != nil {
+		t.Fatalf("部分充值结算失败: %v", err)
+	}
+	if settlement.RefundAmount != money.MustParse("68.95") || settlement.CreditedAmount != money.Yuan(30) || settlement.Duplicated {
+		t.Fatalf("结算结果错误: %+v", settlement)
+	}
+
+	var saved model.Order
+	db.First(&saved, order.ID)
+	if saved.Status != model.OrderStatusPartial || saved.CreditedAmount != money.Yuan(30) || saved.RefundedAmount != money.MustParse("68.95") {
+		t.Fatalf("订单结算信息错误: status=%d credited=%s refunded=%s", saved.Status, saved.CreditedAmount, saved.RefundedAmount)
+	}
+	if saved.ActualCredited() != money.Yuan(30) {
+		t.Fatalf("实际到账面值错误: %s", saved.ActualCredited())
+	}
+
+	// 重复回调不再退款
+	again, err := partial.Settle(ctx, order.ID, money.Yuan(40), service.OrderTransition{})
+	if err != nil || !again.Duplicated || again.CreditedAmount != money.Yuan(30) {
+		t.Fatalf("重复结算应返回已记录的结果: %+v, %v", again, err)
+	}
+
+	var balance model.User
+	db.First(&balance, user.ID)
+	if balance.Balance != money.MustParse("168.95") {
+		t.Fatalf("用户余额错误: %s", balance.Balance)
+	}
+	var logs int64
+	db.Model(&model.BalanceLog{}).Where("order_id = ?", order.ID).Count(&logs)
+	if logs != 1 {
+		t.Fatalf("退款流水数量错误: %d", logs)
+	}
+}
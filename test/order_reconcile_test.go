@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/internal/service/recharge"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// reconcileRechargeService 模拟充值服务，记录重新投递的订单并返回预设的上游状态
+type reconcileRechargeService struct {
+	MockRechargeService
+	db       *gorm.DB
+	queued   []int64
+	upstream map[int64]model.OrderStatus
+	batches  [][]int64
+}
+
+func (m *reconcileRechargeService) PushToRechargeQueue(ctx context.Context, orderID int64) error {
+	m.queued = append(m.queued, orderID)
+	return nil
+}
+
+func (m *reconcileRechargeService) QueryOrderStatus(ctx context.Context, order *model.Order) (*recharge.QueryResult, error) {
+	result := &recharge.QueryResult{Status: order.Status}
+	if status, ok := m.upstream[order.ID]; ok {
+		result.Status = status
+	}
+	return result, nil
+}
+
+func (m *reconcileRechargeService) BatchQueryOrderStatus(ctx context.Context, platformCode string, orders []*model.Order) (map[int64]*recharge.QueryResult, error) {
+	var batch []int64
+	results := make(map[int64]*recharge.QueryResult)
+	for _, order := range orders {
+		batch = append(batch, order.ID)
+		results[order.ID], _ = m.QueryOrderStatus(ctx, order)
+	}
+	m.batches = append(m.batches, batch)
+	return results, nil
+}
+
+func (m *reconcileRechargeService) SettleOrderResult(ctx context.Context, order *model.Order, result service.OrderResult) (bool, error) {
+	if !result.IsFinal() {
+		return false, nil
+	}
+	err := m.db.Model(&model.Order{}).Where("id = ?", order.ID).Update("status", result.Status).Error
+	return err == nil, err
+}
+
+func (m *reconcileRechargeService) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) {
+	var order model.Order
+	err := m.db.First(&order, orderID).Error
+	return &order, err
+}
+
+// reconcileOrderService 模拟订单服务，直接更新订单状态
+type reconcileOrderService struct {
+	service.OrderService
+	db *gorm.DB
+}
+
+func (m *reconcileOrderService) setStatus(orderID int64, status model.OrderStatus) error {
+	return m.db.Model(&model.Order{}).Where("id = ?", orderID).Update("status", status).Error
+}
+
+func (m *reconcileOrderService) ProcessOrderFail(ctx context.Context, orderID int64, remark string) error {
+	return m.setStatus(orderID, model.OrderStatusFailed)
+}
+
+func (m *reconcileOrderService) ProcessOrderCancel(ctx context.Context, orderID int64, remark string) error {
+	return m.setStatus(orderID, model.OrderStatusCancelled)
+}
+
+// TestOrderReconcile 测试滞留订单按策略处置并生成报告
+func TestOrderReconcile(t *testing.T) {
+	dbPath := fmt.Sprintf("test_reconcile_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderReconcileReport{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	now := time.Now()
+	cases := []struct {
+		number   string
+		status   model.OrderStatus
+		created  time.Duration // 距创建时长
+		stuck    time.Duration // 停留在当前状态的时长
+		upstream model.OrderStatus
+		action   string
+		want     model.OrderStatus
+	}{
+		{"RC1", model.OrderStatusPendingRecharge, time.Hour, 20 * time.Minute, 0, model.OrderReconcileActionRequeue, model.OrderStatusPendingRecharge},
+		{"RC2", model.OrderStatusPendingRecharge, 25 * time.Hour, 20 * time.Minute, 0, model.OrderReconcileActionFail, model.OrderStatusFailed},
+		{"RC3", model.OrderStatusRecharging, time.Hour, 10 * time.Minute, model.OrderStatusSuccess, model.OrderReconcileActionQuery, model.OrderStatusSuccess},
+		{"RC4", model.OrderStatusRecharging, 30 * time.Hour, 10 * time.Minute, model.OrderStatusRecharging, model.OrderReconcileActionManual, model.OrderStatusRecharging},
+		{"RC5", model.OrderStatusProcessing, 25 * time.Hour, 20 * time.Minute, model.OrderStatusProcessing, model.OrderReconcileActionFail, model.OrderStatusFailed},
+		{"RC6", model.OrderStatusPendingPayment, time.Hour, time.Hour, 0, model.OrderReconcileActionCancel, model.OrderStatusCancelled},
+		// 未超过 SLA，不处置
+		{"RC7", model.OrderStatusRecharging, time.Hour, time.Minute, model.OrderStatusSuccess, "", model.OrderStatusRecharging},
+	}
+
+	recharge := &reconcileRechargeService{db: db, upstream: map[int64]model.OrderStatus{}}
+	orderIDs := make(map[string]int64)
+	for _, c := range cases {
+		order := &model.Order{OrderNumber: c.number, OutTradeNum: c.number, Status: c.status, CreateTime: now.Add(-c.created)}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+		db.Model(order).UpdateColumn("updated_at", now.Add(-c.stuck))
+		orderIDs[c.number] = order.ID
+		if c.upstream != 0 {
+			recharge.upstream[order.ID] = c.upstream
+		}
+	}
+
+	reportRepo := repository.NewOrderReconcileReportRepository(db)
+	reconciler := service.NewOrderReconciler(db, recharge, &reconcileOrderService{db: db}, reportRepo, service.DefaultOrderReconcileConfig())
+	ctx := context.Background()
+
+	// 预演只生成报告
+	preview, err := reconciler.Reconcile(ctx, service.OrderReconcileSourceManual, true)
+	if err != nil {
+		t.Fatalf("预演对账失败: %v", err)
+	}
+	if preview.Scanned != 6 || len(recharge.queued) != 0 {
+		t.Fatalf("预演结果错误: scanned=%d queued=%v", preview.Scanned, recharge.queued)
+	}
+
+	report, err := reconciler.Reconcile(ctx, service.OrderReconcileSourceManual, false)
+	if err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	if report.Scanned != 6 || report.Requeued != 1 || report.Failed != 2 || report.Resolved != 1 ||
+		report.Manual != 1 || report.Cancelled != 1 || report.Queried != 3 || report.Errors != 0 {
+		t.Fatalf("对账报告统计错误: %+v", report)
+	}
+	if len(recharge.queued) != 1 || recharge.queued[0] != orderIDs["RC1"] {
+		t.Fatalf("应只重新投递 RC1: %v", recharge.queued)
+	}
+
+	items, err := report.ParseItems()
+	if err != nil {
+		t.Fatalf("解析处置明细失败: %v", err)
+	}
+	actions := make(map[int64]string)
+	for _, item := range items {
+		actions[item.OrderID] = item.Action
+	}
+	for _, c := range cases {
+		id := orderIDs[c.number]
+		if actions[id] != c.action {
+			t.Fatalf("%s 处置动作错误: 期望 %q，实际 %q", c.number, c.action, actions[id])
+		}
+		var saved model.Order
+		db.First(&saved, id)
+		if saved.Status != c.want {
+			t.Fatalf("%s 处置后状态错误: 期望 %d，实际 %d", c.number, c.want, saved.Status)
+		}
+	}
+
+	reports, total, err := reportRepo.List(ctx, 1, 10)
+	if err != nil || total != 2 || len(reports) != 2 || !reports[1].DryRun {
+		t.Fatalf("对账报告保存错误: total=%d, %v", total, err)
+	}
+}
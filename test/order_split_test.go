@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"recharge-go/internal/model"
+	notificationModel "recharge-go/internal/model/notification"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// setupSplitTest 在路由测试数据基础上创建一个无通道的200元商品及其拆单规则
+func setupSplitTest(t *testing.T) (*gorm.DB, *service.OrderSplitService) {
+	db, router, _ := setupRoutingTest(t, service.RouteStrategyPriority, []routingChannel{
+		{sort: 1, price: 98.0},
+	})
+	if err := db.AutoMigrate(&model.OrderStatusHistory{}, &model.BalanceHold{}, &model.OrderSplitRule{}, &model.OutboxMessage{}, &notificationModel.NotificationRecord{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	product := &model.Product{ID: 2, Name: "话费200", Price: money.Yuan(200), CategoryID: 1, Status: 1}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("创建商品失败: %v", err)
+	}
+	rules := []*model.OrderSplitRule{
+		// 子商品无可用通道，应被跳过
+		{Name: "200拆4x50", ProductID: 2, Parts: `[{"product_id":3,"denom":50},{"product_id":3,"denom":50},{"product_id":3,"denom":50},{"product_id":3,"denom":50}]`, Sort: 1, Status: 1},
+		{Name: "200拆2x100", ProductID: 2, Parts: `[{"product_id":1,"denom":100},{"product_id":1,"denom":100}]`, Sort: 2, Status: 1},
+	}
+	for _, rule := range rules {
+		if err := db.Create(rule).Error; err != nil {
+			t.Fatalf("创建拆单规则失败: %v", err)
+		}
+	}
+
+	sm := service.NewOrderStateMachine(db)
+	return db, service.NewOrderSplitService(db, router, sm, service.NewOutbox(db, &MockQueue{}))
+}
+
+// createSplitParent 创建待拆单的父订单并执行拆单
+func createSplitParent(t *testing.T, db *gorm.DB, split *service.OrderSplitService, orderNumber string) (*model.Order, []*model.Order) {
+	parent := &model.Order{OrderNumber: orderNumber, OutTradeNum: orderNumber, ProductID: 2, Denom: money.Yuan(200),
+		Price: money.MustParse("199.99"), TotalPrice: money.MustParse("199.99"), UserPayment: money.MustParse("199.99"), ISP: 1, Status: model.OrderStatusPendingRecharge}
+	if err := db.Create(parent).Error; err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+	children, err := split.Split(context.Background(), parent)
+	if err != nil {
+		t.Fatalf("拆单失败: %v", err)
+	}
+	return parent, children
+}
+
+// TestOrderSplit 测试按规则拆单及金额分摊
+func TestOrderSplit(t *testing.T) {
+	db, split := setupSplitTest(t)
+	parent, children := createSplitParent(t, db, split, "SP1")
+
+	if len(children) != 2 {
+		t.Fatalf("应拆分为2笔子订单，实际: %d", len(children))
+	}
+	if children[0].Price != money.Yuan(100) || children[1].Price != money.MustParse("99.99") {
+		t.Fatalf("金额分摊错误，尾差应计入最后一笔: %s %s", children[0].Price, children[1].Price)
+	}
+	for _, child := range children {
+		if child.ID == 0 || !child.IsSplitChild() || child.ApartOrderNumber != "SP1" || child.ProductID != 1 || child.Denom != money.Yuan(100) {
+			t.Fatalf("子订单信息错误: %+v", child)
+		}
+	}
+
+	var saved model.Order
+	if err := db.First(&saved, parent.ID).Error; err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if saved.Status != model.OrderStatusSplit || !saved.IsSplitParent() {
+		t.Fatalf("父订单应为已拆单状态: %d, is_apart=%d", saved.Status, saved.IsApart)
+	}
+
+	// 子订单不再拆分
+	again, err := split.Split(context.Background(), children[0])
+	if err != nil || again != nil {
+		t.Fatalf("子订单不应再次拆单: %v, %v", again, err)
+	}
+}
+
+// TestOrderSplitSettle 测试子订单完成后汇总父订单状态
+func TestOrderSplitSettle(t *testing.T) {
+	db, split := setupSplitTest(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		orderNumber string
+		results     []model.OrderStatus
+		want        model.OrderStatus
+		refund      money.Money
+	}{
+		{"SP2", []model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusFailed}, model.OrderStatusPartial, money.MustParse("99.99")},
+		{"SP3", []model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusSuccess}, model.OrderStatusSuccess, 0},
+		{"SP4", []model.OrderStatus{model.OrderStatusFailed, model.OrderStatusRefunded}, model.OrderStatusFailed, money.MustParse("199.99")},
+	}
+	for _, c := range cases {
+		parent, children := createSplitParent(t, db, split, c.orderNumber)
+
+		// 第一笔完成时不汇总
+		db.Model(children[0]).Update("status", c.results[0])
+		split.OnChildFinished(ctx, children[0])
+		var saved model.Order
+		db.First(&saved, parent.ID)
+		if saved.Status != model.OrderStatusSplit {
+			t.Fatalf("%s 子订单未全部完成时父订单不应汇总: %d", c.orderNumber, saved.Status)
+		}
+
+		db.Model(children[1]).Update("status", c.results[1])
+		split.OnChildFinished(ctx, children[1])
+		db.First(&saved, parent.ID)
+		if saved.Status != c.want {
+			t.Fatalf("%s 父订单汇总状态错误: 期望 %d，实际 %d", c.orderNumber, c.want, saved.Status)
+		}
+
+		settlement, err := split.Summary(ctx, c.orderNumber)
+		if err != nil {
+			t.Fatalf("汇总子订单失败: %v", err)
+		}
+		if settlement.RefundAmount != c.refund {
+			t.Fatalf("%s 退款金额错误: 期望 %s，实际 %s", c.orderNumber, c.refund, settlement.RefundAmount)
+		}
+
+		// 重复汇总不产生新的状态变更
+		if err := split.Settle(ctx, c.orderNumber); err != nil {
+			t.Fatalf("重复汇总失败: %v", err)
+		}
+	}
+
+	var notifications int64
+	db.Model(&notificationModel.NotificationRecord{}).Count(&notifications)
+	if notifications != int64(len(cases)) {
+		t.Fatalf("每个父订单应只通知一次，实际: %d", notifications)
+	}
+}
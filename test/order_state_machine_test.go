@@ -0,0 +1,146 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupStateMachineTest 创建状态机测试数据库
+func setupStateMachineTest(t *testing.T) (*gorm.DB, *service.OrderStateMachine) {
+	dbPath := fmt.Sprintf("test_state_machine_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderStatusHistory{}, &model.BalanceHold{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	return db, service.NewOrderStateMachine(db)
+}
+
+// TestOrderStateMachineTransit 测试合法流转、非法流转、并发冲突与流转记录
+func TestOrderStateMachineTransit(t *testing.T) {
+	db, sm := setupStateMachineTest(t)
+	ctx := context.WithValue(context.Background(), "user_id", int64(7))
+
+	order := &model.Order{OrderNumber: "SM1", OutTradeNum: "SM1", Status: model.OrderStatusPendingRecharge}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+
+	// 合法流转并同时更新附加字段
+	err := sm.Transit(ctx, nil, order.ID, model.OrderStatusPendingRecharge, model.OrderStatusRecharging, service.OrderTransition{
+		Reason: "提交上游成功",
+		Source: service.OrderTransitionSourceWorker,
+		Fields: map[string]interface{}{"const_price": money.MustParse("98.5")},
+	})
+	if err != nil {
+		t.Fatalf("合法流转失败: %v", err)
+	}
+
+	// 旧状态已变化，乐观锁冲突
+	err = sm.Transit(ctx, nil, order.ID, model.OrderStatusPendingRecharge, model.OrderStatusFailed, service.OrderTransition{})
+	if !errors.Is(err, service.ErrOrderStatusConflict) {
+		t.Fatalf("旧状态不匹配应返回 ErrOrderStatusConflict，实际: %v", err)
+	}
+
+	// 待充值订单须先提交上游，不能直接成功或退款
+	if service.CanTransitOrder(model.OrderStatusPendingRecharge, model.OrderStatusSuccess) ||
+		service.CanTransitOrder(model.OrderStatusPendingRecharge, model.OrderStatusRefunded) {
+		t.Fatal("待充值不应直接流转到成功或已退款")
+	}
+
+	// 充值中不能直接退款
+	err = sm.Transit(ctx, nil, order.ID, model.OrderStatusRecharging, model.OrderStatusRefunded, service.OrderTransition{})
+	if !errors.Is(err, service.ErrIllegalOrderTransition) {
+		t.Fatalf("非法流转应返回 ErrIllegalOrderTransition，实际: %v", err)
+	}
+
+	from, err := sm.TransitCurrent(ctx, nil, order.ID, model.OrderStatusSuccess, service.OrderTransition{
+		Actor:  "mf178",
+		Source: service.OrderTransitionSourceCallback,
+	})
+	if err != nil || from != model.OrderStatusRecharging {
+		t.Fatalf("按当前状态流转失败: %v, from: %d", err, from)
+	}
+
+	// 已退款为终态
+	if _, err := sm.TransitCurrent(ctx, nil, order.ID, model.OrderStatusRefunded, service.OrderTransition{}); err != nil {
+		t.Fatalf("成功订单退款失败: %v", err)
+	}
+	if _, err := sm.TransitCurrent(ctx, nil, order.ID, model.OrderStatusRecharging, service.OrderTransition{}); !errors.Is(err, service.ErrIllegalOrderTransition) {
+		t.Fatalf("终态订单不应再流转，实际: %v", err)
+	}
+
+	var saved model.Order
+	if err := db.First(&saved, order.ID).Error; err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if saved.Status != model.OrderStatusRefunded || saved.ConstPrice != money.MustParse("98.5") {
+		t.Fatalf("订单状态或附加字段错误: %d %s", saved.Status, saved.ConstPrice)
+	}
+
+	histories, err := sm.History(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("查询流转记录失败: %v", err)
+	}
+	if len(histories) != 3 {
+		t.Fatalf("流转记录数量错误: %d", len(histories))
+	}
+	first, second := histories[0], histories[1]
+	if first.FromStatus != model.OrderStatusPendingRecharge || first.ToStatus != model.OrderStatusRecharging ||
+		first.Actor != "user:7" || first.Source != service.OrderTransitionSourceWorker || first.Reason != "提交上游成功" {
+		t.Fatalf("第一条流转记录错误: %+v", first)
+	}
+	if second.Actor != "mf178" || second.Source != service.OrderTransitionSourceCallback {
+		t.Fatalf("第二条流转记录错误: %+v", second)
+	}
+	if histories[2].Source != service.OrderTransitionSourceManual {
+		t.Fatalf("登录用户操作应记为人工来源: %+v", histories[2])
+	}
+}
+
+// TestOrderStateMachineRollback 测试外部事务回滚时状态与流转记录一同回滚
+func TestOrderStateMachineRollback(t *testing.T) {
+	db, sm := setupStateMachineTest(t)
+	ctx := context.Background()
+
+	order := &model.Order{OrderNumber: "SM2", OutTradeNum: "SM2", Status: model.OrderStatusRecharging}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := sm.Transit(ctx, tx, order.ID, model.OrderStatusRecharging, model.OrderStatusFailed, service.OrderTransition{}); err != nil {
+			return err
+		}
+		return errors.New("退款失败")
+	})
+	if err == nil {
+		t.Fatal("事务应返回错误")
+	}
+
+	var saved model.Order
+	if err := db.First(&saved, order.ID).Error; err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	histories, _ := sm.History(ctx, order.ID)
+	if saved.Status != model.OrderStatusRecharging || len(histories) != 0 {
+		t.Fatalf("回滚后状态或流转记录错误: %d, %d", saved.Status, len(histories))
+	}
+}
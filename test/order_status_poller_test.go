@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service"
+	"recharge-go/internal/service/recharge"
+	"recharge-go/pkg/money"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestPollBackoff 测试主动查询退避间隔
+func TestPollBackoff(t *testing.T) {
+	cases := []struct {
+		count int
+		want  time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{10, 30 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := service.PollBackoff(c.count, time.Minute, 30*time.Minute); got != c.want {
+			t.Fatalf("第%d次查询退避错误: 期望 %v，实际 %v", c.count, c.want, got)
+		}
+	}
+}
+
+// TestOrderStatusPoll 测试充值中订单超过预期回调时间后主动查询并按退避安排下次查询
+func TestOrderStatusPoll(t *testing.T) {
+	dbPath := fmt.Sprintf("test_poller_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Order{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	now := time.Now()
+	newOrder := func(no, platformCode string, apiID int64, stuck time.Duration) *model.Order {
+		order := &model.Order{OrderNumber: no, OutTradeNum: no, Status: model.OrderStatusRecharging, Denom: money.Yuan(100), CreateTime: now,
+			PlatformCode: platformCode, PlatformAccountID: 1, APICurID: apiID}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+		db.Model(order).UpdateColumn("updated_at", now.Add(-stuck))
+		return order
+	}
+	done := newOrder("PL1", "p1", 1, 10*time.Minute)
+	other := newOrder("PL2", "p2", 2, 9*time.Minute)
+	pending := newOrder("PL3", "p1", 1, 8*time.Minute)
+	fresh := newOrder("PL4", "p1", 1, time.Minute)
+	otherAPI := newOrder("PL5", "p1", 3, 7*time.Minute)
+
+	rechargeService := &reconcileRechargeService{
+		db:       db,
+		upstream: map[int64]model.OrderStatus{done.ID: model.OrderStatusSuccess},
+	}
+	poller := service.NewOrderStatusPoller(db, rechargeService, service.DefaultOrderPollConfig())
+	ctx := context.Background()
+
+	settled, err := poller.Poll(ctx)
+	if err != nil || settled != 1 {
+		t.Fatalf("主动查询结果错误: settled=%d, %v", settled, err)
+	}
+	// 同一平台账号、同一接口的订单合并查询，同账号不同接口分开查询
+	if fmt.Sprint(rechargeService.batches) != fmt.Sprint([][]int64{{done.ID, pending.ID}, {other.ID}, {otherAPI.ID}}) {
+		t.Fatalf("查询分组错误: %v", rechargeService.batches)
+	}
+
+	load := func(id int64) model.Order {
+		var saved model.Order
+		db.First(&saved, id)
+		return saved
+	}
+	saved := load(done.ID)
+	if saved.Status != model.OrderStatusSuccess {
+		t.Fatalf("上游已成功的订单状态错误: %d", saved.Status)
+	}
+	saved = load(pending.ID)
+	if saved.Status != model.OrderStatusRecharging || saved.QueryCount != 1 || saved.NextQueryTime == nil {
+		t.Fatalf("未出结果的订单应安排下次查询: status=%d count=%d next=%v", saved.Status, saved.QueryCount, saved.NextQueryTime)
+	}
+	if !saved.UpdatedAt.Before(now.Add(-5 * time.Minute)) {
+		t.Fatalf("安排下次查询不应更新 updated_at: %v", saved.UpdatedAt)
+	}
+	saved = load(fresh.ID)
+	if saved.QueryCount != 0 {
+		t.Fatalf("未超过预期回调时间的订单不应查询: count=%d", saved.QueryCount)
+	}
+
+	// 下次查询时间未到，不再查询
+	if settled, err := poller.Poll(ctx); err != nil || settled != 0 {
+		t.Fatalf("退避期内不应查询: settled=%d, %v", settled, err)
+	}
+	saved = load(pending.ID)
+	if saved.QueryCount != 1 {
+		t.Fatalf("退避期内查询次数不应增加: %d", saved.QueryCount)
+	}
+}
+
+// batchQueryPlatform 支持批量查询的测试平台，记录每批查询的订单数
+type batchQueryPlatform struct {
+	recharge.Platform
+	batches []int
+}
+
+func (p *batchQueryPlatform) MaxBatchSize() int { return 2 }
+
+func (p *batchQueryPlatform) BatchQueryOrderStatus(ctx context.Context, orders []*model.Order) (map[string]*recharge.QueryResult, error) {
+	p.batches = append(p.batches, len(orders))
+	results := make(map[string]*recharge.QueryResult)
+	for _, order := range orders {
+		// 上游未返回 PB3
+		if order.OrderNumber != "PB3" {
+			results[order.OrderNumber] = &recharge.QueryResult{Status: model.OrderStatusSuccess, Voucher: "V-" + order.OrderNumber}
+		}
+	}
+	return results, nil
+}
+
+// singleQueryPlatform 只支持逐笔查询的测试平台
+type singleQueryPlatform struct {
+	recharge.Platform
+	queried int
+}
+
+func (p *singleQueryPlatform) QueryOrderStatus(ctx context.Context, order *model.Order) (*recharge.QueryResult, error) {
+	p.queried++
+	return &recharge.QueryResult{Status: model.OrderStatusRecharging}, nil
+}
+
+var (
+	testBatchPlatform  = &batchQueryPlatform{}
+	testSinglePlatform = &singleQueryPlatform{}
+)
+
+func init() {
+	recharge.RegisterAdapter(recharge.AdapterDescriptor{Code: "test_batch", Capabilities: recharge.Capabilities{QueryOrderStatus: true, BatchQuery: true}},
+		func(db *gorm.DB) recharge.Platform { return testBatchPlatform })
+	recharge.RegisterAdapter(recharge.AdapterDescriptor{Code: "test_single", Capabilities: recharge.Capabilities{QueryOrderStatus: true}},
+		func(db *gorm.DB) recharge.Platform { return testSinglePlatform })
+}
+
+// TestManagerBatchQueryOrderStatus 测试平台支持批量查询时按批次查询，否则逐笔查询
+func TestManagerBatchQueryOrderStatus(t *testing.T) {
+	manager := recharge.NewManager(nil)
+	ctx := context.Background()
+	orders := []*model.Order{
+		{ID: 1, OrderNumber: "PB1"},
+		{ID: 2, OrderNumber: "PB2"},
+		{ID: 3, OrderNumber: "PB3"},
+	}
+
+	results, err := manager.BatchQueryOrderStatus(ctx, "test_batch", orders)
+	if err != nil {
+		t.Fatalf("批量查询失败: %v", err)
+	}
+	if fmt.Sprint(testBatchPlatform.batches) != "[2 1]" {
+		t.Fatalf("批量查询分批错误: %v", testBatchPlatform.batches)
+	}
+	if len(results) != 2 || results[1].Voucher != "V-PB1" || results[2] == nil || results[3] != nil {
+		t.Fatalf("批量查询结果错误: %v", results)
+	}
+
+	results, err = manager.BatchQueryOrderStatus(ctx, "test_single", orders)
+	if err != nil || len(results) != 3 || testSinglePlatform.queried != 3 {
+		t.Fatalf("逐笔查询结果错误: queried=%d results=%v, %v", testSinglePlatform.queried, results, err)
+	}
+}
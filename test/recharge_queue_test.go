@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/service"
+
+	"github.com/hibiken/asynq"
+)
+
+// queueRechargeService 模拟充值服务，ProcessRechargeTask 将订单流转为 next 状态
+type queueRechargeService struct {
+	MockRechargeService
+	order     *model.Order
+	next      model.OrderStatus
+	processed int
+}
+
+func (m *queueRechargeService) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) {
+	if m.order == nil || m.order.ID != orderID {
+		return nil, fmt.Errorf("order %d not found", orderID)
+	}
+	order := *m.order
+	return &order, nil
+}
+
+func (m *queueRechargeService) ProcessRechargeTask(ctx context.Context, order *model.Order) error {
+	m.processed++
+	m.order.Status = m.next
+	if m.next == model.OrderStatusPendingRecharge {
+		return errors.New("submit order failed")
+	}
+	return nil
+}
+
+func rechargeTask(orderID int64) *asynq.Task {
+	payload, _ := json.Marshal(service.RechargeTaskPayload{OrderID: orderID})
+	return asynq.NewTask(service.TaskTypeRecharge, payload)
+}
+
+// TestRechargeTaskHandler 测试充值任务的完成、冷却与跳过
+func TestRechargeTaskHandler(t *testing.T) {
+	ctx := context.Background()
+	newOrder := func(status model.OrderStatus, created time.Time) *model.Order {
+		return &model.Order{ID: 1, Status: status, CreateTime: created}
+	}
+
+	// 提交成功，任务完成
+	svc := &queueRechargeService{order: newOrder(model.OrderStatusPendingRecharge, time.Now()), next: model.OrderStatusRecharging}
+	if err := service.NewRechargeTaskHandler(svc).ProcessTask(ctx, rechargeTask(1)); err != nil {
+		t.Fatalf("提交成功的任务应完成: %v", err)
+	}
+
+	// 切换通道后回到待充值，返回冷却错误
+	svc = &queueRechargeService{order: newOrder(model.OrderStatusPendingRecharge, time.Now()), next: model.OrderStatusPendingRecharge}
+	err := service.NewRechargeTaskHandler(svc).ProcessTask(ctx, rechargeTask(1))
+	if !errors.Is(err, service.ErrRechargeCooldown) {
+		t.Fatalf("切换通道后应返回冷却错误，实际: %v", err)
+	}
+	if delay := service.RechargeRetryDelay(1, err, rechargeTask(1)); delay != time.Minute {
+		t.Fatalf("冷却任务应在1分钟后重新调度，实际: %v", delay)
+	}
+
+	// 非待充值或超过24小时的订单不处理
+	for _, order := range []*model.Order{
+		newOrder(model.OrderStatusRecharging, time.Now()),
+		newOrder(model.OrderStatusPendingRecharge, time.Now().Add(-25*time.Hour)),
+	} {
+		svc = &queueRechargeService{order: order}
+		if err := service.NewRechargeTaskHandler(svc).ProcessTask(ctx, rechargeTask(1)); err != nil || svc.processed != 0 {
+			t.Fatalf("订单不应被处理: status=%d, processed=%d, err=%v", order.Status, svc.processed, err)
+		}
+	}
+
+	// 无效任务数据不再重试
+	bad := asynq.NewTask(service.TaskTypeRecharge, []byte("oops"))
+	if err := service.NewRechargeTaskHandler(svc).ProcessTask(ctx, bad); !errors.Is(err, asynq.SkipRetry) {
+		t.Fatalf("无效任务数据应跳过重试，实际: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package test
 import (
 	"testing"
 	"recharge-go/internal/model"
+	"recharge-go/pkg/money"
 )
 
 // 简化的测试，不依赖外部mock库
@@ -16,14 +17,14 @@ func TestRefundConsistency(t *testing.T) {
 		ID:                1,
 		OrderNumber:      "TEST123",
 		PlatformAccountID: 100,
-		Price:            10.50, // 单价 - 这是实际扣款金额
-		TotalPrice:       15.75, // 总价 - 修复前错误地用于退款
+		Price:            money.MustParse("10.50"), // 单价 - 这是实际扣款金额
+		TotalPrice:       money.MustParse("15.75"), // 总价 - 修复前错误地用于退款
 	}
 
 	// 验证修复后的逻辑：退款应该使用Price字段
 	expectedRefundAmount := testOrder.Price
-	if expectedRefundAmount != 10.50 {
-		t.Errorf("期望退款金额为 10.50，实际为 %.2f", expectedRefundAmount)
+	if expectedRefundAmount != money.MustParse("10.50") {
+		t.Errorf("期望退款金额为 10.50，实际为 %s", expectedRefundAmount)
 	}
 
 	// 验证不应该使用TotalPrice字段
@@ -33,11 +34,11 @@ func TestRefundConsistency(t *testing.T) {
 
 	// 计算修复前可能的损失
 	lossAmount := testOrder.TotalPrice - testOrder.Price
-	if lossAmount != 5.25 {
-		t.Errorf("期望损失金额为 5.25，实际为 %.2f", lossAmount)
+	if lossAmount != money.MustParse("5.25") {
+		t.Errorf("期望损失金额为 5.25，实际为 %s", lossAmount)
 	}
 
-	t.Logf("修复验证通过：扣款金额=%.2f，退款金额=%.2f，避免损失=%.2f", 
+	t.Logf("修复验证通过：扣款金额=%s，退款金额=%s，避免损失=%s", 
 		testOrder.Price, expectedRefundAmount, lossAmount)
 }
 
@@ -48,8 +49,8 @@ func TestDeductRefundAmountConsistency(t *testing.T) {
 		ID:                1,
 		OrderNumber:      "TEST456",
 		PlatformAccountID: 200,
-		Price:            25.00, // 扣款金额
-		TotalPrice:       30.00, // 故意设置不同的值来测试一致性
+		Price:            money.MustParse("25.00"), // 扣款金额
+		TotalPrice:       money.MustParse("30.00"), // 故意设置不同的值来测试一致性
 	}
 
 	// 验证扣款和退款都应该使用order.Price
@@ -58,14 +59,14 @@ func TestDeductRefundAmountConsistency(t *testing.T) {
 
 	// 验证金额一致性
 	if deductAmount != refundAmount {
-		t.Errorf("扣款和退款金额不一致：扣款=%.2f，退款=%.2f", deductAmount, refundAmount)
+		t.Errorf("扣款和退款金额不一致：扣款=%s，退款=%s", deductAmount, refundAmount)
 	}
 
 	// 验证修复前的问题
 	oldRefundAmount := testOrder.TotalPrice // 修复前错误地使用TotalPrice
 	loss := oldRefundAmount - deductAmount
-	if loss != 5.00 {
-		t.Errorf("期望修复前损失5.00，实际损失%.2f", loss)
+	if loss != money.MustParse("5.00") {
+		t.Errorf("期望修复前损失5.00，实际损失%s", loss)
 	}
 
 	// 验证修复后的正确性
@@ -74,10 +75,10 @@ func TestDeductRefundAmountConsistency(t *testing.T) {
 	}
 
 	t.Logf("一致性验证通过：")
-	t.Logf("  扣款金额: %.2f (使用Price字段)", deductAmount)
-	t.Logf("  退款金额: %.2f (修复后使用Price字段)", refundAmount)
-	t.Logf("  修复前退款: %.2f (错误使用TotalPrice字段)", oldRefundAmount)
-	t.Logf("  避免损失: %.2f", loss)
+	t.Logf("  扣款金额: %s (使用Price字段)", deductAmount)
+	t.Logf("  退款金额: %s (修复后使用Price字段)", refundAmount)
+	t.Logf("  修复前退款: %s (错误使用TotalPrice字段)", oldRefundAmount)
+	t.Logf("  避免损失: %s", loss)
 }
 
 // TestPriceVsTotalPriceDifference 测试Price和TotalPrice差异场景
@@ -88,14 +89,14 @@ func TestPriceVsTotalPriceDifference(t *testing.T) {
 		ID:                1,
 		OrderNumber:      "TEST789",
 		PlatformAccountID: 300,
-		Price:            10.00, // 实际扣款金额（商品单价）
-		TotalPrice:       12.00, // 订单总价（可能包含手续费等）
+		Price:            money.MustParse("10.00"), // 实际扣款金额（商品单价）
+		TotalPrice:       money.MustParse("12.00"), // 订单总价（可能包含手续费等）
 	}
 
 	// 验证修复后的行为
 	expectedAmount := testOrder.Price // 应该使用Price字段
-	if expectedAmount != 10.00 {
-		t.Errorf("退款金额应该等于扣款金额(Price字段)，期望10.00，实际%.2f", expectedAmount)
+	if expectedAmount != money.MustParse("10.00") {
+		t.Errorf("退款金额应该等于扣款金额(Price字段)，期望10.00，实际%s", expectedAmount)
 	}
 	
 	if testOrder.TotalPrice == expectedAmount {
@@ -104,13 +105,13 @@ func TestPriceVsTotalPriceDifference(t *testing.T) {
 	
 	// 计算修复前可能的损失
 	lossAmount := testOrder.TotalPrice - testOrder.Price
-	if lossAmount != 2.00 {
-		t.Errorf("修复前每次退款会多退2.00，实际损失%.2f", lossAmount)
+	if lossAmount != money.MustParse("2.00") {
+		t.Errorf("修复前每次退款会多退2.00，实际损失%s", lossAmount)
 	}
 
 	t.Logf("Price vs TotalPrice差异测试通过：")
-	t.Logf("  商品单价(Price): %.2f", testOrder.Price)
-	t.Logf("  订单总价(TotalPrice): %.2f", testOrder.TotalPrice)
-	t.Logf("  正确退款金额: %.2f (使用Price)", expectedAmount)
-	t.Logf("  避免多退: %.2f", lossAmount)
+	t.Logf("  商品单价(Price): %s", testOrder.Price)
+	t.Logf("  订单总价(TotalPrice): %s", testOrder.TotalPrice)
+	t.Logf("  正确退款金额: %s (使用Price)", expectedAmount)
+	t.Logf("  避免多退: %s", lossAmount)
 }
\ No newline at end of file
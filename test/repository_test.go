@@ -3,6 +3,7 @@ package test
 import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
+	"recharge-go/pkg/money"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -46,8 +47,8 @@ func TestRepositoryGetByID(t *testing.T) {
 		AppKey:       "test_key",
 		AppSecret:    "test_secret",
 		Description:  "测试账号",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
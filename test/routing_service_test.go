@@ -0,0 +1,290 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"recharge-go/internal/model"
+	"recharge-go/internal/repository"
+	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// routingChannel 测试通道配置
+type routingChannel struct {
+	sort            int
+	price           float64
+	cost            float64
+	isp             string
+	allowProvinces  string
+	forbidProvinces string
+	dailyLimit      float64
+}
+
+// setupRoutingTest 创建路由测试数据，返回路由服务和各通道的接口ID
+func setupRoutingTest(t *testing.T, strategy string, channels []routingChannel) (*gorm.DB, *service.RoutingService, []int64) {
+	dbPath := fmt.Sprintf("test_routing_%s.db", t.Name())
+	t.Cleanup(func() { os.Remove(dbPath) })
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.ProductAPIRelation{}, &model.PlatformAccount{},
+		&model.PlatformAPI{}, &model.Order{}, &model.AccountSpendReservation{}); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+	// 套餐表的默认值使用了 MySQL 语法，这里手动建表
+	if err := db.Exec(`CREATE TABLE platform_api_params (
+		id integer PRIMARY KEY AUTOINCREMENT, api_id integer, name text, product_id text, description text,
+		cost real, par_value real, price real, callback_url text, allow_provinces text, allow_cities text,
+		forbid_provinces text, forbid_cities text, sort integer, status integer, created_at datetime, updated_at datetime)`).Error; err != nil {
+		t.Fatalf("创建套餐表失败: %v", err)
+	}
+
+	product := &model.Product{ID: 1, Name: "话费100", Price: money.Yuan(100), CategoryID: 1, Status: 1, RouteStrategy: strategy}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("创建商品失败: %v", err)
+	}
+
+	apiIDs := make([]int64, 0, len(channels))
+	for i, ch := range channels {
+		account := &model.PlatformAccount{PlatformID: 1, AccountName: fmt.Sprintf("acc%d", i), AppKey: "k", AppSecret: "s", Status: 1, DailyLimit: money.FromFloat(ch.dailyLimit)}
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("创建平台账号失败: %v", err)
+		}
+		api := &model.PlatformAPI{PlatformID: 1, Name: fmt.Sprintf("api%d", i), Code: fmt.Sprintf("api%d", i), URL: "http://127.0.0.1", Method: "POST", Status: 1, AccountID: account.ID}
+		if err := db.Create(api).Error; err != nil {
+			t.Fatalf("创建平台接口失败: %v", err)
+		}
+		param := &model.PlatformAPIParam{APIID: api.ID, Name: "100元", ProductID: "P100", Price: money.FromFloat(ch.price), Cost: money.FromFloat(ch.cost), Status: 1,
+			AllowProvinces: ch.allowProvinces, ForbidProvinces: ch.forbidProvinces}
+		if err := db.Create(param).Error; err != nil {
+			t.Fatalf("创建接口套餐失败: %v", err)
+		}
+		relation := &model.ProductAPIRelation{ID: int64(i + 1), ProductID: product.ID, APIID: api.ID, ParamID: param.ID, Sort: ch.sort, Status: 1, ISP: ch.isp}
+		if err := db.Create(relation).Error; err != nil {
+			t.Fatalf("创建商品接口关联失败: %v", err)
+		}
+		apiIDs = append(apiIDs, api.ID)
+	}
+
+	return db, newTestRoutingService(db), apiIDs
+}
+
+// newTestRoutingService 创建路由服务
+func newTestRoutingService(db *gorm.DB) *service.RoutingService {
+	return service.NewRoutingService(db,
+		repository.NewProductRepository(db),
+		repository.NewProductAPIRelationRepository(db),
+		repository.NewPlatformRepository(db),
+		service.NewAccountSpendLimiter(db, nil),
+	)
+}
+
+// TestRoutingCheapestWithFilters 测试最低成本策略及运营商、归属地过滤
+func TestRoutingCheapestWithFilters(t *testing.T) {
+	_, router, apiIDs := setupRoutingTest(t, service.RouteStrategyCheapest, []routingChannel{
+		{sort: 1, price: 99.5, isp: "1,2,3"},
+		{sort: 2, price: 98.0, isp: "1,2,3", forbidProvinces: "广东"},
+		{sort: 3, price: 98.5, isp: "2"},
+		{sort: 4, price: 99.0, isp: "1", allowProvinces: "浙江,江苏"},
+	})
+	ctx := context.Background()
+
+	// 广东移动：禁止广东的通道和只支持电信的通道被过滤
+	order := &model.Order{ID: 1, ProductID: 1, ISP: 1, AccountLocation: "广东深圳"}
+	candidates, err := router.Route(ctx, order, nil)
+	if err != nil {
+		t.Fatalf("路由失败: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].API.ID != apiIDs[0] {
+		t.Fatalf("广东移动应只命中通道1，实际: %d", len(candidates))
+	}
+
+	// 浙江移动：最便宜的通道优先
+	order.AccountLocation = "浙江杭州"
+	candidates, err = router.Route(ctx, order, nil)
+	if err != nil {
+		t.Fatalf("路由失败: %v", err)
+	}
+	if len(candidates) != 3 || candidates[0].API.ID != apiIDs[1] || candidates[1].API.ID != apiIDs[3] {
+		t.Fatalf("最低成本排序错误: %+v", candidates)
+	}
+	if !candidates[1].LocationMatch {
+		t.Fatal("命中允许省份的通道应标记为归属地匹配")
+	}
+
+	// 已使用的接口不再参与路由
+	first, err := router.RouteFirst(ctx, order, map[int64]bool{apiIDs[1]: true})
+	if err != nil || first == nil || first.API.ID != apiIDs[3] {
+		t.Fatalf("排除已使用接口后首选通道错误: %+v, %v", first, err)
+	}
+}
+
+// TestRoutingCostSuccessAndLocation 测试按上游成本排序、按近期订单统计成功率，以及归属地按规范化名称精确匹配
+func TestRoutingCostSuccessAndLocation(t *testing.T) {
+	db, router, apiIDs := setupRoutingTest(t, service.RouteStrategyCheapest, []routingChannel{
+		{sort: 1, price: 97.0, cost: 99.0},
+		{sort: 2, price: 99.0, cost: 96.5, allowProvinces: "广东省,北京"},
+		{sort: 3, price: 98.0, cost: 97.0, forbidProvinces: "江"},
+	})
+	ctx := context.Background()
+
+	// 通道2的上游成本最低，即使售价最高也排在最前；"江"不是完整省名，不会禁止江苏
+	order := &model.Order{ID: 1, ProductID: 1, ISP: 1, AccountLocation: "江苏南京"}
+	candidates, err := router.Route(ctx, order, nil)
+	if err != nil || len(candidates) != 2 {
+		t.Fatalf("路由失败: %d %v", len(candidates), err)
+	}
+	if candidates[0].API.ID != apiIDs[2] || candidates[0].Cost != money.Yuan(97) {
+		t.Fatalf("应按上游成本排序: %+v", candidates[0])
+	}
+
+	order.AccountLocation = "广东省 深圳市"
+	candidates, _ = router.Route(ctx, order, nil)
+	if len(candidates) != 3 || candidates[0].API.ID != apiIDs[1] || !candidates[0].LocationMatch {
+		t.Fatalf("规范化后的省份应命中允许名单: %+v", candidates)
+	}
+	order.AccountLocation = "北京"
+	if candidates, _ = router.Route(ctx, order, nil); !candidates[0].LocationMatch {
+		t.Fatal("直辖市应命中允许名单")
+	}
+	order.AccountLocation = "东"
+	if candidates, _ = router.Route(ctx, order, nil); len(candidates) != 2 {
+		t.Fatalf("不完整的地区名不应匹配允许名单: %d", len(candidates))
+	}
+
+	// 通道1近期订单8成功2失败，成功率按订单统计
+	for i := 0; i < 10; i++ {
+		status := model.OrderStatusSuccess
+		if i < 2 {
+			status = model.OrderStatusFailed
+		}
+		num := fmt.Sprintf("S%d", i)
+		if err := db.Create(&model.Order{OrderNumber: num, OutTradeNum: num, ProductID: 1, Status: status, APICurID: apiIDs[0]}).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+	}
+	router = newTestRoutingService(db)
+	order.AccountLocation = ""
+	candidates, _ = router.Route(ctx, order, nil)
+	for _, c := range candidates {
+		if c.API.ID == apiIDs[0] && c.SuccessRate != 0.8 {
+			t.Fatalf("成功率应按近期订单统计: %v", c.SuccessRate)
+		}
+	}
+}
+
+// TestRoutingQuotaExhausted 测试账号额度不足时路由到其他通道
+func TestRoutingQuotaExhausted(t *testing.T) {
+	db, router, apiIDs := setupRoutingTest(t, service.RouteStrategyPriority, []routingChannel{
+		{sort: 1, price: 98.0, dailyLimit: 150},
+		{sort: 2, price: 99.0},
+	})
+	ctx := context.Background()
+
+	order := &model.Order{ID: 1, ProductID: 1, ISP: 1}
+	first, err := router.RouteFirst(ctx, order, nil)
+	if err != nil || first == nil || first.API.ID != apiIDs[0] {
+		t.Fatalf("额度充足时应按排序选择通道1: %+v, %v", first, err)
+	}
+	if !first.QuotaLimited || first.QuotaRemaining != money.Yuan(150) {
+		t.Fatalf("剩余额度计算错误: %+v", first)
+	}
+
+	// 通道1今日已占用100元，剩余额度不足一单
+	spent := &model.AccountSpendReservation{OrderID: 100, AccountID: first.Account.ID, Amount: money.Yuan(100), ReservedAt: time.Now()}
+	if err := db.Create(spent).Error; err != nil {
+		t.Fatalf("创建额度占用失败: %v", err)
+	}
+	// 对账后计数器以未释放的额度占用为准
+	if err := router.SpendLimiter().Reconcile(ctx); err != nil {
+		t.Fatalf("额度对账失败: %v", err)
+	}
+	first, err = router.RouteFirst(ctx, order, nil)
+	if err != nil || first == nil || first.API.ID != apiIDs[1] {
+		t.Fatalf("额度不足时应切换到通道2: %+v, %v", first, err)
+	}
+}
+
+// TestAccountSpendLimiter 测试账号额度占用、超限拦截与回退
+func TestAccountSpendLimiter(t *testing.T) {
+	db, router, _ := setupRoutingTest(t, service.RouteStrategyPriority, []routingChannel{
+		{sort: 1, price: 98.0},
+	})
+	ctx := context.Background()
+	limiter := router.SpendLimiter()
+
+	account := &model.PlatformAccount{PlatformID: 1, AccountName: "limited", AppKey: "k", AppSecret: "s", Status: 1, DailyLimit: money.Yuan(300), MonthlyLimit: money.Yuan(1000)}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("创建平台账号失败: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Reserve(ctx, account, money.Yuan(100)); err != nil {
+			t.Fatalf("第%d次占用额度失败: %v", i+1, err)
+		}
+	}
+	if err := limiter.Reserve(ctx, account, money.MustParse("0.01")); !errors.Is(err, service.ErrSpendLimitExceeded) {
+		t.Fatalf("超出每日限额应返回 ErrSpendLimitExceeded，实际: %v", err)
+	}
+
+	// 提交失败回退后可再次占用
+	limiter.Release(ctx, account.ID, money.Yuan(100), time.Now())
+	if err := limiter.Reserve(ctx, account, money.Yuan(100)); err != nil {
+		t.Fatalf("回退后占用额度失败: %v", err)
+	}
+
+	usage, err := limiter.Usage(ctx, account)
+	if err != nil {
+		t.Fatalf("查询额度失败: %v", err)
+	}
+	if usage.DailySpent != money.Yuan(300) || usage.MonthlySpent != money.Yuan(300) {
+		t.Fatalf("已用额度错误: %+v", usage)
+	}
+	if remaining, limited := usage.Remaining(); !limited || remaining != 0 {
+		t.Fatalf("剩余额度错误: %s %v", remaining, limited)
+	}
+
+	// 订单占用的额度在订单失败时释放，对账时与占用金额一致
+	if err := limiter.ReserveOrder(ctx, account, 7, money.MustParse("0.01")); !errors.Is(err, service.ErrSpendLimitExceeded) {
+		t.Fatalf("额度已用完时订单占用应失败: %v", err)
+	}
+	limiter.Release(ctx, account.ID, money.Yuan(300), time.Now())
+	if err := limiter.Reconcile(ctx); err != nil {
+		t.Fatalf("额度对账失败: %v", err)
+	}
+	if err := limiter.ReserveOrder(ctx, account, 7, money.MustParse("120.5")); err != nil {
+		t.Fatalf("订单占用额度失败: %v", err)
+	}
+	if err := limiter.Reconcile(ctx); err != nil {
+		t.Fatalf("额度对账失败: %v", err)
+	}
+	if usage, _ = limiter.Usage(ctx, account); usage.DailySpent != money.MustParse("120.5") {
+		t.Fatalf("对账后已用额度应等于订单占用: %+v", usage)
+	}
+	for i := 0; i < 2; i++ {
+		if err := limiter.ReleaseOrder(ctx, nil, 7); err != nil {
+			t.Fatalf("释放订单额度失败: %v", err)
+		}
+	}
+	if usage, _ = limiter.Usage(ctx, account); usage.DailySpent != 0 {
+		t.Fatalf("重复释放只应回退一次: %+v", usage)
+	}
+
+	// 未设置限额的账号不受限制
+	if err := limiter.Reserve(ctx, &model.PlatformAccount{ID: 999}, money.Yuan(1000000)); err != nil {
+		t.Fatalf("未设置限额的账号不应被拦截: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"sync"
 	"testing"
 	"time"
@@ -32,6 +33,8 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 		&model.Platform{},
 		&model.PlatformAccount{},
 		&model.BalanceLog{},
+		&model.LedgerTransaction{},
+		&model.LedgerEntry{}, &model.BalanceHold{},
 	)
 	if err != nil {
 		t.Fatalf("迁移表结构失败: %v", err)
@@ -43,12 +46,12 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 		ID:       3,
 		Username: "testuser_same_order",
 		Password: "password",
-		Balance:  100.00, // 初始余额100
+		Balance:  money.Yuan(100), // 初始余额100
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("创建用户失败: %v", err)
 	}
-	t.Logf("创建用户成功: ID=%d, 初始余额=%.2f", user.ID, user.Balance)
+	t.Logf("创建用户成功: ID=%d, 初始余额=%s", user.ID, user.Balance)
 
 	// 创建平台
 	platform := &model.Platform{
@@ -97,7 +100,7 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 
 	// 5. 同一订单并发退款测试
 	concurrency := 20 // 并发数
-	refundAmount := 50.0 // 退款金额
+	refundAmount := money.Yuan(50) // 退款金额
 	orderID := int64(36097) // 同一个订单ID
 	var wg sync.WaitGroup
 	var successCount int32
@@ -134,9 +137,9 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 					mu.Lock()
 					successCount++
 					mu.Unlock()
-					t.Logf("Goroutine %d (%s) 退款成功，余额从%.2f增加到%.2f", goroutineID, accountName, beforeBalance, afterUser.Balance)
+					t.Logf("Goroutine %d (%s) 退款成功，余额从%s增加到%s", goroutineID, accountName, beforeBalance, afterUser.Balance)
 				} else {
-					t.Logf("Goroutine %d (%s) 幂等性跳过，余额未变化: %.2f", goroutineID, accountName, beforeBalance)
+					t.Logf("Goroutine %d (%s) 幂等性跳过，余额未变化: %s", goroutineID, accountName, beforeBalance)
 				}
 			}
 		}(i)
@@ -152,10 +155,10 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 	}
 
 	expectedBalance := user.Balance + refundAmount // 只应该退款一次
-	t.Logf("最终余额: %.2f, 预期余额: %.2f", finalUser.Balance, expectedBalance)
+	t.Logf("最终余额: %s, 预期余额: %s", finalUser.Balance, expectedBalance)
 
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("余额不一致！最终余额: %.2f, 预期余额: %.2f", finalUser.Balance, expectedBalance)
+		t.Errorf("余额不一致！最终余额: %s, 预期余额: %s", finalUser.Balance, expectedBalance)
 	}
 
 	// 7. 验证退款日志数量 - 应该只有一条
@@ -183,7 +186,7 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 		} else if log.Style == model.BalanceStyleOrderDeduct {
 			logType = "扣款"
 		}
-		t.Logf("  %s: 金额%.2f, 余额变化%.2f->%.2f, 平台账号%d, 时间%s", 
+		t.Logf("  %s: 金额%s, 余额变化%s->%s, 平台账号%d, 时间%s", 
 			logType, log.Amount, log.BalanceBefore, log.Balance, log.PlatformAccountID, log.CreatedAt.Format("15:04:05.000"))
 	}
 
@@ -191,7 +194,7 @@ func TestSameOrderConcurrentRefund(t *testing.T) {
 	if logCount == 1 {
 		t.Logf("✅ 同一订单并发退款幂等性测试通过！基于用户ID的幂等性校验正常工作，防止了同一订单通过不同平台账号重复退款")
 		t.Logf("   - 退款日志数量: %d (正确)", logCount)
-		t.Logf("   - 最终余额: %.2f (正确)", finalUser.Balance)
+		t.Logf("   - 最终余额: %s (正确)", finalUser.Balance)
 		t.Logf("   - 实际退款成功次数: %d (可能因并发检查时序问题略有偏差，但关键是日志数量正确)", successCount)
 	} else {
 		t.Errorf("❌ 同一订单并发退款幂等性测试失败！退款日志数量: %d, 预期: 1", logCount)
@@ -5,6 +5,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -23,7 +24,7 @@ func TestServiceDeductBalance(t *testing.T) {
 	}
 
 	// 2. 自动迁移表结构
-	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.Platform{}, &model.PlatformAccount{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -52,8 +53,8 @@ func TestServiceDeductBalance(t *testing.T) {
 	// 创建用户，初始余额100元
 	user := &model.User{
 		ID:      userID,
-		Balance: 100.0,
-		Credit:  0.0,
+		Balance: money.Yuan(100),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -69,8 +70,8 @@ func TestServiceDeductBalance(t *testing.T) {
 		AppKey:       "test_key",
 		AppSecret:    "test_secret",
 		Description:  "测试账号",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
@@ -102,7 +103,7 @@ func TestServiceDeductBalance(t *testing.T) {
 
 	// 8. 测试服务层扣款
 	t.Log("测试服务层扣款...")
-	deductAmount := 50.0
+	deductAmount := money.Yuan(50)
 	orderID := int64(100)
 	err = balanceService.DeductBalance(ctx, accountID, deductAmount, orderID, "测试扣款")
 	if err != nil {
@@ -112,11 +113,11 @@ func TestServiceDeductBalance(t *testing.T) {
 	// 9. 验证扣款结果
 	var userAfterDeduct model.User
 	db.First(&userAfterDeduct, userID)
-	expectedBalance := 50.0 // 100 - 50
+	expectedBalance := money.Yuan(50) // 100 - 50
 	if userAfterDeduct.Balance != expectedBalance {
-		t.Errorf("余额不正确！预期%.2f，实际%.2f", expectedBalance, userAfterDeduct.Balance)
+		t.Errorf("余额不正确！预期%s，实际%s", expectedBalance, userAfterDeduct.Balance)
 	} else {
-		t.Logf("✅ 扣款成功，余额从100.00变为%.2f", userAfterDeduct.Balance)
+		t.Logf("✅ 扣款成功，余额从100.00变为%s", userAfterDeduct.Balance)
 	}
 
 	// 10. 检查扣款日志
@@ -125,6 +126,6 @@ func TestServiceDeductBalance(t *testing.T) {
 	if deductLog.ID == 0 {
 		t.Error("❌ 扣款日志未创建")
 	} else {
-		t.Logf("✅ 扣款日志创建成功: 金额%.2f, 余额变化%.2f->%.2f", deductLog.Amount, deductLog.BalanceBefore, deductLog.Balance)
+		t.Logf("✅ 扣款日志创建成功: 金额%s, 余额变化%s->%s", deductLog.Amount, deductLog.BalanceBefore, deductLog.Balance)
 	}
 }
\ No newline at end of file
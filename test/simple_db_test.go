@@ -2,6 +2,7 @@ package test
 
 import (
 	"recharge-go/internal/model"
+	"recharge-go/pkg/money"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -50,8 +51,8 @@ func TestSimpleDBCreation(t *testing.T) {
 
 	user := &model.User{
 		ID:      1,
-		Balance: 100.0,
-		Credit:  0.0,
+		Balance: money.Yuan(100),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -68,8 +69,8 @@ func TestSimpleDBCreation(t *testing.T) {
 		AppKey:       "test_key",
 		AppSecret:    "test_secret",
 		Description:  "测试账号",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
@@ -3,6 +3,7 @@ package test
 import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
+	"recharge-go/pkg/money"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -44,8 +45,8 @@ func TestTransactionIssue(t *testing.T) {
 	// 创建用户
 	user := &model.User{
 		ID:      userID,
-		Balance: 100.0,
-		Credit:  0.0,
+		Balance: money.Yuan(100),
+		Credit:  money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("Failed to create user: %v", err)
@@ -61,8 +62,8 @@ func TestTransactionIssue(t *testing.T) {
 		AppKey:       "test_key",
 		AppSecret:    "test_secret",
 		Description:  "测试账号",
-		DailyLimit:   1000.0,
-		MonthlyLimit: 30000.0,
+		DailyLimit:   money.Yuan(1000),
+		MonthlyLimit: money.Yuan(30000),
 		Balance:      0.0,
 		Priority:     1,
 		Status:       1,
@@ -143,7 +144,7 @@ func TestTransactionIssue(t *testing.T) {
 		tx2.Rollback()
 		t.Fatalf("查询用户失败: %v", err)
 	}
-	t.Logf("✅ 查询用户成功: 余额%.2f", serviceUser.Balance)
+	t.Logf("✅ 查询用户成功: 余额%s", serviceUser.Balance)
 
 	tx2.Commit()
 	t.Log("✅ 修复方案测试成功！问题确实是Repository和事务的连接不一致")
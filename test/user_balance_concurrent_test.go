@@ -6,6 +6,7 @@ import (
 	"recharge-go/internal/model"
 	"recharge-go/internal/repository"
 	"recharge-go/internal/service"
+	"recharge-go/pkg/money"
 	"sync"
 	"testing"
 	"time"
@@ -28,7 +29,7 @@ func TestUserBalanceSequentialRefund(t *testing.T) {
 	}
 
 	// 2. 自动迁移
-	err = db.AutoMigrate(&model.User{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("数据库迁移失败: %v", err)
 	}
@@ -37,8 +38,8 @@ func TestUserBalanceSequentialRefund(t *testing.T) {
 	user := &model.User{
 		ID:       1,
 		Username: "test_user_seq",
-		Balance:  100.0,
-		Credit:   0.0,
+		Balance:  money.Yuan(100),
+		Credit:   money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("创建测试用户失败: %v", err)
@@ -52,7 +53,7 @@ func TestUserBalanceSequentialRefund(t *testing.T) {
 	// 5. 顺序退款测试
 	ctx := context.Background()
 	refundCount := 5
-	refundAmount := 10.0
+	refundAmount := money.Yuan(10)
 
 	for i := 0; i < refundCount; i++ {
 		orderID := int64(1000 + i)
@@ -65,12 +66,12 @@ func TestUserBalanceSequentialRefund(t *testing.T) {
 	// 6. 检查最终余额
 	finalUser := &model.User{}
 	db.First(finalUser, user.ID)
-	expectedBalance := 100.0 + float64(refundCount)*refundAmount
+	expectedBalance := money.Yuan(100) + refundAmount.MulInt(int64(refundCount))
 
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("❌ 最终余额不正确！预期%.2f，实际%.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("❌ 最终余额不正确！预期%s，实际%s", expectedBalance, finalUser.Balance)
 	} else {
-		t.Logf("✅ 顺序退款测试通过: 最终余额%.2f", finalUser.Balance)
+		t.Logf("✅ 顺序退款测试通过: 最终余额%s", finalUser.Balance)
 	}
 
 	// 7. 检查退款日志数量
@@ -96,7 +97,7 @@ func TestUserBalanceLimitedConcurrentRefund(t *testing.T) {
 	}
 
 	// 2. 自动迁移
-	err = db.AutoMigrate(&model.User{}, &model.BalanceLog{})
+	err = db.AutoMigrate(&model.User{}, &model.BalanceLog{}, &model.LedgerTransaction{}, &model.LedgerEntry{}, &model.BalanceHold{})
 	if err != nil {
 		t.Fatalf("数据库迁移失败: %v", err)
 	}
@@ -105,8 +106,8 @@ func TestUserBalanceLimitedConcurrentRefund(t *testing.T) {
 	user := &model.User{
 		ID:       1,
 		Username: "test_user_limited",
-		Balance:  100.0,
-		Credit:   0.0,
+		Balance:  money.Yuan(100),
+		Credit:   money.Zero,
 	}
 	if err := db.Create(user).Error; err != nil {
 		t.Fatalf("创建测试用户失败: %v", err)
@@ -119,7 +120,7 @@ func TestUserBalanceLimitedConcurrentRefund(t *testing.T) {
 
 	// 5. 有限并发退款测试（减少并发数量）
 	goroutineCount := 3 // 减少到3个并发
-	refundAmount := 10.0
+	refundAmount := money.Yuan(10)
 	orderIDBase := int64(2000)
 
 	// 6. 执行有限并发退款测试
@@ -164,12 +165,12 @@ func TestUserBalanceLimitedConcurrentRefund(t *testing.T) {
 	// 8. 检查用户最终余额
 	finalUser := &model.User{}
 	db.First(finalUser, user.ID)
-	expectedBalance := 100.0 + float64(successCount)*refundAmount
+	expectedBalance := money.Yuan(100) + refundAmount.MulInt(int64(successCount))
 
 	if finalUser.Balance != expectedBalance {
-		t.Errorf("❌ 最终余额不正确！预期%.2f，实际%.2f", expectedBalance, finalUser.Balance)
+		t.Errorf("❌ 最终余额不正确！预期%s，实际%s", expectedBalance, finalUser.Balance)
 	} else {
-		t.Logf("✅ 最终余额正确: %.2f", finalUser.Balance)
+		t.Logf("✅ 最终余额正确: %s", finalUser.Balance)
 	}
 
 	// 9. 检查退款日志数量
@@ -187,7 +188,7 @@ func TestUserBalanceLimitedConcurrentRefund(t *testing.T) {
 	if int(successCount) >= 2 && finalUser.Balance == expectedBalance && logCount == int64(successCount) {
 		t.Log("✅ 用户余额有限并发退款测试通过！FOR UPDATE锁有效防止了并发问题")
 	} else {
-		t.Errorf("❌ 用户余额有限并发退款测试失败！成功次数:%d，余额:%.2f(期望%.2f)，日志数量:%d(期望%d)", 
+		t.Errorf("❌ 用户余额有限并发退款测试失败！成功次数:%d，余额:%s(期望%s)，日志数量:%d(期望%d)", 
 			successCount, finalUser.Balance, expectedBalance, logCount, successCount)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"recharge-go/pkg/signature/webhook"
+)
+
+// TestWebhookSignature 测试回调通知签名格式与校验
+func TestWebhookSignature(t *testing.T) {
+	secret := "app-secret"
+	body := []byte(`{"out_trade_num":"T001","status":4}`)
+
+	// 签名按文档格式计算
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000\nabc\n" + string(body)))
+	if want := "v1=" + hex.EncodeToString(mac.Sum(nil)); webhook.Sign(secret, "1700000000", "abc", body) != want {
+		t.Fatalf("签名格式错误: %s", webhook.Sign(secret, "1700000000", "abc", body))
+	}
+
+	now := time.Now()
+	header := http.Header{}
+	webhook.SetHeaders(header, secret, body, now)
+	if err := webhook.Verify(secret, header, body, 0, now); err != nil {
+		t.Fatalf("签名校验应通过: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		secret string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"请求体被篡改", secret, []byte(`{"out_trade_num":"T001","status":5}`), now, webhook.ErrSignatureMismatch},
+		{"密钥错误", "other-secret", body, now, webhook.ErrSignatureMismatch},
+		{"时间戳过期", secret, body, now.Add(6 * time.Minute), webhook.ErrTimestampInvalid},
+		{"缺少请求头", secret, body, now, webhook.ErrHeaderMissing},
+	}
+	for _, c := range cases {
+		h := header.Clone()
+		if c.want == webhook.ErrHeaderMissing {
+			h.Del(webhook.NonceHeader)
+		}
+		if err := webhook.Verify(c.secret, h, c.body, 0, c.now); !errors.Is(err, c.want) {
+			t.Fatalf("%s: 期望 %v，实际 %v", c.name, c.want, err)
+		}
+	}
+}
+
+// TestWebhookVerifierMiddleware 测试接入方校验中间件拒绝伪造与重放的通知
+func TestWebhookVerifierMiddleware(t *testing.T) {
+	secret := "app-secret"
+	var received []byte
+	handler := webhook.NewVerifier(secret).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	body := []byte(`{"out_trade_num":"T001","status":4}`)
+	newRequest := func(header http.Header) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		return req
+	}
+
+	header := http.Header{}
+	webhook.SetHeaders(header, secret, body, time.Now())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(header))
+	if rec.Code != http.StatusOK || !bytes.Equal(received, body) {
+		t.Fatalf("合法通知应通过并保留请求体: code=%d body=%s", rec.Code, received)
+	}
+
+	// 同一随机串再次出现视为重放
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(header))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("重放的通知应被拒绝: code=%d", rec.Code)
+	}
+
+	// 伪造签名
+	forged := http.Header{}
+	forged.Set(webhook.TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	forged.Set(webhook.NonceHeader, webhook.NewNonce())
+	forged.Set(webhook.SignatureHeader, webhook.Sign("guessed", forged.Get(webhook.TimestampHeader), forged.Get(webhook.NonceHeader), body))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(forged))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("伪造签名的通知应被拒绝: code=%d", rec.Code)
+	}
+}
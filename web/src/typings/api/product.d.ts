@@ -30,6 +30,7 @@ export interface Product {
   voucher_name: string;
   show_style: number;
   api_fail_style: number;
+  route_strategy: string;
   allow_provinces: string;
   allow_cities: string;
   forbid_provinces: string;